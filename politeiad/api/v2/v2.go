@@ -22,9 +22,19 @@ const (
 	// RouteRecordSetStatus sets the status of a record.
 	RouteRecordSetStatus = "/recordsetstatus"
 
+	// RouteRecordCensorFiles censors individual files of a record.
+	RouteRecordCensorFiles = "/recordcensorfiles"
+
 	// RouteRecordTimestamps returns the record timestamps.
 	RouteRecordTimestamps = "/recordtimestamps"
 
+	// RouteRecordDiff returns the changes that were made to a record
+	// between two record versions.
+	RouteRecordDiff = "/recorddiff"
+
+	// RouteRecordHistory returns the history of a record.
+	RouteRecordHistory = "/recordhistory"
+
 	// RouteRecords retrieves a page of records.
 	RouteRecords = "/records"
 
@@ -46,8 +56,90 @@ const (
 	// RoutePluginInventory returns all registered plugins.
 	RoutePluginInventory = "/plugininventory"
 
+	// RouteFsck starts a filesystem check in the background. This route
+	// requires admin privileges.
+	RouteFsck = "/fsck"
+
+	// RouteFsckStatus returns the progress and the report of the most
+	// recent filesystem check. This route requires admin privileges.
+	RouteFsckStatus = "/fsckstatus"
+
+	// RouteReencrypt rotates the encryption key and starts a re-encryption
+	// of the encrypted data in the background. This route requires admin
+	// privileges.
+	RouteReencrypt = "/reencrypt"
+
+	// RouteReencryptStatus returns the progress and the report of the
+	// most recent re-encryption. This route requires admin privileges.
+	RouteReencryptStatus = "/reencryptstatus"
+
+	// RouteAnchorStatus returns the status of the anchoring of the
+	// records. This route requires admin privileges.
+	RouteAnchorStatus = "/anchorstatus"
+
+	// RouteAnchorDrop drops an anchor without waiting for the anchor
+	// schedule. This route requires admin privileges.
+	RouteAnchorDrop = "/anchordrop"
+
+	// RouteReencode starts a re-encoding of the blobs that use a previous
+	// blob encoding in the background. This route requires admin
+	// privileges.
+	RouteReencode = "/reencode"
+
+	// RouteReencodeStatus returns the progress and the report of the most
+	// recent re-encoding. This route requires admin privileges.
+	RouteReencodeStatus = "/reencodestatus"
+
+	// RouteTransaction executes an ordered list of record writes and
+	// plugin writes on a record atomically.
+	RouteTransaction = "/transaction"
+
+	// RouteDraftNew creates a new draft record.
+	RouteDraftNew = "/draftnew"
+
+	// RouteDraftEdit edits a draft record.
+	RouteDraftEdit = "/draftedit"
+
+	// RouteDraftDel deletes a draft record.
+	RouteDraftDel = "/draftdel"
+
+	// RouteDraft returns a draft record.
+	RouteDraft = "/draft"
+
+	// RouteDraftPromote submits a draft record as a new unvetted record.
+	RouteDraftPromote = "/draftpromote"
+
+	// RouteChanges returns the entries of the change log that come after
+	// a sequence number. This is a GET route. The sequence number is
+	// provided using the "since" query parameter. The changes are
+	// streamed as server-sent events when the client accepts a
+	// text/event-stream reply.
+	RouteChanges = "/changes"
+
+	// RouteSnapshot returns a signed inventory snapshot.
+	RouteSnapshot = "/snapshot"
+
+	// RouteSnapshotProof returns the proof that a record was included in
+	// a signed inventory snapshot.
+	RouteSnapshotProof = "/snapshotproof"
+
+	// RouteIdentityHistory returns the identity history of the server.
+	RouteIdentityHistory = "/identityhistory"
+
+	// RouteAudit returns the entries of the audit log. This route
+	// requires admin privileges.
+	RouteAudit = "/audit"
+
 	// ChallengeSize is the size of a request challenge token in bytes.
 	ChallengeSize = 32
+
+	// ChangesPageSize is the maximum number of changes that are returned
+	// in a single Changes reply.
+	ChangesPageSize uint32 = 100
+
+	// AuditPageSize is the maximum number of audit log entries that are
+	// returned in a single Audit reply.
+	AuditPageSize uint32 = 100
 )
 
 // ErrorCodeT represents a user error code.
@@ -135,10 +227,52 @@ const (
 	// not allowed since they will cause collisions.
 	ErrorCodeDuplicatePayload ErrorCodeT = 22
 
+	// ErrorCodeFsckInProgress is returned when a filesystem check is
+	// requested while a previous filesystem check is still running.
+	ErrorCodeFsckInProgress ErrorCodeT = 23
+
+	// ErrorCodeReencryptInProgress is returned when a re-encryption is
+	// requested while a previous re-encryption is still running.
+	ErrorCodeReencryptInProgress ErrorCodeT = 24
+
+	// ErrorCodeAnchorInProgress is returned when an anchor drop is
+	// requested while a previous anchor is still being dropped.
+	ErrorCodeAnchorInProgress ErrorCodeT = 25
+
+	// ErrorCodeAnchoringDisabled is returned when an anchor drop is
+	// requested but the server has not been configured with any anchor
+	// providers.
+	ErrorCodeAnchoringDisabled ErrorCodeT = 26
+
+	// ErrorCodeReencodeInProgress is returned when a re-encoding is
+	// requested while a previous re-encoding is still running.
+	ErrorCodeReencodeInProgress ErrorCodeT = 27
+
+	// ErrorCodeTxOpInvalid is returned when a transaction contains an
+	// invalid operation type.
+	ErrorCodeTxOpInvalid ErrorCodeT = 28
+
+	// ErrorCodeSnapshotNotFound is returned when an inventory snapshot
+	// does not exist.
+	ErrorCodeSnapshotNotFound ErrorCodeT = 29
+
+	// ErrorCodePermissionDenied is returned when the API key of a request
+	// is not allowed to access the route or to execute a plugin command.
+	ErrorCodePermissionDenied ErrorCodeT = 30
+
+	// ErrorCodeInventoryCursorInvalid is returned when an inventory page
+	// is requested using a cursor that was not returned by the server.
+	ErrorCodeInventoryCursorInvalid ErrorCodeT = 31
+
+	// ErrorCodeTxInProgress is returned when a plugin write is made while
+	// a transaction is executing a plugin command. The write can be
+	// retried once the transaction has ended.
+	ErrorCodeTxInProgress ErrorCodeT = 32
+
 	// ErrorCodeLast is used by unit tests to verify that all error codes have
 	// a human readable entry in the ErrorCodes map. This error will never be
 	// returned.
-	ErrorCodeLast ErrorCodeT = 23
+	ErrorCodeLast ErrorCodeT = 33
 )
 
 var (
@@ -167,6 +301,16 @@ var (
 		ErrorCodeRecordStateInvalid:      "record state invalid",
 		ErrorCodeRecordStatusInvalid:     "record status invalid",
 		ErrorCodeDuplicatePayload:        "duplicate payload",
+		ErrorCodeFsckInProgress:          "fsck in progress",
+		ErrorCodeReencryptInProgress:     "reencrypt in progress",
+		ErrorCodeAnchorInProgress:        "anchor in progress",
+		ErrorCodeAnchoringDisabled:       "anchoring disabled",
+		ErrorCodeReencodeInProgress:      "reencode in progress",
+		ErrorCodeTxOpInvalid:             "transaction op invalid",
+		ErrorCodeSnapshotNotFound:        "snapshot not found",
+		ErrorCodePermissionDenied:        "permission denied",
+		ErrorCodeInventoryCursorInvalid:  "inventory cursor invalid",
+		ErrorCodeTxInProgress:            "transaction in progress",
 	}
 )
 
@@ -222,9 +366,13 @@ const (
 	// RecordStateVetted indicates a record has been made public.
 	RecordStateVetted RecordStateT = 2
 
+	// RecordStateDraft indicates a record is a draft. Drafts are not
+	// timestamped and are only visible to their author.
+	RecordStateDraft RecordStateT = 3
+
 	// RecordStateLast is used for unit test validation of human readable
 	// errors.
-	RecordStateLast = 3
+	RecordStateLast = 4
 )
 
 var (
@@ -233,6 +381,7 @@ var (
 		RecordStateInvalid:  "invalid",
 		RecordStateUnvetted: "unvetted",
 		RecordStateVetted:   "vetted",
+		RecordStateDraft:    "draft",
 	}
 )
 
@@ -314,20 +463,42 @@ type CensorshipRecord struct {
 	Token string `json:"token"`
 
 	// Merkle is the ordered merkle root of all files in the record.
+	// This includes any files that have been censored.
 	Merkle string `json:"merkle"`
 
 	// Signature is the server signature of the Merkle+Token.
 	Signature string `json:"signature"`
 }
 
+// CensoredFile describes a record file whose payload has been permanently
+// deleted by the server. The digest of the censored file is still part of the
+// record merkle root.
+//
+// Signature is the server signature of the
+// Token+Version+Name+Digest+Reason+Timestamp, where the version and timestamp
+// are base 10 encoded.
+type CensoredFile struct {
+	Version   uint32 `json:"version"`   // Record version
+	Name      string `json:"name"`      // Filename
+	MIME      string `json:"mime"`      // MIME type
+	Digest    string `json:"digest"`    // SHA256 of the censored payload
+	Reason    string `json:"reason"`    // Reason for censorship
+	Timestamp int64  `json:"timestamp"` // Unix timestamp of censorship
+	Signature string `json:"signature"` // Server signature
+}
+
 // Record represents a record and all of its contents.
+//
+// CensoredFiles contains the files of this record version that have been
+// censored. These files are not included in the Files field.
 type Record struct {
-	State     RecordStateT     `json:"state"`     // Record state
-	Status    RecordStatusT    `json:"status"`    // Record status
-	Version   uint32           `json:"version"`   // Version of this record
-	Timestamp int64            `json:"timestamp"` // Last update
-	Metadata  []MetadataStream `json:"metadata"`
-	Files     []File           `json:"files"`
+	State         RecordStateT     `json:"state"`     // Record state
+	Status        RecordStatusT    `json:"status"`    // Record status
+	Version       uint32           `json:"version"`   // Version of this record
+	Timestamp     int64            `json:"timestamp"` // Last update
+	Metadata      []MetadataStream `json:"metadata"`
+	Files         []File           `json:"files"`
+	CensoredFiles []CensoredFile   `json:"censoredfiles,omitempty"`
 
 	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
 }
@@ -408,6 +579,163 @@ type RecordSetStatusReply struct {
 	Record   Record `json:"record"`
 }
 
+// CensorFile identifies a record file that is to be censored.
+type CensorFile struct {
+	Version uint32 `json:"version"` // Record version
+	Name    string `json:"name"`    // Filename
+}
+
+// RecordCensorFiles permanently deletes the payloads of the provided record
+// files. Only the files of public and archived records can be censored. The
+// record is not otherwise modified.
+//
+// Unchanged files are shared between record versions. Censoring a file
+// censors it in all versions of the record that contain the same file. The
+// reply will include these additional versions.
+type RecordCensorFiles struct {
+	Challenge string       `json:"challenge"` // Random challenge
+	Token     string       `json:"token"`     // Censorship token
+	Files     []CensorFile `json:"files"`
+	Reason    string       `json:"reason"`
+}
+
+// RecordCensorFilesReply is the reply to the RecordCensorFiles command.
+type RecordCensorFilesReply struct {
+	Response      string         `json:"response"` // Challenge response
+	CensoredFiles []CensoredFile `json:"censoredfiles"`
+}
+
+// TxOpT represents the type of a transaction operation.
+type TxOpT uint32
+
+const (
+	// TxOpInvalid is an invalid transaction operation.
+	TxOpInvalid TxOpT = 0
+
+	// TxOpRecordEdit edits the record. See RecordEdit.
+	TxOpRecordEdit TxOpT = 1
+
+	// TxOpRecordEditMetadata edits the record metadata. See
+	// RecordEditMetadata.
+	TxOpRecordEditMetadata TxOpT = 2
+
+	// TxOpRecordSetStatus sets the record status. See RecordSetStatus.
+	TxOpRecordSetStatus TxOpT = 3
+
+	// TxOpPluginWrite executes a plugin write command. See PluginWrite.
+	TxOpPluginWrite TxOpT = 4
+)
+
+// TxOp is a single write operation of a transaction. The fields that are used
+// depend on the operation type. The metadata and file fields are used by the
+// record writes. Status is only used by TxOpRecordSetStatus. The plugin
+// fields are only used by TxOpPluginWrite.
+type TxOp struct {
+	Type TxOpT `json:"type"`
+
+	// Record write fields
+	MDAppend    []MetadataStream `json:"mdappend,omitempty"`
+	MDOverwrite []MetadataStream `json:"mdoverwrite,omitempty"`
+	FilesAdd    []File           `json:"filesadd,omitempty"`
+	FilesDel    []string         `json:"filesdel,omitempty"`
+	Status      RecordStatusT    `json:"status,omitempty"`
+
+	// Plugin write fields
+	PluginID  string `json:"pluginid,omitempty"`
+	PluginCmd string `json:"plugincmd,omitempty"`
+	Payload   string `json:"payload,omitempty"`
+}
+
+// Transaction executes an ordered list of record writes and plugin writes on
+// a record atomically. Either all of the operations are committed or none of
+// them are. If an operation fails, the error of the failed operation is
+// returned.
+type Transaction struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Censorship token
+	Ops       []TxOp `json:"ops"`
+}
+
+// TransactionReply is the reply to the Transaction command. Record is the
+// record once all operations have been committed. Payloads contains the reply
+// payload of each operation. The payload is empty for record writes.
+type TransactionReply struct {
+	Response string   `json:"response"` // Challenge response
+	Record   Record   `json:"record"`
+	Payloads []string `json:"payloads"`
+}
+
+// DraftNew creates a new draft record. Drafts are saved encrypted and are
+// never timestamped. A draft can be edited freely until it is promoted to an
+// unvetted record using the DraftPromote command.
+type DraftNew struct {
+	Challenge string           `json:"challenge"` // Random challenge
+	Metadata  []MetadataStream `json:"metadata,omitempty"`
+	Files     []File           `json:"files"`
+}
+
+// DraftNewReply is the reply to the DraftNew command.
+type DraftNewReply struct {
+	Response string `json:"response"` // Challenge response
+	Record   Record `json:"record"`
+}
+
+// DraftEdit edits an existing draft record. The fields have the same meaning
+// as the RecordEdit fields. The draft is updated in place. Previous versions
+// of a draft are not retained.
+type DraftEdit struct {
+	Challenge   string           `json:"challenge"` // Random challenge
+	Token       string           `json:"token"`     // Draft token
+	MDAppend    []MetadataStream `json:"mdappend,omitempty"`
+	MDOverwrite []MetadataStream `json:"mdoverwrite,omitempty"`
+	FilesAdd    []File           `json:"filesadd,omitempty"`
+	FilesDel    []string         `json:"filesdel,omitempty"`
+}
+
+// DraftEditReply is the reply to the DraftEdit command.
+type DraftEditReply struct {
+	Response string `json:"response"` // Challenge response
+	Record   Record `json:"record"`
+}
+
+// DraftDel deletes a draft record.
+type DraftDel struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Draft token
+}
+
+// DraftDelReply is the reply to the DraftDel command.
+type DraftDelReply struct {
+	Response string `json:"response"` // Challenge response
+}
+
+// Draft retrieves a draft record.
+type Draft struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Draft token
+}
+
+// DraftReply is the reply to the Draft command.
+type DraftReply struct {
+	Response string `json:"response"` // Challenge response
+	Record   Record `json:"record"`
+}
+
+// DraftPromote submits a draft record as a new unvetted record. The record is
+// subject to the same validation as a record that is submitted using the
+// RecordNew command. The returned record has a new token. The draft is
+// deleted once the record has been created.
+type DraftPromote struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Draft token
+}
+
+// DraftPromoteReply is the reply to the DraftPromote command.
+type DraftPromoteReply struct {
+	Response string `json:"response"` // Challenge response
+	Record   Record `json:"record"`
+}
+
 // Proof contains an inclusion proof for the digest in the merkle root. All
 // digests are hex encoded SHA256 digests.
 //
@@ -421,6 +749,17 @@ type Proof struct {
 	ExtraData  string   `json:"extradata"` // JSON encoded
 }
 
+// TimestampAnchor contains the proof that the log merkle root of a timestamp
+// was anchored by an anchor provider. The TxID is the DCR transaction ID for
+// dcrtime anchors. The other providers use it for their own reference of the
+// anchor.
+type TimestampAnchor struct {
+	Provider   string `json:"provider"`
+	TxID       string `json:"txid"`
+	MerkleRoot string `json:"merkleroot"`
+	Proof      Proof  `json:"proof"`
+}
+
 // Timestamp contains all of the data required to verify that a piece of record
 // content was timestamped onto the decred blockchain.
 //
@@ -430,12 +769,17 @@ type Proof struct {
 // TxID, MerkleRoot, and Proofs will only be populated once the merkle root has
 // been included in a DCR tx and the tx has 6 confirmations. The Data field
 // will not be populated if the data has been censored.
+//
+// The log merkle root can be anchored by multiple anchor providers. Anchors
+// contains the anchors of all providers. TxID, MerkleRoot, and the last proof
+// correspond to the first anchor.
 type Timestamp struct {
-	Data       string  `json:"data"` // JSON encoded
-	Digest     string  `json:"digest"`
-	TxID       string  `json:"txid"`
-	MerkleRoot string  `json:"merkleroot"`
-	Proofs     []Proof `json:"proofs"`
+	Data       string            `json:"data"` // JSON encoded
+	Digest     string            `json:"digest"`
+	TxID       string            `json:"txid"`
+	MerkleRoot string            `json:"merkleroot"`
+	Proofs     []Proof           `json:"proofs"`
+	Anchors    []TimestampAnchor `json:"anchors"`
 }
 
 // RecordTimestamps requests the timestamps for a record. If a version is not
@@ -458,6 +802,126 @@ type RecordTimestampsReply struct {
 	Files map[string]Timestamp `json:"files"`
 }
 
+// DiffActionT represents the type of change that was made to a piece of record
+// content between two record versions.
+type DiffActionT uint32
+
+const (
+	// DiffActionInvalid is an invalid diff action.
+	DiffActionInvalid DiffActionT = 0
+
+	// DiffActionAdd indicates that the content was added.
+	DiffActionAdd DiffActionT = 1
+
+	// DiffActionDel indicates that the content was removed.
+	DiffActionDel DiffActionT = 2
+
+	// DiffActionModify indicates that the content was modified.
+	DiffActionModify DiffActionT = 3
+)
+
+var (
+	// DiffActions contains the human readable diff actions.
+	DiffActions = map[DiffActionT]string{
+		DiffActionInvalid: "invalid",
+		DiffActionAdd:     "add",
+		DiffActionDel:     "del",
+		DiffActionModify:  "modify",
+	}
+)
+
+// RecordMetadataDiff describes a change to a single RecordMetadata field. The
+// values are formatted as strings.
+type RecordMetadataDiff struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// MetadataStreamDiff describes a change to a metadata stream. The from payload
+// is empty for added streams and the to payload is empty for removed streams.
+type MetadataStreamDiff struct {
+	Action      DiffActionT `json:"action"`
+	PluginID    string      `json:"pluginid"`
+	StreamID    uint32      `json:"streamid"`
+	FromPayload string      `json:"frompayload,omitempty"` // JSON encoded
+	ToPayload   string      `json:"topayload,omitempty"`   // JSON encoded
+}
+
+// FileDiff describes a change to a record file. The from fields are empty for
+// added files and the to fields are empty for removed files.
+//
+// LineDiff contains a unified line diff of the file payloads. It is only
+// populated for text files whose payloads have not been censored.
+type FileDiff struct {
+	Action     DiffActionT `json:"action"`
+	Name       string      `json:"name"`
+	FromMIME   string      `json:"frommime,omitempty"`
+	ToMIME     string      `json:"tomime,omitempty"`
+	FromDigest string      `json:"fromdigest,omitempty"`
+	ToDigest   string      `json:"todigest,omitempty"`
+	Censored   bool        `json:"censored,omitempty"`
+	LineDiff   string      `json:"linediff,omitempty"`
+}
+
+// RecordDiff requests the changes that were made to a record between two
+// record versions. If no to version is provided then the most recent version
+// is used. If no from version is provided then the version prior to the to
+// version is used. Version 1 of a record has no prior version so all of its
+// content is reported as added.
+type RecordDiff struct {
+	Challenge   string `json:"challenge"` // Random challenge
+	Token       string `json:"token"`     // Censorship token
+	FromVersion uint32 `json:"fromversion,omitempty"`
+	ToVersion   uint32 `json:"toversion,omitempty"`
+}
+
+// RecordDiffReply is the reply to the RecordDiff command. Content that did not
+// change between the two versions is not included.
+type RecordDiffReply struct {
+	Response       string               `json:"response"` // Challenge response
+	FromVersion    uint32               `json:"fromversion"`
+	ToVersion      uint32               `json:"toversion"`
+	RecordMetadata []RecordMetadataDiff `json:"recordmetadata"`
+	Metadata       []MetadataStreamDiff `json:"metadata"`
+	Files          []FileDiff           `json:"files"`
+}
+
+// RecordHistoryEntry describes a single iteration of a record. Merkle is the
+// merkle root of the record files of the iteration.
+//
+// Metadata contains the metadata streams that were added or modified by the
+// iteration. This includes the status change metadata that is appended to a
+// record when its status is updated.
+//
+// AnchorTxID is the decred transaction that contains the anchor of the
+// iteration. It is empty if the iteration has not been anchored yet.
+type RecordHistoryEntry struct {
+	State      RecordStateT     `json:"state"`
+	Status     RecordStatusT    `json:"status"`
+	Version    uint32           `json:"version"`
+	Iteration  uint32           `json:"iteration"`
+	Timestamp  int64            `json:"timestamp"`
+	Merkle     string           `json:"merkle"`
+	Metadata   []MetadataStream `json:"metadata"`
+	Anchored   bool             `json:"anchored"`
+	AnchorTxID string           `json:"anchortxid,omitempty"`
+}
+
+// RecordHistory requests the history of a record. The history of an unvetted
+// record is not returned once the record has been made vetted.
+type RecordHistory struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`     // Censorship token
+}
+
+// RecordHistoryReply is the reply to the RecordHistory command. It contains
+// an entry for every iteration of the record, sorted from oldest to newest.
+type RecordHistoryReply struct {
+	Response string               `json:"response"` // Challenge response
+	History  []RecordHistoryEntry `json:"history"`
+}
+
 const (
 	// RecordsPageSize is the maximum number of records that can be
 	// requested using the Records commands.
@@ -513,7 +977,11 @@ const (
 // of their most recent status change, sorted from newest to oldest.
 //
 // The state, status, and page arguments can be provided to request a specific
-// page of record tokens.
+// page of record tokens. The following page can also be requested using the
+// cursor that was returned with the preceding page, in which case the page
+// number is ignored. Cursors should be used to page through large inventories
+// since the server does not need to walk the preceding pages to find the
+// start of the page.
 //
 // If no status is provided then a page of tokens for all statuses will be
 // returned. All other arguments will be ignored.
@@ -522,29 +990,37 @@ type Inventory struct {
 	State     RecordStateT  `json:"state,omitempty"`
 	Status    RecordStatusT `json:"status,omitempty"`
 	Page      uint32        `json:"page,omitempty"`
+	Cursor    string        `json:"cursor,omitempty"`
 }
 
 // InventoryReply is the reply to the Inventory command. The map keys are the
-// human readable record statuses defined by the RecordStatuses array.
+// human readable record statuses defined by the RecordStatuses array. The
+// cursor of the following page is only returned when a page of a single
+// status was requested and the page is full.
 type InventoryReply struct {
 	Response string              `json:"response"` // Challenge response
 	Unvetted map[string][]string `json:"unvetted"` // [status][]token
 	Vetted   map[string][]string `json:"vetted"`   // [status][]token
+	Cursor   string              `json:"cursor,omitempty"`
 }
 
 // InventoryOrdered requests a page of record tokens ordered by the timestamp
 // of their most recent status change from newest to oldest. The reply will
-// include tokens for all record statuses.
+// include tokens for all record statuses. The page can be requested by number
+// or by the cursor that was returned with the preceding page.
 type InventoryOrdered struct {
 	Challenge string       `json:"challenge"` // Random challenge
 	State     RecordStateT `json:"state"`
 	Page      uint32       `json:"page"`
+	Cursor    string       `json:"cursor,omitempty"`
 }
 
-// InventoryOrderedReply is the reply to the InventoryOrdered command.
+// InventoryOrderedReply is the reply to the InventoryOrdered command. The
+// cursor of the following page is returned when the page is full.
 type InventoryOrderedReply struct {
 	Response string   `json:"response"` // Challenge response
 	Tokens   []string `json:"tokens"`
+	Cursor   string   `json:"cursor,omitempty"`
 }
 
 // PluginCmd represents plugin command and the command payload. A token is
@@ -620,3 +1096,504 @@ type PluginInventoryReply struct {
 	Response string   `json:"response"` // Challenge response
 	Plugins  []Plugin `json:"plugins"`
 }
+
+// FsckIssueT represents the type of problem that was found by a filesystem
+// check.
+type FsckIssueT string
+
+const (
+	// FsckIssueBlobMissing indicates that a blob that is referenced by a
+	// record tree does not exist in the key-value store. Missing blobs
+	// can not be repaired.
+	FsckIssueBlobMissing FsckIssueT = "blobmissing"
+
+	// FsckIssueCacheStale indicates that a plugin cache does not match
+	// the data that it was built from.
+	FsckIssueCacheStale FsckIssueT = "cachestale"
+
+	// FsckIssueInventoryDrift indicates that a record inventory does not
+	// match the records that it contains.
+	FsckIssueInventoryDrift FsckIssueT = "inventorydrift"
+
+	// FsckIssueUserCacheGap indicates that a record is missing from the
+	// user cache of its author.
+	FsckIssueUserCacheGap FsckIssueT = "usercachegap"
+
+	// FsckIssueBlobOrphaned indicates that a blob, or the reference of
+	// a record to a content blob, is not referenced by any tree. These
+	// are left behind when politeiad stops in the middle of a write.
+	FsckIssueBlobOrphaned FsckIssueT = "bloborphaned"
+)
+
+// FsckIssue describes a problem that was found by a filesystem check. The
+// plugin ID is only populated for problems that were found by a plugin.
+// Repaired is true if the problem was fixed.
+type FsckIssue struct {
+	Type        FsckIssueT `json:"type"`
+	PluginID    string     `json:"pluginid,omitempty"`
+	Token       string     `json:"token,omitempty"`
+	Description string     `json:"description"`
+	Repaired    bool       `json:"repaired"`
+}
+
+// FsckReport is the result of a filesystem check.
+type FsckReport struct {
+	DryRun  bool        `json:"dryrun"`
+	Records int         `json:"records"` // Number of records checked
+	Issues  []FsckIssue `json:"issues"`
+}
+
+// Fsck starts a filesystem check of the backend. The check is performed in
+// the background while the server remains online. The progress and the
+// report of the check can be retrieved using the FsckStatus command.
+//
+// A dry run lists the problems that are found without repairing them.
+type Fsck struct {
+	Challenge string `json:"challenge"` // Random challenge
+	DryRun    bool   `json:"dryrun,omitempty"`
+}
+
+// FsckReply is the reply to the Fsck command.
+type FsckReply struct {
+	Response string `json:"response"` // Challenge response
+}
+
+// FsckStatus retrieves the status of the most recent filesystem check.
+type FsckStatus struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// FsckStatusReply is the reply to the FsckStatus command. Started is zero if
+// a filesystem check has not been run since the server was started. The
+// stage describes the part of the check that is currently being performed and
+// done and total describe the progress of that stage. The error is populated
+// if the check failed. The report is populated once the check has completed
+// successfully.
+type FsckStatusReply struct {
+	Response  string      `json:"response"` // Challenge response
+	Running   bool        `json:"running"`
+	DryRun    bool        `json:"dryrun"`
+	Stage     string      `json:"stage,omitempty"`
+	Done      int         `json:"done"`
+	Total     int         `json:"total"`
+	Started   int64       `json:"started"`   // Unix timestamp
+	Completed int64       `json:"completed"` // Unix timestamp
+	Error     string      `json:"error,omitempty"`
+	Report    *FsckReport `json:"report,omitempty"`
+}
+
+// ReencryptReport is the result of a re-encryption. Inspected is the number of
+// key-value store entries that were inspected and Reencrypted is the number of
+// encrypted blobs that were re-encrypted using the new key.
+type ReencryptReport struct {
+	KeyVersion  uint32 `json:"keyversion"`
+	Resumed     bool   `json:"resumed"`
+	Inspected   int    `json:"inspected"`
+	Reencrypted int    `json:"reencrypted"`
+}
+
+// Reencrypt rotates the encryption key of the backend and re-encrypts all
+// encrypted data using the new key. The encryption key version is recorded in
+// the header of every encrypted blob, so data that has not been re-encrypted
+// yet remains readable. The re-encryption is performed in the background while
+// the server remains online. Its progress and report can be retrieved using
+// the ReencryptStatus command.
+//
+// If a previous re-encryption was interrupted, e.g. by a server restart, it is
+// resumed from where it left off and the key is not rotated again.
+type Reencrypt struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// ReencryptReply is the reply to the Reencrypt command.
+type ReencryptReply struct {
+	Response string `json:"response"` // Challenge response
+}
+
+// ReencryptStatus retrieves the status of the most recent re-encryption.
+type ReencryptStatus struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// ReencryptStatusReply is the reply to the ReencryptStatus command. Started is
+// zero if a re-encryption has not been run since the server was started. Done
+// and total describe the number of entries that have been inspected. The error
+// is populated if the re-encryption failed. The report is populated once the
+// re-encryption has completed successfully.
+type ReencryptStatusReply struct {
+	Response  string           `json:"response"` // Challenge response
+	Running   bool             `json:"running"`
+	Done      int              `json:"done"`
+	Total     int              `json:"total"`
+	Started   int64            `json:"started"`   // Unix timestamp
+	Completed int64            `json:"completed"` // Unix timestamp
+	Error     string           `json:"error,omitempty"`
+	Report    *ReencryptReport `json:"report,omitempty"`
+}
+
+// ReencodeReport is the result of a re-encoding. Version is the blob encoding
+// version that the blobs now use. Records is the number of records whose blobs
+// were inspected, Inspected is the number of blobs that were inspected and
+// Reencoded is the number of blobs that were re-encoded.
+type ReencodeReport struct {
+	Version   uint8 `json:"version"`
+	Resumed   bool  `json:"resumed"`
+	Records   int   `json:"records"`
+	Inspected int   `json:"inspected"`
+	Reencoded int   `json:"reencoded"`
+}
+
+// Reencode re-encodes the blobs that were saved using a previous blob encoding
+// using the current blob encoding. Blobs of all encodings remain readable, so
+// the re-encoding is performed in the background while the server remains
+// online. Its progress and report can be retrieved using the ReencodeStatus
+// command.
+//
+// If a previous re-encoding was interrupted, e.g. by a server restart, it is
+// resumed from where it left off.
+type Reencode struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// ReencodeReply is the reply to the Reencode command.
+type ReencodeReply struct {
+	Response string `json:"response"` // Challenge response
+}
+
+// ReencodeStatus retrieves the status of the most recent re-encoding.
+type ReencodeStatus struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// ReencodeStatusReply is the reply to the ReencodeStatus command. Started is
+// zero if a re-encoding has not been run since the server was started. Done
+// and total describe the number of records that have been re-encoded. The
+// error is populated if the re-encoding failed. The report is populated once
+// the re-encoding has completed successfully.
+type ReencodeStatusReply struct {
+	Response  string          `json:"response"` // Challenge response
+	Running   bool            `json:"running"`
+	Done      int             `json:"done"`
+	Total     int             `json:"total"`
+	Started   int64           `json:"started"`   // Unix timestamp
+	Completed int64           `json:"completed"` // Unix timestamp
+	Error     string          `json:"error,omitempty"`
+	Report    *ReencodeReport `json:"report,omitempty"`
+}
+
+// AnchorTree describes a record that has leaves that have not been included in
+// an anchor yet. Lag is the number of seconds since the oldest unanchored leaf
+// was added. LastAnchor is the Unix timestamp of the most recent anchor of the
+// record and is 0 if the record has never been anchored. InFlight is true if
+// the record is part of the anchor that is waiting to drop.
+type AnchorTree struct {
+	Token      string `json:"token"`
+	Leaves     uint64 `json:"leaves"`
+	Lag        int64  `json:"lag"`
+	LastAnchor int64  `json:"lastanchor"`
+	InFlight   bool   `json:"inflight"`
+}
+
+// AnchorInFlight describes an anchor that has been submitted to the anchor
+// providers and is waiting to drop. Pending contains the digests that each
+// anchor provider has not anchored yet, e.g. the digests that are waiting on
+// dcrtime confirmations.
+type AnchorInFlight struct {
+	Started int64               `json:"started"` // Unix timestamp
+	Tokens  []string            `json:"tokens"`
+	Pending map[string][]string `json:"pending"` // [providerID]digests
+}
+
+// AnchorFailure describes an error that occurred while dropping an anchor.
+// The provider and token are only populated for errors that are specific to
+// an anchor provider or a record.
+type AnchorFailure struct {
+	Provider  string `json:"provider,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Error     string `json:"error"`
+	Timestamp int64  `json:"timestamp"` // Unix timestamp
+}
+
+// AnchorStatus retrieves the status of the anchoring of the records.
+type AnchorStatus struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// AnchorStatusReply is the reply to the AnchorStatus command.
+//
+// Records is the number of records that contain data. Unanchored contains the
+// records that have leaves that have not been anchored yet, ordered by lag
+// from largest to smallest. Leaves and Lag are the total number of unanchored
+// leaves and the largest lag of all records. LastAnchor is the Unix timestamp
+// of the most recent anchor of any record.
+//
+// Dropping is true while an anchor is being dropped. The in-flight anchor is
+// only populated once the anchor has been submitted to the anchor providers.
+// Failures contains the most recent anchoring failures, most recent first.
+// Failures are kept in memory and are lost when the server is restarted.
+type AnchorStatusReply struct {
+	Response   string          `json:"response"` // Challenge response
+	Providers  []string        `json:"providers"`
+	Dropping   bool            `json:"dropping"`
+	InFlight   *AnchorInFlight `json:"inflight,omitempty"`
+	LastAnchor int64           `json:"lastanchor"`
+	Records    int             `json:"records"`
+	Unanchored []AnchorTree    `json:"unanchored"`
+	Leaves     uint64          `json:"leaves"`
+	Lag        int64           `json:"lag"`
+	Failures   []AnchorFailure `json:"failures"`
+}
+
+// AnchorDrop drops an anchor for all records that have unanchored leaves
+// without waiting for the anchor schedule. The digests are submitted to the
+// anchor providers before the reply is returned. The anchor drops in the
+// background and can be monitored using the AnchorStatus command.
+type AnchorDrop struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// AnchorDropReply is the reply to the AnchorDrop command.
+type AnchorDropReply struct {
+	Response string `json:"response"` // Challenge response
+}
+
+// ChangeT represents a type of change in the change log.
+type ChangeT uint32
+
+const (
+	// ChangeInvalid is an invalid change type.
+	ChangeInvalid ChangeT = 0
+
+	// ChangeRecordNew is the change type for a new record.
+	ChangeRecordNew ChangeT = 1
+
+	// ChangeRecordEdit is the change type for a record edit.
+	ChangeRecordEdit ChangeT = 2
+
+	// ChangeRecordEditMetadata is the change type for a record metadata
+	// edit.
+	ChangeRecordEditMetadata ChangeT = 3
+
+	// ChangeRecordSetStatus is the change type for a record status
+	// change.
+	ChangeRecordSetStatus ChangeT = 4
+
+	// ChangeRecordCensorFiles is the change type for a record file
+	// censorship.
+	ChangeRecordCensorFiles ChangeT = 5
+
+	// ChangePluginWrite is the change type for a plugin write command.
+	ChangePluginWrite ChangeT = 6
+
+	// ChangeLast is used for unit test validation of human readable
+	// change types.
+	ChangeLast = 7
+)
+
+var (
+	// Changes contains the human readable change types.
+	Changes = map[ChangeT]string{
+		ChangeInvalid:            "invalid",
+		ChangeRecordNew:          "record new",
+		ChangeRecordEdit:         "record edit",
+		ChangeRecordEditMetadata: "record edit metadata",
+		ChangeRecordSetStatus:    "record set status",
+		ChangeRecordCensorFiles:  "record censor files",
+		ChangePluginWrite:        "plugin write",
+	}
+)
+
+// Change is an entry in the change log. Sequence numbers start at 1 and are
+// incremented by 1 for each change, so a client that has seen all changes up
+// to a sequence number can resume from it. The record fields describe the
+// record once the change has been made. The plugin fields are only populated
+// for plugin writes.
+type Change struct {
+	Sequence  uint64        `json:"sequence"`
+	Type      ChangeT       `json:"type"`
+	Token     string        `json:"token"`
+	State     RecordStateT  `json:"state,omitempty"`
+	Status    RecordStatusT `json:"status,omitempty"`
+	Version   uint32        `json:"version,omitempty"`
+	Iteration uint32        `json:"iteration,omitempty"`
+	PluginID  string        `json:"pluginid,omitempty"`
+	PluginCmd string        `json:"plugincmd,omitempty"`
+	Timestamp int64         `json:"timestamp"` // Unix timestamp
+}
+
+// ChangesReply is the reply to a Changes request. A Changes request is a GET
+// request to the RouteChanges route. It returns at most ChangesPageSize
+// changes that have a sequence number greater than the "since" query
+// parameter.
+//
+// The server waits for new changes when there are none, so the reply can be
+// used for long polling. An empty list is returned if no change is made
+// before the server gives up waiting.
+//
+// When the request has an "Accept: text/event-stream" header, the changes are
+// streamed as server-sent events instead. Each event has the "change" event
+// type, the sequence number as its ID and the JSON encoded Change as its
+// data. The stream is closed periodically. A client resumes it using the
+// Last-Event-ID header, which takes precedence over the "since" query
+// parameter.
+type ChangesReply struct {
+	Changes []Change `json:"changes"`
+}
+
+// SnapshotRoot contains the merkle root of the records of a state in an
+// inventory snapshot. The merkle root is calculated over the snapshot leaves
+// of the records. The snapshot leaf of a record is the SHA256 digest of the
+// hex encoded record token concatenated with the record merkle root. The
+// merkle root is empty if the state has no records.
+type SnapshotRoot struct {
+	State      RecordStateT `json:"state"`
+	Records    uint32       `json:"records"`
+	MerkleRoot string       `json:"merkleroot"`
+}
+
+// InventorySnapshot is a signed commitment to the tokens and the latest merkle
+// roots of all records in the inventory. Snapshots are taken periodically and
+// are appended onto a dedicated tlog tree that is anchored along with the
+// record trees. A record that is included in a snapshot can prove that the
+// server accepted it.
+//
+// The signature is the server signature of the sequence number and the
+// timestamp of the snapshot followed by the state, the record count, and the
+// merkle root of every snapshot root, in order.
+type InventorySnapshot struct {
+	Sequence  uint64         `json:"sequence"`
+	Timestamp int64          `json:"timestamp"` // Unix timestamp
+	Roots     []SnapshotRoot `json:"roots"`
+	PublicKey string         `json:"publickey"`
+	Signature string         `json:"signature"`
+}
+
+// SnapshotInclusion contains the proof that a record was included in an
+// inventory snapshot. The proof is an inclusion proof of the snapshot leaf of
+// the record in the merkle root of its state. The timestamp proves that the
+// snapshot was anchored. Its data is the JSON encoded snapshot.
+type SnapshotInclusion struct {
+	Snapshot  InventorySnapshot `json:"snapshot"`
+	Timestamp Timestamp         `json:"timestamp"`
+	Token     string            `json:"token"`
+	State     RecordStateT      `json:"state"`
+	Merkle    string            `json:"merkle"` // Record merkle root
+	Proof     Proof             `json:"proof"`
+}
+
+// Snapshot retrieves an inventory snapshot and its timestamp. A sequence
+// number of 0 retrieves the most recent snapshot.
+type Snapshot struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Sequence  uint64 `json:"sequence,omitempty"`
+}
+
+// SnapshotReply is the reply to the Snapshot command.
+type SnapshotReply struct {
+	Response  string            `json:"response"` // Challenge response
+	Snapshot  InventorySnapshot `json:"snapshot"`
+	Timestamp Timestamp         `json:"timestamp"`
+}
+
+// SnapshotProof retrieves the proof that a record was included in an
+// inventory snapshot. A sequence number of 0 uses the most recent snapshot.
+type SnapshotProof struct {
+	Challenge string `json:"challenge"` // Random challenge
+	Token     string `json:"token"`
+	Sequence  uint64 `json:"sequence,omitempty"`
+}
+
+// SnapshotProofReply is the reply to the SnapshotProof command.
+type SnapshotProofReply struct {
+	Response  string            `json:"response"` // Challenge response
+	Inclusion SnapshotInclusion `json:"inclusion"`
+}
+
+// AuditEntry is an entry of the audit log. An audit entry is appended for
+// every request to a write route that has been authorized, whether the write
+// succeeded or not. The audit log is appended onto a dedicated tlog tree and
+// is anchored along with the record trees.
+//
+// The credential is the ID of the API key of the caller. The HTTP basic auth
+// user name is used when API keys have not been configured. The digest is the
+// SHA256 digest of the request body. The result is "success" when the write
+// succeeded, otherwise it describes the error that was returned.
+type AuditEntry struct {
+	Sequence   uint64 `json:"sequence"`
+	Timestamp  int64  `json:"timestamp"` // Unix timestamp
+	Route      string `json:"route"`
+	Credential string `json:"credential"`
+	RemoteAddr string `json:"remoteaddr"`
+	Token      string `json:"token,omitempty"`
+	PluginID   string `json:"pluginid,omitempty"`
+	PluginCmd  string `json:"plugincmd,omitempty"`
+	Digest     string `json:"digest"`
+	Result     string `json:"result"`
+}
+
+// Audit queries the audit log. The filter fields that are empty are not
+// applied. Only the entries that have a sequence number greater than After
+// are returned, so the reply can be paginated using the sequence number of
+// the last returned entry. The start and end timestamps are inclusive Unix
+// timestamps.
+//
+// The timestamps of the returned entries are included in the reply when
+// Timestamps is set. The data of an entry timestamp is the JSON encoded entry.
+type Audit struct {
+	Challenge  string `json:"challenge"` // Random challenge
+	After      uint64 `json:"after,omitempty"`
+	Route      string `json:"route,omitempty"`
+	Credential string `json:"credential,omitempty"`
+	Token      string `json:"token,omitempty"`
+	PluginID   string `json:"pluginid,omitempty"`
+	Start      int64  `json:"start,omitempty"`
+	End        int64  `json:"end,omitempty"`
+	Timestamps bool   `json:"timestamps,omitempty"`
+}
+
+// AuditReply is the reply to the Audit command. It contains at most
+// AuditPageSize entries, ordered by sequence number. The timestamps are keyed
+// by sequence number.
+type AuditReply struct {
+	Response   string               `json:"response"` // Challenge response
+	Entries    []AuditEntry         `json:"entries"`
+	Timestamps map[uint64]Timestamp `json:"timestamps,omitempty"`
+}
+
+// IdentityTransition is a transition of the server identity from an old key
+// to a new key. The new key is valid from the transition timestamp onwards.
+// The old key is valid until the transition timestamp. Both keys sign the
+// transition so that the new key is vouched for by the old key and the old
+// key is claimed by the new key.
+//
+// The signatures are of the old public key, the new public key, and the
+// transition timestamp, concatenated in that order.
+type IdentityTransition struct {
+	OldPublicKey string `json:"oldpublickey"`
+	NewPublicKey string `json:"newpublickey"`
+	Timestamp    int64  `json:"timestamp"`    // Unix timestamp
+	OldSignature string `json:"oldsignature"` // Old key signature
+	NewSignature string `json:"newsignature"` // New key signature
+}
+
+// IdentityHistory requests the identity history of the server.
+type IdentityHistory struct {
+	Challenge string `json:"challenge"` // Random challenge
+}
+
+// IdentityHistoryReply is the reply to the IdentityHistory command. It
+// contains the current public key of the server and the transitions that
+// lead up to it, ordered from oldest to newest. The challenge response is
+// signed by the current key. The transitions are empty if the identity has
+// never been rotated.
+type IdentityHistoryReply struct {
+	Response    string               `json:"response"` // Challenge response
+	PublicKey   string               `json:"publickey"`
+	Transitions []IdentityTransition `json:"transitions"`
+
+	// TlogPublicKey is the hex encoded ed25519 public key that the tlog
+	// log roots are signed with. It is only set when politeiad uses
+	// the embedded tlog, since the trillian log roots are signed by
+	// the trillian log signer.
+	TlogPublicKey string `json:"tlogpublickey,omitempty"`
+}
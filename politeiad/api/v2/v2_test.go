@@ -23,4 +23,8 @@ func TestMaps(t *testing.T) {
 	if err != nil {
 		t.Fatalf("RecordStatuses: %v", err)
 	}
+	err = unittest.TestGenericConstMap(Changes, uint64(ChangeLast))
+	if err != nil {
+		t.Fatalf("Changes: %v", err)
+	}
 }
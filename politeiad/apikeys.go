@@ -0,0 +1,173 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+const (
+	// apiKeyWildcard can be used in the routes, plugins, and plugin
+	// commands of an API key to allow all of them.
+	apiKeyWildcard = "*"
+
+	// apiKeySecretMinLen is the minimum length of an API key secret.
+	apiKeySecretMinLen = 16
+)
+
+// apiKey is a named credential that is allowed to access a subset of the
+// politeiad routes. Clients authenticate using HTTP basic auth with the key ID
+// as the user name and the key secret as the password.
+//
+// Routes contains the full request paths that the key is allowed to access,
+// e.g. "/v2/pluginreads". Plugins contains the plugin commands that the key is
+// allowed to execute, by plugin ID. A nil Plugins map allows all plugin
+// commands. The wildcard can be used as a route, a plugin ID, or a command.
+type apiKey struct {
+	ID      string              `json:"id"`
+	Secret  string              `json:"secret"`
+	Routes  []string            `json:"routes"`
+	Plugins map[string][]string `json:"plugins,omitempty"`
+}
+
+// routeAllowed returns whether the key is allowed to access the route.
+func (k *apiKey) routeAllowed(route string) bool {
+	for _, v := range k.Routes {
+		if v == apiKeyWildcard || v == route {
+			return true
+		}
+	}
+	return false
+}
+
+// pluginAllowed returns whether the key is allowed to execute the plugin
+// command.
+func (k *apiKey) pluginAllowed(pluginID, cmd string) bool {
+	if k.Plugins == nil {
+		return true
+	}
+	for _, id := range []string{pluginID, apiKeyWildcard} {
+		for _, v := range k.Plugins[id] {
+			if v == apiKeyWildcard || v == cmd {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// apiKeysFile is the JSON structure of the API keys file.
+type apiKeysFile struct {
+	Keys []apiKey `json:"keys"`
+}
+
+// loadAPIKeys loads and verifies the API keys file.
+func loadAPIKeys(fp string) (map[string]apiKey, error) {
+	b, err := os.ReadFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	var f apiKeysFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %v: %v", fp, err)
+	}
+	keys := make(map[string]apiKey, len(f.Keys))
+	for _, v := range f.Keys {
+		switch {
+		case v.ID == "":
+			return nil, fmt.Errorf("api key id missing")
+		case len(v.Secret) < apiKeySecretMinLen:
+			return nil, fmt.Errorf("api key %v: secret must be at least "+
+				"%v characters", v.ID, apiKeySecretMinLen)
+		case len(v.Routes) == 0:
+			return nil, fmt.Errorf("api key %v: no routes", v.ID)
+		}
+		if _, ok := keys[v.ID]; ok {
+			return nil, fmt.Errorf("duplicate api key %v", v.ID)
+		}
+		keys[v.ID] = v
+	}
+	return keys, nil
+}
+
+// apiKeys contains the API keys that are allowed to access politeiad. The keys
+// are reloaded from the API keys file on demand so that keys can be added,
+// rotated, and revoked without restarting politeiad.
+type apiKeys struct {
+	sync.RWMutex
+	path string
+	keys map[string]apiKey // [id]apiKey
+}
+
+// newAPIKeys returns a new apiKeys that is loaded from the provided file.
+func newAPIKeys(fp string) (*apiKeys, error) {
+	a := &apiKeys{
+		path: fp,
+	}
+	err := a.reload()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// reload reloads the API keys from the API keys file. The existing keys are
+// kept if the file is not valid.
+func (a *apiKeys) reload() error {
+	keys, err := loadAPIKeys(a.path)
+	if err != nil {
+		return err
+	}
+
+	a.Lock()
+	a.keys = keys
+	a.Unlock()
+
+	log.Infof("API keys loaded: %v", len(keys))
+
+	return nil
+}
+
+// authenticate returns the API key with the provided ID if the secret is
+// correct.
+func (a *apiKeys) authenticate(id, secret string) (*apiKey, bool) {
+	a.RLock()
+	k, ok := a.keys[id]
+	a.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(k.Secret), []byte(secret)) != 1 {
+		return nil, false
+	}
+	return &k, true
+}
+
+// apiKeyContextKey is the request context key of the authenticated API key.
+type apiKeyContextKey struct{}
+
+// apiKeyFromContext returns the authenticated API key of a request. A nil key
+// is returned if the request was not authenticated using an API key.
+func apiKeyFromContext(ctx context.Context) *apiKey {
+	k, _ := ctx.Value(apiKeyContextKey{}).(*apiKey)
+	return k
+}
+
+// pluginAllowed returns whether the API key of the request is allowed to
+// execute the plugin command. Requests that were not authenticated using an
+// API key are not restricted.
+func pluginAllowed(ctx context.Context, pluginID, cmd string) bool {
+	k := apiKeyFromContext(ctx)
+	if k == nil {
+		return true
+	}
+	return k.pluginAllowed(pluginID, cmd)
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	"github.com/decred/slog"
+	"github.com/gorilla/mux"
+)
+
+const testAPIKeys = `{
+  "keys": [
+    {
+      "id": "admin",
+      "secret": "adminsecret123456",
+      "routes": ["*"]
+    },
+    {
+      "id": "voter",
+      "secret": "votersecret123456",
+      "routes": ["/v2/pluginwrite"],
+      "plugins": {
+        "ticketvote": ["castballot"]
+      }
+    }
+  ]
+}`
+
+func TestAPIKeys(t *testing.T) {
+	// The log rotator is not initialized during tests
+	log = slog.Disabled
+
+	// Write the API keys file
+	fp := filepath.Join(t.TempDir(), "apikeys.json")
+	err := os.WriteFile(fp, []byte(testAPIKeys), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := newAPIKeys(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup a test router. The handler reports whether the API key of
+	// the request is allowed to cast a ballot.
+	p := &politeia{
+		router:  mux.NewRouter(),
+		apiKeys: a,
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if !pluginAllowed(r.Context(), "ticketvote", "castballot") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	p.addRouteV2(http.MethodPost, v2.RoutePluginWrite, handler, permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteRecordNew, handler, permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteSnapshot, handler, permissionPublic)
+
+	var tests = []struct {
+		name     string
+		route    string
+		id       string
+		secret   string
+		wantCode int
+	}{
+		{
+			"no credentials",
+			v2.RouteRecordNew,
+			"",
+			"",
+			http.StatusUnauthorized,
+		},
+		{
+			"wrong secret",
+			v2.RouteRecordNew,
+			"admin",
+			"votersecret123456",
+			http.StatusUnauthorized,
+		},
+		{
+			"wildcard route",
+			v2.RouteRecordNew,
+			"admin",
+			"adminsecret123456",
+			http.StatusOK,
+		},
+		{
+			"route not allowed",
+			v2.RouteRecordNew,
+			"voter",
+			"votersecret123456",
+			http.StatusForbidden,
+		},
+		{
+			"route and plugin allowed",
+			v2.RoutePluginWrite,
+			"voter",
+			"votersecret123456",
+			http.StatusOK,
+		},
+		{
+			"public route",
+			v2.RouteSnapshot,
+			"",
+			"",
+			http.StatusOK,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost,
+				v2.APIRoute+tc.route, nil)
+			if tc.id != "" {
+				r.SetBasicAuth(tc.id, tc.secret)
+			}
+			w := httptest.NewRecorder()
+			p.router.ServeHTTP(w, r)
+			if w.Code != tc.wantCode {
+				t.Errorf("got code %v, want %v", w.Code, tc.wantCode)
+			}
+		})
+	}
+
+	// Rotate the voter key. The old secret is no longer accepted once
+	// the keys have been reloaded.
+	if _, ok := a.authenticate("voter", "votersecret123456"); !ok {
+		t.Fatalf("voter key not authenticated")
+	}
+	rotated := []byte(`{"keys":[{"id":"voter","secret":"rotatedsecret1234",` +
+		`"routes":["/v2/pluginwrite"],"plugins":{"*":["castballot"]}}]}`)
+	err = os.WriteFile(fp, rotated, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.authenticate("voter", "votersecret123456"); ok {
+		t.Fatalf("old voter secret authenticated")
+	}
+	k, ok := a.authenticate("voter", "rotatedsecret1234")
+	if !ok {
+		t.Fatalf("rotated voter secret not authenticated")
+	}
+	if !k.pluginAllowed("comments", "castballot") ||
+		k.pluginAllowed("comments", "new") {
+		t.Fatalf("wildcard plugin not applied")
+	}
+	if _, ok := a.authenticate("admin", "adminsecret123456"); ok {
+		t.Fatalf("removed admin key authenticated")
+	}
+
+	// An invalid file does not replace the existing keys
+	err = os.WriteFile(fp, []byte(`{"keys":[{"id":"voter","secret":"x",`+
+		`"routes":["*"]}]}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = a.reload()
+	if err == nil {
+		t.Fatalf("short secret accepted")
+	}
+	if _, ok := a.authenticate("voter", "rotatedsecret1234"); !ok {
+		t.Fatalf("keys replaced by an invalid file")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// auditResultSuccess is the result of a write that succeeded.
+	auditResultSuccess = "success"
+
+	// auditReplyMaxSize is the maximum number of bytes of an error reply
+	// that are kept in order to describe the result of a failed write.
+	auditReplyMaxSize = 4096
+)
+
+// auditInfo contains the audit details of a write that are only known to the
+// request handler. It is added to the request context by the audit handler.
+type auditInfo struct {
+	token string
+}
+
+// auditContextKey is the request context key of the audit info.
+type auditContextKey struct{}
+
+// auditSetToken sets the token of the record that a write has been made to.
+// It must be called by the handlers of the writes that create a record, since
+// the token is not part of the request payload.
+func auditSetToken(ctx context.Context, token string) {
+	ai, ok := ctx.Value(auditContextKey{}).(*auditInfo)
+	if !ok {
+		return
+	}
+	ai.token = token
+}
+
+// auditPayload contains the request payload fields that are used to populate
+// an audit entry.
+type auditPayload struct {
+	Token string `json:"token"`
+	Cmd   struct {
+		Token   string `json:"token"`
+		ID      string `json:"id"`
+		Command string `json:"command"`
+	} `json:"cmd"`
+}
+
+// auditReply contains the error reply fields that are used to describe the
+// result of a failed write.
+type auditReply struct {
+	PluginID     string `json:"pluginid"`
+	ErrorCode    int64  `json:"errorcode"`
+	ErrorContext string `json:"errorcontext"`
+}
+
+// auditResponseWriter wraps a http ResponseWriter in order to record the
+// status code of the reply and the body of an error reply.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	reply  bytes.Buffer
+}
+
+// WriteHeader records the status code before writing it.
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body of an error reply before writing it.
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.status != http.StatusOK {
+		n := auditReplyMaxSize - w.reply.Len()
+		if n > len(b) {
+			n = len(b)
+		}
+		if n > 0 {
+			w.reply.Write(b[:n])
+		}
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// result returns the audit result of the reply.
+func (w *auditResponseWriter) result() string {
+	if w.status == http.StatusOK {
+		return auditResultSuccess
+	}
+	var ar auditReply
+	err := json.Unmarshal(w.reply.Bytes(), &ar)
+	if err != nil {
+		return fmt.Sprintf("http %v", w.status)
+	}
+	switch {
+	case w.status == http.StatusBadRequest && ar.PluginID != "":
+		return fmt.Sprintf("plugin error: %v %v", ar.PluginID, ar.ErrorCode)
+	case w.status == http.StatusBadRequest:
+		m := fmt.Sprintf("user error: %v %v", ar.ErrorCode,
+			v2.ErrorCodes[v2.ErrorCodeT(ar.ErrorCode)])
+		if ar.ErrorContext != "" {
+			m += ": " + ar.ErrorContext
+		}
+		return m
+	case w.status == http.StatusInternalServerError:
+		return fmt.Sprintf("server error: %v", ar.ErrorCode)
+	}
+	return fmt.Sprintf("http %v", w.status)
+}
+
+// auditCredential returns the credential that a request was made with.
+func auditCredential(r *http.Request) string {
+	if k := apiKeyFromContext(r.Context()); k != nil {
+		return k.ID
+	}
+	user, _, _ := r.BasicAuth()
+	return user
+}
+
+// audit wraps the handler of a write route in order to append an entry onto
+// the audit log for every request that it handles. The write has already been
+// made when the entry is appended, so a failure to append the entry is logged
+// instead of being returned to the client.
+func (p *politeia) audit(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Read the request body so that its digest can be calculated
+		// and its token can be extracted.
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			respondWithErrorV2(w, r, "audit: read body",
+				v2.UserErrorReply{
+					ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+				})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Execute the handler
+		ai := &auditInfo{}
+		aw := &auditResponseWriter{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+		fn(aw, r.WithContext(context.WithValue(r.Context(),
+			auditContextKey{}, ai)))
+
+		// Append the audit entry
+		var ap auditPayload
+		_ = json.Unmarshal(body, &ap)
+		token := ap.Token
+		if token == "" {
+			token = ap.Cmd.Token
+		}
+		if ai.token != "" {
+			token = ai.token
+		}
+		e := backendv2.AuditEntry{
+			Route:      r.URL.Path,
+			Credential: auditCredential(r),
+			RemoteAddr: util.RemoteAddr(r),
+			Token:      token,
+			PluginID:   ap.Cmd.ID,
+			PluginCmd:  ap.Cmd.Command,
+			Digest:     hex.EncodeToString(util.Digest(body)),
+			Result:     aw.result(),
+		}
+		err = p.backendv2.AuditAppend(e)
+		if err != nil {
+			log.Errorf("Audit %v %v %v: %v", e.Route, e.Credential,
+				e.Token, err)
+		}
+	}
+}
+
+func convertAuditEntryToV2(e backendv2.AuditEntry) v2.AuditEntry {
+	return v2.AuditEntry{
+		Sequence:   e.Sequence,
+		Timestamp:  e.Timestamp,
+		Route:      e.Route,
+		Credential: e.Credential,
+		RemoteAddr: e.RemoteAddr,
+		Token:      e.Token,
+		PluginID:   e.PluginID,
+		PluginCmd:  e.PluginCmd,
+		Digest:     e.Digest,
+		Result:     e.Result,
+	}
+}
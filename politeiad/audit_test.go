@@ -0,0 +1,87 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	"github.com/decred/politeia/util"
+	"github.com/decred/slog"
+)
+
+func TestAuditResult(t *testing.T) {
+	// The log rotator is not initialized during tests
+	log = slog.Disabled
+
+	var tests = []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			"success",
+			func(w http.ResponseWriter, r *http.Request) {
+				util.RespondWithJSON(w, http.StatusOK, v2.RecordNewReply{})
+			},
+			auditResultSuccess,
+		},
+		{
+			"user error",
+			func(w http.ResponseWriter, r *http.Request) {
+				respondWithErrorV2(w, r, "",
+					v2.UserErrorReply{
+						ErrorCode:    v2.ErrorCodeTokenInvalid,
+						ErrorContext: "context",
+					})
+			},
+			"user error: 12 token invalid: context",
+		},
+		{
+			"plugin error",
+			func(w http.ResponseWriter, r *http.Request) {
+				util.RespondWithJSON(w, http.StatusBadRequest,
+					v2.PluginErrorReply{
+						PluginID:  "ticketvote",
+						ErrorCode: 3,
+					})
+			},
+			"plugin error: ticketvote 3",
+		},
+		{
+			"server error",
+			func(w http.ResponseWriter, r *http.Request) {
+				util.RespondWithJSON(w, http.StatusInternalServerError,
+					v2.ServerErrorReply{
+						ErrorCode: 1234,
+					})
+			},
+			"server error: 1234",
+		},
+		{
+			"other error",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			},
+			"http 403",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			aw := &auditResponseWriter{
+				ResponseWriter: httptest.NewRecorder(),
+				status:         http.StatusOK,
+			}
+			r := httptest.NewRequest(http.MethodPost, "/", nil)
+			tc.handler(aw, r)
+			got := aw.result()
+			if got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -41,6 +41,30 @@ var (
 	// data relies on the hash of the payload, therefore duplicate payloads
 	// are not allowed since they will cause collisions.
 	ErrDuplicatePayload = errors.New("duplicate payload")
+
+	// ErrAnchorInProgress is returned when an anchor drop is requested
+	// while a previous anchor is still being dropped.
+	ErrAnchorInProgress = errors.New("anchor in progress")
+
+	// ErrAnchoringDisabled is returned when an anchor drop is requested
+	// but no anchor providers have been configured.
+	ErrAnchoringDisabled = errors.New("anchoring disabled")
+
+	// ErrTxOpInvalid is returned when a transaction contains an invalid
+	// operation type.
+	ErrTxOpInvalid = errors.New("transaction op invalid")
+
+	// ErrSnapshotNotFound is returned when an inventory snapshot does
+	// not exist.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+
+	// ErrInventoryCursorInvalid is returned when an inventory page is
+	// requested using a cursor that was not returned by the inventory.
+	ErrInventoryCursorInvalid = errors.New("inventory cursor invalid")
+
+	// ErrTxInProgress is returned when a plugin write is made while a
+	// transaction is executing a plugin command.
+	ErrTxInProgress = errors.New("transaction in progress")
 )
 
 // StateT represents the state of a record.
@@ -56,8 +80,14 @@ const (
 	// StateVetted indicates a record has been made public.
 	StateVetted StateT = 2
 
+	// StateDraft indicates a record is a draft that has not been
+	// submitted yet. Drafts are saved encrypted and are never
+	// timestamped. A draft becomes an unvetted record once it is
+	// promoted.
+	StateDraft StateT = 3
+
 	// StateLast used for unit test only.
-	StateLast StateT = 3
+	StateLast StateT = 4
 )
 
 var (
@@ -66,6 +96,7 @@ var (
 		StateInvalid:  "invalid",
 		StateUnvetted: "unvetted",
 		StateVetted:   "vetted",
+		StateDraft:    "draft",
 	}
 )
 
@@ -154,12 +185,35 @@ type File struct {
 	Payload string `json:"payload"` // Base64 encoded file payload
 }
 
+// CensorFile identifies a record file that is to be censored.
+type CensorFile struct {
+	Version uint32 `json:"version"` // Record version
+	Name    string `json:"name"`    // Filename
+}
+
+// CensoredFile describes a record file whose payload has been permanently
+// deleted from the backend. The file digest is retained so that the remaining
+// record files can still be verified against the record merkle root.
+type CensoredFile struct {
+	Version   uint32 `json:"version"`   // Record version
+	Name      string `json:"name"`      // Filename
+	MIME      string `json:"mime"`      // MIME type
+	Digest    string `json:"digest"`    // SHA256 of the censored payload
+	Reason    string `json:"reason"`    // Reason for censorship
+	Timestamp int64  `json:"timestamp"` // Unix timestamp of censorship
+	Signature string `json:"signature"` // Server signature of CensoredFileMsg
+}
+
 // Record is a permanent record that includes the submitted files, metadata and
 // internal metadata.
+//
+// CensoredFiles contains the files of this record version that have been
+// censored. These files are not included in the Files field.
 type Record struct {
 	RecordMetadata RecordMetadata   `json:"recordmetadata"`
 	Metadata       []MetadataStream `json:"metadata"`
 	Files          []File           `json:"files"`
+	CensoredFiles  []CensoredFile   `json:"censoredfiles,omitempty"`
 }
 
 // ContentErrorCodeT represents a record content error.
@@ -223,6 +277,20 @@ type Proof struct {
 	ExtraData  string // JSON encoded
 }
 
+// TimestampAnchor contains the proof that a log merkle root was anchored by an
+// anchor provider. The proof digest is the log merkle root.
+//
+// The TxID is the DCR transaction ID for dcrtime anchors. The other anchor
+// providers use it for their own reference of the anchor, i.e. the digest of
+// the time-stamp token for RFC 3161 anchors and the bitcoin block height for
+// OpenTimestamps anchors.
+type TimestampAnchor struct {
+	Provider   string // Anchor provider ID
+	TxID       string
+	MerkleRoot string
+	Proof      Proof
+}
+
 // Timestamp contains all of the data required to verify that a piece of record
 // content was timestamped onto the decred blockchain.
 //
@@ -232,12 +300,17 @@ type Proof struct {
 // TxID, MerkleRoot, and Proofs will only be populated once the merkle root has
 // been included in a DCR tx and the tx has 6 confirmations. The Data field
 // will not be populated if the data has been censored.
+//
+// The log merkle root can be anchored by multiple anchor providers. TxID,
+// MerkleRoot, and the last proof correspond to the first anchor. Anchors
+// contains the anchors of all providers, including the first.
 type Timestamp struct {
 	Data       string // JSON encoded
 	Digest     string
 	TxID       string
 	MerkleRoot string
 	Proofs     []Proof
+	Anchors    []TimestampAnchor
 }
 
 // RecordTimestamps contains a Timestamp for all record data.
@@ -248,12 +321,114 @@ type RecordTimestamps struct {
 	Files    map[string]Timestamp            // map[filename]Timestamp
 }
 
+// DiffActionT represents the type of change that was made to a piece of record
+// content between two record versions.
+type DiffActionT uint32
+
+const (
+	// DiffActionInvalid is an invalid diff action.
+	DiffActionInvalid DiffActionT = 0
+
+	// DiffActionAdd indicates that the content was added.
+	DiffActionAdd DiffActionT = 1
+
+	// DiffActionDel indicates that the content was removed.
+	DiffActionDel DiffActionT = 2
+
+	// DiffActionModify indicates that the content was modified.
+	DiffActionModify DiffActionT = 3
+)
+
+var (
+	// DiffActions contains the human readable diff actions.
+	DiffActions = map[DiffActionT]string{
+		DiffActionInvalid: "invalid",
+		DiffActionAdd:     "add",
+		DiffActionDel:     "del",
+		DiffActionModify:  "modify",
+	}
+)
+
+// RecordMetadataDiff describes a change to a single RecordMetadata field. The
+// values are formatted as strings.
+type RecordMetadataDiff struct {
+	Field string
+	From  string
+	To    string
+}
+
+// MetadataStreamDiff describes a change to a metadata stream. The from payload
+// is empty for added streams and the to payload is empty for removed streams.
+type MetadataStreamDiff struct {
+	Action      DiffActionT
+	PluginID    string
+	StreamID    uint32
+	FromPayload string
+	ToPayload   string
+}
+
+// FileDiff describes a change to a record file. The from fields are empty for
+// added files and the to fields are empty for removed files.
+//
+// LineDiff contains a unified line diff of the file payloads. It is only
+// populated for text files whose payloads have not been censored.
+type FileDiff struct {
+	Action     DiffActionT
+	Name       string
+	FromMIME   string
+	ToMIME     string
+	FromDigest string
+	ToDigest   string
+	Censored   bool // Payload of either version has been censored
+	LineDiff   string
+}
+
+// RecordDiff contains the changes that were made to a record between two
+// record versions. Content that did not change is not included.
+type RecordDiff struct {
+	FromVersion    uint32
+	ToVersion      uint32
+	RecordMetadata []RecordMetadataDiff
+	Metadata       []MetadataStreamDiff
+	Files          []FileDiff
+}
+
+// RecordHistoryEntry describes a single iteration of a record. The Merkle
+// field of the record metadata is the merkle root of the record files of the
+// iteration.
+//
+// Metadata contains the metadata streams that were added or modified by the
+// iteration. This includes the status change metadata that is appended to a
+// record when its status is updated.
+//
+// Anchored is true once the iteration has been anchored. AnchorTxID is the
+// TxID of the first anchor, i.e. the decred transaction that contains the
+// anchor for dcrtime anchors.
+type RecordHistoryEntry struct {
+	RecordMetadata RecordMetadata
+	Metadata       []MetadataStream
+	Anchored       bool
+	AnchorTxID     string
+}
+
 // Inventory contains the tokens of records in the inventory categorized by
 // record state and record status. Tokens are sorted by the timestamp of the
 // status change from newest to oldest.
 type Inventory struct {
 	Unvetted map[StatusT][]string
 	Vetted   map[StatusT][]string
+
+	// Cursor is the cursor of the page that follows the returned page.
+	// It is only set when a page of a single status was requested and
+	// the page is full.
+	Cursor string
+}
+
+// InventoryCounts contains the number of records in the inventory categorized
+// by record state and record status.
+type InventoryCounts struct {
+	Unvetted map[StatusT]int
+	Vetted   map[StatusT]int
 }
 
 // PluginSetting represents a configurable plugin setting.
@@ -290,6 +465,377 @@ func (e PluginError) Error() string {
 		e.PluginID, e.ErrorCode)
 }
 
+// FsckIssueT represents a type of problem that was found during a filesystem
+// check.
+type FsckIssueT string
+
+const (
+	// FsckIssueBlobMissing is used when a blob that is referenced by a
+	// record is not found in the key-value store. Missing blobs can not
+	// be repaired.
+	FsckIssueBlobMissing FsckIssueT = "blobmissing"
+
+	// FsckIssueCacheStale is used when a cache entry does not match the
+	// data that it was built from.
+	FsckIssueCacheStale FsckIssueT = "cachestale"
+
+	// FsckIssueInventoryDrift is used when the record inventory does not
+	// match the state and status of the records in the backend.
+	FsckIssueInventoryDrift FsckIssueT = "inventorydrift"
+
+	// FsckIssueUserCacheGap is used when a record is missing from the
+	// cache of the records submitted by a user.
+	FsckIssueUserCacheGap FsckIssueT = "usercachegap"
+
+	// FsckIssueBlobOrphaned is used when a blob, or the reference of a
+	// record to a content addressed blob, is not referenced by the leaves
+	// of any tree. These are left behind when politeiad stops in the
+	// middle of a write, such as a transaction whose journal is lost.
+	FsckIssueBlobOrphaned FsckIssueT = "bloborphaned"
+)
+
+// FsckIssue describes a problem that was found during a filesystem check.
+type FsckIssue struct {
+	Type        FsckIssueT
+	PluginID    string // Empty for backend issues
+	Token       string // Empty if the issue is not specific to a record
+	Description string
+
+	// Repaired is set to true if the problem was fixed. Problems are
+	// never repaired during a dry run.
+	Repaired bool
+}
+
+// FsckOpts contains the options for a filesystem check.
+type FsckOpts struct {
+	// DryRun can be used to report problems without repairing them.
+	DryRun bool
+
+	// Progress is invoked as the filesystem check moves through its
+	// stages and records. It is optional.
+	Progress func(stage string, done, total int)
+}
+
+// FsckReport contains the results of a filesystem check.
+type FsckReport struct {
+	DryRun  bool
+	Records int // Number of records that were checked
+	Issues  []FsckIssue
+}
+
+// ReencryptOpts contains the options for a re-encryption of the backend
+// data.
+type ReencryptOpts struct {
+	// Progress is invoked after every batch of entries that has been
+	// inspected. It is optional.
+	Progress func(done, total int)
+}
+
+// ReencryptReport contains the results of a re-encryption.
+type ReencryptReport struct {
+	KeyVersion  uint32 // Encryption key version that blobs now use
+	Resumed     bool   // Whether an interrupted re-encryption was resumed
+	Inspected   int    // Number of entries that were inspected
+	Reencrypted int    // Number of blobs that were re-encrypted
+	Started     int64  // Unix timestamp of when the re-encryption started
+}
+
+// ReencodeOpts contains the options for a re-encoding of the backend blobs.
+type ReencodeOpts struct {
+	// Progress is invoked after every record that has been re-encoded.
+	// It is optional.
+	Progress func(done, total int)
+}
+
+// ReencodeReport contains the results of a re-encoding of the backend blobs.
+type ReencodeReport struct {
+	Version   uint8 // Blob encoding version that blobs now use
+	Resumed   bool  // Whether an interrupted re-encoding was resumed
+	Records   int   // Number of records that were re-encoded
+	Inspected int   // Number of blobs that were inspected
+	Reencoded int   // Number of blobs that were re-encoded
+	Started   int64 // Unix timestamp of when the re-encoding started
+}
+
+// AnchorTree describes a record that has leaves that have not been included
+// in an anchor yet.
+type AnchorTree struct {
+	Token      string
+	Leaves     uint64 // Number of leaves that have not been anchored
+	Lag        int64  // Seconds since the oldest unanchored leaf was added
+	LastAnchor int64  // Unix timestamp of the latest anchor, 0 if none
+	InFlight   bool   // Whether the record is part of the in-flight anchor
+}
+
+// AnchorInFlight describes an anchor that has been submitted to the anchor
+// providers and is waiting to be dropped. Pending contains the digests that
+// each provider has not anchored yet.
+type AnchorInFlight struct {
+	Started int64               // Unix timestamp
+	Tokens  []string            // Records that are being anchored
+	Pending map[string][]string // [providerID]digests
+}
+
+// AnchorFailure describes an error that occurred while dropping an anchor.
+type AnchorFailure struct {
+	Provider  string // Empty if not specific to an anchor provider
+	Token     string // Empty if not specific to a record
+	Error     string
+	Timestamp int64 // Unix timestamp
+}
+
+// AnchorStatus describes the state of the anchoring of the backend records.
+//
+// LastAnchor is the Unix timestamp of the most recent anchor of any record.
+// Leaves and Lag are the total number of unanchored leaves and the largest
+// lag of all records.
+type AnchorStatus struct {
+	Providers  []string
+	Dropping   bool            // Whether an anchor is being dropped
+	InFlight   *AnchorInFlight // Nil if no anchor is waiting to drop
+	LastAnchor int64
+	Records    int          // Number of records with leaves
+	Unanchored []AnchorTree // Records with unanchored leaves
+	Leaves     uint64
+	Lag        int64
+	Failures   []AnchorFailure // Most recent first
+}
+
+// TxOpT represents the type of a transaction operation.
+type TxOpT uint32
+
+const (
+	// TxOpInvalid is an invalid transaction operation.
+	TxOpInvalid TxOpT = 0
+
+	// TxOpRecordEdit edits the record. See Backend.RecordEdit.
+	TxOpRecordEdit TxOpT = 1
+
+	// TxOpRecordEditMetadata edits the record metadata. See
+	// Backend.RecordEditMetadata.
+	TxOpRecordEditMetadata TxOpT = 2
+
+	// TxOpRecordSetStatus sets the record status. See
+	// Backend.RecordSetStatus.
+	TxOpRecordSetStatus TxOpT = 3
+
+	// TxOpPluginWrite executes a plugin write command. See
+	// Backend.PluginWrite.
+	TxOpPluginWrite TxOpT = 4
+)
+
+var (
+	// TxOps contains the human readable transaction operation types.
+	TxOps = map[TxOpT]string{
+		TxOpInvalid:            "invalid",
+		TxOpRecordEdit:         "record edit",
+		TxOpRecordEditMetadata: "record edit metadata",
+		TxOpRecordSetStatus:    "record set status",
+		TxOpPluginWrite:        "plugin write",
+	}
+)
+
+// TxOp is a single write operation of a transaction. The fields that are used
+// depend on the operation type. The metadata and file fields are used by the
+// record writes. Status is only used by TxOpRecordSetStatus. The plugin
+// fields are only used by TxOpPluginWrite.
+type TxOp struct {
+	Type TxOpT
+
+	// Record write fields
+	MDAppend    []MetadataStream
+	MDOverwrite []MetadataStream
+	FilesAdd    []File
+	FilesDel    []string
+	Status      StatusT
+
+	// Plugin write fields
+	PluginID  string
+	PluginCmd string
+	Payload   string
+}
+
+// TxReply is the reply to a transaction. Record is the record once all
+// operations have been committed. Payloads contains the reply payload of each
+// operation. The payload is empty for record writes.
+type TxReply struct {
+	Record   Record
+	Payloads []string
+}
+
+// TxError is returned when an operation of a transaction fails. None of the
+// operations of the transaction are committed. Err is the error of the
+// operation.
+type TxError struct {
+	Index int // Index of the failed operation
+	Err   error
+}
+
+// Error satisfies the error interface.
+func (e TxError) Error() string {
+	return fmt.Sprintf("transaction op %v: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the error of the failed operation.
+func (e TxError) Unwrap() error {
+	return e.Err
+}
+
+// TxPartialError is returned when the record changes of a transaction were
+// only partially committed, or when it can not be determined whether they
+// were committed. The changes are not rolled back since the committed ones may
+// depend on them. The record should be checked by fsck.
+type TxPartialError struct {
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e TxPartialError) Error() string {
+	return fmt.Sprintf("transaction partially committed: %v", e.Err)
+}
+
+// Unwrap returns the error that interrupted the commit.
+func (e TxPartialError) Unwrap() error {
+	return e.Err
+}
+
+// ChangeT represents the type of a backend change.
+type ChangeT uint32
+
+const (
+	// ChangeInvalid is an invalid change type.
+	ChangeInvalid ChangeT = 0
+
+	// ChangeRecordNew indicates a new record was created.
+	ChangeRecordNew ChangeT = 1
+
+	// ChangeRecordEdit indicates a record was edited.
+	ChangeRecordEdit ChangeT = 2
+
+	// ChangeRecordEditMetadata indicates the metadata of a record was
+	// edited.
+	ChangeRecordEditMetadata ChangeT = 3
+
+	// ChangeRecordSetStatus indicates the status of a record was
+	// updated.
+	ChangeRecordSetStatus ChangeT = 4
+
+	// ChangeRecordCensorFiles indicates individual files of a record
+	// were censored.
+	ChangeRecordCensorFiles ChangeT = 5
+
+	// ChangePluginWrite indicates a plugin write command was executed
+	// on a record.
+	ChangePluginWrite ChangeT = 6
+
+	// ChangeLast is used for unit test validation of human readable
+	// change types.
+	ChangeLast ChangeT = 7
+)
+
+var (
+	// Changes contains the human readable change types.
+	Changes = map[ChangeT]string{
+		ChangeInvalid:            "invalid",
+		ChangeRecordNew:          "record new",
+		ChangeRecordEdit:         "record edit",
+		ChangeRecordEditMetadata: "record edit metadata",
+		ChangeRecordSetStatus:    "record set status",
+		ChangeRecordCensorFiles:  "record censor files",
+		ChangePluginWrite:        "plugin write",
+	}
+)
+
+// Change is an entry of the backend change log. Every write that is made to a
+// record is appended onto the change log once it has been committed. Sequence
+// numbers start at 1 and are incremented by 1 for every change.
+//
+// The state, status, version, and iteration are the values of the record once
+// the change was made. They are not set for plugin writes. The plugin fields
+// are only set for plugin writes.
+type Change struct {
+	Sequence  uint64  `json:"sequence"`
+	Type      ChangeT `json:"type"`
+	Token     string  `json:"token"`
+	State     StateT  `json:"state,omitempty"`
+	Status    StatusT `json:"status,omitempty"`
+	Version   uint32  `json:"version,omitempty"`
+	Iteration uint32  `json:"iteration,omitempty"`
+	PluginID  string  `json:"pluginid,omitempty"`
+	PluginCmd string  `json:"plugincmd,omitempty"`
+	Timestamp int64   `json:"timestamp"` // Unix timestamp
+}
+
+// SnapshotRoot contains the merkle root of the records of a state in an
+// inventory snapshot. The merkle root is calculated over the snapshot leaves of
+// the records, see SnapshotLeaf. It is empty if the state has no records.
+type SnapshotRoot struct {
+	State      StateT `json:"state"`
+	Records    uint32 `json:"records"`
+	MerkleRoot string `json:"merkleroot"`
+}
+
+// InventorySnapshot is a signed commitment to the tokens and the latest
+// merkle roots of all records in the inventory. Snapshots are taken
+// periodically and are appended onto a dedicated tlog tree that is anchored
+// along with the record trees. A record that is included in a snapshot can
+// prove that the server accepted it, even if the record is later dropped
+// without a trace.
+//
+// The signature is the server signature of the snapshot message, see
+// SnapshotMsg. Sequence numbers start at 1 and are incremented by 1 for every
+// snapshot.
+type InventorySnapshot struct {
+	Sequence  uint64         `json:"sequence"`
+	Timestamp int64          `json:"timestamp"` // Unix timestamp
+	Roots     []SnapshotRoot `json:"roots"`
+	PublicKey string         `json:"publickey"`
+	Signature string         `json:"signature"`
+}
+
+// SnapshotInclusion contains the proof that a record was included in an
+// inventory snapshot. The proof is an inclusion proof of the snapshot leaf of
+// the record in the merkle root of its state. The timestamp proves that the
+// snapshot was anchored.
+type SnapshotInclusion struct {
+	Snapshot  InventorySnapshot
+	Timestamp Timestamp
+	Token     string
+	State     StateT
+	Merkle    string // Record merkle root at the time of the snapshot
+	Proof     Proof
+}
+
+// AuditEntry is an entry of the audit log. An audit entry is appended for
+// every write operation that is requested by a client, whether it succeeded
+// or not. The audit log is appended onto a dedicated tlog tree and is
+// anchored along with the record trees.
+type AuditEntry struct {
+	Sequence   uint64 `json:"sequence"`   // Set by the backend
+	Timestamp  int64  `json:"timestamp"`  // Set by the backend
+	Route      string `json:"route"`      // Request route
+	Credential string `json:"credential"` // Credential of the caller
+	RemoteAddr string `json:"remoteaddr"` // Remote address of the caller
+	Token      string `json:"token,omitempty"`
+	PluginID   string `json:"pluginid,omitempty"`
+	PluginCmd  string `json:"plugincmd,omitempty"`
+	Digest     string `json:"digest"` // SHA256 digest of the request payload
+	Result     string `json:"result"` // Result of the operation
+}
+
+// AuditFilter contains the filters of an audit log query. The fields that are
+// empty are not used as filters. Only the entries that have a sequence number
+// greater than After are returned.
+type AuditFilter struct {
+	After      uint64
+	Route      string
+	Credential string
+	Token      string
+	PluginID   string
+	Start      int64 // Unix timestamp, inclusive
+	End        int64 // Unix timestamp, inclusive
+}
+
 // Backend provides an API for interacting with records in the backend.
 type Backend interface {
 	// RecordNew creates a new record.
@@ -308,14 +854,57 @@ type Backend interface {
 	RecordSetStatus(token []byte, s StatusT, mdAppend,
 		mdOverwrite []MetadataStream) (*Record, error)
 
+	// RecordCensorFiles permanently deletes the payloads of the
+	// provided record files. The record metadata and the record
+	// merkle leaves are not modified. A censored file applies to all
+	// record versions that contain the same file.
+	RecordCensorFiles(token []byte, files []CensorFile,
+		reason string) ([]CensoredFile, error)
+
 	// RecordExists returns whether a record exists.
 	RecordExists(token []byte) bool
 
+	// Transaction executes an ordered list of record writes and plugin
+	// writes on a record atomically. Either all of the operations are
+	// committed or none of them are. A TxPartialError is returned if
+	// the commit is interrupted after some of the operations may have
+	// been committed.
+	Transaction(token []byte, ops []TxOp) (*TxReply, error)
+
+	// DraftNew creates a new draft record. Drafts are saved encrypted
+	// and are not timestamped.
+	DraftNew(metadata []MetadataStream, files []File) (*Record, error)
+
+	// DraftEdit edits a draft record. The draft is updated in place.
+	DraftEdit(token []byte, mdAppend, mdOverwrite []MetadataStream,
+		filesAdd []File, filesDel []string) (*Record, error)
+
+	// DraftDel deletes a draft record.
+	DraftDel(token []byte) error
+
+	// Draft returns a draft record.
+	Draft(token []byte) (*Record, error)
+
+	// DraftPromote submits a draft as a new unvetted record and
+	// deletes the draft. The new record is returned.
+	DraftPromote(token []byte) (*Record, error)
+
 	// RecordTimestamps returns the timestamps for a record. If no
 	// version is provided then timestamps for the most recent version
 	// will be returned.
 	RecordTimestamps(token []byte, version uint32) (*RecordTimestamps, error)
 
+	// RecordDiff returns the changes that were made to a record between
+	// two record versions. If no to version is provided then the most
+	// recent version is used. If no from version is provided then the
+	// version prior to the to version is used.
+	RecordDiff(token []byte, fromVersion, toVersion uint32) (*RecordDiff, error)
+
+	// RecordHistory returns an entry for every iteration of a record,
+	// sorted from oldest to newest. The history of an unvetted record
+	// is not returned once the record has been made vetted.
+	RecordHistory(token []byte) ([]RecordHistoryEntry, error)
+
 	// Records retreives a batch of records. If a record is not found
 	// then it is simply not included in the returned map. An error is
 	// not returned.
@@ -327,17 +916,28 @@ type Backend interface {
 	// sorted from newest to oldest.
 	//
 	// The state, status, and page arguments can be provided to request
-	// a specific page of record tokens.
+	// a specific page of record tokens. A page can also be requested
+	// using the cursor that was returned with the preceding page, in
+	// which case the page number is ignored. Requesting a page by cursor
+	// does not require the preceding pages to be walked.
 	//
 	// If no status is provided then the most recent page of tokens for
 	// all statuses will be returned. All other arguments are ignored.
-	Inventory(state StateT, status StatusT, pageSize,
-		pageNumber uint32) (*Inventory, error)
+	Inventory(state StateT, status StatusT, pageSize, pageNumber uint32,
+		cursor string) (*Inventory, error)
 
 	// InventoryOrdered returns a page of record tokens ordered by the
 	// timestamp of their most recent status change from newest to
-	// oldest. The returned tokens will include all record statuses.
-	InventoryOrdered(s StateT, pageSize, pageNumber uint32) ([]string, error)
+	// oldest. The returned tokens will include all record statuses. The
+	// page can be requested by number or by the cursor that was returned
+	// with the preceding page. The cursor of the following page is
+	// returned when the page is full.
+	InventoryOrdered(s StateT, pageSize, pageNumber uint32,
+		cursor string) ([]string, string, error)
+
+	// InventoryCounts returns the number of records in the inventory
+	// categorized by record state and record status.
+	InventoryCounts() (*InventoryCounts, error)
 
 	// PluginRegister registers a plugin.
 	PluginRegister(Plugin) error
@@ -357,8 +957,74 @@ type Backend interface {
 	PluginInventory() []Plugin
 
 	// Fsck performs a synchronous filesystem check that verifies
-	// the coherency of record and plugin data and caches.
-	Fsck() error
+	// the coherency of record and plugin data and caches. The backend
+	// remains online while the check is performed.
+	Fsck(FsckOpts) (*FsckReport, error)
+
+	// Reencrypt rotates the encryption key and re-encrypts all encrypted
+	// data using the new key. An interrupted re-encryption is resumed
+	// instead of rotating the key again. The backend remains online while
+	// the data is re-encrypted.
+	Reencrypt(ReencryptOpts) (*ReencryptReport, error)
+
+	// Reencode re-encodes all blobs that were saved using a previous
+	// blob encoding using the current encoding. An interrupted
+	// re-encoding is resumed. The backend remains online while the
+	// blobs are re-encoded.
+	Reencode(ReencodeOpts) (*ReencodeReport, error)
+
+	// AnchorStatus returns the status of the anchoring of the records.
+	AnchorStatus() (*AnchorStatus, error)
+
+	// AnchorDrop drops an anchor for all records that have unanchored
+	// leaves without waiting for the anchor schedule. The anchor
+	// providers are waited on in the background.
+	AnchorDrop() error
+
+	// Changes returns the entries of the change log that have a
+	// sequence number greater than the provided sequence number,
+	// ordered by sequence number. At most limit entries are returned.
+	Changes(since uint64, limit uint32) ([]Change, error)
+
+	// ChangesNotify returns a channel that is closed the next time a
+	// change is appended onto the change log. Callers that wait for
+	// new changes must obtain the channel before requesting the
+	// changes so that a change is not missed.
+	ChangesNotify() <-chan struct{}
+
+	// Snapshot returns an inventory snapshot and its timestamp. A
+	// sequence number of 0 returns the most recent snapshot.
+	Snapshot(seq uint64) (*InventorySnapshot, *Timestamp, error)
+
+	// SnapshotProof returns the proof that a record was included in an
+	// inventory snapshot. A sequence number of 0 uses the most recent
+	// snapshot.
+	SnapshotProof(token []byte, seq uint64) (*SnapshotInclusion, error)
+
+	// AuditAppend appends an entry onto the audit log. The sequence
+	// number and the timestamp of the entry are set by the backend. The
+	// entry may be appended asynchronously, in which case it is not
+	// returned by Audit until it has been appended.
+	AuditAppend(AuditEntry) error
+
+	// Audit returns the audit log entries that match the filter, ordered
+	// by sequence number. At most limit entries are returned.
+	Audit(f AuditFilter, limit uint32) ([]AuditEntry, error)
+
+	// AuditTimestamps returns the timestamps of the audit log entries
+	// with the provided sequence numbers. The timestamp data is the JSON
+	// encoded audit entry.
+	AuditTimestamps(seqs []uint64) (map[uint64]Timestamp, error)
+
+	// Health returns the health of the backend dependencies, keyed by
+	// dependency name. A nil error means that the dependency is
+	// reachable.
+	Health() map[string]error
+
+	// TlogPublicKey returns the ed25519 public key that the log roots
+	// of the tlog trees are signed with. nil is returned if the log
+	// roots are not signed by the backend.
+	TlogPublicKey() []byte
 
 	// Close performs cleanup of the backend.
 	Close()
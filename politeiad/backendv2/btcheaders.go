@@ -0,0 +1,128 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package backendv2
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultBitcoinHeaderHost is the default host of the Esplora API
+	// that bitcoin block headers are retrieved from.
+	DefaultBitcoinHeaderHost = "https://blockstream.info/api"
+
+	// btcHeaderSize is the size of a serialized bitcoin block header.
+	btcHeaderSize = 80
+
+	// btcMerkleRootOffset is the offset of the merkle root in a
+	// serialized bitcoin block header.
+	btcMerkleRootOffset = 36
+)
+
+// esploraHeaders is a BitcoinHeaderSource that retrieves bitcoin block headers
+// from an Esplora API, e.g. a blockstream.info or mempool.space instance.
+//
+// The header that is returned for a block hash is verified to hash to that
+// block hash. The block hash of a height is trusted to be on the main chain.
+type esploraHeaders struct {
+	host string
+	http *http.Client
+
+	sync.Mutex
+	roots map[uint64]string // [height]merkleRoot
+}
+
+// NewEsploraHeaderSource returns a BitcoinHeaderSource that retrieves the
+// bitcoin block headers from the Esplora API at the provided host.
+func NewEsploraHeaderSource(host string, c *http.Client) BitcoinHeaderSource {
+	return &esploraHeaders{
+		host:  strings.TrimSuffix(host, "/"),
+		http:  c,
+		roots: make(map[uint64]string),
+	}
+}
+
+// MerkleRoot returns the merkle root of the bitcoin block at the provided
+// height. It is hex encoded using the internal bitcoin byte order.
+//
+// This function satisfies the BitcoinHeaderSource interface.
+func (e *esploraHeaders) MerkleRoot(height uint64) (string, error) {
+	e.Lock()
+	root, ok := e.roots[height]
+	e.Unlock()
+	if ok {
+		return root, nil
+	}
+
+	// Retrieve the block hash of the height and the block header
+	hash, err := e.get("/block-height/" + strconv.FormatUint(height, 10))
+	if err != nil {
+		return "", err
+	}
+	h, err := e.get("/block/" + hash + "/header")
+	if err != nil {
+		return "", err
+	}
+	header, err := hex.DecodeString(h)
+	if err != nil {
+		return "", fmt.Errorf("invalid header: %v", err)
+	}
+	if len(header) != btcHeaderSize {
+		return "", fmt.Errorf("invalid header size: got %v, want %v",
+			len(header), btcHeaderSize)
+	}
+
+	// Verify the header hashes to the block hash. The block hash is
+	// displayed in the reverse byte order of the header hash.
+	d := sha256.Sum256(header)
+	d = sha256.Sum256(d[:])
+	blockHash := make([]byte, len(d))
+	for i := range d {
+		blockHash[i] = d[len(d)-1-i]
+	}
+	want, err := hex.DecodeString(hash)
+	if err != nil {
+		return "", fmt.Errorf("invalid block hash: %v", err)
+	}
+	if !bytes.Equal(blockHash, want) {
+		return "", fmt.Errorf("header does not hash to block %v", hash)
+	}
+
+	root = hex.EncodeToString(header[btcMerkleRootOffset : btcMerkleRootOffset+32])
+
+	e.Lock()
+	e.roots[height] = root
+	e.Unlock()
+
+	return root, nil
+}
+
+// get sends a GET request to the provided route and returns the trimmed
+// response body.
+func (e *esploraHeaders) get(route string) (string, error) {
+	r, err := e.http.Get(e.host + route)
+	if err != nil {
+		return "", err
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(io.LimitReader(r.Body, 1024))
+	if err != nil {
+		return "", err
+	}
+	if r.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%v: %v %s", route, r.StatusCode,
+			bytes.TrimSpace(b))
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package anchors
+
+import (
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+const (
+	// ProviderDcrtime is the ID of the provider that anchors digests
+	// onto the decred blockchain using the dcrtime service.
+	ProviderDcrtime = "dcrtime"
+
+	// ProviderRFC3161 is the ID of the provider that anchors digests
+	// using an RFC 3161 Time-Stamp Authority.
+	ProviderRFC3161 = "rfc3161"
+
+	// ProviderOpenTimestamps is the ID of the provider that anchors
+	// digests onto the bitcoin blockchain using an OpenTimestamps
+	// calendar server.
+	ProviderOpenTimestamps = "opentimestamps"
+)
+
+// Provider represents a service that tstore log roots are anchored to. Each
+// log root digest that is anchored is a hex encoded SHA256 digest.
+//
+// Anchoring is a two step process. The digests are first submitted to the
+// provider. The provider is then polled until the digests have been anchored.
+// Providers that anchor digests synchronously return the anchors on the first
+// poll.
+type Provider interface {
+	// ID returns the provider ID.
+	ID() string
+
+	// Submit submits the provided digests to be anchored.
+	Submit(digests []string) error
+
+	// Verify returns the anchors for the provided digests that have
+	// been anchored. Digests that have not been anchored yet are not
+	// included in the returned map. The proof of every returned anchor
+	// has been verified.
+	Verify(digests []string) (map[string]backend.TimestampAnchor, error)
+
+	// Wait returns how often the provider should be polled for the
+	// submitted digests and the maximum amount of time to wait for the
+	// digests to be anchored.
+	Wait() (period, timeout time.Duration)
+}
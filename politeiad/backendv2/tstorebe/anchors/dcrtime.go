@@ -0,0 +1,367 @@
+// Copyright (c) 2020-2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package anchors
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	dcrtime "github.com/decred/dcrtime/api/v2"
+	"github.com/decred/dcrtime/merkle"
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// dcrtimeID is included in the timestamp and verify requests as a
+	// unique identifier.
+	dcrtimeID = "tstorebe"
+
+	// dcrtimePeriod is how often dcrtime is polled for the submitted
+	// digests.
+	dcrtimePeriod = 5 * time.Minute
+
+	// dcrtimeTimeout is the maximum amount of time to wait for the
+	// submitted digests to be anchored. It is set to 180 minutes to
+	// ensure that enough time is given for the anchor transaction to
+	// receive 6 confirmations. This is based on the fact that each
+	// block has a 99.75% chance of being mined within 30 minutes.
+	dcrtimeTimeout = 180 * time.Minute
+)
+
+var (
+	_ Provider = (*dcrtimeClient)(nil)
+)
+
+// dcrtimeClient is a client for interacting with the dcrtime API.
+//
+// dcrtimeClient satisfies the Provider interface.
+type dcrtimeClient struct {
+	host     string
+	certPath string
+	http     *http.Client
+}
+
+// isDigestSHA256 returns whether the provided digest is a valid SHA256 digest.
+func isDigestSHA256(digest string) bool {
+	return dcrtime.RegexpSHA256.MatchString(digest)
+}
+
+// makeReq makes an http request to a dcrtime method and route, serializing the
+// provided object as the request body. The response body is returned as a byte
+// slice.
+func (c *dcrtimeClient) makeReq(method string, route string, v interface{}) ([]byte, error) {
+	var (
+		reqBody []byte
+		err     error
+	)
+	if v != nil {
+		reqBody, err = json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fullRoute := c.host + route
+
+	log.Tracef("%v %v", method, fullRoute)
+
+	req, err := http.NewRequest(method, fullRoute, bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		e, err := util.GetErrorFromJSON(r.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%v", r.Status)
+		}
+		return nil, fmt.Errorf("%v: %v", r.Status, e)
+	}
+
+	return util.RespBody(r), nil
+}
+
+// timestampBatch posts digests to the dcrtime v2 batch timestamp route.
+func (c *dcrtimeClient) timestampBatch(id string, digests []string) (*dcrtime.TimestampBatchReply, error) {
+	log.Tracef("timestampBatch: %v %v", id, digests)
+
+	// Setup request
+	for _, v := range digests {
+		if !isDigestSHA256(v) {
+			return nil, fmt.Errorf("invalid digest: %v", v)
+		}
+	}
+	tb := dcrtime.TimestampBatch{
+		ID:      id,
+		Digests: digests,
+	}
+
+	// Send request
+	respBody, err := c.makeReq(http.MethodPost, dcrtime.TimestampBatchRoute, tb)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var tbr dcrtime.TimestampBatchReply
+	err = json.Unmarshal(respBody, &tbr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tbr, nil
+}
+
+// verifyBatch returns the data to verify that a digest was included in a
+// dcrtime timestamp. This function verifies the merkle path and merkle root of
+// all successful timestamps. The caller is responsible for checking the result
+// code and handling digests that failed to be timestamped.
+//
+// Note the Result in the reply will be set to OK as soon as the digest is
+// waiting to be anchored. All the ChainInformation fields will be populated
+// once the digest has been included in a dcr transaction, except for the
+// ChainTimestamp field. The ChainTimestamp field is only populated once the
+// dcr transaction has 6 confirmations.
+func (c *dcrtimeClient) verifyBatch(id string, digests []string) (*dcrtime.VerifyBatchReply, error) {
+	log.Tracef("verifyBatch: %v %v", id, digests)
+
+	// Setup request
+	for _, v := range digests {
+		if !isDigestSHA256(v) {
+			return nil, fmt.Errorf("invalid digest: %v", v)
+		}
+	}
+	vb := dcrtime.VerifyBatch{
+		ID:      id,
+		Digests: digests,
+	}
+
+	// Send request
+	respBody, err := c.makeReq(http.MethodPost, dcrtime.VerifyBatchRoute, vb)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var vbr dcrtime.VerifyBatchReply
+	err = json.Unmarshal(respBody, &vbr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the merkle path and the merkle root of the timestamps
+	// that were successful. The caller is responsible for handling
+	// the digests that failed be timestamped.
+	for _, v := range vbr.Digests {
+		if v.Result != dcrtime.ResultOK {
+			// Nothing to verify
+			continue
+		}
+
+		// Verify merkle path
+		root, err := merkle.VerifyAuthPath(&v.ChainInformation.MerklePath)
+		if err != nil {
+			if errors.Is(err, merkle.ErrEmpty) {
+				// A dcr transaction has not been sent yet so there is
+				// nothing to verify.
+				continue
+			}
+			return nil, fmt.Errorf("VerifyAuthPath %v: %v", v.Digest, err)
+		}
+
+		// Verify merkle root
+		merkleRoot, err := hex.DecodeString(v.ChainInformation.MerkleRoot)
+		if err != nil {
+			return nil, fmt.Errorf("invalid merkle root: %v", err)
+		}
+		if !bytes.Equal(merkleRoot, root[:]) {
+			return nil, fmt.Errorf("invalid merkle root %v: got %x, want %x",
+				v.Digest, merkleRoot, root[:])
+		}
+	}
+
+	return &vbr, nil
+}
+
+// ID returns the provider ID.
+//
+// This function satisfies the Provider interface.
+func (c *dcrtimeClient) ID() string {
+	return ProviderDcrtime
+}
+
+// Submit submits the provided digests to dcrtime to be included in the next
+// dcrtime anchor transaction.
+//
+// This function satisfies the Provider interface.
+func (c *dcrtimeClient) Submit(digests []string) error {
+	log.Tracef("dcrtime Submit: %v", digests)
+
+	tbr, err := c.timestampBatch(dcrtimeID, digests)
+	if err != nil {
+		return fmt.Errorf("timestampBatch: %v", err)
+	}
+	var failed bool
+	for i, v := range tbr.Results {
+		switch v {
+		case dcrtime.ResultOK:
+			// We're good; continue
+		case dcrtime.ResultExistsError:
+			// This can happen if politeiad was shutdown in the middle of
+			// an anchor process. This is ok. The anchor process will pick
+			// up where it left off.
+			log.Warnf("Digest already exists %v: %v (%v)",
+				tbr.Digests[i], dcrtime.Result[v], v)
+		default:
+			// Something went wrong; exit
+			log.Errorf("Digest failed %v: %v (%v)",
+				tbr.Digests[i], dcrtime.Result[v], v)
+			failed = true
+		}
+	}
+	if failed {
+		return fmt.Errorf("dcrtime failed to timestamp digests")
+	}
+
+	return nil
+}
+
+// Verify returns the anchors for the provided digests that have been anchored
+// onto the decred blockchain. A digest is not considered anchored until
+// dcrtime returns the ChainTimestamp in the reply. dcrtime does not return the
+// ChainTimestamp until the timestamp transaction has 6 confirmations.
+//
+// This function satisfies the Provider interface.
+func (c *dcrtimeClient) Verify(digests []string) (map[string]backend.TimestampAnchor, error) {
+	log.Tracef("dcrtime Verify: %v", digests)
+
+	vbr, err := c.verifyBatch(dcrtimeID, digests)
+	if err != nil {
+		return nil, fmt.Errorf("verifyBatch: %v", err)
+	}
+
+	anchors := make(map[string]backend.TimestampAnchor, len(vbr.Digests))
+	for _, v := range vbr.Digests {
+		if v.Result != dcrtime.ResultOK {
+			// Something is wrong. Log the error and retry.
+			log.Errorf("Digest %v: %v (%v)",
+				v.Digest, dcrtime.Result[v.Result], v.Result)
+			continue
+		}
+
+		// Transaction will be populated once the tx has been sent,
+		// otherwise is will be a zeroed out SHA256 digest.
+		b := make([]byte, sha256.Size)
+		if v.ChainInformation.Transaction == hex.EncodeToString(b) {
+			log.Debugf("Anchor tx not sent yet for %v", v.Digest)
+			continue
+		}
+
+		// ChainTimestamp will be populated once the tx has 6
+		// confirmations.
+		if v.ChainInformation.ChainTimestamp == 0 {
+			log.Debugf("Anchor tx %v not enough confirmations",
+				v.ChainInformation.Transaction)
+			continue
+		}
+
+		// Verify the digest is in the merkle path. The merkle path and
+		// merkle root were verified by verifyBatch.
+		var found bool
+		for _, h := range v.ChainInformation.MerklePath.Hashes {
+			if hex.EncodeToString(h[:]) == v.Digest {
+				found = true
+				break
+			}
+		}
+		if !found {
+			log.Errorf("Digest %v not found in merkle path", v.Digest)
+			continue
+		}
+
+		a, err := DcrtimeAnchor(v)
+		if err != nil {
+			return nil, err
+		}
+		anchors[v.Digest] = *a
+	}
+
+	return anchors, nil
+}
+
+// Wait returns how often dcrtime should be polled for the submitted digests
+// and the maximum amount of time to wait for the digests to be anchored.
+//
+// This function satisfies the Provider interface.
+func (c *dcrtimeClient) Wait() (time.Duration, time.Duration) {
+	return dcrtimePeriod, dcrtimeTimeout
+}
+
+// DcrtimeAnchor converts a dcrtime VerifyDigest into a backend
+// TimestampAnchor.
+func DcrtimeAnchor(vd dcrtime.VerifyDigest) (*backend.TimestampAnchor, error) {
+	var (
+		numLeaves = vd.ChainInformation.MerklePath.NumLeaves
+		hashes    = vd.ChainInformation.MerklePath.Hashes
+		flags     = vd.ChainInformation.MerklePath.Flags
+	)
+	ed := backend.ExtraDataDcrtime{
+		NumLeaves: numLeaves,
+		Flags:     base64.StdEncoding.EncodeToString(flags),
+	}
+	extraData, err := json.Marshal(ed)
+	if err != nil {
+		return nil, err
+	}
+	merklePath := make([]string, 0, len(hashes))
+	for _, v := range hashes {
+		merklePath = append(merklePath, hex.EncodeToString(v[:]))
+	}
+	return &backend.TimestampAnchor{
+		Provider:   ProviderDcrtime,
+		TxID:       vd.ChainInformation.Transaction,
+		MerkleRoot: vd.ChainInformation.MerkleRoot,
+		Proof: backend.Proof{
+			Type:       backend.ProofTypeDcrtime,
+			Digest:     vd.Digest,
+			MerkleRoot: vd.ChainInformation.MerkleRoot,
+			MerklePath: merklePath,
+			ExtraData:  string(extraData),
+		},
+	}, nil
+}
+
+// NewDcrtime returns a new Provider that anchors digests using the dcrtime
+// service at the provided host.
+func NewDcrtime(host, certPath string) (Provider, error) {
+	_, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("parse dcrtime host '%v': %v", host, err)
+	}
+	c, err := util.NewHTTPClient(false, certPath)
+	if err != nil {
+		return nil, err
+	}
+	return &dcrtimeClient{
+		host:     host,
+		certPath: certPath,
+		http:     c,
+	}, nil
+}
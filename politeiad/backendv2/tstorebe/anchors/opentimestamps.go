@@ -0,0 +1,428 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package anchors
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// DefaultOpenTimestampsCalendar is the default OpenTimestamps
+	// calendar server.
+	DefaultOpenTimestampsCalendar = "https://alice.btc.calendar.opentimestamps.org"
+
+	// otsPeriod is how often the calendar is polled for the submitted
+	// digests.
+	otsPeriod = 10 * time.Minute
+
+	// otsTimeout is the maximum amount of time to wait for the submitted
+	// digests to be anchored. Calendars aggregate the submitted digests
+	// into a bitcoin transaction periodically and the transaction must
+	// then be confirmed, which can take multiple hours.
+	otsTimeout = 24 * time.Hour
+
+	// otsMaxReplySize is the maximum size of a calendar reply.
+	otsMaxReplySize = 64 * 1024 // 64 KiB
+
+	// otsMaxDepth is the maximum nesting depth of a timestamp.
+	otsMaxDepth = 256
+
+	// otsContentType is the content type of the calendar replies.
+	otsContentType = "application/vnd.opentimestamps.v1"
+)
+
+// OpenTimestamps binary serialization tags.
+const (
+	otsTagAttestation = 0x00
+	otsTagFork        = 0xff
+	otsOpSHA256       = 0x08
+	otsOpAppend       = 0xf0
+	otsOpPrepend      = 0xf1
+)
+
+var (
+	_ Provider = (*otsClient)(nil)
+
+	// OpenTimestamps attestation tags.
+	otsAttestationBitcoin = [8]byte{0x05, 0x88, 0x96, 0x0d, 0x73, 0xd7, 0x19, 0x01}
+	otsAttestationPending = [8]byte{0x83, 0xdf, 0xe3, 0x0d, 0x2e, 0xf9, 0x0c, 0x8e}
+)
+
+// otsPath is a path through an OpenTimestamps timestamp. The operations lead
+// from the timestamped message to the message that the attestation commits
+// to.
+type otsPath struct {
+	ops         []backend.OpenTimestampsOp
+	attestation [8]byte
+	payload     []byte
+}
+
+// otsPending contains a digest that has been submitted to the calendar but
+// has not been anchored yet.
+type otsPending struct {
+	ops        []backend.OpenTimestampsOp // Digest to commitment
+	commitment []byte                     // Pending calendar commitment
+}
+
+// otsClient is a client for interacting with an OpenTimestamps calendar
+// server. The calendar aggregates the submitted digests and anchors them onto
+// the bitcoin blockchain. The pending commitments are kept in memory until the
+// digests have been anchored.
+//
+// otsClient satisfies the Provider interface.
+type otsClient struct {
+	sync.Mutex
+	calendar string
+	http     *http.Client
+	pending  map[string]otsPending // [digest]otsPending
+}
+
+// ID returns the provider ID.
+//
+// This function satisfies the Provider interface.
+func (c *otsClient) ID() string {
+	return ProviderOpenTimestamps
+}
+
+// Submit submits the provided digests to the calendar.
+//
+// This function satisfies the Provider interface.
+func (c *otsClient) Submit(digests []string) error {
+	log.Tracef("opentimestamps Submit: %v", digests)
+
+	for _, v := range digests {
+		if !isDigestSHA256(v) {
+			return fmt.Errorf("invalid digest: %v", v)
+		}
+	}
+	for _, v := range digests {
+		d, err := hex.DecodeString(v)
+		if err != nil {
+			return err
+		}
+		b, err := c.makeReq(http.MethodPost, "/digest", d)
+		if err != nil {
+			return fmt.Errorf("submit %v: %v", v, err)
+		}
+		if b == nil {
+			return fmt.Errorf("submit %v: empty reply", v)
+		}
+
+		// Find the pending attestation of the calendar
+		paths, err := otsDecode(b)
+		if err != nil {
+			return fmt.Errorf("decode %v: %v", v, err)
+		}
+		var p *otsPath
+		for i := range paths {
+			if paths[i].attestation == otsAttestationPending {
+				p = &paths[i]
+				break
+			}
+		}
+		if p == nil {
+			return fmt.Errorf("pending attestation not found for %v", v)
+		}
+		commitment, err := otsApply(p.ops, d)
+		if err != nil {
+			return err
+		}
+
+		c.Lock()
+		c.pending[v] = otsPending{
+			ops:        p.ops,
+			commitment: commitment,
+		}
+		c.Unlock()
+	}
+
+	return nil
+}
+
+// Verify returns the anchors of the provided digests that the calendar has
+// anchored onto the bitcoin blockchain.
+//
+// This function satisfies the Provider interface.
+func (c *otsClient) Verify(digests []string) (map[string]backend.TimestampAnchor, error) {
+	log.Tracef("opentimestamps Verify: %v", digests)
+
+	anchors := make(map[string]backend.TimestampAnchor, len(digests))
+	for _, v := range digests {
+		c.Lock()
+		p, ok := c.pending[v]
+		c.Unlock()
+		if !ok {
+			continue
+		}
+
+		// Request the upgraded timestamp of the commitment. A nil
+		// reply indicates that the commitment is still pending.
+		route := "/timestamp/" + hex.EncodeToString(p.commitment)
+		b, err := c.makeReq(http.MethodGet, route, nil)
+		if err != nil {
+			return nil, fmt.Errorf("upgrade %v: %v", v, err)
+		}
+		if b == nil {
+			log.Debugf("Calendar commitment pending for %v", v)
+			continue
+		}
+		paths, err := otsDecode(b)
+		if err != nil {
+			return nil, fmt.Errorf("decode %v: %v", v, err)
+		}
+		var bp *otsPath
+		for i := range paths {
+			if paths[i].attestation == otsAttestationBitcoin {
+				bp = &paths[i]
+				break
+			}
+		}
+		if bp == nil {
+			log.Debugf("Bitcoin attestation not found for %v", v)
+			continue
+		}
+		height, n := binary.Uvarint(bp.payload)
+		if n <= 0 || height == 0 {
+			return nil, fmt.Errorf("invalid bitcoin attestation for %v", v)
+		}
+
+		// Setup and verify the anchor
+		ops := make([]backend.OpenTimestampsOp, 0, len(p.ops)+len(bp.ops))
+		ops = append(ops, p.ops...)
+		ops = append(ops, bp.ops...)
+		d, err := hex.DecodeString(v)
+		if err != nil {
+			return nil, err
+		}
+		root, err := otsApply(ops, d)
+		if err != nil {
+			return nil, err
+		}
+		extraData, err := json.Marshal(backend.ExtraDataOpenTimestamps{
+			Ops:    ops,
+			Height: height,
+		})
+		if err != nil {
+			return nil, err
+		}
+		a := backend.TimestampAnchor{
+			Provider:   ProviderOpenTimestamps,
+			TxID:       strconv.FormatUint(height, 10),
+			MerkleRoot: hex.EncodeToString(root),
+			Proof: backend.Proof{
+				Type:       backend.ProofTypeOpenTimestamps,
+				Digest:     v,
+				MerkleRoot: hex.EncodeToString(root),
+				MerklePath: []string{},
+				ExtraData:  string(extraData),
+			},
+		}
+		// The attestation is verified against the bitcoin block header
+		// by the clients when no header source has been configured.
+		err = backend.VerifyProof(a.Proof)
+		if err != nil && err != backend.ErrAttestationUnverified {
+			return nil, fmt.Errorf("invalid proof %v: %v", v, err)
+		}
+		anchors[v] = a
+
+		c.Lock()
+		delete(c.pending, v)
+		c.Unlock()
+	}
+
+	return anchors, nil
+}
+
+// Wait returns how often the calendar should be polled for the submitted
+// digests and the maximum amount of time to wait for the digests to be
+// anchored.
+//
+// This function satisfies the Provider interface.
+func (c *otsClient) Wait() (time.Duration, time.Duration) {
+	return otsPeriod, otsTimeout
+}
+
+// makeReq makes an http request to a calendar route and returns the response
+// body. A nil body is returned if the calendar responds with a not found
+// status, which it does for commitments that are still pending.
+func (c *otsClient) makeReq(method, route string, body []byte) ([]byte, error) {
+	fullRoute := c.calendar + route
+
+	log.Tracef("%v %v", method, fullRoute)
+
+	req, err := http.NewRequest(method, fullRoute, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", otsContentType)
+
+	r, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	switch r.StatusCode {
+	case http.StatusOK:
+		// We're good; continue
+	case http.StatusNotFound:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("%v", r.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(r.Body, otsMaxReplySize))
+}
+
+// otsApply applies the provided operations to the message and returns the
+// result.
+func otsApply(ops []backend.OpenTimestampsOp, msg []byte) ([]byte, error) {
+	var err error
+	for _, v := range ops {
+		msg, err = backend.ApplyOpenTimestampsOp(v, msg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// otsDecode decodes a binary serialized OpenTimestamps timestamp and returns
+// all of the paths from the timestamped message to an attestation. Only the
+// operations that are used by the calendar servers are supported.
+func otsDecode(b []byte) ([]otsPath, error) {
+	r := bytes.NewReader(b)
+	paths, err := otsDecodeTimestamp(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%v bytes of trailing data", r.Len())
+	}
+	return paths, nil
+}
+
+// otsDecodeTimestamp decodes a timestamp node. Every branch of the node except
+// for the last one is prefixed with a fork tag.
+func otsDecodeTimestamp(r *bytes.Reader, depth int) ([]otsPath, error) {
+	if depth > otsMaxDepth {
+		return nil, fmt.Errorf("max depth exceeded")
+	}
+	paths := make([]otsPath, 0, 1)
+	for {
+		tag, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		fork := tag == otsTagFork
+		if fork {
+			tag, err = r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+		}
+		p, err := otsDecodeBranch(r, tag, depth)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p...)
+		if !fork {
+			return paths, nil
+		}
+	}
+}
+
+// otsDecodeBranch decodes a timestamp branch that starts with the provided
+// tag. A branch is either an attestation or an operation that is followed by
+// the timestamp of the operation result.
+func otsDecodeBranch(r *bytes.Reader, tag byte, depth int) ([]otsPath, error) {
+	if tag == otsTagAttestation {
+		var p otsPath
+		_, err := io.ReadFull(r, p.attestation[:])
+		if err != nil {
+			return nil, err
+		}
+		p.payload, err = otsReadVarbytes(r)
+		if err != nil {
+			return nil, err
+		}
+		return []otsPath{p}, nil
+	}
+
+	var op backend.OpenTimestampsOp
+	switch tag {
+	case otsOpSHA256:
+		op.Type = backend.OpenTimestampsOpSHA256
+	case otsOpAppend, otsOpPrepend:
+		op.Type = backend.OpenTimestampsOpAppend
+		if tag == otsOpPrepend {
+			op.Type = backend.OpenTimestampsOpPrepend
+		}
+		arg, err := otsReadVarbytes(r)
+		if err != nil {
+			return nil, err
+		}
+		op.Arg = hex.EncodeToString(arg)
+	default:
+		return nil, fmt.Errorf("unsupported op 0x%02x", tag)
+	}
+	paths, err := otsDecodeTimestamp(r, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	for i := range paths {
+		paths[i].ops = append([]backend.OpenTimestampsOp{op}, paths[i].ops...)
+	}
+	return paths, nil
+}
+
+// otsReadVarbytes reads a length prefixed byte slice.
+func otsReadVarbytes(r *bytes.Reader) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, l)
+	_, err = io.ReadFull(r, b)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewOpenTimestamps returns a new Provider that anchors digests using the
+// OpenTimestamps calendar server at the provided URL.
+func NewOpenTimestamps(calendar string) (Provider, error) {
+	_, err := url.Parse(calendar)
+	if err != nil {
+		return nil, fmt.Errorf("parse calendar '%v': %v", calendar, err)
+	}
+	c, err := util.NewHTTPClient(false, "")
+	if err != nil {
+		return nil, err
+	}
+	return &otsClient{
+		calendar: strings.TrimSuffix(calendar, "/"),
+		http:     c,
+		pending:  make(map[string]otsPending),
+	}, nil
+}
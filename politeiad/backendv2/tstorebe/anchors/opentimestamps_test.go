@@ -0,0 +1,211 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package anchors
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// otsVarbytes returns the length prefixed encoding of the provided bytes.
+func otsVarbytes(b []byte) []byte {
+	l := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(l, uint64(len(b)))
+	return append(l[:n], b...)
+}
+
+// testCalendar is an OpenTimestamps calendar server that is used for testing.
+type testCalendar struct {
+	t         *testing.T
+	nonce     []byte
+	prefix    []byte
+	height    uint64
+	confirmed bool
+
+	commitment []byte // Commitment of the submitted digest
+}
+
+func (c *testCalendar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/digest":
+		digest, err := io.ReadAll(r.Body)
+		if err != nil {
+			c.t.Error(err)
+			return
+		}
+		// append(nonce), sha256, pending attestation
+		var b bytes.Buffer
+		b.WriteByte(otsOpAppend)
+		b.Write(otsVarbytes(c.nonce))
+		b.WriteByte(otsOpSHA256)
+		b.WriteByte(otsTagAttestation)
+		b.Write(otsAttestationPending[:])
+		b.Write(otsVarbytes([]byte("https://calendar.test")))
+		w.Write(b.Bytes())
+
+		d := sha256.Sum256(append(digest, c.nonce...))
+		c.commitment = d[:]
+
+	case r.Method == http.MethodGet &&
+		strings.HasPrefix(r.URL.Path, "/timestamp/"):
+		if strings.TrimPrefix(r.URL.Path, "/timestamp/") !=
+			hex.EncodeToString(c.commitment) {
+			c.t.Errorf("unexpected commitment %v", r.URL.Path)
+		}
+		if !c.confirmed {
+			http.Error(w, "Pending confirmation", http.StatusNotFound)
+			return
+		}
+		// fork: pending attestation, prepend(prefix), sha256, bitcoin
+		// attestation
+		var b bytes.Buffer
+		b.WriteByte(otsTagFork)
+		b.WriteByte(otsTagAttestation)
+		b.Write(otsAttestationPending[:])
+		b.Write(otsVarbytes([]byte("https://calendar.test")))
+		b.WriteByte(otsOpPrepend)
+		b.Write(otsVarbytes(c.prefix))
+		b.WriteByte(otsOpSHA256)
+		b.WriteByte(otsTagAttestation)
+		b.Write(otsAttestationBitcoin[:])
+		h := make([]byte, binary.MaxVarintLen64)
+		n := binary.PutUvarint(h, c.height)
+		b.Write(otsVarbytes(h[:n]))
+		w.Write(b.Bytes())
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// testHeaders is a bitcoin header source that is used for testing.
+type testHeaders map[uint64]string
+
+// MerkleRoot satisfies the backend BitcoinHeaderSource interface.
+func (h testHeaders) MerkleRoot(height uint64) (string, error) {
+	r, ok := h[height]
+	if !ok {
+		return "", fmt.Errorf("block %v not found", height)
+	}
+	return r, nil
+}
+
+func TestOpenTimestamps(t *testing.T) {
+	cal := &testCalendar{
+		t:      t,
+		nonce:  []byte{0x01, 0x02, 0x03, 0x04},
+		prefix: []byte{0x05, 0x06},
+		height: 700000,
+	}
+	srv := httptest.NewServer(cal)
+	defer srv.Close()
+
+	p, err := NewOpenTimestamps(srv.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := sha256.Sum256([]byte("root"))
+	digest := hex.EncodeToString(d[:])
+
+	// Submit the digest. The digest is not anchored until the calendar
+	// commitment has been confirmed.
+	err = p.Submit([]string{digest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchors, err := p.Verify([]string{digest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anchors) != 0 {
+		t.Fatalf("got %v anchors for a pending commitment", len(anchors))
+	}
+
+	// Confirm the commitment
+	cal.confirmed = true
+	anchors, err = p.Verify([]string{digest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := anchors[digest]
+	if !ok {
+		t.Fatalf("anchor not found")
+	}
+	commitment := sha256.Sum256(append(d[:], cal.nonce...))
+	root := sha256.Sum256(append(cal.prefix, commitment[:]...))
+	if a.MerkleRoot != hex.EncodeToString(root[:]) || a.TxID != "700000" {
+		t.Fatalf("unexpected anchor %+v", a)
+	}
+	// The attestation is unverified until the merkle root has been
+	// verified against the bitcoin block header.
+	err = backend.VerifyProof(a.Proof)
+	if err != backend.ErrAttestationUnverified {
+		t.Fatalf("got %v, want %v", err, backend.ErrAttestationUnverified)
+	}
+	backend.SetBitcoinHeaderSource(testHeaders{700000: a.MerkleRoot})
+	defer backend.SetBitcoinHeaderSource(nil)
+	err = backend.VerifyProof(a.Proof)
+	if err != nil {
+		t.Fatalf("VerifyProof: %v", err)
+	}
+
+	// A proof with modified operations must not verify
+	var ed backend.ExtraDataOpenTimestamps
+	err = json.Unmarshal([]byte(a.Proof.ExtraData), &ed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed.Ops = ed.Ops[1:]
+	b, err := json.Marshal(ed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Proof.ExtraData = string(b)
+	err = backend.VerifyProof(a.Proof)
+	if err == nil {
+		t.Fatalf("VerifyProof succeeded with modified operations")
+	}
+}
+
+func TestOTSDecode(t *testing.T) {
+	// Unsupported operations are rejected
+	_, err := otsDecode([]byte{0x02, 0x00})
+	if err == nil {
+		t.Fatalf("decoded unsupported op")
+	}
+
+	// Truncated timestamps are rejected
+	_, err = otsDecode([]byte{otsOpAppend, 0x05, 0x01})
+	if err == nil {
+		t.Fatalf("decoded truncated timestamp")
+	}
+
+	// Trailing data is rejected
+	b := []byte{otsTagAttestation}
+	b = append(b, otsAttestationBitcoin[:]...)
+	b = append(b, otsVarbytes([]byte{0x01})...)
+	_, err = otsDecode(append(b, 0x00))
+	if err == nil {
+		t.Fatalf("decoded timestamp with trailing data")
+	}
+	paths, err := otsDecode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0].attestation != otsAttestationBitcoin {
+		t.Fatalf("unexpected paths %+v", paths)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package anchors
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// rfc3161Period is how often the time-stamp authority is polled for
+	// the submitted digests. Time-stamp tokens are issued when the
+	// digests are submitted so the digests are anchored on the first
+	// poll.
+	rfc3161Period = time.Second
+
+	// rfc3161Timeout is the maximum amount of time to wait for the
+	// submitted digests to be anchored.
+	rfc3161Timeout = time.Minute
+
+	// rfc3161MaxReplySize is the maximum size of a time-stamp response.
+	rfc3161MaxReplySize = 1024 * 1024 // 1 MiB
+
+	// Content types of the time-stamp request and response.
+	rfc3161ContentTypeQuery = "application/timestamp-query"
+	rfc3161ContentTypeReply = "application/timestamp-reply"
+)
+
+var (
+	_ Provider = (*rfc3161Client)(nil)
+
+	// oidSHA256 is the ASN.1 object identifier of the SHA256 hash
+	// algorithm.
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+// The following types are the ASN.1 structures of a time-stamp request and
+// response as defined by RFC 3161.
+type (
+	timeStampReq struct {
+		Version        int
+		MessageImprint messageImprint
+		Nonce          *big.Int `asn1:"optional"`
+		CertReq        bool     `asn1:"optional"`
+	}
+
+	messageImprint struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		HashedMessage []byte
+	}
+
+	timeStampResp struct {
+		Status         pkiStatusInfo
+		TimeStampToken asn1.RawValue `asn1:"optional"`
+	}
+
+	pkiStatusInfo struct {
+		Status       int
+		StatusString asn1.RawValue  `asn1:"optional"`
+		FailInfo     asn1.BitString `asn1:"optional"`
+	}
+)
+
+// PKI status values of a time-stamp response.
+const (
+	pkiStatusGranted         = 0
+	pkiStatusGrantedWithMods = 1
+)
+
+// rfc3161Client is a client for interacting with an RFC 3161 Time-Stamp
+// Authority (TSA). The TSA issues a signed time-stamp token for each digest
+// when it is submitted. The tokens are kept in memory until they are returned
+// by Verify.
+//
+// rfc3161Client satisfies the Provider interface.
+type rfc3161Client struct {
+	sync.Mutex
+	host string
+	http *http.Client
+
+	// tokens contains the verified time-stamp anchors that have not been
+	// returned by Verify yet.
+	tokens map[string]backend.TimestampAnchor // [digest]anchor
+}
+
+// ID returns the provider ID.
+//
+// This function satisfies the Provider interface.
+func (c *rfc3161Client) ID() string {
+	return ProviderRFC3161
+}
+
+// Submit requests a time-stamp token for each of the provided digests. The
+// digest is used as the message imprint of the time-stamp request.
+//
+// This function satisfies the Provider interface.
+func (c *rfc3161Client) Submit(digests []string) error {
+	log.Tracef("rfc3161 Submit: %v", digests)
+
+	for _, v := range digests {
+		if !isDigestSHA256(v) {
+			return fmt.Errorf("invalid digest: %v", v)
+		}
+	}
+	for _, v := range digests {
+		a, err := c.timestamp(v)
+		if err != nil {
+			return fmt.Errorf("timestamp %v: %v", v, err)
+		}
+
+		c.Lock()
+		c.tokens[v] = *a
+		c.Unlock()
+	}
+
+	return nil
+}
+
+// Verify returns the anchors of the provided digests that a time-stamp token
+// has been issued for.
+//
+// This function satisfies the Provider interface.
+func (c *rfc3161Client) Verify(digests []string) (map[string]backend.TimestampAnchor, error) {
+	log.Tracef("rfc3161 Verify: %v", digests)
+
+	c.Lock()
+	defer c.Unlock()
+
+	anchors := make(map[string]backend.TimestampAnchor, len(digests))
+	for _, v := range digests {
+		a, ok := c.tokens[v]
+		if !ok {
+			continue
+		}
+		anchors[v] = a
+		delete(c.tokens, v)
+	}
+
+	return anchors, nil
+}
+
+// Wait returns how often the time-stamp authority should be polled for the
+// submitted digests and the maximum amount of time to wait for the digests to
+// be anchored.
+//
+// This function satisfies the Provider interface.
+func (c *rfc3161Client) Wait() (time.Duration, time.Duration) {
+	return rfc3161Period, rfc3161Timeout
+}
+
+// timestamp requests a time-stamp token for the provided digest and returns
+// the anchor that contains the verified token.
+func (c *rfc3161Client) timestamp(digest string) (*backend.TimestampAnchor, error) {
+	d, err := hex.DecodeString(digest)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	req, err := asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{
+				Algorithm:  oidSHA256,
+				Parameters: asn1.NullRawValue,
+			},
+			HashedMessage: d,
+		},
+		Nonce:   nonce,
+		CertReq: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Send request
+	log.Tracef("POST %v", c.host)
+
+	r, err := c.http.Post(c.host, rfc3161ContentTypeQuery,
+		bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%v", r.Status)
+	}
+	ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || ct != rfc3161ContentTypeReply {
+		return nil, fmt.Errorf("unexpected content type '%v'",
+			r.Header.Get("Content-Type"))
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, rfc3161MaxReplySize))
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var resp timeStampResp
+	_, err = asn1.Unmarshal(body, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("decode reply: %v", err)
+	}
+	switch resp.Status.Status {
+	case pkiStatusGranted, pkiStatusGrantedWithMods:
+		// We're good; continue
+	default:
+		return nil, fmt.Errorf("time-stamp request rejected: status %v",
+			resp.Status.Status)
+	}
+	token := resp.TimeStampToken.FullBytes
+	if len(token) == 0 {
+		return nil, fmt.Errorf("time-stamp token not found")
+	}
+
+	// Setup and verify the anchor
+	extraData, err := json.Marshal(backend.ExtraDataRFC3161{
+		Token: base64.StdEncoding.EncodeToString(token),
+	})
+	if err != nil {
+		return nil, err
+	}
+	tokenDigest := sha256.Sum256(token)
+	a := backend.TimestampAnchor{
+		Provider:   ProviderRFC3161,
+		TxID:       hex.EncodeToString(tokenDigest[:]),
+		MerkleRoot: digest,
+		Proof: backend.Proof{
+			Type:       backend.ProofTypeRFC3161,
+			Digest:     digest,
+			MerkleRoot: digest,
+			MerklePath: []string{},
+			ExtraData:  string(extraData),
+		},
+	}
+	err = backend.VerifyProof(a.Proof)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time-stamp token: %v", err)
+	}
+
+	return &a, nil
+}
+
+// NewRFC3161 returns a new Provider that anchors digests using the RFC 3161
+// Time-Stamp Authority at the provided URL. The cert path is the path to the
+// HTTPS certificate of the Time-Stamp Authority and is optional.
+func NewRFC3161(host, certPath string) (Provider, error) {
+	_, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("parse tsa host '%v': %v", host, err)
+	}
+	c, err := util.NewHTTPClient(false, certPath)
+	if err != nil {
+		return nil, err
+	}
+	return &rfc3161Client{
+		host:   host,
+		http:   c,
+		tokens: make(map[string]backend.TimestampAnchor),
+	}, nil
+}
@@ -0,0 +1,361 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package anchors
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// The following types are used by the test TSA to encode time-stamp tokens.
+type (
+	testContentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}
+
+	testSignedData struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		EncapContentInfo testEncapContentInfo
+		Certificates     asn1.RawValue
+		SignerInfos      []testSignerInfo `asn1:"set"`
+	}
+
+	testEncapContentInfo struct {
+		EContentType asn1.ObjectIdentifier
+		EContent     []byte `asn1:"explicit,tag:0"`
+	}
+
+	testSignerInfo struct {
+		Version            int
+		SID                asn1.RawValue
+		DigestAlgorithm    pkix.AlgorithmIdentifier
+		SignedAttrs        asn1.RawValue
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          []byte
+	}
+
+	testAttribute struct {
+		Type   asn1.ObjectIdentifier
+		Values asn1.RawValue
+	}
+
+	testTSTInfo struct {
+		Version        int
+		Policy         asn1.ObjectIdentifier
+		MessageImprint messageImprint
+		SerialNumber   *big.Int
+		GenTime        time.Time `asn1:"generalized"`
+		Nonce          *big.Int  `asn1:"optional"`
+	}
+)
+
+var (
+	oidTestSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTestTSTInfo    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidTestCT         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidTestMD         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidTestECDSA256   = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidTestPolicy     = asn1.ObjectIdentifier{1, 2, 3, 4}
+)
+
+// testTSA is an RFC 3161 Time-Stamp Authority that is used for testing.
+type testTSA struct {
+	t      *testing.T
+	key    *ecdsa.PrivateKey
+	cert   *x509.Certificate
+	serial int64
+
+	// tamper is used to corrupt the message imprint of the issued
+	// tokens.
+	tamper bool
+}
+
+// testCA is a certificate authority that is used to issue the certificates of
+// the test TSAs.
+type testCA struct {
+	key  *ecdsa.PrivateKey
+	cert *x509.Certificate
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl,
+		&key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		key:  key,
+		cert: cert,
+	}
+}
+
+// newTestTSA returns a new test TSA. The TSA certificate is issued by the
+// provided certificate authority. The certificate is self-signed if no
+// certificate authority is provided.
+func newTestTSA(t *testing.T, ca *testCA) *testTSA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test tsa"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		SubjectKeyId: []byte{1, 2, 3, 4},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	var (
+		parent    = tmpl
+		parentKey = key
+	)
+	if ca != nil {
+		parent = ca.cert
+		parentKey = ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent,
+		&key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testTSA{
+		t:    t,
+		key:  key,
+		cert: cert,
+	}
+}
+
+// marshal returns the DER encoding of the provided value.
+func (s *testTSA) marshal(v interface{}) []byte {
+	b, err := asn1.Marshal(v)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	return b
+}
+
+// token returns a time-stamp token for the provided request.
+func (s *testTSA) token(req timeStampReq) []byte {
+	s.serial++
+	mi := req.MessageImprint
+	if s.tamper {
+		mi.HashedMessage = make([]byte, sha256.Size)
+	}
+	eContent := s.marshal(testTSTInfo{
+		Version:        1,
+		Policy:         oidTestPolicy,
+		MessageImprint: mi,
+		SerialNumber:   big.NewInt(s.serial),
+		GenTime:        time.Now().UTC().Truncate(time.Second),
+		Nonce:          req.Nonce,
+	})
+
+	// Setup the signed attributes
+	md := sha256.Sum256(eContent)
+	attrSet := func(v interface{}) asn1.RawValue {
+		return asn1.RawValue{FullBytes: s.marshal(asn1.RawValue{
+			Tag:        asn1.TagSet,
+			IsCompound: true,
+			Bytes:      s.marshal(v),
+		})}
+	}
+	attrs := s.marshal([]testAttribute{
+		{Type: oidTestCT, Values: attrSet(oidTestTSTInfo)},
+		{Type: oidTestMD, Values: attrSet(md[:])},
+	})
+	// The attributes are encoded as a SEQUENCE; the signature is over
+	// the SET OF encoding and the signer info uses the implicit tag.
+	attrs[0] = 0x31
+	digest := sha256.Sum256(attrs)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	signedAttrs := make([]byte, len(attrs))
+	copy(signedAttrs, attrs)
+	signedAttrs[0] = 0xa0
+
+	sd := s.marshal(testSignedData{
+		Version: 3,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{
+			{Algorithm: oidSHA256},
+		},
+		EncapContentInfo: testEncapContentInfo{
+			EContentType: oidTestTSTInfo,
+			EContent:     eContent,
+		},
+		Certificates: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      s.cert.Raw,
+		},
+		SignerInfos: []testSignerInfo{{
+			Version: 3,
+			SID: asn1.RawValue{
+				Class: asn1.ClassContextSpecific,
+				Tag:   0,
+				Bytes: s.cert.SubjectKeyId,
+			},
+			DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+			SignedAttrs:        asn1.RawValue{FullBytes: signedAttrs},
+			SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidTestECDSA256},
+			Signature:          sig,
+		}},
+	})
+	return s.marshal(testContentInfo{
+		ContentType: oidTestSignedData,
+		Content: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      sd,
+		},
+	})
+}
+
+func (s *testTSA) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+	var req timeStampReq
+	_, err = asn1.Unmarshal(b, &req)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+	resp := s.marshal(struct {
+		Status pkiStatusInfo
+		Token  asn1.RawValue
+	}{
+		Status: pkiStatusInfo{Status: pkiStatusGranted},
+		Token:  asn1.RawValue{FullBytes: s.token(req)},
+	})
+	w.Header().Set("Content-Type", rfc3161ContentTypeReply)
+	w.Write(resp)
+}
+
+func TestRFC3161(t *testing.T) {
+	ca := newTestCA(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	backend.SetTSARoots(roots)
+	defer backend.SetTSARoots(nil)
+
+	tsa := newTestTSA(t, ca)
+	srv := httptest.NewServer(tsa)
+	defer srv.Close()
+
+	p, err := NewRFC3161(srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := sha256.Sum256([]byte("root"))
+	digest := hex.EncodeToString(d[:])
+
+	// Submit the digest. The token is issued and verified right away.
+	err = p.Submit([]string{digest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	anchors, err := p.Verify([]string{digest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := anchors[digest]
+	if !ok {
+		t.Fatalf("anchor not found")
+	}
+	if a.Provider != ProviderRFC3161 || a.Proof.Digest != digest {
+		t.Fatalf("unexpected anchor %+v", a)
+	}
+	err = backend.VerifyProof(a.Proof)
+	if err != nil {
+		t.Fatalf("VerifyProof: %v", err)
+	}
+
+	// A proof for a different digest must not verify
+	a.Proof.Digest = hex.EncodeToString(make([]byte, sha256.Size))
+	a.Proof.MerkleRoot = a.Proof.Digest
+	err = backend.VerifyProof(a.Proof)
+	if err == nil {
+		t.Fatalf("VerifyProof succeeded for the wrong digest")
+	}
+
+	// A token that does not match the submitted digest is rejected
+	tsa.tamper = true
+	err = p.Submit([]string{digest})
+	if err == nil {
+		t.Fatalf("Submit succeeded with a tampered token")
+	}
+}
+
+func TestRFC3161UntrustedTSA(t *testing.T) {
+	ca := newTestCA(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	backend.SetTSARoots(roots)
+	defer backend.SetTSARoots(nil)
+
+	// A token that is signed by a self-signed TSA certificate is
+	// rejected even though the certificate allows time stamping.
+	tsa := newTestTSA(t, nil)
+	srv := httptest.NewServer(tsa)
+	defer srv.Close()
+
+	p, err := NewRFC3161(srv.URL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := sha256.Sum256([]byte("root"))
+	err = p.Submit([]string{hex.EncodeToString(d[:])})
+	if err == nil {
+		t.Fatalf("Submit succeeded with an untrusted tsa")
+	}
+}
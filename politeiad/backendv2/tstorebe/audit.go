@@ -0,0 +1,319 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstorebe
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/util"
+)
+
+// The audit log is appended onto the tstore audit tree so that it is
+// timestamped. The audit tree is the only copy of the audit log. Queries are
+// answered by reading the entries from the tree.
+//
+// Entries are queued by AuditAppend and are appended onto the audit tree by
+// the audit worker in batches, so that the write routes do not wait on tlog.
+// The sequence number of an entry is assigned when it is queued and is never
+// reused, even if the entry can not be appended, in which case the entry is
+// logged and the audit log has a gap.
+const (
+	// auditQueueSize is the number of audit log entries that can be
+	// queued before AuditAppend blocks.
+	auditQueueSize = 1000
+
+	// auditBatchSize is the maximum number of queued audit log entries
+	// that are appended onto the audit tree at once.
+	auditBatchSize = 100
+
+	// auditRetries is the number of times that appending a batch of
+	// audit log entries onto the audit tree is attempted.
+	auditRetries = 5
+
+	// auditRetryInterval is the amount of time that is waited before
+	// appending a batch of audit log entries is attempted again.
+	auditRetryInterval = 5 * time.Second
+)
+
+// auditItem is an item of the audit queue. It contains either a JSON encoded
+// audit log entry or a channel that is closed by the audit worker once all
+// previously queued entries have been processed.
+type auditItem struct {
+	entry   []byte
+	flushed chan struct{}
+}
+
+// auditEntries returns the audit log entries of the audit tree, ordered by
+// sequence number, and the JSON encoded entries, keyed by sequence number.
+func (t *tstoreBackend) auditEntries() ([]backend.AuditEntry, map[uint64][]byte, error) {
+	blobs, err := t.tstore.AuditEntries()
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		entries = make([]backend.AuditEntry, 0, len(blobs))
+		encoded = make(map[uint64][]byte, len(blobs))
+	)
+	for _, b := range blobs {
+		var e backend.AuditEntry
+		err = json.Unmarshal(b, &e)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, e)
+		encoded[e.Sequence] = b
+	}
+
+	// The leaves of a batch are not guaranteed to be sequenced in the
+	// order that they were appended in.
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Sequence < entries[j].Sequence
+	})
+
+	return entries, encoded, nil
+}
+
+// auditSetup restores the sequence number of the most recent audit log entry
+// from the audit tree and starts the audit worker.
+func (t *tstoreBackend) auditSetup() error {
+	entries, _, err := t.auditEntries()
+	if err != nil {
+		return err
+	}
+	var seq uint64
+	if len(entries) > 0 {
+		seq = entries[len(entries)-1].Sequence
+	}
+
+	t.auditMtx.Lock()
+	t.auditSeq = seq
+	t.auditMtx.Unlock()
+
+	log.Infof("Audit log sequence: %v", seq)
+
+	t.auditStart()
+
+	return nil
+}
+
+// auditStart starts the audit worker.
+func (t *tstoreBackend) auditStart() {
+	t.auditMtx.Lock()
+	defer t.auditMtx.Unlock()
+
+	t.auditC = make(chan auditItem, auditQueueSize)
+	t.auditDone = make(chan struct{})
+	go t.auditWorker(t.auditC, t.auditDone)
+}
+
+// auditStop stops the audit worker once all queued entries have been
+// appended onto the audit tree. Entries can no longer be queued once the
+// audit worker has been stopped.
+func (t *tstoreBackend) auditStop() {
+	t.auditMtx.Lock()
+	c, done := t.auditC, t.auditDone
+	t.auditC = nil
+	t.auditMtx.Unlock()
+
+	if c == nil {
+		return
+	}
+	close(c)
+	<-done
+}
+
+// auditFlush blocks until all entries that were queued prior to the call
+// have been processed by the audit worker.
+func (t *tstoreBackend) auditFlush() {
+	flushed := make(chan struct{})
+
+	t.auditMtx.Lock()
+	if t.auditC == nil {
+		t.auditMtx.Unlock()
+		return
+	}
+	t.auditC <- auditItem{flushed: flushed}
+	t.auditMtx.Unlock()
+
+	<-flushed
+}
+
+// auditWorker appends the queued audit log entries onto the audit tree. The
+// entries that have been queued while a batch was being appended are appended
+// together as the next batch. The done channel is closed once the queue has
+// been closed and drained.
+func (t *tstoreBackend) auditWorker(c <-chan auditItem, done chan<- struct{}) {
+	defer close(done)
+
+	for item := range c {
+		items := []auditItem{item}
+	drain:
+		for len(items) < auditBatchSize {
+			select {
+			case item, ok := <-c:
+				if !ok {
+					break drain
+				}
+				items = append(items, item)
+			default:
+				break drain
+			}
+		}
+		t.auditSave(items)
+	}
+}
+
+// auditSave appends the entries of the provided audit queue items onto the
+// audit tree and closes the flush channels of the items.
+func (t *tstoreBackend) auditSave(items []auditItem) {
+	entries := make([][]byte, 0, len(items))
+	for _, v := range items {
+		if v.entry != nil {
+			entries = append(entries, v.entry)
+		}
+	}
+	if len(entries) > 0 {
+		var err error
+		for i := 0; i < auditRetries; i++ {
+			err = t.tstore.AuditAppend(entries)
+			if errors.Is(err, backend.ErrDuplicatePayload) {
+				// The entries were appended by a previous attempt
+				// whose reply was not received.
+				err = nil
+			}
+			if err == nil {
+				break
+			}
+			log.Errorf("Audit append %v entries: %v", len(entries), err)
+			if i < auditRetries-1 {
+				time.Sleep(auditRetryInterval)
+			}
+		}
+		if err != nil {
+			for _, v := range entries {
+				log.Errorf("Audit entry dropped: %s", v)
+			}
+		}
+	}
+	for _, v := range items {
+		if v.flushed != nil {
+			close(v.flushed)
+		}
+	}
+}
+
+// auditMatch returns whether an audit log entry matches the filter.
+func auditMatch(e backend.AuditEntry, f backend.AuditFilter) bool {
+	switch {
+	case f.Route != "" && f.Route != e.Route:
+		return false
+	case f.Credential != "" && f.Credential != e.Credential:
+		return false
+	case f.Token != "" && f.Token != e.Token:
+		return false
+	case f.PluginID != "" && f.PluginID != e.PluginID:
+		return false
+	case f.Start != 0 && e.Timestamp < f.Start:
+		return false
+	case f.End != 0 && e.Timestamp > f.End:
+		return false
+	}
+	return true
+}
+
+// AuditAppend queues an entry to be appended onto the audit log. The entry
+// is appended onto the audit tree asynchronously by the audit worker.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) AuditAppend(e backend.AuditEntry) error {
+	log.Tracef("AuditAppend: %v %v", e.Route, e.Token)
+
+	if t.isShutdown() {
+		return backend.ErrShutdown
+	}
+
+	t.auditMtx.Lock()
+	defer t.auditMtx.Unlock()
+
+	if t.auditC == nil {
+		return backend.ErrShutdown
+	}
+	e.Sequence = t.auditSeq + 1
+	e.Timestamp = time.Now().Unix()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	t.auditC <- auditItem{entry: b}
+	t.auditSeq = e.Sequence
+
+	return nil
+}
+
+// Audit returns the audit log entries that match the filter, ordered by
+// sequence number. At most limit entries are returned. The entries are read
+// from the audit tree. Entries that are still queued are not returned.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) Audit(f backend.AuditFilter, limit uint32) ([]backend.AuditEntry, error) {
+	log.Tracef("Audit: %+v %v", f, limit)
+
+	all, _, err := t.auditEntries()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]backend.AuditEntry, 0, limit)
+	for _, v := range all {
+		if len(entries) == int(limit) {
+			break
+		}
+		if v.Sequence <= f.After || !auditMatch(v, f) {
+			continue
+		}
+		entries = append(entries, v)
+	}
+
+	return entries, nil
+}
+
+// AuditTimestamps returns the timestamps of the audit log entries with the
+// provided sequence numbers. The sequence numbers that do not correspond to an
+// audit log entry of the audit tree are not included in the returned map.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) AuditTimestamps(seqs []uint64) (map[uint64]backend.Timestamp, error) {
+	log.Tracef("AuditTimestamps: %v", seqs)
+
+	_, encoded, err := t.auditEntries()
+	if err != nil {
+		return nil, err
+	}
+	digests := make(map[string]uint64, len(seqs))
+	ds := make([]string, 0, len(seqs))
+	for _, seq := range seqs {
+		b, ok := encoded[seq]
+		if !ok {
+			continue
+		}
+		d := hex.EncodeToString(util.Digest(b))
+		digests[d] = seq
+		ds = append(ds, d)
+	}
+	ts, err := t.tstore.AuditTimestamps(ds)
+	if err != nil {
+		return nil, err
+	}
+	timestamps := make(map[uint64]backend.Timestamp, len(ts))
+	for d, v := range ts {
+		timestamps[digests[d]] = *v
+	}
+
+	return timestamps, nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstorebe
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// The change log is saved to the key-value store. Each change is saved under
+// a key that contains its sequence number, zero padded so that the keys sort
+// in sequence order. The sequence number of the most recent change is saved
+// alongside every change so that it can be restored on startup.
+const (
+	// changeKeyPrefix is the key-value store key prefix for a change
+	// log entry. The zero padded sequence number is appended onto it.
+	changeKeyPrefix = "tstorebe-change-"
+
+	// changeSeqKey is the key-value store key for the sequence number
+	// of the most recent change.
+	changeSeqKey = "tstorebe-changeseq"
+)
+
+// buildChangeKey returns the key-value store key for a change log entry.
+func buildChangeKey(seq uint64) string {
+	return fmt.Sprintf("%v%020d", changeKeyPrefix, seq)
+}
+
+// changesSetup restores the sequence number of the most recent change from
+// the key-value store.
+func (t *tstoreBackend) changesSetup() error {
+	blobs, err := t.tstore.CacheGet([]string{changeSeqKey})
+	if err != nil {
+		return err
+	}
+	var seq uint64
+	if b, ok := blobs[changeSeqKey]; ok {
+		seq, err = strconv.ParseUint(string(b), 10, 64)
+		if err != nil {
+			return fmt.Errorf("parse change sequence: %v", err)
+		}
+	}
+
+	t.changesMtx.Lock()
+	defer t.changesMtx.Unlock()
+
+	t.changeSeq = seq
+	t.changesC = make(chan struct{})
+
+	log.Infof("Change log sequence: %v", seq)
+
+	return nil
+}
+
+// changeAppend appends a change onto the change log and notifies the callers
+// that are waiting on new changes. It must be called once the change has been
+// committed. The write that the change describes has already been made at
+// this point, so an error is logged instead of being returned.
+func (t *tstoreBackend) changeAppend(c backend.Change) {
+	t.changesMtx.Lock()
+	defer t.changesMtx.Unlock()
+
+	c.Sequence = t.changeSeq + 1
+	c.Timestamp = time.Now().Unix()
+	b, err := json.Marshal(c)
+	if err != nil {
+		log.Errorf("changeAppend %v: %v", c.Token, err)
+		return
+	}
+	err = t.tstore.CachePut(map[string][]byte{
+		buildChangeKey(c.Sequence): b,
+		changeSeqKey:               []byte(strconv.FormatUint(c.Sequence, 10)),
+	})
+	if err != nil {
+		log.Errorf("changeAppend %v %v: %v", c.Token,
+			backend.Changes[c.Type], err)
+		return
+	}
+	t.changeSeq = c.Sequence
+
+	// Notify the waiting callers
+	close(t.changesC)
+	t.changesC = make(chan struct{})
+}
+
+// changeRecord returns a change of the provided type for a record.
+func changeRecord(ct backend.ChangeT, rm backend.RecordMetadata) backend.Change {
+	return backend.Change{
+		Type:      ct,
+		Token:     rm.Token,
+		State:     rm.State,
+		Status:    rm.Status,
+		Version:   rm.Version,
+		Iteration: rm.Iteration,
+	}
+}
+
+// changePluginWrite returns the change for a plugin write.
+func changePluginWrite(token []byte, pluginID, pluginCmd string) backend.Change {
+	return backend.Change{
+		Type:      backend.ChangePluginWrite,
+		Token:     hex.EncodeToString(token),
+		PluginID:  pluginID,
+		PluginCmd: pluginCmd,
+	}
+}
+
+// Changes returns the entries of the change log that have a sequence number
+// greater than the provided sequence number, ordered by sequence number. At
+// most limit entries are returned.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) Changes(since uint64, limit uint32) ([]backend.Change, error) {
+	log.Tracef("Changes: %v %v", since, limit)
+
+	t.changesMtx.Lock()
+	last := t.changeSeq
+	t.changesMtx.Unlock()
+
+	if since >= last || limit == 0 {
+		return []backend.Change{}, nil
+	}
+	end := last
+	if end-since > uint64(limit) {
+		end = since + uint64(limit)
+	}
+
+	// Get the changes
+	keys := make([]string, 0, end-since)
+	for seq := since + 1; seq <= end; seq++ {
+		keys = append(keys, buildChangeKey(seq))
+	}
+	blobs, err := t.tstore.CacheGet(keys)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]backend.Change, 0, len(keys))
+	for _, k := range keys {
+		b, ok := blobs[k]
+		if !ok {
+			// Should not happen
+			return nil, fmt.Errorf("change not found %v", k)
+		}
+		var c backend.Change
+		err = json.Unmarshal(b, &c)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// ChangesNotify returns a channel that is closed the next time a change is
+// appended onto the change log.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) ChangesNotify() <-chan struct{} {
+	t.changesMtx.Lock()
+	defer t.changesMtx.Unlock()
+
+	return t.changesC
+}
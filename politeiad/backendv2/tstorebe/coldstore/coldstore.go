@@ -0,0 +1,61 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coldstore
+
+import (
+	"strings"
+)
+
+const (
+	// TypeFS is the type of a cold store that saves blobs as files in a
+	// filesystem directory.
+	TypeFS = "fs"
+
+	// TypeS3 is the type of a cold store that saves blobs as objects in
+	// a bucket of an S3 compatible object storage service.
+	TypeS3 = "s3"
+)
+
+// Store represents a cold blob store. Cold stores contain the blobs of the
+// record trees that have been frozen and archived. They are written to once
+// and are read from infrequently, so they are optimized for capacity instead
+// of latency.
+//
+// Keys consist of alphanumeric characters and the '-', '_', '.' and '/'
+// characters. The '/' character separates the segments of a key.
+type Store interface {
+	// Put saves the provided blobs. Existing blobs are overwritten.
+	Put(blobs map[string][]byte) error
+
+	// Get returns the blobs for the provided keys. An entry will not
+	// exist in the returned map for any blobs that are not found.
+	Get(keys []string) (map[string][]byte, error)
+
+	// Del deletes the blobs for the provided keys. Keys that do not
+	// exist are ignored.
+	Del(keys []string) error
+}
+
+// isValidKey returns whether the provided key is a valid cold store key.
+func isValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") ||
+		strings.HasSuffix(key, "/") {
+		return false
+	}
+	for _, v := range strings.Split(key, "/") {
+		if v == "" || v == "." || v == ".." {
+			return false
+		}
+	}
+	for _, c := range key {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == '/':
+		default:
+			return false
+		}
+	}
+	return true
+}
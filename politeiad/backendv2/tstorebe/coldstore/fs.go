@@ -0,0 +1,136 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coldstore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var (
+	_ Store = (*fsStore)(nil)
+)
+
+// fsStore is a cold store that saves each blob as a file in a filesystem
+// directory. The segments of a key are used as the subdirectories of the
+// file.
+//
+// fsStore satisfies the Store interface.
+type fsStore struct {
+	dir string
+}
+
+// path returns the file path for the provided key.
+func (s *fsStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+// Put saves the provided blobs. Each blob is written to a temporary file that
+// is renamed once it has been synced to disk, so a blob is never partially
+// written.
+//
+// This function satisfies the Store interface.
+func (s *fsStore) Put(blobs map[string][]byte) error {
+	log.Tracef("fs Put: %v blobs", len(blobs))
+
+	for k := range blobs {
+		if !isValidKey(k) {
+			return fmt.Errorf("invalid key: %v", k)
+		}
+	}
+	for k, v := range blobs {
+		fp := s.path(k)
+		err := os.MkdirAll(filepath.Dir(fp), 0700)
+		if err != nil {
+			return err
+		}
+		err = writeFileSync(fp, v)
+		if err != nil {
+			return fmt.Errorf("write %v: %v", k, err)
+		}
+	}
+
+	return nil
+}
+
+// Get returns the blobs for the provided keys.
+//
+// This function satisfies the Store interface.
+func (s *fsStore) Get(keys []string) (map[string][]byte, error) {
+	log.Tracef("fs Get: %v", keys)
+
+	blobs := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		if !isValidKey(k) {
+			return nil, fmt.Errorf("invalid key: %v", k)
+		}
+		b, err := os.ReadFile(s.path(k))
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// Blob does not exist
+			continue
+		case err != nil:
+			return nil, err
+		}
+		blobs[k] = b
+	}
+
+	return blobs, nil
+}
+
+// Del deletes the blobs for the provided keys.
+//
+// This function satisfies the Store interface.
+func (s *fsStore) Del(keys []string) error {
+	log.Tracef("fs Del: %v", keys)
+
+	for _, k := range keys {
+		if !isValidKey(k) {
+			return fmt.Errorf("invalid key: %v", k)
+		}
+		err := os.Remove(s.path(k))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFileSync writes the data to the provided file path atomically.
+func writeFileSync(fp string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(fp), ".tmp-")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, fp)
+}
+
+// NewFS returns a new Store that saves blobs in the provided directory. The
+// directory is created if it does not exist.
+func NewFS(dir string) (Store, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+	return &fsStore{
+		dir: dir,
+	}, nil
+}
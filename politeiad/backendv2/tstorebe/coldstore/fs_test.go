@@ -0,0 +1,83 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coldstore
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// testStore runs the tests that are common to all Store implementations.
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+
+	blobs := map[string][]byte{
+		"0123456789abcdef/key1":   []byte("blob1"),
+		"0123456789abcdef/key2":   []byte("blob2"),
+		"fedcba9876543210/e_key3": []byte("blob3"),
+	}
+	err := s.Put(blobs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Get the blobs. Blobs that do not exist are not returned.
+	keys := []string{"0123456789abcdef/key1", "0123456789abcdef/key2",
+		"fedcba9876543210/e_key3", "0123456789abcdef/key4"}
+	got, err := s.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(blobs) {
+		t.Fatalf("got %v blobs, want %v", len(got), len(blobs))
+	}
+	for k, v := range blobs {
+		if !bytes.Equal(got[k], v) {
+			t.Fatalf("blob %v: got %s, want %s", k, got[k], v)
+		}
+	}
+
+	// Delete a blob. Deleting a blob that does not exist is not an
+	// error.
+	err = s.Del([]string{"0123456789abcdef/key1", "0123456789abcdef/key4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got["0123456789abcdef/key1"]; ok || len(got) != 2 {
+		t.Fatalf("blob was not deleted")
+	}
+
+	// Invalid keys are rejected
+	invalid := []string{"", "/key", "key/", "a//b", "a/../b", "a b"}
+	for _, v := range invalid {
+		err = s.Put(map[string][]byte{v: []byte("blob")})
+		if err == nil {
+			t.Fatalf("Put succeeded with invalid key '%v'", v)
+		}
+		_, err = s.Get([]string{v})
+		if err == nil {
+			t.Fatalf("Get succeeded with invalid key '%v'", v)
+		}
+	}
+}
+
+func TestFS(t *testing.T) {
+	dir, err := os.MkdirTemp("", "coldstore.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewFS(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+}
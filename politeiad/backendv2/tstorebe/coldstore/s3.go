@@ -0,0 +1,268 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coldstore
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// s3Service is the service name that is used to sign requests.
+	s3Service = "s3"
+
+	// s3Algorithm is the signing algorithm of the requests.
+	s3Algorithm = "AWS4-HMAC-SHA256"
+
+	// s3TimeFormat is the format of the request timestamp.
+	s3TimeFormat = "20060102T150405Z"
+
+	// s3DateFormat is the format of the date in the credential scope.
+	s3DateFormat = "20060102"
+)
+
+var (
+	_ Store = (*s3Store)(nil)
+)
+
+// s3Store is a cold store that saves each blob as an object in a bucket of an
+// S3 compatible object storage service. Objects are addressed using path
+// style URLs, i.e. <endpoint>/<bucket>/<key>, which are supported by most S3
+// compatible services. Requests are signed using AWS Signature Version 4.
+//
+// s3Store satisfies the Store interface.
+type s3Store struct {
+	endpoint  *url.URL
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	http      *http.Client
+}
+
+// objectURL returns the URL of the object for the provided key.
+func (s *s3Store) objectURL(key string) string {
+	u := *s.endpoint
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.bucket + "/" + key
+	return u.String()
+}
+
+// do sends a signed request for the object with the provided key and returns
+// the response status code and body.
+func (s *s3Store) do(method, key string, body []byte) (int, []byte, error) {
+	req, err := http.NewRequest(method, s.objectURL(key),
+		bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	digest := sha256.Sum256(body)
+	s3Sign(req, hex.EncodeToString(digest[:]), time.Now(),
+		s.region, s.accessKey, s.secretKey)
+
+	log.Tracef("%v %v", method, req.URL)
+
+	r, err := s.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer r.Body.Close()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return r.StatusCode, b, nil
+}
+
+// Put saves the provided blobs as objects.
+//
+// This function satisfies the Store interface.
+func (s *s3Store) Put(blobs map[string][]byte) error {
+	log.Tracef("s3 Put: %v blobs", len(blobs))
+
+	for k := range blobs {
+		if !isValidKey(k) {
+			return fmt.Errorf("invalid key: %v", k)
+		}
+	}
+	for k, v := range blobs {
+		code, b, err := s.do(http.MethodPut, k, v)
+		if err != nil {
+			return fmt.Errorf("put %v: %v", k, err)
+		}
+		if code != http.StatusOK {
+			return fmt.Errorf("put %v: %v %s", k, code, b)
+		}
+	}
+
+	return nil
+}
+
+// Get returns the objects for the provided keys.
+//
+// This function satisfies the Store interface.
+func (s *s3Store) Get(keys []string) (map[string][]byte, error) {
+	log.Tracef("s3 Get: %v", keys)
+
+	blobs := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		if !isValidKey(k) {
+			return nil, fmt.Errorf("invalid key: %v", k)
+		}
+		code, b, err := s.do(http.MethodGet, k, nil)
+		if err != nil {
+			return nil, fmt.Errorf("get %v: %v", k, err)
+		}
+		switch code {
+		case http.StatusOK:
+			blobs[k] = b
+		case http.StatusNotFound:
+			// Object does not exist
+		default:
+			return nil, fmt.Errorf("get %v: %v %s", k, code, b)
+		}
+	}
+
+	return blobs, nil
+}
+
+// Del deletes the objects for the provided keys.
+//
+// This function satisfies the Store interface.
+func (s *s3Store) Del(keys []string) error {
+	log.Tracef("s3 Del: %v", keys)
+
+	for _, k := range keys {
+		if !isValidKey(k) {
+			return fmt.Errorf("invalid key: %v", k)
+		}
+		code, b, err := s.do(http.MethodDelete, k, nil)
+		if err != nil {
+			return fmt.Errorf("del %v: %v", k, err)
+		}
+		switch code {
+		case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+			// We're good; continue
+		default:
+			return fmt.Errorf("del %v: %v %s", k, code, b)
+		}
+	}
+
+	return nil
+}
+
+// s3Sign signs the provided request using AWS Signature Version 4. The host
+// and all headers that have been set on the request are signed. Headers that
+// are set on the request after it has been signed are not part of the
+// signature.
+func s3Sign(req *http.Request, payloadHash string, now time.Time, region, accessKey, secretKey string) {
+	var (
+		timestamp = now.UTC().Format(s3TimeFormat)
+		date      = now.UTC().Format(s3DateFormat)
+		scope     = date + "/" + region + "/" + s3Service + "/aws4_request"
+	)
+	req.Header.Set("X-Amz-Date", timestamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	// Setup the canonical headers
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{
+		"host": host,
+	}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, v := range names {
+		canonicalHeaders.WriteString(v + ":" + headers[v] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	// Setup the string to sign
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	crDigest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		timestamp,
+		scope,
+		hex.EncodeToString(crDigest[:]),
+	}, "\n")
+
+	// Derive the signing key and sign
+	key := s3HMAC([]byte("AWS4"+secretKey), date)
+	key = s3HMAC(key, region)
+	key = s3HMAC(key, s3Service)
+	key = s3HMAC(key, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%v Credential=%v/%v, "+
+		"SignedHeaders=%v, Signature=%v", s3Algorithm, accessKey, scope,
+		signedHeaders, signature))
+}
+
+// s3HMAC returns the HMAC-SHA256 of the provided data.
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// NewS3 returns a new Store that saves blobs as objects in the provided bucket
+// of an S3 compatible object storage service. The endpoint is the URL of the
+// service, e.g. https://s3.us-east-1.amazonaws.com. The cert path is the path
+// to the HTTPS certificate of the service and is optional.
+func NewS3(endpoint, bucket, region, accessKey, secretKey, certPath string) (Store, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parse s3 endpoint '%v': %v", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid s3 endpoint scheme '%v'", u.Scheme)
+	}
+	if bucket == "" || strings.Contains(bucket, "/") {
+		return nil, fmt.Errorf("invalid s3 bucket '%v'", bucket)
+	}
+	c, err := util.NewHTTPClient(false, certPath)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{
+		endpoint:  u,
+		bucket:    bucket,
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		http:      c,
+	}, nil
+}
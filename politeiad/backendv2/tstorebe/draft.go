@@ -0,0 +1,262 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstorebe
+
+import (
+	"encoding/json"
+	"fmt"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
+)
+
+// DraftNew creates a new draft record. Drafts are saved encrypted to the
+// key-value store and are never appended onto a tlog tree, so they are not
+// timestamped. The draft is validated by the new draft plugin hooks, not the
+// new record plugin hooks. The new record validation is performed once the
+// draft is promoted.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) DraftNew(metadata []backend.MetadataStream, files []backend.File) (*backend.Record, error) {
+	log.Tracef("DraftNew: %v metadata, %v files", len(metadata), len(files))
+
+	// Verify draft content
+	err := metadataStreamsVerify(metadata)
+	if err != nil {
+		return nil, err
+	}
+	err = filesVerify(files, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call pre plugin hooks
+	pre := plugins.HookNewRecordPre{
+		Metadata: metadata,
+		Files:    files,
+	}
+	b, err := json.Marshal(pre)
+	if err != nil {
+		return nil, err
+	}
+	err = t.tstore.PluginHookPre(plugins.HookTypeNewDraftPre, string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new token
+	if t.isShutdown() {
+		return nil, backend.ErrShutdown
+	}
+	token, err := t.tstore.DraftToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Save the draft
+	rm, err := recordMetadataNew(token, files, nil, backend.StateDraft,
+		backend.StatusUnreviewed, 1, 1)
+	if err != nil {
+		return nil, err
+	}
+	r := backend.Record{
+		RecordMetadata: *rm,
+		Metadata:       metadata,
+		Files:          files,
+	}
+	err = t.tstore.DraftSave(r)
+	if err != nil {
+		return nil, fmt.Errorf("DraftSave: %v", err)
+	}
+
+	// Call post plugin hooks
+	post := plugins.HookNewRecordPost{
+		Metadata:       metadata,
+		Files:          files,
+		RecordMetadata: *rm,
+	}
+	b, err = json.Marshal(post)
+	if err != nil {
+		return nil, err
+	}
+	t.tstore.WriteBegin()
+	t.tstore.PluginHookPost(plugins.HookTypeNewDraftPost, string(b))
+	t.tstore.WriteEnd()
+
+	return &r, nil
+}
+
+// DraftEdit edits a draft record. Unlike a record edit, the draft is updated
+// in place. The version of the draft is incremented, but the previous version
+// is not retained.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) DraftEdit(token []byte, mdAppend, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
+	log.Tracef("DraftEdit: %x", token)
+
+	// Verify draft contents. Send in a single metadata array to
+	// verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	err := metadataStreamsVerify(allMD)
+	if err != nil {
+		return nil, err
+	}
+	err = filesVerify(filesAdd, filesDel)
+	if err != nil {
+		return nil, err
+	}
+
+	// The draft lock must be held for the remainder of the function
+	if t.isShutdown() {
+		return nil, backend.ErrShutdown
+	}
+	defer t.recordLock(token)()
+
+	// Get existing draft
+	d, err := t.tstore.Draft(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply changes
+	var (
+		rm       = d.RecordMetadata
+		metadata = metadataStreamsUpdate(d.Metadata, mdAppend, mdOverwrite)
+		files    = filesUpdate(d.Files, filesAdd, filesDel)
+	)
+	recordMD, err := recordMetadataNew(token, files, nil, backend.StateDraft,
+		rm.Status, rm.Version+1, rm.Iteration+1)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify that changes are being made. Drafts can be edited
+	// freely, so a metadata only change is allowed.
+	if rm.Merkle == recordMD.Merkle && len(allMD) == 0 {
+		return nil, backend.ErrNoRecordChanges
+	}
+
+	// Call pre plugin hooks
+	her := plugins.HookEditRecord{
+		Record:         *d,
+		RecordMetadata: *recordMD,
+		Metadata:       metadata,
+		Files:          files,
+	}
+	b, err := json.Marshal(her)
+	if err != nil {
+		return nil, err
+	}
+	err = t.tstore.PluginHookPre(plugins.HookTypeEditDraftPre, string(b))
+	if err != nil {
+		return nil, err
+	}
+
+	// Save draft
+	r := backend.Record{
+		RecordMetadata: *recordMD,
+		Metadata:       metadata,
+		Files:          files,
+	}
+	err = t.tstore.DraftSave(r)
+	if err != nil {
+		return nil, fmt.Errorf("DraftSave: %v", err)
+	}
+
+	return &r, nil
+}
+
+// DraftDel deletes a draft record.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) DraftDel(token []byte) error {
+	log.Tracef("DraftDel: %x", token)
+
+	if t.isShutdown() {
+		return backend.ErrShutdown
+	}
+	defer t.recordLock(token)()
+
+	d, err := t.tstore.Draft(token)
+	if err != nil {
+		return err
+	}
+
+	return t.draftDel(token, *d)
+}
+
+// draftDel deletes a draft record and executes the post del draft plugin
+// hooks.
+//
+// This function must be called WITH the draft lock held.
+func (t *tstoreBackend) draftDel(token []byte, d backend.Record) error {
+	err := t.tstore.DraftDel(token)
+	if err != nil {
+		return err
+	}
+
+	// Call post plugin hooks
+	hdd := plugins.HookDelDraft{
+		RecordMetadata: d.RecordMetadata,
+		Metadata:       d.Metadata,
+	}
+	b, err := json.Marshal(hdd)
+	if err != nil {
+		return err
+	}
+	t.tstore.PluginHookPost(plugins.HookTypeDelDraftPost, string(b))
+
+	return nil
+}
+
+// Draft returns a draft record.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) Draft(token []byte) (*backend.Record, error) {
+	log.Tracef("Draft: %x", token)
+
+	return t.tstore.Draft(token)
+}
+
+// DraftPromote submits a draft as a new unvetted record. The draft goes
+// through the same validation as any other new record. The draft is deleted
+// once the record has been created. The draft is left untouched if the record
+// can not be created.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) DraftPromote(token []byte) (*backend.Record, error) {
+	log.Tracef("DraftPromote: %x", token)
+
+	// The draft lock must be held for the remainder of the function
+	// so that the draft can not be promoted twice.
+	if t.isShutdown() {
+		return nil, backend.ErrShutdown
+	}
+	defer t.recordLock(token)()
+
+	d, err := t.tstore.Draft(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the record
+	r, err := t.RecordNew(d.Metadata, d.Files)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Draft %x promoted to record %v", token,
+		r.RecordMetadata.Token)
+
+	// Delete the draft. The record has already been created at this
+	// point so an error is only logged. The author can delete the
+	// draft manually.
+	err = t.draftDel(token, *d)
+	if err != nil {
+		log.Errorf("draftDel %x: %v", token, err)
+	}
+
+	return r, nil
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2021 The Decred developers
+// Copyright (c) 2020-2022 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -11,116 +11,241 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	backend "github.com/decred/politeia/politeiad/backendv2"
 )
 
+// The record inventory is saved to the key-value store as an index that is
+// ordered by record state, record status, and the timestamp of the most
+// recent status change. Each record has an inventory entry and two index
+// keys: one in the index for its status and one in the index for all
+// statuses of its state. The index keys sort in ascending order from the
+// newest to the oldest status change so that a page of the inventory can be
+// retrieved using a single key range lookup. Updating the inventory of a
+// record only touches the keys of that record and the counters of its old and
+// new status.
 const (
-	// Filenames of the inventory caches.
+	// invKeyPrefix is the key-value store key prefix for all inventory
+	// keys.
+	invKeyPrefix = "tstorebe-inv-"
+
+	// invEntryKey is the key-value store key for the inventory entry of
+	// a record. The "{token}" is replaced with the hex encoded full
+	// length record token.
+	invEntryKey       = invEntryKeyPrefix + "{token}"
+	invEntryKeyPrefix = invKeyPrefix + "entry-"
+
+	// invIndexKey is the key-value store key for an inventory index
+	// entry. The state and status are two digit decimal values. A status
+	// of 00 is used for the index that contains all statuses. The
+	// timestamp is the bitwise complement of the unix nano timestamp of
+	// the status change, encoded as 16 hex characters, so that newer
+	// entries sort first.
+	invIndexKey       = invIndexKeyPrefix + "{state}-{status}-{timestamp}-{token}"
+	invIndexKeyPrefix = invKeyPrefix + "index-"
+
+	// invCountKey is the key-value store key for the number of records
+	// in the inventory that have the state and status. The state and
+	// status are formatted the same way as in the index keys. The
+	// counters do not share the inventory key prefix since they do not
+	// belong to a single record.
+	invCountKey       = invCountKeyPrefix + "{state}-{status}"
+	invCountKeyPrefix = "tstorebe-invcount-"
+
+	// Filenames of the legacy inventory caches. The inventory used to be
+	// saved to these JSON files. They are only read by the inventory
+	// migration.
 	filenameInvUnvetted = "inv-unvetted.json"
 	filenameInvVetted   = "inv-vetted.json"
 )
 
 // entry represents a record entry in the inventory.
 type entry struct {
-	Token  string          `json:"token"`
-	Status backend.StatusT `json:"status"`
+	Token     string          `json:"token"`
+	State     backend.StateT  `json:"state"`
+	Status    backend.StatusT `json:"status"`
+	Timestamp int64           `json:"timestamp"` // Unix nano of status change
 }
 
-// inventory represents the record inventory.
-type inventory struct {
-	Entries []entry `json:"entries"`
+// indexKeys returns the inventory index keys of the entry.
+func (e *entry) indexKeys() []string {
+	return []string{
+		buildInvIndexKey(e.State, e.Status, e.Timestamp, e.Token),
+		buildInvIndexKey(e.State, backend.StatusInvalid, e.Timestamp, e.Token),
+	}
 }
 
-// invPathUnvetted returns the file path for the unvetted inventory.
-func (t *tstoreBackend) invPathUnvetted() string {
-	return filepath.Join(t.dataDir, filenameInvUnvetted)
+// buildInvEntryKey returns the key-value store key for the inventory entry of
+// a record.
+func buildInvEntryKey(token string) string {
+	return strings.Replace(invEntryKey, "{token}", token, 1)
 }
 
-// invPathVetted returns the file path for the vetted inventory.
-func (t *tstoreBackend) invPathVetted() string {
-	return filepath.Join(t.dataDir, filenameInvVetted)
+// buildInvIndexPrefix returns the key prefix of the inventory index for the
+// provided state and status.
+func buildInvIndexPrefix(state backend.StateT, s backend.StatusT) string {
+	r := strings.NewReplacer(
+		"{state}", fmt.Sprintf("%02d", state),
+		"{status}", fmt.Sprintf("%02d", s),
+	)
+	k := r.Replace(invIndexKey)
+	return k[:strings.Index(k, "{timestamp}")]
 }
 
-// invRemoveUnvetted removes the unvetted inventory from its respective path.
-//
-// This function must be called WITHOUT the write lock held.
-func (t *tstoreBackend) invRemoveUnvetted() error {
-	t.Lock()
-	defer t.Unlock()
-
-	return os.RemoveAll(t.invPathUnvetted())
+// buildInvIndexKey returns the key-value store key for an inventory index
+// entry.
+func buildInvIndexKey(state backend.StateT, s backend.StatusT, timestamp int64, token string) string {
+	return fmt.Sprintf("%v%016x-%v", buildInvIndexPrefix(state, s),
+		^uint64(timestamp), token)
 }
 
-// invRemoveVetted removes the vetted inventory from its respective path.
-//
-// This function must be called WITHOUT the write lock held.
-func (t *tstoreBackend) invRemoveVetted() error {
-	t.Lock()
-	defer t.Unlock()
+// buildInvCountKey returns the key-value store key for the inventory counter of
+// the provided state and status.
+func buildInvCountKey(state backend.StateT, s backend.StatusT) string {
+	r := strings.NewReplacer(
+		"{state}", fmt.Sprintf("%02d", state),
+		"{status}", fmt.Sprintf("%02d", s),
+	)
+	return r.Replace(invCountKey)
+}
 
-	return os.RemoveAll(t.invPathVetted())
+// invIndexKeyToken returns the token of an inventory index key.
+func invIndexKeyToken(key string) string {
+	return key[strings.LastIndex(key, "-")+1:]
 }
 
-// invGetLocked retrieves the inventory from disk. A new inventory is returned
-// if one does not exist yet.
+// invEntriesLocked returns the inventory entries for the provided hex encoded
+// tokens. Tokens that do not have an inventory entry are not included in the
+// returned map.
 //
 // This function must be called WITH the read lock held.
-func (t *tstoreBackend) invGetLocked(filePath string) (*inventory, error) {
-	b, err := os.ReadFile(filePath)
+func (t *tstoreBackend) invEntriesLocked(tokens []string) (map[string]entry, error) {
+	keys := make([]string, 0, len(tokens))
+	for _, v := range tokens {
+		keys = append(keys, buildInvEntryKey(v))
+	}
+	blobs, err := t.tstore.CacheGet(keys)
 	if err != nil {
-		var e *os.PathError
-		if errors.As(err, &e) && !os.IsExist(err) {
-			// File does't exist. Return a new inventory.
-			return &inventory{
-				Entries: make([]entry, 0, 1024),
-			}, nil
-		}
 		return nil, err
 	}
+	entries := make(map[string]entry, len(blobs))
+	for _, b := range blobs {
+		var e entry
+		err = json.Unmarshal(b, &e)
+		if err != nil {
+			return nil, err
+		}
+		entries[e.Token] = e
+	}
+	return entries, nil
+}
 
-	var inv inventory
-	err = json.Unmarshal(b, &inv)
+// invCountsGetLocked returns the inventory counters for the provided counter
+// keys. A counter that does not exist is returned as 0.
+//
+// This function must be called WITH the read lock held.
+func (t *tstoreBackend) invCountsGetLocked(keys []string) (map[string]int, error) {
+	blobs, err := t.tstore.CacheGet(keys)
 	if err != nil {
 		return nil, err
 	}
-
-	return &inv, nil
+	counts := make(map[string]int, len(keys))
+	for _, k := range keys {
+		b, ok := blobs[k]
+		if !ok {
+			counts[k] = 0
+			continue
+		}
+		c, err := strconv.Atoi(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("invalid counter %v: %v", k, err)
+		}
+		counts[k] = c
+	}
+	return counts, nil
 }
 
-// invGet retrieves the inventory from disk. A new inventory is returned if one
-// does not exist yet.
+// invPutLocked saves the inventory entry of a record and moves the record to
+// the position in the inventory indexes that corresponds to the entry. The
+// existing index keys of the record are deleted.
 //
-// This function must be called WITHOUT the read lock held.
-func (t *tstoreBackend) invGet(filePath string) (*inventory, error) {
-	t.RLock()
-	defer t.RUnlock()
-
-	return t.invGetLocked(filePath)
-}
-
-// invSaveLocked writes the inventory to disk.
+// The new entry, the index keys and the counters of the new and the previous
+// status are saved atomically before the existing index keys are deleted. If
+// the deletion fails the stale index keys are ignored by the inventory lookups
+// since they do not match the saved entry.
 //
 // This function must be called WITH the read/write lock held.
-func (t *tstoreBackend) invSaveLocked(filePath string, inv inventory) error {
-	b, err := json.Marshal(inv)
+func (t *tstoreBackend) invPutLocked(e entry) error {
+	entries, err := t.invEntriesLocked([]string{e.Token})
+	if err != nil {
+		return err
+	}
+
+	// Save the new entry
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	blobs := map[string][]byte{
+		buildInvEntryKey(e.Token): b,
+	}
+	for _, k := range e.indexKeys() {
+		blobs[k] = []byte{}
+	}
+
+	// Move the record to the counter of its new status
+	prev, ok := entries[e.Token]
+	if !ok || prev.State != e.State || prev.Status != e.Status {
+		newKey := buildInvCountKey(e.State, e.Status)
+		keys := []string{newKey}
+		if ok {
+			keys = append(keys, buildInvCountKey(prev.State, prev.Status))
+		}
+		counts, err := t.invCountsGetLocked(keys)
+		if err != nil {
+			return err
+		}
+		counts[newKey]++
+		if ok {
+			counts[keys[1]]--
+		}
+		for k, v := range counts {
+			blobs[k] = []byte(strconv.Itoa(v))
+		}
+	}
+
+	err = t.tstore.CachePut(blobs)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filePath, b, 0664)
+
+	// Delete the existing index keys
+	if !ok {
+		return nil
+	}
+	del := make([]string, 0, 2)
+	for _, k := range prev.indexKeys() {
+		if _, ok := blobs[k]; !ok {
+			del = append(del, k)
+		}
+	}
+	if len(del) == 0 {
+		return nil
+	}
+	return t.tstore.CacheDel(del)
 }
 
 // invAdd adds a new record to the inventory.
 //
 // This function must be called WITHOUT the read/write lock held.
 func (t *tstoreBackend) invAdd(state backend.StateT, token []byte, s backend.StatusT) error {
-	// Get inventory file path
-	var fp string
 	switch state {
-	case backend.StateUnvetted:
-		fp = t.invPathUnvetted()
-	case backend.StateVetted:
-		fp = t.invPathVetted()
+	case backend.StateUnvetted, backend.StateVetted:
+		// These are allowed
 	default:
 		return fmt.Errorf("invalid state %v", state)
 	}
@@ -128,21 +253,12 @@ func (t *tstoreBackend) invAdd(state backend.StateT, token []byte, s backend.Sta
 	t.Lock()
 	defer t.Unlock()
 
-	// Get inventory
-	inv, err := t.invGetLocked(fp)
-	if err != nil {
-		return err
-	}
-
-	// Prepend token
-	e := entry{
-		Token:  hex.EncodeToString(token),
-		Status: s,
-	}
-	inv.Entries = append([]entry{e}, inv.Entries...)
-
-	// Save inventory
-	err = t.invSaveLocked(fp, *inv)
+	err := t.invPutLocked(entry{
+		Token:     hex.EncodeToString(token),
+		State:     state,
+		Status:    s,
+		Timestamp: time.Now().UnixNano(),
+	})
 	if err != nil {
 		return err
 	}
@@ -158,41 +274,24 @@ func (t *tstoreBackend) invAdd(state backend.StateT, token []byte, s backend.Sta
 //
 // This function must be called WITHOUT the read/write lock held.
 func (t *tstoreBackend) invUpdate(state backend.StateT, token []byte, s backend.StatusT) error {
-	// Get inventory file path
-	var fp string
-	switch state {
-	case backend.StateUnvetted:
-		fp = t.invPathUnvetted()
-	case backend.StateVetted:
-		fp = t.invPathVetted()
-	default:
-		return fmt.Errorf("invalid state %v", state)
-	}
-
 	t.Lock()
 	defer t.Unlock()
 
-	// Get inventory
-	inv, err := t.invGetLocked(fp)
+	// Verify the record exists in the inventory for the state
+	htoken := hex.EncodeToString(token)
+	entries, err := t.invEntriesLocked([]string{htoken})
 	if err != nil {
 		return err
 	}
-
-	// Del entry
-	entries, err := entryDel(inv.Entries, token)
-	if err != nil {
-		return fmt.Errorf("%v entry del: %v", state, err)
-	}
-
-	// Prepend new entry to inventory
-	e := entry{
-		Token:  hex.EncodeToString(token),
-		Status: s,
+	e, ok := entries[htoken]
+	if !ok || e.State != state {
+		return fmt.Errorf("%v token not found %x", state, token)
 	}
-	inv.Entries = append([]entry{e}, entries...)
 
-	// Save inventory
-	err = t.invSaveLocked(fp, *inv)
+	// Update the entry
+	e.Status = s
+	e.Timestamp = time.Now().UnixNano()
+	err = t.invPutLocked(e)
 	if err != nil {
 		return err
 	}
@@ -208,49 +307,27 @@ func (t *tstoreBackend) invUpdate(state backend.StateT, token []byte, s backend.
 //
 // This function must be called WITHOUT the read/write lock held.
 func (t *tstoreBackend) invMoveToVetted(token []byte, s backend.StatusT) error {
-	var (
-		upath = t.invPathUnvetted()
-		vpath = t.invPathVetted()
-	)
-
 	t.Lock()
 	defer t.Unlock()
 
-	// Get unvetted inventory
-	u, err := t.invGetLocked(upath)
-	if err != nil {
-		return fmt.Errorf("unvetted invGetLocked: %v", err)
-	}
-
-	// Del entry
-	u.Entries, err = entryDel(u.Entries, token)
+	// Verify the record exists in the unvetted inventory
+	htoken := hex.EncodeToString(token)
+	entries, err := t.invEntriesLocked([]string{htoken})
 	if err != nil {
-		return fmt.Errorf("entryDel: %v", err)
+		return err
 	}
-
-	// Save unvetted inventory
-	err = t.invSaveLocked(upath, *u)
-	if err != nil {
-		return fmt.Errorf("unvetted invSaveLocked: %v", err)
+	e, ok := entries[htoken]
+	if !ok || e.State != backend.StateUnvetted {
+		return fmt.Errorf("unvetted token not found %x", token)
 	}
 
-	// Get vetted inventory
-	v, err := t.invGetLocked(vpath)
+	// Move the entry to the vetted inventory
+	e.State = backend.StateVetted
+	e.Status = s
+	e.Timestamp = time.Now().UnixNano()
+	err = t.invPutLocked(e)
 	if err != nil {
-		return fmt.Errorf("vetted invGetLocked: %v", err)
-	}
-
-	// Prepend new entry to inventory
-	e := entry{
-		Token:  hex.EncodeToString(token),
-		Status: s,
-	}
-	v.Entries = append([]entry{e}, v.Entries...)
-
-	// Save vetted inventory
-	err = t.invSaveLocked(vpath, *v)
-	if err != nil {
-		return fmt.Errorf("vetted invSaveLocked: %v", err)
+		return err
 	}
 
 	log.Debugf("Inv move to vetted %x %v", token, backend.Statuses[s])
@@ -269,7 +346,7 @@ func (t *tstoreBackend) inventoryAdd(state backend.StateT, token []byte, s backe
 }
 
 // inventoryUpdate is a wrapper around the invUpdate method that allows us to
-// decide how disk read/write errors should be handled. For now we just panic.
+// decide how key-value store errors should be handled. For now we just panic.
 // If an error occurs the cache is no longer coherent and the only way to fix
 // it is to rebuild it.
 func (t *tstoreBackend) inventoryUpdate(state backend.StateT, token []byte, s backend.StatusT) {
@@ -280,7 +357,7 @@ func (t *tstoreBackend) inventoryUpdate(state backend.StateT, token []byte, s ba
 }
 
 // inventoryMoveToVetted is a wrapper around the invMoveToVetted method that
-// allows us to decide how disk read/write errors should be handled. For now we
+// allows us to decide how key-value store errors should be handled. For now we
 // just panic. If an error occurs the cache is no longer coherent and the only
 // way to fix it is to rebuild it.
 func (t *tstoreBackend) inventoryMoveToVetted(token []byte, s backend.StatusT) {
@@ -290,106 +367,352 @@ func (t *tstoreBackend) inventoryMoveToVetted(token []byte, s backend.StatusT) {
 	}
 }
 
+// invCursor returns the inventory cursor for an index key. The cursor is the
+// part of the index key that follows the index prefix, i.e. the timestamp and
+// the token, so that it remains valid when the record is moved to a different
+// position in the index.
+func invCursor(key string) string {
+	return key[strings.LastIndex(key, "-")-16:]
+}
+
+// invCursorIsValid returns whether the provided inventory cursor has the
+// format of an index key timestamp and token.
+func invCursorIsValid(cursor string) bool {
+	ts, token, ok := strings.Cut(cursor, "-")
+	if !ok || len(ts) != 16 || token == "" {
+		return false
+	}
+	if _, err := hex.DecodeString(ts); err != nil {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+// invTokens returns a page of tokens from the inventory index for the provided
+// state and status. A status of StatusInvalid returns a page of tokens from
+// the index that contains all statuses. The tokens are sorted by the timestamp
+// of their most recent status change from newest to oldest.
+//
+// The page is looked up using a key range that starts after the provided
+// cursor. The cursor of the following page is returned when the page is full.
+// Page numbers are supported for backwards compatibility. The index keys of
+// the preceding pages must be walked to find the start of a page that is
+// requested by number, so the cursor should be used for deep pages. The page
+// number is ignored if a cursor is provided.
+//
+// Index keys that do not match the inventory entry of their record are stale
+// keys that were left behind by a failed update. They are not returned, which
+// means that a page may contain fewer tokens than the page size in the rare
+// case that stale keys exist.
+func (t *tstoreBackend) invTokens(state backend.StateT, s backend.StatusT, pageSize, page uint32, cursor string) ([]string, string, error) {
+	if pageSize == 0 || (page == 0 && cursor == "") {
+		return []string{}, "", nil
+	}
+	if cursor != "" && !invCursorIsValid(cursor) {
+		return nil, "", backend.ErrInventoryCursorInvalid
+	}
+
+	t.RLock()
+	defer t.RUnlock()
+
+	// Find the index key that the page starts after
+	var (
+		prefix = buildInvIndexPrefix(state, s)
+		after  string
+	)
+	if cursor != "" {
+		after = prefix + cursor
+	} else {
+		for i := uint32(1); i < page; i++ {
+			keys, err := t.tstore.CacheKeys(prefix, after, pageSize)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(keys) < int(pageSize) {
+				return []string{}, "", nil
+			}
+			after = keys[len(keys)-1]
+		}
+	}
+
+	// Get the page of index keys
+	keys, err := t.tstore.CacheKeys(prefix, after, pageSize)
+	if err != nil {
+		return nil, "", err
+	}
+	tokens := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tokens = append(tokens, invIndexKeyToken(k))
+	}
+	var next string
+	if len(keys) == int(pageSize) {
+		next = invCursor(keys[len(keys)-1])
+	}
+
+	// Filter out any stale index keys
+	entries, err := t.invEntriesLocked(tokens)
+	if err != nil {
+		return nil, "", err
+	}
+	valid := make([]string, 0, len(tokens))
+	for i, token := range tokens {
+		e, ok := entries[token]
+		if !ok {
+			continue
+		}
+		if keys[i] != buildInvIndexKey(state, s, e.Timestamp, token) {
+			continue
+		}
+		valid = append(valid, token)
+	}
+
+	return valid, next, nil
+}
+
 // invByStatus contains the inventory categorized by record state and record
 // status. Each list contains a page of tokens that are sorted by the timestamp
 // of the status change from newest to oldest.
 type invByStatus struct {
 	Unvetted map[backend.StatusT][]string
 	Vetted   map[backend.StatusT][]string
+	Cursor   string // Cursor of the following page
 }
 
+var (
+	// invStatusesUnvetted and invStatusesVetted contain the record
+	// statuses that are allowed for the unvetted and the vetted records.
+	invStatusesUnvetted = []backend.StatusT{
+		backend.StatusUnreviewed,
+		backend.StatusCensored,
+		backend.StatusArchived,
+	}
+	invStatusesVetted = []backend.StatusT{
+		backend.StatusPublic,
+		backend.StatusCensored,
+		backend.StatusArchived,
+	}
+)
+
 // invByStatusAll returns a page of tokens for all record states and statuses.
 func (t *tstoreBackend) invByStatusAll(pageSize uint32) (*invByStatus, error) {
-	// Get unvetted inventory
-	u, err := t.invGet(t.invPathUnvetted())
+	var (
+		unvetted = invStatusesUnvetted
+		vetted   = invStatusesVetted
+		ibs      = invByStatus{
+			Unvetted: make(map[backend.StatusT][]string, len(unvetted)),
+			Vetted:   make(map[backend.StatusT][]string, len(vetted)),
+		}
+	)
+	for _, s := range unvetted {
+		tokens, _, err := t.invTokens(backend.StateUnvetted, s, pageSize, 1, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) != 0 {
+			ibs.Unvetted[s] = tokens
+		}
+	}
+	for _, s := range vetted {
+		tokens, _, err := t.invTokens(backend.StateVetted, s, pageSize, 1, "")
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) != 0 {
+			ibs.Vetted[s] = tokens
+		}
+	}
+
+	return &ibs, nil
+}
+
+// invCounts returns the number of records in the inventory for all record
+// states and statuses. The counts are read from the inventory counters, which
+// are updated along with the inventory entries.
+func (t *tstoreBackend) invCounts() (*backend.InventoryCounts, error) {
+	t.RLock()
+	defer t.RUnlock()
+
+	keys := make([]string, 0, len(invStatusesUnvetted)+len(invStatusesVetted))
+	for _, s := range invStatusesUnvetted {
+		keys = append(keys, buildInvCountKey(backend.StateUnvetted, s))
+	}
+	for _, s := range invStatusesVetted {
+		keys = append(keys, buildInvCountKey(backend.StateVetted, s))
+	}
+	counts, err := t.invCountsGetLocked(keys)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prepare unvetted inventory reply
-	var (
-		unvetted = tokensParse(u.Entries, backend.StatusUnreviewed, pageSize, 1)
-		censored = tokensParse(u.Entries, backend.StatusCensored, pageSize, 1)
-		archived = tokensParse(u.Entries, backend.StatusArchived, pageSize, 1)
+	unvetted := make(map[backend.StatusT]int, len(invStatusesUnvetted))
+	for _, s := range invStatusesUnvetted {
+		unvetted[s] = counts[buildInvCountKey(backend.StateUnvetted, s)]
+	}
+	vetted := make(map[backend.StatusT]int, len(invStatusesVetted))
+	for _, s := range invStatusesVetted {
+		vetted[s] = counts[buildInvCountKey(backend.StateVetted, s)]
+	}
 
-		unvettedInv = make(map[backend.StatusT][]string, 16)
-	)
-	if len(unvetted) != 0 {
-		unvettedInv[backend.StatusUnreviewed] = unvetted
+	return &backend.InventoryCounts{
+		Unvetted: unvetted,
+		Vetted:   vetted,
+	}, nil
+}
+
+// invCountsBuildLocked counts the inventory entries by state and status and
+// returns the counters that correspond to them. A counter is returned for
+// every status of the inventory, including the statuses without any records.
+//
+// This function must be called WITH the read lock held.
+func (t *tstoreBackend) invCountsBuildLocked() (map[string]int, error) {
+	counts := make(map[string]int, len(invStatusesUnvetted)+len(invStatusesVetted))
+	for _, s := range invStatusesUnvetted {
+		counts[buildInvCountKey(backend.StateUnvetted, s)] = 0
 	}
-	if len(censored) != 0 {
-		unvettedInv[backend.StatusCensored] = censored
+	for _, s := range invStatusesVetted {
+		counts[buildInvCountKey(backend.StateVetted, s)] = 0
 	}
-	if len(archived) != 0 {
-		unvettedInv[backend.StatusArchived] = archived
+
+	entryKeys, err := t.tstore.CacheKeys(invEntryKeyPrefix, "", 0)
+	if err != nil {
+		return nil, err
 	}
 
-	// Get vetted inventory
-	v, err := t.invGet(t.invPathVetted())
+	// The entries are retrieved in batches to limit the size of a
+	// single key-value store request.
+	const batchSize = 500
+	for len(entryKeys) > 0 {
+		n := batchSize
+		if len(entryKeys) < n {
+			n = len(entryKeys)
+		}
+		tokens := make([]string, 0, n)
+		for _, k := range entryKeys[:n] {
+			tokens = append(tokens, strings.TrimPrefix(k, invEntryKeyPrefix))
+		}
+		entries, err := t.invEntriesLocked(tokens)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			counts[buildInvCountKey(e.State, e.Status)]++
+		}
+		entryKeys = entryKeys[n:]
+	}
+
+	return counts, nil
+}
+
+// invCountsFsckLocked verifies that the inventory counters match the number
+// of inventory entries of each state and status. A FsckIssue is returned for
+// every counter that does not match. The counters are corrected unless this
+// is a dry run.
+//
+// This function must be called WITH the read/write lock held.
+func (t *tstoreBackend) invCountsFsckLocked(dryRun bool) ([]backend.FsckIssue, error) {
+	want, err := t.invCountsBuildLocked()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	got, err := t.invCountsGetLocked(keys)
 	if err != nil {
 		return nil, err
 	}
 
-	// Prepare vetted inventory reply
 	var (
-		vetted    = tokensParse(v.Entries, backend.StatusPublic, pageSize, 1)
-		vcensored = tokensParse(v.Entries, backend.StatusCensored, pageSize, 1)
-		varchived = tokensParse(v.Entries, backend.StatusArchived, pageSize, 1)
-
-		vettedInv = make(map[backend.StatusT][]string, 16)
+		issues = make([]backend.FsckIssue, 0, len(keys))
+		blobs  = make(map[string][]byte, len(keys))
 	)
-	if len(vetted) != 0 {
-		vettedInv[backend.StatusPublic] = vetted
+	for _, k := range keys {
+		if got[k] == want[k] {
+			continue
+		}
+		issues = append(issues, backend.FsckIssue{
+			Type: backend.FsckIssueInventoryDrift,
+			Description: fmt.Sprintf("inventory counter %v is %v, want %v",
+				k, got[k], want[k]),
+			Repaired: !dryRun,
+		})
+		blobs[k] = []byte(strconv.Itoa(want[k]))
 	}
-	if len(vcensored) != 0 {
-		vettedInv[backend.StatusCensored] = vcensored
+	if dryRun || len(blobs) == 0 {
+		return issues, nil
 	}
-	if len(varchived) != 0 {
-		vettedInv[backend.StatusArchived] = varchived
+
+	err = t.tstore.CachePut(blobs)
+	if err != nil {
+		return nil, err
 	}
 
-	return &invByStatus{
-		Unvetted: unvettedInv,
-		Vetted:   vettedInv,
-	}, nil
+	return issues, nil
+}
+
+// invCountsMigrate builds the inventory counters from the inventory entries
+// of an inventory that was created before the counters were added. It is a
+// no-op once the counters exist.
+//
+// This function must be called WITHOUT the read/write lock held.
+func (t *tstoreBackend) invCountsMigrate() error {
+	t.Lock()
+	defer t.Unlock()
+
+	keys, err := t.tstore.CacheKeys(invCountKeyPrefix, "", 1)
+	if err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		return nil
+	}
+
+	log.Infof("Building the inventory counters")
+
+	counts, err := t.invCountsBuildLocked()
+	if err != nil {
+		return err
+	}
+	blobs := make(map[string][]byte, len(counts))
+	for k, v := range counts {
+		blobs[k] = []byte(strconv.Itoa(v))
+	}
+	return t.tstore.CachePut(blobs)
 }
 
 // invByStatus returns the tokens of records in the inventory categorized by
 // record state and record status. The tokens are ordered by the timestamp of
 // their most recent status change, sorted from newest to oldest.
 //
-// The state, status, and page arguments can be provided to request a specific
-// page of record tokens.
+// The state, status, and page or cursor arguments can be provided to request
+// a specific page of record tokens. The cursor of the following page is
+// returned when the page is full.
 //
 // If no status is provided then the most recent page of tokens for all
 // statuses will be returned. All other arguments are ignored.
-func (t *tstoreBackend) invByStatus(state backend.StateT, s backend.StatusT, pageSize, page uint32) (*invByStatus, error) {
+func (t *tstoreBackend) invByStatus(state backend.StateT, s backend.StatusT, pageSize, page uint32, cursor string) (*invByStatus, error) {
 	// If no status is provided a page of tokens for each status should
 	// be returned.
 	if s == backend.StatusInvalid {
 		return t.invByStatusAll(pageSize)
 	}
 
-	// Get inventory file path
-	var fp string
 	switch state {
-	case backend.StateUnvetted:
-		fp = t.invPathUnvetted()
-	case backend.StateVetted:
-		fp = t.invPathVetted()
+	case backend.StateUnvetted, backend.StateVetted:
+		// These are allowed
 	default:
 		return nil, fmt.Errorf("unknown state '%v'", state)
 	}
 
-	// Get inventory
-	inv, err := t.invGet(fp)
+	// Get the page of tokens
+	tokens, next, err := t.invTokens(state, s, pageSize, page, cursor)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get the page of tokens
-	tokens := tokensParse(inv.Entries, s, pageSize, page)
-
 	// Prepare reply
 	var ibs invByStatus
 	switch state {
@@ -399,6 +722,7 @@ func (t *tstoreBackend) invByStatus(state backend.StateT, s backend.StatusT, pag
 				s: tokens,
 			},
 			Vetted: map[backend.StatusT][]string{},
+			Cursor: next,
 		}
 	case backend.StateVetted:
 		ibs = invByStatus{
@@ -406,6 +730,7 @@ func (t *tstoreBackend) invByStatus(state backend.StateT, s backend.StatusT, pag
 			Vetted: map[backend.StatusT][]string{
 				s: tokens,
 			},
+			Cursor: next,
 		}
 	}
 
@@ -414,95 +739,343 @@ func (t *tstoreBackend) invByStatus(state backend.StateT, s backend.StatusT, pag
 
 // invOrdered returns a page of record tokens ordered by the timestamp of their
 // most recent status change. The returned tokens will include tokens for all
-// record statuses.
-func (t *tstoreBackend) invOrdered(state backend.StateT, pageSize, pageNumber uint32) ([]string, error) {
-	// Get inventory file path
-	var fp string
+// record statuses. The cursor of the following page is returned when the page
+// is full.
+func (t *tstoreBackend) invOrdered(state backend.StateT, pageSize, pageNumber uint32, cursor string) ([]string, string, error) {
 	switch state {
-	case backend.StateUnvetted:
-		fp = t.invPathUnvetted()
-	case backend.StateVetted:
-		fp = t.invPathVetted()
+	case backend.StateUnvetted, backend.StateVetted:
+		// These are allowed
 	default:
-		return nil, fmt.Errorf("unknown state '%v'", state)
+		return nil, "", fmt.Errorf("unknown state '%v'", state)
 	}
 
-	// Get inventory
-	inv, err := t.invGet(fp)
+	return t.invTokens(state, backend.StatusInvalid, pageSize, pageNumber,
+		cursor)
+}
+
+// invVerify verifies that the inventory entry of a record matches the record
+// metadata and that the index keys of the entry exist. A FsckIssue is returned
+// if a problem is found. The entry is saved again unless this is a dry run.
+//
+// This function must be called WITHOUT the read/write lock held.
+func (t *tstoreBackend) invVerify(rm backend.RecordMetadata, dryRun bool) (*backend.FsckIssue, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	entries, err := t.invEntriesLocked([]string{rm.Token})
 	if err != nil {
 		return nil, err
 	}
+	e, ok := entries[rm.Token]
+
+	var desc string
+	switch {
+	case !ok:
+		desc = fmt.Sprintf("%v %v record missing from the inventory",
+			backend.States[rm.State], backend.Statuses[rm.Status])
+	case e.State != rm.State || e.Status != rm.Status:
+		desc = fmt.Sprintf("inventory entry is %v %v, want %v %v",
+			backend.States[e.State], backend.Statuses[e.Status],
+			backend.States[rm.State], backend.Statuses[rm.Status])
+	default:
+		keys := e.indexKeys()
+		blobs, err := t.tstore.CacheGet(keys)
+		if err != nil {
+			return nil, err
+		}
+		if len(blobs) != len(keys) {
+			desc = "inventory index keys missing"
+		}
+	}
+	if desc == "" {
+		return nil, nil
+	}
+
+	issue := backend.FsckIssue{
+		Type:        backend.FsckIssueInventoryDrift,
+		Token:       rm.Token,
+		Description: desc,
+		Repaired:    !dryRun,
+	}
+	if dryRun {
+		return &issue, nil
+	}
+
+	// The timestamp of the most recent status change is not known
+	// when the entry is missing or incorrect. The record metadata
+	// timestamp is used instead. It is updated anytime the record
+	// status is changed, but also when the record is edited.
+	if !ok || e.State != rm.State || e.Status != rm.Status {
+		e = entry{
+			Token:     rm.Token,
+			State:     rm.State,
+			Status:    rm.Status,
+			Timestamp: time.Unix(rm.Timestamp, 0).UnixNano(),
+		}
+	}
+	err = t.invPutLocked(e)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Inv entry repaired %v: %v", rm.Token, desc)
+
+	return &issue, nil
+}
+
+// invFsck verifies that the inventory does not contain entries for records
+// that do not exist and that it does not contain stale index keys. The
+// provided tokens are the tokens of all records in the backend. The entries
+// and index keys that are found are deleted unless this is a dry run. The
+// inventory counters are verified against the remaining entries afterwards.
+//
+// Records may be created while the check is running. The candidates are
+// verified again while holding the read/write lock before they are reported.
+//
+// This function must be called WITHOUT the read/write lock held.
+func (t *tstoreBackend) invFsck(tokens [][]byte, dryRun bool) ([]backend.FsckIssue, error) {
+	exists := make(map[string]struct{}, len(tokens))
+	for _, v := range tokens {
+		exists[hex.EncodeToString(v)] = struct{}{}
+	}
+
+	// Find the entries of records that do not exist and the index
+	// keys that do not match their entry.
+	t.RLock()
+	entryKeys, err := t.tstore.CacheKeys(invEntryKeyPrefix, "", 0)
+	if err != nil {
+		t.RUnlock()
+		return nil, err
+	}
+	indexKeys, err := t.tstore.CacheKeys(invIndexKeyPrefix, "", 0)
+	if err != nil {
+		t.RUnlock()
+		return nil, err
+	}
+	t.RUnlock()
+
+	orphans := make([]string, 0, 16)
+	for _, k := range entryKeys {
+		token := strings.TrimPrefix(k, invEntryKeyPrefix)
+		if _, ok := exists[token]; !ok {
+			orphans = append(orphans, token)
+		}
+	}
+	stale, err := t.invStaleKeys(indexKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the candidates again
+	t.Lock()
+	defer t.Unlock()
 
-	// Return specified page of tokens
 	var (
-		startIdx = int((pageNumber - 1) * pageSize)
-		endIdx   = startIdx + int(pageSize)
-		tokens   = make([]string, 0, pageSize)
+		issues = make([]backend.FsckIssue, 0, len(orphans)+len(stale))
+		del    = make([]string, 0, len(orphans)*3+len(stale))
+
+		// removed contains the number of orphaned entries of each
+		// inventory counter.
+		removed = make(map[string]int, len(orphans))
 	)
-	for i := startIdx; i < endIdx; i++ {
-		if i >= len(inv.Entries) {
-			// We've reached the end of the inventory. We're done.
-			break
+	for _, token := range orphans {
+		b, err := hex.DecodeString(token)
+		if err != nil {
+			return nil, err
 		}
+		if t.tstore.RecordExists(b) {
+			continue
+		}
+		entries, err := t.invEntriesLocked([]string{token})
+		if err != nil {
+			return nil, err
+		}
+		e, ok := entries[token]
+		if !ok {
+			continue
+		}
+		issues = append(issues, backend.FsckIssue{
+			Type:        backend.FsckIssueInventoryDrift,
+			Token:       token,
+			Description: "inventory entry found for a record that does not exist",
+			Repaired:    !dryRun,
+		})
+		del = append(del, buildInvEntryKey(token))
+		del = append(del, e.indexKeys()...)
+		removed[buildInvCountKey(e.State, e.Status)]++
+	}
+	stale, err = t.invStaleKeysLocked(stale)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range stale {
+		issues = append(issues, backend.FsckIssue{
+			Type:        backend.FsckIssueInventoryDrift,
+			Token:       invIndexKeyToken(k),
+			Description: fmt.Sprintf("stale inventory index key %v", k),
+			Repaired:    !dryRun,
+		})
+		del = append(del, k)
+	}
+	if !dryRun && len(del) > 0 {
+		err = t.tstore.CacheDel(del)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove the deleted entries from the counters. Any drift that
+	// remains is reported below.
+	if !dryRun && len(removed) > 0 {
+		keys := make([]string, 0, len(removed))
+		for k := range removed {
+			keys = append(keys, k)
+		}
+		counts, err := t.invCountsGetLocked(keys)
+		if err != nil {
+			return nil, err
+		}
+		blobs := make(map[string][]byte, len(counts))
+		for k, v := range counts {
+			blobs[k] = []byte(strconv.Itoa(v - removed[k]))
+		}
+		err = t.tstore.CachePut(blobs)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		tokens = append(tokens, inv.Entries[i].Token)
+	// Verify the counters once the orphaned entries have been deleted
+	ci, err := t.invCountsFsckLocked(dryRun)
+	if err != nil {
+		return nil, err
 	}
+	issues = append(issues, ci...)
 
-	return tokens, nil
+	return issues, nil
 }
 
-// entryDel removes the entry for the token and returns the updated slice.
-func entryDel(entries []entry, token []byte) ([]entry, error) {
-	// Find token in entries
-	var i int
-	var found bool
-	htoken := hex.EncodeToString(token)
-	for k, v := range entries {
-		if v.Token == htoken {
-			i = k
-			found = true
-			break
+// invStaleKeys returns the inventory index keys that do not match the
+// inventory entry of their record.
+//
+// This function must be called WITHOUT the read/write lock held.
+func (t *tstoreBackend) invStaleKeys(keys []string) ([]string, error) {
+	// The entries are retrieved in batches to limit the size of a
+	// single key-value store request.
+	const batchSize = 500
+	stale := make([]string, 0, 16)
+	for len(keys) > 0 {
+		n := batchSize
+		if len(keys) < n {
+			n = len(keys)
 		}
+		t.RLock()
+		s, err := t.invStaleKeysLocked(keys[:n])
+		t.RUnlock()
+		if err != nil {
+			return nil, err
+		}
+		stale = append(stale, s...)
+		keys = keys[n:]
 	}
-	if !found {
-		return nil, fmt.Errorf("token not found %x", token)
-	}
+	return stale, nil
+}
 
-	// Del token from entries (linear time)
-	copy(entries[i:], entries[i+1:])   // Shift entries[i+1:] left one index
-	entries[len(entries)-1] = entry{}  // Del last element (write zero value)
-	entries = entries[:len(entries)-1] // Truncate slice
+// invStaleKeysLocked returns the provided inventory index keys that do not
+// match the inventory entry of their record.
+//
+// This function must be called WITH the read lock held.
+func (t *tstoreBackend) invStaleKeysLocked(keys []string) ([]string, error) {
+	tokens := make([]string, 0, len(keys))
+	for _, k := range keys {
+		tokens = append(tokens, invIndexKeyToken(k))
+	}
+	entries, err := t.invEntriesLocked(tokens)
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]string, 0, 16)
+	for i, k := range keys {
+		e, ok := entries[tokens[i]]
+		if !ok {
+			stale = append(stale, k)
+			continue
+		}
+		var valid bool
+		for _, v := range e.indexKeys() {
+			if v == k {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			stale = append(stale, k)
+		}
+	}
+	return stale, nil
+}
 
-	return entries, nil
+// legacyInventory is the format of the legacy inventory JSON files. The
+// entries are ordered from the newest to the oldest status change.
+type legacyInventory struct {
+	Entries []struct {
+		Token  string          `json:"token"`
+		Status backend.StatusT `json:"status"`
+	} `json:"entries"`
 }
 
-// tokensParse parses a page of tokens from the provided entries that meet the
-// provided criteria.
-func tokensParse(entries []entry, s backend.StatusT, countPerPage, page uint32) []string {
-	tokens := make([]string, 0, countPerPage)
-	if countPerPage == 0 || page == 0 {
-		return tokens
+// invMigrate performs a one time migration of the legacy inventory JSON files
+// into the key-value store inventory. The legacy files do not contain
+// timestamps. The entries are assigned timestamps, one nanosecond apart,
+// that preserve their existing order. The legacy files are deleted once they
+// have been migrated.
+//
+// The migration can be safely re-run if it is interrupted.
+func (t *tstoreBackend) invMigrate() error {
+	legacy := []struct {
+		state backend.StateT
+		path  string
+	}{
+		{backend.StateUnvetted, filepath.Join(t.dataDir, filenameInvUnvetted)},
+		{backend.StateVetted, filepath.Join(t.dataDir, filenameInvVetted)},
 	}
-
-	startAt := (page - 1) * countPerPage
-	var foundCount uint32
-	for _, v := range entries {
-		if v.Status != s {
-			// Status does not match
+	for _, v := range legacy {
+		b, err := os.ReadFile(v.path)
+		if errors.Is(err, os.ErrNotExist) {
 			continue
+		} else if err != nil {
+			return err
+		}
+		var inv legacyInventory
+		err = json.Unmarshal(b, &inv)
+		if err != nil {
+			return fmt.Errorf("unmarshal %v: %v", v.path, err)
 		}
 
-		// Matching status found
-		if foundCount >= startAt {
-			tokens = append(tokens, v.Token)
-			if len(tokens) == int(countPerPage) {
-				// We have a full page. We're done.
-				return tokens
+		log.Infof("Migrating %v %v inventory entries to the key-value store",
+			len(inv.Entries), backend.States[v.state])
+
+		t.Lock()
+		now := time.Now().UnixNano()
+		for i, e := range inv.Entries {
+			err = t.invPutLocked(entry{
+				Token:     e.Token,
+				State:     v.state,
+				Status:    e.Status,
+				Timestamp: now - int64(i),
+			})
+			if err != nil {
+				t.Unlock()
+				return err
 			}
 		}
+		t.Unlock()
 
-		foundCount++
+		err = os.Remove(v.path)
+		if err != nil {
+			return err
+		}
 	}
 
-	return tokens
+	return nil
 }
@@ -0,0 +1,512 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstorebe
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// invPutTest saves an inventory entry using the provided timestamp.
+func invPutTest(t *testing.T, tb *tstoreBackend, e entry) {
+	t.Helper()
+
+	tb.Lock()
+	defer tb.Unlock()
+
+	err := tb.invPutLocked(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// invCountTest verifies the inventory count of the provided state and status.
+func invCountTest(t *testing.T, tb *tstoreBackend, state backend.StateT, s backend.StatusT, want int) {
+	t.Helper()
+
+	ic, err := tb.invCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := ic.Unvetted
+	if state == backend.StateVetted {
+		counts = ic.Vetted
+	}
+	if counts[s] != want {
+		t.Fatalf("got %v %v count %v, want %v", backend.States[state],
+			backend.Statuses[s], counts[s], want)
+	}
+}
+
+func TestInvOrdered(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Add entries with increasing timestamps
+	var (
+		unreviewed = []string{"0000000000000001", "0000000000000002"}
+		censored   = "0000000000000003"
+	)
+	invPutTest(t, tb, entry{unreviewed[0], backend.StateUnvetted,
+		backend.StatusUnreviewed, 100})
+	invPutTest(t, tb, entry{censored, backend.StateUnvetted,
+		backend.StatusUnreviewed, 200})
+	invPutTest(t, tb, entry{unreviewed[1], backend.StateUnvetted,
+		backend.StatusUnreviewed, 300})
+
+	// Censoring a record moves it to the front of the ordered inventory
+	// and out of the unreviewed inventory.
+	invPutTest(t, tb, entry{censored, backend.StateUnvetted,
+		backend.StatusCensored, 400})
+
+	tests := []struct {
+		name     string
+		pageSize uint32
+		page     uint32
+		want     []string
+	}{
+		{"all", 10, 1, []string{censored, unreviewed[1], unreviewed[0]}},
+		{"first page", 2, 1, []string{censored, unreviewed[1]}},
+		{"second page", 2, 2, []string{unreviewed[0]}},
+		{"out of range", 2, 3, []string{}},
+		{"page zero", 2, 0, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _, err := tb.invOrdered(backend.StateUnvetted, tc.pageSize,
+				tc.page, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// Page through the inventory using the cursor. The cursor remains
+	// valid when the record that it points to is moved.
+	got, cursor, err := tb.invOrdered(backend.StateUnvetted, 2, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{}) || cursor != "" {
+		t.Fatalf("got %v %q, want no tokens and no cursor", got, cursor)
+	}
+	got, cursor, err = tb.invOrdered(backend.StateUnvetted, 2, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tests[1].want) || cursor == "" {
+		t.Fatalf("got %v %q, want %v and a cursor", got, cursor, tests[1].want)
+	}
+	invPutTest(t, tb, entry{unreviewed[1], backend.StateUnvetted,
+		backend.StatusUnreviewed, 450})
+	got, next, err := tb.invOrdered(backend.StateUnvetted, 2, 1, cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tests[2].want) || next != "" {
+		t.Fatalf("got %v %q, want %v and no cursor", got, next, tests[2].want)
+	}
+	invPutTest(t, tb, entry{unreviewed[1], backend.StateUnvetted,
+		backend.StatusUnreviewed, 300})
+	_, _, err = tb.invOrdered(backend.StateUnvetted, 2, 1, "invalid")
+	if !errors.Is(err, backend.ErrInventoryCursorInvalid) {
+		t.Fatalf("got err %v, want %v", err, backend.ErrInventoryCursorInvalid)
+	}
+
+	// Verify the inventory by status
+	ibs, err := tb.invByStatus(backend.StateUnvetted, backend.StatusInvalid, 10, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[backend.StatusT][]string{
+		backend.StatusUnreviewed: {unreviewed[1], unreviewed[0]},
+		backend.StatusCensored:   {censored},
+	}
+	if !reflect.DeepEqual(ibs.Unvetted, want) {
+		t.Fatalf("got unvetted %v, want %v", ibs.Unvetted, want)
+	}
+	if len(ibs.Vetted) != 0 {
+		t.Fatalf("got vetted %v, want none", ibs.Vetted)
+	}
+
+	// Verify the inventory counts
+	ic, err := tb.invCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCounts := backend.InventoryCounts{
+		Unvetted: map[backend.StatusT]int{
+			backend.StatusUnreviewed: 2,
+			backend.StatusCensored:   1,
+			backend.StatusArchived:   0,
+		},
+		Vetted: map[backend.StatusT]int{
+			backend.StatusPublic:   0,
+			backend.StatusCensored: 0,
+			backend.StatusArchived: 0,
+		},
+	}
+	if !reflect.DeepEqual(*ic, wantCounts) {
+		t.Fatalf("got counts %+v, want %+v", *ic, wantCounts)
+	}
+
+	// Stale index keys must be ignored
+	stale := buildInvIndexKey(backend.StateUnvetted, backend.StatusInvalid,
+		500, unreviewed[0])
+	err = tb.tstore.CachePut(map[string][]byte{stale: {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err = tb.invOrdered(backend.StateUnvetted, 10, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, tests[0].want) {
+		t.Fatalf("got %v, want %v", got, tests[0].want)
+	}
+}
+
+func TestInvMoveToVetted(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	token := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	err := tb.invAdd(backend.StateUnvetted, token, backend.StatusUnreviewed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A record can not be updated in a state that it is not in
+	err = tb.invUpdate(backend.StateVetted, token, backend.StatusArchived)
+	if err == nil {
+		t.Fatalf("expected error for vetted update of unvetted record")
+	}
+
+	// Move the record to vetted
+	err = tb.invMoveToVetted(token, backend.StatusPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tb.invMoveToVetted(token, backend.StatusPublic)
+	if err == nil {
+		t.Fatalf("expected error for moving a vetted record")
+	}
+	unvetted, _, err := tb.invOrdered(backend.StateUnvetted, 10, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unvetted) != 0 {
+		t.Fatalf("got unvetted tokens %v, want none", unvetted)
+	}
+
+	// Update the vetted status
+	err = tb.invUpdate(backend.StateVetted, token, backend.StatusArchived)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ibs, err := tb.invByStatus(backend.StateVetted, backend.StatusPublic, 10, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ibs.Vetted[backend.StatusPublic]) != 0 {
+		t.Fatalf("got public tokens %v, want none",
+			ibs.Vetted[backend.StatusPublic])
+	}
+	ibs, err = tb.invByStatus(backend.StateVetted, backend.StatusArchived, 10, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0102030405060708"}
+	if !reflect.DeepEqual(ibs.Vetted[backend.StatusArchived], want) {
+		t.Fatalf("got archived tokens %v, want %v",
+			ibs.Vetted[backend.StatusArchived], want)
+	}
+
+	// Each record only has an entry and two index keys
+	keys, err := tb.tstore.CacheKeys(invKeyPrefix, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("got %v inventory keys, want 3: %v", len(keys), keys)
+	}
+}
+
+func TestInvMigrate(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Write the legacy inventory files. The entries are ordered from
+	// newest to oldest.
+	files := map[string]string{
+		filenameInvUnvetted: `{"entries":[
+			{"token":"0000000000000002","status":1},
+			{"token":"0000000000000001","status":1}]}`,
+		filenameInvVetted: `{"entries":[
+			{"token":"0000000000000004","status":4},
+			{"token":"0000000000000003","status":2},
+			{"token":"0000000000000005","status":2}]}`,
+	}
+	for fn, v := range files {
+		err := os.WriteFile(filepath.Join(tb.dataDir, fn), []byte(v), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := tb.invMigrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the order was preserved
+	for fn, v := range files {
+		var inv legacyInventory
+		err = json.Unmarshal([]byte(v), &inv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make([]string, 0, len(inv.Entries))
+		for _, e := range inv.Entries {
+			want = append(want, e.Token)
+		}
+		state := backend.StateUnvetted
+		if fn == filenameInvVetted {
+			state = backend.StateVetted
+		}
+		got, _, err := tb.invOrdered(state, 10, 1, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: got %v, want %v", fn, got, want)
+		}
+
+		// The legacy file must have been deleted
+		_, err = os.Stat(filepath.Join(tb.dataDir, fn))
+		if !os.IsNotExist(err) {
+			t.Fatalf("%v was not deleted: %v", fn, err)
+		}
+	}
+	ibs, err := tb.invByStatus(backend.StateVetted, backend.StatusPublic, 10, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"0000000000000003", "0000000000000005"}
+	if !reflect.DeepEqual(ibs.Vetted[backend.StatusPublic], want) {
+		t.Fatalf("got public tokens %v, want %v",
+			ibs.Vetted[backend.StatusPublic], want)
+	}
+
+	// Running the migration again is a no-op
+	err = tb.invMigrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInvVerify(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	token := "0000000000000001"
+	invPutTest(t, tb, entry{token, backend.StateVetted,
+		backend.StatusPublic, 100})
+	rm := backend.RecordMetadata{
+		Token:     token,
+		State:     backend.StateVetted,
+		Status:    backend.StatusArchived,
+		Timestamp: 200,
+	}
+
+	// A dry run must not change the inventory
+	issue, err := tb.invVerify(rm, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue == nil || issue.Repaired ||
+		issue.Type != backend.FsckIssueInventoryDrift {
+		t.Fatalf("got issue %+v, want unrepaired inventory drift", issue)
+	}
+	ibs, err := tb.invByStatus(backend.StateVetted, backend.StatusArchived, 10, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ibs.Vetted[backend.StatusArchived]) != 0 {
+		t.Fatalf("dry run repaired the inventory")
+	}
+
+	// Repair the entry
+	issue, err = tb.invVerify(rm, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue == nil || !issue.Repaired {
+		t.Fatalf("got issue %+v, want repaired inventory drift", issue)
+	}
+	ibs, err = tb.invByStatus(backend.StateVetted, backend.StatusArchived, 10, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{token}
+	if !reflect.DeepEqual(ibs.Vetted[backend.StatusArchived], want) {
+		t.Fatalf("got archived tokens %v, want %v",
+			ibs.Vetted[backend.StatusArchived], want)
+	}
+
+	// The repaired entry must verify
+	issue, err = tb.invVerify(rm, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue != nil {
+		t.Fatalf("got issue %+v, want none", issue)
+	}
+}
+
+func TestInvFsck(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Add an entry for a record that exists, an entry for a record
+	// that does not exist, and a stale index key.
+	var (
+		token  = "0000000000000001"
+		orphan = "0000000000000002"
+	)
+	invPutTest(t, tb, entry{token, backend.StateVetted,
+		backend.StatusPublic, 100})
+	invPutTest(t, tb, entry{orphan, backend.StateVetted,
+		backend.StatusPublic, 200})
+	stale := buildInvIndexKey(backend.StateVetted, backend.StatusCensored,
+		300, token)
+	err := tb.tstore.CachePut(map[string][]byte{stale: {}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenB, err := hex.DecodeString(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := [][]byte{tokenB}
+
+	// A dry run must not change the inventory
+	issues, err := tb.invFsck(tokens, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("got %v issues, want 2: %+v", len(issues), issues)
+	}
+	keys, err := tb.tstore.CacheKeys(invKeyPrefix, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 7 {
+		t.Fatalf("got %v inventory keys, want 7: %v", len(keys), keys)
+	}
+
+	// Repair the inventory
+	issues, err = tb.invFsck(tokens, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("got %v issues, want 2: %+v", len(issues), issues)
+	}
+	got, _, err := tb.invOrdered(backend.StateVetted, 10, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{token}) {
+		t.Fatalf("got %v, want %v", got, []string{token})
+	}
+	keys, err = tb.tstore.CacheKeys(invKeyPrefix, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 {
+		t.Fatalf("got %v inventory keys, want 3: %v", len(keys), keys)
+	}
+	invCountTest(t, tb, backend.StateVetted, backend.StatusPublic, 1)
+
+	// Nothing is left to repair
+	issues, err = tb.invFsck(tokens, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("got issues %+v, want none", issues)
+	}
+
+	// A counter that does not match the entries is corrected
+	err = tb.tstore.CachePut(map[string][]byte{
+		buildInvCountKey(backend.StateVetted, backend.StatusPublic): []byte("5"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err = tb.invFsck(tokens, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 {
+		t.Fatalf("got %v issues, want 1: %+v", len(issues), issues)
+	}
+	invCountTest(t, tb, backend.StateVetted, backend.StatusPublic, 1)
+}
+
+func TestInvCountsMigrate(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Add entries and delete the counters to simulate an inventory
+	// that was created before the counters were added.
+	invPutTest(t, tb, entry{"0000000000000001", backend.StateUnvetted,
+		backend.StatusUnreviewed, 100})
+	invPutTest(t, tb, entry{"0000000000000002", backend.StateVetted,
+		backend.StatusPublic, 200})
+	invPutTest(t, tb, entry{"0000000000000003", backend.StateVetted,
+		backend.StatusPublic, 300})
+	keys, err := tb.tstore.CacheKeys(invCountKeyPrefix, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tb.tstore.CacheDel(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tb.invCountsMigrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invCountTest(t, tb, backend.StateUnvetted, backend.StatusUnreviewed, 1)
+	invCountTest(t, tb, backend.StateVetted, backend.StatusPublic, 2)
+	invCountTest(t, tb, backend.StateVetted, backend.StatusArchived, 0)
+
+	// The counters are updated along with the entries
+	invPutTest(t, tb, entry{"0000000000000003", backend.StateVetted,
+		backend.StatusArchived, 400})
+	invCountTest(t, tb, backend.StateVetted, backend.StatusPublic, 1)
+	invCountTest(t, tb, backend.StateVetted, backend.StatusArchived, 1)
+
+	// Running the migration again is a no-op
+	err = tb.invCountsMigrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	invCountTest(t, tb, backend.StateVetted, backend.StatusArchived, 1)
+}
@@ -331,12 +331,28 @@ func (p *commentsPlugin) timestamp(token []byte, digest []byte) (*comments.Times
 			ExtraData:  v.ExtraData,
 		})
 	}
+	anchors := make([]comments.TimestampAnchor, 0, len(t.Anchors))
+	for _, v := range t.Anchors {
+		anchors = append(anchors, comments.TimestampAnchor{
+			Provider:   v.Provider,
+			TxID:       v.TxID,
+			MerkleRoot: v.MerkleRoot,
+			Proof: comments.Proof{
+				Type:       v.Proof.Type,
+				Digest:     v.Proof.Digest,
+				MerkleRoot: v.Proof.MerkleRoot,
+				MerklePath: v.Proof.MerklePath,
+				ExtraData:  v.Proof.ExtraData,
+			},
+		})
+	}
 	return &comments.Timestamp{
 		Data:       t.Data,
 		Digest:     t.Digest,
 		TxID:       t.TxID,
 		MerkleRoot: t.MerkleRoot,
 		Proofs:     proofs,
+		Anchors:    anchors,
 	}, nil
 }
 
@@ -5,6 +5,7 @@
 package comments
 
 import (
+	"encoding/hex"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -97,6 +98,11 @@ func (p *commentsPlugin) Cmd(token []byte, cmd, payload string) (string, error)
 func (p *commentsPlugin) Hook(h plugins.HookT, payload string) error {
 	log.Tracef("comments Hook: %x %v", plugins.Hooks[h])
 
+	switch h {
+	case plugins.HookTypeTxRollbackPost:
+		return p.hookTxRollbackPost(payload)
+	}
+
 	return nil
 }
 
@@ -104,30 +110,39 @@ func (p *commentsPlugin) Hook(h plugins.HookT, payload string) error {
 // tokens for all records in the backend.
 //
 // This function satisfies the plugins PluginClient interface.
-func (p *commentsPlugin) Fsck(tokens [][]byte) error {
+func (p *commentsPlugin) Fsck(tokens [][]byte, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
 	log.Infof("Comments fsck starting for %v records", len(tokens))
 
 	// Range the provided record tokens and verify that the
 	// cached record index is coherent for each token. The
 	// cache entry will be built from scratch if any errors
 	// are found with it.
-	var rebuilt int
+	issues := make([]backend.FsckIssue, 0, 16)
 	for i, token := range tokens {
 		log.Debugf("Comments fsck for record %v/%v", i+1, len(tokens))
 
-		wasRebuilt, err := p.fsckRecordIndex(token)
+		unlock := opts.RecordLock(token)
+		coherent, err := p.fsckRecordIndex(token, opts.DryRun)
+		unlock()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if wasRebuilt {
-			rebuilt++
+		if !coherent {
+			issues = append(issues, backend.FsckIssue{
+				Type:        backend.FsckIssueCacheStale,
+				Token:       hex.EncodeToString(token),
+				Description: "comments record index is not coherent",
+				Repaired:    !opts.DryRun,
+			})
 		}
+
+		opts.Progress(i+1, len(tokens))
 	}
 
-	log.Infof("%v/%v record indexes required a rebuild", rebuilt, len(tokens))
+	log.Infof("%v/%v record indexes were not coherent", len(issues), len(tokens))
 	log.Infof("Comments fsck complete")
 
-	return nil
+	return issues, nil
 }
 
 // Settings returns the plugin settings.
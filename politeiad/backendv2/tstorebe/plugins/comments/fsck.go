@@ -6,29 +6,20 @@ package comments
 
 import (
 	"encoding/hex"
+	"encoding/json"
+
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
 )
 
 // fsckRecordIndex verifies the coherency of a record index. The record index
-// is rebuilt from scratch if any errors are found. The returned bool will be
-// true if the record index was rebuilt.
-func (p *commentsPlugin) fsckRecordIndex(token []byte) (bool, error) {
+// is rebuilt from scratch if any errors are found, unless this is a dry run.
+// The returned bool will be false if the record index was not coherent.
+func (p *commentsPlugin) fsckRecordIndex(token []byte, dryRun bool) (bool, error) {
 	log.Debugf("%x fsck record index", token)
 
 	// Get the digests for all of the comment add, del, and
-	// vote entries for the record. The digests are the keys
-	// that are used to pull the full entries from tstore.
-	addD, err := p.tstore.DigestsByDataDesc(token,
-		[]string{dataDescriptorCommentAdd})
-	if err != nil {
-		return false, err
-	}
-	delD, err := p.tstore.DigestsByDataDesc(token,
-		[]string{dataDescriptorCommentDel})
-	if err != nil {
-		return false, err
-	}
-	voteD, err := p.tstore.DigestsByDataDesc(token,
-		[]string{dataDescriptorCommentVote})
+	// vote entries for the record.
+	addD, delD, voteD, err := p.recordDigests(token)
 	if err != nil {
 		return false, err
 	}
@@ -47,6 +38,11 @@ func (p *commentsPlugin) fsckRecordIndex(token []byte) (bool, error) {
 	if recordIndexIsCoherent(*rindex, addD, delD, voteD) {
 		log.Debugf("%x indexes are coherent", token)
 
+		return true, nil
+	}
+	if dryRun {
+		log.Infof("%x indexes are not coherent", token)
+
 		return false, nil
 	}
 
@@ -58,7 +54,50 @@ func (p *commentsPlugin) fsckRecordIndex(token []byte) (bool, error) {
 		return false, err
 	}
 
-	return true, nil
+	return false, nil
+}
+
+// hookTxRollbackPost rebuilds the record index of a record once a transaction
+// on the record has been rolled back. The record index is saved to the plugin
+// data dir and is not undone by the rollback. The comments that were added by
+// the transaction are not part of the record anymore.
+func (p *commentsPlugin) hookTxRollbackPost(payload string) error {
+	var tr plugins.HookTxRollback
+	err := json.Unmarshal([]byte(payload), &tr)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("%x rebuilding indexes after tx rollback", tr.Token)
+
+	addD, delD, voteD, err := p.recordDigests(tr.Token)
+	if err != nil {
+		return err
+	}
+
+	return p.rebuildRecordIndex(tr.Token, addD, delD, voteD)
+}
+
+// recordDigests returns the digests for all of the comment add, del, and vote
+// entries of a record. The digests are the keys that are used to pull the full
+// entries from tstore.
+func (p *commentsPlugin) recordDigests(token []byte) ([][]byte, [][]byte, [][]byte, error) {
+	addD, err := p.tstore.DigestsByDataDesc(token,
+		[]string{dataDescriptorCommentAdd})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	delD, err := p.tstore.DigestsByDataDesc(token,
+		[]string{dataDescriptorCommentDel})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	voteD, err := p.tstore.DigestsByDataDesc(token,
+		[]string{dataDescriptorCommentVote})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return addD, delD, voteD, nil
 }
 
 // rebuildRecordIndex rebuilds a recordIndex and saves it to the cache. If
@@ -224,10 +224,10 @@ func (p *dcrdataPlugin) Hook(h plugins.HookT, payload string) error {
 // tokens for all records in the backend.
 //
 // This function satisfies the plugins PluginClient interface.
-func (p *dcrdataPlugin) Fsck(tokens [][]byte) error {
+func (p *dcrdataPlugin) Fsck(tokens [][]byte, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
 	log.Tracef("dcrdata Fsck")
 
-	return nil
+	return nil, nil
 }
 
 // Settings returns the plugin's settings.
@@ -6,6 +6,7 @@ package pi
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -136,6 +137,22 @@ func (p *piPlugin) hookPluginPre(payload string) error {
 	return nil
 }
 
+// hookTxRollbackPost removes a record from the proposal statuses cache once a
+// transaction on the record has been rolled back. The statuses cache is a
+// memory cache and is not undone by the rollback. The proposal status is
+// determined again the next time that it is requested.
+func (p *piPlugin) hookTxRollbackPost(payload string) error {
+	var tr plugins.HookTxRollback
+	err := json.Unmarshal([]byte(payload), &tr)
+	if err != nil {
+		return err
+	}
+
+	p.statuses.del(hex.EncodeToString(tr.Token))
+
+	return nil
+}
+
 // titleIsValid returns whether the provided title, which can be either a
 // proposal name or an author update title, matches the pi plugin title regex.
 func (p *piPlugin) titleIsValid(title string) bool {
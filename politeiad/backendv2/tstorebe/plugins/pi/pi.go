@@ -107,6 +107,8 @@ func (p *piPlugin) Hook(h plugins.HookT, payload string) error {
 		return p.hookEditRecordPre(payload)
 	case plugins.HookTypePluginPre:
 		return p.hookPluginPre(payload)
+	case plugins.HookTypeTxRollbackPost:
+		return p.hookTxRollbackPost(payload)
 	}
 
 	return nil
@@ -116,10 +118,10 @@ func (p *piPlugin) Hook(h plugins.HookT, payload string) error {
 // tokens for all records in the backend.
 //
 // This function satisfies the plugins PluginClient interface.
-func (p *piPlugin) Fsck(tokens [][]byte) error {
+func (p *piPlugin) Fsck(tokens [][]byte, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
 	log.Tracef("pi Fsck")
 
-	return nil
+	return nil, nil
 }
 
 // Settings returns the plugin's settings.
@@ -100,3 +100,21 @@ func (s *proposalStatuses) set(token string, entry statusEntry) {
 	log.Debugf("proposalStatuses: added entry %v with status %v",
 		token, entry.propStatus)
 }
+
+// del removes the entry associated with the given token from the cache.
+func (s *proposalStatuses) del(token string) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.data[token]; !ok {
+		return
+	}
+	delete(s.data, token)
+	for e := s.entries.Front(); e != nil; e = e.Next() {
+		if e.Value.(string) == token {
+			s.entries.Remove(e)
+			break
+		}
+	}
+	log.Debugf("proposalStatuses: removed entry %v", token)
+}
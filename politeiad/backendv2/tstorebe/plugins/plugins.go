@@ -54,8 +54,36 @@ const (
 	// HookTypePluginPost is called after a plugin command is executed.
 	HookTypePluginPost HookT = 10
 
+	// HookTypeCensorFilesPost is called after individual record files
+	// have been censored.
+	HookTypeCensorFilesPost HookT = 11
+
+	// HookTypeNewDraftPre is called before a new draft is saved to
+	// disk. The payload is a HookNewRecordPre.
+	HookTypeNewDraftPre HookT = 12
+
+	// HookTypeNewDraftPost is called after a new draft is saved to
+	// disk. The payload is a HookNewRecordPost.
+	HookTypeNewDraftPost HookT = 13
+
+	// HookTypeEditDraftPre is called before a draft update is saved
+	// to disk. The payload is a HookEditRecord.
+	HookTypeEditDraftPre HookT = 14
+
+	// HookTypeDelDraftPost is called after a draft has been deleted,
+	// either by the author or because it was promoted to a record.
+	HookTypeDelDraftPost HookT = 15
+
+	// HookTypeTxRollbackPost is called after a transaction that
+	// executed plugin commands has been rolled back. The payload is a
+	// HookTxRollback. The plugin cache writes of the transaction have
+	// been undone by the time the hook is executed. It gives the plugins
+	// the opportunity to rebuild the caches of the record that are not
+	// saved to tstore, such as memory caches.
+	HookTypeTxRollbackPost HookT = 16
+
 	// HookTypeLast unit test only
-	HookTypeLast HookT = 11
+	HookTypeLast HookT = 17
 )
 
 var (
@@ -72,6 +100,12 @@ var (
 		HookTypeSetRecordStatusPost: "set record status post",
 		HookTypePluginPre:           "plugin pre",
 		HookTypePluginPost:          "plugin post",
+		HookTypeCensorFilesPost:     "censor files post",
+		HookTypeNewDraftPre:         "new draft pre",
+		HookTypeNewDraftPost:        "new draft post",
+		HookTypeEditDraftPre:        "edit draft pre",
+		HookTypeDelDraftPost:        "del draft post",
+		HookTypeTxRollbackPost:      "tx rollback post",
 	}
 )
 
@@ -116,6 +150,24 @@ type HookSetRecordStatus struct {
 	Metadata       []backend.MetadataStream `json:"metadata"`
 }
 
+// HookCensorFiles is the payload for the post censor files hook. The payloads
+// of the censored files have been deleted by the time the hook is executed.
+type HookCensorFiles struct {
+	RecordMetadata backend.RecordMetadata `json:"recordmetadata"`
+	CensoredFiles  []backend.CensoredFile `json:"censoredfiles"`
+}
+
+// HookDelDraft is the payload for the post del draft hook.
+type HookDelDraft struct {
+	RecordMetadata backend.RecordMetadata   `json:"recordmetadata"`
+	Metadata       []backend.MetadataStream `json:"metadata"`
+}
+
+// HookTxRollback is the payload for the post tx rollback hook.
+type HookTxRollback struct {
+	Token []byte `json:"token"`
+}
+
 // HookPluginPre is the payload for the pre plugin hook.
 type HookPluginPre struct {
 	Token    []byte `json:"token"`
@@ -133,6 +185,22 @@ type HookPluginPost struct {
 	Reply    string `json:"reply"`
 }
 
+// FsckOpts contains the options for a plugin filesystem check.
+type FsckOpts struct {
+	// DryRun is set when problems must only be reported and not
+	// repaired.
+	DryRun bool
+
+	// RecordLock locks a record against concurrent writes and returns
+	// a function that releases the lock. The backend remains online
+	// during a filesystem check. A plugin must hold the record lock
+	// while it verifies and repairs the data of a record.
+	RecordLock func(token []byte) (unlock func())
+
+	// Progress reports the number of records that have been checked.
+	Progress func(done, total int)
+}
+
 // PluginClient provides an API for a tstore instance to use when interacting
 // with a plugin. All tstore plugins must implement the PluginClient interface.
 type PluginClient interface {
@@ -146,8 +214,9 @@ type PluginClient interface {
 	Hook(h HookT, payload string) error
 
 	// Fsck performs a plugin file system check. The plugin is
-	// provided with the tokens for all records in the backend.
-	Fsck(tokens [][]byte) error
+	// provided with the tokens for all records in the backend. The
+	// problems that were found are returned.
+	Fsck(tokens [][]byte, opts FsckOpts) ([]backend.FsckIssue, error)
 
 	// Settings returns the plugin settings.
 	Settings() []backend.PluginSetting
@@ -0,0 +1,81 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"encoding/json"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/plugins/search"
+)
+
+// cmdSearch returns the records that match a search query. The results are
+// ranked by relevance and are paginated using the plugin page size.
+func (p *searchPlugin) cmdSearch(payload string) (string, error) {
+	// Decode payload
+	var s search.Search
+	err := json.Unmarshal([]byte(payload), &s)
+	if err != nil {
+		return "", err
+	}
+
+	// Verify the query
+	terms := queryTerms(s.Query)
+	if len(terms) == 0 {
+		return "", backend.PluginError{
+			PluginID:     search.PluginID,
+			ErrorCode:    uint32(search.ErrorCodeQueryInvalid),
+			ErrorContext: "query does not contain any searchable terms",
+		}
+	}
+	if s.Before != 0 && s.After > s.Before {
+		return "", backend.PluginError{
+			PluginID:  search.PluginID,
+			ErrorCode: uint32(search.ErrorCodeDateRangeInvalid),
+		}
+	}
+
+	// Only search the documents of the requested record state
+	state := backend.StateVetted
+	if s.Unvetted {
+		state = backend.StateUnvetted
+	}
+	docs, err := p.index.Docs(state)
+	if err != nil {
+		return "", err
+	}
+	results := rank(docs, terms, s)
+
+	// Get the requested page
+	page := s.Page
+	if page == 0 {
+		page = 1
+	}
+	var (
+		total = uint32(len(results))
+		start = (page - 1) * p.pageSize
+		end   = start + p.pageSize
+	)
+	switch {
+	case start >= total:
+		results = []search.Result{}
+	case end > total:
+		results = results[start:]
+	default:
+		results = results[start:end]
+	}
+
+	// Prepare reply
+	sr := search.SearchReply{
+		Results: results,
+		Total:   total,
+	}
+	reply, err := json.Marshal(sr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(reply), nil
+}
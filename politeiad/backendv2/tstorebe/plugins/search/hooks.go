@@ -0,0 +1,96 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
+)
+
+// hookNewRecordPost adds a new record to the search index.
+func (p *searchPlugin) hookNewRecordPost(payload string) error {
+	var nr plugins.HookNewRecordPost
+	err := json.Unmarshal([]byte(payload), &nr)
+	if err != nil {
+		return err
+	}
+
+	d, err := newDocument(nr.RecordMetadata, nr.Metadata, nr.Files)
+	if err != nil {
+		return err
+	}
+
+	return p.index.Put(*d)
+}
+
+// hookEditRecordPost replaces the indexed document of a record with the
+// document of the new record version.
+func (p *searchPlugin) hookEditRecordPost(payload string) error {
+	var er plugins.HookEditRecord
+	err := json.Unmarshal([]byte(payload), &er)
+	if err != nil {
+		return err
+	}
+
+	d, err := newDocument(er.RecordMetadata, er.Metadata, er.Files)
+	if err != nil {
+		return err
+	}
+
+	return p.index.Put(*d)
+}
+
+// hookSetRecordStatusPost updates the indexed document of a record after a
+// status change. A record that is made public is moved to the vetted index and
+// its document is no longer encrypted. A censored record is removed from the
+// index since its files have been deleted.
+func (p *searchPlugin) hookSetRecordStatusPost(payload string) error {
+	var srs plugins.HookSetRecordStatus
+	err := json.Unmarshal([]byte(payload), &srs)
+	if err != nil {
+		return err
+	}
+	rm := srs.RecordMetadata
+
+	if rm.Status == backend.StatusCensored {
+		return p.index.Del(rm.Token)
+	}
+
+	d, err := newDocument(rm, srs.Metadata, srs.Record.Files)
+	if err != nil {
+		return err
+	}
+
+	return p.index.Put(*d)
+}
+
+// hookCensorFilesPost rebuilds the indexed document of a record after some of
+// its files have been censored. The payloads of the censored files are no
+// longer returned for the record, which removes their content from the
+// document.
+func (p *searchPlugin) hookCensorFilesPost(payload string) error {
+	var cf plugins.HookCensorFiles
+	err := json.Unmarshal([]byte(payload), &cf)
+	if err != nil {
+		return err
+	}
+
+	token, err := hex.DecodeString(cf.RecordMetadata.Token)
+	if err != nil {
+		return err
+	}
+	d, err := p.document(token)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return p.index.Del(cf.RecordMetadata.Token)
+	}
+
+	return p.index.Put(*d)
+}
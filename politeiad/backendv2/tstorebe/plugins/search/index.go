@@ -0,0 +1,461 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
+	"github.com/decred/politeia/politeiad/plugins/pi"
+	"github.com/decred/politeia/politeiad/plugins/search"
+	"github.com/decred/politeia/politeiad/plugins/usermd"
+	"github.com/google/uuid"
+)
+
+const (
+	// docKeyPrefix is the plugin cache key prefix for a document. The
+	// record token is appended onto the prefix.
+	docKeyPrefix = "doc-"
+
+	// listKeyPrefix is the plugin cache key prefix for the list of
+	// documents of a record state. The record state is appended onto
+	// the prefix.
+	listKeyPrefix = "list-"
+
+	// nameWeight is the number of times that a term in the record name
+	// is counted. This ranks records whose name matches the search
+	// query above records where the term is only found in the body.
+	nameWeight = 3
+
+	// termLengthMin is the minimum number of characters in a term.
+	// Shorter terms are not indexed.
+	termLengthMin = 2
+
+	// bm25K1 and bm25B are the BM25 ranking function parameters. K1
+	// controls the term frequency saturation and B controls the
+	// document length normalization.
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// document is the search index entry of a record. The terms contain the
+// frequency of every term that was found in the record name, the record index
+// file, and the author user ID.
+//
+// The document of an unvetted record is encrypted in the plugin cache since
+// it contains the unvetted record content.
+type document struct {
+	Token     string            `json:"token"`
+	State     backend.StateT    `json:"state"`
+	Status    backend.StatusT   `json:"status"`
+	Timestamp int64             `json:"timestamp"`
+	Name      string            `json:"name"`
+	UserID    string            `json:"userid"`
+	Terms     map[string]uint32 `json:"terms"`  // [term]frequency
+	Length    uint32            `json:"length"` // Sum of all frequencies
+}
+
+// docList contains the tokens of all documents of a record state.
+type docList struct {
+	Tokens map[string]struct{} `json:"tokens"`
+}
+
+// newDocument returns the search document for a record. The files must
+// include the record index file and the proposal metadata file if they exist.
+// Any other files are ignored.
+func newDocument(rm backend.RecordMetadata, metadata []backend.MetadataStream, files []backend.File) (*document, error) {
+	d := document{
+		Token:     rm.Token,
+		State:     rm.State,
+		Status:    rm.Status,
+		Timestamp: rm.Timestamp,
+		Terms:     make(map[string]uint32, 256),
+	}
+	add := func(text string, weight uint32) {
+		for _, v := range tokenize(text) {
+			d.Terms[v] += weight
+			d.Length += weight
+		}
+	}
+
+	for _, v := range files {
+		switch v.Name {
+		case pi.FileNameProposalMetadata:
+			b, err := base64.StdEncoding.DecodeString(v.Payload)
+			if err != nil {
+				return nil, err
+			}
+			var pm pi.ProposalMetadata
+			err = json.Unmarshal(b, &pm)
+			if err != nil {
+				return nil, err
+			}
+			d.Name = pm.Name
+			add(pm.Name, nameWeight)
+
+		case pi.FileNameIndexFile:
+			b, err := base64.StdEncoding.DecodeString(v.Payload)
+			if err != nil {
+				return nil, err
+			}
+			add(string(b), 1)
+		}
+	}
+
+	for _, v := range metadata {
+		if v.PluginID != usermd.PluginID ||
+			v.StreamID != usermd.StreamIDUserMetadata {
+			continue
+		}
+		var um usermd.UserMetadata
+		err := json.Unmarshal([]byte(v.Payload), &um)
+		if err != nil {
+			return nil, err
+		}
+		d.UserID = um.UserID
+		d.Terms[strings.ToLower(um.UserID)]++
+		d.Length++
+		break
+	}
+
+	return &d, nil
+}
+
+// tokenize splits the provided text into lower case terms. Any character that
+// is not a letter or a digit separates terms.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, 0, len(words))
+	for _, v := range words {
+		if utf8.RuneCountInString(v) < termLengthMin {
+			continue
+		}
+		terms = append(terms, v)
+	}
+	return terms
+}
+
+// queryTerms returns the unique terms of a search query. A user ID is kept
+// as a single term so that it matches the author of a record.
+func queryTerms(query string) []string {
+	var (
+		terms = make([]string, 0, 16)
+		seen  = make(map[string]struct{}, 16)
+	)
+	for _, word := range strings.Fields(query) {
+		words := []string{strings.ToLower(word)}
+		if _, err := uuid.Parse(word); err != nil {
+			words = tokenize(word)
+		}
+		for _, v := range words {
+			if _, ok := seen[v]; ok {
+				continue
+			}
+			seen[v] = struct{}{}
+			terms = append(terms, v)
+		}
+	}
+	return terms
+}
+
+// filter returns whether a document passes the search filters.
+func filter(d document, s search.Search) bool {
+	switch {
+	case s.Status != 0 && uint32(d.Status) != s.Status:
+		return false
+	case s.UserID != "" && d.UserID != s.UserID:
+		return false
+	case s.After != 0 && d.Timestamp < s.After:
+		return false
+	case s.Before != 0 && d.Timestamp > s.Before:
+		return false
+	}
+	return true
+}
+
+// rank returns the documents that match at least one of the provided terms
+// and pass the search filters. The results are ranked using the BM25 ranking
+// function and are sorted by score from highest to lowest. Results with the
+// same score are sorted by timestamp from newest to oldest.
+//
+// The document frequencies and the average document length are computed
+// using all provided documents, not only the ones that pass the filters, so
+// that the filters do not change the relative scores of the results.
+func rank(docs []document, terms []string, s search.Search) []search.Result {
+	if len(docs) == 0 {
+		return []search.Result{}
+	}
+
+	// Compile the document frequency of every term and the average
+	// document length.
+	var (
+		df    = make(map[string]int, len(terms))
+		total uint64
+	)
+	for _, d := range docs {
+		total += uint64(d.Length)
+		for _, t := range terms {
+			if _, ok := d.Terms[t]; ok {
+				df[t]++
+			}
+		}
+	}
+	var (
+		n     = float64(len(docs))
+		avgdl = float64(total) / n
+	)
+	if avgdl == 0 {
+		avgdl = 1
+	}
+
+	results := make([]search.Result, 0, len(docs))
+	for _, d := range docs {
+		if !filter(d, s) {
+			continue
+		}
+		var score float64
+		for _, t := range terms {
+			tf, ok := d.Terms[t]
+			if !ok {
+				continue
+			}
+			var (
+				f    = float64(tf)
+				idf  = math.Log(1 + (n-float64(df[t])+0.5)/(float64(df[t])+0.5))
+				norm = bm25K1 * (1 - bm25B + bm25B*float64(d.Length)/avgdl)
+			)
+			score += idf * f * (bm25K1 + 1) / (f + norm)
+		}
+		if score == 0 {
+			continue
+		}
+		results = append(results, search.Result{
+			Token:     d.Token,
+			Name:      d.Name,
+			UserID:    d.UserID,
+			Status:    uint32(d.Status),
+			Timestamp: d.Timestamp,
+			Score:     score,
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].Timestamp != results[j].Timestamp {
+			return results[i].Timestamp > results[j].Timestamp
+		}
+		return results[i].Token < results[j].Token
+	})
+
+	return results
+}
+
+// indexClient provides an API for interacting with the search index. The
+// index is saved to the TstoreClient provided plugin cache.
+//
+// A mutex is required because tstore does not provide plugins with a sql
+// transaction that can be used to execute multiple database requests
+// atomically. Concurrent access to the document lists during updates must be
+// controlled locally using a mutex.
+type indexClient struct {
+	sync.Mutex
+	tstore plugins.TstoreClient
+}
+
+// newIndexClient returns a new indexClient.
+func newIndexClient(tstore plugins.TstoreClient) *indexClient {
+	return &indexClient{
+		tstore: tstore,
+	}
+}
+
+// Put adds a document to the index or replaces the existing document of the
+// record. The document is moved to the document list of its record state.
+//
+// This function is concurrency safe.
+func (c *indexClient) Put(d document) error {
+	c.Lock()
+	defer c.Unlock()
+
+	b, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	encrypt := d.State != backend.StateVetted
+	err = c.tstore.CachePut(map[string][]byte{docKey(d.Token): b}, encrypt)
+	if err != nil {
+		return err
+	}
+
+	return c.updateLists(d.Token, d.State)
+}
+
+// Del deletes the document of a record from the index. This is a no-op if the
+// record has not been indexed.
+//
+// This function is concurrency safe.
+func (c *indexClient) Del(token string) error {
+	c.Lock()
+	defer c.Unlock()
+
+	err := c.updateLists(token, backend.StateInvalid)
+	if err != nil {
+		return err
+	}
+
+	return c.tstore.CacheDel([]string{docKey(token)})
+}
+
+// Get returns the indexed document of a record. Nil is returned if the record
+// has not been indexed.
+//
+// This function is concurrency safe.
+func (c *indexClient) Get(token string) (*document, error) {
+	key := docKey(token)
+	blobs, err := c.tstore.CacheGet([]string{key})
+	if err != nil {
+		return nil, err
+	}
+	b, ok := blobs[key]
+	if !ok {
+		return nil, nil
+	}
+	var d document
+	err = json.Unmarshal(b, &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Docs returns all indexed documents of a record state.
+//
+// This function is concurrency safe.
+func (c *indexClient) Docs(state backend.StateT) ([]document, error) {
+	l, err := c.list(state)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(l.Tokens))
+	for token := range l.Tokens {
+		keys = append(keys, docKey(token))
+	}
+	blobs, err := c.tstore.CacheGet(keys)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]document, 0, len(blobs))
+	for _, b := range blobs {
+		var d document
+		err = json.Unmarshal(b, &d)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+// Tokens returns the tokens of all indexed records.
+//
+// This function is concurrency safe.
+func (c *indexClient) Tokens() ([]string, error) {
+	tokens := make([]string, 0, 256)
+	for _, s := range []backend.StateT{backend.StateUnvetted,
+		backend.StateVetted} {
+		l, err := c.list(s)
+		if err != nil {
+			return nil, err
+		}
+		for token := range l.Tokens {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// updateLists adds the token to the document list of the provided record
+// state and removes it from the document lists of all other states. The
+// token is removed from all lists if the state is StateInvalid.
+//
+// This function must be called WITH the lock held.
+func (c *indexClient) updateLists(token string, state backend.StateT) error {
+	blobs := make(map[string][]byte, 2)
+	for _, s := range []backend.StateT{backend.StateUnvetted,
+		backend.StateVetted} {
+		l, err := c.list(s)
+		if err != nil {
+			return err
+		}
+		_, ok := l.Tokens[token]
+		switch {
+		case s == state && !ok:
+			l.Tokens[token] = struct{}{}
+		case s != state && ok:
+			delete(l.Tokens, token)
+		default:
+			// No changes
+			continue
+		}
+		b, err := json.Marshal(l)
+		if err != nil {
+			return err
+		}
+		blobs[listKey(s)] = b
+	}
+	if len(blobs) == 0 {
+		return nil
+	}
+	return c.tstore.CachePut(blobs, false)
+}
+
+// list returns the document list of a record state. An empty list is returned
+// if one does not exist yet.
+func (c *indexClient) list(state backend.StateT) (*docList, error) {
+	key := listKey(state)
+	blobs, err := c.tstore.CacheGet([]string{key})
+	if err != nil {
+		return nil, err
+	}
+	l := docList{
+		Tokens: make(map[string]struct{}),
+	}
+	b, ok := blobs[key]
+	if !ok {
+		return &l, nil
+	}
+	err = json.Unmarshal(b, &l)
+	if err != nil {
+		return nil, err
+	}
+	if l.Tokens == nil {
+		l.Tokens = make(map[string]struct{})
+	}
+	return &l, nil
+}
+
+// docKey returns the plugin cache key for the document of a record.
+func docKey(token string) string {
+	return docKeyPrefix + token
+}
+
+// listKey returns the plugin cache key for the document list of a record
+// state.
+func listKey(state backend.StateT) string {
+	return fmt.Sprintf("%v%v", listKeyPrefix, state)
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
+	"github.com/decred/politeia/politeiad/plugins/pi"
+	"github.com/decred/politeia/politeiad/plugins/search"
+	"github.com/decred/politeia/politeiad/plugins/usermd"
+)
+
+// testCache is an in-memory plugin cache. Only the cache methods of the
+// TstoreClient interface are implemented.
+type testCache struct {
+	plugins.TstoreClient
+	blobs     map[string][]byte
+	encrypted map[string]bool
+}
+
+func newTestCache() *testCache {
+	return &testCache{
+		blobs:     make(map[string][]byte),
+		encrypted: make(map[string]bool),
+	}
+}
+
+func (c *testCache) CachePut(blobs map[string][]byte, encrypt bool) error {
+	for k, v := range blobs {
+		c.blobs[k] = v
+		c.encrypted[k] = encrypt
+	}
+	return nil
+}
+
+func (c *testCache) CacheDel(keys []string) error {
+	for _, k := range keys {
+		delete(c.blobs, k)
+		delete(c.encrypted, k)
+	}
+	return nil
+}
+
+func (c *testCache) CacheGet(keys []string) (map[string][]byte, error) {
+	blobs := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		if b, ok := c.blobs[k]; ok {
+			blobs[k] = b
+		}
+	}
+	return blobs, nil
+}
+
+// newTestDocument returns the document of a proposal with the provided name,
+// index file text, and author.
+func newTestDocument(t *testing.T, token, name, text, userID string, timestamp int64) document {
+	t.Helper()
+
+	pm, err := json.Marshal(pi.ProposalMetadata{Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	um, err := json.Marshal(usermd.UserMetadata{UserID: userID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := newDocument(
+		backend.RecordMetadata{
+			Token:     token,
+			State:     backend.StateVetted,
+			Status:    backend.StatusPublic,
+			Timestamp: timestamp,
+		},
+		[]backend.MetadataStream{
+			{
+				PluginID: usermd.PluginID,
+				StreamID: usermd.StreamIDUserMetadata,
+				Payload:  string(um),
+			},
+		},
+		[]backend.File{
+			{
+				Name:    pi.FileNameProposalMetadata,
+				Payload: base64.StdEncoding.EncodeToString(pm),
+			},
+			{
+				Name:    pi.FileNameIndexFile,
+				Payload: base64.StdEncoding.EncodeToString([]byte(text)),
+			},
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *d
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("# Marketing Proposal\n\nA *new* [website](x.io) for Q3!")
+	want := []string{"marketing", "proposal", "new", "website", "io",
+		"for", "q3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestQueryTerms(t *testing.T) {
+	userID := "6e6c7f4c-6a4d-4a2b-9a0e-3c2b1a0f9e8d"
+	got := queryTerms("Website website, redesign " + userID)
+	want := []string{"website", "redesign", userID}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestNewDocument(t *testing.T) {
+	userID := "6e6c7f4c-6a4d-4a2b-9a0e-3c2b1a0f9e8d"
+	d := newTestDocument(t, "aa", "Website redesign",
+		"Redesign the website.", userID, 100)
+
+	if d.Name != "Website redesign" || d.UserID != userID {
+		t.Fatalf("got name %v user %v", d.Name, d.UserID)
+	}
+	wantTerms := map[string]uint32{
+		"website":  nameWeight + 1,
+		"redesign": nameWeight + 1,
+		"the":      1,
+		userID:     1,
+	}
+	if !reflect.DeepEqual(d.Terms, wantTerms) {
+		t.Fatalf("got terms %v, want %v", d.Terms, wantTerms)
+	}
+	if d.Length != 2*nameWeight+4 {
+		t.Fatalf("got length %v, want %v", d.Length, 2*nameWeight+4)
+	}
+}
+
+func TestRank(t *testing.T) {
+	var (
+		alice = "6e6c7f4c-6a4d-4a2b-9a0e-3c2b1a0f9e8d"
+		bob   = "0b1c2d3e-4f50-4a6b-8c7d-8e9f0a1b2c3d"
+	)
+	docs := []document{
+		newTestDocument(t, "aa", "Website redesign",
+			"Redesign the politeia website.", alice, 100),
+		newTestDocument(t, "bb", "Marketing",
+			"Marketing that links to the website.", bob, 200),
+		newTestDocument(t, "cc", "Development",
+			"Development of dcrd.", bob, 300),
+	}
+
+	// A match in the name is ranked above a match in the body
+	results := rank(docs, queryTerms("website"), search.Search{})
+	if len(results) != 2 {
+		t.Fatalf("got %v results, want 2", len(results))
+	}
+	if results[0].Token != "aa" || results[1].Token != "bb" {
+		t.Fatalf("got order %v %v, want aa bb",
+			results[0].Token, results[1].Token)
+	}
+
+	// Filters
+	tests := []struct {
+		name   string
+		query  string
+		filter search.Search
+		want   []string
+	}{
+		{"author query", alice, search.Search{}, []string{"aa"}},
+		{"user filter", "website", search.Search{UserID: bob}, []string{"bb"}},
+		{"after", "the", search.Search{After: 150}, []string{"bb"}},
+		{"before", "the", search.Search{Before: 150}, []string{"aa"}},
+		{
+			"status",
+			"development",
+			search.Search{Status: uint32(backend.StatusArchived)},
+			[]string{},
+		},
+		{"no match", "treasury", search.Search{}, []string{}},
+	}
+	for _, v := range tests {
+		t.Run(v.name, func(t *testing.T) {
+			results := rank(docs, queryTerms(v.query), v.filter)
+			got := make([]string, 0, len(results))
+			for _, r := range results {
+				got = append(got, r.Token)
+			}
+			if !reflect.DeepEqual(got, v.want) {
+				t.Fatalf("got %v, want %v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestIndexClient(t *testing.T) {
+	var (
+		cache = newTestCache()
+		c     = newIndexClient(cache)
+		d     = newTestDocument(t, "aa", "Website", "", "", 100)
+	)
+
+	// Unvetted documents are encrypted
+	d.State = backend.StateUnvetted
+	err := c.Put(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cache.encrypted[docKey("aa")] {
+		t.Fatalf("unvetted document was not encrypted")
+	}
+	docs, err := c.Docs(backend.StateVetted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 0 {
+		t.Fatalf("got %v vetted documents, want 0", len(docs))
+	}
+
+	// Making the record vetted moves the document
+	d.State = backend.StateVetted
+	err = c.Put(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.encrypted[docKey("aa")] {
+		t.Fatalf("vetted document was encrypted")
+	}
+	docs, err = c.Docs(backend.StateVetted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || !reflect.DeepEqual(docs[0], d) {
+		t.Fatalf("got vetted documents %+v, want %+v", docs, d)
+	}
+	docs, err = c.Docs(backend.StateUnvetted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 0 {
+		t.Fatalf("got %v unvetted documents, want 0", len(docs))
+	}
+
+	// Delete the document
+	err = c.Del("aa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := c.Tokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 0 {
+		t.Fatalf("got tokens %v after delete", tokens)
+	}
+	if _, ok := cache.blobs[docKey("aa")]; ok {
+		t.Fatalf("document was not deleted")
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package search
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
+	"github.com/decred/politeia/politeiad/plugins/pi"
+	"github.com/decred/politeia/politeiad/plugins/search"
+)
+
+var (
+	_ plugins.PluginClient = (*searchPlugin)(nil)
+)
+
+// searchPlugin is the tstore backend implementation of the search plugin. The
+// search plugin indexes the text content of records and provides full-text
+// search of the indexed records.
+//
+// The index is updated using the post plugin hooks of all record writes. A
+// record is removed from the index when it is censored and the individually
+// censored files of a record are removed from its document.
+//
+// searchPlugin satisfies the plugins PluginClient interface.
+type searchPlugin struct {
+	tstore plugins.TstoreClient
+
+	// index provides an API for interacting with the search index. The
+	// data is saved to the tstore provided plugin cache.
+	index *indexClient
+
+	// Plugin settings
+	pageSize uint32
+}
+
+// Setup performs any plugin setup that is required.
+//
+// This function satisfies the plugins PluginClient interface.
+func (p *searchPlugin) Setup() error {
+	log.Tracef("search Setup")
+
+	return nil
+}
+
+// Cmd executes a plugin command.
+//
+// This function satisfies the plugins PluginClient interface.
+func (p *searchPlugin) Cmd(token []byte, cmd, payload string) (string, error) {
+	log.Tracef("search Cmd: %x %v %v", token, cmd, payload)
+
+	switch cmd {
+	case search.CmdSearch:
+		return p.cmdSearch(payload)
+	}
+
+	return "", backend.ErrPluginCmdInvalid
+}
+
+// Hook executes a plugin hook.
+//
+// This function satisfies the plugins PluginClient interface.
+func (p *searchPlugin) Hook(h plugins.HookT, payload string) error {
+	log.Tracef("search Hook: %v", plugins.Hooks[h])
+
+	switch h {
+	case plugins.HookTypeNewRecordPost:
+		return p.hookNewRecordPost(payload)
+	case plugins.HookTypeEditRecordPost:
+		return p.hookEditRecordPost(payload)
+	case plugins.HookTypeSetRecordStatusPost:
+		return p.hookSetRecordStatusPost(payload)
+	case plugins.HookTypeCensorFilesPost:
+		return p.hookCensorFilesPost(payload)
+	}
+
+	return nil
+}
+
+// Fsck performs a plugin file system check. The plugin is provided with the
+// tokens for all records in the backend.
+//
+// The document of every record is rebuilt from the record content and is
+// compared to the indexed document. Missing and stale documents are replaced.
+// Documents of records that no longer exist are removed from the index.
+//
+// This function satisfies the plugins PluginClient interface.
+func (p *searchPlugin) Fsck(tokens [][]byte, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
+	log.Tracef("search Fsck")
+
+	var (
+		issues = make([]backend.FsckIssue, 0, 16)
+		all    = make(map[string]struct{}, len(tokens))
+	)
+	for i, token := range tokens {
+		all[hex.EncodeToString(token)] = struct{}{}
+
+		unlock := opts.RecordLock(token)
+		issue, err := p.fsckDocument(token, opts.DryRun)
+		unlock()
+		if err != nil {
+			return nil, err
+		}
+		if issue != nil {
+			issues = append(issues, *issue)
+		}
+
+		opts.Progress(i+1, len(tokens))
+	}
+
+	// Remove the documents of records that do not exist
+	indexed, err := p.index.Tokens()
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range indexed {
+		if _, ok := all[token]; ok {
+			continue
+		}
+		issues = append(issues, backend.FsckIssue{
+			Type:        backend.FsckIssueCacheStale,
+			Token:       token,
+			Description: "search index contains a record that does not exist",
+			Repaired:    !opts.DryRun,
+		})
+		if opts.DryRun {
+			continue
+		}
+		err := p.index.Del(token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Infof("%v search index issues found", len(issues))
+
+	return issues, nil
+}
+
+// fsckDocument verifies the indexed document of a record. A missing or stale
+// document is replaced unless this is a dry run.
+//
+// This function must be called WITH the record lock held.
+func (p *searchPlugin) fsckDocument(token []byte, dryRun bool) (*backend.FsckIssue, error) {
+	want, err := p.document(token)
+	if err != nil {
+		return nil, err
+	}
+	got, err := p.index.Get(hex.EncodeToString(token))
+	if err != nil {
+		return nil, err
+	}
+
+	var desc string
+	switch {
+	case want == nil && got == nil:
+		// Censored record that has not been indexed
+		return nil, nil
+	case want == nil:
+		desc = "search index contains a censored record"
+	case got == nil:
+		desc = "record missing from the search index"
+	case !reflect.DeepEqual(want, got):
+		desc = "search index document does not match the record"
+	default:
+		// Document is up to date
+		return nil, nil
+	}
+	issue := backend.FsckIssue{
+		Type:        backend.FsckIssueCacheStale,
+		Token:       hex.EncodeToString(token),
+		Description: desc,
+		Repaired:    !dryRun,
+	}
+	if dryRun {
+		return &issue, nil
+	}
+
+	if want == nil {
+		err = p.index.Del(issue.Token)
+	} else {
+		err = p.index.Put(*want)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &issue, nil
+}
+
+// document builds the search document of a record from the latest version of
+// the record. Nil is returned if the record has been censored. Only the files
+// that are indexed are retrieved.
+func (p *searchPlugin) document(token []byte) (*document, error) {
+	filenames := []string{
+		pi.FileNameIndexFile,
+		pi.FileNameProposalMetadata,
+	}
+	r, err := p.tstore.RecordPartial(token, 0, filenames, false)
+	if err != nil {
+		return nil, err
+	}
+	if r.RecordMetadata.Status == backend.StatusCensored {
+		return nil, nil
+	}
+	return newDocument(r.RecordMetadata, r.Metadata, r.Files)
+}
+
+// Settings returns the plugin's settings.
+//
+// This function satisfies the plugins PluginClient interface.
+func (p *searchPlugin) Settings() []backend.PluginSetting {
+	log.Tracef("search Settings")
+
+	return []backend.PluginSetting{
+		{
+			Key:   search.SettingKeyPageSize,
+			Value: strconv.FormatUint(uint64(p.pageSize), 10),
+		},
+	}
+}
+
+// New returns a new searchPlugin.
+func New(tstore plugins.TstoreClient, settings []backend.PluginSetting) (*searchPlugin, error) {
+	// Setup plugin setting default values
+	pageSize := search.SettingPageSize
+
+	// Override defaults with any passed in settings
+	for _, v := range settings {
+		switch v.Key {
+		case search.SettingKeyPageSize:
+			u, err := strconv.ParseUint(v.Value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("plugin setting '%v': ParseUint(%v): %v",
+					v.Key, v.Value, err)
+			}
+			pageSize = uint32(u)
+			log.Infof("Plugin setting updated: search %v %v",
+				search.SettingKeyPageSize, pageSize)
+
+		default:
+			return nil, fmt.Errorf("invalid plugin setting '%v'", v.Key)
+		}
+	}
+
+	return &searchPlugin{
+		tstore:   tstore,
+		index:    newIndexClient(tstore),
+		pageSize: pageSize,
+	}, nil
+}
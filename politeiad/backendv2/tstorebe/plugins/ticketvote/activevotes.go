@@ -206,6 +206,21 @@ func (a *activeVotes) AddCastVote(token, ticket, votebit string) {
 	av.CastVotes[ticket] = votebit
 }
 
+// ResetCastVotes removes all of the cast ticket votes of an active vote from
+// the active votes cache.
+func (a *activeVotes) ResetCastVotes(token string) {
+	a.Lock()
+	defer a.Unlock()
+
+	av, ok := a.activeVotes[token]
+	if !ok {
+		return
+	}
+
+	av.CastVotes = make(map[string]string, len(av.CastVotes))
+	a.activeVotes[token] = av
+}
+
 // AddCommitmentAddrs adds commitment addresses to the cache for a record.
 func (a *activeVotes) AddCommitmentAddrs(token string, addrs map[string]commitmentAddr) {
 	a.Lock()
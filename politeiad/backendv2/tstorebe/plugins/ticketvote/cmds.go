@@ -2706,12 +2706,28 @@ func (p *ticketVotePlugin) timestamp(token []byte, digest []byte) (*ticketvote.T
 			ExtraData:  v.ExtraData,
 		})
 	}
+	anchors := make([]ticketvote.TimestampAnchor, 0, len(t.Anchors))
+	for _, v := range t.Anchors {
+		anchors = append(anchors, ticketvote.TimestampAnchor{
+			Provider:   v.Provider,
+			TxID:       v.TxID,
+			MerkleRoot: v.MerkleRoot,
+			Proof: ticketvote.Proof{
+				Type:       v.Proof.Type,
+				Digest:     v.Proof.Digest,
+				MerkleRoot: v.Proof.MerkleRoot,
+				MerklePath: v.Proof.MerklePath,
+				ExtraData:  v.Proof.ExtraData,
+			},
+		})
+	}
 	return &ticketvote.Timestamp{
 		Data:       t.Data,
 		Digest:     t.Digest,
 		TxID:       t.TxID,
 		MerkleRoot: t.MerkleRoot,
 		Proofs:     proofs,
+		Anchors:    anchors,
 	}, nil
 }
 
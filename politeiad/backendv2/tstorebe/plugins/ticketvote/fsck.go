@@ -5,24 +5,36 @@
 package ticketvote
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+
 	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
 	"github.com/decred/politeia/politeiad/plugins/ticketvote"
 )
 
 // fsck performs the ticketvote plugin file system check. The following checks
 // are performed:
 //
-//  1. Rebuild the vote summaries cache. Records that have finished voting
-//     will have a vote summary saved to the cache. This cache is rebuilt
-//     from scratch.
+//  1. Verify the vote summaries cache. Records that have finished voting
+//     will have a vote summary saved to the cache. A cached summary is stale
+//     if its vote results do not match the cast votes. Stale summaries are
+//     rebuilt.
 //
-//  2. Rebuild the vote inventory cache. All vetted records are included in the
-//     vote inventory cache. This cache is rebuilt from scratch.
+//  2. Verify the vote inventory cache. All vetted records are included in the
+//     vote inventory cache. Entries that do not match the vote summary of
+//     their record are replaced.
 //
-//  3. Rebuild the runoff vote submissions cache. The submissions cache contains
+//  3. Verify the runoff vote submissions cache. The submissions cache contains
 //     the list of runoff vote parent records and all of their runoff vote
-//     submissions. This cache is built from scratch.
-func (p *ticketVotePlugin) fsck(tokens [][]byte) error {
+//     submissions. Missing submissions are added and invalid submissions are
+//     removed.
+//
+// The backend remains online during the fsck. The caches are repaired one
+// record at a time while holding the record lock so that a repair does not
+// overwrite a concurrent update. Nothing is repaired during a dry run.
+func (p *ticketVotePlugin) fsck(tokens [][]byte, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
 	log.Infof("Starting ticketvote fsck for %v records", len(tokens))
 
 	// Filter out the vetted records. The ticketvote plugin
@@ -31,7 +43,7 @@ func (p *ticketVotePlugin) fsck(tokens [][]byte) error {
 	for _, token := range tokens {
 		state, err := p.tstore.RecordState(token)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if state == backend.StateVetted {
 			vetted = append(vetted, token)
@@ -40,23 +52,18 @@ func (p *ticketVotePlugin) fsck(tokens [][]byte) error {
 
 	log.Infof("%v vetted records found", len(vetted))
 
-	// 1. Rebuild the vote summaries cache.
-	//
-	// Records that have finished voting will have a vote summary saved
-	// to the cache. The summary() function builds the vote summary from
-	// scratch for a record and saves it to the cache when appropriate.
-	// The only thing we need to do to rebuild the vote summaries cache
-	// is to delete existing entries and then invoke the summary()
-	// function on all vetted records.
-
-	log.Infof("Building the vote summaries cache")
+	// 1. Verify the vote summaries cache.
+	log.Infof("Verifying the vote summaries cache")
 
 	bestBlock, err := p.bestBlock()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	summaries := make(map[string]*ticketvote.SummaryReply, len(vetted))
+	var (
+		issues    = make([]backend.FsckIssue, 0, 16)
+		summaries = make(map[string]*ticketvote.SummaryReply, len(vetted))
+	)
 	for i, tokenB := range vetted {
 		// Building a vote summary requires retrieving various pieces
 		// of data from the database. This is expensive. Log progress
@@ -64,102 +71,339 @@ func (p *ticketVotePlugin) fsck(tokens [][]byte) error {
 		if i%5 == 0 {
 			log.Infof("Vote summaries cache progress %v/%v", i, len(vetted))
 		}
-		token := tokenEncode(tokenB)
-		err = p.summaries.Del(token)
+		unlock := opts.RecordLock(tokenB)
+		s, desc, err := p.fsckSummary(tokenB, bestBlock, opts.DryRun)
+		unlock()
 		if err != nil {
-			return err
+			return nil, err
+		}
+		token := tokenEncode(tokenB)
+		if desc != "" {
+			issues = append(issues, backend.FsckIssue{
+				Type:        backend.FsckIssueCacheStale,
+				Token:       token,
+				Description: desc,
+				Repaired:    !opts.DryRun,
+			})
 		}
+		summaries[token] = s
+
+		opts.Progress(i+1, len(vetted))
+	}
+
+	log.Infof("Vote summaries cache verified")
+
+	// 2. Verify the vote inventory cache.
+	log.Infof("Verifying the vote inventory cache")
+
+	ii, err := p.fsckInv(summaries, bestBlock, opts)
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, ii...)
+
+	log.Infof("Vote inventory cache verified")
+
+	// 3. Verify the runoff vote submissions cache.
+	log.Infof("Verifying the runoff vote submissions cache")
+
+	si, err := p.fsckSubs(vetted, opts)
+	if err != nil {
+		return nil, err
+	}
+	issues = append(issues, si...)
+
+	log.Info("Runoff vote submissions cache verified")
+
+	return issues, nil
+}
+
+// fsckSummary verifies the cached vote summary of a record and returns the
+// vote summary. A description of the problem is returned if the cached
+// summary is stale. The stale summary is rebuilt unless this is a dry run.
+//
+// This function must be called WITH the record lock held.
+func (p *ticketVotePlugin) fsckSummary(tokenB []byte, bestBlock uint32, dryRun bool) (*ticketvote.SummaryReply, string, error) {
+	token := tokenEncode(tokenB)
+	cached, err := p.summaries.Get(token)
+	if errors.Is(err, errSummaryNotFound) {
+		// Summaries are only cached once the voting period has
+		// ended. There is nothing to verify.
 		s, err := p.summary(tokenB, bestBlock)
+		return s, "", err
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	// Verify the cached summary against the cast votes
+	var desc string
+	vd, err := p.voteDetails(tokenB)
+	if err != nil {
+		return nil, "", err
+	}
+	if vd == nil {
+		desc = "vote summary is cached for a record that has no vote details"
+	} else {
+		results, err := p.voteOptionResults(tokenB, vd.Params.Options)
 		if err != nil {
-			return err
+			return nil, "", err
 		}
-		summaries[token] = s
+		if !reflect.DeepEqual(cached.Results, results) {
+			desc = "cached vote summary results do not match the cast votes"
+		}
+	}
+	if desc == "" || dryRun {
+		cached.BestBlock = bestBlock
+		return cached, desc, nil
 	}
 
-	log.Infof("Vote summaries cache complete")
+	// Rebuild the vote summary
+	err = p.summaries.Del(token)
+	if err != nil {
+		return nil, "", err
+	}
+	s, err := p.summary(tokenB, bestBlock)
+	if err != nil {
+		return nil, "", err
+	}
 
-	// 2. Rebuild the vote inventory cache.
-	//
-	// All vetted records are included in the vote inventory cache.
-	// This cache is built from scratch.
+	return s, desc, nil
+}
 
-	log.Infof("Building the vote inventory cache")
+// fsckInv verifies the vote inventory cache against the provided vote
+// summaries of all vetted records.
+//
+// The entries that need to be verified are found using a snapshot of the
+// inventory. Each of these entries is then verified again while holding the
+// record lock before it is repaired.
+func (p *ticketVotePlugin) fsckInv(summaries map[string]*ticketvote.SummaryReply, bestBlock uint32, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
+	inv, err := p.inv.Get(bestBlock)
+	if err != nil {
+		return nil, err
+	}
 
-	entries := make([]invEntry, 0, len(summaries))
-	for token, v := range summaries {
-		e := newInvEntry(token, v.Status, v.Timestamp, v.EndBlockHeight)
-		entries = append(entries, *e)
+	// Compile the tokens whose entries don't match
+	var (
+		cached  = make(map[string]ticketvote.VoteStatusT, len(summaries))
+		suspect = make([]string, 0, 16)
+	)
+	for status, entries := range inv.Entries {
+		for _, v := range entries {
+			cached[v.Token] = status
+		}
+	}
+	for token, s := range summaries {
+		status, ok := cached[token]
+		if !ok || status != s.Status {
+			suspect = append(suspect, token)
+		}
+	}
+	for token := range cached {
+		if _, ok := summaries[token]; !ok {
+			suspect = append(suspect, token)
+		}
 	}
-	p.inv.Rebuild(entries)
 
-	log.Infof("Vote inventory cache complete")
+	// Verify the suspect entries
+	issues := make([]backend.FsckIssue, 0, len(suspect))
+	for _, token := range suspect {
+		desc, err := p.fsckInvEntry(token, bestBlock, opts)
+		if err != nil {
+			return nil, err
+		}
+		if desc == "" {
+			// The entry was updated after the snapshot was taken
+			continue
+		}
+		issues = append(issues, backend.FsckIssue{
+			Type:        backend.FsckIssueInventoryDrift,
+			Token:       token,
+			Description: desc,
+			Repaired:    !opts.DryRun,
+		})
+	}
 
-	// 3. Rebuild the runoff vote submissions cache.
-	//
-	// The submissions cache contains the list of runoff vote parent
-	// records and all of their runoff vote submissions. This cache
-	// is built from scratch.
-	log.Infof("Building the runoff vote submissions cache")
+	return issues, nil
+}
 
-	err = p.rebuildSubsCache(vetted)
+// fsckInvEntry verifies the vote inventory entry of a record while holding
+// the record lock. The entry is replaced if it is incorrect, unless this is a
+// dry run.
+func (p *ticketVotePlugin) fsckInvEntry(token string, bestBlock uint32, opts plugins.FsckOpts) (string, error) {
+	tokenB, err := tokenDecode(token)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	log.Info("Runoff vote submissions cache complete")
+	unlock := opts.RecordLock(tokenB)
+	defer unlock()
+
+	// Only vetted records are part of the vote inventory
+	var s *ticketvote.SummaryReply
+	state, err := p.tstore.RecordState(tokenB)
+	switch {
+	case errors.Is(err, backend.ErrRecordNotFound):
+		// Not a record
+	case err != nil:
+		return "", err
+	case state == backend.StateVetted:
+		s, err = p.summary(tokenB, bestBlock)
+		if err != nil {
+			return "", err
+		}
+	}
 
-	return nil
+	return p.inv.Verify(token, s, bestBlock, !opts.DryRun)
 }
 
-// rebuildSubsCache rebuilds the runoff vote submissions cache from scratch.
+// fsckSubs verifies the runoff vote submissions cache.
 //
 // The provided list of tokens should include all runoff vote parent records
-// as well as all runoff vote submissions. The cache is not rebuilt for any
-// parent records that are not included in the list.
-func (p *ticketVotePlugin) rebuildSubsCache(tokens [][]byte) error {
-	// Compile the vote metadata of all runoff vote parents and
-	// submissions.
-	voteMD := make(map[string]*ticketvote.VoteMetadata, len(tokens))
+// as well as all runoff vote submissions.
+func (p *ticketVotePlugin) fsckSubs(tokens [][]byte, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
+	// Compile the expected submissions of all runoff vote parents.
+	// Only public submissions are included in the cache.
+	expected := make(map[string]map[string]struct{}, 16)
 	for _, tokenB := range tokens {
-		r, err := p.recordAbridged(tokenB)
-		if err != nil {
-			return err
-		}
-		vmd, err := voteMetadataDecode(r.Files)
+		parent, isSub, err := p.fsckRunoffSub(tokenB)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if !isRunoffParent(vmd) && !isRunoffSub(vmd) {
-			// Not a runoff vote parent or submission
+		if parent != "" && !isSub {
+			// This is a runoff vote parent
+			if _, ok := expected[parent]; !ok {
+				expected[parent] = make(map[string]struct{}, 16)
+			}
 			continue
 		}
-		if r.RecordMetadata.Status != backend.StatusPublic {
-			// Only public records are included in
-			// the runoff vote submissions cache.
+		if !isSub {
 			continue
 		}
-
-		voteMD[tokenEncode(tokenB)] = vmd
+		subs, ok := expected[parent]
+		if !ok {
+			subs = make(map[string]struct{}, 16)
+			expected[parent] = subs
+		}
+		subs[tokenEncode(tokenB)] = struct{}{}
 	}
 
-	// Delete all existing cache entries
-	for token, v := range voteMD {
-		if !isRunoffParent(v) {
-			continue
-		}
-		err := p.subs.DelEntry(token)
+	// Compare the cached submissions to the expected submissions
+	issues := make([]backend.FsckIssue, 0, 16)
+	for parent, want := range expected {
+		got, err := p.subs.Get(parent)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		suspect := make([]string, 0, 16)
+		for sub := range want {
+			if _, ok := got.Tokens[sub]; !ok {
+				suspect = append(suspect, sub)
+			}
+		}
+		for sub := range got.Tokens {
+			if _, ok := want[sub]; !ok {
+				suspect = append(suspect, sub)
+			}
+		}
+		for _, sub := range suspect {
+			issue, err := p.fsckSub(parent, sub, opts)
+			if err != nil {
+				return nil, err
+			}
+			if issue != nil {
+				issues = append(issues, *issue)
+			}
 		}
 	}
 
-	// Build the cache from scratch
-	for token, v := range voteMD {
-		if !isRunoffSub(v) {
-			continue
-		}
-		p.subs.Add(v.LinkTo, token)
+	return issues, nil
+}
+
+// fsckSub verifies the runoff vote submissions cache entry of a single
+// submission while holding the submission's record lock. The submission is
+// added to or deleted from the parent's submissions list if the list is
+// incorrect, unless this is a dry run.
+func (p *ticketVotePlugin) fsckSub(parent, sub string, opts plugins.FsckOpts) (*backend.FsckIssue, error) {
+	tokenB, err := tokenDecode(sub)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	unlock := opts.RecordLock(tokenB)
+	defer unlock()
+
+	// Determine if the record is a public submission of the parent
+	linkTo, isSub, err := p.fsckRunoffSub(tokenB)
+	switch {
+	case errors.Is(err, backend.ErrRecordNotFound):
+		// Not a record
+	case err != nil:
+		return nil, err
+	}
+	want := isSub && linkTo == parent
+
+	// Compare it to the cache
+	subs, err := p.subs.Get(parent)
+	if err != nil {
+		return nil, err
+	}
+	_, got := subs.Tokens[sub]
+	if got == want {
+		// The cache was updated after it was compared
+		return nil, nil
+	}
+
+	issue := backend.FsckIssue{
+		Type:     backend.FsckIssueCacheStale,
+		Token:    parent,
+		Repaired: !opts.DryRun,
+	}
+	if want {
+		issue.Description = fmt.Sprintf("runoff vote submission %v missing "+
+			"from the submissions cache", sub)
+	} else {
+		issue.Description = fmt.Sprintf("submissions cache contains %v, "+
+			"which is not a public runoff vote submission", sub)
+	}
+	if opts.DryRun {
+		return &issue, nil
+	}
+
+	if want {
+		err = p.subs.Add(parent, sub)
+	} else {
+		err = p.subs.Del(parent, sub)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &issue, nil
+}
+
+// fsckRunoffSub returns the runoff vote parent of a record. The returned bool
+// is true if the record is a public runoff vote submission. The token of the
+// record itself is returned if it is a public runoff vote parent. An empty
+// string is returned for all other records.
+func (p *ticketVotePlugin) fsckRunoffSub(tokenB []byte) (string, bool, error) {
+	r, err := p.recordAbridged(tokenB)
+	if err != nil {
+		return "", false, err
+	}
+	if r.RecordMetadata.Status != backend.StatusPublic {
+		// Only public records are included in the runoff
+		// vote submissions cache.
+		return "", false, nil
+	}
+	vmd, err := voteMetadataDecode(r.Files)
+	if err != nil {
+		return "", false, err
+	}
+	switch {
+	case isRunoffSub(vmd):
+		return vmd.LinkTo, true, nil
+	case isRunoffParent(vmd):
+		return tokenEncode(tokenB), false, nil
+	}
+	return "", false, nil
 }
@@ -6,6 +6,7 @@ package ticketvote
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -91,6 +92,54 @@ func (p *ticketVotePlugin) hookSetRecordStatusPost(payload string) error {
 		recordMD.State, recordMD.Status, srs.Record.Files)
 }
 
+// hookTxRollbackPost rebuilds the active votes cache entry of a record once a
+// transaction on the record has been rolled back. The active votes cache is a
+// memory cache and is not undone by the rollback.
+func (p *ticketVotePlugin) hookTxRollbackPost(payload string) error {
+	var tr plugins.HookTxRollback
+	err := json.Unmarshal([]byte(payload), &tr)
+	if err != nil {
+		return err
+	}
+	token := hex.EncodeToString(tr.Token)
+
+	// Remove the record from the active votes cache if it does not
+	// have an ongoing vote.
+	vd, err := p.voteDetails(tr.Token)
+	if err != nil {
+		return err
+	}
+	if vd == nil {
+		p.activeVotes.Del(token)
+		return nil
+	}
+	bestBlock, err := p.bestBlock()
+	if err != nil {
+		return err
+	}
+	if voteHasEnded(bestBlock, vd.EndBlockHeight) {
+		p.activeVotes.Del(token)
+		return nil
+	}
+
+	// Rebuild the cast votes of the ongoing vote. The commitment
+	// addresses are kept if the vote is already cached.
+	if p.activeVotes.VoteDetails(tr.Token) == nil {
+		p.activeVotesAdd(*vd)
+	} else {
+		p.activeVotes.ResetCastVotes(token)
+	}
+	votes, err := p.voteResults(tr.Token)
+	if err != nil {
+		return err
+	}
+	for _, v := range votes {
+		p.activeVotes.AddCastVote(v.Token, v.Ticket, v.VoteBit)
+	}
+
+	return nil
+}
+
 // linkByVerify verifies that the provided link by timestamp meets all
 // ticketvote plugin requirements. See the ticketvote VoteMetadata structure
 // for more details on the link by timestamp.
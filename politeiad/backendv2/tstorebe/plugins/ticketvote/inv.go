@@ -339,6 +339,83 @@ func (c *invClient) Rebuild(entries []invEntry) error {
 	return c.saveInv(*inv)
 }
 
+// Get returns the full inventory, updated with the provided best block.
+//
+// This function is concurrency safe.
+func (c *invClient) Get(bestBlock uint32) (*inv, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.updateBlockHeight(bestBlock)
+}
+
+// Verify verifies that the inventory entry of a record matches the provided
+// vote summary. A nil summary indicates that the record should not be part of
+// the inventory. A description of the problem is returned if the entry does
+// not match. An empty string is returned if the entry is correct. The entry
+// is replaced if it does not match and fix is set.
+//
+// This function is concurrency safe.
+func (c *invClient) Verify(token string, s *ticketvote.SummaryReply, bestBlock uint32, fix bool) (string, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	inv, err := c.updateBlockHeight(bestBlock)
+	if err != nil {
+		return "", err
+	}
+
+	// Find the existing entry
+	var (
+		status ticketvote.VoteStatusT
+		found  bool
+	)
+	for k, entries := range inv.Entries {
+		if entriesIncludeToken(entries, token) {
+			status = k
+			found = true
+			break
+		}
+	}
+
+	// Compare the entry to the vote summary
+	var desc string
+	switch {
+	case s == nil && found:
+		desc = fmt.Sprintf("vote inventory contains a %v entry for a record "+
+			"that is not vetted", ticketvote.VoteStatuses[status])
+	case s != nil && !found:
+		desc = fmt.Sprintf("%v record missing from the vote inventory",
+			ticketvote.VoteStatuses[s.Status])
+	case s != nil && status != s.Status:
+		desc = fmt.Sprintf("vote inventory status is %v, want %v",
+			ticketvote.VoteStatuses[status], ticketvote.VoteStatuses[s.Status])
+	}
+	if desc == "" || !fix {
+		return desc, nil
+	}
+
+	// Replace the entry
+	if found {
+		err = inv.Del(token, status)
+		if err != nil {
+			return "", err
+		}
+	}
+	if s != nil {
+		inv.Add(*newInvEntry(token, s.Status, s.Timestamp, s.EndBlockHeight))
+		inv.Sort()
+	}
+	err = c.saveInv(*inv)
+	if err != nil {
+		return "", err
+	}
+
+	log.Debugf("Vote inv entry replaced %v", token)
+
+	return desc, nil
+}
+
 // addEntry adds a new entry to the inventory.
 //
 // New entries will always correspond to a vote status that has not been voted
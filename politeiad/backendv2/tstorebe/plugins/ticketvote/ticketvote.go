@@ -219,6 +219,8 @@ func (p *ticketVotePlugin) Hook(h plugins.HookT, payload string) error {
 		return p.hookSetRecordStatusPre(payload)
 	case plugins.HookTypeSetRecordStatusPost:
 		return p.hookSetRecordStatusPost(payload)
+	case plugins.HookTypeTxRollbackPost:
+		return p.hookTxRollbackPost(payload)
 	}
 
 	return nil
@@ -228,10 +230,10 @@ func (p *ticketVotePlugin) Hook(h plugins.HookT, payload string) error {
 // tokens for all records in the backend.
 //
 // This function satisfies the plugins PluginClient interface.
-func (p *ticketVotePlugin) Fsck(tokens [][]byte) error {
+func (p *ticketVotePlugin) Fsck(tokens [][]byte, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
 	log.Tracef("ticketvote Fsck")
 
-	return p.fsck(tokens)
+	return p.fsck(tokens, opts)
 }
 
 // Settings returns the plugin's settings.
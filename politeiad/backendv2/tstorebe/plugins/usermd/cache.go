@@ -26,10 +26,12 @@ const (
 //
 // The Unvetted and Vetted fields contain the records that have been submitted
 // by the user. All record tokens are sorted by the timestamp of their most
-// recent status change from newest to oldest.
+// recent status change from newest to oldest. The Drafts field contains the
+// draft records of the user, sorted by the time they were created.
 type userCache struct {
 	Unvetted []string `json:"unvetted"`
 	Vetted   []string `json:"vetted"`
+	Drafts   []string `json:"drafts,omitempty"`
 }
 
 // userCachePath returns the filepath to the userCache for the specified user.
@@ -116,6 +118,8 @@ func (p *usermdPlugin) userCacheAddToken(userID string, state backend.StateT, to
 		uc.Unvetted = append(uc.Unvetted, token)
 	case backend.StateVetted:
 		uc.Vetted = append(uc.Vetted, token)
+	case backend.StateDraft:
+		uc.Drafts = append(uc.Drafts, token)
 	default:
 		return fmt.Errorf("invalid state %v", state)
 	}
@@ -159,6 +163,13 @@ func (p *usermdPlugin) userCacheDelToken(userID string, state backend.StateT, to
 				userID, state, err)
 		}
 		uc.Vetted = tokens
+	case backend.StateDraft:
+		tokens, err := delToken(uc.Drafts, token)
+		if err != nil {
+			return fmt.Errorf("delToken %v %v: %v",
+				userID, state, err)
+		}
+		uc.Drafts = tokens
 	default:
 		return fmt.Errorf("invalid state %v", state)
 	}
@@ -63,10 +63,17 @@ func (p *usermdPlugin) cmdUserRecords(payload string) (string, error) {
 		vetted = append(vetted, uc.Vetted[i])
 	}
 
+	// Drafts are returned newest to oldest as well
+	drafts := make([]string, 0, len(uc.Drafts))
+	for i := len(uc.Drafts) - 1; i >= 0; i-- {
+		drafts = append(drafts, uc.Drafts[i])
+	}
+
 	// Prepare reply
 	urr := usermd.UserRecordsReply{
 		Unvetted: unvetted,
 		Vetted:   vetted,
+		Drafts:   drafts,
 	}
 	reply, err := json.Marshal(urr)
 	if err != nil {
@@ -156,6 +156,31 @@ func (p *usermdPlugin) hookSetRecordStatusPost(payload string) error {
 	return nil
 }
 
+// hookDelDraftPost removes a deleted draft from the user cache of its
+// author.
+func (p *usermdPlugin) hookDelDraftPost(payload string) error {
+	var dd plugins.HookDelDraft
+	err := json.Unmarshal([]byte(payload), &dd)
+	if err != nil {
+		return err
+	}
+
+	// Decode user metadata
+	um, err := userMetadataDecode(dd.Metadata)
+	if err != nil {
+		return err
+	}
+	if um == nil {
+		// Should not happen. Drafts can not be created without
+		// user metadata.
+		return fmt.Errorf("user metadata not found %v",
+			dd.RecordMetadata.Token)
+	}
+
+	return p.userCacheDelToken(um.UserID, backend.StateDraft,
+		dd.RecordMetadata.Token)
+}
+
 // userMetadataDecode decodes and returns the UserMetadata from the provided
 // backend metadata streams. If a UserMetadata is not found, nil is returned.
 func userMetadataDecode(metadata []backend.MetadataStream) (*usermd.UserMetadata, error) {
@@ -6,6 +6,7 @@ package usermd
 
 import (
 	"encoding/hex"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
@@ -78,6 +79,14 @@ func (p *usermdPlugin) Hook(h plugins.HookT, payload string) error {
 		return p.hookSetRecordStatusPre(payload)
 	case plugins.HookTypeSetRecordStatusPost:
 		return p.hookSetRecordStatusPost(payload)
+	case plugins.HookTypeNewDraftPre:
+		return p.hookNewRecordPre(payload)
+	case plugins.HookTypeNewDraftPost:
+		return p.hookNewRecordPost(payload)
+	case plugins.HookTypeEditDraftPre:
+		return p.hookEditRecordPre(payload)
+	case plugins.HookTypeDelDraftPost:
+		return p.hookDelDraftPost(payload)
 	}
 
 	return nil
@@ -135,80 +144,111 @@ func (p *usermdPlugin) addMissingRecord(tokens []string, missingRecord *backend.
 //     from oldest to newest.
 //
 // This function satisfies the plugins PluginClient interface.
-func (p *usermdPlugin) Fsck(tokens [][]byte) error {
+func (p *usermdPlugin) Fsck(tokens [][]byte, opts plugins.FsckOpts) ([]backend.FsckIssue, error) {
 	log.Tracef("usermd Fsck")
 
-	// Number of records which were added to the user cache.
-	var c int64
-
-	for _, token := range tokens {
-		r, err := p.tstore.RecordPartial(token, 0, nil, true)
+	issues := make([]backend.FsckIssue, 0, 16)
+	for i, token := range tokens {
+		unlock := opts.RecordLock(token)
+		issue, err := p.fsckUserCache(token, opts.DryRun)
+		unlock()
 		if err != nil {
-			return err
+			return nil, err
 		}
-
-		// Decode user metadata
-		um, err := userMetadataDecode(r.Metadata)
-		if err != nil {
-			return err
+		if issue != nil {
+			issues = append(issues, *issue)
 		}
 
-		// Get the user cache for the record's author
-		uc, err := p.userCache(um.UserID)
-		if err != nil {
-			return err
-		}
+		opts.Progress(i+1, len(tokens))
+	}
 
-		// Verify that the record is listed in the user cache under the
-		// correct category.
-		var found bool
-		tokenStr := hex.EncodeToString(token)
-		switch r.RecordMetadata.State {
-		case backend.StateUnvetted:
-			for _, t := range uc.Unvetted {
-				if t == tokenStr {
-					found = true
-				}
-			}
-			// Unvetted record is missing, add it
-			if !found {
-				uc.Unvetted, err = p.addMissingRecord(uc.Unvetted, r)
-				if err != nil {
-					return err
-				}
-			}
-
-		case backend.StateVetted:
-			for _, t := range uc.Vetted {
-				if t == tokenStr {
-					found = true
-				}
-			}
-			// Vetted record is missing, add it
-			if !found {
-				uc.Vetted, err = p.addMissingRecord(uc.Vetted, r)
-				if err != nil {
-					return err
-				}
-			}
-		}
+	log.Infof("%v records were missing from the user records cache",
+		len(issues))
 
-		// If a missing token was added to the user cache, save new user cache
-		// to disk.
-		if !found {
-			err = p.userCacheSave(um.UserID, *uc)
-			if err != nil {
-				return err
-			}
-			c++
-			log.Debugf("Missing %v record %v was added to %v user records cache",
-				backend.States[r.RecordMetadata.State], tokenStr, um.UserID)
+	return issues, nil
+}
+
+// fsckUserCache verifies that a record is listed in the user cache of its
+// author. A missing record is added to the user cache unless this is a dry
+// run. A FsckIssueUserCacheGap issue is returned if the record was missing.
+//
+// This function must be called WITHOUT the lock held.
+func (p *usermdPlugin) fsckUserCache(token []byte, dryRun bool) (*backend.FsckIssue, error) {
+	r, err := p.tstore.RecordPartial(token, 0, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode user metadata
+	um, err := userMetadataDecode(r.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	if um == nil {
+		return nil, fmt.Errorf("user metadata not found %x", token)
+	}
+
+	p.Lock()
+	defer p.Unlock()
+
+	// Get the user cache for the record's author
+	uc, err := p.userCacheLocked(um.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify that the record is listed in the user cache under the
+	// correct category.
+	var (
+		state    = r.RecordMetadata.State
+		tokenStr = hex.EncodeToString(token)
+		cached   []string
+	)
+	switch state {
+	case backend.StateUnvetted:
+		cached = uc.Unvetted
+	case backend.StateVetted:
+		cached = uc.Vetted
+	default:
+		return nil, fmt.Errorf("invalid state %v", state)
+	}
+	for _, t := range cached {
+		if t == tokenStr {
+			return nil, nil
 		}
 	}
 
-	log.Infof("%v missing records were added to the user records cache", c)
+	issue := backend.FsckIssue{
+		Type:  backend.FsckIssueUserCacheGap,
+		Token: tokenStr,
+		Description: fmt.Sprintf("%v record missing from the user "+
+			"cache of %v", backend.States[state], um.UserID),
+	}
+	if dryRun {
+		return &issue, nil
+	}
+
+	// The record is missing. Add it and save the user cache.
+	tokens, err := p.addMissingRecord(cached, r)
+	if err != nil {
+		return nil, err
+	}
+	switch state {
+	case backend.StateUnvetted:
+		uc.Unvetted = tokens
+	case backend.StateVetted:
+		uc.Vetted = tokens
+	}
+	err = p.userCacheSaveLocked(um.UserID, *uc)
+	if err != nil {
+		return nil, err
+	}
+	issue.Repaired = true
+
+	log.Debugf("Missing %v record %v was added to %v user records cache",
+		backend.States[state], tokenStr, um.UserID)
 
-	return nil
+	return &issue, nil
 }
 
 // Settings returns the plugin's settings.
@@ -0,0 +1,381 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstorebe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrtime/merkle"
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// Inventory snapshots are signed commitments to the tokens and the latest
+// merkle roots of all records in the inventory. The signed snapshot is
+// appended onto the tstore snapshot tree, which is anchored along with the
+// record trees. The records that were included in a snapshot are saved to
+// the key-value store so that inclusion proofs can be generated for them.
+const (
+	// snapshotSchedule determines how often an inventory snapshot is
+	// taken. The snapshot is taken a few minutes prior to the tstore
+	// anchor drop so that it is included in the anchor.
+	// Seconds Minutes Hours Days Months DayOfWeek
+	snapshotSchedule = "0 50 * * * *" // At minute 50 of every hour
+
+	// snapshotKey is the key-value store key for the records that were
+	// included in an inventory snapshot. The "{seq}" is replaced with
+	// the snapshot sequence number.
+	snapshotKey = "tstorebe-snapshot-{seq}"
+
+	// snapshotBatchSize is the maximum number of inventory entries that
+	// are retrieved at once when a snapshot is taken.
+	snapshotBatchSize = 500
+)
+
+var (
+	// snapshotStates contains the record states that are included in an
+	// inventory snapshot, in the order of the snapshot roots.
+	snapshotStates = []backend.StateT{
+		backend.StateUnvetted,
+		backend.StateVetted,
+	}
+)
+
+// snapshotEntry is a record that was included in an inventory snapshot.
+type snapshotEntry struct {
+	Token  string         `json:"token"`
+	State  backend.StateT `json:"state"`
+	Merkle string         `json:"merkle"`
+}
+
+// buildSnapshotKey returns the key-value store key for the records of an
+// inventory snapshot.
+func buildSnapshotKey(seq uint64) string {
+	return strings.Replace(snapshotKey, "{seq}", fmt.Sprintf("%v", seq), 1)
+}
+
+// snapshotEntries returns a snapshot entry for every record in the inventory.
+func (t *tstoreBackend) snapshotEntries() ([]snapshotEntry, error) {
+	// Get the inventory entries
+	t.RLock()
+	keys, err := t.tstore.CacheKeys(invEntryKeyPrefix, "", 0)
+	if err != nil {
+		t.RUnlock()
+		return nil, err
+	}
+	entries := make([]entry, 0, len(keys))
+	for len(keys) > 0 {
+		n := snapshotBatchSize
+		if n > len(keys) {
+			n = len(keys)
+		}
+		blobs, err := t.tstore.CacheGet(keys[:n])
+		if err != nil {
+			t.RUnlock()
+			return nil, err
+		}
+		for _, b := range blobs {
+			var e entry
+			err = json.Unmarshal(b, &e)
+			if err != nil {
+				t.RUnlock()
+				return nil, err
+			}
+			entries = append(entries, e)
+		}
+		keys = keys[n:]
+	}
+	t.RUnlock()
+
+	// Get the latest merkle root of every record
+	se := make([]snapshotEntry, 0, len(entries))
+	for _, e := range entries {
+		token, err := hex.DecodeString(e.Token)
+		if err != nil {
+			return nil, err
+		}
+		r, err := t.tstore.RecordPartial(token, 0, nil, true)
+		if err != nil {
+			return nil, fmt.Errorf("RecordPartial %v: %v", e.Token, err)
+		}
+		rm := r.RecordMetadata
+		se = append(se, snapshotEntry{
+			Token:  rm.Token,
+			State:  rm.State,
+			Merkle: rm.Merkle,
+		})
+	}
+
+	return se, nil
+}
+
+// snapshotLeaves returns the sorted snapshot leaves of the entries that have
+// the provided state.
+func snapshotLeaves(entries []snapshotEntry, state backend.StateT) ([]*[sha256.Size]byte, error) {
+	leaves := make([]*[sha256.Size]byte, 0, len(entries))
+	for _, v := range entries {
+		if v.State != state {
+			continue
+		}
+		b, err := hex.DecodeString(backend.SnapshotLeaf(v.Token, v.Merkle))
+		if err != nil {
+			return nil, err
+		}
+		var d [sha256.Size]byte
+		copy(d[:], b)
+		leaves = append(leaves, &d)
+	}
+
+	// The leaves are sorted the same way that the merkle tree sorts
+	// them so that the authentication path matches the merkle root.
+	sort.Slice(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i][:], leaves[j][:]) < 0
+	})
+
+	return leaves, nil
+}
+
+// snapshotRoots returns the snapshot roots of the provided entries.
+func snapshotRoots(entries []snapshotEntry) ([]backend.SnapshotRoot, error) {
+	roots := make([]backend.SnapshotRoot, 0, len(snapshotStates))
+	for _, state := range snapshotStates {
+		leaves, err := snapshotLeaves(entries, state)
+		if err != nil {
+			return nil, err
+		}
+		var mr string
+		if len(leaves) > 0 {
+			mr = hex.EncodeToString(merkle.Root(leaves)[:])
+		}
+		roots = append(roots, backend.SnapshotRoot{
+			State:      state,
+			Records:    uint32(len(leaves)),
+			MerkleRoot: mr,
+		})
+	}
+	return roots, nil
+}
+
+// snapshotProof returns the inclusion proof of a snapshot entry in the merkle
+// root of its state.
+func snapshotProof(entries []snapshotEntry, e snapshotEntry) (*backend.Proof, error) {
+	leaves, err := snapshotLeaves(entries, e.State)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := hex.DecodeString(backend.SnapshotLeaf(e.Token, e.Merkle))
+	if err != nil {
+		return nil, err
+	}
+	var d [sha256.Size]byte
+	copy(d[:], leaf)
+	b := merkle.AuthPath(leaves, &d)
+	if b == nil {
+		return nil, fmt.Errorf("no snapshot leaves")
+	}
+
+	path := make([]string, 0, len(b.Hashes))
+	for _, v := range b.Hashes {
+		path = append(path, hex.EncodeToString(v[:]))
+	}
+	ed, err := json.Marshal(backend.ExtraDataDcrtime{
+		NumLeaves: b.NumLeaves,
+		Flags:     base64.StdEncoding.EncodeToString(b.Flags),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &backend.Proof{
+		Type:       backend.ProofTypeDcrtime,
+		Digest:     hex.EncodeToString(d[:]),
+		MerkleRoot: hex.EncodeToString(merkle.Root(leaves)[:]),
+		MerklePath: path,
+		ExtraData:  string(ed),
+	}, nil
+}
+
+// snapshotNew takes a new inventory snapshot. A snapshot is not taken if the
+// inventory has not changed since the most recent snapshot.
+func (t *tstoreBackend) snapshotNew() error {
+	t.snapshotMtx.Lock()
+	defer t.snapshotMtx.Unlock()
+
+	if t.isShutdown() {
+		return backend.ErrShutdown
+	}
+
+	// Compile the snapshot roots
+	entries, err := t.snapshotEntries()
+	if err != nil {
+		return err
+	}
+	roots, err := snapshotRoots(entries)
+	if err != nil {
+		return err
+	}
+
+	// Get the most recent snapshot
+	var seq uint64 = 1
+	latest, _, err := t.Snapshot(0)
+	switch {
+	case errors.Is(err, backend.ErrSnapshotNotFound):
+		// This is the first snapshot
+	case err != nil:
+		return err
+	default:
+		if reflect.DeepEqual(latest.Roots, roots) {
+			log.Debugf("Inventory unchanged since snapshot %v",
+				latest.Sequence)
+			return nil
+		}
+		seq = latest.Sequence + 1
+	}
+
+	// Save the snapshot entries. They must be saved before the
+	// snapshot so that an inclusion proof can be generated for every
+	// snapshot. A failed snapshot is retried with the same sequence
+	// number, which overwrites the entries.
+	b, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	err = t.tstore.CachePut(map[string][]byte{buildSnapshotKey(seq): b})
+	if err != nil {
+		return err
+	}
+
+	// Sign and save the snapshot
+	s := backend.InventorySnapshot{
+		Sequence:  seq,
+		Timestamp: time.Now().Unix(),
+		Roots:     roots,
+		PublicKey: t.identity.Public.String(),
+	}
+	sig := t.identity.SignMessage([]byte(backend.SnapshotMsg(s)))
+	s.Signature = hex.EncodeToString(sig[:])
+	b, err = json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	err = t.tstore.SnapshotSave(b)
+	if err != nil {
+		return fmt.Errorf("SnapshotSave: %v", err)
+	}
+
+	log.Infof("Inventory snapshot %v: %v unvetted, %v vetted records",
+		seq, roots[0].Records, roots[1].Records)
+
+	return nil
+}
+
+// Snapshot returns an inventory snapshot and its timestamp. A sequence number
+// of 0 returns the most recent snapshot.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) Snapshot(seq uint64) (*backend.InventorySnapshot, *backend.Timestamp, error) {
+	log.Tracef("Snapshot: %v", seq)
+
+	b, ts, err := t.tstore.Snapshot(seq)
+	if err != nil {
+		return nil, nil, err
+	}
+	var s backend.InventorySnapshot
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &s, ts, nil
+}
+
+// SnapshotProof returns the proof that a record was included in an inventory
+// snapshot. A sequence number of 0 uses the most recent snapshot. A
+// ErrRecordNotFound error is returned if the record was not included in the
+// snapshot.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) SnapshotProof(token []byte, seq uint64) (*backend.SnapshotInclusion, error) {
+	log.Tracef("SnapshotProof: %x %v", token, seq)
+
+	s, ts, err := t.Snapshot(seq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the snapshot entries
+	k := buildSnapshotKey(s.Sequence)
+	blobs, err := t.tstore.CacheGet([]string{k})
+	if err != nil {
+		return nil, err
+	}
+	b, ok := blobs[k]
+	if !ok {
+		// Should not happen
+		return nil, fmt.Errorf("snapshot entries not found %v", s.Sequence)
+	}
+	var entries []snapshotEntry
+	err = json.Unmarshal(b, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find the record
+	var (
+		tokenStr = hex.EncodeToString(token)
+		e        *snapshotEntry
+	)
+	for i, v := range entries {
+		if v.Token == tokenStr {
+			e = &entries[i]
+			break
+		}
+	}
+	if e == nil {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// Build the inclusion proof
+	p, err := snapshotProof(entries, *e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &backend.SnapshotInclusion{
+		Snapshot:  *s,
+		Timestamp: *ts,
+		Token:     e.Token,
+		State:     e.State,
+		Merkle:    e.Merkle,
+		Proof:     *p,
+	}, nil
+}
+
+// snapshotSetup launches the inventory snapshot cron job.
+func (t *tstoreBackend) snapshotSetup() error {
+	log.Infof("Launch cron inventory snapshot job")
+
+	err := t.cron.AddFunc(snapshotSchedule, func() {
+		err := t.snapshotNew()
+		if err != nil {
+			log.Errorf("snapshotNew: %v", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	t.cron.Start()
+
+	return nil
+}
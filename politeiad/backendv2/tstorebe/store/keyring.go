@@ -0,0 +1,241 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/decred/politeia/util"
+	"github.com/marcopeereboom/sbox"
+)
+
+// Keyring contains the versioned encryption keys of a BlobKV implementation.
+//
+// The version of the key that was used to encrypt a blob is recorded in the
+// version field of the blob's sbox header. Version 0 is the master key that
+// the BlobKV implementation was created with. Blobs that were encrypted prior
+// to the introduction of key versions were encrypted with the master key and
+// contain a version of 0 in their sbox header, so they remain readable.
+//
+// All key versions greater than 0 are randomly generated when the key is
+// rotated. They are persisted by the BlobKV implementation in their encoded
+// form, which wraps each key using the master key. New blobs are always
+// encrypted using the active key. Keys are never removed from the keyring
+// since a blob that was encrypted with any previous key must remain readable
+// until it has been re-encrypted.
+type Keyring struct {
+	sync.RWMutex
+	active uint32
+	keys   map[uint32]*[32]byte // [version]key
+}
+
+// keyringEncoded is the persisted form of a Keyring. The master key is not
+// included. The keys are sbox encrypted using the master key and are hex
+// encoded.
+type keyringEncoded struct {
+	Active uint32            `json:"active"`
+	Keys   map[uint32]string `json:"keys"` // [version]wrappedKey
+}
+
+// NewKeyring returns a new Keyring that uses the provided key as the master
+// key. The master key is the active key until the keyring is loaded or
+// rotated. The provided key is copied.
+func NewKeyring(masterKey *[32]byte) *Keyring {
+	var k [32]byte
+	copy(k[:], masterKey[:])
+	return &Keyring{
+		active: 0,
+		keys: map[uint32]*[32]byte{
+			0: &k,
+		},
+	}
+}
+
+// Active returns the version of the key that new blobs are encrypted with.
+func (k *Keyring) Active() uint32 {
+	k.RLock()
+	defer k.RUnlock()
+
+	return k.active
+}
+
+// Encrypt encrypts the provided data with the active key using a random
+// nonce.
+func (k *Keyring) Encrypt(data []byte) ([]byte, error) {
+	k.RLock()
+	defer k.RUnlock()
+
+	return sbox.Encrypt(k.active, k.keys[k.active], data)
+}
+
+// EncryptN encrypts the provided data with the active key using the provided
+// nonce. It is the callers responsibility to ensure that a nonce is never
+// reused.
+func (k *Keyring) EncryptN(nonce [24]byte, data []byte) ([]byte, error) {
+	k.RLock()
+	defer k.RUnlock()
+
+	return sbox.EncryptN(k.active, k.keys[k.active], nonce, data)
+}
+
+// Decrypt decrypts the provided sbox blob using the key version that is
+// recorded in its header. The decrypted data and the key version are
+// returned.
+func (k *Keyring) Decrypt(blob []byte) ([]byte, uint32, error) {
+	version, err := KeyVersion(blob)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	k.RLock()
+	defer k.RUnlock()
+
+	key, ok := k.keys[version]
+	if !ok {
+		return nil, 0, fmt.Errorf("encryption key version %v not found",
+			version)
+	}
+	return sbox.Decrypt(key, blob)
+}
+
+// Rotate creates a new random key and makes it the active key. The encoded
+// keyring, which includes the new key, is passed to the save function before
+// the key is activated. The key is not activated if the save function returns
+// an error. The version of the new key is returned.
+func (k *Keyring) Rotate(save func(encoded []byte) error) (uint32, error) {
+	k.Lock()
+	defer k.Unlock()
+
+	key, err := sbox.NewKey()
+	if err != nil {
+		return 0, err
+	}
+	var version uint32
+	for v := range k.keys {
+		if v > version {
+			version = v
+		}
+	}
+	version++
+
+	// Save the keyring with the new key
+	k.keys[version] = key
+	b, err := k.encode(version)
+	if err == nil {
+		err = save(b)
+	}
+	if err != nil {
+		util.Zero(key[:])
+		delete(k.keys, version)
+		return 0, err
+	}
+
+	k.active = version
+
+	return version, nil
+}
+
+// Load loads the keys from an encoded keyring. The keys must have been
+// wrapped using the same master key that this keyring was created with.
+func (k *Keyring) Load(encoded []byte) error {
+	var ke keyringEncoded
+	err := json.Unmarshal(encoded, &ke)
+	if err != nil {
+		return err
+	}
+
+	k.Lock()
+	defer k.Unlock()
+
+	master := k.keys[0]
+	for version, wrapped := range ke.Keys {
+		if version == 0 {
+			return fmt.Errorf("master key found in encoded keyring")
+		}
+		b, err := hex.DecodeString(wrapped)
+		if err != nil {
+			return fmt.Errorf("decode key %v: %v", version, err)
+		}
+		key, v, err := sbox.Decrypt(master, b)
+		if err != nil {
+			return fmt.Errorf("unwrap key %v: %v", version, err)
+		}
+		if v != version || len(key) != 32 {
+			return fmt.Errorf("invalid key %v", version)
+		}
+		var kb [32]byte
+		copy(kb[:], key)
+		util.Zero(key)
+		k.keys[version] = &kb
+	}
+	if _, ok := k.keys[ke.Active]; !ok {
+		return fmt.Errorf("active key %v not found", ke.Active)
+	}
+	k.active = ke.Active
+
+	return nil
+}
+
+// encode returns the encoded keyring using the provided active version.
+//
+// This function must be called WITH the lock held.
+func (k *Keyring) encode(active uint32) ([]byte, error) {
+	ke := keyringEncoded{
+		Active: active,
+		Keys:   make(map[uint32]string, len(k.keys)),
+	}
+	for version, key := range k.keys {
+		if version == 0 {
+			// The master key is never persisted
+			continue
+		}
+		b, err := sbox.Encrypt(version, k.keys[0], key[:])
+		if err != nil {
+			return nil, err
+		}
+		ke.Keys[version] = hex.EncodeToString(b)
+	}
+	return json.Marshal(ke)
+}
+
+// Zero zeroes all of the keys in the keyring.
+func (k *Keyring) Zero() {
+	k.Lock()
+	defer k.Unlock()
+
+	for _, key := range k.keys {
+		util.Zero(key[:])
+	}
+}
+
+// KeyVersion returns the encryption key version that is recorded in the
+// header of an sbox blob.
+func KeyVersion(blob []byte) (uint32, error) {
+	// The sbox header is the 4 byte magic followed by a 4 byte big
+	// endian version.
+	if len(blob) < 8 || string(blob[:4]) != "sbox" {
+		return 0, sbox.ErrInvalidHeader
+	}
+	return binary.BigEndian.Uint32(blob[4:8]), nil
+}
+
+// ReencryptReply is the reply to the BlobKV Reencrypt method.
+type ReencryptReply struct {
+	// LastKey is the last key that was inspected. It is used as the
+	// starting point of the next batch.
+	LastKey string
+
+	// Inspected is the number of entries that were inspected. The walk
+	// has reached the end of the store when this is less than the limit
+	// that was requested.
+	Inspected uint32
+
+	// Reencrypted is the number of blobs that were re-encrypted.
+	Reencrypted uint32
+}
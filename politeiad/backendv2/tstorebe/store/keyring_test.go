@@ -0,0 +1,97 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/marcopeereboom/sbox"
+)
+
+func TestKeyring(t *testing.T) {
+	master, err := sbox.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := NewKeyring(master)
+
+	// Encrypt a blob using the master key
+	data := []byte("encryptmeyo")
+	b0, err := k.Encrypt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed save does not activate the new key
+	_, err = k.Rotate(func([]byte) error {
+		return errors.New("save failed")
+	})
+	if err == nil {
+		t.Fatalf("expected save error")
+	}
+	if k.Active() != 0 {
+		t.Fatalf("got active key %v, want 0", k.Active())
+	}
+
+	// Rotate the key and encrypt a blob using the new key
+	var encoded []byte
+	version, err := k.Rotate(func(b []byte) error {
+		encoded = b
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 || k.Active() != 1 {
+		t.Fatalf("got key version %v, want 1", version)
+	}
+	b1, err := k.Encrypt(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the key versions that are recorded in the headers
+	for i, b := range [][]byte{b0, b1} {
+		v, err := KeyVersion(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != uint32(i) {
+			t.Errorf("got key version %v, want %v", v, i)
+		}
+	}
+
+	// A keyring that is loaded using the same master key is able to
+	// decrypt blobs from all key versions.
+	k2 := NewKeyring(master)
+	err = k2.Load(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k2.Active() != 1 {
+		t.Fatalf("got active key %v, want 1", k2.Active())
+	}
+	for _, b := range [][]byte{b0, b1} {
+		d, _, err := k2.Decrypt(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(d, data) {
+			t.Fatalf("got '%s', want '%s'", d, data)
+		}
+	}
+
+	// A keyring that uses a different master key can not be loaded
+	other, err := sbox.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewKeyring(other).Load(encoded)
+	if err == nil {
+		t.Fatalf("expected error when loading with a different master key")
+	}
+}
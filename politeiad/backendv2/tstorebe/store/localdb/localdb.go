@@ -9,18 +9,23 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
 	"github.com/decred/politeia/util"
-	"github.com/marcopeereboom/sbox"
 	"github.com/syndtr/goleveldb/leveldb"
+	ldbutil "github.com/syndtr/goleveldb/leveldb/util"
 )
 
 const (
 	// encryptionKeyFilename is the filename of the encryption key that
 	// is created in the store data directory.
 	encryptionKeyFilename = "leveldb-sbox.key"
+
+	// encryptionKeysKey is the database key for the encoded keyring that
+	// contains the encryption keys that were created by key rotations.
+	encryptionKeysKey = "store-localdb-encryptionkeys"
 )
 
 var (
@@ -32,11 +37,16 @@ var (
 // NOTE: this implementation was created for testing. The encryption techniques
 // used may not be suitable for a production environment. A random secretbox
 // encryption key is created on startup and saved to the politeiad application
-// dir. Blobs are encrypted using random 24 byte nonces.
+// dir. Blobs are encrypted using random 24 byte nonces. The key file key is
+// the master key of the keyring.
 type localdb struct {
 	shutdown uint64
 	db       *leveldb.DB
-	key      [32]byte
+	keyring  *store.Keyring
+
+	// mtx serializes writes so that a blob is not updated by a concurrent
+	// write while it is being re-encrypted.
+	mtx sync.Mutex
 }
 
 func (l *localdb) isShutdown() bool {
@@ -44,11 +54,11 @@ func (l *localdb) isShutdown() bool {
 }
 
 func (l *localdb) encrypt(data []byte) ([]byte, error) {
-	return sbox.Encrypt(0, &l.key, data)
+	return l.keyring.Encrypt(data)
 }
 
 func (l *localdb) decrypt(data []byte) ([]byte, uint32, error) {
-	return sbox.Decrypt(&l.key, data)
+	return l.keyring.Decrypt(data)
 }
 
 // Put saves the provided key-value entries to the database. New entries are
@@ -82,6 +92,9 @@ func (l *localdb) Put(blobs map[string][]byte, encrypt bool) error {
 	}
 
 	// Write batch
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
 	err := l.db.Write(batch, nil)
 	if err != nil {
 		return fmt.Errorf("write batch: %v", err)
@@ -108,6 +121,10 @@ func (l *localdb) Del(keys []string) error {
 	for _, v := range keys {
 		batch.Delete([]byte(v))
 	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
 	err := l.db.Write(batch, nil)
 	if err != nil {
 		return err
@@ -169,6 +186,164 @@ func (l *localdb) Get(keys []string) (map[string][]byte, error) {
 	return blobs, nil
 }
 
+// Keys returns the keys of the entries in the database that begin with the
+// provided prefix that sort after the provided after key, sorted in ascending
+// order. The limit is the maximum number of keys that are returned. A limit of
+// 0 returns all remaining keys. The encryption keys are not returned.
+//
+// This function satisfies the store BlobKV interface.
+func (l *localdb) Keys(prefix, after string, limit uint32) ([]string, error) {
+	log.Tracef("Keys: %v %v %v", prefix, after, limit)
+
+	if l.isShutdown() {
+		return nil, store.ErrShutdown
+	}
+
+	// The smallest key that sorts after the after key is the after key
+	// with a zero byte appended.
+	r := ldbutil.BytesPrefix([]byte(prefix))
+	if start := []byte(after + "\x00"); after != "" &&
+		bytes.Compare(start, r.Start) > 0 {
+		r.Start = start
+	}
+
+	keys := make([]string, 0, limit)
+	iter := l.db.NewIterator(r, nil)
+	defer iter.Release()
+	for iter.Next() {
+		if string(iter.Key()) == encryptionKeysKey {
+			continue
+		}
+		keys = append(keys, string(iter.Key()))
+		if limit > 0 && len(keys) == int(limit) {
+			break
+		}
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// EncryptionKeyVersion returns the version of the encryption key that new
+// blobs are encrypted with.
+//
+// This function satisfies the store BlobKV interface.
+func (l *localdb) EncryptionKeyVersion() uint32 {
+	return l.keyring.Active()
+}
+
+// RotateEncryptionKey creates a new encryption key and makes it the active
+// key. The new key is saved to the database before it is used.
+//
+// This function satisfies the store BlobKV interface.
+func (l *localdb) RotateEncryptionKey() (uint32, error) {
+	log.Tracef("RotateEncryptionKey")
+
+	if l.isShutdown() {
+		return 0, store.ErrShutdown
+	}
+
+	version, err := l.keyring.Rotate(func(encoded []byte) error {
+		return l.db.Put([]byte(encryptionKeysKey), encoded, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infof("Encryption key rotated; new key version %v", version)
+
+	return version, nil
+}
+
+// Reencrypt walks the entries in the database in ascending key order,
+// starting after the provided key, and re-encrypts the blobs that were not
+// encrypted using the active encryption key. At most limit entries are
+// inspected. The encryption keys are not inspected.
+//
+// This operation is atomic.
+//
+// This function satisfies the store BlobKV interface.
+func (l *localdb) Reencrypt(after string, limit uint32) (*store.ReencryptReply, error) {
+	log.Tracef("Reencrypt: %v %v", after, limit)
+
+	if l.isShutdown() {
+		return nil, store.ErrShutdown
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var (
+		rr     store.ReencryptReply
+		active = l.keyring.Active()
+		batch  = new(leveldb.Batch)
+	)
+	iter := l.db.NewIterator(&ldbutil.Range{Start: []byte(after)}, nil)
+	defer iter.Release()
+	for rr.Inspected < limit && iter.Next() {
+		k := string(iter.Key())
+		if k == after || k == encryptionKeysKey {
+			// The start of the range is inclusive and the
+			// encryption keys are not inspected.
+			continue
+		}
+		rr.Inspected++
+		rr.LastKey = k
+
+		v := iter.Value()
+		if !isEncrypted(v) {
+			continue
+		}
+		version, err := store.KeyVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		if version == active {
+			continue
+		}
+		b, _, err := l.decrypt(v)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt %v: %v", k, err)
+		}
+		e, err := l.encrypt(b)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: %v", err)
+		}
+		batch.Put([]byte(k), e)
+		rr.Reencrypted++
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+	err = l.db.Write(batch, nil)
+	if err != nil {
+		return nil, fmt.Errorf("write batch: %v", err)
+	}
+
+	log.Debugf("Re-encrypted blobs (%v/%v) in store",
+		rr.Reencrypted, rr.Inspected)
+
+	return &rr, nil
+}
+
+// Ping verifies that the database is reachable. The leveldb database is
+// embedded so it is reachable until it has been closed.
+//
+// This function satisfies the store BlobKV interface.
+func (l *localdb) Ping() error {
+	log.Tracef("Ping")
+
+	if l.isShutdown() {
+		return store.ErrShutdown
+	}
+
+	return nil
+}
+
 // Close closes the database connection.
 //
 // This function satisfies the store BlobKV interface.
@@ -177,8 +352,8 @@ func (l *localdb) Close() {
 
 	atomic.AddUint64(&l.shutdown, 1)
 
-	// Zero the encryption key
-	util.Zero(l.key[:])
+	// Zero the encryption keys
+	l.keyring.Zero()
 
 	// Close database
 	l.db.Close()
@@ -201,10 +376,26 @@ func New(appDir, dataDir string) (*localdb, error) {
 
 	// Create context
 	ldb := localdb{
-		db: db,
+		db:      db,
+		keyring: store.NewKeyring(key),
 	}
-	copy(ldb.key[:], key[:])
 	util.Zero(key[:])
 
+	// Load the keys that were created by previous key rotations
+	b, err := db.Get([]byte(encryptionKeysKey), nil)
+	switch {
+	case errors.Is(err, leveldb.ErrNotFound):
+		// The key has never been rotated
+	case err != nil:
+		ldb.Close()
+		return nil, err
+	default:
+		err = ldb.keyring.Load(b)
+		if err != nil {
+			ldb.Close()
+			return nil, err
+		}
+	}
+
 	return &ldb, nil
 }
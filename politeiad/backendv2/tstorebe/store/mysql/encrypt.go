@@ -11,6 +11,7 @@ import (
 	"encoding/binary"
 	"encoding/json"
 
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
 	"github.com/decred/politeia/util"
 	"github.com/marcopeereboom/sbox"
 	"github.com/pkg/errors"
@@ -21,6 +22,10 @@ const (
 	// encryptionKeyParamsKey is the kv store key for the encryption
 	// key params that are saved on initial key derivation.
 	encryptionKeyParamsKey = "store-mysql-encryptionkeyparams"
+
+	// encryptionKeysKey is the kv store key for the encoded keyring that
+	// contains the encryption keys that were created by key rotations.
+	encryptionKeysKey = "store-mysql-encryptionkeys"
 )
 
 // encryptionKeyParams is saved to the kv store on initial derivation of the
@@ -35,12 +40,14 @@ type encryptionKeyParams struct {
 
 // argon2idKey derives an encryption key using the provided parameters and the
 // Argon2id key derivation function. The derived key is set to be the
-// encryption key on the mysql context.
+// encryption key on the mysql context and is used as the master key of the
+// keyring.
 func (s *mysqlCtx) argon2idKey(password string, ap util.Argon2Params) {
 	k := argon2.IDKey([]byte(password), ap.Salt, ap.Time, ap.Memory,
 		ap.Threads, ap.KeyLen)
 	copy(s.key[:], k)
 	util.Zero(k)
+	s.keyring = store.NewKeyring(&s.key)
 }
 
 // deriveEncryption derives a 32 byte key from the provided password using the
@@ -113,6 +120,63 @@ func (s *mysqlCtx) deriveEncryptionKey(password string) error {
 	return nil
 }
 
+// loadEncryptionKeys loads the encryption keys that were created by previous
+// key rotations into the keyring. The encryption key must be derived prior to
+// calling this function.
+func (s *mysqlCtx) loadEncryptionKeys() error {
+	blobs, err := s.Get([]string{encryptionKeysKey})
+	if err != nil {
+		return err
+	}
+	b, ok := blobs[encryptionKeysKey]
+	if !ok {
+		// The key has never been rotated
+		return nil
+	}
+	err = s.keyring.Load(b)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Encryption key version: %v", s.keyring.Active())
+
+	return nil
+}
+
+// EncryptionKeyVersion returns the version of the encryption key that new
+// blobs are encrypted with.
+//
+// This function satisfies the store BlobKV interface.
+func (s *mysqlCtx) EncryptionKeyVersion() uint32 {
+	return s.keyring.Active()
+}
+
+// RotateEncryptionKey creates a new encryption key and makes it the active
+// key. The new key is saved to the kv store before it is used.
+//
+// This function satisfies the store BlobKV interface.
+func (s *mysqlCtx) RotateEncryptionKey() (uint32, error) {
+	log.Tracef("RotateEncryptionKey")
+
+	if s.isShutdown() {
+		return 0, store.ErrShutdown
+	}
+
+	version, err := s.keyring.Rotate(func(encoded []byte) error {
+		kv := map[string][]byte{
+			encryptionKeysKey: encoded,
+		}
+		return s.Put(kv, false)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infof("Encryption key rotated; new key version %v", version)
+
+	return version, nil
+}
+
 var emptyNonce = [24]byte{}
 
 func (s *mysqlCtx) getDBNonce(ctx context.Context, tx *sql.Tx) ([24]byte, error) {
@@ -158,11 +222,11 @@ func (s *mysqlCtx) encrypt(ctx context.Context, tx *sql.Tx, data []byte) ([]byte
 	if err != nil {
 		return nil, err
 	}
-	return sbox.EncryptN(0, &s.key, nonce, data)
+	return s.keyring.EncryptN(nonce, data)
 }
 
 func (s *mysqlCtx) decrypt(data []byte) ([]byte, uint32, error) {
-	return sbox.Decrypt(&s.key, data)
+	return s.keyring.Decrypt(data)
 }
 
 // isEncrypted returns whether the provided blob has been prefixed with an sbox
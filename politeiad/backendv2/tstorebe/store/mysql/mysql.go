@@ -56,7 +56,8 @@ var (
 type mysqlCtx struct {
 	shutdown uint64
 	db       *sql.DB
-	key      [32]byte
+	key      [32]byte // Master encryption key
+	keyring  *store.Keyring
 
 	// The following fields are only used during unit tests.
 	testing bool
@@ -267,14 +268,192 @@ func (s *mysqlCtx) Get(keys []string) (map[string][]byte, error) {
 	return reply, nil
 }
 
+// Keys returns the keys of the entries in the database that begin with the
+// provided prefix that sort after the provided after key, sorted in ascending
+// order. The limit is the maximum number of keys that are returned. A limit of
+// 0 returns all remaining keys. The encryption
+// key params and the encryption keys are not returned.
+//
+// This function satisfies the store BlobKV interface.
+func (s *mysqlCtx) Keys(prefix, after string, limit uint32) ([]string, error) {
+	log.Tracef("Keys: %v %v %v", prefix, after, limit)
+
+	if s.isShutdown() {
+		return nil, store.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	q, args := buildKeysQuery(prefix, after, limit)
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0, limit)
+	for rows.Next() {
+		var k string
+		err = rows.Scan(&k)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		keys = append(keys, k)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return keys, nil
+}
+
+// Reencrypt walks the entries in the database in ascending key order,
+// starting after the provided key, and re-encrypts the blobs that were not
+// encrypted using the active encryption key. At most limit entries are
+// inspected. The encryption key params and the encryption keys are not
+// inspected.
+//
+// This operation is atomic.
+//
+// This function satisfies the store BlobKV interface.
+func (s *mysqlCtx) Reencrypt(after string, limit uint32) (*store.ReencryptReply, error) {
+	log.Tracef("Reencrypt: %v %v", after, limit)
+
+	if s.isShutdown() {
+		return nil, store.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	// Start transaction
+	opts := &sql.TxOptions{
+		Isolation: sql.LevelDefault,
+	}
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-encrypt blobs
+	rr, err := s.reencrypt(ctx, tx, after, limit)
+	if err != nil {
+		// Attempt to roll back the transaction
+		if err2 := tx.Rollback(); err2 != nil {
+			// We're in trouble!
+			e := fmt.Sprintf("reencrypt: %v, unable to rollback: %v", err, err2)
+			panic(e)
+		}
+		return nil, err
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Re-encrypted blobs (%v/%v) in store",
+		rr.Reencrypted, rr.Inspected)
+
+	return rr, nil
+}
+
+// reencrypt re-encrypts a batch of blobs using the provided transaction.
+func (s *mysqlCtx) reencrypt(ctx context.Context, tx *sql.Tx, after string, limit uint32) (*store.ReencryptReply, error) {
+	// Get the batch of entries
+	rows, err := tx.QueryContext(ctx,
+		"SELECT k, v FROM kv WHERE k > ? AND k NOT IN (?, ?) "+
+			"ORDER BY k LIMIT ? FOR UPDATE;", after, encryptionKeyParamsKey,
+		encryptionKeysKey, limit)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var (
+		rr      store.ReencryptReply
+		entries = make(map[string][]byte, limit)
+	)
+	for rows.Next() {
+		var k string
+		var v []byte
+		err = rows.Scan(&k, &v)
+		if err != nil {
+			rows.Close()
+			return nil, errors.WithStack(err)
+		}
+		rr.Inspected++
+		rr.LastKey = k
+		if isEncrypted(v) {
+			entries[k] = v
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Re-encrypt the blobs that were encrypted with a previous key
+	active := s.keyring.Active()
+	for k, v := range entries {
+		version, err := store.KeyVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		if version == active {
+			continue
+		}
+		b, _, err := s.decrypt(v)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt %v: %v", k, err)
+		}
+		e, err := s.encrypt(ctx, tx, b)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE kv SET v = ? WHERE k = ?;", e, k)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		rr.Reencrypted++
+	}
+
+	return &rr, nil
+}
+
+// Ping verifies that the database is reachable.
+//
+// This function satisfies the store BlobKV interface.
+func (s *mysqlCtx) Ping() error {
+	log.Tracef("Ping")
+
+	if s.isShutdown() {
+		return store.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	err := s.db.PingContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
 // Close closes the database connection.
 func (s *mysqlCtx) Close() {
 	log.Tracef("Close")
 
 	atomic.AddUint64(&s.shutdown, 1)
 
-	// Zero the encryption key
+	// Zero the encryption keys
 	util.Zero(s.key[:])
+	s.keyring.Zero()
 
 	// Close mysql connection
 	s.db.Close()
@@ -414,5 +593,55 @@ func New(host, user, password, dbname string) (*mysqlCtx, error) {
 		return nil, err
 	}
 
+	// Load the keys that were created by previous key rotations
+	err = s.loadEncryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+
 	return s, nil
 }
+
+// buildKeysQuery returns the SELECT statement and the arguments that are
+// used to retrieve a page of keys that begin with the provided prefix and that
+// sort after the provided after key. The prefix and the after key are matched
+// using a range on the primary key so that the lookup is able to use the
+// primary key index.
+func buildKeysQuery(prefix, after string, limit uint32) (string, []interface{}) {
+	var (
+		q    = "SELECT k FROM kv WHERE k NOT IN (?, ?)"
+		args = []interface{}{encryptionKeyParamsKey, encryptionKeysKey}
+	)
+	if prefix != "" {
+		q += " AND k >= ?"
+		args = append(args, prefix)
+		if end := prefixEnd(prefix); end != "" {
+			q += " AND k < ?"
+			args = append(args, end)
+		}
+	}
+	if after != "" {
+		q += " AND k > ?"
+		args = append(args, after)
+	}
+	q += " ORDER BY k"
+	if limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, int64(limit))
+	}
+	return q + ";", args
+}
+
+// prefixEnd returns the smallest string that is greater than all strings that
+// begin with the provided prefix. An empty string is returned if no such
+// string exists, i.e. the prefix only contains 0xff bytes.
+func prefixEnd(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
@@ -287,3 +287,23 @@ func TestBuildPlaceholders(t *testing.T) {
 		})
 	}
 }
+
+func TestPrefixEnd(t *testing.T) {
+	var tests = []struct {
+		prefix string
+		output string
+	}{
+		{"", ""},
+		{"pi-", "pi."},
+		{"e_", "e`"},
+		{"a\xff", "b"},
+		{"\xff\xff", ""},
+	}
+	for _, tc := range tests {
+		output := prefixEnd(tc.prefix)
+		if output != tc.output {
+			t.Errorf("prefix %q: got %q, want %q",
+				tc.prefix, output, tc.output)
+		}
+	}
+}
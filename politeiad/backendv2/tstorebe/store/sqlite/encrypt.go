@@ -0,0 +1,217 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/util"
+	"github.com/marcopeereboom/sbox"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// encryptionKeyParamsKey is the kv store key for the encryption
+	// key params that are saved on initial key derivation.
+	encryptionKeyParamsKey = "store-sqlite-encryptionkeyparams"
+
+	// encryptionKeysKey is the kv store key for the encoded keyring that
+	// contains the encryption keys that were created by key rotations.
+	encryptionKeysKey = "store-sqlite-encryptionkeys"
+)
+
+// encryptionKeyParams is saved to the kv store on initial derivation of the
+// encryption key. It contains the params that were used to derive the key and
+// a SHA256 digest of the key. Subsequent derivations will use the existing
+// params to derive the key and will use the digest to verify that the
+// encryption key has not changed.
+type encryptionKeyParams struct {
+	Digest []byte            `json:"digest"` // SHA256 digest
+	Params util.Argon2Params `json:"params"`
+}
+
+// argon2idKey derives an encryption key using the provided parameters and the
+// Argon2id key derivation function. The derived key is set to be the
+// encryption key on the sqlite context and is used as the master key of the
+// keyring.
+func (s *sqliteCtx) argon2idKey(password string, ap util.Argon2Params) {
+	k := argon2.IDKey([]byte(password), ap.Salt, ap.Time, ap.Memory,
+		ap.Threads, ap.KeyLen)
+	copy(s.key[:], k)
+	util.Zero(k)
+	s.keyring = store.NewKeyring(&s.key)
+}
+
+// deriveEncryption derives a 32 byte key from the provided password using the
+// Aragon2id key derivation function. A random 16 byte salt is created the
+// first time the key is derived. The salt and the other argon2id params are
+// saved to the kv store. Subsequent calls to this fuction will pull the
+// existing salt and params from the kv store and use them to derive the key,
+// then will use the saved encryption key digest to verify that the key has
+// not changed.
+func (s *sqliteCtx) deriveEncryptionKey(password string) error {
+	log.Infof("Deriving encryption key")
+
+	// Check if the key params already exist in the kv store. Existing
+	// params means that the key has been derived previously. These
+	// params will be used if found. If no params exist then new ones
+	// will be created and saved to the kv store for future use.
+	blobs, err := s.Get([]string{encryptionKeyParamsKey})
+	if err != nil {
+		return err
+	}
+	var (
+		save bool
+		ekp  encryptionKeyParams
+	)
+	b, ok := blobs[encryptionKeyParamsKey]
+	if ok {
+		log.Debugf("Encryption key params found in kv store")
+		err = json.Unmarshal(b, &ekp)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Infof("Encryption key params not found; creating new ones")
+		ekp = encryptionKeyParams{
+			Params: util.NewArgon2Params(),
+		}
+		save = true
+	}
+
+	// Derive key
+	s.argon2idKey(password, ekp.Params)
+
+	// Check if the params need to be saved
+	keyDigest := util.Digest(s.key[:])
+	if save {
+		// This was the first time the key was derived. Save the params
+		// to the kv store.
+		ekp.Digest = keyDigest
+		b, err := json.Marshal(ekp)
+		if err != nil {
+			return err
+		}
+		kv := map[string][]byte{
+			encryptionKeyParamsKey: b,
+		}
+		err = s.Put(kv, false)
+		if err != nil {
+			return err
+		}
+
+		log.Infof("Encryption key params saved to kv store")
+	} else {
+		// This was not the first time the key was derived. Verify that
+		// the key has not changed.
+		if !bytes.Equal(ekp.Digest, keyDigest) {
+			return errors.Errorf("attempting to use different encryption key")
+		}
+	}
+
+	return nil
+}
+
+// loadEncryptionKeys loads the encryption keys that were created by previous
+// key rotations into the keyring. The encryption key must be derived prior to
+// calling this function.
+func (s *sqliteCtx) loadEncryptionKeys() error {
+	blobs, err := s.Get([]string{encryptionKeysKey})
+	if err != nil {
+		return err
+	}
+	b, ok := blobs[encryptionKeysKey]
+	if !ok {
+		// The key has never been rotated
+		return nil
+	}
+	err = s.keyring.Load(b)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Encryption key version: %v", s.keyring.Active())
+
+	return nil
+}
+
+// EncryptionKeyVersion returns the version of the encryption key that new
+// blobs are encrypted with.
+//
+// This function satisfies the store BlobKV interface.
+func (s *sqliteCtx) EncryptionKeyVersion() uint32 {
+	return s.keyring.Active()
+}
+
+// RotateEncryptionKey creates a new encryption key and makes it the active
+// key. The new key is saved to the kv store before it is used.
+//
+// This function satisfies the store BlobKV interface.
+func (s *sqliteCtx) RotateEncryptionKey() (uint32, error) {
+	log.Tracef("RotateEncryptionKey")
+
+	if s.isShutdown() {
+		return 0, store.ErrShutdown
+	}
+
+	version, err := s.keyring.Rotate(func(encoded []byte) error {
+		kv := map[string][]byte{
+			encryptionKeysKey: encoded,
+		}
+		return s.Put(kv, false)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infof("Encryption key rotated; new key version %v", version)
+
+	return version, nil
+}
+
+var emptyNonce = [24]byte{}
+
+func (s *sqliteCtx) getNonce(ctx context.Context, tx *sql.Tx) ([24]byte, error) {
+	// Get nonce value
+	nonce, err := s.nonce(ctx, tx)
+	if err != nil {
+		return emptyNonce, err
+	}
+
+	log.Tracef("Encrypting with nonce: %v", nonce)
+
+	// Prepare nonce
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(nonce))
+	n, err := sbox.NewNonceFromBytes(b)
+	if err != nil {
+		return emptyNonce, err
+	}
+	return n.Current(), nil
+}
+
+func (s *sqliteCtx) encrypt(ctx context.Context, tx *sql.Tx, data []byte) ([]byte, error) {
+	nonce, err := s.getNonce(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	return s.keyring.EncryptN(nonce, data)
+}
+
+func (s *sqliteCtx) decrypt(data []byte) ([]byte, uint32, error) {
+	return s.keyring.Decrypt(data)
+}
+
+// isEncrypted returns whether the provided blob has been prefixed with an sbox
+// header, indicating that it is an encrypted blob.
+func isEncrypted(b []byte) bool {
+	return bytes.HasPrefix(b, []byte("sbox"))
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// nonce returns a new nonce value. This function guarantees that the returned
+// nonce will be unique for every invocation.
+//
+// This function must be called using a transaction.
+func (s *sqliteCtx) nonce(ctx context.Context, tx *sql.Tx) (int64, error) {
+	// Create and retrieve new nonce value in an atomic database
+	// transaction. The nonce table uses AUTOINCREMENT so the rowid
+	// of the inserted row is guaranteed to be unique.
+	r, err := tx.ExecContext(ctx, "INSERT INTO nonce DEFAULT VALUES;")
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	nonce, err := r.LastInsertId()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if nonce == 0 {
+		return 0, errors.Errorf("invalid 0 nonce")
+	}
+
+	return nonce, nil
+}
@@ -0,0 +1,661 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/util"
+	"github.com/pkg/errors"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// Database options
+	connTimeout = 1 * time.Minute
+
+	// busyTimeout is the number of milliseconds that a connection will
+	// wait on a locked database before returning a SQLITE_BUSY error.
+	busyTimeout = 5000
+
+	// Database table names
+	tableNameKeyValue = "kv"
+	tableNameNonce    = "nonce"
+
+	// maxPlaceholders is the maximum number of placeholders, "(?, ?, ?)",
+	// that can be used in a prepared statement. This is the default
+	// SQLITE_MAX_VARIABLE_NUMBER for SQLite versions prior to 3.32.0.
+	// Later versions allow more, but the lower limit is used so that
+	// queries work regardless of the SQLite version that is linked.
+	maxPlaceholders = 999
+)
+
+// tableKeyValue defines the key-value table.
+const tableKeyValue = `
+  k TEXT NOT NULL PRIMARY KEY,
+  v BLOB NOT NULL
+`
+
+// tableNonce defines the table used to track the encryption nonce. The
+// AUTOINCREMENT keyword guarantees that a nonce value is never reused, even
+// if rows are deleted from the table.
+const tableNonce = `
+  n INTEGER PRIMARY KEY AUTOINCREMENT
+`
+
+var (
+	_ store.BlobKV = (*sqliteCtx)(nil)
+)
+
+// sqliteCtx implements the store BlobKV interface using a sqlite driver.
+type sqliteCtx struct {
+	shutdown uint64
+	db       *sql.DB
+	key      [32]byte // Master encryption key
+	keyring  *store.Keyring
+}
+
+func ctxWithTimeout() (context.Context, func()) {
+	return context.WithTimeout(context.Background(), connTimeout)
+}
+
+func (s *sqliteCtx) isShutdown() bool {
+	return atomic.LoadUint64(&s.shutdown) != 0
+}
+
+// put saves the provided key-value pairs to the database using a transaction.
+// New entries are inserted. Existing entries are updated.
+func (s *sqliteCtx) put(blobs map[string][]byte, encrypt bool, ctx context.Context, tx *sql.Tx) error {
+	// Encrypt blobs
+	if encrypt {
+		encrypted := make(map[string][]byte, len(blobs))
+		for k, v := range blobs {
+			e, err := s.encrypt(ctx, tx, v)
+			if err != nil {
+				return err
+			}
+			encrypted[k] = e
+		}
+
+		// Sanity check
+		if len(encrypted) != len(blobs) {
+			return errors.Errorf("unexpected number of encrypted blobs")
+		}
+
+		blobs = encrypted
+	}
+
+	// Save blobs
+	for k, v := range blobs {
+		_, err := tx.ExecContext(ctx,
+			"INSERT OR REPLACE INTO kv (k, v) VALUES (?, ?);", k, v)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+// Put saves the provided key-value entries to the database. New entries are
+// inserted. Existing entries are updated.
+//
+// This operation is atomic.
+//
+// This function satisfies the store BlobKV interface.
+func (s *sqliteCtx) Put(blobs map[string][]byte, encrypt bool) error {
+	log.Tracef("Put: %v blobs", len(blobs))
+
+	if s.isShutdown() {
+		return store.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	// Start transaction
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Save blobs
+	err = s.put(blobs, encrypt, ctx, tx)
+	if err != nil {
+		// Attempt to roll back the transaction
+		if err2 := tx.Rollback(); err2 != nil {
+			// We're in trouble!
+			e := fmt.Sprintf("put: %v, unable to rollback: %v", err, err2)
+			panic(e)
+		}
+		return err
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Saved blobs (%v) to store", len(blobs))
+
+	return nil
+}
+
+// Del deletes the key-value entries from the database for the provided keys.
+//
+// This operation is atomic.
+//
+// This function satisfies the store BlobKV interface.
+func (s *sqliteCtx) Del(keys []string) error {
+	log.Tracef("Del: %v", keys)
+
+	if s.isShutdown() {
+		return store.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	// Start transaction
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	// Delete blobs
+	for _, v := range keys {
+		_, err = tx.ExecContext(ctx, "DELETE FROM kv WHERE k = ?;", v)
+		if err != nil {
+			// Attempt to roll back the transaction
+			if err2 := tx.Rollback(); err2 != nil {
+				// We're in trouble!
+				e := fmt.Sprintf("del: %v, unable to rollback: %v", err, err2)
+				panic(e)
+			}
+			return errors.WithStack(err)
+		}
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("Deleted blobs (%v) from store", len(keys))
+
+	return nil
+}
+
+// Get retrieves the key-value entries from the database for the provided
+// keys.
+//
+// An entry will not exist in the returned map for any blobs that are not
+// found. It is the responsibility of the caller to ensure a blob was returned
+// for all provided keys.
+//
+// This function satisfies the store BlobKV interface.
+func (s *sqliteCtx) Get(keys []string) (map[string][]byte, error) {
+	log.Tracef("Get: %v", keys)
+
+	if s.isShutdown() {
+		return nil, store.ErrShutdown
+	}
+
+	// Build the select statements
+	statements := buildSelectStatements(keys, maxPlaceholders)
+
+	log.Debugf("Get %v blobs using %v prepared statements",
+		len(keys), len(statements))
+
+	// Execute the statements
+	reply := make(map[string][]byte, len(keys))
+	for i, e := range statements {
+		log.Debugf("Executing select statement %v/%v", i+1, len(statements))
+
+		err := s.get(e, reply)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return reply, nil
+}
+
+// get executes the provided select statement and adds the returned blobs to
+// the reply map. The rows are closed before this function returns so that
+// the connection is released prior to the next statement being executed.
+func (s *sqliteCtx) get(e selectStatement, reply map[string][]byte) error {
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, e.Query, e.Args...)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	// Unpack the reply
+	for rows.Next() {
+		var k string
+		var v []byte
+		err = rows.Scan(&k, &v)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		// Decrypt the blob if required
+		if isEncrypted(v) {
+			log.Tracef("Encrypted blob: %v", k)
+			v, _, err = s.decrypt(v)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Save the blob
+		reply[k] = v
+	}
+	err = rows.Err()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Keys returns the keys of the entries in the database that begin with the
+// provided prefix that sort after the provided after key, sorted in ascending
+// order. The limit is the maximum number of keys that are returned. A limit of
+// 0 returns all remaining keys. The encryption
+// key params and the encryption keys are not returned.
+//
+// This function satisfies the store BlobKV interface.
+func (s *sqliteCtx) Keys(prefix, after string, limit uint32) ([]string, error) {
+	log.Tracef("Keys: %v %v %v", prefix, after, limit)
+
+	if s.isShutdown() {
+		return nil, store.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	q, args := buildKeysQuery(prefix, after, limit)
+	rows, err := s.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0, limit)
+	for rows.Next() {
+		var k string
+		err = rows.Scan(&k)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		keys = append(keys, k)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return keys, nil
+}
+
+// Reencrypt walks the entries in the database in ascending key order,
+// starting after the provided key, and re-encrypts the blobs that were not
+// encrypted using the active encryption key. At most limit entries are
+// inspected. The encryption key params and the encryption keys are not
+// inspected.
+//
+// This operation is atomic.
+//
+// This function satisfies the store BlobKV interface.
+func (s *sqliteCtx) Reencrypt(after string, limit uint32) (*store.ReencryptReply, error) {
+	log.Tracef("Reencrypt: %v %v", after, limit)
+
+	if s.isShutdown() {
+		return nil, store.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	// Start transaction
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-encrypt blobs
+	rr, err := s.reencrypt(ctx, tx, after, limit)
+	if err != nil {
+		// Attempt to roll back the transaction
+		if err2 := tx.Rollback(); err2 != nil {
+			// We're in trouble!
+			e := fmt.Sprintf("reencrypt: %v, unable to rollback: %v", err, err2)
+			panic(e)
+		}
+		return nil, err
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Re-encrypted blobs (%v/%v) in store",
+		rr.Reencrypted, rr.Inspected)
+
+	return rr, nil
+}
+
+// reencrypt re-encrypts a batch of blobs using the provided transaction.
+func (s *sqliteCtx) reencrypt(ctx context.Context, tx *sql.Tx, after string, limit uint32) (*store.ReencryptReply, error) {
+	// Get the batch of entries
+	rows, err := tx.QueryContext(ctx,
+		"SELECT k, v FROM kv WHERE k > ? AND k NOT IN (?, ?) "+
+			"ORDER BY k LIMIT ?;", after, encryptionKeyParamsKey,
+		encryptionKeysKey, limit)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var (
+		rr      store.ReencryptReply
+		entries = make(map[string][]byte, limit)
+	)
+	for rows.Next() {
+		var k string
+		var v []byte
+		err = rows.Scan(&k, &v)
+		if err != nil {
+			rows.Close()
+			return nil, errors.WithStack(err)
+		}
+		rr.Inspected++
+		rr.LastKey = k
+		if isEncrypted(v) {
+			entries[k] = v
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Re-encrypt the blobs that were encrypted with a previous key
+	active := s.keyring.Active()
+	for k, v := range entries {
+		version, err := store.KeyVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		if version == active {
+			continue
+		}
+		b, _, err := s.decrypt(v)
+		if err != nil {
+			return nil, fmt.Errorf("decrypt %v: %v", k, err)
+		}
+		e, err := s.encrypt(ctx, tx, b)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE kv SET v = ? WHERE k = ?;", e, k)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		rr.Reencrypted++
+	}
+
+	return &rr, nil
+}
+
+// Ping verifies that the database is reachable.
+//
+// This function satisfies the store BlobKV interface.
+func (s *sqliteCtx) Ping() error {
+	log.Tracef("Ping")
+
+	if s.isShutdown() {
+		return store.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	err := s.db.PingContext(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Close closes the database connection.
+func (s *sqliteCtx) Close() {
+	log.Tracef("Close")
+
+	atomic.AddUint64(&s.shutdown, 1)
+
+	// Zero the encryption keys
+	util.Zero(s.key[:])
+	s.keyring.Zero()
+
+	// Close sqlite connection
+	s.db.Close()
+}
+
+// selectStatement contains the query string and arguments for a SELECT
+// statement.
+type selectStatement struct {
+	Query string
+	Args  []interface{}
+}
+
+// buildSelectStatements builds the SELECT statements that can be executed
+// against the SQLite key-value store. The maximum number of records that will
+// be retrieved in any individual SELECT statement is determined by the size
+// argument. The keys are split up into multiple statements if they exceed this
+// limit.
+func buildSelectStatements(keys []string, size int) []selectStatement {
+	statements := make([]selectStatement, 0, (len(keys)/size)+1)
+	var startIdx int
+	for startIdx < len(keys) {
+		// Find the end index
+		endIdx := startIdx + size
+		if endIdx > len(keys) {
+			// We've reached the end of the slice
+			endIdx = len(keys)
+		}
+
+		// startIdx is included. endIdx is excluded.
+		statementKeys := keys[startIdx:endIdx]
+
+		// Build the query
+		q := buildSelectQuery(len(statementKeys))
+		log.Tracef("%v", q)
+
+		// Convert the keys to interfaces. The sql query
+		// methods require arguments be interfaces.
+		args := make([]interface{}, len(statementKeys))
+		for i, v := range statementKeys {
+			args[i] = v
+		}
+
+		// Save the statement
+		statements = append(statements, selectStatement{
+			Query: q,
+			Args:  args,
+		})
+
+		// Update the start index
+		startIdx = endIdx
+	}
+
+	return statements
+}
+
+// buildSelectQuery returns a query string for the SQLite key-value store.
+//
+// Example: "SELECT k, v FROM kv WHERE k IN (?,?);"
+func buildSelectQuery(placeholders int) string {
+	return fmt.Sprintf("SELECT k, v FROM kv WHERE k IN %v;",
+		buildPlaceholders(placeholders))
+}
+
+// buildPlaceholders builds and returns a parameter placeholder string with the
+// specified number of placeholders.
+//
+// Input: 1  Output: "(?)"
+// Input: 3  Output: "(?,?,?)"
+func buildPlaceholders(placeholders int) string {
+	var b strings.Builder
+
+	b.WriteString("(")
+	for i := 0; i < placeholders; i++ {
+		b.WriteString("?")
+		// Don't add a comma on the last one
+		if i < placeholders-1 {
+			b.WriteString(",")
+		}
+	}
+	b.WriteString(")")
+
+	return b.String()
+}
+
+// New opens the SQLite database file at the provided path, creating it if it
+// does not exist, and returns a pointer to the created sqlite struct. The
+// password is used to derive the encryption key that is used to encrypt blobs
+// at rest.
+func New(dbFile, password string) (*sqliteCtx, error) {
+	// The password is required to derive the encryption key
+	if password == "" {
+		return nil, errors.Errorf("password not provided")
+	}
+
+	// Setup the database directory
+	err := os.MkdirAll(filepath.Dir(dbFile), 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	// Open database
+	log.Infof("SQLite database: %v", dbFile)
+
+	dsn := fmt.Sprintf("file:%v?_busy_timeout=%v&_journal_mode=WAL"+
+		"&_txlock=immediate", dbFile, busyTimeout)
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only allows a single writer at a time. Limiting the pool
+	// to a single connection serializes access to the database and
+	// prevents SQLITE_BUSY errors from concurrent writers.
+	db.SetMaxOpenConns(1)
+
+	// Verify database connection
+	err = db.Ping()
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup key-value table
+	q := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %v (%v)`,
+		tableNameKeyValue, tableKeyValue)
+	_, err = db.Exec(q)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Setup nonce table
+	q = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %v (%v)`,
+		tableNameNonce, tableNonce)
+	_, err = db.Exec(q)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Setup sqlite context
+	s := &sqliteCtx{
+		db: db,
+	}
+
+	// Derive encryption key from password. Key is set in argon2idKey
+	err = s.deriveEncryptionKey(password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load the keys that were created by previous key rotations
+	err = s.loadEncryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// buildKeysQuery returns the SELECT statement and the arguments that are
+// used to retrieve a page of keys that begin with the provided prefix and that
+// sort after the provided after key. The prefix and the after key are matched
+// using a range on the primary key so that the lookup is able to use the
+// primary key index.
+func buildKeysQuery(prefix, after string, limit uint32) (string, []interface{}) {
+	var (
+		q    = "SELECT k FROM kv WHERE k NOT IN (?, ?)"
+		args = []interface{}{encryptionKeyParamsKey, encryptionKeysKey}
+	)
+	if prefix != "" {
+		q += " AND k >= ?"
+		args = append(args, prefix)
+		if end := prefixEnd(prefix); end != "" {
+			q += " AND k < ?"
+			args = append(args, end)
+		}
+	}
+	if after != "" {
+		q += " AND k > ?"
+		args = append(args, after)
+	}
+	q += " ORDER BY k"
+	if limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, int64(limit))
+	}
+	return q + ";", args
+}
+
+// prefixEnd returns the smallest string that is greater than all strings that
+// begin with the provided prefix. An empty string is returned if no such
+// string exists, i.e. the prefix only contains 0xff bytes.
+func prefixEnd(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
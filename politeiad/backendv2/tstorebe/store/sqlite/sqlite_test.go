@@ -0,0 +1,377 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package sqlite
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+)
+
+const testPassword = "passwordsosikrit"
+
+// newTestSQLite returns a new sqlite structure that has been setup for
+// testing. The database is created in a temporary directory that is removed
+// by the cleanup function.
+func newTestSQLite(t *testing.T) (*sqliteCtx, string, func()) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "sqlite.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbFile := filepath.Join(dir, "kv.db")
+	s, err := New(dbFile, testPassword)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+
+	return s, dbFile, cleanup
+}
+
+func TestPutGetDel(t *testing.T) {
+	s, _, cleanup := newTestSQLite(t)
+	defer cleanup()
+
+	var (
+		key1   = "key1"
+		key2   = "key2"
+		value1 = []byte("value1")
+		value2 = []byte("value2")
+	)
+
+	// Save one cleartext and one encrypted blob
+	err := s.Put(map[string][]byte{key1: value1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put(map[string][]byte{key2: value2}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the encrypted blob was encrypted at rest
+	var v []byte
+	err = s.db.QueryRow("SELECT v FROM kv WHERE k = ?;", key2).Scan(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncrypted(v) {
+		t.Fatalf("blob was not encrypted")
+	}
+
+	// Retrieve both blobs. Include a key that does not exist.
+	blobs, err := s.Get([]string{key1, key2, "key3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 2 {
+		t.Fatalf("got %v blobs, want 2", len(blobs))
+	}
+	if !bytes.Equal(blobs[key1], value1) {
+		t.Errorf("got '%s' for value 1; want '%s'", blobs[key1], value1)
+	}
+	if !bytes.Equal(blobs[key2], value2) {
+		t.Errorf("got '%s' for value 2; want '%s'", blobs[key2], value2)
+	}
+
+	// Overwrite an existing blob
+	value1 = []byte("value1-updated")
+	err = s.Put(map[string][]byte{key1: value1}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs, err = s.Get([]string{key1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blobs[key1], value1) {
+		t.Errorf("got '%s' for value 1; want '%s'", blobs[key1], value1)
+	}
+
+	// Delete the blobs
+	err = s.Del([]string{key1, key2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs, err = s.Get([]string{key1, key2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 0 {
+		t.Fatalf("got %v blobs, want 0", len(blobs))
+	}
+}
+
+func TestGetMultiQuery(t *testing.T) {
+	s, _, cleanup := newTestSQLite(t)
+	defer cleanup()
+
+	// Verify that blobs are returned correctly when the keys are
+	// split up into multiple select statements.
+	keys := []string{"key1", "key2", "key3", "key4", "key5"}
+	blobs := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		blobs[k] = []byte(k)
+	}
+	err := s.Put(blobs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statements := buildSelectStatements(keys, 2)
+	if len(statements) != 3 {
+		t.Fatalf("got %v statements, want 3", len(statements))
+	}
+	reply := make(map[string][]byte, len(keys))
+	for _, e := range statements {
+		err = s.get(e, reply)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(reply) != len(keys) {
+		t.Fatalf("got %v blobs, want %v", len(reply), len(keys))
+	}
+	for _, k := range keys {
+		if !bytes.Equal(reply[k], []byte(k)) {
+			t.Errorf("got '%s' for %v", reply[k], k)
+		}
+	}
+}
+
+func TestGetManyKeys(t *testing.T) {
+	s, _, cleanup := newTestSQLite(t)
+	defer cleanup()
+
+	// Verify that a get of more keys than the maximum number of
+	// placeholders is split up into multiple queries.
+	keys := make([]string, 0, maxPlaceholders+501)
+	blobs := make(map[string][]byte, cap(keys))
+	for i := 0; i < cap(keys); i++ {
+		k := fmt.Sprintf("key%v", i)
+		keys = append(keys, k)
+		blobs[k] = []byte(k)
+	}
+	err := s.Put(blobs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := s.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reply) != len(keys) {
+		t.Fatalf("got %v blobs, want %v", len(reply), len(keys))
+	}
+	for _, k := range keys {
+		if !bytes.Equal(reply[k], []byte(k)) {
+			t.Errorf("got '%s' for %v", reply[k], k)
+		}
+	}
+}
+
+func TestEncryptionKey(t *testing.T) {
+	s, dbFile, cleanup := newTestSQLite(t)
+	defer cleanup()
+
+	// Save an encrypted blob then close the database
+	var (
+		key   = "key"
+		value = []byte("encryptmeyo")
+	)
+	err := s.Put(map[string][]byte{key: value}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Reopening the database using a different password should fail
+	_, err = New(dbFile, "wrongpassword")
+	if err == nil {
+		t.Fatalf("expected error when using a different password")
+	}
+
+	// Reopening the database using the same password should derive
+	// the same encryption key.
+	s2, err := New(dbFile, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	blobs, err := s2.Get([]string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blobs[key], value) {
+		t.Fatalf("got '%s', want '%s'", blobs[key], value)
+	}
+
+	// Verify that the closed context returns a shutdown error
+	_, err = s.Get([]string{key})
+	if err != store.ErrShutdown {
+		t.Fatalf("got err %v, want %v", err, store.ErrShutdown)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	s, _, cleanup := newTestSQLite(t)
+	defer cleanup()
+
+	blobs := map[string][]byte{
+		"e_key1":  []byte("value1"),
+		"e_key2":  []byte("value2"),
+		"e_key3":  []byte("value3"),
+		"ekey4":   []byte("value4"),
+		"pi-key5": []byte("value5"),
+	}
+	err := s.Put(blobs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		prefix string
+		after  string
+		limit  uint32
+		keys   []string
+	}{
+		{"", "", 0, []string{"e_key1", "e_key2", "e_key3", "ekey4", "pi-key5"}},
+		{"e_", "", 0, []string{"e_key1", "e_key2", "e_key3"}},
+		{"e_", "e_key1", 1, []string{"e_key2"}},
+		{"e_", "e_key1", 0, []string{"e_key2", "e_key3"}},
+		{"e_", "e_key3", 2, []string{}},
+		{"e_", "a", 1, []string{"e_key1"}},
+		{"", "e_key3", 0, []string{"ekey4", "pi-key5"}},
+		{"pi-", "", 10, []string{"pi-key5"}},
+		{"%", "", 0, []string{}},
+	}
+	for _, tc := range tests {
+		keys, err := s.Keys(tc.prefix, tc.after, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(keys, ",") != strings.Join(tc.keys, ",") {
+			t.Errorf("prefix '%v' after '%v' limit %v: got %v, want %v",
+				tc.prefix, tc.after, tc.limit, keys, tc.keys)
+		}
+	}
+}
+
+func TestReencrypt(t *testing.T) {
+	s, dbFile, cleanup := newTestSQLite(t)
+	defer cleanup()
+
+	// Save blobs using the initial encryption key. Include a cleartext
+	// blob, which must not be modified.
+	blobs := map[string][]byte{
+		"key1": []byte("value1"),
+		"key2": []byte("value2"),
+		"key3": []byte("value3"),
+	}
+	err := s.Put(blobs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Put(map[string][]byte{"key4": []byte("value4")}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate the key and save a blob using the new key
+	version, err := s.RotateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 1 || s.EncryptionKeyVersion() != 1 {
+		t.Fatalf("got key version %v, want 1", version)
+	}
+	err = s.Put(map[string][]byte{"key5": []byte("value5")}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-encrypt the blobs in batches
+	var (
+		after       string
+		reencrypted uint32
+	)
+	for {
+		rr, err := s.Reencrypt(after, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reencrypted += rr.Reencrypted
+		after = rr.LastKey
+		if rr.Inspected < 2 {
+			break
+		}
+	}
+	if reencrypted != 3 {
+		t.Fatalf("got %v blobs re-encrypted, want 3", reencrypted)
+	}
+
+	// Verify the key version of every blob at rest
+	keys := []string{"key1", "key2", "key3", "key4", "key5"}
+	for _, k := range keys {
+		var v []byte
+		err = s.db.QueryRow("SELECT v FROM kv WHERE k = ?;", k).Scan(&v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k == "key4" {
+			if isEncrypted(v) {
+				t.Fatalf("cleartext blob was encrypted")
+			}
+			continue
+		}
+		version, err := store.KeyVersion(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != 1 {
+			t.Errorf("%v: got key version %v, want 1", k, version)
+		}
+	}
+
+	// Reopening the database loads the rotated key. The encryption
+	// keys are not returned as part of the keys.
+	s.Close()
+	s2, err := New(dbFile, testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	if s2.EncryptionKeyVersion() != 1 {
+		t.Fatalf("got key version %v, want 1", s2.EncryptionKeyVersion())
+	}
+	got, err := s2.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		want := []byte(strings.Replace(k, "key", "value", 1))
+		if !bytes.Equal(got[k], want) {
+			t.Errorf("got '%s' for %v, want '%s'", got[k], k, want)
+		}
+	}
+	all, err := s2.Keys("", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(all, ",") != strings.Join(keys, ",") {
+		t.Fatalf("got keys %v, want %v", all, keys)
+	}
+}
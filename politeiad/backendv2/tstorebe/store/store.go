@@ -8,10 +8,12 @@ import (
 	"bytes"
 	"compress/gzip"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/gob"
 	"encoding/hex"
 
 	"github.com/decred/politeia/util"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pkg/errors"
 )
 
@@ -50,8 +52,134 @@ func NewBlobEntry(dataHint, data []byte) BlobEntry {
 	}
 }
 
-// Blobify encodes the provided BlobEntry into a gzipped byte slice.
+// Blobs are encoded using a versioned envelope. The first byte of the
+// envelope is the blobMagic byte and the second byte is the encoding version.
+// The remainder of the envelope is the encoded blob entry.
+//
+// Blobs that were encoded prior to the envelope being introduced are gzipped
+// gob encodings of the blob entry. They begin with the gzip magic bytes and do
+// not have a version. These legacy blobs can still be decoded.
+const (
+	// blobMagic is the first byte of a versioned blob envelope. It can
+	// not be confused with the first byte of a legacy gzipped blob.
+	blobMagic byte = 0xbe
+
+	// BlobVersionLegacy is the version of the legacy gzip+gob blobs.
+	BlobVersionLegacy uint8 = 0
+
+	// BlobVersion1 blobs contain the raw digest, data hint and data of
+	// the blob entry, compressed using zstd. The digest and the data
+	// hint are prefixed with their uvarint encoded lengths. The data is
+	// the remainder of the decompressed payload.
+	BlobVersion1 uint8 = 1
+
+	// BlobVersion is the version that new blobs are encoded with.
+	BlobVersion = BlobVersion1
+
+	// gzipMagic is the first byte of a gzip stream.
+	gzipMagic byte = 0x1f
+)
+
+var (
+	// zstdEncoder and zstdDecoder are safe for concurrent use when only
+	// EncodeAll and DecodeAll are used.
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// BlobVersionGet returns the encoding version of the provided blob.
+func BlobVersionGet(blob []byte) (uint8, error) {
+	switch {
+	case len(blob) > 0 && blob[0] == gzipMagic:
+		return BlobVersionLegacy, nil
+	case len(blob) > 1 && blob[0] == blobMagic:
+		return blob[1], nil
+	}
+	return 0, errors.Errorf("unknown blob encoding")
+}
+
+// Blobify encodes the provided BlobEntry using the current blob version.
 func Blobify(be BlobEntry) ([]byte, error) {
+	// The blob entry fields are decoded so that the raw bytes are
+	// stored. Strict decoding ensures that the fields are re-encoded
+	// to the exact same strings when the blob is decoded.
+	digest, err := hex.DecodeString(be.Digest)
+	if err != nil || hex.EncodeToString(digest) != be.Digest {
+		return nil, errors.Errorf("invalid digest '%v'", be.Digest)
+	}
+	hint, err := base64.StdEncoding.Strict().DecodeString(be.DataHint)
+	if err != nil {
+		return nil, errors.Errorf("invalid data hint: %v", err)
+	}
+	data, err := base64.StdEncoding.Strict().DecodeString(be.Data)
+	if err != nil {
+		return nil, errors.Errorf("invalid data: %v", err)
+	}
+
+	payload := make([]byte, 0, 2*binary.MaxVarintLen64+
+		len(digest)+len(hint)+len(data))
+	payload = binary.AppendUvarint(payload, uint64(len(digest)))
+	payload = append(payload, digest...)
+	payload = binary.AppendUvarint(payload, uint64(len(hint)))
+	payload = append(payload, hint...)
+	payload = append(payload, data...)
+
+	return zstdEncoder.EncodeAll(payload, []byte{blobMagic, BlobVersion1}), nil
+}
+
+// Deblob decodes the provided blob into a BlobEntry. Both versioned blobs and
+// legacy gzip+gob blobs are supported.
+func Deblob(blob []byte) (*BlobEntry, error) {
+	version, err := BlobVersionGet(blob)
+	if err != nil {
+		return nil, err
+	}
+	switch version {
+	case BlobVersionLegacy:
+		return deblobLegacy(blob)
+	case BlobVersion1:
+		return deblobV1(blob[2:])
+	}
+	return nil, errors.Errorf("unsupported blob version %v", version)
+}
+
+// deblobV1 decodes the payload of a version 1 blob.
+func deblobV1(b []byte) (*BlobEntry, error) {
+	payload, err := zstdDecoder.DecodeAll(b, nil)
+	if err != nil {
+		return nil, err
+	}
+	digest, payload, err := readUvarintBytes(payload)
+	if err != nil {
+		return nil, errors.Errorf("digest: %v", err)
+	}
+	hint, data, err := readUvarintBytes(payload)
+	if err != nil {
+		return nil, errors.Errorf("data hint: %v", err)
+	}
+	return &BlobEntry{
+		Digest:   hex.EncodeToString(digest),
+		DataHint: base64.StdEncoding.EncodeToString(hint),
+		Data:     base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// readUvarintBytes reads a uvarint length prefixed byte slice from the
+// provided buffer. The byte slice and the remainder of the buffer are
+// returned.
+func readUvarintBytes(b []byte) ([]byte, []byte, error) {
+	l, n := binary.Uvarint(b)
+	if n <= 0 || l > uint64(len(b)-n) {
+		return nil, nil, errors.Errorf("invalid length")
+	}
+	b = b[n:]
+	return b[:l], b[l:], nil
+}
+
+// blobifyLegacy encodes the provided BlobEntry into a gzipped byte slice.
+// This is the legacy blob encoding. It is only used to test that legacy blobs
+// can still be decoded.
+func blobifyLegacy(be BlobEntry) ([]byte, error) {
 	var b bytes.Buffer
 	zw := gzip.NewWriter(&b)
 	enc := gob.NewEncoder(zw)
@@ -67,8 +195,8 @@ func Blobify(be BlobEntry) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
-// Deblob decodes the provided gzipped byte slice into a BlobEntry.
-func Deblob(blob []byte) (*BlobEntry, error) {
+// deblobLegacy decodes the provided gzipped byte slice into a BlobEntry.
+func deblobLegacy(blob []byte) (*BlobEntry, error) {
 	zr, err := gzip.NewReader(bytes.NewReader(blob))
 	if err != nil {
 		return nil, err
@@ -108,6 +236,39 @@ type BlobKV interface {
 	// returned for all provided keys.
 	Get(keys []string) (map[string][]byte, error)
 
+	// Keys returns the keys of the entries in the database that begin
+	// with the provided prefix, sorted in ascending order. Only the keys
+	// that sort after the provided after key are returned, which allows
+	// the keys to be paged through using the last key of the previous
+	// page as the cursor. The limit is the maximum number of keys that
+	// are returned. A limit of 0 returns all remaining keys. All keys are
+	// matched if the prefix is empty and all matching keys are returned
+	// if the after key is empty.
+	// Entries that are used internally by the store implementation, such
+	// as the encryption key params, are not returned.
+	Keys(prefix, after string, limit uint32) ([]string, error)
+
+	// EncryptionKeyVersion returns the version of the encryption key that
+	// new blobs are encrypted with.
+	EncryptionKeyVersion() uint32
+
+	// RotateEncryptionKey creates a new encryption key and makes it the
+	// active key. Blobs that were encrypted using a previous key remain
+	// readable. The version of the new key is returned.
+	RotateEncryptionKey() (uint32, error)
+
+	// Reencrypt walks the entries in the database in ascending key order,
+	// starting after the provided key, and re-encrypts the blobs that were
+	// not encrypted using the active encryption key. At most limit entries
+	// are inspected. Cleartext blobs are not modified. Entries that are
+	// used internally by the store implementation are not inspected.
+	//
+	// This operation is atomic.
+	Reencrypt(after string, limit uint32) (*ReencryptReply, error)
+
+	// Ping verifies that the database is reachable.
+	Ping() error
+
 	// Close closes the database connection.
 	Close()
 }
@@ -0,0 +1,134 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBlobify(t *testing.T) {
+	be := NewBlobEntry([]byte(`{"type":"struct","descriptor":"test"}`),
+		bytes.Repeat([]byte("vote"), 1024))
+
+	// Encode using the current version
+	b, err := Blobify(be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := BlobVersionGet(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != BlobVersion {
+		t.Fatalf("got version %v, want %v", version, BlobVersion)
+	}
+	got, err := Deblob(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != be {
+		t.Fatalf("got %+v, want %+v", got, be)
+	}
+
+	// Legacy blobs can still be decoded
+	legacy, err := blobifyLegacy(be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err = BlobVersionGet(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != BlobVersionLegacy {
+		t.Fatalf("got version %v, want %v", version, BlobVersionLegacy)
+	}
+	got, err = Deblob(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != be {
+		t.Fatalf("got %+v, want %+v", got, be)
+	}
+	if len(b) >= len(legacy) {
+		t.Fatalf("versioned blob is not smaller than the legacy blob: "+
+			"%v >= %v", len(b), len(legacy))
+	}
+
+	// An empty data hint and payload are allowed
+	empty := NewBlobEntry(nil, nil)
+	b, err = Blobify(empty)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = Deblob(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != empty {
+		t.Fatalf("got %+v, want %+v", got, empty)
+	}
+}
+
+func TestBlobifyErrors(t *testing.T) {
+	be := NewBlobEntry([]byte("hint"), []byte("data"))
+
+	// Blob entries that can not be re-encoded exactly are rejected
+	tests := []struct {
+		name string
+		be   BlobEntry
+	}{
+		{
+			"uppercase digest",
+			BlobEntry{
+				Digest:   "AB" + be.Digest[2:],
+				DataHint: be.DataHint,
+				Data:     be.Data,
+			},
+		},
+		{
+			"invalid data hint",
+			BlobEntry{
+				Digest:   be.Digest,
+				DataHint: "not base64",
+				Data:     be.Data,
+			},
+		},
+		{
+			"unpadded data",
+			BlobEntry{
+				Digest:   be.Digest,
+				DataHint: be.DataHint,
+				Data:     "ZGF0YQ",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Blobify(tc.be)
+			if err == nil {
+				t.Fatalf("got nil error")
+			}
+		})
+	}
+
+	// Unknown and corrupt blobs are rejected
+	b, err := Blobify(be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs := [][]byte{
+		nil,
+		{0x00, 0x01},
+		{blobMagic, 0xff},
+		b[:len(b)-2],
+	}
+	for i, v := range blobs {
+		_, err := Deblob(v)
+		if err == nil {
+			t.Fatalf("blob %v: got nil error", i)
+		}
+	}
+}
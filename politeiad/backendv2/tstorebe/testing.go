@@ -10,7 +10,9 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tstore"
+	"github.com/robfig/cron"
 )
 
 // NewTestTstoreBackend returns a tstoreBackend that is setup for testing and a
@@ -24,15 +26,30 @@ func NewTestTstoreBackend(t *testing.T) (*tstoreBackend, func()) {
 		t.Fatal(err)
 	}
 	dataDir := filepath.Join(appDir, "data")
+	err = os.MkdirAll(dataDir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	tstoreBackend := tstoreBackend{
 		appDir:     appDir,
 		dataDir:    dataDir,
 		tstore:     tstore.NewTestTstore(t, dataDir),
 		recordMtxs: make(map[string]*sync.Mutex),
+		changesC:   make(chan struct{}),
+		identity:   id,
+		cron:       cron.New(),
 	}
+	tstoreBackend.auditStart()
 
 	return &tstoreBackend, func() {
+		tstoreBackend.auditStop()
+
 		err = os.RemoveAll(appDir)
 		if err != nil {
 			t.Fatal(err)
@@ -42,7 +42,9 @@ type client struct {
 	ctx   context.Context
 }
 
-// NewClient returns a new client.
+// NewClient returns a new client. The grpc connection is established in the
+// background so that a trillian instance that is down does not block the
+// caller. Use Ping to check whether trillian is reachable.
 func NewClient(host string) (*client, error) {
 	// Default gprc max message size is ~4MB (4194304 bytes). This is
 	// not large enough for trees with tens of thousands of leaves.
@@ -56,25 +58,32 @@ func NewClient(host string) (*client, error) {
 	}
 
 	t := client{
+		host:  host,
 		grpc:  g,
 		log:   trillian.NewTrillianLogClient(g),
 		admin: trillian.NewTrillianAdminClient(g),
 		ctx:   context.Background(),
 	}
 
-	// The grpc dial requires a little time to connect
-	time.Sleep(time.Second)
-
-	// Ensure trillian is up and running
-	for t.grpc.GetState() != connectivity.Ready {
-		wait := 15 * time.Second
-		log.Infof("Cannot connect to trillian at %v; retry in %v ", host, wait)
-		time.Sleep(wait)
-	}
-
 	return &t, nil
 }
 
+// Ping verifies that the trillian grpc connection is ready. An idle
+// connection is asked to reconnect so that a trillian instance that comes
+// back up is picked up without waiting for the next request.
+//
+// This function satisfies the Client interface.
+func (t *client) Ping() error {
+	state := t.grpc.GetState()
+	switch state {
+	case connectivity.Ready:
+		return nil
+	case connectivity.Idle:
+		t.grpc.Connect()
+	}
+	return fmt.Errorf("trillian %v grpc connection is %v", t.host, state)
+}
+
 // Close closes the trillian grpc connection.
 //
 // This function satisfies the Client interface.
@@ -390,12 +399,14 @@ func (t *client) LeavesAppend(treeID int64, leaves []*trillian.LogLeaf) ([]Queue
 	return proofs, lr, nil
 }
 
-// leavesByRange returns the log leaves of a trillian tree by the range provided
-// by the user.
+// LeavesByRange returns count leaves of a trillian tree starting at the
+// provided leaf index. An error is returned if trillian does not return all
+// of the requested leaves, e.g. because they have not been integrated into the
+// tree yet.
 //
 // This function satisfies the Client interface.
-func (t *client) leavesByRange(treeID int64, startIndex, count int64) ([]*trillian.LogLeaf, error) {
-	log.Tracef("leavesByRange: %v %v %v", treeID, startIndex, count)
+func (t *client) LeavesByRange(treeID, startIndex, count int64) ([]*trillian.LogLeaf, error) {
+	log.Tracef("LeavesByRange: %v %v %v", treeID, startIndex, count)
 
 	glbrr, err := t.log.GetLeavesByRange(t.ctx,
 		&trillian.GetLeavesByRangeRequest{
@@ -406,10 +417,29 @@ func (t *client) leavesByRange(treeID int64, startIndex, count int64) ([]*trilli
 	if err != nil {
 		return nil, err
 	}
+	if int64(len(glbrr.Leaves)) != count {
+		return nil, fmt.Errorf("got %v leaves, want %v",
+			len(glbrr.Leaves), count)
+	}
 
 	return glbrr.Leaves, nil
 }
 
+// TreeSize returns the number of leaves that have been integrated into a
+// trillian tree according to its latest signed log root.
+//
+// This function satisfies the Client interface.
+func (t *client) TreeSize(treeID int64) (uint64, error) {
+	log.Tracef("TreeSize: %v", treeID)
+
+	_, lr, err := t.SignedLogRoot(&trillian.Tree{TreeId: treeID})
+	if err != nil {
+		return 0, err
+	}
+
+	return lr.TreeSize, nil
+}
+
 // LeavesAll returns all of the leaves for the provided treeID.
 //
 // This function satisfies the Client interface.
@@ -432,9 +462,9 @@ func (t *client) LeavesAll(treeID int64) ([]*trillian.LogLeaf, error) {
 	}
 
 	// Get all leaves
-	leaves, err := t.leavesByRange(treeID, 0, int64(lr.TreeSize))
+	leaves, err := t.LeavesByRange(treeID, 0, int64(lr.TreeSize))
 	if err != nil {
-		return nil, fmt.Errorf("leavesByRange: %v", err)
+		return nil, fmt.Errorf("LeavesByRange: %v", err)
 	}
 
 	return leaves, nil
@@ -0,0 +1,736 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlog
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle"
+	"github.com/google/trillian/merkle/compact"
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/types"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	rstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// Key prefixes for the embedded log database. Tree IDs and indexes
+	// are encoded as fixed width big endian integers so that iterating
+	// over a key prefix returns the entries in order.
+	keyPrefixTree = "tree-"  // tree-[treeID]
+	keyPrefixLeaf = "leaf-"  // leaf-[treeID][leafIndex]
+	keyPrefixHash = "lhash-" // lhash-[treeID][merkleLeafHash]
+	keyPrefixNode = "node-"  // node-[treeID][level][index]
+)
+
+var (
+	_ Client        = (*embeddedClient)(nil)
+	_ TreeImporter  = (*embeddedClient)(nil)
+	_ LogRootSigner = (*embeddedClient)(nil)
+)
+
+// LogRootSigner is implemented by the clients that sign the log roots using a
+// key that is known to the client.
+type LogRootSigner interface {
+	// PublicKey returns the public key that is used to verify the log
+	// root signatures.
+	PublicKey() ed25519.PublicKey
+}
+
+// embeddedClient implements the Client interface using an embedded RFC 6962
+// transparent log that persists all trees, leaves, and merkle tree nodes to a
+// local leveldb database. It removes the need to run a separate trillian log
+// server and log signer.
+//
+// All complete subtree nodes are saved to the database as they are created so
+// that inclusion proofs can be built without rehashing the tree. Log roots are
+// signed using an ed25519 key that is provided by the caller, who is
+// responsible for storing it securely. The signature is included in the log
+// root metadata.
+type embeddedClient struct {
+	sync.Mutex
+	db      *leveldb.DB
+	privKey ed25519.PrivateKey
+	factory *compact.RangeFactory
+}
+
+// treeEntry is the database representation of a tree.
+type treeEntry struct {
+	TreeID     int64              `json:"treeid"`
+	State      trillian.TreeState `json:"state"`
+	CreateTime int64              `json:"createtime"` // Unix nano
+	UpdateTime int64              `json:"updatetime"` // Unix nano
+
+	// Log root fields. Range contains the compact range hashes of the
+	// tree, i.e. the roots of the minimal set of perfect subtrees that
+	// cover all of the leaves. This allows new leaves to be appended
+	// without needing to load the full tree.
+	Size      uint64   `json:"size"`
+	RootHash  []byte   `json:"roothash"`
+	Timestamp uint64   `json:"timestamp"` // Unix nano
+	Revision  uint64   `json:"revision"`
+	Range     [][]byte `json:"range"`
+}
+
+// tree returns the trillian Tree for the tree entry.
+func (e *treeEntry) tree() *trillian.Tree {
+	return &trillian.Tree{
+		TreeId:     e.TreeID,
+		TreeState:  e.State,
+		TreeType:   trillian.TreeType_LOG,
+		CreateTime: timestamppb.New(time.Unix(0, e.CreateTime)),
+		UpdateTime: timestamppb.New(time.Unix(0, e.UpdateTime)),
+	}
+}
+
+func int64Bytes(i int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(i))
+	return b
+}
+
+func uint64Bytes(i uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, i)
+	return b
+}
+
+func keyTree(treeID int64) []byte {
+	return append([]byte(keyPrefixTree), int64Bytes(treeID)...)
+}
+
+func keyLeafPrefix(treeID int64) []byte {
+	return append([]byte(keyPrefixLeaf), int64Bytes(treeID)...)
+}
+
+func keyLeaf(treeID int64, leafIndex uint64) []byte {
+	return append(keyLeafPrefix(treeID), uint64Bytes(leafIndex)...)
+}
+
+func keyHash(treeID int64, merkleLeafHash []byte) []byte {
+	k := append([]byte(keyPrefixHash), int64Bytes(treeID)...)
+	return append(k, merkleLeafHash...)
+}
+
+func keyNode(treeID int64, id compact.NodeID) []byte {
+	k := append([]byte(keyPrefixNode), int64Bytes(treeID)...)
+	k = append(k, byte(id.Level))
+	return append(k, uint64Bytes(id.Index)...)
+}
+
+// treeGet returns the tree entry for a tree. A gRPC NotFound error is
+// returned if the tree does not exist so that callers are able to handle the
+// error the same way that they would handle a trillian error.
+func (e *embeddedClient) treeGet(treeID int64) (*treeEntry, error) {
+	b, err := e.db.Get(keyTree(treeID), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound,
+				"tree %v not found", treeID)
+		}
+		return nil, err
+	}
+	var te treeEntry
+	err = json.Unmarshal(b, &te)
+	if err != nil {
+		return nil, err
+	}
+	return &te, nil
+}
+
+// treePut adds the tree entry to the provided batch.
+func treePut(batch *leveldb.Batch, te treeEntry) error {
+	b, err := json.Marshal(te)
+	if err != nil {
+		return err
+	}
+	batch.Put(keyTree(te.TreeID), b)
+	return nil
+}
+
+// signedLogRoot returns the signed log root for the provided tree entry. The
+// log root is signed with the embedded log signing key and the signature is
+// set as the log root metadata.
+func (e *embeddedClient) signedLogRoot(te treeEntry) (*trillian.SignedLogRoot, *types.LogRootV1, error) {
+	lr := types.LogRootV1{
+		TreeSize:       te.Size,
+		RootHash:       te.RootHash,
+		TimestampNanos: te.Timestamp,
+		Revision:       te.Revision,
+	}
+	b, err := lr.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	lr.Metadata = ed25519.Sign(e.privKey, b)
+	b, err = lr.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &trillian.SignedLogRoot{LogRoot: b}, &lr, nil
+}
+
+// PublicKey returns the public key that is used to verify the log root
+// signatures of the embedded log.
+func (e *embeddedClient) PublicKey() ed25519.PublicKey {
+	return e.privKey.Public().(ed25519.PublicKey)
+}
+
+// VerifyLogRootSignature verifies that the provided log root was signed by
+// the embedded log that corresponds to the provided public key.
+func VerifyLogRootSignature(pubKey ed25519.PublicKey, lr types.LogRootV1) error {
+	sig := lr.Metadata
+	lr.Metadata = nil
+	b, err := lr.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pubKey, b, sig) {
+		return fmt.Errorf("invalid log root signature")
+	}
+	return nil
+}
+
+// Close closes the database.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) Close() {
+	log.Tracef("Close")
+
+	e.db.Close()
+}
+
+// Ping verifies that the tlog is reachable. The embedded tlog is stored in a
+// local database so it is always reachable.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) Ping() error {
+	return nil
+}
+
+// TreeNew creates a new tree and returns the tree and the signed log root of
+// the empty tree.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) TreeNew() (*trillian.Tree, *trillian.SignedLogRoot, error) {
+	log.Tracef("TreeNew")
+
+	e.Lock()
+	defer e.Unlock()
+
+	// Create a random, unused tree ID. The tree ID is used as the
+	// record token so it must be a positive value.
+	var treeID int64
+	for {
+		n, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+		if err != nil {
+			return nil, nil, err
+		}
+		treeID = n.Int64() + 1
+		_, err = e.treeGet(treeID)
+		if status.Code(err) == codes.NotFound {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return e.treeCreate(treeID)
+}
+
+// TreeImport creates a new tree using the provided tree ID and returns the
+// tree and the signed log root of the empty tree. A gRPC AlreadyExists error
+// is returned if a tree with the tree ID already exists.
+//
+// This function satisfies the TreeImporter interface.
+func (e *embeddedClient) TreeImport(treeID int64) (*trillian.Tree, *trillian.SignedLogRoot, error) {
+	log.Tracef("TreeImport: %v", treeID)
+
+	if treeID <= 0 {
+		return nil, nil, fmt.Errorf("invalid tree id %v", treeID)
+	}
+
+	e.Lock()
+	defer e.Unlock()
+
+	_, err := e.treeGet(treeID)
+	switch {
+	case err == nil:
+		return nil, nil, status.Errorf(codes.AlreadyExists,
+			"tree %v already exists", treeID)
+	case status.Code(err) != codes.NotFound:
+		return nil, nil, err
+	}
+
+	return e.treeCreate(treeID)
+}
+
+// treeCreate saves a new, empty tree to the database and returns the tree and
+// its signed log root.
+//
+// This function must be called WITH the lock held.
+func (e *embeddedClient) treeCreate(treeID int64) (*trillian.Tree, *trillian.SignedLogRoot, error) {
+	now := time.Now().UnixNano()
+	te := treeEntry{
+		TreeID:     treeID,
+		State:      trillian.TreeState_ACTIVE,
+		CreateTime: now,
+		UpdateTime: now,
+		Size:       0,
+		RootHash:   hasher.EmptyRoot(),
+		Timestamp:  uint64(now),
+		Revision:   0,
+		Range:      [][]byte{},
+	}
+	batch := new(leveldb.Batch)
+	err := treePut(batch, te)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = e.db.Write(batch, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	slr, _, err := e.signedLogRoot(te)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Debugf("Created tree: %v", treeID)
+
+	return te.tree(), slr, nil
+}
+
+// TreeFreeze sets the status of a tree to frozen and returns the updated tree.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) TreeFreeze(treeID int64) (*trillian.Tree, error) {
+	log.Tracef("TreeFreeze: %v", treeID)
+
+	e.Lock()
+	defer e.Unlock()
+
+	te, err := e.treeGet(treeID)
+	if err != nil {
+		return nil, err
+	}
+	te.State = trillian.TreeState_FROZEN
+	te.UpdateTime = time.Now().UnixNano()
+
+	batch := new(leveldb.Batch)
+	err = treePut(batch, *te)
+	if err != nil {
+		return nil, err
+	}
+	err = e.db.Write(batch, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return te.tree(), nil
+}
+
+// Tree returns a tree.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) Tree(treeID int64) (*trillian.Tree, error) {
+	log.Tracef("Tree: %v", treeID)
+
+	te, err := e.treeGet(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return te.tree(), nil
+}
+
+// TreesAll returns all trees in the embedded log.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) TreesAll() ([]*trillian.Tree, error) {
+	log.Tracef("TreesAll")
+
+	trees := make([]*trillian.Tree, 0, 1024)
+	iter := e.db.NewIterator(util.BytesPrefix([]byte(keyPrefixTree)), nil)
+	defer iter.Release()
+	for iter.Next() {
+		var te treeEntry
+		err := json.Unmarshal(iter.Value(), &te)
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, te.tree())
+	}
+	err := iter.Error()
+	if err != nil {
+		return nil, err
+	}
+
+	return trees, nil
+}
+
+// LeavesAppend appends leaves onto a tree. The leaves are appended in the
+// order in which they are provided and are integrated into the tree
+// immediately. Leaves that are duplicates of an existing leaf in the tree are
+// not appended. The queued leaf for a duplicate will contain an AlreadyExists
+// status code and no inclusion proof.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) LeavesAppend(treeID int64, leaves []*trillian.LogLeaf) ([]QueuedLeafProof, *types.LogRootV1, error) {
+	log.Tracef("LeavesAppend: %v %v", treeID, len(leaves))
+
+	e.Lock()
+	defer e.Unlock()
+
+	te, err := e.treeGet(treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if te.State == trillian.TreeState_FROZEN {
+		return nil, nil, fmt.Errorf("tree is frozen")
+	}
+
+	// Load the compact range of the existing tree
+	cr, err := e.factory.NewRange(0, te.Size, te.Range)
+	if err != nil {
+		return nil, nil, fmt.Errorf("NewRange: %v", err)
+	}
+
+	// Append the leaves. All new complete subtree nodes are saved to
+	// the batch by the visitor function.
+	var (
+		batch   = new(leveldb.Batch)
+		visitor = func(id compact.NodeID, hash []byte) {
+			batch.Put(keyNode(treeID, id), hash)
+		}
+		now    = time.Now()
+		queued = make([]*trillian.QueuedLogLeaf, 0, len(leaves))
+		dups   = make(map[string]struct{}, len(leaves))
+	)
+	for _, v := range leaves {
+		m := MerkleLeafHash(v.LeafValue)
+		l := &trillian.LogLeaf{
+			MerkleLeafHash:     m,
+			LeafValue:          v.LeafValue,
+			ExtraData:          v.ExtraData,
+			LeafIdentityHash:   m,
+			QueueTimestamp:     timestamppb.New(now),
+			IntegrateTimestamp: timestamppb.New(now),
+		}
+
+		// Check for duplicates
+		_, dup := dups[string(m)]
+		if !dup {
+			dup, err = e.db.Has(keyHash(treeID, m), nil)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if dup {
+			queued = append(queued, &trillian.QueuedLogLeaf{
+				Leaf: l,
+				Status: &rstatus.Status{
+					Code:    int32(codes.AlreadyExists),
+					Message: "leaf already exists",
+				},
+			})
+			continue
+		}
+		dups[string(m)] = struct{}{}
+
+		// Append the leaf
+		l.LeafIndex = int64(cr.End())
+		b, err := proto.Marshal(l)
+		if err != nil {
+			return nil, nil, err
+		}
+		batch.Put(keyLeaf(treeID, cr.End()), b)
+		batch.Put(keyHash(treeID, m), uint64Bytes(cr.End()))
+		batch.Put(keyNode(treeID, compact.NewNodeID(0, cr.End())), m)
+		err = cr.Append(m, visitor)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Append: %v", err)
+		}
+
+		queued = append(queued, &trillian.QueuedLogLeaf{
+			Leaf: l,
+			Status: &rstatus.Status{
+				Code: int32(codes.OK),
+			},
+		})
+	}
+
+	// Update the tree
+	if cr.End() != te.Size {
+		root, err := cr.GetRootHash(nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("GetRootHash: %v", err)
+		}
+		te.Size = cr.End()
+		te.RootHash = root
+		te.Timestamp = uint64(now.UnixNano())
+		te.Revision++
+		te.Range = cr.Hashes()
+		te.UpdateTime = now.UnixNano()
+		err = treePut(batch, *te)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Save all changes atomically
+	err = e.db.Write(batch, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Get the new signed log root and the inclusion proofs
+	_, lr, err := e.signedLogRoot(*te)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		proofs = make([]QueuedLeafProof, 0, len(queued))
+		failed int
+	)
+	for _, v := range queued {
+		qlp := QueuedLeafProof{
+			QueuedLeaf: v,
+		}
+		if codes.Code(v.GetStatus().GetCode()) == codes.OK {
+			qlp.Proof, err = e.inclusionProof(treeID, v.Leaf.LeafIndex, lr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("inclusionProof %v %x: %v",
+					treeID, v.Leaf.MerkleLeafHash, err)
+			}
+		} else {
+			failed++
+		}
+		proofs = append(proofs, qlp)
+	}
+
+	log.Debugf("Appended leaves (%v/%v) to tree %v",
+		len(leaves)-failed, len(leaves), treeID)
+
+	return proofs, lr, nil
+}
+
+// LeavesAll returns all leaves of a tree.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) LeavesAll(treeID int64) ([]*trillian.LogLeaf, error) {
+	log.Tracef("LeavesAll: %v", treeID)
+
+	te, err := e.treeGet(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only return the leaves that are part of the tree. Leaves are
+	// always saved atomically with the tree update so this should
+	// always be all of the leaves in the database.
+	leaves := make([]*trillian.LogLeaf, 0, te.Size)
+	iter := e.db.NewIterator(util.BytesPrefix(keyLeafPrefix(treeID)), nil)
+	defer iter.Release()
+	for iter.Next() && uint64(len(leaves)) < te.Size {
+		var l trillian.LogLeaf
+		err := proto.Unmarshal(iter.Value(), &l)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, &l)
+	}
+	err = iter.Error()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(leaves)) != te.Size {
+		return nil, fmt.Errorf("missing leaves: got %v, want %v",
+			len(leaves), te.Size)
+	}
+
+	return leaves, nil
+}
+
+// LeavesByRange returns count leaves of a tree starting at the provided leaf
+// index. An error is returned if the range extends past the end of the tree.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) LeavesByRange(treeID, startIndex, count int64) ([]*trillian.LogLeaf, error) {
+	log.Tracef("LeavesByRange: %v %v %v", treeID, startIndex, count)
+
+	te, err := e.treeGet(treeID)
+	if err != nil {
+		return nil, err
+	}
+	if startIndex < 0 || count < 0 ||
+		uint64(startIndex)+uint64(count) > te.Size {
+		return nil, fmt.Errorf("invalid range %v+%v for tree size %v",
+			startIndex, count, te.Size)
+	}
+
+	leaves := make([]*trillian.LogLeaf, 0, count)
+	iter := e.db.NewIterator(&util.Range{
+		Start: keyLeaf(treeID, uint64(startIndex)),
+		Limit: keyLeaf(treeID, uint64(startIndex+count)),
+	}, nil)
+	defer iter.Release()
+	for iter.Next() {
+		var l trillian.LogLeaf
+		err := proto.Unmarshal(iter.Value(), &l)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, &l)
+	}
+	err = iter.Error()
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(leaves)) != count {
+		return nil, fmt.Errorf("missing leaves: got %v, want %v",
+			len(leaves), count)
+	}
+
+	return leaves, nil
+}
+
+// TreeSize returns the number of leaves that have been appended onto a tree.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) TreeSize(treeID int64) (uint64, error) {
+	log.Tracef("TreeSize: %v", treeID)
+
+	te, err := e.treeGet(treeID)
+	if err != nil {
+		return 0, err
+	}
+
+	return te.Size, nil
+}
+
+// SignedLogRoot returns the signed log root of a tree.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) SignedLogRoot(tree *trillian.Tree) (*trillian.SignedLogRoot, *types.LogRootV1, error) {
+	log.Tracef("SignedLogRoot: %v", tree.TreeId)
+
+	te, err := e.treeGet(tree.TreeId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e.signedLogRoot(*te)
+}
+
+// InclusionProof returns a proof for the inclusion of a merkle leaf hash in a
+// log root.
+//
+// This function satisfies the Client interface.
+func (e *embeddedClient) InclusionProof(treeID int64, merkleLeafHash []byte, lrv1 *types.LogRootV1) (*trillian.Proof, error) {
+	log.Tracef("InclusionProof: %v %x", treeID, merkleLeafHash)
+
+	b, err := e.db.Get(keyHash(treeID, merkleLeafHash), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound,
+				"leaf hash %x not found", merkleLeafHash)
+		}
+		return nil, err
+	}
+	leafIndex := int64(binary.BigEndian.Uint64(b))
+
+	return e.inclusionProof(treeID, leafIndex, lrv1)
+}
+
+// inclusionProof returns the inclusion proof for the leaf at the provided
+// index. The proof is built from the complete subtree nodes that were saved
+// to the database when the leaves were appended and is verified against the
+// log root before being returned.
+func (e *embeddedClient) inclusionProof(treeID, leafIndex int64, lrv1 *types.LogRootV1) (*trillian.Proof, error) {
+	nodes, err := merkle.CalcInclusionProofNodeAddresses(int64(lrv1.TreeSize),
+		leafIndex)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, 0, len(nodes))
+	for _, v := range nodes {
+		h, err := e.db.Get(keyNode(treeID, v.ID), nil)
+		if err != nil {
+			return nil, fmt.Errorf("node %v/%v: %v", v.ID.Level, v.ID.Index, err)
+		}
+		hashes = append(hashes, h)
+	}
+	hashes, err = merkle.Rehash(hashes, nodes, hasher.HashChildren)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify inclusion proof
+	b, err := e.db.Get(keyLeaf(treeID, uint64(leafIndex)), nil)
+	if err != nil {
+		return nil, err
+	}
+	var l trillian.LogLeaf
+	err = proto.Unmarshal(b, &l)
+	if err != nil {
+		return nil, err
+	}
+	verifier := logverifier.New(hasher)
+	err = verifier.VerifyInclusionProof(leafIndex, int64(lrv1.TreeSize),
+		hashes, lrv1.RootHash, l.MerkleLeafHash)
+	if err != nil {
+		return nil, fmt.Errorf("VerifyInclusionProof: %v", err)
+	}
+
+	return &trillian.Proof{
+		LeafIndex: leafIndex,
+		Hashes:    hashes,
+	}, nil
+}
+
+// NewEmbeddedClient returns a new embedded log client that persists its data
+// to a leveldb database in the provided directory. The log roots are signed
+// using the provided key.
+func NewEmbeddedClient(dbDir string, privKey ed25519.PrivateKey) (*embeddedClient, error) {
+	if len(privKey) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid signing key size %v", len(privKey))
+	}
+
+	err := os.MkdirAll(dbDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Embedded tlog database: %v", dbDir)
+
+	db, err := leveldb.OpenFile(dbDir, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &embeddedClient{
+		db:      db,
+		privKey: privKey,
+		factory: &compact.RangeFactory{
+			Hash: hasher.HashChildren,
+		},
+	}, nil
+}
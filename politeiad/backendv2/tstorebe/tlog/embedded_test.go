@@ -0,0 +1,252 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tlog
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/logverifier"
+	"github.com/google/trillian/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestEmbeddedClient returns a new embeddedClient that has been setup for
+// testing.
+func newTestEmbeddedClient(t *testing.T) (*embeddedClient, string, func()) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "tlog.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := NewEmbeddedClient(dir, privKey)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	cleanup := func() {
+		e.Close()
+		os.RemoveAll(dir)
+	}
+
+	return e, dir, cleanup
+}
+
+// verifyInclusion verifies an inclusion proof the same way that the backend
+// verifies a trillian proof.
+func verifyInclusion(leafValue []byte, p *trillian.Proof, lr *types.LogRootV1) error {
+	verifier := logverifier.New(hasher)
+	return verifier.VerifyInclusionProof(p.LeafIndex, int64(lr.TreeSize),
+		p.Hashes, lr.RootHash, MerkleLeafHash(leafValue))
+}
+
+func TestEmbeddedClient(t *testing.T) {
+	e, dir, cleanup := newTestEmbeddedClient(t)
+	defer cleanup()
+
+	// Create a new tree
+	tree, slr, err := e.TreeNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lr types.LogRootV1
+	err = lr.UnmarshalBinary(slr.LogRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lr.TreeSize != 0 {
+		t.Fatalf("got tree size %v, want 0", lr.TreeSize)
+	}
+	err = VerifyLogRootSignature(e.PublicKey(), lr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Append leaves in batches of varying sizes so that the inclusion
+	// proofs are tested against trees that are and are not perfect.
+	var (
+		leafValues = make([][]byte, 0, 64)
+		roots      = make([]*types.LogRootV1, 0, 16)
+	)
+	for batch := 1; len(leafValues) < 50; batch++ {
+		leaves := make([]*trillian.LogLeaf, 0, batch)
+		for i := 0; i < batch; i++ {
+			v := []byte(fmt.Sprintf("leaf-%v", len(leafValues)))
+			leaves = append(leaves, NewLogLeaf(v, nil))
+			leafValues = append(leafValues, v)
+		}
+		queued, lr, err := e.LeavesAppend(tree.TreeId, leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lr.TreeSize != uint64(len(leafValues)) {
+			t.Fatalf("got tree size %v, want %v", lr.TreeSize, len(leafValues))
+		}
+		err = VerifyLogRootSignature(e.PublicKey(), *lr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range queued {
+			err = verifyInclusion(v.QueuedLeaf.Leaf.LeafValue, v.Proof, lr)
+			if err != nil {
+				t.Fatalf("queued leaf %v: %v", v.QueuedLeaf.Leaf.LeafIndex, err)
+			}
+		}
+		roots = append(roots, lr)
+	}
+
+	// Verify the inclusion proofs of all leaves against all previous
+	// log roots that include the leaf.
+	for i, v := range leafValues {
+		for _, lr := range roots {
+			if uint64(i) >= lr.TreeSize {
+				continue
+			}
+			p, err := e.InclusionProof(tree.TreeId, MerkleLeafHash(v), lr)
+			if err != nil {
+				t.Fatalf("leaf %v size %v: %v", i, lr.TreeSize, err)
+			}
+			err = verifyInclusion(v, p, lr)
+			if err != nil {
+				t.Fatalf("leaf %v size %v: %v", i, lr.TreeSize, err)
+			}
+		}
+	}
+
+	// Verify that duplicate leaves are not appended
+	queued, _, err := e.LeavesAppend(tree.TreeId,
+		[]*trillian.LogLeaf{NewLogLeaf(leafValues[0], nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := codes.Code(queued[0].QueuedLeaf.GetStatus().GetCode())
+	if c != codes.AlreadyExists {
+		t.Fatalf("got status code %v, want %v", c, codes.AlreadyExists)
+	}
+
+	// Verify that the data persists after the database is reopened
+	e.Close()
+	e, err = NewEmbeddedClient(dir, e.privKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	leaves, err := e.LeavesAll(tree.TreeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaves) != len(leafValues) {
+		t.Fatalf("got %v leaves, want %v", len(leaves), len(leafValues))
+	}
+	for i, v := range leaves {
+		if string(v.LeafValue) != string(leafValues[i]) {
+			t.Fatalf("leaf %v: got %s, want %s", i, v.LeafValue, leafValues[i])
+		}
+	}
+	_, lr2, err := e.SignedLogRoot(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := roots[len(roots)-1]
+	if lr2.TreeSize != last.TreeSize ||
+		string(lr2.RootHash) != string(last.RootHash) {
+		t.Fatalf("log root mismatch after reopen")
+	}
+
+	// Verify that a range of leaves can be retrieved
+	size, err := e.TreeSize(tree.TreeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != uint64(len(leafValues)) {
+		t.Fatalf("got tree size %v, want %v", size, len(leafValues))
+	}
+	leaves, err = e.LeavesByRange(tree.TreeId, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaves) != 3 || leaves[0].LeafIndex != 2 ||
+		string(leaves[2].LeafValue) != string(leafValues[4]) {
+		t.Fatalf("unexpected leaves by range %v", leaves)
+	}
+	_, err = e.LeavesByRange(tree.TreeId, int64(size)-1, 2)
+	if err == nil {
+		t.Fatalf("expected invalid range error")
+	}
+
+	// Verify frozen trees can not be appended to
+	_, err = e.TreeFreeze(tree.TreeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = e.LeavesAppend(tree.TreeId,
+		[]*trillian.LogLeaf{NewLogLeaf([]byte("frozen"), nil)})
+	if err == nil {
+		t.Fatalf("expected frozen tree error")
+	}
+
+	// Verify tree not found errors
+	_, err = e.LeavesAll(tree.TreeId + 1)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("got err %v, want NotFound", err)
+	}
+}
+
+func TestEmbeddedClientTreeImport(t *testing.T) {
+	e, _, cleanup := newTestEmbeddedClient(t)
+	defer cleanup()
+
+	// Import a tree using a specific tree ID
+	var treeID int64 = 1234567890
+	tree, _, err := e.TreeImport(treeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.TreeId != treeID {
+		t.Fatalf("got tree id %v, want %v", tree.TreeId, treeID)
+	}
+
+	// The tree ID can only be imported once
+	_, _, err = e.TreeImport(treeID)
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("got err %v, want AlreadyExists", err)
+	}
+
+	// Verify that the root hash computed from the leaf hashes matches
+	// the root hash of the log.
+	hashes := make([][]byte, 0, 7)
+	for i := 0; i < 7; i++ {
+		v := []byte(fmt.Sprintf("leaf-%v", i))
+		_, _, err := e.LeavesAppend(treeID,
+			[]*trillian.LogLeaf{NewLogLeaf(v, nil)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, MerkleLeafHash(v))
+
+		_, lr, err := e.SignedLogRoot(tree)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := RootHash(hashes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(root) != string(lr.RootHash) {
+			t.Fatalf("size %v: got root %x, want %x",
+				len(hashes), root, lr.RootHash)
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/google/trillian"
 	"github.com/google/trillian/types"
 	rstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var (
@@ -43,6 +44,14 @@ func NewTestClient(t *testing.T) *testClient {
 // This function satisfies the Client interface.
 func (t *testClient) Close() {}
 
+// Ping verifies that the tlog is reachable. There is nothing to do for the
+// test tlog client.
+//
+// This function satisfies the Client interface.
+func (t *testClient) Ping() error {
+	return nil
+}
+
 // TreeNew creates a new tree.
 //
 // This function satisfies the Client interface.
@@ -105,7 +114,7 @@ func (t *testClient) TreesAll() ([]*trillian.Tree, error) {
 	t.Lock()
 	defer t.Unlock()
 
-	trees := make([]*trillian.Tree, len(t.trees))
+	trees := make([]*trillian.Tree, 0, len(t.trees))
 	for _, v := range t.trees {
 		trees = append(trees, &trillian.Tree{
 			TreeId:      v.TreeId,
@@ -131,18 +140,17 @@ func (t *testClient) LeavesAppend(treeID int64, leavesAppend []*trillian.LogLeaf
 		leaves = make([]*trillian.LogLeaf, 0, len(leavesAppend))
 	}
 
-	// Get last leaf index
-	var index int64
-	if len(leaves) > 0 {
-		index = int64(len(leaves)) - 1
-	}
+	// Get next leaf index
+	index := int64(len(leaves))
 
 	// Append leaves
+	now := timestamppb.Now()
 	queued := make([]QueuedLeafProof, 0, len(leavesAppend))
 	for _, v := range leavesAppend {
 		// Append to leaves
 		v.MerkleLeafHash = MerkleLeafHash(v.LeafValue)
-		v.LeafIndex = index + 1
+		v.LeafIndex = index
+		v.QueueTimestamp = now
 		leaves = append(leaves, v)
 		index++
 
@@ -182,12 +190,56 @@ func (t *testClient) LeavesAll(treeID int64) ([]*trillian.LogLeaf, error) {
 		leaves = make([]*trillian.LogLeaf, 0)
 	}
 
-	// Copy leaves
+	return copyLeaves(leaves), nil
+}
+
+// LeavesByRange returns count leaves of a tree starting at the provided leaf
+// index.
+//
+// This function satisfies the Client interface.
+func (t *testClient) LeavesByRange(treeID, startIndex, count int64) ([]*trillian.LogLeaf, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	// Verify tree exists
+	_, ok := t.trees[treeID]
+	if !ok {
+		return nil, fmt.Errorf("tree not found")
+	}
+
+	leaves := t.leaves[treeID]
+	if startIndex < 0 || count < 0 || startIndex+count > int64(len(leaves)) {
+		return nil, fmt.Errorf("invalid range %v+%v for tree size %v",
+			startIndex, count, len(leaves))
+	}
+
+	return copyLeaves(leaves[startIndex : startIndex+count]), nil
+}
+
+// TreeSize returns the number of leaves that have been appended onto a tree.
+//
+// This function satisfies the Client interface.
+func (t *testClient) TreeSize(treeID int64) (uint64, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	// Verify tree exists
+	_, ok := t.trees[treeID]
+	if !ok {
+		return 0, fmt.Errorf("tree not found")
+	}
+
+	return uint64(len(t.leaves[treeID])), nil
+}
+
+// copyLeaves returns a deep copy of the provided leaves so that the caller is
+// not able to modify the leaves that are saved in memory.
+func copyLeaves(leaves []*trillian.LogLeaf) []*trillian.LogLeaf {
 	leavesCopy := make([]*trillian.LogLeaf, 0, len(leaves))
 	for _, v := range leaves {
 		var (
-			leafValue []byte
-			extraData []byte
+			leafValue = make([]byte, len(v.LeafValue))
+			extraData = make([]byte, len(v.ExtraData))
 		)
 		copy(leafValue, v.LeafValue)
 		copy(extraData, v.ExtraData)
@@ -196,10 +248,10 @@ func (t *testClient) LeavesAll(treeID int64) ([]*trillian.LogLeaf, error) {
 			LeafValue:      leafValue,
 			ExtraData:      extraData,
 			LeafIndex:      v.LeafIndex,
+			QueueTimestamp: v.QueueTimestamp,
 		})
 	}
-
-	return leavesCopy, nil
+	return leavesCopy
 }
 
 // SignedLogRoot has not been implemented yet.
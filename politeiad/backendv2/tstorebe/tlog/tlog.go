@@ -6,6 +6,7 @@ package tlog
 
 import (
 	"github.com/google/trillian"
+	"github.com/google/trillian/merkle/compact"
 	"github.com/google/trillian/merkle/rfc6962"
 	"github.com/google/trillian/types"
 )
@@ -18,6 +19,9 @@ type Client interface {
 	// Close closes the client connection.
 	Close()
 
+	// Ping verifies that the tlog is reachable.
+	Ping() error
+
 	// TreeNew creates a new tree.
 	TreeNew() (*trillian.Tree, *trillian.SignedLogRoot, error)
 
@@ -38,6 +42,14 @@ type Client interface {
 	// LeavesAll returns all leaves of a tree.
 	LeavesAll(treeID int64) ([]*trillian.LogLeaf, error)
 
+	// LeavesByRange returns count leaves of a tree starting at the
+	// provided leaf index.
+	LeavesByRange(treeID, startIndex, count int64) ([]*trillian.LogLeaf, error)
+
+	// TreeSize returns the number of leaves that have been appended
+	// onto a tree.
+	TreeSize(treeID int64) (uint64, error)
+
 	// SignedLogRoot returns the signed log root for a tree.
 	SignedLogRoot(tree *trillian.Tree) (*trillian.SignedLogRoot,
 		*types.LogRootV1, error)
@@ -48,6 +60,16 @@ type Client interface {
 		lrv1 *types.LogRootV1) (*trillian.Proof, error)
 }
 
+// TreeImporter is implemented by the tlog clients that are able to create a
+// tree using a caller provided tree ID. Tree IDs are used as record tokens so
+// they must be preserved when a tstore backup is restored. The trillian client
+// does not implement this interface since trillian always assigns the tree ID
+// itself.
+type TreeImporter interface {
+	// TreeImport creates a new, empty tree using the provided tree ID.
+	TreeImport(treeID int64) (*trillian.Tree, *trillian.SignedLogRoot, error)
+}
+
 // QueuedLeafProof contains the results of a leaf append command, i.e. the
 // QueuedLeaf and the inclusion proof for that leaf. If the append leaf command
 // fails the QueuedLeaf will contain an error code from the failure and the
@@ -76,3 +98,23 @@ var (
 func MerkleLeafHash(leafValue []byte) []byte {
 	return hasher.HashLeaf(leafValue)
 }
+
+// RootHash returns the RFC 6962 merkle root hash of a log that contains the
+// provided merkle leaf hashes. The leaf hashes must be provided in the order
+// in which they were appended onto the log.
+func RootHash(merkleLeafHashes [][]byte) ([]byte, error) {
+	factory := compact.RangeFactory{
+		Hash: hasher.HashChildren,
+	}
+	cr := factory.NewEmptyRange(0)
+	for _, v := range merkleLeafHashes {
+		err := cr.Append(v, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cr.End() == 0 {
+		return hasher.EmptyRoot(), nil
+	}
+	return cr.GetRootHash(nil)
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2021 The Decred developers
+// Copyright (c) 2020-2022 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -6,7 +6,6 @@ package tstore
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -15,7 +14,8 @@ import (
 	"time"
 
 	dcrtime "github.com/decred/dcrtime/api/v2"
-	"github.com/decred/dcrtime/merkle"
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/anchors"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
 	"github.com/decred/politeia/util"
@@ -30,23 +30,38 @@ const (
 	// anchors a few minutes prior to that.
 	// Seconds Minutes Hours Days Months DayOfWeek
 	anchorSchedule = "0 56 * * * *" // At minute 56 of every hour
-
-	// anchorID is included in the timestamp and verify requests as a
-	// unique identifier.
-	anchorID = "tstorebe"
 )
 
 // anchor represents an anchor, i.e. timestamp, of a trillian tree at a
 // specific tree size. The LogRootV1.RootHash is the merkle root hash of a
-// trillian tree. This root hash is submitted to dcrtime to be anchored and is
-// the anchored digest in the VerifyDigest. Only the root hash is anchored, but
-// the full LogRootV1 struct is saved as part of an anchor record so that it
-// can be used to retrieve inclusion proofs for any leaves that were included
-// in the root hash.
+// trillian tree. This root hash is submitted to the anchor providers and is
+// the anchored digest of every anchor proof. Only the root hash is anchored,
+// but the full LogRootV1 struct is saved as part of an anchor record so that
+// it can be used to retrieve inclusion proofs for any leaves that were
+// included in the root hash.
+//
+// Anchor records that were saved prior to the introduction of anchor
+// providers only contain the dcrtime VerifyDigest. New anchor records contain
+// an anchor for every provider that anchored the root hash, in the order that
+// the providers were configured.
 type anchor struct {
-	TreeID       int64                 `json:"treeid"`
-	LogRoot      *types.LogRootV1      `json:"logroot"`
-	VerifyDigest *dcrtime.VerifyDigest `json:"verifydigest"`
+	TreeID       int64                     `json:"treeid"`
+	LogRoot      *types.LogRootV1          `json:"logroot"`
+	VerifyDigest *dcrtime.VerifyDigest     `json:"verifydigest,omitempty"`
+	Anchors      []backend.TimestampAnchor `json:"anchors,omitempty"`
+}
+
+// timestampAnchors returns the anchors of the anchor record. The dcrtime
+// VerifyDigest of a legacy anchor record is converted into an anchor.
+func (a *anchor) timestampAnchors() ([]backend.TimestampAnchor, error) {
+	if a.VerifyDigest == nil {
+		return a.Anchors, nil
+	}
+	ta, err := anchors.DcrtimeAnchor(*a.VerifyDigest)
+	if err != nil {
+		return nil, err
+	}
+	return append([]backend.TimestampAnchor{*ta}, a.Anchors...), nil
 }
 
 // droppingAnchorGet returns the dropping anchor boolean, which is used to
@@ -67,6 +82,19 @@ func (t *Tstore) droppingAnchorSet(b bool) {
 	t.droppingAnchor = b
 }
 
+// droppingAnchorStart sets the dropping anchor boolean if an anchor is not
+// already being dropped. It returns whether the boolean was set.
+func (t *Tstore) droppingAnchorStart() bool {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.droppingAnchor {
+		return false
+	}
+	t.droppingAnchor = true
+	return true
+}
+
 var (
 	// errAnchorNotFound is returned when a anchor record does not
 	// exist for a leaf yet.
@@ -164,30 +192,23 @@ func (t *Tstore) anchorLatest(treeID int64) (*anchor, error) {
 	}
 
 	// Find the most recent anchor leaf
-	var key string
-	for i := len(leavesAll) - 1; i >= 0; i-- {
-		ed, err := extraDataDecode(leavesAll[i].ExtraData)
-		if err != nil {
-			return nil, err
-		}
-		if ed.Desc == dataDescriptorAnchor {
-			key = ed.storeKey()
-			break
-		}
+	key, err := anchorKeyLatest(leavesAll)
+	if err != nil {
+		return nil, err
 	}
 	if key == "" {
 		return nil, errAnchorNotFound
 	}
 
-	// Pull blob from key-value store
+	return t.anchorGet(key)
+}
+
+// anchorGet returns the anchor record for the provided key-value store key.
+func (t *Tstore) anchorGet(key string) (*anchor, error) {
 	blobs, err := t.store.Get([]string{key})
 	if err != nil {
 		return nil, fmt.Errorf("store Get: %v", err)
 	}
-	if len(blobs) != 1 {
-		return nil, fmt.Errorf("unexpected blobs count: got %v, want 1",
-			len(blobs))
-	}
 	b, ok := blobs[key]
 	if !ok {
 		return nil, fmt.Errorf("blob not found %v", key)
@@ -196,12 +217,23 @@ func (t *Tstore) anchorLatest(treeID int64) (*anchor, error) {
 	if err != nil {
 		return nil, err
 	}
-	a, err := convertAnchorFromBlobEntry(*be)
-	if err != nil {
-		return nil, err
-	}
+	return convertAnchorFromBlobEntry(*be)
+}
 
-	return a, nil
+// anchorKeyLatest returns the key-value store key of the most recent anchor
+// leaf in the provided leaves. An empty string is returned if the leaves do
+// not contain an anchor leaf.
+func anchorKeyLatest(leaves []*trillian.LogLeaf) (string, error) {
+	for i := len(leaves) - 1; i >= 0; i-- {
+		ed, err := extraDataDecode(leaves[i].ExtraData)
+		if err != nil {
+			return "", err
+		}
+		if ed.Desc == dataDescriptorAnchor {
+			return ed.storeKey(), nil
+		}
+	}
+	return "", nil
 }
 
 // anchorSave saves an anchor to the key-value store and appends a log leaf
@@ -213,8 +245,8 @@ func (t *Tstore) anchorSave(a anchor) error {
 		return fmt.Errorf("invalid tree id of 0")
 	case a.LogRoot == nil:
 		return fmt.Errorf("log root not found")
-	case a.VerifyDigest == nil:
-		return fmt.Errorf("verify digest not found")
+	case a.VerifyDigest == nil && len(a.Anchors) == 0:
+		return fmt.Errorf("anchors not found")
 	}
 
 	// Save anchor record to the kv store
@@ -226,6 +258,9 @@ func (t *Tstore) anchorSave(a anchor) error {
 	if err != nil {
 		return err
 	}
+	t.anchorSaveMtx.Lock()
+	defer t.anchorSaveMtx.Unlock()
+
 	key := storeKeyNew(false)
 	kv := map[string][]byte{key: b}
 	err = t.store.Put(kv, false)
@@ -245,7 +280,7 @@ func (t *Tstore) anchorSave(a anchor) error {
 	leaves := []*trillian.LogLeaf{
 		tlog.NewLogLeaf(d, extraData),
 	}
-	queued, _, err := t.tlog.LeavesAppend(a.TreeID, leaves)
+	queued, _, err := t.tlogLeavesAppend(a.TreeID, leaves)
 	if err != nil {
 		return fmt.Errorf("LeavesAppend: %v", err)
 	}
@@ -270,193 +305,192 @@ func (t *Tstore) anchorSave(a anchor) error {
 	return nil
 }
 
-// anchorWait waits for the anchor to drop. The anchor is not considered
-// dropped until dcrtime returns the ChainTimestamp in the reply. dcrtime does
-// not return the ChainTimestamp until the timestamp transaction has 6
-// confirmations. Once the timestamp has been dropped, the anchor record is
-// saved to the tstore, which means that an anchor leaf will be appended onto
-// all trees that were anchored and the anchor records saved to the kv store.
-func (t *Tstore) anchorWait(anchors []anchor, digests []string) {
-	// Verify we are not reentrant
-	if t.droppingAnchorGet() {
-		log.Errorf("waitForAchor: called reentrantly")
-		return
-	}
-
-	// We are now condsidered to be dropping an anchor
-	t.droppingAnchorSet(true)
-
+// anchorWait waits for the anchor to drop. The anchor providers are polled
+// until they have anchored all of the digests or until the provider times out.
+// The dcrtime provider, for example, does not consider a digest anchored until
+// the timestamp transaction has 6 confirmations. Once all providers have
+// finished, the anchor records are saved to the tstore, which means that an
+// anchor leaf will be appended onto all trees that were anchored and the
+// anchor records saved to the kv store. A tree is only skipped if none of the
+// providers anchored it. It will be anchored again during the next anchor
+// drop.
+//
+// The caller must have set the dropping anchor boolean. It is cleared once the
+// anchor has dropped.
+func (t *Tstore) anchorWait(pending []anchor, digests []string, providers []anchors.Provider) {
 	// Whatever happens in this function we must clear droppingAnchor
-	var exitErr error
-	defer func() {
-		t.droppingAnchorSet(false)
+	// and the in-flight anchor.
+	defer t.droppingAnchorSet(false)
+	defer t.anchorInFlightSet(nil)
 
-		if exitErr != nil {
-			log.Errorf("anchorWait: %v", exitErr)
-		}
-	}()
+	// Setup the in-flight anchor
+	inFlight := backend.AnchorInFlight{
+		Started: time.Now().Unix(),
+		Tokens:  make([]string, 0, len(pending)),
+		Pending: make(map[string][]string, len(providers)),
+	}
+	for _, v := range pending {
+		inFlight.Tokens = append(inFlight.Tokens,
+			hex.EncodeToString(tokenFromTreeID(v.TreeID)))
+	}
+	for _, v := range providers {
+		inFlight.Pending[v.ID()] = digests
+	}
+	t.anchorInFlightSet(&inFlight)
 
 	// Wait for anchor to drop
 	log.Infof("Waiting for anchor to drop")
 
-	// Continually check with dcrtime if the anchor has been dropped.
-	// The anchor is not considered dropped until the ChainTimestamp
-	// field of the dcrtime reply has been populated. dcrtime only
-	// populates the ChainTimestamp field once the dcr transaction has
-	// 6 confirmations.
 	var (
-		// The max retry period is set to 180 minutes to ensure that
-		// enough time is given for the anchor transaction to receive 6
-		// confirmations. This is based on the fact that each block has
-		// a 99.75% chance of being mined within 30 minutes.
-		period  = 5 * time.Minute             // check every 5 minute
-		retries = 180 / int(period.Minutes()) // for up to 180 minutes
-		ticker  = time.NewTicker(period)
-	)
-	defer ticker.Stop()
-	for try := 0; try < retries; try++ {
-		<-ticker.C
+		start = time.Now()
 
-		log.Debugf("Verify anchor attempt %v/%v", try+1, retries)
+		// waiting contains the providers that have not anchored all of
+		// the digests yet.
+		waiting = make(map[string]anchors.Provider, len(providers))
 
-		vbr, err := t.dcrtime.verifyBatch(anchorID, digests)
-		if err != nil {
-			exitErr = fmt.Errorf("verifyBatch: %v", err)
-			return
-		}
-
-		// We must wait until all digests have been anchored. Under
-		// normal circumstances this will happen during the same dcrtime
-		// transaction, but its possible for some of the digests to have
-		// already been anchored in previous transactions if politeiad
-		// was shutdown in the middle of the anchoring process.
-		//
-		// Ex: politeiad submits a digest for treeA to dcrtime. politeiad
-		// gets shutdown before an anchor record is added to treeA.
-		// dcrtime timestamps the treeA digest into block 1000. politeiad
-		// gets turned back on and a new record, treeB, is submitted
-		// prior to an anchor drop attempt. On the next anchor drop,
-		// politeiad will try to drop an anchor for both treeA and treeB
-		// since treeA is still considered unachored, however, when this
-		// part of the code gets hit dcrtime will immediately return a
-		// valid timestamp for treeA since it was already timestamped
-		// into block 1000. In this situation, the verify loop must also
-		// wait for treeB to be timestamped by dcrtime before continuing.
-		anchored := make(map[string]struct{}, len(digests))
-		for _, v := range vbr.Digests {
-			if v.Result != dcrtime.ResultOK {
-				// Something is wrong. Log the error and retry.
-				log.Errorf("Digest %v: %v (%v)",
-					v.Digest, dcrtime.Result[v.Result], v.Result)
-				break
-			}
-
-			// Transaction will be populated once the tx has been sent,
-			// otherwise is will be a zeroed out SHA256 digest.
-			b := make([]byte, sha256.Size)
-			if v.ChainInformation.Transaction == hex.EncodeToString(b) {
-				log.Debugf("Anchor tx not sent yet; retry in %v", period)
-				break
-			}
-
-			// ChainTimestamp will be populated once the tx has 6
-			// confirmations.
-			if v.ChainInformation.ChainTimestamp == 0 {
-				log.Debugf("Anchor tx %v not enough confirmations; retry in %v",
-					v.ChainInformation.Transaction, period)
-				break
-			}
-
-			// This digest has been anchored
-			anchored[v.Digest] = struct{}{}
-		}
-		if len(anchored) != len(digests) {
-			// There are still digests that are waiting to be anchored.
-			// Retry again after the wait period.
-			continue
-		}
-
-		// Save anchor records
-		for k, v := range anchors {
-			var (
-				verifyDigest = vbr.Digests[k]
-				digest       = verifyDigest.Digest
-				merkleRoot   = verifyDigest.ChainInformation.MerkleRoot
-				merklePath   = verifyDigest.ChainInformation.MerklePath
-			)
-
-			// Verify the anchored digest matches the root hash
-			if digest != hex.EncodeToString(v.LogRoot.RootHash) {
-				log.Errorf("anchorWait: digest mismatch: got %x, want %v",
-					digest, v.LogRoot.RootHash)
-				continue
+		// anchored contains the anchors of each digest.
+		anchored = make(map[string]map[string]backend.TimestampAnchor,
+			len(digests)) // [digest][providerID]anchor
+	)
+	for _, v := range providers {
+		waiting[v.ID()] = v
+	}
+	for try := 1; len(waiting) > 0; try++ {
+		var period time.Duration
+		for id, p := range waiting {
+			log.Debugf("Verify %v anchor attempt %v", id, try)
+
+			// We must wait until all digests have been anchored. Under
+			// normal circumstances this will happen during the same
+			// provider anchor, but its possible for some of the digests
+			// to have already been anchored in previous anchors if
+			// politeiad was shutdown in the middle of the anchoring
+			// process.
+			//
+			// Ex: politeiad submits a digest for treeA to dcrtime.
+			// politeiad gets shutdown before an anchor record is added
+			// to treeA. dcrtime timestamps the treeA digest into block
+			// 1000. politeiad gets turned back on and a new record,
+			// treeB, is submitted prior to an anchor drop attempt. On
+			// the next anchor drop, politeiad will try to drop an anchor
+			// for both treeA and treeB since treeA is still considered
+			// unachored, however, when this part of the code gets hit
+			// dcrtime will immediately return a valid timestamp for treeA
+			// since it was already timestamped into block 1000. In this
+			// situation, the verify loop must also wait for treeB to be
+			// timestamped by dcrtime before continuing.
+			remaining := make([]string, 0, len(digests))
+			for _, d := range digests {
+				if _, ok := anchored[d][id]; !ok {
+					remaining = append(remaining, d)
+				}
 			}
-
-			// Verify merkle path
-			mk, err := merkle.VerifyAuthPath(&merklePath)
+			proofs, err := p.Verify(remaining)
 			if err != nil {
-				log.Errorf("anchorWait: VerifyAuthPath: %v", err)
-				continue
+				log.Errorf("anchorWait: %v Verify: %v", id, err)
+				t.anchorFailureAdd(id, 0, fmt.Errorf("verify: %v", err))
 			}
-			if hex.EncodeToString(mk[:]) != merkleRoot {
-				log.Errorf("anchorWait: merkle root invalid: got %x, want %v",
-					mk[:], merkleRoot)
-				continue
+			for d, a := range proofs {
+				if anchored[d] == nil {
+					anchored[d] = make(map[string]backend.TimestampAnchor,
+						len(providers))
+				}
+				anchored[d][id] = a
 			}
-
-			// Verify digest is in the merkle path
-			var found bool
-			for _, v := range merklePath.Hashes {
-				if hex.EncodeToString(v[:]) == digest {
-					found = true
-					break
+			stillPending := make([]string, 0, len(remaining))
+			for _, d := range remaining {
+				if _, ok := proofs[d]; !ok {
+					stillPending = append(stillPending, d)
 				}
 			}
-			if !found {
-				log.Errorf("anchorWait: digest %v not found in merkle path", digest)
+			t.anchorPendingSet(id, stillPending)
+
+			pp, timeout := p.Wait()
+			switch {
+			case len(proofs) == len(remaining) && err == nil:
+				log.Infof("Anchor dropped by %v for %v digests", id,
+					len(digests))
+				delete(waiting, id)
+				continue
+			case time.Since(start) > timeout:
+				log.Errorf("Anchor drop timeout for %v, waited for: %v",
+					id, timeout)
+				t.anchorFailureAdd(id, 0, fmt.Errorf("timeout after %v; "+
+					"%v digests were not anchored", timeout,
+					len(stillPending)))
+				delete(waiting, id)
 				continue
 			}
+			if period == 0 || pp < period {
+				period = pp
+			}
+		}
+		if len(waiting) > 0 {
+			time.Sleep(period)
+		}
+	}
 
-			// Add VerifyDigest to the anchor record
-			v.VerifyDigest = &verifyDigest
-
-			// Save anchor
-			err = t.anchorSave(v)
-			if err != nil {
-				log.Errorf("anchorWait: anchorSave %v: %v", v.TreeID, err)
+	// Save anchor records
+	var saved int
+	for _, v := range pending {
+		digest := hex.EncodeToString(v.LogRoot.RootHash)
+		for _, p := range providers {
+			a, ok := anchored[digest][p.ID()]
+			if !ok {
 				continue
 			}
+			v.Anchors = append(v.Anchors, a)
 		}
-
-		log.Infof("Anchor dropped for %v records", len(vbr.Digests))
-		return
+		if len(v.Anchors) == 0 {
+			log.Errorf("anchorWait: tree %v was not anchored", v.TreeID)
+			t.anchorFailureAdd("", v.TreeID,
+				fmt.Errorf("not anchored by any provider"))
+			continue
+		}
+		err := t.anchorSave(v)
+		if err != nil {
+			log.Errorf("anchorWait: anchorSave %v: %v", v.TreeID, err)
+			t.anchorFailureAdd("", v.TreeID, fmt.Errorf("save: %v", err))
+			continue
+		}
+		saved++
 	}
 
-	log.Errorf("Anchor drop timeout, waited for: %v",
-		int(period.Minutes())*retries)
+	log.Infof("Anchor dropped for %v records", saved)
 }
 
 // anchorTrees drops an anchor for any trees that have unanchored leaves at the
 // time of invocation. A SHA256 digest of the tree's log root at its current
-// height is timestamped onto the decred blockchain using the dcrtime service.
-// The anchor data is saved to the key-value store and the tlog tree is updated
-// with an anchor leaf.
+// height is submitted to all of the anchor providers, e.g. timestamped onto the
+// decred blockchain using the dcrtime service. The anchor data is saved to the
+// key-value store and the tlog tree is updated with an anchor leaf. A
+// backend ErrAnchorInProgress is returned if the previous anchor has not
+// finished dropping.
 func (t *Tstore) anchorTrees() error {
-	// Ensure we are not reentrant
-	if t.droppingAnchorGet() {
-		// An anchor is not considered dropped until dcrtime returns the
-		// ChainTimestamp in the VerifyReply. dcrtime does not do this
-		// until the anchor tx has 6 confirmations, therefor, this code
-		// path can be hit if 6 blocks are not mined within the period
-		// specified by the anchor schedule. Though rare, the probability
-		// of this happening is not zero and should not be considered an
-		// error. We simply exit and will drop a new anchor at the next
-		// anchor period.
-		log.Infof("Attempting to drop an anchor while previous anchor " +
-			"has not finished dropping; skipping current anchor period")
+	if len(t.providers) == 0 {
+		// Anchoring is disabled
 		return nil
 	}
 
+	// Ensure we are not reentrant. An anchor is not considered dropped
+	// until the anchor providers have anchored the digests. dcrtime
+	// does not do this until the anchor tx has 6 confirmations,
+	// therefor, this code path can be hit if 6 blocks are not mined
+	// within the period specified by the anchor schedule.
+	if !t.droppingAnchorStart() {
+		return backend.ErrAnchorInProgress
+	}
+
+	// The dropping anchor boolean is cleared by anchorWait once the
+	// anchor has dropped. It must be cleared here if the providers are
+	// not waited on.
+	var waiting bool
+	defer func() {
+		if !waiting {
+			t.droppingAnchorSet(false)
+		}
+	}()
+
 	trees, err := t.tlog.TreesAll()
 	if err != nil {
 		return fmt.Errorf("TreesAll: %v", err)
@@ -466,14 +500,14 @@ func (t *Tstore) anchorTrees() error {
 
 	// digests contains the SHA256 digests of the LogRootV1.RootHash
 	// for all trees that need to be anchored. These will be submitted
-	// to dcrtime to be included in a dcrtime timestamp.
+	// to the anchor providers.
 	digests := make([]string, 0, len(trees))
 
-	// anchors contains an anchor structure for each tree that is being
-	// anchored. Once the dcrtime timestamp is successful, these
-	// anchors will be updated with the timestamp data and saved to the
-	// key-value store.
-	anchors := make([]anchor, 0, len(trees))
+	// pending contains an anchor structure for each tree that is being
+	// anchored. Once the anchor providers have anchored the digests,
+	// these anchors will be updated with the anchor proofs and saved to
+	// the key-value store.
+	pending := make([]anchor, 0, len(trees))
 
 	// Find the trees that need to be anchored. This is done by pulling
 	// the most recent anchor from the tree and checking the anchored
@@ -522,54 +556,45 @@ func (t *Tstore) anchorTrees() error {
 		if err != nil {
 			return fmt.Errorf("SignedLogRoot %v: %v", v.TreeId, err)
 		}
-		anchors = append(anchors, anchor{
+		pending = append(pending, anchor{
 			TreeID:  v.TreeId,
 			LogRoot: lr,
 		})
 
 		// Collate the tree's root hash. This is what gets submitted to
-		// dcrtime.
+		// the anchor providers.
 		digests = append(digests, hex.EncodeToString(lr.RootHash))
 
 		log.Debugf("Anchoring tree %v at height %v",
 			v.TreeId, lr.TreeSize)
 	}
-	if len(anchors) == 0 {
+	if len(pending) == 0 {
 		log.Infof("No trees to to anchor")
 		return nil
 	}
 
-	// Submit dcrtime anchor request
-	log.Infof("Anchoring %v trees", len(anchors))
+	// Submit the digests to the anchor providers. A provider that fails
+	// is skipped for this anchor period. The trees will be anchored by
+	// the other providers.
+	log.Infof("Anchoring %v trees", len(pending))
 
-	tbr, err := t.dcrtime.timestampBatch(anchorID, digests)
-	if err != nil {
-		return fmt.Errorf("timestampBatch: %v", err)
-	}
-	var failed bool
-	for i, v := range tbr.Results {
-		switch v {
-		case dcrtime.ResultOK:
-			// We're good; continue
-		case dcrtime.ResultExistsError:
-			// This can happen if politeiad was shutdown in the middle of
-			// an anchor process. This is ok. The anchor process will pick
-			// up where it left off.
-			log.Warnf("Digest already exists %v: %v (%v)",
-				tbr.Digests[i], dcrtime.Result[v], v)
-		default:
-			// Something went wrong; exit
-			log.Errorf("Digest failed %v: %v (%v)",
-				tbr.Digests[i], dcrtime.Result[v], v)
-			failed = true
+	submitted := make([]anchors.Provider, 0, len(t.providers))
+	for _, v := range t.providers {
+		err := v.Submit(digests)
+		if err != nil {
+			log.Errorf("%v Submit: %v", v.ID(), err)
+			t.anchorFailureAdd(v.ID(), 0, fmt.Errorf("submit: %v", err))
+			continue
 		}
+		submitted = append(submitted, v)
 	}
-	if failed {
-		return fmt.Errorf("dcrtime failed to timestamp digests")
+	if len(submitted) == 0 {
+		return fmt.Errorf("all anchor providers failed to anchor digests")
 	}
 
-	// Launch go routine that polls dcrtime for the anchor tx
-	go t.anchorWait(anchors, digests)
+	// Launch go routine that polls the anchor providers
+	waiting = true
+	go t.anchorWait(pending, digests, submitted)
 
 	return nil
 }
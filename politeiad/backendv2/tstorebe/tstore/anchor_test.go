@@ -0,0 +1,257 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/anchors"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+)
+
+// testProvider is an anchor provider that is used for testing. Only the
+// digests in the anchor set are anchored.
+type testProvider struct {
+	id      string
+	anchor  map[string]bool
+	timeout time.Duration
+	err     error // Returned by Verify
+}
+
+func (p *testProvider) ID() string {
+	return p.id
+}
+
+func (p *testProvider) Submit(digests []string) error {
+	return nil
+}
+
+func (p *testProvider) Verify(digests []string) (map[string]backend.TimestampAnchor, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	r := make(map[string]backend.TimestampAnchor, len(digests))
+	for _, v := range digests {
+		if !p.anchor[v] {
+			continue
+		}
+		r[v] = backend.TimestampAnchor{
+			Provider:   p.id,
+			TxID:       p.id + v,
+			MerkleRoot: v,
+		}
+	}
+	return r, nil
+}
+
+func (p *testProvider) Wait() (time.Duration, time.Duration) {
+	return time.Millisecond, p.timeout
+}
+
+func TestAnchorWait(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.anchor.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	// Setup three trees. The first tree is anchored by both providers,
+	// the second tree is only anchored by the second provider, and the
+	// third tree is not anchored at all.
+	var (
+		pending = make([]anchor, 0, 3)
+		digests = make([]string, 0, 3)
+	)
+	for i := 0; i < 3; i++ {
+		tree, _, err := ts.tlog.TreeNew()
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := sha256.Sum256([]byte{byte(i)})
+		pending = append(pending, anchor{
+			TreeID: tree.TreeId,
+			LogRoot: &types.LogRootV1{
+				TreeSize: 1,
+				RootHash: d[:],
+			},
+		})
+		digests = append(digests, hex.EncodeToString(d[:]))
+	}
+	p1 := &testProvider{
+		id:      "p1",
+		anchor:  map[string]bool{digests[0]: true},
+		timeout: 10 * time.Millisecond,
+	}
+	p2 := &testProvider{
+		id:      "p2",
+		anchor:  map[string]bool{digests[0]: true, digests[1]: true},
+		timeout: 10 * time.Millisecond,
+	}
+
+	ts.droppingAnchorSet(true)
+	ts.anchorWait(pending, digests, []anchors.Provider{p1, p2})
+	if ts.droppingAnchorGet() {
+		t.Fatalf("dropping anchor was not cleared")
+	}
+
+	want := [][]string{
+		{"p1", "p2"},
+		{"p2"},
+		nil,
+	}
+	for i, v := range pending {
+		a, err := ts.anchorLatest(v.TreeID)
+		if want[i] == nil {
+			if err != errAnchorNotFound {
+				t.Fatalf("tree %v: got err %v, want %v",
+					i, err, errAnchorNotFound)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		tas, err := a.timestampAnchors()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tas) != len(want[i]) {
+			t.Fatalf("tree %v: got %v anchors, want %v",
+				i, len(tas), len(want[i]))
+		}
+		for j, ta := range tas {
+			if ta.Provider != want[i][j] || ta.MerkleRoot != digests[i] {
+				t.Fatalf("tree %v: unexpected anchor %+v", i, ta)
+			}
+		}
+	}
+}
+
+func TestAnchorStatus(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.anchorstatus.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	// Anchoring is disabled when there are no providers
+	err = ts.AnchorDrop()
+	if !errors.Is(err, backend.ErrAnchoringDisabled) {
+		t.Fatalf("got err %v, want %v", err, backend.ErrAnchoringDisabled)
+	}
+
+	// Setup two trees with two leaves each
+	var (
+		pending = make([]anchor, 0, 2)
+		digests = make([]string, 0, 2)
+	)
+	for i := 0; i < 2; i++ {
+		tree, _, err := ts.tlog.TreeNew()
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves := make([]*trillian.LogLeaf, 0, 2)
+		for j := 0; j < 2; j++ {
+			ed, err := extraDataEncode(storeKeyNew(false),
+				dataDescriptorFile, backend.StateVetted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaves = append(leaves, tlog.NewLogLeaf([]byte{byte(i), byte(j)}, ed))
+		}
+		_, _, err = ts.tlog.LeavesAppend(tree.TreeId, leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d := sha256.Sum256([]byte{byte(i)})
+		pending = append(pending, anchor{
+			TreeID: tree.TreeId,
+			LogRoot: &types.LogRootV1{
+				TreeSize:       2,
+				RootHash:       d[:],
+				TimestampNanos: uint64(time.Now().UnixNano()),
+			},
+		})
+		digests = append(digests, hex.EncodeToString(d[:]))
+	}
+
+	s, err := ts.AnchorStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Records != 2 || len(s.Unanchored) != 2 || s.Leaves != 4 ||
+		s.LastAnchor != 0 {
+		t.Fatalf("unexpected status %+v", s)
+	}
+
+	// An anchor drop is rejected while an anchor is being dropped
+	p1 := &testProvider{
+		id:      "p1",
+		anchor:  map[string]bool{digests[0]: true},
+		timeout: 10 * time.Millisecond,
+	}
+	p2 := &testProvider{
+		id:      "p2",
+		timeout: 10 * time.Millisecond,
+		err:     errors.New("unavailable"),
+	}
+	ts.providers = []anchors.Provider{p1, p2}
+	ts.droppingAnchorSet(true)
+	err = ts.AnchorDrop()
+	if !errors.Is(err, backend.ErrAnchorInProgress) {
+		t.Fatalf("got err %v, want %v", err, backend.ErrAnchorInProgress)
+	}
+
+	// Drop the anchor. The first tree is anchored by the first provider.
+	// The second provider fails to verify the digests.
+	ts.anchorWait(pending, digests, ts.providers)
+
+	s, err = ts.AnchorStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch {
+	case s.Dropping || s.InFlight != nil:
+		t.Fatalf("anchor is still in flight")
+	case s.Records != 2 || len(s.Unanchored) != 1 || s.Leaves != 2:
+		t.Fatalf("unexpected status %+v", s)
+	case s.LastAnchor == 0:
+		t.Fatalf("last anchor not found")
+	}
+	token := hex.EncodeToString(tokenFromTreeID(pending[1].TreeID))
+	if s.Unanchored[0].Token != token || s.Unanchored[0].Leaves != 2 {
+		t.Fatalf("unexpected unanchored tree %+v", s.Unanchored[0])
+	}
+
+	// The most recent failure is the tree that was not anchored. The
+	// failures of the providers precede it.
+	if len(s.Failures) < 3 {
+		t.Fatalf("got %v failures, want at least 3", len(s.Failures))
+	}
+	if s.Failures[0].Token != token {
+		t.Fatalf("unexpected failure %+v", s.Failures[0])
+	}
+	var verifyFailed bool
+	for _, v := range s.Failures[1:] {
+		if v.Provider == "p2" && v.Error == "verify: unavailable" {
+			verifyFailed = true
+		}
+	}
+	if !verifyFailed {
+		t.Fatalf("verify failure not found")
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+const (
+	// anchorFailuresMax is the maximum number of recent anchor failures
+	// that are kept in memory.
+	anchorFailuresMax = 50
+)
+
+// anchorState contains the anchoring state that is not persisted. It is lost
+// on restart.
+type anchorState struct {
+	inFlight *backend.AnchorInFlight
+
+	// failures contains the most recent anchor failures, oldest first.
+	failures []backend.AnchorFailure
+}
+
+// anchorInFlightSet sets the in-flight anchor. A nil value clears it.
+func (t *Tstore) anchorInFlightSet(f *backend.AnchorInFlight) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.anchorState.inFlight = f
+}
+
+// anchorPendingSet updates the digests that the provided anchor provider has
+// not anchored yet for the in-flight anchor.
+func (t *Tstore) anchorPendingSet(providerID string, digests []string) {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.anchorState.inFlight == nil {
+		return
+	}
+	t.anchorState.inFlight.Pending[providerID] = digests
+}
+
+// anchorFailureAdd records an anchor failure. The provider ID and the tree ID
+// are optional. Only the most recent failures are kept.
+func (t *Tstore) anchorFailureAdd(providerID string, treeID int64, err error) {
+	f := backend.AnchorFailure{
+		Provider:  providerID,
+		Error:     err.Error(),
+		Timestamp: time.Now().Unix(),
+	}
+	if treeID != 0 {
+		f.Token = hex.EncodeToString(tokenFromTreeID(treeID))
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	t.anchorState.failures = append(t.anchorState.failures, f)
+	if len(t.anchorState.failures) > anchorFailuresMax {
+		t.anchorState.failures = t.anchorState.failures[1:]
+	}
+}
+
+// anchorStateGet returns a copy of the anchoring state along with the dropping
+// anchor boolean. The failures are returned most recent first.
+func (t *Tstore) anchorStateGet() (bool, *backend.AnchorInFlight, []backend.AnchorFailure) {
+	t.RLock()
+	defer t.RUnlock()
+
+	var inFlight *backend.AnchorInFlight
+	if f := t.anchorState.inFlight; f != nil {
+		inFlight = &backend.AnchorInFlight{
+			Started: f.Started,
+			Tokens:  f.Tokens,
+			Pending: make(map[string][]string, len(f.Pending)),
+		}
+		for k, v := range f.Pending {
+			inFlight.Pending[k] = v
+		}
+	}
+	failures := make([]backend.AnchorFailure, 0, len(t.anchorState.failures))
+	for i := len(t.anchorState.failures) - 1; i >= 0; i-- {
+		failures = append(failures, t.anchorState.failures[i])
+	}
+
+	return t.droppingAnchor, inFlight, failures
+}
+
+// AnchorStatus returns the status of the anchoring of the tlog trees. The
+// lag of each tree with unanchored leaves is determined using the leaves that
+// were appended after the tree's most recent anchor.
+func (t *Tstore) AnchorStatus() (*backend.AnchorStatus, error) {
+	log.Tracef("AnchorStatus")
+
+	trees, err := t.tlog.TreesAll()
+	if err != nil {
+		return nil, fmt.Errorf("TreesAll: %v", err)
+	}
+
+	dropping, inFlight, failures := t.anchorStateGet()
+	s := backend.AnchorStatus{
+		Providers:  make([]string, 0, len(t.providers)),
+		Dropping:   dropping,
+		InFlight:   inFlight,
+		Unanchored: make([]backend.AnchorTree, 0, len(trees)),
+		Failures:   failures,
+	}
+	for _, v := range t.providers {
+		s.Providers = append(s.Providers, v.ID())
+	}
+	var inFlightTokens map[string]struct{}
+	if inFlight != nil {
+		inFlightTokens = make(map[string]struct{}, len(inFlight.Tokens))
+		for _, v := range inFlight.Tokens {
+			inFlightTokens[v] = struct{}{}
+		}
+	}
+
+	now := time.Now()
+	for _, tree := range trees {
+		leaves, err := t.tlog.LeavesAll(tree.TreeId)
+		if err != nil {
+			return nil, fmt.Errorf("LeavesAll %v: %v", tree.TreeId, err)
+		}
+		if len(leaves) == 0 {
+			// Tree does not have any leaves. Nothing to anchor.
+			continue
+		}
+		s.Records++
+
+		// Get the most recent anchor of the tree
+		var a *anchor
+		key, err := anchorKeyLatest(leaves)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			a, err = t.anchorGet(key)
+			if err != nil {
+				return nil, fmt.Errorf("anchorGet %v: %v", tree.TreeId, err)
+			}
+		}
+		var (
+			anchored   uint64
+			lastAnchor int64
+		)
+		if a != nil {
+			anchored = a.LogRoot.TreeSize
+			lastAnchor = int64(a.LogRoot.TimestampNanos / uint64(time.Second))
+		}
+		if lastAnchor > s.LastAnchor {
+			s.LastAnchor = lastAnchor
+		}
+
+		// Find the leaves that were not included in the anchor. Anchor
+		// leaves are not anchored themselves.
+		var (
+			count  uint64
+			oldest time.Time
+		)
+		for _, v := range leaves {
+			if uint64(v.LeafIndex) < anchored {
+				continue
+			}
+			ed, err := extraDataDecode(v.ExtraData)
+			if err != nil {
+				return nil, err
+			}
+			if ed.Desc == dataDescriptorAnchor {
+				continue
+			}
+			count++
+			if ts := v.GetQueueTimestamp(); ts.IsValid() {
+				qt := ts.AsTime()
+				if oldest.IsZero() || qt.Before(oldest) {
+					oldest = qt
+				}
+			}
+		}
+		if count == 0 {
+			// Tree is fully anchored
+			continue
+		}
+
+		token := hex.EncodeToString(tokenFromTreeID(tree.TreeId))
+		_, ok := inFlightTokens[token]
+		at := backend.AnchorTree{
+			Token:      token,
+			Leaves:     count,
+			LastAnchor: lastAnchor,
+			InFlight:   ok,
+		}
+		if !oldest.IsZero() {
+			at.Lag = int64(now.Sub(oldest) / time.Second)
+		}
+		s.Unanchored = append(s.Unanchored, at)
+		s.Leaves += count
+		if at.Lag > s.Lag {
+			s.Lag = at.Lag
+		}
+	}
+
+	// Order the trees by lag, largest first
+	sort.SliceStable(s.Unanchored, func(i, j int) bool {
+		a, b := s.Unanchored[i], s.Unanchored[j]
+		if a.Lag != b.Lag {
+			return a.Lag > b.Lag
+		}
+		return a.Leaves > b.Leaves
+	})
+
+	return &s, nil
+}
+
+// AnchorDrop drops an anchor for all trees that have unanchored leaves
+// without waiting for the anchor schedule. The digests are submitted to the
+// anchor providers before this function returns. The providers are waited on
+// in the background.
+func (t *Tstore) AnchorDrop() error {
+	log.Tracef("AnchorDrop")
+
+	if len(t.providers) == 0 {
+		return backend.ErrAnchoringDisabled
+	}
+
+	log.Infof("Anchor drop requested")
+
+	err := t.anchorTrees()
+	if err != nil && err != backend.ErrAnchorInProgress {
+		t.anchorFailureAdd("", 0, err)
+	}
+	return err
+}
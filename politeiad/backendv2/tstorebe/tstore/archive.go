@@ -0,0 +1,479 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/trillian"
+)
+
+const (
+	// archiveManifestKey is the key-value store key for the manifest of
+	// an archived record tree. The "{token}" is replaced with the hex
+	// encoded full length record token.
+	archiveManifestKey = "archive-{token}"
+
+	// archiveBatchSize is the maximum number of blobs that are moved to
+	// the cold store at once.
+	archiveBatchSize = 200
+)
+
+// archiveManifest is left behind in the key-value store when the blobs of a
+// frozen record tree are moved to the cold store. It contains the key and the
+// SHA256 digest of every blob that was moved. The digests are verified when
+// the blobs are retrieved from the cold store.
+//
+// Complete is set once the blobs have been deleted from the key-value store.
+// A manifest that is not complete is finished during the next archival run.
+type archiveManifest struct {
+	Token    string            `json:"token"`
+	Archived int64             `json:"archived"` // Unix timestamp
+	Complete bool              `json:"complete"`
+	Blobs    map[string]string `json:"blobs"` // [key]digest
+}
+
+// buildArchiveManifestKey returns the key-value store key for the archive
+// manifest of a record.
+func buildArchiveManifestKey(token []byte) string {
+	return strings.Replace(archiveManifestKey, "{token}",
+		hex.EncodeToString(token), 1)
+}
+
+// coldKey returns the cold store key for a blob of a record. The blobs of a
+// record are grouped by the record token.
+func coldKey(token []byte, key string) string {
+	return hex.EncodeToString(token) + "/" + key
+}
+
+// blobDigest returns the hex encoded SHA256 digest of a blob.
+func blobDigest(b []byte) string {
+	d := sha256.Sum256(b)
+	return hex.EncodeToString(d[:])
+}
+
+// archiveManifestGet returns the archive manifest of a record. Nil is returned
+// if the record has not been archived.
+func (t *Tstore) archiveManifestGet(token []byte) (*archiveManifest, error) {
+	key := buildArchiveManifestKey(token)
+	blobs, err := t.store.Get([]string{key})
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	b, ok := blobs[key]
+	if !ok {
+		return nil, nil
+	}
+	var m archiveManifest
+	err = json.Unmarshal(b, &m)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// archiveManifestSave saves the archive manifest of a record to the key-value
+// store, overwriting any existing entry.
+func (t *Tstore) archiveManifestSave(token []byte, m archiveManifest) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	key := buildArchiveManifestKey(token)
+	err = t.store.Put(map[string][]byte{key: b}, false)
+	if err != nil {
+		return fmt.Errorf("store Put: %v", err)
+	}
+	return nil
+}
+
+// blobsGet returns the blobs for the provided key-value store keys of a
+// record tree. If the tree has been archived, the blobs that are not found in
+// the key-value store are retrieved from the cold store and their digests are
+// verified against the archive manifest. An entry will not exist in the
+// returned map for any blobs that are not found.
+func (t *Tstore) blobsGet(treeID int64, keys []string) (map[string][]byte, error) {
+	blobs, err := t.blobsGetHot(keys)
+	if err != nil {
+		return nil, err
+	}
+	if len(blobs) == len(keys) || t.cold == nil {
+		return blobs, nil
+	}
+
+	// Check if the missing blobs have been archived
+	token := tokenFromTreeID(treeID)
+	m, err := t.archiveManifestGet(token)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		// Tree has not been archived
+		return blobs, nil
+	}
+	archived := make([]string, 0, len(keys)-len(blobs))
+	for _, v := range keys {
+		if _, ok := blobs[v]; ok {
+			continue
+		}
+		if _, ok := m.Blobs[v]; ok {
+			archived = append(archived, coldKey(token, v))
+		}
+	}
+	if len(archived) == 0 {
+		return blobs, nil
+	}
+
+	// Retrieve the blobs from the cold store and verify them
+	log.Debugf("Retrieving %v archived blobs of %x", len(archived), token)
+
+	cold, err := t.cold.Get(archived)
+	if err != nil {
+		return nil, fmt.Errorf("cold store Get: %v", err)
+	}
+	for _, v := range keys {
+		digest, ok := m.Blobs[v]
+		if !ok {
+			continue
+		}
+		if _, ok := blobs[v]; ok {
+			continue
+		}
+		b, ok := cold[coldKey(token, v)]
+		if !ok {
+			return nil, fmt.Errorf("archived blob not found: %v", v)
+		}
+		if blobDigest(b) != digest {
+			return nil, fmt.Errorf("archived blob %v digest mismatch: "+
+				"got %v, want %v", v, blobDigest(b), digest)
+		}
+		blobs[v] = b
+	}
+
+	return blobs, nil
+}
+
+// blobsDel deletes the blobs for the provided key-value store keys of a record
+// tree. Content addressed blobs that are still referenced by other records are
+// not deleted. Blobs that have been archived are deleted from the cold store
+// and are removed from the archive manifest.
+func (t *Tstore) blobsDel(treeID int64, keys []string) error {
+	err := t.storeBlobsDel(treeID, keys)
+	if err != nil {
+		return err
+	}
+	if t.cold == nil {
+		return nil
+	}
+	if t.txGet(treeID) == nil {
+		return t.coldBlobsDel(treeID, keys)
+	}
+
+	// The deletion of the archived blobs can not be undone so it is
+	// deferred until the transaction on the tree has been committed.
+	t.txCommitted(treeID, func() {
+		err := t.coldBlobsDel(treeID, keys)
+		if err != nil {
+			log.Errorf("coldBlobsDel %v: %v", treeID, err)
+		}
+	})
+
+	return nil
+}
+
+// storeBlobsDel deletes the blobs for the provided key-value store keys of a
+// record tree from the key-value store and updates the reference entries of
+// the content addressed blobs.
+func (t *Tstore) storeBlobsDel(treeID int64, keys []string) error {
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	// The blobs are deleted before the reference entries are updated
+	// so that the deletion can be retried if it is interrupted.
+	del, refs, err := t.blobsRelease(treeID, keys)
+	if err != nil {
+		return err
+	}
+	err = t.kv(treeID).Del(del)
+	if err != nil {
+		return fmt.Errorf("store Del: %v", err)
+	}
+	return t.blobRefsSave(refs)
+}
+
+// coldBlobsDel deletes the archived blobs for the provided key-value store
+// keys of a record tree from the cold store and removes them from the archive
+// manifest.
+func (t *Tstore) coldBlobsDel(treeID int64, keys []string) error {
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	token := tokenFromTreeID(treeID)
+	m, err := t.archiveManifestGet(token)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		// Tree has not been archived
+		return nil
+	}
+	archived := make([]string, 0, len(keys))
+	for _, v := range keys {
+		if _, ok := m.Blobs[v]; ok {
+			archived = append(archived, coldKey(token, v))
+			delete(m.Blobs, v)
+		}
+	}
+	if len(archived) == 0 {
+		return nil
+	}
+
+	// The manifest is updated first so that a deleted blob is never
+	// looked up in the cold store.
+	err = t.archiveManifestSave(token, *m)
+	if err != nil {
+		return err
+	}
+	err = t.cold.Del(archived)
+	if err != nil {
+		return fmt.Errorf("cold store Del: %v", err)
+	}
+
+	log.Debugf("Deleted %v archived blobs of %x", len(archived), token)
+
+	return nil
+}
+
+// archiveTrees moves the blobs of all frozen trees that have not been archived
+// yet to the cold store. Archival runs that were interrupted are finished.
+func (t *Tstore) archiveTrees() error {
+	if t.cold == nil {
+		// Archival is disabled
+		return nil
+	}
+
+	trees, err := t.tlog.TreesAll()
+	if err != nil {
+		return fmt.Errorf("TreesAll: %v", err)
+	}
+
+	log.Infof("Starting tree archival process; checking %v trees", len(trees))
+
+	var archived int
+	for _, v := range trees {
+		if v.TreeState != trillian.TreeState_FROZEN {
+			continue
+		}
+		token := tokenFromTreeID(v.TreeId)
+		m, err := t.archiveManifestGet(token)
+		if err != nil {
+			return err
+		}
+		if m != nil && m.Complete {
+			// Tree has already been archived
+			continue
+		}
+		n, err := t.archiveTree(v.TreeId, m)
+		if err != nil {
+			log.Errorf("archiveTree %v: %v", v.TreeId, err)
+			continue
+		}
+
+		log.Infof("Tree archived %v %x; %v blobs moved to the cold store",
+			v.TreeId, token, n)
+
+		archived++
+	}
+
+	log.Infof("%v trees were archived", archived)
+
+	return nil
+}
+
+// archiveTree moves the blobs of a frozen tree to the cold store and leaves
+// an archive manifest behind in the key-value store. The provided manifest is
+// the manifest of a previous archival run that was interrupted and is nil if
+// the tree has not been archived yet. The number of blobs that were moved is
+// returned.
+//
+// The record indexes and the anchors remain in the key-value store since they
+// are needed to build the record inventory and to drop anchors. Encrypted
+// blobs also remain in the key-value store so that they are never saved to
+// the cold store in clear text. Content addressed blobs that are shared with
+// other records remain in the key-value store as well.
+func (t *Tstore) archiveTree(treeID int64, m *archiveManifest) (int, error) {
+	token := tokenFromTreeID(treeID)
+	if m == nil {
+		leaves, err := t.leavesAll(treeID)
+		if err != nil {
+			return 0, err
+		}
+		keys := make([]string, 0, len(leaves))
+		for _, v := range leaves {
+			ed, err := extraDataDecode(v.ExtraData)
+			if err != nil {
+				return 0, err
+			}
+			switch ed.Desc {
+			case dataDescriptorAnchor, dataDescriptorRecordIndex:
+				continue
+			}
+			keys = append(keys, ed.storeKeyNoPrefix())
+		}
+
+		// Copy the blobs to the cold store. The copies are read back
+		// and verified before the manifest is saved.
+		m = &archiveManifest{
+			Token:    hex.EncodeToString(token),
+			Archived: time.Now().Unix(),
+			Blobs:    make(map[string]string, len(keys)),
+		}
+		for len(keys) > 0 {
+			batch := keys
+			if len(batch) > archiveBatchSize {
+				batch = keys[:archiveBatchSize]
+			}
+			keys = keys[len(batch):]
+
+			hot, err := t.blobsUnshared(treeID, batch)
+			if err != nil {
+				return 0, err
+			}
+			blobs, err := t.blobsGetHot(hot)
+			if err != nil {
+				return 0, err
+			}
+			if len(blobs) == 0 {
+				// The blobs of censored content do not exist
+				continue
+			}
+			cold := make(map[string][]byte, len(blobs))
+			coldKeys := make([]string, 0, len(blobs))
+			for k, v := range blobs {
+				cold[coldKey(token, k)] = v
+				coldKeys = append(coldKeys, coldKey(token, k))
+			}
+			err = t.cold.Put(cold)
+			if err != nil {
+				return 0, fmt.Errorf("cold store Put: %v", err)
+			}
+			copies, err := t.cold.Get(coldKeys)
+			if err != nil {
+				return 0, fmt.Errorf("cold store Get: %v", err)
+			}
+			for k, v := range blobs {
+				c, ok := copies[coldKey(token, k)]
+				if !ok || blobDigest(c) != blobDigest(v) {
+					return 0, fmt.Errorf("cold store copy of %v is invalid", k)
+				}
+				m.Blobs[k] = blobDigest(v)
+			}
+		}
+		err = t.archiveManifestSave(token, *m)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	// Delete the blobs from the key-value store and mark the manifest
+	// as complete. A content addressed blob may have been saved by
+	// another record since it was copied to the cold store, so the
+	// references are checked again while the blobs mutex is held.
+	keys := make([]string, 0, len(m.Blobs))
+	for k := range m.Blobs {
+		keys = append(keys, k)
+	}
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	links, err := t.blobLinksGet(keys)
+	if err != nil {
+		return 0, err
+	}
+	del := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if link, ok := links[k]; ok {
+			// The link entry remains so that the reference to the
+			// blob can be released if the blob is deleted.
+			k = link
+		}
+		del = append(del, k)
+	}
+	del, err = t.blobsUnsharedLocked(treeID, del)
+	if err != nil {
+		return 0, err
+	}
+	err = t.store.Del(del)
+	if err != nil {
+		return 0, fmt.Errorf("store Del: %v", err)
+	}
+	m.Complete = true
+	err = t.archiveManifestSave(token, *m)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(keys), nil
+}
+
+// blobsUnshared returns the provided keys of a record tree without the keys of
+// the content addressed blobs that are also referenced by other records.
+func (t *Tstore) blobsUnshared(treeID int64, keys []string) ([]string, error) {
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	links, err := t.blobLinksGet(keys)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if link, ok := links[k]; ok {
+			k = link
+		}
+		resolved = append(resolved, k)
+	}
+	unshared, err := t.blobsUnsharedLocked(treeID, resolved)
+	if err != nil {
+		return nil, err
+	}
+	isUnshared := make(map[string]struct{}, len(unshared))
+	for _, v := range unshared {
+		isUnshared[v] = struct{}{}
+	}
+	r := make([]string, 0, len(keys))
+	for i, k := range keys {
+		if _, ok := isUnshared[resolved[i]]; ok {
+			r = append(r, k)
+		}
+	}
+	return r, nil
+}
+
+// blobsUnsharedLocked returns the provided key-value store keys without the
+// keys of the content addressed blobs that are referenced by records other
+// than the provided record tree.
+//
+// The blobs mutex must be held by the caller.
+func (t *Tstore) blobsUnsharedLocked(treeID int64, keys []string) ([]string, error) {
+	refs, err := t.blobRefsGet(keys)
+	if err != nil {
+		return nil, err
+	}
+	token := hex.EncodeToString(tokenFromTreeID(treeID))
+	r := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if len(refsRemove(refs[k], token)) > 0 {
+			continue
+		}
+		r = append(r, k)
+	}
+	return r, nil
+}
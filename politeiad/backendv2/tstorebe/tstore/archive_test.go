@@ -0,0 +1,189 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/coldstore"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/google/trillian"
+)
+
+func TestArchiveTrees(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.archive.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+	coldDir := filepath.Join(dir, "cold")
+	ts.cold, err = coldstore.NewFS(coldDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup a tree with three file blobs, a record index blob, an
+	// encrypted blob and a content addressed blob that is shared with
+	// another tree.
+	tree, _, err := ts.tlog.TreeNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeID := tree.TreeId
+	other, _, err := ts.tlog.TreeNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	be, shared := newTestBlob(t, "shared")
+	sharedKey := storeKeyContent(be, false)
+	type entry struct {
+		key  string
+		desc string
+		blob []byte
+	}
+	entries := []entry{
+		{storeKeyNew(false), dataDescriptorFile, []byte("file0")},
+		{storeKeyNew(false), dataDescriptorFile, []byte("file1")},
+		{storeKeyNew(false), dataDescriptorFile, []byte("file2")},
+		{storeKeyNew(false), dataDescriptorRecordIndex, []byte("index")},
+		{storeKeyNew(true), dataDescriptorFile, []byte("secret")},
+		{sharedKey, dataDescriptorFile, shared},
+	}
+	var (
+		leaves = make([]*trillian.LogLeaf, 0, len(entries))
+		blobs  = make(map[string][]byte, len(entries))
+		keys   = make([]string, 0, len(entries))
+	)
+	for _, v := range entries {
+		state := backend.StateVetted
+		if storeKeyCleaned(v.key) != v.key {
+			state = backend.StateUnvetted
+		}
+		ed, err := extraDataEncode(v.key, v.desc, state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, tlog.NewLogLeaf(v.blob, ed))
+		blobs[v.key] = v.blob
+		keys = append(keys, v.key)
+	}
+	_, _, err = ts.tlog.LeavesAppend(treeID, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{treeID, other.TreeId} {
+		err = ts.blobsPut(id, map[string][]byte{sharedKey: shared}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ts.store.Put(blobs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Trees that are not frozen are not archived
+	err = ts.archiveTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ts.archiveManifestGet(tokenFromTreeID(treeID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m != nil {
+		t.Fatalf("tree that is not frozen was archived")
+	}
+
+	// Freeze and archive the tree
+	_, err = ts.tlog.TreeFreeze(treeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ts.archiveTrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = ts.archiveManifestGet(tokenFromTreeID(treeID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m == nil || !m.Complete || len(m.Blobs) != 3 {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+
+	// Only the file blobs that are not shared were moved to the cold
+	// store.
+	hot, err := ts.store.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hot) != 3 {
+		t.Fatalf("got %v hot blobs, want 3", len(hot))
+	}
+	for _, v := range keys[3:] {
+		if _, ok := hot[v]; !ok {
+			t.Fatalf("blob %v was moved to the cold store", v)
+		}
+	}
+
+	// The archived blobs are retrieved transparently
+	got, err := ts.blobsGet(treeID, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range blobs {
+		if !bytes.Equal(got[k], v) {
+			t.Fatalf("blob %v: got %s, want %s", k, got[k], v)
+		}
+	}
+
+	// A tampered cold store blob is rejected
+	token := tokenFromTreeID(treeID)
+	err = ts.cold.Put(map[string][]byte{
+		coldKey(token, keys[0]): []byte("tampered"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.blobsGet(treeID, keys[:1])
+	if err == nil {
+		t.Fatalf("tampered blob was not rejected")
+	}
+
+	// Deleting an archived blob removes it from the manifest and the
+	// cold store.
+	err = ts.blobsDel(treeID, keys[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err = ts.archiveManifestGet(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Blobs[keys[0]]; ok || len(m.Blobs) != 2 {
+		t.Fatalf("deleted blob is still in the manifest %+v", m)
+	}
+	cold, err := ts.cold.Get([]string{coldKey(token, keys[0])})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cold) != 0 {
+		t.Fatalf("deleted blob is still in the cold store")
+	}
+	got, err = ts.blobsGet(treeID, keys[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := got[keys[0]]; ok || len(got) != 1 {
+		t.Fatalf("unexpected blobs %v", got)
+	}
+}
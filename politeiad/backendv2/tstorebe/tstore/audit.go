@@ -0,0 +1,89 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/hex"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+const (
+	// dataDescriptorAudit is the data descriptor of an audit log leaf.
+	dataDescriptorAudit = "pd-audit-v1"
+
+	// auditTreeKey is the key-value store key for the ID of the tlog
+	// tree that the audit log entries are appended onto.
+	auditTreeKey = "tstore-audittree"
+)
+
+// AuditAppend saves a batch of audit log entries to the key-value store and
+// appends a leaf onto the audit tree for each of them. The audit tree is
+// anchored along with the record trees. The entries are saved as clear text.
+func (t *Tstore) AuditAppend(entries [][]byte) error {
+	log.Tracef("AuditAppend: %v", len(entries))
+
+	return t.serviceAppend(auditTreeKey, dataDescriptorAudit, entries...)
+}
+
+// AuditEntries returns all audit log entries of the audit tree, ordered by
+// leaf index. The entries are read from the audit tree so that only entries
+// that are part of the tree are returned.
+func (t *Tstore) AuditEntries() ([][]byte, error) {
+	log.Tracef("AuditEntries")
+
+	treeID, err := t.serviceTreeGet(auditTreeKey)
+	if err != nil {
+		return nil, err
+	}
+	if treeID == 0 {
+		return [][]byte{}, nil
+	}
+	leaves, _, err := t.serviceLeaves(treeID, dataDescriptorAudit)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.serviceData(leaves)
+}
+
+// AuditTimestamps returns the timestamps of the audit log entries with the
+// provided digests. The digest of an entry is the SHA256 digest of its data.
+// The returned map does not contain an entry for the digests that are not
+// part of the audit tree.
+func (t *Tstore) AuditTimestamps(digests []string) (map[string]*backend.Timestamp, error) {
+	log.Tracef("AuditTimestamps: %v", digests)
+
+	ts := make(map[string]*backend.Timestamp, len(digests))
+	treeID, err := t.serviceTreeGet(auditTreeKey)
+	if err != nil {
+		return nil, err
+	}
+	if treeID == 0 {
+		return ts, nil
+	}
+
+	entries, leaves, err := t.serviceLeaves(treeID, dataDescriptorAudit)
+	if err != nil {
+		return nil, err
+	}
+	want := make(map[string]struct{}, len(digests))
+	for _, v := range digests {
+		want[v] = struct{}{}
+	}
+	for _, v := range entries {
+		d := hex.EncodeToString(v.LeafValue)
+		if _, ok := want[d]; !ok {
+			continue
+		}
+		timestamp, err := t.timestamp(treeID, v.MerkleLeafHash, leaves)
+		if err != nil {
+			return nil, err
+		}
+		ts[d] = timestamp
+	}
+
+	return ts, nil
+}
@@ -0,0 +1,784 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/decred/politeia/util"
+	"github.com/google/trillian"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// A tstore backup archive is an encrypted, gzipped stream of JSON encoded
+// backupEntry structures. See backupWriter for the encryption. The archive
+// contains the following entries, in order:
+//
+//  1. A header entry that contains the archive version.
+//
+//  2. A tree entry for each tlog tree that contains all of the tree leaves,
+//     followed by a blob entry for each key-value store blob that is
+//     referenced by the tree leaves.
+//
+//  3. A blob entry for each remaining key-value store blob. These are the
+//     blobs that are not referenced by a tree leaf, i.e. the backend record
+//     inventory, the plugin caches, and the record censorship lists.
+//
+//  4. A file entry for each file in the data directory that is not managed
+//     by the tlog or the key-value store, i.e. any legacy backend inventory
+//     files that have not been migrated yet and the plugin data directories.
+//
+//  5. A footer entry that contains the number of items in the archive. This
+//     allows a truncated archive to be detected during a restore.
+//
+// Blobs are written to the archive entries in clear text, including the blobs
+// of unvetted records, so that the archive can be restored into a key-value
+// store that uses a different encryption key. The archive as a whole is
+// encrypted using a key that is provided by the operator.
+const (
+	// backupVersion is the version of the backup archive format.
+	backupVersion uint32 = 2
+
+	// Backup entry types
+	backupTypeHeader = "header"
+	backupTypeTree   = "tree"
+	backupTypeBlob   = "blob"
+	backupTypeFile   = "file"
+	backupTypeFooter = "footer"
+
+	// backupBatchSize is the maximum number of blobs that are retrieved
+	// from or saved to the key-value store in a single call during a
+	// backup or a restore.
+	backupBatchSize = 500
+
+	// leavesAppendBatchSize is the maximum number of leaves that are
+	// appended onto a tree in a single call during a restore.
+	leavesAppendBatchSize = 1000
+)
+
+// errBackupUnsupported is returned when the tlog of the tstore does not
+// support importing trees. Trillian assigns the tree IDs of new trees itself.
+var errBackupUnsupported = errors.New("the tlog does not support " +
+	"importing trees; only a tstore that uses the embedded tlog can be " +
+	"backed up and restored")
+
+// backupEntry is an entry in a backup archive. Only the field that
+// corresponds to the entry type is populated.
+type backupEntry struct {
+	Type   string         `json:"type"`
+	Header *backupHeader  `json:"header,omitempty"`
+	Tree   *backupTree    `json:"tree,omitempty"`
+	Blob   *backupBlob    `json:"blob,omitempty"`
+	File   *backupFile    `json:"file,omitempty"`
+	Footer *BackupSummary `json:"footer,omitempty"`
+}
+
+// backupHeader is the first entry of a backup archive.
+type backupHeader struct {
+	Version   uint32 `json:"version"`
+	Network   string `json:"network"`
+	Timestamp int64  `json:"timestamp"` // Unix time
+}
+
+// backupTree contains a tlog tree and all of its leaves. The tree size and
+// root hash are taken from the signed log root of the tree at the time of
+// the backup.
+type backupTree struct {
+	TreeID   int64        `json:"treeid"`
+	Frozen   bool         `json:"frozen"`
+	TreeSize uint64       `json:"treesize"`
+	RootHash []byte       `json:"roothash"`
+	Leaves   []backupLeaf `json:"leaves"`
+}
+
+// backupLeaf contains a tlog leaf.
+type backupLeaf struct {
+	LeafValue      []byte `json:"leafvalue"`
+	ExtraData      []byte `json:"extradata"`
+	MerkleLeafHash []byte `json:"merkleleafhash"`
+}
+
+// backupBlob contains a key-value store entry. The value is the clear text
+// value that is returned by the key-value store.
+type backupBlob struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// backupFile contains a file from the data directory. The path is slash
+// separated and relative to the data directory.
+type backupFile struct {
+	Path string      `json:"path"`
+	Mode fs.FileMode `json:"mode"`
+	Data []byte      `json:"data"`
+}
+
+// BackupSummary contains the number of items in a backup archive.
+type BackupSummary struct {
+	Trees  int `json:"trees"`
+	Leaves int `json:"leaves"`
+	Blobs  int `json:"blobs"`
+	Files  int `json:"files"`
+}
+
+// Backup writes a backup archive of the full tstore to the provided writer.
+// The archive contains all tlog trees and leaves, all key-value store blobs,
+// and the files in the data directory that are not managed by the tlog or the
+// key-value store. The signed log root of every tree is verified against the
+// tree leaves before the tree is written to the archive. The archive is
+// encrypted using the provided key.
+//
+// Only a tstore whose tlog implements the tlog TreeImporter interface, i.e.
+// the embedded tlog, can be backed up. An archive can only be restored into
+// such a tlog since the tree IDs, and therefore the record tokens, must be
+// preserved, and a tstore must be able to be restored from its own backups.
+//
+// Backup must not be run while the tstore is being written to. It is intended
+// to be run by a standalone tool while politeiad is stopped. The anchor cron
+// job of this tstore instance is stopped.
+func (t *Tstore) Backup(w io.Writer, key *[32]byte) (*BackupSummary, error) {
+	log.Tracef("Backup")
+
+	if _, ok := t.tlog.(tlog.TreeImporter); !ok {
+		return nil, errBackupUnsupported
+	}
+
+	// Anchoring a tree appends a new leaf onto it
+	t.cron.Stop()
+
+	bw, err := newBackupWriter(w, key)
+	if err != nil {
+		return nil, err
+	}
+	zw := gzip.NewWriter(bw)
+	enc := json.NewEncoder(zw)
+
+	err = enc.Encode(backupEntry{
+		Type: backupTypeHeader,
+		Header: &backupHeader{
+			Version:   backupVersion,
+			Network:   t.activeNetParams.Name,
+			Timestamp: time.Now().Unix(),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Backup the trees and the blobs that they reference
+	trees, err := t.tlog.TreesAll()
+	if err != nil {
+		return nil, fmt.Errorf("TreesAll: %v", err)
+	}
+	sort.Slice(trees, func(i, j int) bool {
+		return trees[i].TreeId < trees[j].TreeId
+	})
+	var (
+		summary BackupSummary
+		saved   = make(map[string]struct{}, len(trees)*64) // [key]struct{}
+	)
+	for _, tree := range trees {
+		bt, err := t.backupTree(tree)
+		if err != nil {
+			return nil, errors.Errorf("tree %v: %v", tree.TreeId, err)
+		}
+		err = enc.Encode(backupEntry{
+			Type: backupTypeTree,
+			Tree: bt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		summary.Trees++
+		summary.Leaves += len(bt.Leaves)
+
+		// A leaf blob may be saved as both an encrypted blob and a
+		// clear text blob. Censored blobs will not exist.
+		keys := make([]string, 0, len(bt.Leaves)*2)
+		for _, v := range bt.Leaves {
+			ed, err := extraDataDecode(v.ExtraData)
+			if err != nil {
+				return nil, errors.Errorf("tree %v: %v", tree.TreeId, err)
+			}
+			keys = append(keys, ed.storeKeyNoPrefix())
+			if ed.storeKey() != ed.storeKeyNoPrefix() {
+				keys = append(keys, ed.storeKey())
+			}
+		}
+		n, err := t.backupBlobs(enc, tree.TreeId, keys, saved)
+		if err != nil {
+			return nil, err
+		}
+		summary.Blobs += n
+
+		log.Debugf("Backed up tree %v: %v leaves, %v blobs",
+			tree.TreeId, len(bt.Leaves), n)
+	}
+
+	// Backup the remaining key-value store blobs
+	keys, err := t.storeKeys()
+	if err != nil {
+		return nil, err
+	}
+	n, err := t.backupBlobs(enc, 0, keys, saved)
+	if err != nil {
+		return nil, err
+	}
+	summary.Blobs += n
+
+	// Backup the data directory files
+	files, err := t.backupFilePaths()
+	if err != nil {
+		return nil, err
+	}
+	for _, fp := range files {
+		fi, err := os.Stat(fp)
+		if err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(fp)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(t.dataDir, fp)
+		if err != nil {
+			return nil, err
+		}
+		err = enc.Encode(backupEntry{
+			Type: backupTypeFile,
+			File: &backupFile{
+				Path: filepath.ToSlash(rel),
+				Mode: fi.Mode().Perm(),
+				Data: b,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		summary.Files++
+	}
+
+	// Write the footer
+	err = enc.Encode(backupEntry{
+		Type:   backupTypeFooter,
+		Footer: &summary,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = bw.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Backup complete: %v trees, %v leaves, %v blobs, %v files",
+		summary.Trees, summary.Leaves, summary.Blobs, summary.Files)
+
+	return &summary, nil
+}
+
+// backupTree returns the backup of a tlog tree. The leaf hashes and the root
+// hash of the tree are verified against the tree leaves.
+func (t *Tstore) backupTree(tree *trillian.Tree) (*backupTree, error) {
+	_, lr, err := t.tlog.SignedLogRoot(tree)
+	if err != nil {
+		return nil, fmt.Errorf("SignedLogRoot: %v", err)
+	}
+	leaves, err := t.tlog.LeavesAll(tree.TreeId)
+	if err != nil {
+		return nil, fmt.Errorf("LeavesAll: %v", err)
+	}
+	if uint64(len(leaves)) != lr.TreeSize {
+		return nil, errors.Errorf("got %v leaves, log root tree size is %v",
+			len(leaves), lr.TreeSize)
+	}
+
+	bt := backupTree{
+		TreeID:   tree.TreeId,
+		Frozen:   tree.TreeState == trillian.TreeState_FROZEN,
+		TreeSize: lr.TreeSize,
+		RootHash: lr.RootHash,
+		Leaves:   make([]backupLeaf, 0, len(leaves)),
+	}
+	for _, v := range leaves {
+		bt.Leaves = append(bt.Leaves, backupLeaf{
+			LeafValue:      v.LeafValue,
+			ExtraData:      v.ExtraData,
+			MerkleLeafHash: v.MerkleLeafHash,
+		})
+	}
+	err = verifyBackupTree(bt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bt, nil
+}
+
+// storeKeys returns the keys of the key-value store blobs that are part of a
+// backup. The embedded tlog signing key belongs to the tstore instance and is
+// neither backed up nor restored.
+func (t *Tstore) storeKeys() ([]string, error) {
+	all, err := t.store.Keys("", "", 0)
+	if err != nil {
+		return nil, fmt.Errorf("store Keys: %v", err)
+	}
+	keys := make([]string, 0, len(all))
+	for _, k := range all {
+		if k == embeddedTlogKeyKey {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// backupBlobs retrieves the blobs for the provided keys from the key-value
+// store and writes them to the archive. The blobs of an archived tree are
+// retrieved from the cold store. The tree ID is zero for blobs that are not
+// referenced by a tree. Keys that are in the saved map have already been
+// written to the archive and are skipped. Keys that do not exist are skipped.
+// The saved map is updated and the number of blobs that were written is
+// returned.
+func (t *Tstore) backupBlobs(enc *json.Encoder, treeID int64, keys []string, saved map[string]struct{}) (int, error) {
+	pending := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if _, ok := saved[k]; ok {
+			continue
+		}
+		saved[k] = struct{}{}
+		pending = append(pending, k)
+	}
+
+	var n int
+	for len(pending) > 0 {
+		batch := pending
+		if len(batch) > backupBatchSize {
+			batch = batch[:backupBatchSize]
+		}
+		pending = pending[len(batch):]
+
+		blobs, err := t.blobsGet(treeID, batch)
+		if err != nil {
+			return 0, err
+		}
+		for _, k := range batch {
+			v, ok := blobs[k]
+			if !ok {
+				continue
+			}
+			err = enc.Encode(backupEntry{
+				Type: backupTypeBlob,
+				Blob: &backupBlob{
+					Key:   k,
+					Value: v,
+				},
+			})
+			if err != nil {
+				return 0, err
+			}
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// backupFilePaths returns the paths of the data directory files that are
+// included in a backup. These are the JSON files at the root of the data
+// directory, which contain any legacy backend inventory files, and all files
+// in the plugin data directory.
+func (t *Tstore) backupFilePaths() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(t.dataDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	pluginDir := filepath.Join(t.dataDir, pluginDataDirname)
+	err = filepath.WalkDir(pluginDir,
+		func(fp string, d fs.DirEntry, err error) error {
+			switch {
+			case errors.Is(err, fs.ErrNotExist) && fp == pluginDir:
+				// No plugin data has been saved
+				return nil
+			case err != nil:
+				return err
+			case d.Type().IsRegular():
+				paths = append(paths, fp)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Restore restores a backup archive that was created using Backup. The tstore
+// must be empty. Every tree is re-created using its original tree ID so that
+// the record tokens are preserved. The leaf hashes and root hash of every tree
+// are verified against the archive, the signed log root of every restored
+// tree is verified against the archived tree, and every leaf blob is verified
+// against the digest in its leaf before the restore is considered successful.
+// The archive is decrypted using the key that it was encrypted with. The blobs
+// of unvetted records are encrypted using the key of the key-value store.
+//
+// Restoring a backup requires a tlog that implements the tlog TreeImporter
+// interface. Backups can be restored into the embedded tlog, but not into a
+// trillian log since trillian does not allow the tree ID to be specified.
+// See Backup.
+//
+// Restore must not be run while the tstore is in use. It is intended to be
+// run by a standalone tool while politeiad is stopped. The anchor cron job of
+// this tstore instance is stopped.
+func (t *Tstore) Restore(r io.Reader, key *[32]byte) (*BackupSummary, error) {
+	log.Tracef("Restore")
+
+	importer, ok := t.tlog.(tlog.TreeImporter)
+	if !ok {
+		return nil, errBackupUnsupported
+	}
+
+	// Verify that the tstore is empty
+	trees, err := t.tlog.TreesAll()
+	if err != nil {
+		return nil, fmt.Errorf("TreesAll: %v", err)
+	}
+	if len(trees) != 0 {
+		return nil, errors.Errorf("tlog is not empty: %v trees found",
+			len(trees))
+	}
+	keys, err := t.storeKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != 0 {
+		return nil, errors.Errorf("key-value store is not empty: "+
+			"%v blobs found", len(keys))
+	}
+
+	// Anchoring a tree appends a new leaf onto it
+	t.cron.Stop()
+
+	br, err := newBackupReader(r, key)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(zr)
+
+	// Verify the header
+	var e backupEntry
+	err = dec.Decode(&e)
+	if err != nil {
+		return nil, fmt.Errorf("decode header: %v", err)
+	}
+	switch {
+	case e.Type != backupTypeHeader || e.Header == nil:
+		return nil, errors.Errorf("archive header not found")
+	case e.Header.Version != backupVersion:
+		return nil, errors.Errorf("unsupported archive version %v",
+			e.Header.Version)
+	case e.Header.Network != t.activeNetParams.Name:
+		return nil, errors.Errorf("archive network %v does not match %v",
+			e.Header.Network, t.activeNetParams.Name)
+	}
+
+	var (
+		summary BackupSummary
+		footer  *BackupSummary
+
+		// digests contains the expected blob digests of the leaf
+		// blobs of the tree that is currently being restored.
+		digests = make(map[string]string, 1024) // [key]digest
+
+		// The blobs are saved to the key-value store in batches
+		blobs = newRestoreBatch(t.store)
+	)
+	for footer == nil {
+		var e backupEntry
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return nil, errors.Errorf("archive is truncated")
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case e.Type == backupTypeTree && e.Tree != nil:
+			err = t.restoreTree(importer, *e.Tree)
+			if err != nil {
+				return nil, errors.Errorf("tree %v: %v", e.Tree.TreeID, err)
+			}
+			digests = make(map[string]string, len(e.Tree.Leaves)*2)
+			for _, v := range e.Tree.Leaves {
+				ed, err := extraDataDecode(v.ExtraData)
+				if err != nil {
+					return nil, errors.Errorf("tree %v: %v",
+						e.Tree.TreeID, err)
+				}
+				d := hex.EncodeToString(v.LeafValue)
+				digests[ed.storeKeyNoPrefix()] = d
+				digests[ed.storeKey()] = d
+			}
+			summary.Trees++
+			summary.Leaves += len(e.Tree.Leaves)
+
+		case e.Type == backupTypeBlob && e.Blob != nil:
+			if d, ok := digests[e.Blob.Key]; ok {
+				err = verifyBlobDigest(e.Blob.Value, d)
+				if err != nil {
+					return nil, errors.Errorf("blob %v: %v", e.Blob.Key, err)
+				}
+			}
+			err = blobs.add(e.Blob.Key, e.Blob.Value)
+			if err != nil {
+				return nil, err
+			}
+			summary.Blobs++
+
+		case e.Type == backupTypeFile && e.File != nil:
+			err = t.restoreFile(*e.File)
+			if err != nil {
+				return nil, errors.Errorf("file %v: %v", e.File.Path, err)
+			}
+			summary.Files++
+
+		case e.Type == backupTypeFooter && e.Footer != nil:
+			footer = e.Footer
+
+		default:
+			return nil, errors.Errorf("invalid archive entry type '%v'",
+				e.Type)
+		}
+	}
+	err = blobs.flush()
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify that the full archive was restored
+	if summary != *footer {
+		return nil, errors.Errorf("archive contents do not match the "+
+			"footer: got %+v, want %+v", summary, *footer)
+	}
+
+	log.Infof("Restore complete: %v trees, %v leaves, %v blobs, %v files",
+		summary.Trees, summary.Leaves, summary.Blobs, summary.Files)
+
+	return &summary, nil
+}
+
+// restoreTree re-creates a tree using the archived tree ID and appends the
+// archived leaves onto it. The archived tree is verified prior to being
+// restored and the signed log root of the restored tree is verified against
+// the archived tree once all leaves have been appended.
+func (t *Tstore) restoreTree(importer tlog.TreeImporter, bt backupTree) error {
+	err := verifyBackupTree(bt)
+	if err != nil {
+		return err
+	}
+
+	tree, _, err := importer.TreeImport(bt.TreeID)
+	if err != nil {
+		return fmt.Errorf("TreeImport: %v", err)
+	}
+
+	// Append the leaves in the order in which they were archived
+	for i := 0; i < len(bt.Leaves); i += leavesAppendBatchSize {
+		end := i + leavesAppendBatchSize
+		if end > len(bt.Leaves) {
+			end = len(bt.Leaves)
+		}
+		leaves := make([]*trillian.LogLeaf, 0, end-i)
+		for _, v := range bt.Leaves[i:end] {
+			leaves = append(leaves, tlog.NewLogLeaf(v.LeafValue, v.ExtraData))
+		}
+		queued, _, err := t.tlogLeavesAppend(bt.TreeID, leaves)
+		if err != nil {
+			return fmt.Errorf("LeavesAppend: %v", err)
+		}
+		if len(queued) != len(leaves) {
+			return errors.Errorf("wrong number of queued leaves: "+
+				"got %v, want %v", len(queued), len(leaves))
+		}
+		for j, v := range queued {
+			c := codes.Code(v.QueuedLeaf.GetStatus().GetCode())
+			if c != codes.OK {
+				return errors.Errorf("leaf %v not appended: %v", i+j, c)
+			}
+			if !bytes.Equal(v.QueuedLeaf.Leaf.MerkleLeafHash,
+				bt.Leaves[i+j].MerkleLeafHash) {
+				return errors.Errorf("leaf %v merkle leaf hash mismatch", i+j)
+			}
+		}
+	}
+
+	// Verify the signed log root of the restored tree
+	_, lr, err := t.tlog.SignedLogRoot(tree)
+	if err != nil {
+		return fmt.Errorf("SignedLogRoot: %v", err)
+	}
+	if lr.TreeSize != bt.TreeSize || !bytes.Equal(lr.RootHash, bt.RootHash) {
+		return errors.Errorf("log root mismatch: got size %v root %x, "+
+			"want size %v root %x", lr.TreeSize, lr.RootHash,
+			bt.TreeSize, bt.RootHash)
+	}
+	if pubKey := t.TlogPublicKey(); pubKey != nil {
+		err = tlog.VerifyLogRootSignature(pubKey, *lr)
+		if err != nil {
+			return err
+		}
+	}
+
+	if bt.Frozen {
+		_, err = t.tlog.TreeFreeze(bt.TreeID)
+		if err != nil {
+			return fmt.Errorf("TreeFreeze: %v", err)
+		}
+	}
+
+	log.Debugf("Restored tree %v: %v leaves", bt.TreeID, len(bt.Leaves))
+
+	return nil
+}
+
+// restoreFile writes an archived file to the data directory.
+func (t *Tstore) restoreFile(f backupFile) error {
+	// Only allow files to be written inside of the data directory
+	p := path.Clean(f.Path)
+	if p != f.Path || path.IsAbs(p) || p == "." ||
+		p == ".." || strings.HasPrefix(p, "../") {
+		return errors.Errorf("invalid path")
+	}
+	fp := filepath.Join(t.dataDir, filepath.FromSlash(p))
+	err := os.MkdirAll(filepath.Dir(fp), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fp, f.Data, f.Mode.Perm())
+}
+
+// verifyBackupTree verifies that the merkle leaf hash of every archived leaf
+// is correct and that the archived leaves produce the archived root hash.
+func verifyBackupTree(bt backupTree) error {
+	if uint64(len(bt.Leaves)) != bt.TreeSize {
+		return errors.Errorf("got %v leaves, want %v",
+			len(bt.Leaves), bt.TreeSize)
+	}
+	hashes := make([][]byte, 0, len(bt.Leaves))
+	for i, v := range bt.Leaves {
+		if !bytes.Equal(tlog.MerkleLeafHash(v.LeafValue), v.MerkleLeafHash) {
+			return errors.Errorf("leaf %v merkle leaf hash mismatch", i)
+		}
+		hashes = append(hashes, v.MerkleLeafHash)
+	}
+	root, err := tlog.RootHash(hashes)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(root, bt.RootHash) {
+		return errors.Errorf("root hash mismatch: got %x, want %x",
+			root, bt.RootHash)
+	}
+	return nil
+}
+
+// verifyBlobDigest verifies that the provided blob contains a blob entry
+// whose digest matches the provided leaf value digest and whose data matches
+// the blob entry digest.
+func verifyBlobDigest(blob []byte, digest string) error {
+	be, err := store.Deblob(blob)
+	if err != nil {
+		return err
+	}
+	if be.Digest != digest {
+		return errors.Errorf("digest mismatch: got %v, want %v",
+			be.Digest, digest)
+	}
+	b, err := base64.StdEncoding.DecodeString(be.Data)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(util.Digest(b)) != digest {
+		return errors.Errorf("data does not match digest %v", digest)
+	}
+	return nil
+}
+
+// restoreBatch buffers restored blobs and saves them to the key-value store in
+// batches. Blobs that belong to unvetted records are encrypted when they are
+// saved.
+type restoreBatch struct {
+	store     store.BlobKV
+	clearText map[string][]byte
+	encrypted map[string][]byte
+}
+
+// newRestoreBatch returns a new restoreBatch.
+func newRestoreBatch(kv store.BlobKV) *restoreBatch {
+	return &restoreBatch{
+		store:     kv,
+		clearText: make(map[string][]byte, backupBatchSize),
+		encrypted: make(map[string][]byte, backupBatchSize),
+	}
+}
+
+// add adds a blob to the batch. The batch is saved to the key-value store
+// once it is full.
+func (b *restoreBatch) add(key string, value []byte) error {
+	if strings.HasPrefix(key, keyPrefixEncrypted) {
+		b.encrypted[key] = value
+	} else {
+		b.clearText[key] = value
+	}
+	if len(b.encrypted)+len(b.clearText) < backupBatchSize {
+		return nil
+	}
+	return b.flush()
+}
+
+// flush saves all buffered blobs to the key-value store.
+func (b *restoreBatch) flush() error {
+	if len(b.clearText) > 0 {
+		err := b.store.Put(b.clearText, false)
+		if err != nil {
+			return fmt.Errorf("store Put: %v", err)
+		}
+		b.clearText = make(map[string][]byte, backupBatchSize)
+	}
+	if len(b.encrypted) > 0 {
+		err := b.store.Put(b.encrypted, true)
+		if err != nil {
+			return fmt.Errorf("store Put: %v", err)
+		}
+		b.encrypted = make(map[string][]byte, backupBatchSize)
+	}
+	return nil
+}
@@ -0,0 +1,399 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store/localdb"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/decred/politeia/util"
+	"github.com/google/trillian"
+	"github.com/marcopeereboom/sbox"
+	"github.com/robfig/cron"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// newTestBackupTstore returns a tstore instance that uses the embedded tlog
+// so that backups can be restored into it.
+func newTestBackupTstore(t *testing.T) (*Tstore, func()) {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "tstore.backup.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := localdb.New(dir, filepath.Join(dir, "kv"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	tlogDir := filepath.Join(dir, "tlog")
+	privKey, err := embeddedTlogSigningKey(kv)
+	if err != nil {
+		kv.Close()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	tlogClient, err := tlog.NewEmbeddedClient(tlogDir, privKey)
+	if err != nil {
+		kv.Close()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	ts := &Tstore{
+		dataDir:         filepath.Join(dir, "data"),
+		activeNetParams: chaincfg.TestNet3Params(),
+		tlog:            tlogClient,
+		store:           kv,
+		cron:            cron.New(),
+		plugins:         make(map[string]plugin),
+		tokens:          make(map[string][]byte),
+	}
+	cleanup := func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+
+	return ts, cleanup
+}
+
+// saveTestRecord creates a new record and saves the provided record states to
+// it, in order. The record token is returned.
+func saveTestRecord(t *testing.T, ts *Tstore, states ...backend.StateT) []byte {
+	t.Helper()
+
+	token, err := ts.RecordNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload := []byte("This is my proposal.")
+	f := backend.File{
+		Name:    "index.md",
+		MIME:    http.DetectContentType(payload),
+		Digest:  hex.EncodeToString(util.Digest(payload)),
+		Payload: base64.StdEncoding.EncodeToString(payload),
+	}
+	for _, s := range states {
+		status := backend.StatusUnreviewed
+		if s == backend.StateVetted {
+			status = backend.StatusPublic
+		}
+		rm := backend.RecordMetadata{
+			Token:     hex.EncodeToString(token),
+			Version:   1,
+			Iteration: 1,
+			State:     s,
+			Status:    status,
+			Timestamp: time.Now().Unix(),
+			Merkle:    f.Digest,
+		}
+		err = ts.RecordSave(token, rm, nil, []backend.File{f})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return token
+}
+
+func TestBackupRestore(t *testing.T) {
+	src, cleanupSrc := newTestBackupTstore(t)
+	defer cleanupSrc()
+
+	// Create an unvetted record, whose blobs are encrypted, and a
+	// vetted record whose tree has been frozen.
+	unvetted := saveTestRecord(t, src, backend.StateUnvetted)
+	vetted := saveTestRecord(t, src, backend.StateUnvetted,
+		backend.StateVetted)
+	_, err := src.tlog.TreeFreeze(treeIDFromToken(vetted))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Add a plugin cache entry and the data directory files
+	var (
+		cacheKey   = "ticketvote-summary-abcdefg"
+		cacheValue = []byte("summary")
+		files      = map[string][]byte{
+			"inv-vetted.json":          []byte(`{"entries":[]}`),
+			"plugins/usermd/user.json": []byte(`{"tokens":[]}`),
+		}
+	)
+	err = src.store.Put(map[string][]byte{cacheKey: cacheValue}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for fn, b := range files {
+		fp := filepath.Join(src.dataDir, filepath.FromSlash(fn))
+		err = os.MkdirAll(filepath.Dir(fp), 0700)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(fp, b, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Backup the source tstore
+	key, err := sbox.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	bs, err := src.Backup(&archive, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs.Trees != 2 || bs.Files != len(files) {
+		t.Fatalf("unexpected backup summary %+v", bs)
+	}
+
+	// A truncated archive must be rejected
+	dst, cleanupDst := newTestBackupTstore(t)
+	defer cleanupDst()
+	truncated := archive.Bytes()[:archive.Len()/2]
+	_, err = dst.Restore(bytes.NewReader(truncated), key)
+	if err == nil {
+		t.Fatalf("expected error for truncated archive")
+	}
+
+	// An archive can not be restored without its key
+	dst, cleanupDst = newTestBackupTstore(t)
+	defer cleanupDst()
+	wrongKey, err := sbox.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dst.Restore(bytes.NewReader(archive.Bytes()), wrongKey)
+	if err == nil {
+		t.Fatalf("expected error for the wrong key")
+	}
+
+	// Restore the archive into a new tstore
+	dst, cleanupDst = newTestBackupTstore(t)
+	defer cleanupDst()
+	rs, err := dst.Restore(bytes.NewReader(archive.Bytes()), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *rs != *bs {
+		t.Fatalf("got restore summary %+v, want %+v", rs, bs)
+	}
+
+	// Verify the records were restored using the same tokens
+	for _, token := range [][]byte{unvetted, vetted} {
+		want, err := src.RecordLatest(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := dst.RecordLatest(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantB, _ := json.Marshal(want)
+		gotB, _ := json.Marshal(got)
+		if !bytes.Equal(gotB, wantB) {
+			t.Fatalf("record %x mismatch:\ngot  %s\nwant %s",
+				token, gotB, wantB)
+		}
+	}
+
+	// Verify the tree state was restored
+	tree, err := dst.tlog.Tree(treeIDFromToken(vetted))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.TreeState != trillian.TreeState_FROZEN {
+		t.Fatalf("got tree state %v, want frozen", tree.TreeState)
+	}
+
+	// Verify the unvetted blobs are encrypted at rest again
+	keys, err := dst.store.Keys(keyPrefixEncrypted, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) == 0 {
+		t.Fatalf("no encrypted blobs were restored")
+	}
+
+	// Verify the plugin cache and the data directory files
+	blobs, err := dst.store.Get([]string{cacheKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(blobs[cacheKey], cacheValue) {
+		t.Fatalf("got cache value '%s', want '%s'", blobs[cacheKey], cacheValue)
+	}
+	for fn, b := range files {
+		got, err := os.ReadFile(filepath.Join(dst.dataDir, fn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, b) {
+			t.Fatalf("%v: got '%s', want '%s'", fn, got, b)
+		}
+	}
+
+	// A backup can not be restored into a tstore that is not empty
+	_, err = dst.Restore(bytes.NewReader(archive.Bytes()), key)
+	if err == nil {
+		t.Fatalf("expected error when restoring into a non-empty tstore")
+	}
+}
+
+func TestBackupUnsupported(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.backup.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	// The test tlog does not support importing trees, the same as
+	// trillian, so the tstore can not be backed up.
+	key, err := sbox.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	_, err = ts.Backup(&archive, key)
+	if err != errBackupUnsupported {
+		t.Fatalf("got err %v, want %v", err, errBackupUnsupported)
+	}
+	if archive.Len() != 0 {
+		t.Fatalf("archive was written")
+	}
+}
+
+func TestBackupCrypt(t *testing.T) {
+	key, err := sbox.NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, backupChunkSize*2+100)
+	_, err = rand.Read(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Encrypt the data in multiple writes
+	var archive bytes.Buffer
+	w, err := newBackupWriter(&archive, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range [][]byte{data[:10], data[10:backupChunkSize],
+		data[backupChunkSize:]} {
+		_, err = w.Write(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the data round trips
+	r, err := newBackupReader(bytes.NewReader(archive.Bytes()), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("decrypted data does not match")
+	}
+
+	// Dropping the last chunk must be detected even though the archive
+	// ends on a chunk boundary.
+	header := len(backupMagic) + backupNoncePrefixSize
+	chunk := 4 + backupChunkSize + secretbox.Overhead
+	r, err = newBackupReader(bytes.NewReader(
+		archive.Bytes()[:header+2*chunk]), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(r)
+	if err == nil {
+		t.Fatalf("expected error for truncated archive")
+	}
+
+	// Reordered chunks must be detected
+	b := archive.Bytes()
+	reordered := append([]byte{}, b[:header]...)
+	reordered = append(reordered, b[header+chunk:header+2*chunk]...)
+	reordered = append(reordered, b[header:header+chunk]...)
+	reordered = append(reordered, b[header+2*chunk:]...)
+	r, err = newBackupReader(bytes.NewReader(reordered), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(r)
+	if err == nil {
+		t.Fatalf("expected error for reordered chunks")
+	}
+}
+
+func TestVerifyBackupTree(t *testing.T) {
+	leaves := make([]backupLeaf, 0, 3)
+	hashes := make([][]byte, 0, 3)
+	for _, v := range []string{"leaf-0", "leaf-1", "leaf-2"} {
+		h := tlog.MerkleLeafHash([]byte(v))
+		leaves = append(leaves, backupLeaf{
+			LeafValue:      []byte(v),
+			MerkleLeafHash: h,
+		})
+		hashes = append(hashes, h)
+	}
+	root, err := tlog.RootHash(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bt := backupTree{
+		TreeID:   1,
+		TreeSize: uint64(len(leaves)),
+		RootHash: root,
+		Leaves:   leaves,
+	}
+	err = verifyBackupTree(bt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A modified leaf value must be detected
+	tampered := bt
+	tampered.Leaves = append([]backupLeaf{}, bt.Leaves...)
+	tampered.Leaves[1].LeafValue = []byte("tampered")
+	err = verifyBackupTree(tampered)
+	if err == nil {
+		t.Fatalf("expected error for tampered leaf value")
+	}
+
+	// A modified root hash must be detected
+	tampered = bt
+	tampered.RootHash = hashes[0]
+	err = verifyBackupTree(tampered)
+	if err == nil {
+		t.Fatalf("expected error for tampered root hash")
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// A backup archive is encrypted using a secretbox key that is provided by the
+// operator. The archive begins with the backupMagic bytes and a random nonce
+// prefix. The gzipped archive entries follow as a sequence of chunks. Each
+// chunk is a 4 byte big endian length followed by a secretbox that seals at
+// most backupChunkSize bytes of the gzip stream.
+//
+// The nonce of a chunk is the nonce prefix followed by the 8 byte big endian
+// index of the chunk. The most significant bit of the index is set on the last
+// chunk. Chunks that have been reordered, dropped or appended fail to open and
+// a truncated archive is detected by the missing last chunk.
+const (
+	// backupMagic identifies an encrypted backup archive.
+	backupMagic = "tstorebk"
+
+	// backupChunkSize is the maximum number of clear text bytes that are
+	// sealed in a single chunk.
+	backupChunkSize = 1 << 16
+
+	// backupNoncePrefixSize is the size of the random nonce prefix.
+	backupNoncePrefixSize = 16
+
+	// backupChunkLast is set in the index of the last chunk.
+	backupChunkLast uint64 = 1 << 63
+)
+
+// backupNonce returns the nonce of the chunk at the provided index.
+func backupNonce(prefix []byte, index uint64, last bool) *[24]byte {
+	if last {
+		index |= backupChunkLast
+	}
+	var n [24]byte
+	copy(n[:], prefix)
+	binary.BigEndian.PutUint64(n[backupNoncePrefixSize:], index)
+	return &n
+}
+
+// backupWriter encrypts the data that is written to it and writes the sealed
+// chunks to the underlying writer. Close must be called to write the last
+// chunk. It does not close the underlying writer.
+type backupWriter struct {
+	w      io.Writer
+	key    *[32]byte
+	prefix []byte
+	index  uint64
+	buf    []byte
+}
+
+// newBackupWriter writes the archive header to the provided writer and
+// returns a backupWriter that encrypts using the provided key.
+func newBackupWriter(w io.Writer, key *[32]byte) (*backupWriter, error) {
+	prefix := make([]byte, backupNoncePrefixSize)
+	_, err := io.ReadFull(rand.Reader, prefix)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(append([]byte(backupMagic), prefix...))
+	if err != nil {
+		return nil, err
+	}
+	return &backupWriter{
+		w:      w,
+		key:    key,
+		prefix: prefix,
+		buf:    make([]byte, 0, backupChunkSize),
+	}, nil
+}
+
+// Write satisfies the io.Writer interface.
+func (b *backupWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		c := backupChunkSize - len(b.buf)
+		if c > len(p) {
+			c = len(p)
+		}
+		b.buf = append(b.buf, p[:c]...)
+		p = p[c:]
+		n += c
+
+		// The chunk is only sealed once more data is written so that
+		// the last chunk is never empty unless the archive is.
+		if len(b.buf) == backupChunkSize && len(p) > 0 {
+			err := b.seal(false)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close seals the buffered data as the last chunk.
+func (b *backupWriter) Close() error {
+	return b.seal(true)
+}
+
+// seal seals the buffered data as the next chunk and writes it to the
+// underlying writer.
+func (b *backupWriter) seal(last bool) error {
+	box := secretbox.Seal(nil, b.buf,
+		backupNonce(b.prefix, b.index, last), b.key)
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(box)))
+	_, err := b.w.Write(append(l[:], box...))
+	if err != nil {
+		return err
+	}
+	b.index++
+	b.buf = b.buf[:0]
+	return nil
+}
+
+// backupReader decrypts an archive that was written using a backupWriter.
+type backupReader struct {
+	r      io.Reader
+	key    *[32]byte
+	prefix []byte
+	index  uint64
+	buf    []byte
+	last   bool
+}
+
+// newBackupReader reads the archive header from the provided reader and
+// returns a backupReader that decrypts using the provided key.
+func newBackupReader(r io.Reader, key *[32]byte) (*backupReader, error) {
+	h := make([]byte, len(backupMagic)+backupNoncePrefixSize)
+	_, err := io.ReadFull(r, h)
+	if err != nil {
+		return nil, errors.Errorf("read archive header: %v", err)
+	}
+	if !bytes.Equal(h[:len(backupMagic)], []byte(backupMagic)) {
+		return nil, errors.Errorf("not an encrypted backup archive")
+	}
+	return &backupReader{
+		r:      r,
+		key:    key,
+		prefix: h[len(backupMagic):],
+	}, nil
+}
+
+// Read satisfies the io.Reader interface.
+func (b *backupReader) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 {
+		if b.last {
+			return 0, io.EOF
+		}
+		err := b.open()
+		if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
+
+// open reads and opens the next chunk.
+func (b *backupReader) open() error {
+	var l [4]byte
+	_, err := io.ReadFull(b.r, l[:])
+	if err == io.EOF {
+		return errors.Errorf("archive is truncated")
+	} else if err != nil {
+		return err
+	}
+	size := binary.BigEndian.Uint32(l[:])
+	if size > backupChunkSize+secretbox.Overhead {
+		return errors.Errorf("chunk %v is too large", b.index)
+	}
+	box := make([]byte, size)
+	_, err = io.ReadFull(b.r, box)
+	if err != nil {
+		return errors.Errorf("archive is truncated")
+	}
+
+	// The chunk is either the next chunk or the last chunk
+	for _, last := range []bool{false, true} {
+		data, ok := secretbox.Open(nil, box,
+			backupNonce(b.prefix, b.index, last), b.key)
+		if !ok {
+			continue
+		}
+		b.buf = data
+		b.last = last
+		b.index++
+		return nil
+	}
+	return errors.Errorf("chunk %v can not be decrypted; wrong key or "+
+		"corrupt archive", b.index)
+}
@@ -0,0 +1,542 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+)
+
+// Record content blobs, i.e. the record metadata, the metadata streams and
+// the files, are saved to the key-value store using content addressed keys.
+// The same content that is saved by multiple records, or by multiple versions
+// of a record, is only stored once per encryption domain. Unvetted content is
+// saved using the encrypted key prefix and is never deduplicated against
+// vetted content.
+//
+// Each content addressed blob has a reference entry that contains the tokens
+// of the records that reference it. A blob is only deleted from the key-value
+// store once no records reference it, which allows censorship and record
+// deletion to purge content that is not shared with other records.
+//
+// The record content blobs that were saved prior to content addressing use
+// random keys. These keys can not be changed since they are part of the tlog
+// leaves. The migration moves these blobs to their content addressed keys and
+// leaves a link entry behind that maps the legacy key to the new key.
+const (
+	// keyPrefixContent is prefixed onto content addressed key-value
+	// store keys. The prefix is followed by the hex encoded SHA256
+	// digest of the blob entry.
+	keyPrefixContent = "c_"
+
+	// blobRefsKey is the key-value store key for the reference entry of
+	// a content addressed blob. The "{key}" is replaced with the key of
+	// the blob, including the encryption prefix.
+	blobRefsKey = "blobrefs-{key}"
+
+	// blobLinkKey is the key-value store key for the link entry of a
+	// legacy record content blob that has been migrated to a content
+	// addressed key. The "{key}" is replaced with the legacy key,
+	// including the encryption prefix.
+	blobLinkKey = "bloblink-{key}"
+
+	// blobKeysMigrationKey is the key-value store key for the record of
+	// a completed blob key migration.
+	blobKeysMigrationKey = "tstore-blobkeys"
+
+	// blobKeysMigrationBatchSize is the maximum number of legacy blobs
+	// that are migrated at once.
+	blobKeysMigrationBatchSize = 200
+)
+
+// storeKeyContent returns the content addressed key-value store key for a
+// blob entry. The key is derived from the data hint and the data digest of the
+// blob entry. If the data is encrypted the key is prefixed.
+func storeKeyContent(be store.BlobEntry, encrypt bool) string {
+	d := sha256.Sum256([]byte(be.DataHint + be.Digest))
+	k := keyPrefixContent + hex.EncodeToString(d[:])
+	if encrypt {
+		k = keyPrefixEncrypted + k
+	}
+	return k
+}
+
+// isContentKey returns whether the provided key-value store key is a content
+// addressed key.
+func isContentKey(key string) bool {
+	return strings.HasPrefix(storeKeyCleaned(key), keyPrefixContent)
+}
+
+// buildBlobRefsKey returns the key-value store key for the reference entry of
+// a content addressed blob.
+func buildBlobRefsKey(key string) string {
+	return strings.Replace(blobRefsKey, "{key}", key, 1)
+}
+
+// buildBlobLinkKey returns the key-value store key for the link entry of a
+// legacy blob.
+func buildBlobLinkKey(key string) string {
+	return strings.Replace(blobLinkKey, "{key}", key, 1)
+}
+
+// blobRefsGet returns the tokens of the records that reference each of the
+// provided content addressed keys. An entry will not exist in the returned
+// map for keys that are not referenced by any records.
+func (t *Tstore) blobRefsGet(keys []string) (map[string][]string, error) {
+	refKeys := make([]string, 0, len(keys))
+	for _, v := range keys {
+		refKeys = append(refKeys, buildBlobRefsKey(v))
+	}
+	blobs, err := t.store.Get(refKeys)
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	refs := make(map[string][]string, len(blobs))
+	for _, v := range keys {
+		b, ok := blobs[buildBlobRefsKey(v)]
+		if !ok {
+			continue
+		}
+		var tokens []string
+		err = json.Unmarshal(b, &tokens)
+		if err != nil {
+			return nil, err
+		}
+		refs[v] = tokens
+	}
+	return refs, nil
+}
+
+// blobRefsSave saves the provided reference entries to the key-value store.
+// The entries of keys that are no longer referenced by any records are
+// deleted.
+//
+// The reference entries are shared between records and are saved directly to
+// the key-value store, even when a transaction is in progress. A transaction
+// undoes the changes that it made to them by delta. See txRefsUpdated.
+func (t *Tstore) blobRefsSave(refs map[string][]string) error {
+	var (
+		save = make(map[string][]byte, len(refs))
+		del  = make([]string, 0, len(refs))
+	)
+	for k, v := range refs {
+		if len(v) == 0 {
+			del = append(del, buildBlobRefsKey(k))
+			continue
+		}
+		sort.Strings(v)
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		save[buildBlobRefsKey(k)] = b
+	}
+	if len(save) > 0 {
+		err := t.store.Put(save, false)
+		if err != nil {
+			return fmt.Errorf("store Put: %v", err)
+		}
+	}
+	if len(del) > 0 {
+		err := t.store.Del(del)
+		if err != nil {
+			return fmt.Errorf("store Del: %v", err)
+		}
+	}
+	return nil
+}
+
+// blobRefsAdd adds the provided record token to the reference entries of the
+// provided keys. Keys that are not content addressed are ignored. Adding a
+// token that is already referenced is a no-op.
+//
+// The blobs mutex must be held by the caller.
+func (t *Tstore) blobRefsAdd(token []byte, keys []string) error {
+	content := make([]string, 0, len(keys))
+	for _, v := range keys {
+		if isContentKey(v) {
+			content = append(content, v)
+		}
+	}
+	if len(content) == 0 {
+		return nil
+	}
+	refs, err := t.blobRefsGet(content)
+	if err != nil {
+		return err
+	}
+	var (
+		tokenHex = hex.EncodeToString(token)
+		updated  = make(map[string][]string, len(content))
+		added    = make([]string, 0, len(content))
+	)
+	for _, k := range content {
+		if refsContain(refs[k], tokenHex) {
+			continue
+		}
+		updated[k] = append(refs[k], tokenHex)
+		added = append(added, k)
+	}
+	t.txRefsUpdated(treeIDFromToken(token), added, nil)
+
+	return t.blobRefsSave(updated)
+}
+
+// refsContain returns whether the provided tokens contain the token.
+func refsContain(tokens []string, token string) bool {
+	for _, v := range tokens {
+		if v == token {
+			return true
+		}
+	}
+	return false
+}
+
+// refsRemove returns the provided tokens without the token.
+func refsRemove(tokens []string, token string) []string {
+	r := make([]string, 0, len(tokens))
+	for _, v := range tokens {
+		if v != token {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// blobsPut saves the provided blobs of a record tree to the key-value store.
+// The record is added to the reference entries of the content addressed
+// blobs prior to the blobs being saved. A blob that already exists because it
+// is referenced by another record is overwritten with the same content.
+func (t *Tstore) blobsPut(treeID int64, blobs map[string][]byte, encrypt bool) error {
+	keys := make([]string, 0, len(blobs))
+	for k := range blobs {
+		keys = append(keys, k)
+	}
+
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	err := t.blobRefsAdd(tokenFromTreeID(treeID), keys)
+	if err != nil {
+		return err
+	}
+	err = t.kv(treeID).Put(blobs, encrypt)
+	if err != nil {
+		return fmt.Errorf("store Put: %v", err)
+	}
+
+	return nil
+}
+
+// blobLinksGet returns the content addressed keys that the provided legacy
+// keys have been migrated to. An entry will not exist in the returned map for
+// keys that have not been migrated.
+func (t *Tstore) blobLinksGet(keys []string) (map[string]string, error) {
+	linkKeys := make([]string, 0, len(keys))
+	for _, v := range keys {
+		if isContentKey(v) {
+			// Content addressed keys are never linked
+			continue
+		}
+		linkKeys = append(linkKeys, buildBlobLinkKey(v))
+	}
+	if len(linkKeys) == 0 {
+		return map[string]string{}, nil
+	}
+	blobs, err := t.store.Get(linkKeys)
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	links := make(map[string]string, len(blobs))
+	for _, v := range keys {
+		b, ok := blobs[buildBlobLinkKey(v)]
+		if !ok {
+			continue
+		}
+		links[v] = string(b)
+	}
+	return links, nil
+}
+
+// blobsGetHot returns the blobs for the provided keys from the key-value
+// store. The keys of legacy blobs that have been migrated are resolved to
+// their content addressed keys, but the blobs are returned using the provided
+// keys. An entry will not exist in the returned map for any blobs that are not
+// found.
+func (t *Tstore) blobsGetHot(keys []string) (map[string][]byte, error) {
+	blobs, err := t.store.Get(keys)
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	if len(blobs) == len(keys) {
+		return blobs, nil
+	}
+
+	// Resolve the keys of the migrated blobs
+	missing := make([]string, 0, len(keys)-len(blobs))
+	for _, v := range keys {
+		if _, ok := blobs[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	links, err := t.blobLinksGet(missing)
+	if err != nil {
+		return nil, err
+	}
+	if len(links) == 0 {
+		return blobs, nil
+	}
+	linked := make([]string, 0, len(links))
+	for _, v := range links {
+		linked = append(linked, v)
+	}
+	content, err := t.store.Get(linked)
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	for k, v := range links {
+		b, ok := content[v]
+		if !ok {
+			continue
+		}
+		blobs[k] = b
+	}
+
+	return blobs, nil
+}
+
+// blobsRelease releases the references of a record tree to the provided keys
+// and returns the key-value store keys that must be deleted along with the
+// updated reference entries. Keys that are not content addressed are always
+// deleted. Content addressed blobs are only deleted once they are no longer
+// referenced by any records. The link entries of migrated legacy keys are
+// deleted and the references to the blobs that they link to are released.
+//
+// The blobs mutex must be held by the caller.
+func (t *Tstore) blobsRelease(treeID int64, keys []string) ([]string, map[string][]string, error) {
+	links, err := t.blobLinksGet(keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		del     = make([]string, 0, len(keys)+len(links))
+		content = make([]string, 0, len(keys)+len(links))
+	)
+	for _, v := range keys {
+		if isContentKey(v) {
+			content = append(content, v)
+			continue
+		}
+		del = append(del, v)
+		if link, ok := links[v]; ok {
+			del = append(del, buildBlobLinkKey(v))
+			content = append(content, link)
+		}
+	}
+	if len(content) == 0 {
+		return del, map[string][]string{}, nil
+	}
+
+	refs, err := t.blobRefsGet(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		token   = hex.EncodeToString(tokenFromTreeID(treeID))
+		updated = make(map[string][]string, len(content))
+		removed = make([]string, 0, len(content))
+	)
+	for _, k := range content {
+		r, ok := updated[k]
+		if !ok {
+			r = refs[k]
+		}
+		if refsContain(r, token) {
+			removed = append(removed, k)
+		}
+		r = refsRemove(r, token)
+		updated[k] = r
+		if len(r) == 0 {
+			del = append(del, k)
+		}
+	}
+	t.txRefsUpdated(treeID, nil, removed)
+
+	return del, updated, nil
+}
+
+// blobKeysMigration is the record of a completed blob key migration.
+type blobKeysMigration struct {
+	Timestamp int64 `json:"timestamp"` // Unix timestamp
+	Migrated  int   `json:"migrated"`  // Legacy blobs migrated
+	Shared    int   `json:"shared"`    // Blobs that were deduplicated
+}
+
+// blobKeysMigrate performs a one time migration of the legacy record content
+// blobs to content addressed keys. A link entry is saved for each legacy key
+// and the legacy blob is deleted. The blobs of archived records are not
+// migrated since they no longer exist in the key-value store.
+//
+// The migration can be safely re-run if it is interrupted. A legacy blob that
+// has already been migrated no longer exists and is skipped.
+func (t *Tstore) blobKeysMigrate() error {
+	blobs, err := t.store.Get([]string{blobKeysMigrationKey})
+	if err != nil {
+		return fmt.Errorf("store Get: %v", err)
+	}
+	if _, ok := blobs[blobKeysMigrationKey]; ok {
+		// Migration has already been performed
+		return nil
+	}
+
+	trees, err := t.tlog.TreesAll()
+	if err != nil {
+		return fmt.Errorf("TreesAll: %v", err)
+	}
+
+	log.Infof("Migrating the record content blobs of %v trees to content "+
+		"addressed keys", len(trees))
+
+	var m blobKeysMigration
+	for _, tree := range trees {
+		leaves, err := t.leavesAll(tree.TreeId)
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(leaves)*2)
+		for _, v := range leaves {
+			ed, err := extraDataDecode(v.ExtraData)
+			if err != nil {
+				return err
+			}
+			switch ed.Desc {
+			case dataDescriptorRecordMetadata, dataDescriptorMetadataStream,
+				dataDescriptorFile:
+				// These are record content blobs
+			default:
+				continue
+			}
+			if isContentKey(ed.Key) {
+				continue
+			}
+			keys = append(keys, ed.storeKey())
+			if ed.storeKey() != ed.storeKeyNoPrefix() {
+				// The blob may have been resaved as clear text when
+				// the record was made public.
+				keys = append(keys, ed.storeKeyNoPrefix())
+			}
+		}
+		for len(keys) > 0 {
+			batch := keys
+			if len(batch) > blobKeysMigrationBatchSize {
+				batch = keys[:blobKeysMigrationBatchSize]
+			}
+			keys = keys[len(batch):]
+
+			migrated, shared, err := t.blobKeysMigrateBatch(tree.TreeId, batch)
+			if err != nil {
+				return fmt.Errorf("tree %v: %v", tree.TreeId, err)
+			}
+			m.Migrated += migrated
+			m.Shared += shared
+		}
+	}
+
+	log.Infof("%v legacy blobs migrated; %v were shared with other records",
+		m.Migrated, m.Shared)
+
+	m.Timestamp = time.Now().Unix()
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	err = t.store.Put(map[string][]byte{blobKeysMigrationKey: b}, false)
+	if err != nil {
+		return fmt.Errorf("store Put: %v", err)
+	}
+
+	return nil
+}
+
+// blobKeysMigrateBatch migrates the provided legacy keys of a record tree to
+// content addressed keys. Keys that do not exist are skipped. The number of
+// blobs that were migrated and the number of those blobs that already existed
+// under their content addressed key are returned.
+func (t *Tstore) blobKeysMigrateBatch(treeID int64, keys []string) (int, int, error) {
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	blobs, err := t.store.Get(keys)
+	if err != nil {
+		return 0, 0, fmt.Errorf("store Get: %v", err)
+	}
+	if len(blobs) == 0 {
+		return 0, 0, nil
+	}
+	var (
+		clearText = make(map[string][]byte, len(blobs))
+		encrypted = make(map[string][]byte, len(blobs))
+		links     = make(map[string][]byte, len(blobs))
+		content   = make([]string, 0, len(blobs))
+		legacy    = make([]string, 0, len(blobs))
+	)
+	for k, v := range blobs {
+		be, err := store.Deblob(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("deblob %v: %v", k, err)
+		}
+		encrypt := strings.HasPrefix(k, keyPrefixEncrypted)
+		ck := storeKeyContent(*be, encrypt)
+		if encrypt {
+			encrypted[ck] = v
+		} else {
+			clearText[ck] = v
+		}
+		links[buildBlobLinkKey(k)] = []byte(ck)
+		content = append(content, ck)
+		legacy = append(legacy, k)
+	}
+	existing, err := t.store.Get(content)
+	if err != nil {
+		return 0, 0, fmt.Errorf("store Get: %v", err)
+	}
+
+	// The content addressed blobs and the links are saved before the
+	// legacy blobs are deleted so that the migration can be re-run if
+	// it is interrupted.
+	err = t.blobRefsAdd(tokenFromTreeID(treeID), content)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(clearText) > 0 {
+		err = t.store.Put(clearText, false)
+		if err != nil {
+			return 0, 0, fmt.Errorf("store Put: %v", err)
+		}
+	}
+	if len(encrypted) > 0 {
+		err = t.store.Put(encrypted, true)
+		if err != nil {
+			return 0, 0, fmt.Errorf("store Put: %v", err)
+		}
+	}
+	err = t.store.Put(links, false)
+	if err != nil {
+		return 0, 0, fmt.Errorf("store Put: %v", err)
+	}
+	err = t.store.Del(legacy)
+	if err != nil {
+		return 0, 0, fmt.Errorf("store Del: %v", err)
+	}
+
+	return len(legacy), len(existing), nil
+}
@@ -0,0 +1,255 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/google/trillian"
+)
+
+// newTestBlob returns a blob entry and its blobified form for the provided
+// data.
+func newTestBlob(t *testing.T, data string) (store.BlobEntry, []byte) {
+	t.Helper()
+
+	be := store.NewBlobEntry([]byte(dataDescriptorFile), []byte(data))
+	b, err := store.Blobify(be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return be, b
+}
+
+func TestBlobsPutDel(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.blobkeys.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	// Save the same content for two records
+	be, b := newTestBlob(t, "logo")
+	key := storeKeyContent(be, false)
+	if key != storeKeyContent(be, false) {
+		t.Fatalf("content key is not deterministic")
+	}
+	if storeKeyContent(be, true) != keyPrefixEncrypted+key {
+		t.Fatalf("encrypted content key is not prefixed")
+	}
+	var treeIDs []int64
+	for i := 0; i < 2; i++ {
+		tree, _, err := ts.tlog.TreeNew()
+		if err != nil {
+			t.Fatal(err)
+		}
+		treeIDs = append(treeIDs, tree.TreeId)
+		err = ts.blobsPut(tree.TreeId, map[string][]byte{key: b}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	refs, err := ts.blobRefsGet([]string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs[key]) != 2 {
+		t.Fatalf("got %v refs, want 2", len(refs[key]))
+	}
+
+	// Saving the content again for the same record does not add a
+	// reference.
+	err = ts.blobsPut(treeIDs[0], map[string][]byte{key: b}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs, err = ts.blobRefsGet([]string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs[key]) != 2 {
+		t.Fatalf("got %v refs, want 2", len(refs[key]))
+	}
+
+	// Shared content is not deleted
+	err = ts.blobsDel(treeIDs[0], []string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs, err := ts.store.Get([]string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := blobs[key]; !ok {
+		t.Fatalf("shared blob was deleted")
+	}
+	refs, err = ts.blobRefsGet([]string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := hex.EncodeToString(tokenFromTreeID(treeIDs[1]))
+	if len(refs[key]) != 1 || refs[key][0] != token {
+		t.Fatalf("unexpected refs %v", refs[key])
+	}
+
+	// Content that is no longer shared is purged
+	err = ts.blobsDel(treeIDs[1], []string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs, err = ts.store.Get([]string{key, buildBlobRefsKey(key)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 0 {
+		t.Fatalf("unreferenced blob was not deleted")
+	}
+}
+
+func TestBlobKeysMigrate(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.blobkeys.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	// Setup two records that contain the same legacy file blob. The
+	// second record also contains an unvetted file blob.
+	var (
+		treeIDs = make([]int64, 0, 2)
+		keys    = make([][]string, 0, 2)
+		blobs   = make(map[string][]byte, 3)
+	)
+	_, shared := newTestBlob(t, "logo")
+	_, secret := newTestBlob(t, "secret")
+	for i := 0; i < 2; i++ {
+		tree, _, err := ts.tlog.TreeNew()
+		if err != nil {
+			t.Fatal(err)
+		}
+		treeIDs = append(treeIDs, tree.TreeId)
+
+		entries := map[string][]byte{
+			storeKeyNew(false): shared,
+		}
+		if i == 1 {
+			entries[storeKeyNew(true)] = secret
+		}
+		var (
+			leaves    = make([]*trillian.LogLeaf, 0, len(entries))
+			clearText = make(map[string][]byte, len(entries))
+			encrypted = make(map[string][]byte, len(entries))
+			treeKeys  = make([]string, 0, len(entries))
+		)
+		for k, v := range entries {
+			state := backend.StateVetted
+			if storeKeyCleaned(k) != k {
+				state = backend.StateUnvetted
+				encrypted[k] = v
+			} else {
+				clearText[k] = v
+			}
+			ed, err := extraDataEncode(k, dataDescriptorFile, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaves = append(leaves, tlog.NewLogLeaf([]byte(k), ed))
+			treeKeys = append(treeKeys, k)
+			blobs[k] = v
+		}
+		_, _, err = ts.tlog.LeavesAppend(tree.TreeId, leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ts.store.Put(clearText, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(encrypted) > 0 {
+			err = ts.store.Put(encrypted, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		keys = append(keys, treeKeys)
+	}
+
+	// Migrate the legacy blobs. The migration is only performed once.
+	err = ts.blobKeysMigrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ts.blobKeysMigrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The legacy blobs have been replaced by two content addressed
+	// blobs.
+	all := append(append([]string{}, keys[0]...), keys[1]...)
+	legacy, err := ts.store.Get(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy) != 0 {
+		t.Fatalf("got %v legacy blobs, want 0", len(legacy))
+	}
+	content, err := ts.store.Keys(keyPrefixContent, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := ts.store.Keys(keyPrefixEncrypted+keyPrefixContent, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(content) != 1 || len(encrypted) != 1 {
+		t.Fatalf("got %v clear text and %v encrypted content blobs, "+
+			"want 1 and 1", len(content), len(encrypted))
+	}
+
+	// The legacy keys are resolved transparently
+	for i, treeID := range treeIDs {
+		got, err := ts.blobsGet(treeID, keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range keys[i] {
+			if !bytes.Equal(got[k], blobs[k]) {
+				t.Fatalf("blob %v not resolved", k)
+			}
+		}
+	}
+
+	// Deleting the shared blob from one record leaves it intact for
+	// the other record.
+	err = ts.blobsDel(treeIDs[0], keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ts.blobsGet(treeIDs[0], keys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("deleted blob was returned")
+	}
+	got, err = ts.blobsGet(treeIDs[1], keys[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(keys[1]) {
+		t.Fatalf("got %v blobs, want %v", len(got), len(keys[1]))
+	}
+}
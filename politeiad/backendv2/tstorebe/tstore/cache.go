@@ -0,0 +1,66 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import "fmt"
+
+// The cache methods provide the backend with access to the key-value store so
+// that it is able to persist data that is not part of any record, such as the
+// record inventory. Cached data is not appended onto a tlog tree and is not
+// timestamped. The caller is responsible for prefixing the keys so that they
+// do not collide with the keys used by tstore and the plugins.
+
+// CachePut saves the provided key-value pairs to the key-value store. New
+// entries are inserted. Existing entries are updated.
+//
+// This operation is atomic.
+func (t *Tstore) CachePut(blobs map[string][]byte) error {
+	log.Tracef("CachePut: %v blobs", len(blobs))
+
+	err := t.store.Put(blobs, false)
+	if err != nil {
+		return fmt.Errorf("store Put: %v", err)
+	}
+	return nil
+}
+
+// CacheGet returns the blobs from the key-value store for the provided keys.
+// An entry will not exist in the returned map for any blobs that are not
+// found.
+func (t *Tstore) CacheGet(keys []string) (map[string][]byte, error) {
+	log.Tracef("CacheGet: %v", keys)
+
+	blobs, err := t.store.Get(keys)
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	return blobs, nil
+}
+
+// CacheDel deletes the provided keys from the key-value store.
+//
+// This operation is atomic.
+func (t *Tstore) CacheDel(keys []string) error {
+	log.Tracef("CacheDel: %v", keys)
+
+	err := t.store.Del(keys)
+	if err != nil {
+		return fmt.Errorf("store Del: %v", err)
+	}
+	return nil
+}
+
+// CacheKeys returns a page of the key-value store keys that begin with the
+// provided prefix and that sort after the provided after key, sorted in
+// ascending order. A limit of 0 returns all remaining keys.
+func (t *Tstore) CacheKeys(prefix, after string, limit uint32) ([]string, error) {
+	log.Tracef("CacheKeys: %v %v %v", prefix, after, limit)
+
+	keys, err := t.store.Keys(prefix, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("store Keys: %v", err)
+	}
+	return keys, nil
+}
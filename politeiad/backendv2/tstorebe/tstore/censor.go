@@ -0,0 +1,352 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+)
+
+const (
+	// censorshipKey is the key-value store key for the list of censored
+	// files of a record. The "{token}" is replaced with the hex encoded
+	// full length record token.
+	//
+	// The censored files are saved to the kv store instead of being
+	// appended onto the record tree since the tree of a public record
+	// may already be frozen.
+	censorshipKey = "censorship-{token}"
+)
+
+// buildCensorshipKey returns the key-value store key for the censored files
+// of a record.
+func buildCensorshipKey(token []byte) string {
+	return strings.Replace(censorshipKey, "{token}",
+		hex.EncodeToString(token), 1)
+}
+
+// censoredFiles returns the censored files of a record. An empty slice is
+// returned if no files have been censored.
+func (t *Tstore) censoredFiles(token []byte) ([]backend.CensoredFile, error) {
+	key := buildCensorshipKey(token)
+	blobs, err := t.store.Get([]string{key})
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	b, ok := blobs[key]
+	if !ok {
+		return []backend.CensoredFile{}, nil
+	}
+	var cf []backend.CensoredFile
+	err = json.Unmarshal(b, &cf)
+	if err != nil {
+		return nil, err
+	}
+	return cf, nil
+}
+
+// censoredFilesForVersion returns the censored files of the specified record
+// version.
+func (t *Tstore) censoredFilesForVersion(token []byte, version uint32) ([]backend.CensoredFile, error) {
+	all, err := t.censoredFiles(token)
+	if err != nil {
+		return nil, err
+	}
+	cf := make([]backend.CensoredFile, 0, len(all))
+	for _, v := range all {
+		if v.Version == version {
+			cf = append(cf, v)
+		}
+	}
+	return cf, nil
+}
+
+// censoredContentVerify returns a ContentError if any of the provided files
+// contains content that has been censored from the record. Saving censored
+// content again is not allowed. The file leaf of the content already exists in
+// the tree, so the new record version would reference the censored blob that
+// has been deleted.
+func (t *Tstore) censoredContentVerify(token []byte, files []backend.File) error {
+	censored, err := t.censoredFiles(token)
+	if err != nil {
+		return err
+	}
+	if len(censored) == 0 {
+		return nil
+	}
+	digests := make(map[string]struct{}, len(censored))
+	for _, v := range censored {
+		digests[v.Digest] = struct{}{}
+	}
+	for _, v := range files {
+		if _, ok := digests[v.Digest]; ok {
+			return backend.ContentError{
+				ErrorCode:    backend.ContentErrorFilePayloadInvalid,
+				ErrorContext: fmt.Sprintf("%v contains censored content", v.Name),
+			}
+		}
+	}
+	return nil
+}
+
+// censoredFilesSave saves the censored files of a record to the key-value
+// store, overwriting any existing entry.
+func (t *Tstore) censoredFilesSave(token []byte, cf []backend.CensoredFile) error {
+	// Keep the list sorted so that it is returned in a deterministic
+	// order.
+	sort.SliceStable(cf, func(i, j int) bool {
+		if cf[i].Version != cf[j].Version {
+			return cf[i].Version < cf[j].Version
+		}
+		return cf[i].Name < cf[j].Name
+	})
+	b, err := json.Marshal(cf)
+	if err != nil {
+		return err
+	}
+	err = t.kv(treeIDFromToken(token)).Put(map[string][]byte{buildCensorshipKey(token): b}, false)
+	if err != nil {
+		return fmt.Errorf("store Put: %v", err)
+	}
+	return nil
+}
+
+// RecordCensorFiles permanently deletes the blobs of the provided record
+// files from the key-value store. The record tree is not modified. The file
+// leaves and the record indexes remain intact so the digests of the censored
+// files can still be proven to be part of the record.
+//
+// Unchanged files are shared between record versions. Censoring a file will
+// censor it in all vetted versions of the record that contain the same file.
+// The returned censored files include these additional versions. Files that
+// have already been censored are not returned. A backend.ErrNoRecordChanges
+// is returned if all of the provided files have already been censored.
+//
+// The censored files are signed using the provided identity when they are
+// created. The signature is saved along with the censored file.
+//
+// This function can only be called on vetted records.
+func (t *Tstore) RecordCensorFiles(token []byte, files []backend.CensorFile, reason string, fid *identity.FullIdentity) ([]backend.CensoredFile, error) {
+	log.Tracef("RecordCensorFiles: %x %v", token, files)
+
+	// Verify token is valid. The full length token must be used when
+	// writing data.
+	if !tokenIsFullLength(token) {
+		return nil, backend.ErrTokenInvalid
+	}
+
+	// Get the record indexes
+	treeID := treeIDFromToken(token)
+	leaves, err := t.leavesAll(treeID)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := t.recordIndexes(treeID, leaves)
+	if err != nil {
+		return nil, err
+	}
+	if indexes[0].State != backend.StateVetted {
+		return nil, fmt.Errorf("record is not vetted")
+	}
+
+	// Verify the provided files exist and compile the merkle leaf
+	// hashes of the file leaves.
+	merkles := make(map[string]struct{}, len(files))
+	for _, v := range files {
+		idx, err := parseRecordIndex(indexes, v.Version)
+		if err != nil {
+			return nil, backend.ContentError{
+				ErrorCode: backend.ContentErrorFileNameInvalid,
+				ErrorContext: fmt.Sprintf("version %v not found",
+					v.Version),
+			}
+		}
+		m, ok := idx.Files[v.Name]
+		if !ok {
+			return nil, backend.ContentError{
+				ErrorCode: backend.ContentErrorFileNameInvalid,
+				ErrorContext: fmt.Sprintf("%v not found in version %v",
+					v.Name, v.Version),
+			}
+		}
+		merkles[hex.EncodeToString(m)] = struct{}{}
+	}
+
+	// Find all record versions that contain the files being censored
+	type versionFile struct {
+		version uint32
+		name    string
+		merkle  string
+	}
+	var (
+		affected = make([]versionFile, 0, len(files))
+		seen     = make(map[string]struct{}, len(files))
+	)
+	for _, idx := range indexes {
+		for fn, m := range idx.Files {
+			merkle := hex.EncodeToString(m)
+			if _, ok := merkles[merkle]; !ok {
+				continue
+			}
+			id := fmt.Sprintf("%v/%v", idx.Version, fn)
+			if _, ok := seen[id]; ok {
+				// Multiple iterations can share the same version
+				continue
+			}
+			seen[id] = struct{}{}
+			affected = append(affected, versionFile{
+				version: idx.Version,
+				name:    fn,
+				merkle:  merkle,
+			})
+		}
+	}
+
+	// Compile the kv store keys of the file blobs. A vetted file blob
+	// may exist as both an encrypted blob and a clear text blob. Both
+	// need to be deleted.
+	var (
+		keys      = make([]string, 0, len(merkles)*2)
+		clearText = make(map[string]string, len(merkles)) // [merkle]key
+	)
+	for _, v := range leaves {
+		merkle := hex.EncodeToString(v.MerkleLeafHash)
+		if _, ok := merkles[merkle]; !ok {
+			continue
+		}
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, ed.storeKeyNoPrefix())
+		if ed.storeKey() != ed.storeKeyNoPrefix() {
+			keys = append(keys, ed.storeKey())
+		}
+		clearText[merkle] = ed.storeKeyNoPrefix()
+	}
+
+	// Get the existing censored files
+	censored, err := t.censoredFiles(token)
+	if err != nil {
+		return nil, err
+	}
+	isCensored := make(map[string]struct{}, len(censored))
+	for _, v := range censored {
+		isCensored[fmt.Sprintf("%v/%v", v.Version, v.Name)] = struct{}{}
+	}
+
+	// Get the file blobs that have not been censored yet. The file
+	// MIME type and digest must be retrieved before the blob is
+	// deleted.
+	blobKeys := make([]string, 0, len(clearText))
+	for _, v := range affected {
+		id := fmt.Sprintf("%v/%v", v.version, v.name)
+		if _, ok := isCensored[id]; ok {
+			continue
+		}
+		blobKeys = append(blobKeys, clearText[v.merkle])
+	}
+	blobs, err := t.blobsGet(treeID, blobKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the new censored file entries
+	var (
+		timestamp = time.Now().Unix()
+		newFiles  = make([]backend.CensoredFile, 0, len(affected))
+	)
+	for _, v := range affected {
+		id := fmt.Sprintf("%v/%v", v.version, v.name)
+		if _, ok := isCensored[id]; ok {
+			continue
+		}
+		b, ok := blobs[clearText[v.merkle]]
+		if !ok {
+			return nil, fmt.Errorf("file blob not found: %v", id)
+		}
+		f, err := convertFileFromBlob(b)
+		if err != nil {
+			return nil, err
+		}
+		cf := backend.CensoredFile{
+			Version:   v.version,
+			Name:      v.name,
+			MIME:      f.MIME,
+			Digest:    f.Digest,
+			Reason:    reason,
+			Timestamp: timestamp,
+		}
+		msg := backend.CensoredFileMsg(hex.EncodeToString(token), cf)
+		sig := fid.SignMessage([]byte(msg))
+		cf.Signature = hex.EncodeToString(sig[:])
+		newFiles = append(newFiles, cf)
+	}
+
+	// Save the censored files prior to deleting the blobs. If the
+	// deletion fails the call can be retried since the blobs of
+	// previously censored files are always deleted.
+	if len(newFiles) > 0 {
+		err = t.censoredFilesSave(token, append(censored, newFiles...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Delete the file blobs
+	err = t.blobsDel(treeID, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(newFiles) == 0 {
+		return nil, backend.ErrNoRecordChanges
+	}
+
+	log.Debugf("Censored %v files of %x", len(newFiles), token)
+
+	return newFiles, nil
+}
+
+// convertFileFromBlob decodes a file blob that was saved to the key-value
+// store.
+func convertFileFromBlob(b []byte) (*backend.File, error) {
+	be, err := store.Deblob(b)
+	if err != nil {
+		return nil, err
+	}
+	b, err = base64.StdEncoding.DecodeString(be.DataHint)
+	if err != nil {
+		return nil, fmt.Errorf("decode DataHint: %v", err)
+	}
+	var dd store.DataDescriptor
+	err = json.Unmarshal(b, &dd)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal DataHint: %v", err)
+	}
+	if dd.Descriptor != dataDescriptorFile {
+		return nil, fmt.Errorf("unexpected data descriptor: got %v, want %v",
+			dd.Descriptor, dataDescriptorFile)
+	}
+	b, err = base64.StdEncoding.DecodeString(be.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode Data: %v", err)
+	}
+	var f backend.File
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal File: %v", err)
+	}
+	return &f, nil
+}
@@ -0,0 +1,323 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	// diffContextLines is the number of unchanged lines that are included
+	// around each change in a file line diff.
+	diffContextLines = 3
+)
+
+// RecordDiff returns the changes that were made to a record between two
+// record versions. If no to version is provided then the most recent version
+// is used. If no from version is provided then the version prior to the to
+// version is used. Version 1 of a record has no prior version so all of its
+// content is reported as added.
+//
+// The record indexes of the two versions are used to determine which files
+// changed. Only the payloads of the changed files are retrieved.
+func (t *Tstore) RecordDiff(token []byte, fromVersion, toVersion uint32) (*backend.RecordDiff, error) {
+	log.Tracef("RecordDiff: %x %v %v", token, fromVersion, toVersion)
+
+	// Read methods are allowed to use short tokens. Lookup the full
+	// length token.
+	var err error
+	token, err = t.fullLengthToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the record indexes of both versions
+	treeID := treeIDFromToken(token)
+	leaves, err := t.leavesAll(treeID)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := t.recordIndexes(treeID, leaves)
+	if err != nil {
+		return nil, err
+	}
+	toIdx, err := parseRecordIndex(indexes, toVersion)
+	if err != nil {
+		return nil, err
+	}
+	if fromVersion == 0 {
+		fromVersion = toIdx.Version - 1
+	}
+	fromIdx := &recordIndex{}
+	if fromVersion > 0 {
+		fromIdx, err = parseRecordIndex(indexes, fromVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Compile the names of the files that changed
+	changed := make([]string, 0, len(toIdx.Files))
+	for fn, merkle := range toIdx.Files {
+		if m, ok := fromIdx.Files[fn]; !ok || !bytes.Equal(m, merkle) {
+			changed = append(changed, fn)
+		}
+	}
+	for fn := range fromIdx.Files {
+		if _, ok := toIdx.Files[fn]; !ok {
+			changed = append(changed, fn)
+		}
+	}
+
+	// Get the record content of both versions
+	omitAllFiles := len(changed) == 0
+	to, err := t.record(treeID, toIdx.Version, changed, omitAllFiles)
+	if err != nil {
+		return nil, err
+	}
+	var from *backend.Record
+	if fromVersion > 0 {
+		from, err = t.record(treeID, fromIdx.Version, changed, omitAllFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return diffRecords(from, fromIdx.Files, to, toIdx.Files)
+}
+
+// diffRecords returns the changes between two versions of a record. The from
+// record is nil if the to record is being compared against an empty record.
+// The file maps contain the merkle leaf hashes of all files of each version
+// and are used to determine which files changed. The records only need to
+// contain the files that changed.
+func diffRecords(from *backend.Record, fromFiles map[string][]byte, to *backend.Record, toFiles map[string][]byte) (*backend.RecordDiff, error) {
+	var (
+		fromMD      *backend.RecordMetadata
+		fromStreams []backend.MetadataStream
+		fromVersion uint32
+	)
+	if from != nil {
+		fromMD = &from.RecordMetadata
+		fromStreams = from.Metadata
+		fromVersion = from.RecordMetadata.Version
+	}
+
+	// Compile the files of both versions in a deterministic order
+	names := make([]string, 0, len(toFiles))
+	for fn := range toFiles {
+		names = append(names, fn)
+	}
+	for fn := range fromFiles {
+		if _, ok := toFiles[fn]; !ok {
+			names = append(names, fn)
+		}
+	}
+	sort.Strings(names)
+
+	files := make([]backend.FileDiff, 0, len(names))
+	for _, fn := range names {
+		fm, inFrom := fromFiles[fn]
+		tm, inTo := toFiles[fn]
+		fd := backend.FileDiff{
+			Name: fn,
+		}
+		switch {
+		case inFrom && inTo && bytes.Equal(fm, tm):
+			// Not changed
+			continue
+		case !inFrom:
+			fd.Action = backend.DiffActionAdd
+		case !inTo:
+			fd.Action = backend.DiffActionDel
+		default:
+			fd.Action = backend.DiffActionModify
+		}
+
+		var fromPayload, toPayload string
+		if inFrom {
+			f, ok := diffFile(from, fn)
+			fd.FromMIME = f.MIME
+			fd.FromDigest = f.Digest
+			fd.Censored = !ok
+			fromPayload = f.Payload
+		}
+		if inTo {
+			f, ok := diffFile(to, fn)
+			fd.ToMIME = f.MIME
+			fd.ToDigest = f.Digest
+			fd.Censored = fd.Censored || !ok
+			toPayload = f.Payload
+		}
+		if !fd.Censored && isTextMIME(fd.FromMIME) && isTextMIME(fd.ToMIME) {
+			ld, err := lineDiff(fn, fromPayload, toPayload,
+				fromVersion, to.RecordMetadata.Version)
+			if err != nil {
+				return nil, err
+			}
+			fd.LineDiff = ld
+		}
+		files = append(files, fd)
+	}
+
+	return &backend.RecordDiff{
+		FromVersion:    fromVersion,
+		ToVersion:      to.RecordMetadata.Version,
+		RecordMetadata: diffRecordMetadata(fromMD, to.RecordMetadata),
+		Metadata:       diffMetadataStreams(fromStreams, to.Metadata),
+		Files:          files,
+	}, nil
+}
+
+// diffFile returns the provided file of a record. The payload of a censored
+// file is empty. The returned bool is false if the file payload has been
+// censored. This includes the files of a censored record, which are not
+// returned at all.
+func diffFile(r *backend.Record, name string) (backend.File, bool) {
+	for _, v := range r.Files {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	for _, v := range r.CensoredFiles {
+		if v.Name == name {
+			return backend.File{
+				Name:   v.Name,
+				MIME:   v.MIME,
+				Digest: v.Digest,
+			}, false
+		}
+	}
+	return backend.File{Name: name}, false
+}
+
+// isTextMIME returns whether the provided MIME type is a text type. An empty
+// MIME type is considered text since it belongs to a file that does not
+// exist in one of the versions being compared.
+func isTextMIME(mimeType string) bool {
+	return mimeType == "" || strings.HasPrefix(mimeType, "text/")
+}
+
+// lineDiff returns the unified line diff of two base64 encoded file payloads.
+func lineDiff(name, from, to string, fromVersion, toVersion uint32) (string, error) {
+	a, err := base64.StdEncoding.DecodeString(from)
+	if err != nil {
+		return "", err
+	}
+	b, err := base64.StdEncoding.DecodeString(to)
+	if err != nil {
+		return "", err
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(a)),
+		B:        difflib.SplitLines(string(b)),
+		FromFile: name,
+		FromDate: fmt.Sprintf("version %v", fromVersion),
+		ToFile:   name,
+		ToDate:   fmt.Sprintf("version %v", toVersion),
+		Context:  diffContextLines,
+	})
+}
+
+// diffRecordMetadata returns the RecordMetadata fields that differ between two
+// record versions. The from record metadata is nil when comparing against an
+// empty record, in which case all fields are returned.
+func diffRecordMetadata(from *backend.RecordMetadata, to backend.RecordMetadata) []backend.RecordMetadataDiff {
+	var f backend.RecordMetadata
+	if from != nil {
+		f = *from
+	}
+	u := func(v uint32) string {
+		return strconv.FormatUint(uint64(v), 10)
+	}
+	fields := []backend.RecordMetadataDiff{
+		{Field: "version", From: u(f.Version), To: u(to.Version)},
+		{Field: "iteration", From: u(f.Iteration), To: u(to.Iteration)},
+		{Field: "state", From: backend.States[f.State],
+			To: backend.States[to.State]},
+		{Field: "status", From: backend.Statuses[f.Status],
+			To: backend.Statuses[to.Status]},
+		{Field: "timestamp", From: strconv.FormatInt(f.Timestamp, 10),
+			To: strconv.FormatInt(to.Timestamp, 10)},
+		{Field: "merkle", From: f.Merkle, To: to.Merkle},
+	}
+	diffs := make([]backend.RecordMetadataDiff, 0, len(fields))
+	for _, v := range fields {
+		if from == nil {
+			v.From = ""
+		}
+		if v.From != v.To {
+			diffs = append(diffs, v)
+		}
+	}
+	return diffs
+}
+
+// diffMetadataStreams returns the metadata streams that differ between two
+// record versions. The diffs are sorted by plugin ID then stream ID.
+func diffMetadataStreams(from, to []backend.MetadataStream) []backend.MetadataStreamDiff {
+	type streamKey struct {
+		pluginID string
+		streamID uint32
+	}
+	var (
+		fromPayloads = make(map[streamKey]string, len(from))
+		toPayloads   = make(map[streamKey]string, len(to))
+		keys         = make([]streamKey, 0, len(to))
+	)
+	for _, v := range from {
+		k := streamKey{v.PluginID, v.StreamID}
+		fromPayloads[k] = v.Payload
+		keys = append(keys, k)
+	}
+	for _, v := range to {
+		k := streamKey{v.PluginID, v.StreamID}
+		toPayloads[k] = v.Payload
+		if _, ok := fromPayloads[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].pluginID != keys[j].pluginID {
+			return keys[i].pluginID < keys[j].pluginID
+		}
+		return keys[i].streamID < keys[j].streamID
+	})
+
+	diffs := make([]backend.MetadataStreamDiff, 0, len(keys))
+	for _, k := range keys {
+		fp, inFrom := fromPayloads[k]
+		tp, inTo := toPayloads[k]
+		var action backend.DiffActionT
+		switch {
+		case inFrom && inTo && fp == tp:
+			// Not changed
+			continue
+		case !inFrom:
+			action = backend.DiffActionAdd
+		case !inTo:
+			action = backend.DiffActionDel
+		default:
+			action = backend.DiffActionModify
+		}
+		diffs = append(diffs, backend.MetadataStreamDiff{
+			Action:      action,
+			PluginID:    k.pluginID,
+			StreamID:    k.streamID,
+			FromPayload: fp,
+			ToPayload:   tp,
+		})
+	}
+
+	return diffs
+}
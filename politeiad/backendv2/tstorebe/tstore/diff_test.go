@@ -0,0 +1,173 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+func TestDiffRecords(t *testing.T) {
+	textFile := func(name, payload string) backend.File {
+		return backend.File{
+			Name:    name,
+			MIME:    "text/plain; charset=utf-8",
+			Digest:  payload,
+			Payload: base64.StdEncoding.EncodeToString([]byte(payload)),
+		}
+	}
+	var (
+		from = &backend.Record{
+			RecordMetadata: backend.RecordMetadata{
+				Version:   1,
+				Iteration: 1,
+				State:     backend.StateVetted,
+				Status:    backend.StatusPublic,
+				Merkle:    "aa",
+			},
+			Metadata: []backend.MetadataStream{
+				{PluginID: "usermd", StreamID: 1, Payload: "a"},
+				{PluginID: "usermd", StreamID: 2, Payload: "b"},
+			},
+			Files: []backend.File{
+				textFile("index.md", "line 1\nline 2\n"),
+				textFile("removed.md", "removed\n"),
+			},
+		}
+		fromFiles = map[string][]byte{
+			"index.md":     {0x01},
+			"removed.md":   {0x02},
+			"unchanged.md": {0x03},
+		}
+		to = &backend.Record{
+			RecordMetadata: backend.RecordMetadata{
+				Version:   2,
+				Iteration: 2,
+				State:     backend.StateVetted,
+				Status:    backend.StatusPublic,
+				Merkle:    "bb",
+			},
+			Metadata: []backend.MetadataStream{
+				{PluginID: "usermd", StreamID: 1, Payload: "a"},
+				{PluginID: "usermd", StreamID: 3, Payload: "c"},
+			},
+			Files: []backend.File{
+				textFile("index.md", "line 1\nline 2 edited\n"),
+				{
+					Name:    "image.png",
+					MIME:    "image/png",
+					Digest:  "cc",
+					Payload: "",
+				},
+			},
+		}
+		toFiles = map[string][]byte{
+			"index.md":     {0x04},
+			"image.png":    {0x05},
+			"unchanged.md": {0x03},
+		}
+	)
+
+	d, err := diffRecords(from, fromFiles, to, toFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the record metadata diff
+	wantRM := []backend.RecordMetadataDiff{
+		{Field: "version", From: "1", To: "2"},
+		{Field: "iteration", From: "1", To: "2"},
+		{Field: "merkle", From: "aa", To: "bb"},
+	}
+	if !reflect.DeepEqual(d.RecordMetadata, wantRM) {
+		t.Fatalf("got record metadata diff %+v, want %+v",
+			d.RecordMetadata, wantRM)
+	}
+
+	// Verify the metadata stream diff
+	wantMD := []backend.MetadataStreamDiff{
+		{
+			Action:      backend.DiffActionDel,
+			PluginID:    "usermd",
+			StreamID:    2,
+			FromPayload: "b",
+		},
+		{
+			Action:    backend.DiffActionAdd,
+			PluginID:  "usermd",
+			StreamID:  3,
+			ToPayload: "c",
+		},
+	}
+	if !reflect.DeepEqual(d.Metadata, wantMD) {
+		t.Fatalf("got metadata diff %+v, want %+v", d.Metadata, wantMD)
+	}
+
+	// Verify the file diff
+	wantActions := map[string]backend.DiffActionT{
+		"image.png":  backend.DiffActionAdd,
+		"index.md":   backend.DiffActionModify,
+		"removed.md": backend.DiffActionDel,
+	}
+	if len(d.Files) != len(wantActions) {
+		t.Fatalf("got %v file diffs, want %v", len(d.Files), len(wantActions))
+	}
+	for _, v := range d.Files {
+		if v.Action != wantActions[v.Name] {
+			t.Errorf("%v: got action %v, want %v",
+				v.Name, v.Action, wantActions[v.Name])
+		}
+		switch v.Name {
+		case "image.png":
+			if v.LineDiff != "" {
+				t.Errorf("got line diff for a binary file")
+			}
+		case "index.md":
+			if !strings.Contains(v.LineDiff, "-line 2\n+line 2 edited\n") {
+				t.Errorf("unexpected line diff:\n%v", v.LineDiff)
+			}
+		case "removed.md":
+			if !strings.Contains(v.LineDiff, "-removed\n") {
+				t.Errorf("unexpected line diff:\n%v", v.LineDiff)
+			}
+		}
+	}
+
+	// A censored file is reported without a line diff
+	to.Files = to.Files[1:]
+	to.CensoredFiles = []backend.CensoredFile{
+		{Version: 2, Name: "index.md", MIME: "text/plain; charset=utf-8"},
+	}
+	d, err = diffRecords(from, fromFiles, to, toFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range d.Files {
+		if v.Name != "index.md" {
+			continue
+		}
+		if !v.Censored || v.LineDiff != "" {
+			t.Fatalf("got censored file diff %+v", v)
+		}
+	}
+
+	// Comparing against an empty record reports all content as added
+	d, err = diffRecords(nil, nil, from, fromFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.FromVersion != 0 || len(d.Metadata) != 2 {
+		t.Fatalf("got diff %+v, want all content added", d)
+	}
+	for _, v := range d.Files {
+		if v.Action != backend.DiffActionAdd {
+			t.Fatalf("got file diff %+v, want add", v)
+		}
+	}
+}
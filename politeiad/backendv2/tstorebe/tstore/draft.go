@@ -0,0 +1,121 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// draftKey is the key-value store key for a draft record. The
+	// "{token}" is replaced with the hex encoded draft token.
+	//
+	// Drafts are saved to the kv store instead of a tlog tree since
+	// they are not timestamped. Drafts are always encrypted.
+	draftKey = keyPrefixEncrypted + "draft-{token}"
+)
+
+// buildDraftKey returns the key-value store key for a draft record.
+func buildDraftKey(token []byte) string {
+	return strings.Replace(draftKey, "{token}",
+		hex.EncodeToString(token), 1)
+}
+
+// DraftToken returns a new random draft token. Draft tokens are the same
+// length as full length record tokens, but they do not correspond to a tlog
+// tree.
+func (t *Tstore) DraftToken() ([]byte, error) {
+	log.Tracef("DraftToken")
+
+	for i := 0; i < 10; i++ {
+		n, err := util.RandomUint64()
+		if err != nil {
+			return nil, err
+		}
+		token := tokenFromTreeID(int64(n))
+		blobs, err := t.store.Get([]string{buildDraftKey(token)})
+		if err != nil {
+			return nil, fmt.Errorf("store Get: %v", err)
+		}
+		if _, ok := blobs[buildDraftKey(token)]; ok {
+			// Token collision. Try again.
+			continue
+		}
+		return token, nil
+	}
+
+	return nil, fmt.Errorf("failed to find a unique draft token")
+}
+
+// DraftSave saves a draft record to the key-value store, overwriting any
+// existing version of the draft.
+func (t *Tstore) DraftSave(r backend.Record) error {
+	log.Tracef("DraftSave: %v", r.RecordMetadata.Token)
+
+	token, err := hex.DecodeString(r.RecordMetadata.Token)
+	if err != nil {
+		return err
+	}
+	if !tokenIsFullLength(token) {
+		return backend.ErrTokenInvalid
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	err = t.store.Put(map[string][]byte{buildDraftKey(token): b}, true)
+	if err != nil {
+		return fmt.Errorf("store Put: %v", err)
+	}
+
+	return nil
+}
+
+// Draft returns a draft record. A ErrRecordNotFound error is returned if the
+// draft does not exist.
+func (t *Tstore) Draft(token []byte) (*backend.Record, error) {
+	log.Tracef("Draft: %x", token)
+
+	if !tokenIsFullLength(token) {
+		return nil, backend.ErrRecordNotFound
+	}
+	k := buildDraftKey(token)
+	blobs, err := t.store.Get([]string{k})
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	b, ok := blobs[k]
+	if !ok {
+		return nil, backend.ErrRecordNotFound
+	}
+	var r backend.Record
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// DraftDel deletes a draft record from the key-value store.
+func (t *Tstore) DraftDel(token []byte) error {
+	log.Tracef("DraftDel: %x", token)
+
+	if !tokenIsFullLength(token) {
+		return backend.ErrTokenInvalid
+	}
+	err := t.store.Del([]string{buildDraftKey(token)})
+	if err != nil {
+		return fmt.Errorf("store Del: %v", err)
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ package tstore
 
 import (
 	"encoding/json"
+	"strings"
 
 	backend "github.com/decred/politeia/politeiad/backendv2"
 	"github.com/google/uuid"
@@ -85,8 +86,10 @@ func extraDataDecode(b []byte) (*extraData, error) {
 	return &ed, nil
 }
 
-// storeKeyNew returns a new key for the key-value store. If the data is
-// encrypted the key is prefixed.
+// storeKeyNew returns a new random key for the key-value store. It is used for
+// the blobs that are not record content, such as the record indexes, the
+// anchors and the plugin data. Record content blobs use content addressed
+// keys, see storeKeyContent. If the data is encrypted the key is prefixed.
 func storeKeyNew(encrypt bool) string {
 	k := uuid.New().String()
 	if encrypt {
@@ -98,7 +101,5 @@ func storeKeyNew(encrypt bool) string {
 // storeKeyCleaned strips the key-value store key of the encryption prefix if
 // one is present.
 func storeKeyCleaned(key string) string {
-	// A uuid string is 36 bytes. Return the last 36 bytes of the
-	// string. This will strip the prefix if it exists.
-	return key[len(key)-36:]
+	return strings.TrimPrefix(key, keyPrefixEncrypted)
 }
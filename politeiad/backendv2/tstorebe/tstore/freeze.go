@@ -84,7 +84,7 @@ func (t *Tstore) treeShouldBeFrozen(tree *trillian.Tree) (bool, error) {
 	if len(leaves) == 0 {
 		return false, nil
 	}
-	r, err := t.recordIndexLatest(leaves)
+	r, err := t.recordIndexLatest(tree.TreeId, leaves)
 	switch {
 	case errors.Is(err, backend.ErrRecordNotFound):
 		// A record index doesn't exist on this tree
@@ -0,0 +1,545 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/google/trillian"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	// fsckBlobsPageSize is the number of key-value store keys that are
+	// listed at once when checking for orphaned blobs.
+	fsckBlobsPageSize = 1000
+)
+
+// Fsck performs a filesystem check on the tstore. The anchoring and freezing
+// of trees is only performed when the check is not a dry run. The plugin
+// fscks are run after the tstore checks and are provided with the tokens of
+// all records.
+//
+// The recordLock function must lock the provided record against concurrent
+// writes and return a function that releases the lock. It is passed to the
+// plugins so that they can safely repair the data of a record while the
+// backend is online.
+func (t *Tstore) Fsck(allTokens [][]byte, opts backend.FsckOpts, recordLock func(token []byte) func()) ([]backend.FsckIssue, error) {
+	log.Infof("Starting tstore fsck")
+
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string, int, int) {}
+	}
+
+	if !opts.DryRun {
+		err := t.anchorTrees()
+		if err != nil {
+			// Anchoring trees relies on external anchor providers.
+			// Don't allow a provider error to stop execution. The
+			// anchoring process will be kicked off again by the
+			// tstore cron job at a later time.
+			log.Errorf("anchorTrees: %v", err)
+		}
+		err = t.freezeTrees()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Remove the blobs that were left behind by interrupted writes
+	log.Infof("Verifying the blob references")
+
+	issues, err := t.fsckBlobs(allTokens, opts.DryRun, recordLock,
+		func(done, total int) {
+			progress("blobs", done, total)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the plugin fscks
+	for _, pluginID := range t.pluginIDs() {
+		p, _ := t.plugin(pluginID)
+
+		log.Infof("Starting %v plugin fsck", pluginID)
+
+		stage := "plugin " + pluginID
+		pi, err := p.client.Fsck(allTokens, plugins.FsckOpts{
+			DryRun:     opts.DryRun,
+			RecordLock: recordLock,
+			Progress: func(done, total int) {
+				progress(stage, done, total)
+			},
+		})
+		if err != nil {
+			return nil, errors.Errorf("plugin %v fsck: %v", pluginID, err)
+		}
+		for _, v := range pi {
+			v.PluginID = pluginID
+			issues = append(issues, v)
+		}
+	}
+
+	return issues, nil
+}
+
+// FsckRecord verifies that the blobs that make up a record exist in the
+// key-value store. This includes the record indexes, the anchors, and the
+// record content of all record versions. Missing blobs can not be recreated
+// so they are only reported.
+//
+// The blobs of the files of a censored record and the blobs of individually
+// censored files are deleted on purpose and are not reported.
+func (t *Tstore) FsckRecord(token []byte) ([]backend.FsckIssue, error) {
+	log.Tracef("FsckRecord: %x", token)
+
+	treeID := treeIDFromToken(token)
+	leaves, err := t.leavesAll(treeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the record index and anchor blobs. The record content
+	// can't be checked if a record index is missing.
+	required := make([]*trillian.LogLeaf, 0, len(leaves))
+	for _, v := range leaves {
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil {
+			return nil, err
+		}
+		switch ed.Desc {
+		case dataDescriptorRecordIndex, dataDescriptorAnchor:
+			required = append(required, v)
+		}
+	}
+	issues, err := t.fsckLeaves(token, required)
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) > 0 {
+		return issues, nil
+	}
+
+	// Compile the record content that is referenced by the record
+	// indexes. The merkle leaf hashes of the files that may have been
+	// deleted are tracked separately.
+	indexes, err := t.recordIndexes(treeID, leaves)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		content = make(map[string]struct{}, 256)
+		files   = make(map[string]struct{}, 256)
+	)
+	for _, idx := range indexes {
+		content[hex.EncodeToString(idx.RecordMetadata)] = struct{}{}
+		for _, streams := range idx.Metadata {
+			for _, v := range streams {
+				content[hex.EncodeToString(v)] = struct{}{}
+			}
+		}
+		for _, v := range idx.Files {
+			files[hex.EncodeToString(v)] = struct{}{}
+		}
+	}
+
+	// The files of a censored record are deleted. The record status
+	// can only be read if the record metadata blob exists. A missing
+	// record metadata blob is reported below.
+	latest := indexes[len(indexes)-1]
+	rm, err := t.fsckRecordMetadata(token, leaves, latest.RecordMetadata)
+	if err != nil {
+		return nil, err
+	}
+	if rm != nil && rm.Status == backend.StatusCensored {
+		files = map[string]struct{}{}
+	}
+
+	// Individually censored files are deleted
+	cf, err := t.censoredFiles(token)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range cf {
+		for _, idx := range indexes {
+			if idx.Version != v.Version {
+				continue
+			}
+			if m, ok := idx.Files[v.Name]; ok {
+				delete(files, hex.EncodeToString(m))
+			}
+		}
+	}
+
+	// Verify the record content blobs
+	required = required[:0]
+	for _, v := range leaves {
+		m := hex.EncodeToString(v.MerkleLeafHash)
+		_, isContent := content[m]
+		_, isFile := files[m]
+		if isContent || isFile {
+			required = append(required, v)
+		}
+	}
+
+	return t.fsckLeaves(token, required)
+}
+
+// fsckLeaves returns a FsckIssueBlobMissing issue for each of the provided
+// leaves whose blob does not exist in the key-value store.
+//
+// The blobs of a record that has been made public exist as plain text blobs
+// even though the leaves still point to the encrypted blobs. A blob is
+// considered to exist if either of its keys is found. The blobs that are
+// listed in the archive manifest of the record are considered to exist
+// without being retrieved from the cold store.
+func (t *Tstore) fsckLeaves(token []byte, leaves []*trillian.LogLeaf) ([]backend.FsckIssue, error) {
+	if len(leaves) == 0 {
+		return nil, nil
+	}
+	var (
+		eds  = make([]*extraData, 0, len(leaves))
+		keys = make([]string, 0, len(leaves)*2)
+	)
+	for _, v := range leaves {
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil {
+			return nil, err
+		}
+		eds = append(eds, ed)
+		keys = append(keys, ed.storeKey())
+		if ed.storeKey() != ed.storeKeyNoPrefix() {
+			keys = append(keys, ed.storeKeyNoPrefix())
+		}
+	}
+	blobs, err := t.blobsGetHot(keys)
+	if err != nil {
+		return nil, err
+	}
+	m, err := t.archiveManifestGet(token)
+	if err != nil {
+		return nil, err
+	}
+	if m == nil {
+		m = &archiveManifest{}
+	}
+
+	var issues []backend.FsckIssue
+	for i, ed := range eds {
+		_, ok1 := blobs[ed.storeKey()]
+		_, ok2 := blobs[ed.storeKeyNoPrefix()]
+		_, ok3 := m.Blobs[ed.storeKeyNoPrefix()]
+		if ok1 || ok2 || ok3 {
+			continue
+		}
+		issues = append(issues, backend.FsckIssue{
+			Type:  backend.FsckIssueBlobMissing,
+			Token: hex.EncodeToString(token),
+			Description: fmt.Sprintf("%v blob %v of leaf %v not found",
+				ed.Desc, ed.Key, leaves[i].LeafIndex),
+		})
+	}
+
+	return issues, nil
+}
+
+// fsckRecordMetadata returns the record metadata for the provided merkle leaf
+// hash. Nil is returned if the record metadata blob does not exist.
+func (t *Tstore) fsckRecordMetadata(token []byte, leaves []*trillian.LogLeaf, merkle []byte) (*backend.RecordMetadata, error) {
+	var ed *extraData
+	for _, v := range leaves {
+		if hex.EncodeToString(v.MerkleLeafHash) != hex.EncodeToString(merkle) {
+			continue
+		}
+		var err error
+		ed, err = extraDataDecode(v.ExtraData)
+		if err != nil {
+			return nil, err
+		}
+		break
+	}
+	if ed == nil {
+		return nil, fmt.Errorf("record metadata leaf %x not found", merkle)
+	}
+
+	blobs, err := t.blobsGet(treeIDFromToken(token),
+		[]string{ed.storeKeyNoPrefix(), ed.storeKey()})
+	if err != nil {
+		return nil, err
+	}
+	b, ok := blobs[ed.storeKeyNoPrefix()]
+	if !ok {
+		b, ok = blobs[ed.storeKey()]
+	}
+	if !ok {
+		return nil, nil
+	}
+	be, err := store.Deblob(b)
+	if err != nil {
+		return nil, err
+	}
+	data, err := base64.StdEncoding.DecodeString(be.Data)
+	if err != nil {
+		return nil, err
+	}
+	var rm backend.RecordMetadata
+	err = json.Unmarshal(data, &rm)
+	if err != nil {
+		return nil, err
+	}
+	return &rm, nil
+}
+
+// fsckBlobs removes the blobs, and the references of records to content
+// addressed blobs, that are not referenced by the leaves of any tree. A write
+// saves its blobs before it appends the leaves that reference them and the
+// journal of a transaction is only kept in memory, so these are left behind
+// when politeiad stops in the middle of a write. They are never read since the
+// trees are the source of truth, but an orphaned reference prevents a content
+// blob from being deleted once the records that use it are censored.
+//
+// The keys are listed before the trees are read. Each tree is read while its
+// record lock is held, and the anchor saves and the service tree appends that
+// are in progress are waited on beforehand. Any write that saved a listed blob
+// has therefore appended its leaves by the time that its tree is read. Blobs
+// that are saved after the keys have been listed are not inspected, and
+// neither are the references of records that are not part of the provided
+// tokens, such as records that were created after the tokens were retrieved.
+//
+// A reference is only ever removed. A record whose leaves reference a blob
+// that does not list the record is not repaired since the reference may have
+// been released on purpose, e.g. by censoring the record.
+func (t *Tstore) fsckBlobs(allTokens [][]byte, dryRun bool, recordLock func(token []byte) func(), progress func(done, total int)) ([]backend.FsckIssue, error) {
+	// List the blobs, the reference entries and the link entries
+	var (
+		blobs    = make(map[string]struct{}, 1024) // Orphan candidates
+		refKeys  = make([]string, 0, 1024)
+		linkKeys = make([]string, 0, 64)
+
+		refsPrefix = buildBlobRefsKey("")
+		linkPrefix = buildBlobLinkKey("")
+
+		after string
+	)
+	for {
+		keys, err := t.store.Keys("", after, fsckBlobsPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("store Keys: %v", err)
+		}
+		for _, k := range keys {
+			switch {
+			case strings.HasPrefix(k, refsPrefix):
+				refKeys = append(refKeys, strings.TrimPrefix(k, refsPrefix))
+			case strings.HasPrefix(k, linkPrefix):
+				linkKeys = append(linkKeys, strings.TrimPrefix(k, linkPrefix))
+			case isContentKey(k) || isRandomKey(k):
+				blobs[k] = struct{}{}
+			}
+		}
+		if len(keys) < fsckBlobsPageSize {
+			break
+		}
+		after = keys[len(keys)-1]
+	}
+	links, err := t.blobLinksGet(linkKeys)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := t.blobRefsGet(refKeys)
+	if err != nil {
+		return nil, err
+	}
+	byToken := make(map[string][]string, len(allTokens)) // [token][]key
+	for k, tokens := range refs {
+		for _, v := range tokens {
+			byToken[v] = append(byToken[v], k)
+		}
+	}
+	records := make(map[string]struct{}, len(allTokens))
+	for _, v := range allTokens {
+		records[hex.EncodeToString(v)] = struct{}{}
+	}
+
+	// Wait for the writes that append leaves without holding a record
+	// lock to finish.
+	t.anchorSaveMtx.Lock()
+	t.anchorSaveMtx.Unlock()
+	t.serviceMtx.Lock()
+	t.serviceMtx.Unlock()
+
+	// Walk the trees
+	trees, err := t.tlog.TreesAll()
+	if err != nil {
+		return nil, fmt.Errorf("TreesAll: %v", err)
+	}
+	var (
+		issues   []backend.FsckIssue
+		released = make(map[string][]string, 64) // [key][]token
+	)
+	for i, tree := range trees {
+		token := tokenFromTreeID(tree.TreeId)
+		unlock := recordLock(token)
+		ri, err := t.fsckTreeBlobs(token, blobs, links,
+			byToken[hex.EncodeToString(token)], records, released, dryRun)
+		unlock()
+		if err != nil {
+			return nil, fmt.Errorf("tree %v: %v", tree.TreeId, err)
+		}
+		issues = append(issues, ri...)
+
+		progress(i+1, len(trees))
+	}
+
+	// The remaining blobs are not referenced by any tree. The content
+	// addressed blobs are only deleted if no record references them,
+	// which is verified while the blobs mutex is held so that the blobs
+	// are not deleted while a record is adding a reference to them.
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	keys := make([]string, 0, len(blobs))
+	for k := range blobs {
+		if isContentKey(k) {
+			keys = append(keys, k)
+		}
+	}
+	refs, err = t.blobRefsGet(keys)
+	if err != nil {
+		return nil, err
+	}
+	orphaned := make([]string, 0, len(blobs))
+	for k := range blobs {
+		var tokens []string
+		for _, v := range refs[k] {
+			if !refsContain(released[k], v) {
+				tokens = append(tokens, v)
+			}
+		}
+		if len(tokens) > 0 {
+			continue
+		}
+		orphaned = append(orphaned, k)
+	}
+	if len(orphaned) == 0 {
+		return issues, nil
+	}
+	if !dryRun {
+		err = t.store.Del(orphaned)
+		if err != nil {
+			return nil, fmt.Errorf("store Del: %v", err)
+		}
+	}
+	for _, k := range orphaned {
+		issues = append(issues, backend.FsckIssue{
+			Type:        backend.FsckIssueBlobOrphaned,
+			Description: fmt.Sprintf("blob %v is not referenced by any tree", k),
+			Repaired:    !dryRun,
+		})
+	}
+
+	return issues, nil
+}
+
+// fsckTreeBlobs removes the blobs that are referenced by the leaves of a tree
+// from the provided orphan candidates. The references of the record to the
+// content addressed blobs that its leaves do not reference are released and
+// are added to the released references.
+//
+// The record lock must be held by the caller.
+func (t *Tstore) fsckTreeBlobs(token []byte, blobs map[string]struct{}, links map[string]string, refKeys []string, records map[string]struct{}, released map[string][]string, dryRun bool) ([]backend.FsckIssue, error) {
+	leaves, err := t.leavesAll(treeIDFromToken(token))
+	if errors.Is(err, backend.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Compile the keys that are referenced by the leaves. Both the
+	// encrypted and the clear text key of a blob are considered to be
+	// referenced since the blobs of a record that has been made public
+	// are saved under the clear text key.
+	referenced := make(map[string]struct{}, len(leaves)*2)
+	for _, v := range leaves {
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil {
+			return nil, err
+		}
+		key := storeKeyCleaned(ed.Key)
+		for _, k := range []string{key, keyPrefixEncrypted + key} {
+			referenced[k] = struct{}{}
+			if l, ok := links[k]; ok {
+				referenced[l] = struct{}{}
+			}
+		}
+	}
+	for k := range referenced {
+		delete(blobs, k)
+	}
+
+	// Release the references of the record that its leaves do not
+	// reference. The references of records that were not part of the
+	// inventory are not checked.
+	tokenHex := hex.EncodeToString(token)
+	if _, ok := records[tokenHex]; !ok {
+		return nil, nil
+	}
+	stale := make([]string, 0, len(refKeys))
+	for _, k := range refKeys {
+		if _, ok := referenced[k]; !ok {
+			stale = append(stale, k)
+		}
+	}
+	if len(stale) == 0 {
+		return nil, nil
+	}
+	if !dryRun {
+		t.blobsMtx.Lock()
+		refs, err := t.blobRefsGet(stale)
+		if err == nil {
+			updated := make(map[string][]string, len(stale))
+			for _, k := range stale {
+				updated[k] = refsRemove(refs[k], tokenHex)
+			}
+			err = t.blobRefsSave(updated)
+		}
+		t.blobsMtx.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	issues := make([]backend.FsckIssue, 0, len(stale))
+	for _, k := range stale {
+		released[k] = append(released[k], tokenHex)
+		issues = append(issues, backend.FsckIssue{
+			Type:  backend.FsckIssueBlobOrphaned,
+			Token: tokenHex,
+			Description: fmt.Sprintf("blob %v lists the record, but is "+
+				"not referenced by the record tree", k),
+			Repaired: !dryRun,
+		})
+	}
+
+	return issues, nil
+}
+
+// isRandomKey returns whether the provided key-value store key is a random
+// key that was created using storeKeyNew.
+func isRandomKey(key string) bool {
+	k := storeKeyCleaned(key)
+	if len(k) != 36 {
+		return false
+	}
+	_, err := uuid.Parse(k)
+	return err == nil
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/util"
+)
+
+// RecordHistory returns an entry for every iteration of a record, sorted from
+// oldest to newest. The history of an unvetted record is not returned once
+// the record has been made vetted.
+//
+// The record indexes are used to determine which metadata streams were added
+// or modified by each iteration. Only the record metadata and the payloads of
+// these metadata streams are retrieved. Record files are never retrieved.
+func (t *Tstore) RecordHistory(token []byte) ([]backend.RecordHistoryEntry, error) {
+	log.Tracef("RecordHistory: %x", token)
+
+	// Read methods are allowed to use short tokens. Lookup the full
+	// length token.
+	var err error
+	token, err = t.fullLengthToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the record indexes
+	treeID := treeIDFromToken(token)
+	leaves, err := t.leavesAll(treeID)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := t.recordIndexes(treeID, leaves)
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) == 0 {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// Compile the merkle leaf hashes of the record content that is
+	// part of the history.
+	var (
+		streams = historyStreams(indexes)
+		merkles = make(map[string]struct{}, len(indexes)*2)
+	)
+	for i, idx := range indexes {
+		merkles[hex.EncodeToString(idx.RecordMetadata)] = struct{}{}
+		for _, v := range streams[i] {
+			merkles[hex.EncodeToString(v)] = struct{}{}
+		}
+	}
+
+	// Walk the tree and extract the record content keys. All indexes
+	// share the same record state. If the record is vetted the content
+	// may exist in the store as both an encrypted blob and a plain text
+	// blob. Always pull the plain text blob.
+	var (
+		vetted = indexes[0].State == backend.StateVetted
+		keys   = make([]string, 0, len(merkles))
+		keyMap = make(map[string]string, len(merkles)) // [key]merkle
+	)
+	for _, v := range leaves {
+		m := hex.EncodeToString(v.MerkleLeafHash)
+		if _, ok := merkles[m]; !ok {
+			continue
+		}
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil {
+			return nil, err
+		}
+		key := ed.storeKey()
+		if vetted {
+			key = ed.storeKeyNoPrefix()
+		}
+		keys = append(keys, key)
+		keyMap[key] = m
+	}
+
+	// Get the record content from the store
+	blobs, err := t.blobsGet(treeID, keys)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		rms = make(map[string]backend.RecordMetadata, len(indexes))
+		mds = make(map[string]backend.MetadataStream, len(merkles))
+	)
+	for key, b := range blobs {
+		be, err := store.Deblob(b)
+		if err != nil {
+			return nil, err
+		}
+		desc, data, err := decodeHistoryBlobEntry(*be)
+		if err != nil {
+			return nil, err
+		}
+		m := keyMap[key]
+		switch desc {
+		case dataDescriptorRecordMetadata:
+			var rm backend.RecordMetadata
+			err = json.Unmarshal(data, &rm)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal RecordMetadata: %v", err)
+			}
+			rms[m] = rm
+		case dataDescriptorMetadataStream:
+			var ms backend.MetadataStream
+			err = json.Unmarshal(data, &ms)
+			if err != nil {
+				return nil, fmt.Errorf("unmarshal MetadataStream: %v", err)
+			}
+			mds[m] = ms
+		default:
+			return nil, fmt.Errorf("invalid descriptor %v", desc)
+		}
+	}
+
+	// Compile the history entries
+	entries := make([]backend.RecordHistoryEntry, 0, len(indexes))
+	for i, idx := range indexes {
+		rm, ok := rms[hex.EncodeToString(idx.RecordMetadata)]
+		if !ok {
+			return nil, fmt.Errorf("record metadata not found for "+
+				"iteration %v", idx.Iteration)
+		}
+		metadata := make([]backend.MetadataStream, 0, len(streams[i]))
+		for _, v := range streams[i] {
+			ms, ok := mds[hex.EncodeToString(v)]
+			if !ok {
+				return nil, fmt.Errorf("metadata stream %x not found for "+
+					"iteration %v", v, idx.Iteration)
+			}
+			metadata = append(metadata, ms)
+		}
+		sort.Slice(metadata, func(i, j int) bool {
+			if metadata[i].PluginID != metadata[j].PluginID {
+				return metadata[i].PluginID < metadata[j].PluginID
+			}
+			return metadata[i].StreamID < metadata[j].StreamID
+		})
+		e := backend.RecordHistoryEntry{
+			RecordMetadata: rm,
+			Metadata:       metadata,
+		}
+
+		// The record metadata is saved for every iteration so its
+		// anchor is the anchor of the iteration.
+		a, err := t.anchorForLeaf(treeID, idx.RecordMetadata, leaves)
+		switch {
+		case errors.Is(err, errAnchorNotFound):
+			// Not anchored yet
+		case err != nil:
+			return nil, fmt.Errorf("anchorForLeaf %v: %v", idx.Iteration, err)
+		default:
+			tas, err := a.timestampAnchors()
+			if err != nil {
+				return nil, err
+			}
+			if len(tas) > 0 {
+				e.Anchored = true
+				e.AnchorTxID = tas[0].TxID
+			}
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// historyStreams returns the merkle leaf hashes of the metadata streams that
+// were added or modified by each of the provided record indexes. The indexes
+// must be sorted by iteration. All metadata streams of the first index are
+// considered to have been added.
+func historyStreams(indexes []recordIndex) [][][]byte {
+	var (
+		streams = make([][][]byte, 0, len(indexes))
+		prev    map[string]map[uint32][]byte
+	)
+	for _, idx := range indexes {
+		changed := make([][]byte, 0, 8)
+		for pluginID, s := range idx.Metadata {
+			for streamID, merkle := range s {
+				m, ok := prev[pluginID][streamID]
+				if ok && bytes.Equal(m, merkle) {
+					continue
+				}
+				changed = append(changed, merkle)
+			}
+		}
+		streams = append(streams, changed)
+		prev = idx.Metadata
+	}
+	return streams
+}
+
+// decodeHistoryBlobEntry decodes a record content blob entry and returns its
+// data descriptor and the verified data.
+func decodeHistoryBlobEntry(be store.BlobEntry) (string, []byte, error) {
+	b, err := base64.StdEncoding.DecodeString(be.DataHint)
+	if err != nil {
+		return "", nil, fmt.Errorf("decode DataHint: %v", err)
+	}
+	var dd store.DataDescriptor
+	err = json.Unmarshal(b, &dd)
+	if err != nil {
+		return "", nil, fmt.Errorf("unmarshal DataHint: %v", err)
+	}
+	if dd.Type != store.DataTypeStructure {
+		return "", nil, fmt.Errorf("invalid data type; got %v, want %v",
+			dd.Type, store.DataTypeStructure)
+	}
+	b, err = base64.StdEncoding.DecodeString(be.Data)
+	if err != nil {
+		return "", nil, fmt.Errorf("decode Data: %v", err)
+	}
+	digest, err := hex.DecodeString(be.Digest)
+	if err != nil {
+		return "", nil, fmt.Errorf("decode Hash: %v", err)
+	}
+	if !bytes.Equal(util.Digest(b), digest) {
+		return "", nil, fmt.Errorf("data is not coherent; got %x, want %x",
+			util.Digest(b), digest)
+	}
+	return dd.Descriptor, b, nil
+}
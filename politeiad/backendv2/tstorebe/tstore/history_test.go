@@ -0,0 +1,71 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHistoryStreams(t *testing.T) {
+	indexes := []recordIndex{
+		{
+			Iteration: 1,
+			Metadata: map[string]map[uint32][]byte{
+				"usermd": {1: {0x01}, 2: {0x02}},
+			},
+		},
+		{
+			// Status change
+			Iteration: 2,
+			Metadata: map[string]map[uint32][]byte{
+				"usermd": {1: {0x01}, 2: {0x03}},
+			},
+		},
+		{
+			// File only update
+			Iteration: 3,
+			Metadata: map[string]map[uint32][]byte{
+				"usermd": {1: {0x01}, 2: {0x03}},
+			},
+		},
+		{
+			// New plugin stream
+			Iteration: 4,
+			Metadata: map[string]map[uint32][]byte{
+				"usermd":     {1: {0x01}, 2: {0x03}},
+				"ticketvote": {1: {0x04}},
+			},
+		},
+	}
+	want := [][][]byte{
+		{{0x01}, {0x02}},
+		{{0x03}},
+		{},
+		{{0x04}},
+	}
+
+	got := historyStreams(indexes)
+	if len(got) != len(want) {
+		t.Fatalf("got %v iterations, want %v", len(got), len(want))
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			t.Fatalf("iteration %v: got %x, want %x", i+1, got[i], want[i])
+		}
+		for _, w := range want[i] {
+			var found bool
+			for _, g := range got[i] {
+				if bytes.Equal(g, w) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("iteration %v: merkle %x not found", i+1, w)
+			}
+		}
+	}
+}
@@ -15,11 +15,13 @@ import (
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/comments"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/dcrdata"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/pi"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/search"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/ticketvote"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/usermd"
 	cmplugin "github.com/decred/politeia/politeiad/plugins/comments"
 	ddplugin "github.com/decred/politeia/politeiad/plugins/dcrdata"
 	piplugin "github.com/decred/politeia/politeiad/plugins/pi"
+	srplugin "github.com/decred/politeia/politeiad/plugins/search"
 	tkplugin "github.com/decred/politeia/politeiad/plugins/ticketvote"
 	umplugin "github.com/decred/politeia/politeiad/plugins/usermd"
 )
@@ -96,6 +98,12 @@ func (t *Tstore) PluginRegister(b backend.Backend, p backend.Plugin) error {
 		if err != nil {
 			return err
 		}
+	case srplugin.PluginID:
+		tstoreClient := NewTstoreClient(t, srplugin.PluginID)
+		pluginClient, err = search.New(tstoreClient, p.Settings)
+		if err != nil {
+			return err
+		}
 	case tkplugin.PluginID:
 		tstoreClient := NewTstoreClient(t, tkplugin.PluginID)
 		pluginClient, err = ticketvote.New(b, tstoreClient,
@@ -219,6 +227,23 @@ func (t *Tstore) PluginWrite(token []byte, pluginID, cmd, payload string) (strin
 		return "", backend.ErrPluginIDInvalid
 	}
 
+	// The plugin cache writes of a command that is executed inside of
+	// a transaction can only be undone if the transaction holds the
+	// plugin gate. The command is flagged while it executes so that
+	// the writes that it makes to other records are rejected instead
+	// of waiting on the transaction. See PluginWriteBegin.
+	if tokenIsFullLength(token) {
+		x := t.txGet(treeIDFromToken(token))
+		if x != nil {
+			if x.cache == nil {
+				return "", fmt.Errorf("plugin write in a transaction " +
+					"that does not hold the plugin gate")
+			}
+			t.txPluginsCmd(true)
+			defer t.txPluginsCmd(false)
+		}
+	}
+
 	// Execute plugin command
 	return p.client.Cmd(token, cmd, payload)
 }
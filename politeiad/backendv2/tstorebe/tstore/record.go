@@ -75,7 +75,7 @@ func (t *Tstore) recordSave(treeID int64, recordMD backend.RecordMetadata, metad
 	}
 
 	// Get the existing record index
-	currIdx, err := t.recordIndexLatest(leavesAll)
+	currIdx, err := t.recordIndexLatest(treeID, leavesAll)
 	if err == backend.ErrRecordNotFound {
 		// No record versions exist yet. This is ok.
 		currIdx = &recordIndex{
@@ -111,6 +111,14 @@ func (t *Tstore) recordSave(treeID int64, recordMD backend.RecordMetadata, metad
 		md[v.PluginID] = pmd
 	}
 
+	// Verify that censored content is not being saved again
+	if recordMD.State == backend.StateVetted {
+		err = t.censoredContentVerify(tokenFromTreeID(treeID), files)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Verify there are no duplicate files
 	fn := make(map[string]struct{}, len(files))
 	for _, v := range files {
@@ -209,6 +217,29 @@ func (t *Tstore) recordSave(treeID int64, recordMD backend.RecordMetadata, metad
 		digests[be.Digest] = struct{}{}
 	}
 
+	// The payloads of censored files no longer exist so the caller is
+	// not able to provide them. Censored files remain part of a record
+	// version though, so they are carried over from the current record
+	// index when a new iteration of the same version is being saved.
+	if idx.State == backend.StateVetted && idx.State == currIdx.State &&
+		idx.Version == currIdx.Version {
+		censored, err := t.censoredFilesForVersion(tokenFromTreeID(treeID),
+			idx.Version)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range censored {
+			if _, ok := idx.Files[v.Name]; ok {
+				continue
+			}
+			m, ok := currIdx.Files[v.Name]
+			if !ok {
+				continue
+			}
+			idx.Files[v.Name] = m
+		}
+	}
+
 	// Check if any of the content already exists. Different record
 	// versions that reference the same data is fine, but this data
 	// should not be saved to the store again. We can find duplicates
@@ -258,7 +289,7 @@ func (t *Tstore) recordSave(treeID int64, recordMD backend.RecordMetadata, metad
 		if err != nil {
 			return nil, err
 		}
-		k := storeKeyNew(encrypt)
+		k := storeKeyContent(*beRecordMD, encrypt)
 		blobs[k] = b
 
 		// Prepare tlog leaf
@@ -294,7 +325,7 @@ func (t *Tstore) recordSave(treeID int64, recordMD backend.RecordMetadata, metad
 			if err != nil {
 				return nil, err
 			}
-			k := storeKeyNew(encrypt)
+			k := storeKeyContent(be, encrypt)
 			blobs[k] = b
 
 			// Prepare tlog leaf
@@ -327,7 +358,7 @@ func (t *Tstore) recordSave(treeID int64, recordMD backend.RecordMetadata, metad
 		if err != nil {
 			return nil, err
 		}
-		k := storeKeyNew(encrypt)
+		k := storeKeyContent(be, encrypt)
 		blobs[k] = b
 
 		// Prepare tlog leaf
@@ -350,14 +381,15 @@ func (t *Tstore) recordSave(treeID int64, recordMD backend.RecordMetadata, metad
 
 	log.Debugf("Saving %v record content blobs", len(blobs))
 
-	// Save blobs to the kv store
-	err = t.store.Put(blobs, encrypt)
+	// Save blobs to the kv store. The blobs are content addressed and
+	// may already exist if they are shared with another record.
+	err = t.blobsPut(treeID, blobs, encrypt)
 	if err != nil {
-		return nil, fmt.Errorf("store Put: %v", err)
+		return nil, err
 	}
 
 	// Append leaves onto the trillian tree
-	queued, _, err := t.tlog.LeavesAppend(treeID, leaves)
+	queued, err := t.leavesAppend(treeID, leaves)
 	if err != nil {
 		return nil, fmt.Errorf("LeavesAppend: %v", err)
 	}
@@ -428,9 +460,9 @@ func (t *Tstore) recordSave(treeID int64, recordMD backend.RecordMetadata, metad
 
 	log.Debugf("Resaving %v encrypted blobs as plain text", len(blobs))
 
-	err = t.store.Put(blobs, false)
+	err = t.blobsPut(treeID, blobs, false)
 	if err != nil {
-		return nil, fmt.Errorf("store Put: %v", err)
+		return nil, err
 	}
 
 	return &idx, nil
@@ -488,7 +520,7 @@ func (t *Tstore) RecordDel(token []byte) error {
 
 	// Ensure tree is frozen. Deleting files from the store is only
 	// allowed on frozen trees.
-	currIdx, err := t.recordIndexLatest(leavesAll)
+	currIdx, err := t.recordIndexLatest(treeID, leavesAll)
 	if err != nil {
 		return err
 	}
@@ -497,7 +529,7 @@ func (t *Tstore) RecordDel(token []byte) error {
 	}
 
 	// Retrieve all record indexes
-	indexes, err := t.recordIndexes(leavesAll)
+	indexes, err := t.recordIndexes(treeID, leavesAll)
 	if err != nil {
 		return err
 	}
@@ -534,9 +566,9 @@ func (t *Tstore) RecordDel(token []byte) error {
 	}
 
 	// Delete file blobs from the store
-	err = t.store.Del(keys)
+	err = t.blobsDel(treeID, keys)
 	if err != nil {
-		return fmt.Errorf("store Del: %v", err)
+		return err
 	}
 
 	return nil
@@ -626,7 +658,7 @@ func (t *Tstore) record(treeID int64, version uint32, filenames []string, omitAl
 	// Use the record index to pull the record content from the store.
 	// The keys for the record content first need to be extracted from
 	// their log leaf.
-	idx, err := t.recordIndex(leaves, version)
+	idx, err := t.recordIndex(treeID, leaves, version)
 	if err != nil {
 		return nil, err
 	}
@@ -690,9 +722,9 @@ func (t *Tstore) record(treeID int64, version uint32, filenames []string, omitAl
 	}
 
 	// Get record content from store
-	blobs, err := t.store.Get(keys)
+	blobs, err := t.blobsGet(treeID, keys)
 	if err != nil {
-		return nil, fmt.Errorf("store Get: %v", err)
+		return nil, err
 	}
 	if len(keys) != len(blobs) {
 		// One or more blobs were not found. This is allowed since the
@@ -779,10 +811,67 @@ func (t *Tstore) record(treeID int64, version uint32, filenames []string, omitAl
 		}
 	}
 
+	if recordMD == nil {
+		return nil, fmt.Errorf("record metadata not found %v", treeID)
+	}
+
+	// Remove the censored files. Individual files can only be censored
+	// once a record is vetted. Content blobs are shared between records,
+	// so the blob of a censored file may still exist if another record
+	// references the same content. A file is dropped when either its
+	// name is censored in this version or its digest has been censored
+	// in any version of the record.
+	var censored []backend.CensoredFile
+	if idx.State == backend.StateVetted {
+		all, err := t.censoredFiles(tokenFromTreeID(treeID))
+		if err != nil {
+			return nil, err
+		}
+		var (
+			names   = make(map[string]struct{}, len(all))
+			digests = make(map[string]struct{}, len(all))
+		)
+		for _, v := range all {
+			digests[v.Digest] = struct{}{}
+			if v.Version == idx.Version {
+				names[v.Name] = struct{}{}
+			}
+		}
+		filtered := make([]backend.File, 0, len(files))
+		for _, v := range files {
+			_, nameCensored := names[v.Name]
+			_, digestCensored := digests[v.Digest]
+			if nameCensored || digestCensored {
+				continue
+			}
+			filtered = append(filtered, v)
+		}
+		files = filtered
+
+		// Return the censored files of this version
+		if !omitAllFiles {
+			filesToInclude := make(map[string]struct{}, len(filenames))
+			for _, v := range filenames {
+				filesToInclude[v] = struct{}{}
+			}
+			for _, v := range all {
+				if v.Version != idx.Version {
+					continue
+				}
+				if _, ok := filesToInclude[v.Name]; len(filenames) > 0 && !ok {
+					// Only include the specified files
+					continue
+				}
+				censored = append(censored, v)
+			}
+		}
+	}
+
 	return &backend.Record{
 		RecordMetadata: *recordMD,
 		Metadata:       metadata,
 		Files:          files,
+		CensoredFiles:  censored,
 	}, nil
 }
 
@@ -905,9 +994,9 @@ func (t *Tstore) timestamp(treeID int64, merkleLeafHash []byte, leaves []*trilli
 	if err != nil {
 		return nil, err
 	}
-	blobs, err := t.store.Get([]string{ed.storeKey()})
+	blobs, err := t.blobsGet(treeID, []string{ed.storeKey()})
 	if err != nil {
-		return nil, fmt.Errorf("store get: %v", err)
+		return nil, err
 	}
 
 	// Extract the data blob. Its possible for the data blob to not
@@ -978,47 +1067,36 @@ func (t *Tstore) timestamp(treeID int64, merkleLeafHash []byte, leaves []*trilli
 		ExtraData:  string(extraData),
 	}
 
-	// Setup proof for log merkle root inclusion in the dcrtime merkle
-	// root
-	if a.VerifyDigest.Digest != trillianProof.MerkleRoot {
-		return nil, fmt.Errorf("trillian merkle root not anchored")
-	}
-	var (
-		numLeaves = a.VerifyDigest.ChainInformation.MerklePath.NumLeaves
-		hashes    = a.VerifyDigest.ChainInformation.MerklePath.Hashes
-		flags     = a.VerifyDigest.ChainInformation.MerklePath.Flags
-	)
-	edd := backend.ExtraDataDcrtime{
-		NumLeaves: numLeaves,
-		Flags:     base64.StdEncoding.EncodeToString(flags),
-	}
-	extraData, err = json.Marshal(edd)
+	// Setup the anchor proofs of the log merkle root. The first anchor
+	// populates the timestamp TxID, merkle root, and the second proof.
+	tas, err := a.timestampAnchors()
 	if err != nil {
 		return nil, err
 	}
-	merklePath = make([]string, 0, len(hashes))
-	for _, v := range hashes {
-		merklePath = append(merklePath, hex.EncodeToString(v[:]))
+	if len(tas) == 0 {
+		return nil, fmt.Errorf("anchor proofs not found")
 	}
-	dcrtimeProof := backend.Proof{
-		Type:       backend.ProofTypeDcrtime,
-		Digest:     a.VerifyDigest.Digest,
-		MerkleRoot: a.VerifyDigest.ChainInformation.MerkleRoot,
-		MerklePath: merklePath,
-		ExtraData:  string(extraData),
+	for _, v := range tas {
+		if v.Proof.Digest != trillianProof.MerkleRoot {
+			return nil, fmt.Errorf("trillian merkle root not anchored by %v",
+				v.Provider)
+		}
 	}
 
 	// Update timestamp
-	ts.TxID = a.VerifyDigest.ChainInformation.Transaction
-	ts.MerkleRoot = a.VerifyDigest.ChainInformation.MerkleRoot
+	ts.TxID = tas[0].TxID
+	ts.MerkleRoot = tas[0].MerkleRoot
 	ts.Proofs = []backend.Proof{
 		trillianProof,
-		dcrtimeProof,
+		tas[0].Proof,
 	}
+	ts.Anchors = tas
 
-	// Verify timestamp
+	// Verify timestamp. A bitcoin attestation that can not be verified
+	// because no bitcoin header source has been configured is left for
+	// the clients to verify.
 	err = backend.VerifyTimestamp(ts)
-	if err != nil {
+	if err != nil && err != backend.ErrAttestationUnverified {
 		return nil, fmt.Errorf("VerifyTimestamp: %v", err)
 	}
 
@@ -1045,7 +1123,7 @@ func (t *Tstore) RecordTimestamps(token []byte, version uint32) (*backend.Record
 	if err != nil {
 		return nil, err
 	}
-	idx, err := t.recordIndex(leaves, version)
+	idx, err := t.recordIndex(treeID, leaves, version)
 	if err != nil {
 		return nil, err
 	}
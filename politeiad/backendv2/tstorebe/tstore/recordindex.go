@@ -108,7 +108,7 @@ func (t *Tstore) recordIndexSave(treeID int64, idx recordIndex) error {
 	}
 	key := storeKeyNew(encrypt)
 	kv := map[string][]byte{key: b}
-	err = t.store.Put(kv, encrypt)
+	err = t.kv(treeID).Put(kv, encrypt)
 	if err != nil {
 		return fmt.Errorf("store Put: %v", err)
 	}
@@ -126,7 +126,7 @@ func (t *Tstore) recordIndexSave(treeID int64, idx recordIndex) error {
 	leaves := []*trillian.LogLeaf{
 		tlog.NewLogLeaf(d, extraData),
 	}
-	queued, _, err := t.tlog.LeavesAppend(treeID, leaves)
+	queued, err := t.leavesAppend(treeID, leaves)
 	if err != nil {
 		return fmt.Errorf("LeavesAppend: %v", err)
 	}
@@ -149,8 +149,30 @@ func (t *Tstore) recordIndexSave(treeID int64, idx recordIndex) error {
 }
 
 // recordIndexes returns all record indexes found in the provided trillian
-// leaves.
-func (t *Tstore) recordIndexes(leaves []*trillian.LogLeaf) ([]recordIndex, error) {
+// leaves. The leaves must be all of the leaves of the tree. The record indexes
+// are served from the tree cache when possible. The cache is not used while a
+// transaction is in progress on the tree since the leaves may include leaves
+// that are never appended onto the tree.
+func (t *Tstore) recordIndexes(treeID int64, leaves []*trillian.LogLeaf) ([]recordIndex, error) {
+	if t.txGet(treeID) != nil {
+		return t.recordIndexesDecode(leaves)
+	}
+	indexes, ok := t.cache.indexesGet(treeID, leaves)
+	if ok {
+		return indexes, nil
+	}
+	indexes, err := t.recordIndexesDecode(leaves)
+	if err != nil {
+		return nil, err
+	}
+	t.cache.indexesPut(treeID, len(leaves), indexes)
+
+	return indexes[:len(indexes):len(indexes)], nil
+}
+
+// recordIndexesDecode retrieves and decodes all record indexes found in the
+// provided trillian leaves.
+func (t *Tstore) recordIndexesDecode(leaves []*trillian.LogLeaf) ([]recordIndex, error) {
 	// Walk the leaves and compile the keys for all record indexes.  Once a
 	// record is made vetted the record history is considered to restart.
 	// If any vetted indexes exist, ignore all unvetted indexes.
@@ -264,8 +286,8 @@ func (t *Tstore) recordIndexes(leaves []*trillian.LogLeaf) ([]recordIndex, error
 
 // recordIndex returns the specified version of a record index for a slice of
 // trillian leaves.
-func (t *Tstore) recordIndex(leaves []*trillian.LogLeaf, version uint32) (*recordIndex, error) {
-	indexes, err := t.recordIndexes(leaves)
+func (t *Tstore) recordIndex(treeID int64, leaves []*trillian.LogLeaf, version uint32) (*recordIndex, error) {
+	indexes, err := t.recordIndexes(treeID, leaves)
 	if err != nil {
 		return nil, err
 	}
@@ -274,8 +296,8 @@ func (t *Tstore) recordIndex(leaves []*trillian.LogLeaf, version uint32) (*recor
 
 // recordIndexLatest returns the most recent record index for a slice of
 // trillian leaves.
-func (t *Tstore) recordIndexLatest(leaves []*trillian.LogLeaf) (*recordIndex, error) {
-	return t.recordIndex(leaves, 0)
+func (t *Tstore) recordIndexLatest(treeID int64, leaves []*trillian.LogLeaf) (*recordIndex, error) {
+	return t.recordIndex(treeID, leaves, 0)
 }
 
 // parseRecordIndex takes a list of record indexes and returns the most recent
@@ -307,7 +329,8 @@ func parseRecordIndex(indexes []recordIndex, version uint32) (*recordIndex, erro
 	if version == 0 {
 		// A version of 0 indicates that the most recent version should
 		// be returned.
-		ri = &indexes[len(indexes)-1]
+		r := indexes[len(indexes)-1]
+		ri = &r
 	} else {
 		// Walk the indexes backwards so the most recent iteration of
 		// the specified version is selected.
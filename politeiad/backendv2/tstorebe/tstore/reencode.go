@@ -0,0 +1,255 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+)
+
+const (
+	// reencodeKey is the key-value store key for the state of a
+	// re-encoding that has not completed yet.
+	reencodeKey = "tstore-reencode"
+
+	// reencodeBatchSize is the maximum number of blobs that are
+	// re-encoded at once.
+	reencodeBatchSize = 100
+)
+
+// reencodeState is the persisted state of a re-encoding. It is saved to the
+// key-value store after every tree so that an interrupted re-encoding can be
+// resumed from where it left off. It is deleted once the re-encoding has
+// completed.
+type reencodeState struct {
+	Version   uint8 `json:"version"`   // Target blob version
+	LastTree  int64 `json:"lasttree"`  // Last tree that was re-encoded
+	Records   int   `json:"records"`   // Trees re-encoded so far
+	Inspected int   `json:"inspected"` // Blobs inspected so far
+	Reencoded int   `json:"reencoded"` // Blobs re-encoded so far
+	Started   int64 `json:"started"`   // Unix timestamp
+}
+
+// Reencode re-encodes all blobs that are referenced by the tlog trees and that
+// were encoded using a previous blob version using the current blob version.
+// The trees are walked in ascending tree ID order. If a previous re-encoding
+// was interrupted, it is resumed after the last tree that was completed.
+//
+// The blobs of archived trees that have been moved to the cold store are not
+// re-encoded. Their digests are recorded in the archive manifests.
+func (t *Tstore) Reencode(opts backend.ReencodeOpts) (*backend.ReencodeReport, error) {
+	log.Tracef("Reencode")
+
+	// Lookup the state of an interrupted re-encoding
+	s, err := t.reencodeStateGet()
+	if err != nil {
+		return nil, err
+	}
+	var resumed bool
+	switch {
+	case s == nil || s.Version != store.BlobVersion:
+		s = &reencodeState{
+			Version: store.BlobVersion,
+			Started: time.Now().Unix(),
+		}
+		log.Infof("Re-encoding blobs using blob version %v", s.Version)
+
+	default:
+		resumed = true
+		log.Infof("Resuming re-encoding using blob version %v after "+
+			"%v records", s.Version, s.Records)
+	}
+	err = t.reencodeStateSave(*s)
+	if err != nil {
+		return nil, err
+	}
+
+	trees, err := t.tlog.TreesAll()
+	if err != nil {
+		return nil, fmt.Errorf("TreesAll: %v", err)
+	}
+	sort.Slice(trees, func(i, j int) bool {
+		return trees[i].TreeId < trees[j].TreeId
+	})
+	for i, tree := range trees {
+		if resumed && tree.TreeId <= s.LastTree {
+			continue
+		}
+		inspected, reencoded, err := t.reencodeTree(tree.TreeId)
+		if err != nil {
+			return nil, fmt.Errorf("tree %v: %v", tree.TreeId, err)
+		}
+		s.LastTree = tree.TreeId
+		s.Records++
+		s.Inspected += inspected
+		s.Reencoded += reencoded
+		err = t.reencodeStateSave(*s)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(trees))
+		}
+	}
+
+	// The re-encoding is complete
+	err = t.store.Del([]string{reencodeKey})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Re-encoding complete: %v of %v blobs re-encoded using blob "+
+		"version %v", s.Reencoded, s.Inspected, s.Version)
+
+	return &backend.ReencodeReport{
+		Version:   s.Version,
+		Resumed:   resumed,
+		Records:   s.Records,
+		Inspected: s.Inspected,
+		Reencoded: s.Reencoded,
+		Started:   s.Started,
+	}, nil
+}
+
+// reencodeTree re-encodes the blobs of a tree that were not encoded using the
+// current blob version. The number of blobs that were inspected and the number
+// of blobs that were re-encoded are returned.
+func (t *Tstore) reencodeTree(treeID int64) (int, int, error) {
+	leaves, err := t.leavesAll(treeID)
+	if err != nil {
+		return 0, 0, err
+	}
+	keys := make([]string, 0, len(leaves)*2)
+	for _, v := range leaves {
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil {
+			return 0, 0, err
+		}
+		keys = append(keys, ed.storeKey())
+		if ed.storeKey() != ed.storeKeyNoPrefix() {
+			// The blob may have been resaved as clear text when the
+			// record was made public.
+			keys = append(keys, ed.storeKeyNoPrefix())
+		}
+	}
+
+	var inspected, reencoded int
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > reencodeBatchSize {
+			batch = keys[:reencodeBatchSize]
+		}
+		keys = keys[len(batch):]
+
+		i, r, err := t.reencodeBlobs(batch)
+		if err != nil {
+			return 0, 0, err
+		}
+		inspected += i
+		reencoded += r
+	}
+
+	return inspected, reencoded, nil
+}
+
+// reencodeBlobs re-encodes the blobs for the provided keys that were not
+// encoded using the current blob version. The keys of migrated legacy blobs
+// are resolved to their content addressed keys. The blobs mutex is held so
+// that a blob that is being deleted is not saved again.
+//
+// Blobs that are referenced by the tlog trees are encrypted if and only if
+// their key has the encryption prefix.
+func (t *Tstore) reencodeBlobs(keys []string) (int, int, error) {
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	links, err := t.blobLinksGet(keys)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, k := range keys {
+		if link, ok := links[k]; ok {
+			keys[i] = link
+		}
+	}
+	blobs, err := t.store.Get(keys)
+	if err != nil {
+		return 0, 0, fmt.Errorf("store Get: %v", err)
+	}
+	var (
+		clearText = make(map[string][]byte, len(blobs))
+		encrypted = make(map[string][]byte, len(blobs))
+	)
+	for k, v := range blobs {
+		version, err := store.BlobVersionGet(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("blob %v: %v", k, err)
+		}
+		if version == store.BlobVersion {
+			continue
+		}
+		be, err := store.Deblob(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("deblob %v: %v", k, err)
+		}
+		b, err := store.Blobify(*be)
+		if err != nil {
+			return 0, 0, fmt.Errorf("blobify %v: %v", k, err)
+		}
+		if strings.HasPrefix(k, keyPrefixEncrypted) {
+			encrypted[k] = b
+		} else {
+			clearText[k] = b
+		}
+	}
+	if len(clearText) > 0 {
+		err = t.store.Put(clearText, false)
+		if err != nil {
+			return 0, 0, fmt.Errorf("store Put: %v", err)
+		}
+	}
+	if len(encrypted) > 0 {
+		err = t.store.Put(encrypted, true)
+		if err != nil {
+			return 0, 0, fmt.Errorf("store Put: %v", err)
+		}
+	}
+
+	return len(blobs), len(clearText) + len(encrypted), nil
+}
+
+// reencodeStateGet returns the state of an interrupted re-encoding. Nil is
+// returned if there is no re-encoding in progress.
+func (t *Tstore) reencodeStateGet() (*reencodeState, error) {
+	blobs, err := t.store.Get([]string{reencodeKey})
+	if err != nil {
+		return nil, err
+	}
+	b, ok := blobs[reencodeKey]
+	if !ok {
+		return nil, nil
+	}
+	var s reencodeState
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// reencodeStateSave saves the state of a re-encoding to the key-value store.
+func (t *Tstore) reencodeStateSave(s reencodeState) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return t.store.Put(map[string][]byte{reencodeKey: b}, false)
+}
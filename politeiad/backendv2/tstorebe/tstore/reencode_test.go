@@ -0,0 +1,174 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/google/trillian"
+)
+
+// blobifyLegacy encodes the provided blob entry using the legacy gzip+gob
+// blob encoding.
+func blobifyLegacy(t *testing.T, be store.BlobEntry) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	err := gob.NewEncoder(zw).Encode(be)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = zw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestReencode(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.reencode.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	// Setup two trees with enough legacy blobs to require multiple
+	// batches. Every other blob is encrypted.
+	var (
+		treeIDs = make([]int64, 0, 2)
+		entries = make(map[string]store.BlobEntry, 2*reencodeBatchSize)
+	)
+	for i := 0; i < 2; i++ {
+		tree, _, err := ts.tlog.TreeNew()
+		if err != nil {
+			t.Fatal(err)
+		}
+		treeIDs = append(treeIDs, tree.TreeId)
+		var (
+			leaves    = make([]*trillian.LogLeaf, 0, reencodeBatchSize)
+			clearText = make(map[string][]byte, reencodeBatchSize)
+			encrypted = make(map[string][]byte, reencodeBatchSize)
+		)
+		for j := 0; j < reencodeBatchSize; j++ {
+			be := store.NewBlobEntry([]byte(dataDescriptorFile),
+				[]byte(fmt.Sprintf("vote %v %v", i, j)))
+			k := storeKeyNew(j%2 == 0)
+			state := backend.StateVetted
+			if j%2 == 0 {
+				state = backend.StateUnvetted
+				encrypted[k] = blobifyLegacy(t, be)
+			} else {
+				clearText[k] = blobifyLegacy(t, be)
+			}
+			ed, err := extraDataEncode(k, dataDescriptorFile, state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaves = append(leaves, tlog.NewLogLeaf([]byte(k), ed))
+			entries[k] = be
+		}
+		_, _, err = ts.tlog.LeavesAppend(tree.TreeId, leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ts.store.Put(clearText, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ts.store.Put(encrypted, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Simulate an interrupted re-encoding that completed the first
+	// tree. Only the blobs of the second tree are re-encoded.
+	first := treeIDs[0]
+	if treeIDs[1] < first {
+		first = treeIDs[1]
+	}
+	err = ts.reencodeStateSave(reencodeState{
+		Version:  store.BlobVersion,
+		LastTree: first,
+		Records:  1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var done, total int
+	report, err := ts.Reencode(backend.ReencodeOpts{
+		Progress: func(d, t int) {
+			done, total = d, t
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Resumed || report.Records != 2 ||
+		report.Reencoded != reencodeBatchSize {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if done != 2 || total != 2 {
+		t.Fatalf("got progress %v/%v, want 2/2", done, total)
+	}
+
+	// Run a new re-encoding. The remaining legacy blobs are
+	// re-encoded.
+	report, err = ts.Reencode(backend.ReencodeOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Resumed || report.Records != 2 ||
+		report.Inspected != len(entries) ||
+		report.Reencoded != reencodeBatchSize {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	s, err := ts.reencodeStateGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != nil {
+		t.Fatalf("re-encoding state was not deleted")
+	}
+
+	// Verify that all blobs use the current blob version and that
+	// their contents did not change.
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	blobs, err := ts.store.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, be := range entries {
+		version, err := store.BlobVersionGet(blobs[k])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != store.BlobVersion {
+			t.Fatalf("blob %v: got version %v, want %v",
+				k, version, store.BlobVersion)
+		}
+		got, err := store.Deblob(blobs[k])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *got != be {
+			t.Fatalf("blob %v: got %+v, want %+v", k, got, be)
+		}
+	}
+}
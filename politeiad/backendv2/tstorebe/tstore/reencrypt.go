@@ -0,0 +1,169 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+const (
+	// reencryptKey is the key-value store key for the state of a
+	// re-encryption that has not completed yet.
+	reencryptKey = "tstore-reencrypt"
+
+	// reencryptBatchSize is the number of key-value store entries that
+	// are inspected in a single re-encryption batch.
+	reencryptBatchSize = 100
+)
+
+// reencryptState is the persisted state of a re-encryption. It is saved to
+// the key-value store after every batch so that an interrupted re-encryption
+// can be resumed from where it left off. It is deleted once the re-encryption
+// has completed.
+type reencryptState struct {
+	KeyVersion  uint32 `json:"keyversion"`  // Target key version
+	LastKey     string `json:"lastkey"`     // Last key that was inspected
+	Inspected   int    `json:"inspected"`   // Entries inspected so far
+	Reencrypted int    `json:"reencrypted"` // Blobs re-encrypted so far
+	Started     int64  `json:"started"`     // Unix timestamp
+}
+
+// Reencrypt re-encrypts all encrypted blobs in the key-value store using the
+// active encryption key.
+//
+// If a previous re-encryption was interrupted, it is resumed from the last
+// batch that was completed. Otherwise, the encryption key is rotated and all
+// key-value store entries are walked. Writes that are made while the
+// re-encryption is in progress use the new key, so the server remains online.
+//
+// If the encryption key was rotated since an interrupted re-encryption was
+// started, the walk is restarted so that all blobs end up using the same key.
+func (t *Tstore) Reencrypt(opts backend.ReencryptOpts) (*backend.ReencryptReport, error) {
+	log.Tracef("Reencrypt")
+
+	// Lookup the state of an interrupted re-encryption
+	s, err := t.reencryptStateGet()
+	if err != nil {
+		return nil, err
+	}
+	var resumed bool
+	switch {
+	case s == nil:
+		// Start a new re-encryption using a new key
+		version, err := t.store.RotateEncryptionKey()
+		if err != nil {
+			return nil, fmt.Errorf("rotate key: %v", err)
+		}
+		s = &reencryptState{
+			KeyVersion: version,
+			Started:    time.Now().Unix(),
+		}
+		log.Infof("Re-encrypting blobs using key version %v", version)
+
+	case s.KeyVersion != t.store.EncryptionKeyVersion():
+		// The key was rotated since the re-encryption was started
+		log.Infof("Key version changed from %v to %v; restarting "+
+			"re-encryption", s.KeyVersion, t.store.EncryptionKeyVersion())
+		s = &reencryptState{
+			KeyVersion: t.store.EncryptionKeyVersion(),
+			Started:    time.Now().Unix(),
+		}
+
+	default:
+		// Resume the re-encryption
+		resumed = true
+		log.Infof("Resuming re-encryption using key version %v after "+
+			"%v entries", s.KeyVersion, s.Inspected)
+	}
+	err = t.reencryptStateSave(*s)
+	if err != nil {
+		return nil, err
+	}
+
+	// The total is an estimate since entries may be added or removed
+	// while the re-encryption is in progress.
+	keys, err := t.store.Keys("", "", 0)
+	if err != nil {
+		return nil, err
+	}
+	total := len(keys)
+
+	// Walk the key-value store
+	for {
+		rr, err := t.store.Reencrypt(s.LastKey, reencryptBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("reencrypt after '%v': %v", s.LastKey, err)
+		}
+		s.Inspected += int(rr.Inspected)
+		s.Reencrypted += int(rr.Reencrypted)
+		if rr.LastKey != "" {
+			s.LastKey = rr.LastKey
+		}
+		if s.Inspected > total {
+			total = s.Inspected
+		}
+		if opts.Progress != nil {
+			opts.Progress(s.Inspected, total)
+		}
+		if rr.Inspected < reencryptBatchSize {
+			// We've reached the end of the key-value store
+			break
+		}
+		err = t.reencryptStateSave(*s)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The re-encryption is complete
+	err = t.store.Del([]string{reencryptKey})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Re-encryption complete: %v blobs re-encrypted using key "+
+		"version %v", s.Reencrypted, s.KeyVersion)
+
+	return &backend.ReencryptReport{
+		KeyVersion:  s.KeyVersion,
+		Resumed:     resumed,
+		Inspected:   s.Inspected,
+		Reencrypted: s.Reencrypted,
+		Started:     s.Started,
+	}, nil
+}
+
+// reencryptStateGet returns the state of an interrupted re-encryption. Nil is
+// returned if there is no re-encryption in progress.
+func (t *Tstore) reencryptStateGet() (*reencryptState, error) {
+	blobs, err := t.store.Get([]string{reencryptKey})
+	if err != nil {
+		return nil, err
+	}
+	b, ok := blobs[reencryptKey]
+	if !ok {
+		return nil, nil
+	}
+	var s reencryptState
+	err = json.Unmarshal(b, &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// reencryptStateSave saves the state of a re-encryption to the key-value
+// store.
+func (t *Tstore) reencryptStateSave(s reencryptState) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return t.store.Put(map[string][]byte{reencryptKey: b}, false)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+func TestReencrypt(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.reencrypt.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	// Save enough encrypted blobs to require multiple batches
+	blobs := make(map[string][]byte, reencryptBatchSize+50)
+	for i := 0; i < reencryptBatchSize+50; i++ {
+		k := fmt.Sprintf("key%03d", i)
+		blobs[k] = []byte(k)
+	}
+	err = ts.store.Put(blobs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run a new re-encryption. The key is rotated. The re-encryption
+	// state is saved to the store and is inspected along with the
+	// blobs.
+	var done, total int
+	report, err := ts.Reencrypt(backend.ReencryptOpts{
+		Progress: func(d, t int) {
+			done, total = d, t
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := len(blobs) + 1
+	if report.KeyVersion != 1 || report.Resumed ||
+		report.Reencrypted != len(blobs) || report.Inspected != entries {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if done != entries || total != entries {
+		t.Fatalf("got progress %v/%v, want %v/%v",
+			done, total, entries, entries)
+	}
+	s, err := ts.reencryptStateGet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != nil {
+		t.Fatalf("re-encryption state was not deleted")
+	}
+
+	// Simulate an interrupted re-encryption. The key is rotated and
+	// the blobs before the last key are marked as completed.
+	version, err := ts.store.RotateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ts.reencryptStateSave(reencryptState{
+		KeyVersion:  version,
+		LastKey:     "key099",
+		Inspected:   100,
+		Reencrypted: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The re-encryption is resumed without rotating the key again
+	report, err = ts.Reencrypt(backend.ReencryptOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Resumed || report.KeyVersion != version ||
+		ts.store.EncryptionKeyVersion() != version {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Reencrypted != len(blobs) {
+		t.Fatalf("got %v blobs re-encrypted, want %v",
+			report.Reencrypted, len(blobs))
+	}
+
+	// Verify the blobs are still readable
+	keys := make([]string, 0, len(blobs))
+	for k := range blobs {
+		keys = append(keys, k)
+	}
+	got, err := ts.store.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k := range blobs {
+		if string(got[k]) != k {
+			t.Fatalf("got '%s' for %v", got[k], k)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/decred/politeia/util"
+	"github.com/google/trillian"
+	"google.golang.org/grpc/codes"
+)
+
+// Service trees are tlog trees that do not belong to a record. They are used
+// to timestamp data that is generated by politeiad itself, such as the
+// inventory snapshots and the audit log. The ID of a service tree is saved to
+// the key-value store. Service trees are not added to the tokens cache, but
+// they are anchored along with the record trees.
+
+// serviceTree returns the ID of the service tree that is saved under the
+// provided key-value store key. The tree is created if it does not exist yet
+// and create is true. A tree ID of 0 is returned if the tree does not exist
+// and create is false.
+//
+// This function must be called WITH the service lock held.
+func (t *Tstore) serviceTree(key string, create bool) (int64, error) {
+	blobs, err := t.store.Get([]string{key})
+	if err != nil {
+		return 0, fmt.Errorf("store Get: %v", err)
+	}
+	if b, ok := blobs[key]; ok {
+		return strconv.ParseInt(string(b), 10, 64)
+	}
+	if !create {
+		return 0, nil
+	}
+
+	tree, _, err := t.tlog.TreeNew()
+	if err != nil {
+		return 0, fmt.Errorf("TreeNew: %v", err)
+	}
+	b := []byte(strconv.FormatInt(tree.TreeId, 10))
+	err = t.store.Put(map[string][]byte{key: b}, false)
+	if err != nil {
+		return 0, fmt.Errorf("store Put: %v", err)
+	}
+
+	log.Infof("Service tree created %v %v", key, tree.TreeId)
+
+	return tree.TreeId, nil
+}
+
+// serviceTreeGet returns the ID of the service tree that is saved under the
+// provided key-value store key. A tree ID of 0 is returned if the tree does
+// not exist.
+func (t *Tstore) serviceTreeGet(key string) (int64, error) {
+	t.serviceMtx.Lock()
+	defer t.serviceMtx.Unlock()
+
+	return t.serviceTree(key, false)
+}
+
+// serviceAppend saves the data blobs to the key-value store as clear text and
+// appends a leaf for each of them onto the service tree that is saved under
+// the provided key-value store key. The leaves are appended using a single
+// tlog call. The tree is created if it does not exist yet.
+func (t *Tstore) serviceAppend(treeKey, desc string, data ...[]byte) error {
+	t.serviceMtx.Lock()
+	defer t.serviceMtx.Unlock()
+
+	treeID, err := t.serviceTree(treeKey, true)
+	if err != nil {
+		return err
+	}
+
+	// Prepare the data blobs and leaves
+	hint, err := json.Marshal(
+		store.DataDescriptor{
+			Type:       store.DataTypeStructure,
+			Descriptor: desc,
+		})
+	if err != nil {
+		return err
+	}
+	var (
+		blobs  = make(map[string][]byte, len(data))
+		leaves = make([]*trillian.LogLeaf, 0, len(data))
+	)
+	for _, v := range data {
+		be := store.NewBlobEntry(hint, v)
+		b, err := store.Blobify(be)
+		if err != nil {
+			return err
+		}
+		key := storeKeyNew(false)
+		blobs[key] = b
+
+		d, err := hex.DecodeString(be.Digest)
+		if err != nil {
+			return err
+		}
+		extraData, err := extraDataEncode(key, desc, 0)
+		if err != nil {
+			return err
+		}
+		leaves = append(leaves, tlog.NewLogLeaf(d, extraData))
+	}
+
+	// Save the data blobs
+	err = t.store.Put(blobs, false)
+	if err != nil {
+		return fmt.Errorf("store Put: %v", err)
+	}
+
+	// Append the leaves
+	queued, err := t.leavesAppend(treeID, leaves)
+	if err != nil {
+		return fmt.Errorf("LeavesAppend: %v", err)
+	}
+	if len(queued) != len(leaves) {
+		return fmt.Errorf("wrong queued leaves count: got %v, want %v",
+			len(queued), len(leaves))
+	}
+	for _, v := range queued {
+		c := codes.Code(v.QueuedLeaf.GetStatus().GetCode())
+		switch c {
+		case codes.OK:
+			// This is ok; continue
+		case codes.AlreadyExists:
+			return backend.ErrDuplicatePayload
+		default:
+			return fmt.Errorf("queued leaf error: %v", c)
+		}
+	}
+
+	return nil
+}
+
+// serviceLeaves returns the leaves of a service tree that have the provided
+// data descriptor, ordered from oldest to newest. All leaves of the tree are
+// returned as well so that timestamps can be created for the leaves.
+func (t *Tstore) serviceLeaves(treeID int64, desc string) ([]*trillian.LogLeaf, []*trillian.LogLeaf, error) {
+	leaves, err := t.leavesAll(treeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	filtered := make([]*trillian.LogLeaf, 0, len(leaves))
+	for _, v := range leaves {
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ed.Desc == desc {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered, leaves, nil
+}
+
+// serviceData returns the data of the provided service tree leaves, in the
+// same order as the leaves. The data is read from the key-value store and is
+// verified against the leaf value.
+func (t *Tstore) serviceData(leaves []*trillian.LogLeaf) ([][]byte, error) {
+	keys := make([]string, 0, len(leaves))
+	for _, v := range leaves {
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, ed.storeKey())
+	}
+	blobs, err := t.store.Get(keys)
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	data := make([][]byte, 0, len(leaves))
+	for i, k := range keys {
+		b, ok := blobs[k]
+		if !ok {
+			return nil, fmt.Errorf("blob not found %v", k)
+		}
+		be, err := store.Deblob(b)
+		if err != nil {
+			return nil, err
+		}
+		d, err := base64.StdEncoding.DecodeString(be.Data)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(util.Digest(d), leaves[i].LeafValue) {
+			return nil, fmt.Errorf("data does not match leaf value %x",
+				leaves[i].LeafValue)
+		}
+		data = append(data, d)
+	}
+	return data, nil
+}
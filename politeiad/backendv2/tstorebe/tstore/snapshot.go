@@ -0,0 +1,66 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	backend "github.com/decred/politeia/politeiad/backendv2"
+)
+
+const (
+	// dataDescriptorSnapshot is the data descriptor of an inventory
+	// snapshot leaf.
+	dataDescriptorSnapshot = "pd-snapshot-v1"
+
+	// snapshotTreeKey is the key-value store key for the ID of the tlog
+	// tree that the inventory snapshots are appended onto.
+	snapshotTreeKey = "tstore-snapshottree"
+)
+
+// SnapshotSave saves an inventory snapshot to the key-value store and appends
+// a leaf onto the snapshot tree for it. The snapshot tree is anchored along
+// with the record trees. The snapshot is saved as clear text.
+func (t *Tstore) SnapshotSave(data []byte) error {
+	log.Tracef("SnapshotSave")
+
+	return t.serviceAppend(snapshotTreeKey, dataDescriptorSnapshot, data)
+}
+
+// Snapshot returns the inventory snapshot with the provided sequence number
+// and its timestamp. The sequence number is the position of the snapshot in
+// the snapshot tree, starting at 1. A sequence number of 0 returns the most
+// recent snapshot. A ErrSnapshotNotFound error is returned if the snapshot
+// does not exist.
+func (t *Tstore) Snapshot(seq uint64) ([]byte, *backend.Timestamp, error) {
+	log.Tracef("Snapshot: %v", seq)
+
+	treeID, err := t.serviceTreeGet(snapshotTreeKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if treeID == 0 {
+		return nil, nil, backend.ErrSnapshotNotFound
+	}
+
+	// Find the snapshot leaf
+	snapshots, leaves, err := t.serviceLeaves(treeID, dataDescriptorSnapshot)
+	if err != nil {
+		return nil, nil, err
+	}
+	if seq == 0 {
+		seq = uint64(len(snapshots))
+	}
+	if seq == 0 || seq > uint64(len(snapshots)) {
+		return nil, nil, backend.ErrSnapshotNotFound
+	}
+	l := snapshots[seq-1]
+
+	// Get the timestamp. The timestamp contains the snapshot data.
+	ts, err := t.timestamp(treeID, l.MerkleLeafHash, leaves)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []byte(ts.Data), ts, nil
+}
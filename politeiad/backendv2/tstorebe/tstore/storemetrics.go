@@ -0,0 +1,56 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"time"
+
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/politeiad/metrics"
+)
+
+// metricsStore wraps a BlobKV in order to record the duration and the size of
+// the gets and the puts.
+type metricsStore struct {
+	store.BlobKV
+}
+
+// newMetricsStore returns a new metricsStore.
+func newMetricsStore(kv store.BlobKV) *metricsStore {
+	return &metricsStore{
+		BlobKV: kv,
+	}
+}
+
+// blobsSize returns the total size of the blobs.
+func blobsSize(blobs map[string][]byte) int {
+	var size int
+	for _, v := range blobs {
+		size += len(v)
+	}
+	return size
+}
+
+// Put saves the provided key-value entries to the database and records the
+// duration and the size of the put.
+//
+// This function satisfies the store BlobKV interface.
+func (s *metricsStore) Put(blobs map[string][]byte, encrypt bool) error {
+	start := time.Now()
+	err := s.BlobKV.Put(blobs, encrypt)
+	metrics.BlobKV(metrics.BlobKVPut, blobsSize(blobs), start)
+	return err
+}
+
+// Get retrieves the key-value entries from the database and records the
+// duration and the size of the get.
+//
+// This function satisfies the store BlobKV interface.
+func (s *metricsStore) Get(keys []string) (map[string][]byte, error) {
+	start := time.Now()
+	blobs, err := s.BlobKV.Get(keys)
+	metrics.BlobKV(metrics.BlobKVGet, blobsSize(blobs), start)
+	return blobs, err
+}
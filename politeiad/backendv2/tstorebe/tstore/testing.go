@@ -33,7 +33,11 @@ func NewTestTstore(t *testing.T, dataDir string) *Tstore {
 	}
 
 	return &Tstore{
-		tlog:  tlog.NewTestClient(t),
-		store: store,
+		dataDir: dataDir,
+		tlog:    tlog.NewTestClient(t),
+		store:   store,
+		cache:   newTreeCache(1<<20, 1<<20),
+		plugins: make(map[string]plugin),
+		tokens:  make(map[string][]byte),
 	}
 }
@@ -1,4 +1,4 @@
-// Copyright (c) 2020-2021 The Decred developers
+// Copyright (c) 2020-2022 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
@@ -6,23 +6,92 @@ package tstore
 
 import (
 	"fmt"
+	"time"
 
 	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/decred/politeia/politeiad/metrics"
 	"github.com/google/trillian"
+	"github.com/google/trillian/types"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// tlogLeavesAppend provides a wrapper around the tlog LeavesAppend method that
+// records the duration of the append.
+func (t *Tstore) tlogLeavesAppend(treeID int64, leaves []*trillian.LogLeaf) ([]tlog.QueuedLeafProof, *types.LogRootV1, error) {
+	defer metrics.TlogAppend(time.Now())
+	return t.tlog.LeavesAppend(treeID, leaves)
+}
+
 // leavesAll provides a wrapper around the tlog LeavesAll method that unpacks
 // any tree not found errors and instead returns a backend ErrRecordNotFound
-// error.
+// error. The leaves are served from the tree cache when it is enabled. If a
+// transaction is in progress on the tree, the leaves that it has buffered are
+// included.
 func (t *Tstore) leavesAll(treeID int64) ([]*trillian.LogLeaf, error) {
-	leaves, err := t.tlog.LeavesAll(treeID)
+	x := t.txGet(treeID)
+	if x != nil {
+		// The tx lock is held so that the buffered leaves are not
+		// appended onto the tree by a concurrent commit in the
+		// meantime.
+		x.Lock()
+		defer x.Unlock()
+	}
+	leaves, err := t.leavesAllCached(treeID)
 	if err != nil {
 		if c := status.Code(err); c == codes.NotFound {
 			return nil, backend.ErrRecordNotFound
 		}
 		return nil, fmt.Errorf("LeavesAll: %v", err)
 	}
+	if x != nil {
+		leaves = x.leavesWithBuffered(leaves)
+	}
 	return leaves, nil
 }
+
+// leavesAllCached returns all leaves of a tree using the tree cache. The tree
+// size is looked up on every call. The cached leaves are returned if they
+// are all of the leaves of the tree. If leaves have been appended onto the
+// tree since it was cached, only the new leaves are fetched from tlog.
+//
+// The returned slice has no spare capacity, so appending to it does not modify
+// the cache entry.
+func (t *Tstore) leavesAllCached(treeID int64) ([]*trillian.LogLeaf, error) {
+	if !t.cache.leavesEnabled() {
+		return t.tlog.LeavesAll(treeID)
+	}
+
+	size, err := t.tlog.TreeSize(treeID)
+	if err != nil {
+		return nil, err
+	}
+	cached, ok := t.cache.leavesGet(treeID)
+	var leaves []*trillian.LogLeaf
+	switch {
+	case ok && uint64(len(cached)) == size:
+		t.cache.leavesRead(metrics.TreeCacheHit)
+		return cached[:len(cached):len(cached)], nil
+
+	case ok && uint64(len(cached)) < size:
+		start := int64(len(cached))
+		appended, err := t.tlog.LeavesByRange(treeID, start,
+			int64(size)-start)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(cached[:len(cached):len(cached)], appended...)
+		t.cache.leavesRead(metrics.TreeCacheExtend)
+
+	default:
+		leaves, err = t.tlog.LeavesAll(treeID)
+		if err != nil {
+			return nil, err
+		}
+		t.cache.leavesRead(metrics.TreeCacheMiss)
+	}
+	t.cache.leavesPut(treeID, leaves)
+
+	return leaves[:len(leaves):len(leaves)], nil
+}
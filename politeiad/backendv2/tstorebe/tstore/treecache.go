@@ -0,0 +1,323 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/decred/politeia/politeiad/metrics"
+	"github.com/google/trillian"
+)
+
+const (
+	// leafOverhead is the approximate number of bytes of memory that a
+	// cached leaf uses in addition to the size of its byte slices.
+	leafOverhead = 160
+
+	// indexOverhead is the approximate number of bytes of memory that
+	// a cached record index uses in addition to the size of its
+	// merkle leaf hashes and the size of the names that they're mapped
+	// by.
+	indexOverhead = 256
+)
+
+// CacheStats contains the usage statistics of the tree cache. The counts are
+// cumulative since the tstore instance was created.
+type CacheStats struct {
+	LeafHits      uint64 // Leaves reads served from the cache
+	LeafExtends   uint64 // Leaves reads that only fetched the new leaves
+	LeafMisses    uint64 // Leaves reads that fetched all leaves
+	LeafEvictions uint64
+	LeafTrees     int   // Trees that have cached leaves
+	LeafBytes     int64 // Approximate memory used by the cached leaves
+
+	IndexHits      uint64 // Record indexes reads served from the cache
+	IndexMisses    uint64 // Record indexes reads that decoded the blobs
+	IndexEvictions uint64
+	IndexTrees     int   // Trees that have cached record indexes
+	IndexBytes     int64 // Approximate memory used by the cached indexes
+}
+
+// treeCache is an in-memory cache of the leaves of the tlog trees and of the
+// record indexes that have been decoded from those leaves. Both are bounded
+// by a memory limit and the least recently used trees are evicted first.
+//
+// Leaves are cached by tree ID and are served only if the number of cached
+// leaves matches the current tree size. Appending leaves onto a tree grows the
+// tree, so the next read of the tree fetches only the leaves past the cached
+// ones and extends the cache entry. Record indexes are cached by tree ID and
+// the tree size that they were decoded at. They remain valid as the tree
+// grows until a new record index leaf is appended. Nothing is served from the
+// cache that does not reflect the latest tree.
+//
+// The cached leaves and record indexes are shared between callers and must not
+// be modified.
+type treeCache struct {
+	sync.Mutex
+	leaves  lruCache // Values are []*trillian.LogLeaf
+	indexes lruCache // Values are *indexesEntry
+	stats   CacheStats
+}
+
+// indexesEntry is a record indexes cache entry.
+type indexesEntry struct {
+	treeSize int
+	indexes  []recordIndex
+}
+
+// newTreeCache returns a new tree cache. A limit of 0 disables the cache for
+// leaves or record indexes respectively.
+func newTreeCache(leavesMaxBytes, indexesMaxBytes int64) *treeCache {
+	return &treeCache{
+		leaves:  newLRUCache(leavesMaxBytes),
+		indexes: newLRUCache(indexesMaxBytes),
+	}
+}
+
+// leavesEnabled returns whether the leaves cache is enabled.
+func (c *treeCache) leavesEnabled() bool {
+	return c != nil && c.leaves.maxBytes > 0
+}
+
+// leavesGet returns the cached leaves of a tree.
+func (c *treeCache) leavesGet(treeID int64) ([]*trillian.LogLeaf, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	v, ok := c.leaves.get(treeID)
+	if !ok {
+		return nil, false
+	}
+	return v.([]*trillian.LogLeaf), true
+}
+
+// leavesPut caches the leaves of a tree. The leaves are not cached if the
+// cache already contains more leaves for the tree, which happens when a
+// concurrent read saw a larger tree.
+func (c *treeCache) leavesPut(treeID int64, leaves []*trillian.LogLeaf) {
+	c.Lock()
+	defer c.Unlock()
+
+	if v, ok := c.leaves.get(treeID); ok &&
+		len(v.([]*trillian.LogLeaf)) > len(leaves) {
+		return
+	}
+	var size int64
+	for _, v := range leaves {
+		size += int64(len(v.MerkleLeafHash) + len(v.LeafValue) +
+			len(v.ExtraData) + leafOverhead)
+	}
+	c.put(metrics.TreeCacheLeaves, &c.leaves, treeID, leaves, size)
+}
+
+// leavesRead counts a leaves read with the provided result. The read is
+// counted in the cache stats and in the tree cache metrics.
+func (c *treeCache) leavesRead(result string) {
+	c.Lock()
+	defer c.Unlock()
+
+	switch result {
+	case metrics.TreeCacheHit:
+		c.stats.LeafHits++
+	case metrics.TreeCacheExtend:
+		c.stats.LeafExtends++
+	case metrics.TreeCacheMiss:
+		c.stats.LeafMisses++
+	}
+	metrics.TreeCacheRead(metrics.TreeCacheLeaves, result)
+}
+
+// put caches a value in the provided lru cache and updates the size metrics of
+// the cache.
+//
+// This function must be called WITH the lock held.
+func (c *treeCache) put(name string, l *lruCache, treeID int64, value interface{}, size int64) {
+	evictions := l.evictions
+	l.put(treeID, value, size)
+	metrics.TreeCacheSize(name, len(l.entries), l.bytes,
+		l.evictions-evictions)
+}
+
+// indexesGet returns the cached record indexes of a tree. The provided leaves
+// must be all of the leaves of the tree. The cached record indexes are only
+// returned if no record index leaf has been appended onto the tree since they
+// were cached.
+func (c *treeCache) indexesGet(treeID int64, leaves []*trillian.LogLeaf) ([]recordIndex, bool) {
+	if c == nil || c.indexes.maxBytes == 0 {
+		return nil, false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	miss := func() {
+		c.stats.IndexMisses++
+		metrics.TreeCacheRead(metrics.TreeCacheIndexes, metrics.TreeCacheMiss)
+	}
+	v, ok := c.indexes.get(treeID)
+	if !ok {
+		miss()
+		return nil, false
+	}
+	e := v.(*indexesEntry)
+	if e.treeSize > len(leaves) {
+		// The leaves are older than the cache entry
+		miss()
+		return nil, false
+	}
+	for _, v := range leaves[e.treeSize:] {
+		ed, err := extraDataDecode(v.ExtraData)
+		if err != nil || ed.Desc == dataDescriptorRecordIndex {
+			miss()
+			return nil, false
+		}
+	}
+	e.treeSize = len(leaves)
+	c.stats.IndexHits++
+	metrics.TreeCacheRead(metrics.TreeCacheIndexes, metrics.TreeCacheHit)
+
+	return e.indexes[:len(e.indexes):len(e.indexes)], true
+}
+
+// indexesPut caches the record indexes of a tree that were decoded from the
+// leaves of a tree of the provided size.
+func (c *treeCache) indexesPut(treeID int64, treeSize int, indexes []recordIndex) {
+	if c == nil || c.indexes.maxBytes == 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if v, ok := c.indexes.get(treeID); ok &&
+		v.(*indexesEntry).treeSize > treeSize {
+		return
+	}
+	var size int64
+	for _, v := range indexes {
+		size += int64(len(v.RecordMetadata) + indexOverhead)
+		for fn, m := range v.Files {
+			size += int64(len(fn) + len(m))
+		}
+		for pluginID, streams := range v.Metadata {
+			size += int64(len(pluginID))
+			for _, m := range streams {
+				size += int64(4 + len(m))
+			}
+		}
+	}
+	c.put(metrics.TreeCacheIndexes, &c.indexes, treeID, &indexesEntry{
+		treeSize: treeSize,
+		indexes:  indexes,
+	}, size)
+}
+
+// statsGet returns the cache stats.
+func (c *treeCache) statsGet() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	s := c.stats
+	s.LeafEvictions = c.leaves.evictions
+	s.LeafTrees = len(c.leaves.entries)
+	s.LeafBytes = c.leaves.bytes
+	s.IndexEvictions = c.indexes.evictions
+	s.IndexTrees = len(c.indexes.entries)
+	s.IndexBytes = c.indexes.bytes
+	return s
+}
+
+// CacheStats returns the usage statistics of the tree cache.
+func (t *Tstore) CacheStats() CacheStats {
+	return t.cache.statsGet()
+}
+
+// cacheStatsLog logs the usage statistics of the tree cache.
+func (t *Tstore) cacheStatsLog() {
+	if t.cache == nil {
+		return
+	}
+	s := t.cache.statsGet()
+	log.Infof("Tree cache leaves: %v hits, %v extends, %v misses, "+
+		"%v evictions, %v trees, %v bytes", s.LeafHits, s.LeafExtends,
+		s.LeafMisses, s.LeafEvictions, s.LeafTrees, s.LeafBytes)
+	log.Infof("Tree cache record indexes: %v hits, %v misses, "+
+		"%v evictions, %v trees, %v bytes", s.IndexHits, s.IndexMisses,
+		s.IndexEvictions, s.IndexTrees, s.IndexBytes)
+}
+
+// lruCache is a least recently used cache of tree data that is bounded by the
+// approximate number of bytes of memory that the cached values use. It is not
+// concurrency safe.
+type lruCache struct {
+	maxBytes  int64
+	bytes     int64
+	entries   map[int64]*list.Element // [treeID]*lruEntry
+	order     *list.List              // Most recently used first
+	evictions uint64
+}
+
+// lruEntry is a lruCache entry.
+type lruEntry struct {
+	treeID int64
+	value  interface{}
+	size   int64
+}
+
+// newLRUCache returns a new lruCache.
+func newLRUCache(maxBytes int64) lruCache {
+	return lruCache{
+		maxBytes: maxBytes,
+		entries:  make(map[int64]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// get returns the cached value for a tree and marks it as recently used.
+func (c *lruCache) get(treeID int64) (interface{}, bool) {
+	el, ok := c.entries[treeID]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+// put caches the value for a tree, replacing any existing value, then evicts
+// the least recently used trees until the cache is within its memory limit.
+// Values that are larger than the limit are not cached.
+func (c *lruCache) put(treeID int64, value interface{}, size int64) {
+	c.del(treeID)
+	if size > c.maxBytes {
+		return
+	}
+	c.entries[treeID] = c.order.PushFront(&lruEntry{
+		treeID: treeID,
+		value:  value,
+		size:   size,
+	})
+	c.bytes += size
+	for c.bytes > c.maxBytes {
+		el := c.order.Back()
+		c.del(el.Value.(*lruEntry).treeID)
+		c.evictions++
+	}
+}
+
+// del removes the cached value for a tree.
+func (c *lruCache) del(treeID int64) {
+	el, ok := c.entries[treeID]
+	if !ok {
+		return
+	}
+	c.order.Remove(el)
+	delete(c.entries, treeID)
+	c.bytes -= el.Value.(*lruEntry).size
+}
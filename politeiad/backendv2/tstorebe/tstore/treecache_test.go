@@ -0,0 +1,139 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"os"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/google/trillian"
+)
+
+func TestTreeCache(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.treecache.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	tree, _, err := ts.tlog.TreeNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeID := tree.TreeId
+
+	// leavesAppend appends a leaf with the provided data descriptor
+	// onto the tree.
+	leavesAppend := func(desc string) {
+		t.Helper()
+		ed, err := extraDataEncode(storeKeyNew(false), desc,
+			backend.StateUnvetted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf := tlog.NewLogLeaf([]byte(desc), ed)
+		_, _, err = ts.tlog.LeavesAppend(treeID,
+			[]*trillian.LogLeaf{leaf})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// leavesVerify reads the leaves of the tree and verifies the number
+	// of leaves and the cache stats.
+	leavesVerify := func(size int, hits, extends, misses uint64) []*trillian.LogLeaf {
+		t.Helper()
+		leaves, err := ts.leavesAll(treeID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(leaves) != size {
+			t.Fatalf("got %v leaves, want %v", len(leaves), size)
+		}
+		s := ts.CacheStats()
+		if s.LeafHits != hits || s.LeafExtends != extends ||
+			s.LeafMisses != misses {
+			t.Fatalf("got leaf hits %v extends %v misses %v, "+
+				"want %v %v %v", s.LeafHits, s.LeafExtends, s.LeafMisses,
+				hits, extends, misses)
+		}
+		return leaves
+	}
+
+	// The first read misses and the second read is served from the
+	// cache.
+	leavesAppend(dataDescriptorFile)
+	leavesAppend(dataDescriptorRecordIndex)
+	leavesVerify(2, 0, 0, 1)
+	leaves := leavesVerify(2, 1, 0, 1)
+
+	// Appending to the returned leaves must not modify the cache
+	_ = append(leaves, &trillian.LogLeaf{})
+	leavesVerify(2, 2, 0, 1)
+
+	// Appending leaves onto the tree extends the cache entry
+	leavesAppend(dataDescriptorFile)
+	leaves = leavesVerify(3, 2, 1, 1)
+	if string(leaves[2].LeafValue) != dataDescriptorFile {
+		t.Fatalf("unexpected appended leaf %s", leaves[2].LeafValue)
+	}
+	leavesVerify(3, 3, 1, 1)
+
+	// Record indexes are served from the cache as long as no record
+	// index leaf has been appended onto the tree.
+	indexes := []recordIndex{{Version: 1, Iteration: 1}}
+	ts.cache.indexesPut(treeID, 2, indexes)
+	got, ok := ts.cache.indexesGet(treeID, leaves)
+	if !ok || len(got) != 1 || got[0].Iteration != 1 {
+		t.Fatalf("record indexes not served from the cache")
+	}
+	_, ok = ts.cache.indexesGet(treeID, leaves[:1])
+	if ok {
+		t.Fatalf("record indexes served for an older tree")
+	}
+	leavesAppend(dataDescriptorRecordIndex)
+	leaves = leavesVerify(4, 3, 2, 1)
+	_, ok = ts.cache.indexesGet(treeID, leaves)
+	if ok {
+		t.Fatalf("stale record indexes served from the cache")
+	}
+	s := ts.CacheStats()
+	if s.IndexHits != 1 || s.IndexMisses != 2 {
+		t.Fatalf("got index hits %v misses %v, want 1 2",
+			s.IndexHits, s.IndexMisses)
+	}
+}
+
+func TestLRUCache(t *testing.T) {
+	c := newLRUCache(10)
+	c.put(1, "a", 4)
+	c.put(2, "b", 4)
+
+	// Mark tree 1 as recently used so that tree 2 is evicted first
+	_, ok := c.get(1)
+	if !ok {
+		t.Fatalf("tree 1 not found")
+	}
+	c.put(3, "c", 4)
+	if _, ok := c.get(2); ok {
+		t.Fatalf("tree 2 was not evicted")
+	}
+	if _, ok := c.get(1); !ok {
+		t.Fatalf("tree 1 was evicted")
+	}
+	if c.bytes != 8 || c.evictions != 1 {
+		t.Fatalf("got %v bytes %v evictions, want 8 1", c.bytes, c.evictions)
+	}
+
+	// Values that are larger than the limit are not cached
+	c.put(4, "d", 11)
+	if _, ok := c.get(4); ok {
+		t.Fatalf("oversized value was cached")
+	}
+}
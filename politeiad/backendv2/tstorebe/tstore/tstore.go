@@ -5,28 +5,91 @@
 package tstore
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql/driver"
 	"encoding/binary"
 	"fmt"
-	"net/url"
+	"net"
 	"os"
 	"path/filepath"
 	"sync"
 
 	"github.com/decred/dcrd/chaincfg/v3"
 	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/anchors"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/coldstore"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store/mysql"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store/sqlite"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
 	"github.com/decred/politeia/util"
 	"github.com/pkg/errors"
 	"github.com/robfig/cron"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
+	// DBTypeMySQL is the database type for a MySQL key-value store.
+	DBTypeMySQL = "mysql"
+
+	// DBTypeSQLite is the database type for a SQLite key-value store.
+	// The SQLite database file is created in the tstore data directory.
+	DBTypeSQLite = "sqlite"
+
+	// TlogTypeTrillian is the tlog type for a trillian log server that
+	// is accessed over gRPC.
+	TlogTypeTrillian = "trillian"
+
+	// TlogTypeEmbedded is the tlog type for the embedded transparent log.
+	// The embedded log database is created in the tstore data directory.
+	TlogTypeEmbedded = "embedded"
+
 	// MySQL settings
 	dbUser = "politeiad"
+
+	// embeddedTlogDirname is the name of the directory that the embedded
+	// tlog database is created in.
+	embeddedTlogDirname = "tlog"
+
+	// embeddedTlogKeyKey is the key-value store key for the signing key
+	// of the embedded tlog. The signing key is saved encrypted.
+	embeddedTlogKeyKey = "tstore-tlogsigningkey"
 )
 
+// ConnError is returned by New when the key-value store or the trillian log
+// server could not be reached. The setup can be retried once the service is
+// reachable. All other setup errors are caused by the settings or the data
+// and fail again on a retry.
+type ConnError struct {
+	Err error
+}
+
+// Error satisfies the error interface.
+func (e ConnError) Error() string {
+	return fmt.Sprintf("connection failed: %v", e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ConnError) Unwrap() error {
+	return e.Err
+}
+
+// isConnError returns whether the error was caused by a network connection
+// that could not be established or that was lost.
+func isConnError(err error) bool {
+	var ne net.Error
+	if errors.As(err, &ne) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
+
 // Tstore is a data store that automatically timestamps all data saved to it
 // onto the decred blockchain, making it possible to cryptographically prove
 // that a piece of data existed at a specific block height. It combines a
@@ -52,15 +115,55 @@ type Tstore struct {
 	activeNetParams *chaincfg.Params
 	tlog            tlog.Client
 	store           store.BlobKV
-	dcrtime         *dcrtimeClient
 	cron            *cron.Cron
 	plugins         map[string]plugin // [pluginID]plugin
 
+	// providers contains the anchor providers that the tlog trees are
+	// anchored to. Anchoring is disabled if there are no providers.
+	providers []anchors.Provider
+
+	// cold is the cold store that the blobs of frozen trees are moved
+	// to. Archival is disabled if it is nil.
+	cold coldstore.Store
+
+	// cache caches the tlog tree leaves and the decoded record indexes.
+	// Nil disables the cache.
+	cache *treeCache
+
+	// txs contains the transactions that are in progress on the record
+	// trees. It is lazy loaded.
+	txs map[int64]*tx // [treeID]tx
+
+	// gate serializes the transactions that execute plugin commands
+	// with the other writes. See TxPluginsBegin.
+	gate pluginGate
+
+	// blobsMtx serializes the updates to the reference entries of the
+	// content addressed blobs with the saves and the deletions of the
+	// blobs themselves. A blob must not be deleted while another record
+	// is adding a reference to it.
+	blobsMtx sync.Mutex
+
+	// serviceMtx serializes the creation of the service trees, i.e. the
+	// inventory snapshot tree and the audit tree, and the appends onto
+	// them.
+	serviceMtx sync.Mutex
+
+	// anchorSaveMtx is held while an anchor blob is saved and its leaf
+	// is appended onto the record tree, which is done without holding
+	// the record lock. See fsckBlobs.
+	anchorSaveMtx sync.Mutex
+
 	// droppingAnchor indicates whether tstore is in the process of
 	// dropping an anchor, i.e. timestamping unanchored tlog trees
-	// using dcrtime. An anchor is dropped periodically using cron.
+	// using the anchor providers. An anchor is dropped periodically
+	// using cron.
 	droppingAnchor bool
 
+	// anchorState contains the in-flight anchor and the recent anchor
+	// failures. It is reported by the anchor status.
+	anchorState anchorState
+
 	// tokens contains the short token to full token mappings. The
 	// short token is the first n characters of the hex encoded record
 	// token, where n is defined by the short token length politeiad
@@ -152,47 +255,69 @@ func (t *Tstore) fullLengthToken(token []byte) ([]byte, error) {
 	return fullToken, nil
 }
 
-// Fsck performs a filesystem check on the tstore.
-func (t *Tstore) Fsck(allTokens [][]byte) error {
-	log.Infof("Starting tstore fsck")
+// embeddedTlogSigningKey returns the signing key of the embedded tlog. The
+// signing key is saved encrypted to the key-value store. A new signing key is
+// created if one does not exist yet.
+func embeddedTlogSigningKey(kv store.BlobKV) (ed25519.PrivateKey, error) {
+	blobs, err := kv.Get([]string{embeddedTlogKeyKey})
+	if err != nil {
+		return nil, fmt.Errorf("store Get: %v", err)
+	}
+	if b, ok := blobs[embeddedTlogKeyKey]; ok {
+		if len(b) != ed25519.PrivateKeySize {
+			return nil, errors.Errorf("invalid tlog signing key size %v",
+				len(b))
+		}
+		return ed25519.PrivateKey(b), nil
+	}
+
+	log.Infof("Creating embedded tlog signing key")
 
-	err := t.anchorTrees()
+	_, privKey, err := ed25519.GenerateKey(rand.Reader)
 	if err != nil {
-		// Anchoring trees relies on the external dcrtime API.
-		// Don't allow a dcrtime error to stop execution. The
-		// anchoring process will be kicked off again by the
-		// tstore cron job at a later time.
-		log.Errorf("anchorTrees: %v", err)
+		return nil, err
 	}
-	err = t.freezeTrees()
+	err = kv.Put(map[string][]byte{embeddedTlogKeyKey: privKey}, true)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("store Put: %v", err)
 	}
 
-	// Run the plugin fscks
-	for _, pluginID := range t.pluginIDs() {
-		p, _ := t.plugin(pluginID)
-
-		log.Infof("Starting %v plugin fsck", pluginID)
+	return privKey, nil
+}
 
-		err := p.client.Fsck(allTokens)
-		if err != nil {
-			return errors.Errorf("plugin %v fsck: %v", pluginID, err)
-		}
+// TlogPublicKey returns the public key that is used to verify the signed log
+// roots of the tlog trees. nil is returned if the log roots are not signed by
+// the tlog client, i.e. when a trillian log server is used, in which case the
+// log roots are verified by trillian.
+func (t *Tstore) TlogPublicKey() ed25519.PublicKey {
+	s, ok := t.tlog.(tlog.LogRootSigner)
+	if !ok {
+		return nil
 	}
-
-	return nil
+	return s.PublicKey()
 }
 
 // Close performs cleanup of the tstore.
 func (t *Tstore) Close() {
 	log.Tracef("Close")
 
+	// Stop the anchor job
+	t.cron.Stop()
+
 	// Close connections
 	t.tlog.Close()
 	t.store.Close()
 }
 
+// Health returns the health of the tstore dependencies, keyed by dependency
+// name. A nil error means that the dependency is reachable.
+func (t *Tstore) Health() map[string]error {
+	return map[string]error{
+		"tlog":   t.tlog.Ping(),
+		"blobkv": t.store.Ping(),
+	}
+}
+
 // Setup performs any required work to setup the tstore instance.
 func (t *Tstore) Setup() error {
 	log.Infof("Building backend token prefix cache")
@@ -208,11 +333,20 @@ func (t *Tstore) Setup() error {
 		t.tokenAdd(v)
 	}
 
-	return nil
+	// Migrate the legacy record content blobs
+	return t.blobKeysMigrate()
 }
 
 // New returns a new tstore instance.
-func New(appDir, dataDir string, anp *chaincfg.Params, tlogHost, dbHost, dbPass, dcrtimeHost, dcrtimeCert string) (*Tstore, error) {
+//
+// The tlog trees are anchored to all of the provided anchor providers.
+// Anchoring is disabled if no anchor providers are provided. The blobs of
+// frozen trees are moved to the cold store. Archival is disabled if the cold
+// store is nil. The tlog leaves and the record indexes are cached in memory up
+// to the provided number of bytes. A limit of 0 disables the respective cache.
+// A ConnError is returned if the key-value store or the trillian log server
+// can not be reached.
+func New(appDir, dataDir string, anp *chaincfg.Params, tlogType, tlogHost, dbType, dbHost, dbPass string, providers []anchors.Provider, cold coldstore.Store, leafCacheSize, indexCacheSize int64) (*Tstore, error) {
 	// Setup datadir for this tstore instance
 	dataDir = filepath.Join(dataDir)
 	err := os.MkdirAll(dataDir, 0700)
@@ -222,40 +356,91 @@ func New(appDir, dataDir string, anp *chaincfg.Params, tlogHost, dbHost, dbPass,
 
 	// Setup the key-value store
 	//
-	// Example db name: testnet3_unvetted_kv
-	dbName := fmt.Sprintf("%v_kv", anp.Name)
-	kvstore, err := mysql.New(dbHost, dbUser, dbPass, dbName)
-	if err != nil {
-		return nil, err
+	// Example db name: testnet3_kv
+	var (
+		dbName  = fmt.Sprintf("%v_kv", anp.Name)
+		kvstore store.BlobKV
+	)
+	switch dbType {
+	case DBTypeMySQL:
+		kvstore, err = mysql.New(dbHost, dbUser, dbPass, dbName)
+		if err != nil {
+			if isConnError(err) {
+				err = ConnError{Err: fmt.Errorf("mysql %v: %v", dbHost, err)}
+			}
+			return nil, err
+		}
+	case DBTypeSQLite:
+		dbFile := filepath.Join(dataDir, dbName+".db")
+		kvstore, err = sqlite.New(dbFile, dbPass)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("invalid db type: %v", dbType)
 	}
 
-	// Setup trillian client
-	log.Infof("Tlog host: %v", tlogHost)
-	tlogClient, err := tlog.NewClient(tlogHost)
+	// Setup tlog client
+	var tlogClient tlog.Client
+	switch tlogType {
+	case TlogTypeTrillian:
+		log.Infof("Tlog host: %v", tlogHost)
+		tlogClient, err = tlog.NewClient(tlogHost)
+		if err != nil {
+			break
+		}
+
+		// The grpc connection is established lazily. Verify that the
+		// log server is reachable so that the caller is able to tell
+		// an unreachable log server apart from other setup errors.
+		_, err = tlogClient.TreesAll()
+		if err != nil {
+			tlogClient.Close()
+			if isConnError(err) {
+				err = ConnError{Err: fmt.Errorf("trillian %v: %v",
+					tlogHost, err)}
+			}
+		}
+	case TlogTypeEmbedded:
+		var (
+			dbDir   = filepath.Join(dataDir, embeddedTlogDirname)
+			privKey ed25519.PrivateKey
+		)
+		privKey, err = embeddedTlogSigningKey(kvstore)
+		if err != nil {
+			break
+		}
+		tlogClient, err = tlog.NewEmbeddedClient(dbDir, privKey)
+	default:
+		err = errors.Errorf("invalid tlog type: %v", tlogType)
+	}
 	if err != nil {
+		kvstore.Close()
 		return nil, err
 	}
 
-	// Verify dcrtime host
-	_, err = url.Parse(dcrtimeHost)
-	if err != nil {
-		return nil, fmt.Errorf("parse dcrtime host '%v': %v", dcrtimeHost, err)
+	// Log the anchor providers
+	if len(providers) == 0 {
+		log.Warnf("Anchoring is disabled")
 	}
-	log.Infof("Anchor host: %v", dcrtimeHost)
-
-	// Setup dcrtime client
-	dcrtimeClient, err := newDcrtimeClient(dcrtimeHost, dcrtimeCert)
-	if err != nil {
-		return nil, err
+	for _, v := range providers {
+		log.Infof("Anchor provider: %v", v.ID())
 	}
+	if cold == nil {
+		log.Infof("Archival of frozen trees is disabled")
+	}
+	log.Infof("Tree cache: %v MiB leaves, %v MiB record indexes",
+		leafCacheSize/(1<<20), indexCacheSize/(1<<20))
 
 	// Setup tstore
 	t := Tstore{
 		dataDir:         dataDir,
 		activeNetParams: anp,
 		tlog:            tlogClient,
-		store:           kvstore,
-		dcrtime:         dcrtimeClient,
+		store:           newMetricsStore(kvstore),
+		providers:       providers,
+		cold:            cold,
+		cache:           newTreeCache(leafCacheSize, indexCacheSize),
 		cron:            cron.New(),
 		plugins:         make(map[string]plugin),
 		tokens:          make(map[string][]byte),
@@ -265,15 +450,31 @@ func New(appDir, dataDir string, anp *chaincfg.Params, tlogHost, dbHost, dbPass,
 	log.Infof("Launch cron anchor job")
 	err = t.cron.AddFunc(anchorSchedule, func() {
 		err := t.anchorTrees()
-		if err != nil {
+		switch {
+		case errors.Is(err, backend.ErrAnchorInProgress):
+			// Though rare, the probability of the previous anchor not
+			// having dropped yet is not zero and should not be
+			// considered an error. We simply exit and will drop a new
+			// anchor at the next anchor period.
+			log.Infof("Attempting to drop an anchor while previous " +
+				"anchor has not finished dropping; skipping current " +
+				"anchor period")
+		case err != nil:
 			log.Errorf("anchorTrees: %v", err)
+			t.anchorFailureAdd("", 0, err)
 		}
 		err = t.freezeTrees()
 		if err != nil {
 			log.Errorf("freeTrees: %v", err)
 		}
+		err = t.archiveTrees()
+		if err != nil {
+			log.Errorf("archiveTrees: %v", err)
+		}
+		t.cacheStatsLog()
 	})
 	if err != nil {
+		t.Close()
 		return nil, err
 	}
 	t.cron.Start()
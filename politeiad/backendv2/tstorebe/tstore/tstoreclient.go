@@ -54,7 +54,7 @@ func (t *tstoreClient) BlobSave(token []byte, be store.BlobEntry) error {
 	if err != nil {
 		return err
 	}
-	idx, err := t.tstore.recordIndexLatest(leaves)
+	idx, err := t.tstore.recordIndexLatest(treeID, leaves)
 	if err != nil {
 		return err
 	}
@@ -102,7 +102,7 @@ func (t *tstoreClient) BlobSave(token []byte, be store.BlobEntry) error {
 	log.Debugf("Saving plugin data blob %v", dd.Descriptor)
 
 	// Save blob to store
-	err = t.tstore.store.Put(kv, encrypt)
+	err = t.tstore.kv(treeID).Put(kv, encrypt)
 	if err != nil {
 		return fmt.Errorf("store Put: %v", err)
 	}
@@ -117,7 +117,7 @@ func (t *tstoreClient) BlobSave(token []byte, be store.BlobEntry) error {
 	}
 
 	// Append log leaf to trillian tree
-	queued, _, err := t.tstore.tlog.LeavesAppend(treeID, leaves)
+	queued, err := t.tstore.leavesAppend(treeID, leaves)
 	if err != nil {
 		return fmt.Errorf("LeavesAppend: %v", err)
 	}
@@ -176,9 +176,9 @@ func (t *tstoreClient) BlobsDel(token []byte, digests [][]byte) error {
 	}
 
 	// Delete file blobs from the store
-	err = t.tstore.store.Del(keys)
+	err = t.tstore.blobsDel(treeID, keys)
 	if err != nil {
-		return fmt.Errorf("store Del: %v", err)
+		return err
 	}
 
 	return nil
@@ -241,9 +241,9 @@ func (t *tstoreClient) Blobs(token []byte, digests [][]byte) (map[string]store.B
 	}
 
 	// Pull the blobs from the store
-	blobs, err := t.tstore.store.Get(matchedKeys)
+	blobs, err := t.tstore.blobsGet(treeID, matchedKeys)
 	if err != nil {
-		return nil, fmt.Errorf("store Get: %v", err)
+		return nil, err
 	}
 
 	// Prepare reply
@@ -300,9 +300,9 @@ func (t *tstoreClient) BlobsByDataDesc(token []byte, dataDesc []string) ([]store
 	}
 
 	// Pull the blobs from the store
-	blobs, err := t.tstore.store.Get(keys)
+	blobs, err := t.tstore.blobsGet(treeID, keys)
 	if err != nil {
-		return nil, fmt.Errorf("store Get: %v", err)
+		return nil, err
 	}
 	if len(blobs) != len(keys) {
 		// One or more blobs were not found
@@ -416,7 +416,7 @@ func (t *tstoreClient) CachePut(blobs map[string][]byte, encrypt bool) error {
 	// the data they own.
 	prefixedBlobs := prefixMapKeys(t.pluginID, blobs)
 
-	return t.tstore.store.Put(prefixedBlobs, encrypt)
+	return t.tstore.cacheKV().Put(prefixedBlobs, encrypt)
 }
 
 // CacheDel deletes the provided blobs from the key-value store. This
@@ -431,7 +431,7 @@ func (t *tstoreClient) CacheDel(keys []string) error {
 	// the data they own.
 	pkeys := prefixKeys(t.pluginID, keys)
 
-	return t.tstore.store.Del(pkeys)
+	return t.tstore.cacheKV().Del(pkeys)
 }
 
 // CacheGet returns blobs from the key-value store for the provided keys. An
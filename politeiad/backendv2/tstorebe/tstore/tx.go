@@ -0,0 +1,868 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/google/trillian"
+	rstatus "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+)
+
+// tx is a transaction on a record tree.
+//
+// The leaves that are appended onto the tree during the transaction are
+// buffered and are only appended onto the tree once the transaction is
+// committed. The writes that are made to the key-value store on behalf of the
+// record are applied immediately, but are journaled so that they can be undone
+// if the transaction is rolled back.
+//
+// The journal is only kept in memory. If politeiad stops in the middle of a
+// transaction, the blobs that it saved and the references that it added are
+// left behind without any leaves that reference them. They are never read and
+// are removed by fsck. See Tstore.fsckBlobs. The plugin caches that it updated
+// are rebuilt by the plugin fscks.
+type tx struct {
+	sync.Mutex
+	store  *txStore
+	leaves []*trillian.LogLeaf // Buffered leaves
+
+	// committed contains the functions that are executed once the
+	// transaction has been committed. They perform the writes that
+	// can not be undone, such as deleting blobs from the cold store.
+	committed []func()
+
+	// cache journals the writes that are made to the plugin caches. It
+	// is only set when the transaction executes plugin commands. See
+	// Tstore.TxPluginsBegin.
+	cache *txStore
+}
+
+// leavesWithBuffered returns the provided tree leaves with the buffered leaves
+// of the transaction appended. The buffered leaves are assigned the leaf
+// indexes that follow the provided leaves.
+//
+// This function must be called WITH the tx lock held.
+func (x *tx) leavesWithBuffered(leaves []*trillian.LogLeaf) []*trillian.LogLeaf {
+	if len(x.leaves) == 0 {
+		return leaves
+	}
+	all := make([]*trillian.LogLeaf, 0, len(leaves)+len(x.leaves))
+	all = append(all, leaves...)
+	for i, v := range x.leaves {
+		all = append(all, &trillian.LogLeaf{
+			MerkleLeafHash:   v.MerkleLeafHash,
+			LeafValue:        v.LeafValue,
+			ExtraData:        v.ExtraData,
+			LeafIndex:        int64(len(leaves) + i),
+			LeafIdentityHash: v.LeafIdentityHash,
+		})
+	}
+	return all
+}
+
+// txStore is a store.BlobKV that journals the writes that are made to the
+// underlying key-value store on behalf of a record tree so that they can be
+// undone.
+//
+// The entries that are scoped to the record tree, such as the record indexes
+// and the plugin data blobs, are journaled by saving their prior value the
+// first time that they are written. Content addressed blobs are shared with
+// other records and are not restored to their prior value. The journal only
+// tracks which of them were saved or deleted by the transaction. Whether they
+// are kept once the transaction has been rolled back is decided by their
+// reference entries, which are undone by delta. See Tstore.txUndo.
+type txStore struct {
+	store.BlobKV
+
+	// encrypted returns whether a journaled entry is encrypted when it
+	// is restored to its prior value.
+	encrypted func(key string) bool
+
+	mtx     sync.Mutex
+	journal map[string]txEntry   // [key]entry prior to the transaction
+	content map[string]txContent // [key]content blob changes
+	refs    map[string]int       // [key]reference change of the record
+}
+
+// txEntry is a key-value store entry as it was prior to a transaction.
+type txEntry struct {
+	blob   []byte
+	exists bool
+}
+
+// txContent contains the changes that a transaction made to a content
+// addressed blob.
+type txContent struct {
+	put  bool   // Blob was saved
+	blob []byte // Blob prior to being deleted, nil if not deleted
+}
+
+// newTxStore returns a new txStore.
+func newTxStore(kv store.BlobKV, encrypted func(key string) bool) *txStore {
+	return &txStore{
+		BlobKV:    kv,
+		encrypted: encrypted,
+		journal:   make(map[string]txEntry),
+		content:   make(map[string]txContent),
+		refs:      make(map[string]int),
+	}
+}
+
+// journalAdd saves the current value of the provided tree entries to the
+// journal. Keys that have already been journaled are skipped.
+//
+// This function must be called WITH the mtx held.
+func (s *txStore) journalAdd(keys []string) error {
+	missing := make([]string, 0, len(keys))
+	for _, v := range keys {
+		if _, ok := s.journal[v]; !ok {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	blobs, err := s.BlobKV.Get(missing)
+	if err != nil {
+		return err
+	}
+	for _, v := range missing {
+		b, ok := blobs[v]
+		s.journal[v] = txEntry{
+			blob:   b,
+			exists: ok,
+		}
+	}
+	return nil
+}
+
+// contentDelAdd saves the current value of the provided content addressed
+// blobs to the journal prior to them being deleted. Blobs that have already
+// been saved are skipped.
+//
+// This function must be called WITH the mtx held.
+func (s *txStore) contentDelAdd(keys []string) error {
+	missing := make([]string, 0, len(keys))
+	for _, v := range keys {
+		if s.content[v].blob == nil {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	blobs, err := s.BlobKV.Get(missing)
+	if err != nil {
+		return err
+	}
+	for k, v := range blobs {
+		c := s.content[k]
+		c.blob = v
+		s.content[k] = c
+	}
+	return nil
+}
+
+// splitKeys splits the provided keys into the content addressed keys and the
+// keys that are scoped to the record tree.
+func splitKeys(keys []string) ([]string, []string) {
+	var (
+		content = make([]string, 0, len(keys))
+		tree    = make([]string, 0, len(keys))
+	)
+	for _, v := range keys {
+		if isContentKey(v) {
+			content = append(content, v)
+			continue
+		}
+		tree = append(tree, v)
+	}
+	return content, tree
+}
+
+// Put journals the provided entries then saves them to the key-value store.
+//
+// This function satisfies the store BlobKV interface.
+func (s *txStore) Put(blobs map[string][]byte, encrypt bool) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	keys := make([]string, 0, len(blobs))
+	for k := range blobs {
+		keys = append(keys, k)
+	}
+	content, tree := splitKeys(keys)
+	err := s.journalAdd(tree)
+	if err != nil {
+		return err
+	}
+	for _, v := range content {
+		c := s.content[v]
+		c.put = true
+		s.content[v] = c
+	}
+	return s.BlobKV.Put(blobs, encrypt)
+}
+
+// Del journals the provided entries then deletes them from the key-value
+// store.
+//
+// This function satisfies the store BlobKV interface.
+func (s *txStore) Del(keys []string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	content, tree := splitKeys(keys)
+	err := s.journalAdd(tree)
+	if err != nil {
+		return err
+	}
+	err = s.contentDelAdd(content)
+	if err != nil {
+		return err
+	}
+	return s.BlobKV.Del(keys)
+}
+
+// refsUpdated records the content addressed keys whose reference entries the
+// record was added to or removed from.
+func (s *txStore) refsUpdated(added, removed []string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, v := range added {
+		s.refs[v]++
+	}
+	for _, v := range removed {
+		s.refs[v]--
+	}
+}
+
+// rollback restores the journaled tree entries to their prior values. Entries
+// that did not exist prior to the transaction are deleted.
+//
+// This function must be called WITH the mtx held.
+func (s *txStore) rollback() error {
+	prior := make(map[string][]byte, len(s.journal))
+	del := make([]string, 0, len(s.journal))
+	for k, v := range s.journal {
+		if !v.exists {
+			del = append(del, k)
+			continue
+		}
+		prior[k] = v.blob
+	}
+	if len(del) > 0 {
+		err := s.BlobKV.Del(del)
+		if err != nil {
+			return fmt.Errorf("store Del: %v", err)
+		}
+	}
+	return storePut(s.BlobKV, prior, s.encrypted)
+}
+
+// keyIsEncrypted returns whether the blob of a record tree key is encrypted.
+// The encryption of the blob is derived from the key.
+func keyIsEncrypted(key string) bool {
+	return strings.HasPrefix(key, keyPrefixEncrypted)
+}
+
+// cacheIsEncrypted returns whether a plugin cache entry is encrypted when it
+// is restored. The plugins decide whether a cache entry is encrypted when it
+// is saved and the encryption of the prior value is not known. The entries are
+// always encrypted since the store decrypts them transparently.
+func cacheIsEncrypted(key string) bool {
+	return true
+}
+
+// storePut saves the provided blobs to the key-value store. The encrypted
+// function returns whether a blob is encrypted.
+func storePut(kv store.BlobKV, blobs map[string][]byte, encrypted func(key string) bool) error {
+	var (
+		clearText = make(map[string][]byte, len(blobs))
+		encrypt   = make(map[string][]byte, len(blobs))
+	)
+	for k, v := range blobs {
+		if encrypted(k) {
+			encrypt[k] = v
+			continue
+		}
+		clearText[k] = v
+	}
+	if len(clearText) > 0 {
+		err := kv.Put(clearText, false)
+		if err != nil {
+			return fmt.Errorf("store Put: %v", err)
+		}
+	}
+	if len(encrypt) > 0 {
+		err := kv.Put(encrypt, true)
+		if err != nil {
+			return fmt.Errorf("store Put encrypted: %v", err)
+		}
+	}
+	return nil
+}
+
+// pluginGate serializes the transactions that execute plugin commands with the
+// other writes. The plugin caches are shared between records, so the writes
+// that are made to them during a transaction can only be attributed to the
+// transaction, and undone when it is rolled back, if no other writes are in
+// progress while the transaction is.
+type pluginGate struct {
+	mtx     sync.Mutex
+	cond    *sync.Cond // Lazy loaded
+	writers int        // Writes in progress
+	tx      bool       // Held by a transaction
+	cmd     bool       // Transaction is executing a plugin command
+
+	// cache is the plugin cache journal of the transaction that holds
+	// the gate. It is set once the transaction has started.
+	cache *txStore
+}
+
+// wait waits for the gate to be updated.
+//
+// This function must be called WITH the mtx held.
+func (g *pluginGate) wait() {
+	if g.cond == nil {
+		g.cond = sync.NewCond(&g.mtx)
+	}
+	g.cond.Wait()
+}
+
+// broadcast wakes up the callers that are waiting for the gate.
+//
+// This function must be called WITH the mtx held.
+func (g *pluginGate) broadcast() {
+	if g.cond != nil {
+		g.cond.Broadcast()
+	}
+}
+
+// WriteBegin registers a write that may update the plugin caches. It waits
+// for the transaction that holds the plugin gate, if any, to end.
+//
+// WriteBegin must be called prior to acquiring the record lock. Each call must
+// be paired with a call to WriteEnd.
+func (t *Tstore) WriteBegin() {
+	// A write that is not a plugin write is never rejected
+	_ = t.writeBegin(false)
+}
+
+// PluginWriteBegin registers a plugin write. It is the same as WriteBegin,
+// except that a plugin write that is made while the transaction that holds
+// the plugin gate is executing a plugin command is rejected with a
+// ErrTxInProgress error. The plugin write may have been made by the plugin
+// command itself, which would then wait on itself.
+func (t *Tstore) PluginWriteBegin() error {
+	return t.writeBegin(true)
+}
+
+// writeBegin registers a write. See WriteBegin and PluginWriteBegin.
+func (t *Tstore) writeBegin(pluginWrite bool) error {
+	g := &t.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	for g.tx {
+		if pluginWrite && g.cmd {
+			return backend.ErrTxInProgress
+		}
+		g.wait()
+	}
+	g.writers++
+
+	return nil
+}
+
+// WriteEnd ends a write that was registered using WriteBegin or
+// PluginWriteBegin.
+func (t *Tstore) WriteEnd() {
+	g := &t.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.writers--
+	g.broadcast()
+}
+
+// TxPluginsBegin acquires the plugin gate for a transaction that executes
+// plugin commands. It waits for the writes that are in progress to end and
+// blocks any new writes until TxPluginsEnd is called. The plugin cache writes
+// that are made during the transaction are journaled so that they can be
+// undone.
+//
+// TxPluginsBegin must be called prior to acquiring the record lock.
+func (t *Tstore) TxPluginsBegin() {
+	g := &t.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	for g.tx || g.writers > 0 {
+		g.wait()
+	}
+	g.tx = true
+}
+
+// TxPluginsEnd releases the plugin gate that was acquired using
+// TxPluginsBegin.
+func (t *Tstore) TxPluginsEnd() {
+	g := &t.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.tx = false
+	g.cmd = false
+	g.cache = nil
+	g.broadcast()
+}
+
+// txPluginsJournal returns the plugin cache journal for a transaction that is
+// starting. Nil is returned if the transaction does not hold the plugin gate.
+func (t *Tstore) txPluginsJournal() *txStore {
+	g := &t.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if !g.tx {
+		return nil
+	}
+	g.cache = newTxStore(t.store, cacheIsEncrypted)
+	return g.cache
+}
+
+// txPluginsJournalEnd stops journaling the plugin cache writes once the
+// transaction that holds the plugin gate has ended. The writes of its post
+// plugin hooks are not part of the transaction.
+func (t *Tstore) txPluginsJournalEnd() {
+	g := &t.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.cache = nil
+}
+
+// txPluginsCmd flags whether the transaction that holds the plugin gate is
+// executing a plugin command.
+func (t *Tstore) txPluginsCmd(cmd bool) {
+	g := &t.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	g.cmd = cmd
+}
+
+// cacheKV returns the key-value store that the plugin cache writes must be
+// made to. The writes are journaled if a transaction holds the plugin gate.
+func (t *Tstore) cacheKV() store.BlobKV {
+	g := &t.gate
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.cache == nil {
+		return t.store
+	}
+	return g.cache
+}
+
+// txGet returns the transaction that is in progress on a tree. Nil is
+// returned if there is no transaction in progress.
+func (t *Tstore) txGet(treeID int64) *tx {
+	t.RLock()
+	defer t.RUnlock()
+
+	return t.txs[treeID]
+}
+
+// kv returns the key-value store that the writes of a record tree must be
+// made to. The writes are journaled if a transaction is in progress on the
+// tree.
+func (t *Tstore) kv(treeID int64) store.BlobKV {
+	x := t.txGet(treeID)
+	if x == nil {
+		return t.store
+	}
+	return x.store
+}
+
+// txRefsUpdated records the content addressed keys whose reference entries the
+// record was added to or removed from during the transaction that is in
+// progress on a tree. It is a no-op if there is no transaction in progress.
+func (t *Tstore) txRefsUpdated(treeID int64, added, removed []string) {
+	x := t.txGet(treeID)
+	if x == nil {
+		return
+	}
+	x.store.refsUpdated(added, removed)
+}
+
+// txCommitted registers a function that is executed once the transaction
+// that is in progress on a tree has been committed. The function is executed
+// immediately if there is no transaction in progress.
+func (t *Tstore) txCommitted(treeID int64, fn func()) {
+	x := t.txGet(treeID)
+	if x == nil {
+		fn()
+		return
+	}
+
+	x.Lock()
+	defer x.Unlock()
+
+	x.committed = append(x.committed, fn)
+}
+
+// leavesAppend appends leaves onto a tree and returns the queued leaves. If a
+// transaction is in progress on the tree the leaves are buffered until the
+// transaction is committed. Leaves that are duplicates of an existing leaf
+// are not buffered and their queued leaf contains an AlreadyExists status
+// code, the same as the tlog clients.
+func (t *Tstore) leavesAppend(treeID int64, leaves []*trillian.LogLeaf) ([]tlog.QueuedLeafProof, error) {
+	x := t.txGet(treeID)
+	if x == nil {
+		queued, _, err := t.tlogLeavesAppend(treeID, leaves)
+		return queued, err
+	}
+
+	// Get the merkle leaf hashes of the existing leaves, including
+	// the leaves that have already been buffered.
+	existing, err := t.leavesAll(treeID)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]struct{}, len(existing)+len(leaves))
+	for _, v := range existing {
+		hashes[string(v.MerkleLeafHash)] = struct{}{}
+	}
+
+	x.Lock()
+	defer x.Unlock()
+
+	queued := make([]tlog.QueuedLeafProof, 0, len(leaves))
+	for _, v := range leaves {
+		m := tlog.MerkleLeafHash(v.LeafValue)
+		l := &trillian.LogLeaf{
+			MerkleLeafHash:   m,
+			LeafValue:        v.LeafValue,
+			ExtraData:        v.ExtraData,
+			LeafIdentityHash: m,
+		}
+		ql := &trillian.QueuedLogLeaf{
+			Leaf: l,
+		}
+		if _, ok := hashes[string(m)]; ok {
+			ql.Status = &rstatus.Status{
+				Code:    int32(codes.AlreadyExists),
+				Message: "leaf already exists",
+			}
+			queued = append(queued, tlog.QueuedLeafProof{QueuedLeaf: ql})
+			continue
+		}
+		hashes[string(m)] = struct{}{}
+		x.leaves = append(x.leaves, l)
+		queued = append(queued, tlog.QueuedLeafProof{QueuedLeaf: ql})
+	}
+
+	return queued, nil
+}
+
+// Tx executes the provided function as a transaction on a record. The leaves
+// that are appended onto the record tree by the function are buffered and the
+// writes that are made to the key-value store on behalf of the record are
+// journaled. The buffered leaves are appended onto the tree once the function
+// returns successfully. If the function returns an error or none of the leaves
+// were appended, the key-value store writes are undone and the buffered leaves
+// are dropped.
+//
+// If only some of the leaves were appended, or if the append fails in a way
+// that does not rule out that leaves were appended, the key-value store writes
+// are kept since the appended leaves may reference them and a
+// backend.TxPartialError is returned. See txPartial.
+//
+// The caller must hold the record lock for the duration of the transaction.
+// Reads of the record that are made while the transaction is in progress
+// observe the uncommitted changes. The writes that are made to other records
+// are not part of the transaction.
+//
+// Plugin commands may only be executed inside of a transaction if the caller
+// holds the plugin gate. See TxPluginsBegin. Their plugin cache writes are
+// journaled and undone along with the record writes. The plugins are notified
+// of the rollback so that they can rebuild the caches that they keep outside
+// of tstore, such as memory caches.
+func (t *Tstore) Tx(token []byte, fn func() error) error {
+	log.Tracef("Tx: %x", token)
+
+	// Verify token is valid. The full length token must be used when
+	// writing data.
+	if !tokenIsFullLength(token) {
+		return backend.ErrTokenInvalid
+	}
+
+	// Register the transaction
+	treeID := treeIDFromToken(token)
+	x := &tx{
+		store: newTxStore(t.store, keyIsEncrypted),
+		cache: t.txPluginsJournal(),
+	}
+	t.Lock()
+	if t.txs == nil {
+		// txs is lazy loaded
+		t.txs = make(map[int64]*tx)
+	}
+	if _, ok := t.txs[treeID]; ok {
+		t.Unlock()
+		return fmt.Errorf("transaction already in progress on %x", token)
+	}
+	t.txs[treeID] = x
+	t.Unlock()
+
+	// Execute the transaction
+	err := fn()
+	if err == nil {
+		var partial bool
+		partial, err = t.txCommit(treeID, x)
+		if partial {
+			t.txPartial(treeID, x, err)
+			return backend.TxPartialError{
+				Err: err,
+			}
+		}
+	}
+	if err != nil {
+		t.txRollback(treeID, x)
+		return err
+	}
+
+	// Perform the writes that were deferred until the commit
+	for _, fn := range x.committed {
+		fn()
+	}
+
+	return nil
+}
+
+// txCommit appends the buffered leaves of a transaction onto the tree, then
+// ends the transaction. The tx lock is held while the leaves are appended so
+// that concurrent reads of the tree do not observe the leaves twice.
+//
+// When an error is returned, partial reports whether any of the leaves may
+// have been appended onto the tree. The tlog clients may queue some of the
+// leaves and fail the others, and a failed append call does not rule out that
+// the leaves were queued before the error was returned, e.g. a gRPC deadline
+// that is exceeded after trillian received the request. Only a reply in which
+// every queued leaf failed guarantees that nothing was appended.
+func (t *Tstore) txCommit(treeID int64, x *tx) (partial bool, err error) {
+	x.Lock()
+	defer x.Unlock()
+
+	if len(x.leaves) > 0 {
+		leaves := make([]*trillian.LogLeaf, 0, len(x.leaves))
+		for _, v := range x.leaves {
+			leaves = append(leaves, tlog.NewLogLeaf(v.LeafValue, v.ExtraData))
+		}
+		queued, _, err := t.tlogLeavesAppend(treeID, leaves)
+		if err != nil {
+			return true, fmt.Errorf("LeavesAppend: %v", err)
+		}
+		failed := make([]string, 0, len(queued))
+		for _, v := range queued {
+			c := codes.Code(v.QueuedLeaf.GetStatus().GetCode())
+			if c != codes.OK {
+				failed = append(failed, fmt.Sprintf("%v", c))
+			}
+		}
+		if len(failed) > 0 {
+			err := fmt.Errorf("append leaves failed: %v", failed)
+			return len(failed) < len(leaves), err
+		}
+		x.leaves = nil
+	}
+
+	t.txEnd(treeID)
+
+	return false, nil
+}
+
+// txRollback drops the buffered leaves of a transaction and undoes its
+// key-value store writes, then ends the transaction.
+func (t *Tstore) txRollback(treeID int64, x *tx) {
+	x.Lock()
+	x.leaves = nil
+	x.Unlock()
+
+	err := t.txUndo(treeID, x.store)
+	if err != nil {
+		// The tlog tree is the source of truth. Blobs that were saved
+		// during the transaction are orphaned and ignored until they
+		// are removed by fsck.
+		log.Errorf("Tx rollback %v: %v", treeID, err)
+	}
+
+	if x.cache != nil {
+		x.cache.mtx.Lock()
+		err = x.cache.rollback()
+		x.cache.mtx.Unlock()
+		if err != nil {
+			log.Errorf("Tx rollback %v plugin caches: %v", treeID, err)
+		}
+		t.txRollbackHook(treeID)
+	}
+
+	t.txEnd(treeID)
+}
+
+// txPartial ends a transaction whose buffered leaves may have been partially
+// appended onto the tree. Nothing is undone. The key-value store writes are
+// kept since the appended leaves may reference them, and the writes that were
+// deferred until the commit are not performed. The blobs that end up not being
+// referenced by any leaf are orphaned until they are removed by fsck, the
+// same as the blobs of a transaction that could not be rolled back.
+//
+// The plugins are notified the same as on a rollback so that they rebuild the
+// caches of the record from the leaves that made it onto the tree.
+func (t *Tstore) txPartial(treeID int64, x *tx, err error) {
+	log.Criticalf("Tx %v partially committed: %v", treeID, err)
+
+	x.Lock()
+	x.leaves = nil
+	x.Unlock()
+
+	if x.cache != nil {
+		t.txRollbackHook(treeID)
+	}
+
+	t.txEnd(treeID)
+}
+
+// txRollbackHook executes the tx rollback post plugin hook for a record.
+func (t *Tstore) txRollbackHook(treeID int64) {
+	// Notify the plugins so that they can rebuild the caches of the
+	// record that are not saved to the key-value store.
+	b, err := json.Marshal(plugins.HookTxRollback{
+		Token: tokenFromTreeID(treeID),
+	})
+	if err != nil {
+		log.Errorf("Tx rollback %v: %v", treeID, err)
+		return
+	}
+	t.PluginHookPost(plugins.HookTypeTxRollbackPost, string(b))
+}
+
+// txUndo undoes the key-value store writes of a transaction.
+//
+// The reference entries of the content addressed blobs are shared with other
+// records, which may have updated them while the transaction was in progress.
+// Only the changes that were made on behalf of the record are undone. The
+// content addressed blobs that were saved by the transaction are deleted if
+// they are no longer referenced by any records and the ones that were deleted
+// are restored if they are still referenced. The tree entries are then
+// restored to their prior values.
+func (t *Tstore) txUndo(treeID int64, s *txStore) error {
+	t.blobsMtx.Lock()
+	defer t.blobsMtx.Unlock()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	keys := make([]string, 0, len(s.content)+len(s.refs))
+	for k := range s.content {
+		keys = append(keys, k)
+	}
+	for k := range s.refs {
+		if _, ok := s.content[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	if len(keys) > 0 {
+		refs, err := t.blobRefsGet(keys)
+		if err != nil {
+			return err
+		}
+		var (
+			token   = hex.EncodeToString(tokenFromTreeID(treeID))
+			updated = make(map[string][]string, len(s.refs))
+		)
+		for k, v := range s.refs {
+			switch {
+			case v > 0:
+				refs[k] = refsRemove(refs[k], token)
+			case v < 0 && !refsContain(refs[k], token):
+				refs[k] = append(refs[k], token)
+			default:
+				continue
+			}
+			updated[k] = refs[k]
+		}
+		err = t.blobRefsSave(updated)
+		if err != nil {
+			return err
+		}
+
+		var (
+			del     = make([]string, 0, len(s.content))
+			restore = make(map[string][]byte, len(s.content))
+		)
+		for k, v := range s.content {
+			switch {
+			case len(refs[k]) == 0:
+				if v.put {
+					del = append(del, k)
+				}
+			case v.blob != nil:
+				restore[k] = v.blob
+			}
+		}
+		if len(del) > 0 {
+			err = t.store.Del(del)
+			if err != nil {
+				return fmt.Errorf("store Del: %v", err)
+			}
+		}
+		if len(restore) > 0 {
+			// Only restore the blobs that are missing
+			missing := make([]string, 0, len(restore))
+			for k := range restore {
+				missing = append(missing, k)
+			}
+			existing, err := t.store.Get(missing)
+			if err != nil {
+				return fmt.Errorf("store Get: %v", err)
+			}
+			for k := range existing {
+				delete(restore, k)
+			}
+			err = storePut(t.store, restore, keyIsEncrypted)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return s.rollback()
+}
+
+// txEnd removes the transaction of a tree.
+func (t *Tstore) txEnd(treeID int64) {
+	t.Lock()
+	x := t.txs[treeID]
+	delete(t.txs, treeID)
+	t.Unlock()
+
+	if x != nil && x.cache != nil {
+		t.txPluginsJournalEnd()
+	}
+}
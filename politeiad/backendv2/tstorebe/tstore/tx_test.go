@@ -0,0 +1,385 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstore
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
+	"github.com/google/trillian"
+	"github.com/google/trillian/types"
+)
+
+func TestTxRollbackSharedContent(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.tx.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	// Setup the trees. The transaction is performed on the first tree
+	// while the other trees are written to concurrently.
+	var (
+		treeIDs = make([]int64, 0, 3)
+		tokens  = make([]string, 0, 3)
+	)
+	for i := 0; i < 3; i++ {
+		tree, _, err := ts.tlog.TreeNew()
+		if err != nil {
+			t.Fatal(err)
+		}
+		treeIDs = append(treeIDs, tree.TreeId)
+		tokens = append(tokens,
+			hex.EncodeToString(tokenFromTreeID(tree.TreeId)))
+	}
+	var (
+		keys  = make([]string, 0, 3)
+		blobs = make(map[string][]byte, 3) // [key]blob
+	)
+	for _, v := range []string{"shared", "added", "created"} {
+		be, b := newTestBlob(t, v)
+		k := storeKeyContent(be, false)
+		keys = append(keys, k)
+		blobs[k] = b
+	}
+	var (
+		sharedKey  = keys[0]
+		addedKey   = keys[1]
+		createdKey = keys[2]
+		shared     = blobs[sharedKey]
+		added      = blobs[addedKey]
+		created    = blobs[createdKey]
+	)
+	err = ts.blobsPut(treeIDs[1], map[string][]byte{sharedKey: shared}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run a transaction that fails after another record has updated
+	// the reference entries of the content that it saved.
+	errTx := errors.New("tx failed")
+	err = ts.Tx(tokenFromTreeID(treeIDs[0]), func() error {
+		blobs := map[string][]byte{
+			sharedKey:  shared,
+			addedKey:   added,
+			createdKey: created,
+		}
+		err := ts.blobsPut(treeIDs[0], blobs, false)
+		if err != nil {
+			return err
+		}
+		blobs = map[string][]byte{
+			sharedKey: shared,
+			addedKey:  added,
+		}
+		err = ts.blobsPut(treeIDs[2], blobs, false)
+		if err != nil {
+			return err
+		}
+		return errTx
+	})
+	if !errors.Is(err, errTx) {
+		t.Fatalf("got err %v, want %v", err, errTx)
+	}
+
+	// Verify that only the references of the rolled back record were
+	// undone.
+	refs, err := ts.blobRefsGet(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantShared := []string{tokens[1], tokens[2]}
+	sort.Strings(wantShared)
+	if !reflect.DeepEqual(refs[sharedKey], wantShared) {
+		t.Fatalf("got shared refs %v, want %v", refs[sharedKey], wantShared)
+	}
+	if !reflect.DeepEqual(refs[addedKey], []string{tokens[2]}) {
+		t.Fatalf("got added refs %v, want %v", refs[addedKey], tokens[2])
+	}
+	if _, ok := refs[createdKey]; ok {
+		t.Fatalf("got created refs %v, want none", refs[createdKey])
+	}
+
+	// Verify that the blobs that are still referenced were kept and
+	// that the blob that was only saved by the transaction was deleted.
+	blobs, err = ts.store.Get(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{sharedKey, addedKey} {
+		if _, ok := blobs[v]; !ok {
+			t.Fatalf("referenced blob %v was deleted", v)
+		}
+	}
+	if _, ok := blobs[createdKey]; ok {
+		t.Fatalf("unreferenced blob was not deleted")
+	}
+}
+
+func TestTxRollbackPluginCache(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.tx.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	tree, _, err := ts.tlog.TreeNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		token = tokenFromTreeID(tree.TreeId)
+		c     = NewTstoreClient(ts, "test")
+	)
+	err = c.CachePut(map[string][]byte{"existing": []byte("prior")}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run a transaction that updates the plugin cache, then fails
+	errTx := errors.New("tx failed")
+	ts.TxPluginsBegin()
+	err = ts.Tx(token, func() error {
+		blobs := map[string][]byte{
+			"existing": []byte("updated"),
+			"new":      []byte("new"),
+		}
+		err := c.CachePut(blobs, true)
+		if err != nil {
+			return err
+		}
+
+		// A plugin write that is made while a plugin command of the
+		// transaction is executing must be rejected.
+		ts.txPluginsCmd(true)
+		err = ts.PluginWriteBegin()
+		ts.txPluginsCmd(false)
+		if !errors.Is(err, backend.ErrTxInProgress) {
+			return fmt.Errorf("got plugin write err %v, want %v",
+				err, backend.ErrTxInProgress)
+		}
+
+		return errTx
+	})
+	ts.TxPluginsEnd()
+	if !errors.Is(err, errTx) {
+		t.Fatalf("got err %v, want %v", err, errTx)
+	}
+
+	// Verify that the plugin cache writes were undone
+	blobs, err := c.CacheGet([]string{"existing", "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(blobs["existing"]) != "prior" {
+		t.Fatalf("got existing entry %s, want prior", blobs["existing"])
+	}
+	if _, ok := blobs["new"]; ok {
+		t.Fatalf("new entry was not deleted")
+	}
+
+	// The plugin cache writes that are made once the transaction has
+	// ended are not journaled.
+	if ts.cacheKV() != ts.store {
+		t.Fatalf("plugin cache writes are still journaled")
+	}
+}
+
+// appendErrClient is a tlog client whose appends succeed but return an error,
+// the same as a request that times out after trillian received it.
+type appendErrClient struct {
+	tlog.Client
+}
+
+// LeavesAppend appends the leaves onto the tree, then returns an error.
+func (c appendErrClient) LeavesAppend(treeID int64, leaves []*trillian.LogLeaf) ([]tlog.QueuedLeafProof, *types.LogRootV1, error) {
+	_, _, err := c.Client.LeavesAppend(treeID, leaves)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, errors.New("deadline exceeded")
+}
+
+func TestTxPartialCommit(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.tx.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	tree, _, err := ts.tlog.TreeNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.tlog = appendErrClient{ts.tlog}
+
+	// Run a transaction whose leaves are appended even though the
+	// append returns an error.
+	be, b := newTestBlob(t, "content")
+	key := storeKeyContent(be, false)
+	err = ts.Tx(tokenFromTreeID(tree.TreeId), func() error {
+		err := ts.blobsPut(tree.TreeId, map[string][]byte{key: b}, false)
+		if err != nil {
+			return err
+		}
+		leaves := []*trillian.LogLeaf{
+			tlog.NewLogLeaf([]byte("leaf"), []byte(key)),
+		}
+		_, err = ts.leavesAppend(tree.TreeId, leaves)
+		return err
+	})
+	var pe backend.TxPartialError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got err %v, want %T", err, pe)
+	}
+
+	// Verify that the leaf was appended, that the blob that it
+	// references was kept, and that the transaction has ended.
+	leaves, err := ts.tlog.LeavesAll(tree.TreeId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaves) != 1 {
+		t.Fatalf("got %v leaves, want 1", len(leaves))
+	}
+	blobs, err := ts.store.Get([]string{key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := blobs[key]; !ok {
+		t.Fatalf("blob of an appended leaf was deleted")
+	}
+	if ts.txGet(tree.TreeId) != nil {
+		t.Fatalf("transaction was not ended")
+	}
+}
+
+func TestTxInterruptedFsck(t *testing.T) {
+	dir, err := os.MkdirTemp("", "tstore.tx.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ts := NewTestTstore(t, dir)
+	defer ts.store.Close()
+
+	tree, _, err := ts.tlog.TreeNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		treeID = tree.TreeId
+		token  = tokenFromTreeID(treeID)
+	)
+
+	// Save a blob that is referenced by a leaf of the record
+	be, b := newTestBlob(t, "committed")
+	committedKey := storeKeyContent(be, false)
+	err = ts.blobsPut(treeID, map[string][]byte{committedKey: b}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := extraDataEncode(committedKey, dataDescriptorFile,
+		backend.StateVetted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := hex.DecodeString(be.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = ts.tlog.LeavesAppend(treeID,
+		[]*trillian.LogLeaf{tlog.NewLogLeaf(d, ed)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Save the blobs of a transaction that was interrupted before its
+	// leaves were appended, i.e. a content addressed blob along with
+	// its reference entry and a blob that uses a random key.
+	be, b = newTestBlob(t, "interrupted")
+	contentKey := storeKeyContent(be, false)
+	err = ts.blobsPut(treeID, map[string][]byte{contentKey: b}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	randomKey := storeKeyNew(false)
+	err = ts.store.Put(map[string][]byte{randomKey: b}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify that a dry run reports the orphans without removing them
+	recordLock := func([]byte) func() { return func() {} }
+	noProgress := func(int, int) {}
+	issues, err := ts.fsckBlobs([][]byte{token}, true, recordLock, noProgress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 3 {
+		t.Fatalf("got %v issues, want 3: %v", len(issues), issues)
+	}
+	blobs, err := ts.store.Get([]string{contentKey, randomKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blobs) != 2 {
+		t.Fatalf("dry run deleted blobs")
+	}
+
+	// Repair the orphans
+	issues, err = ts.fsckBlobs([][]byte{token}, false, recordLock, noProgress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 3 {
+		t.Fatalf("got %v issues, want 3: %v", len(issues), issues)
+	}
+	for _, v := range issues {
+		if v.Type != backend.FsckIssueBlobOrphaned || !v.Repaired {
+			t.Fatalf("got issue %+v, want repaired %v", v,
+				backend.FsckIssueBlobOrphaned)
+		}
+	}
+	blobs, err = ts.store.Get([]string{committedKey, contentKey, randomKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := blobs[committedKey]; !ok || len(blobs) != 1 {
+		t.Fatalf("got blobs %v, want only %v", len(blobs), committedKey)
+	}
+	refs, err := ts.blobRefsGet([]string{committedKey, contentKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := refs[contentKey]; ok {
+		t.Fatalf("orphaned reference was not released")
+	}
+	if _, ok := refs[committedKey]; !ok {
+		t.Fatalf("committed reference was released")
+	}
+
+	// A second run finds nothing
+	issues, err = ts.fsckBlobs([][]byte{token}, false, recordLock, noProgress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("got %v issues, want 0: %v", len(issues), issues)
+	}
+}
@@ -9,19 +9,24 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/politeia/politeiad/api/v1/identity"
 	"github.com/decred/politeia/politeiad/api/v1/mime"
 	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/anchors"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/coldstore"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tstore"
+	"github.com/decred/politeia/politeiad/metrics"
 	"github.com/decred/politeia/util"
+	"github.com/robfig/cron"
 	"github.com/subosito/gozaru"
 )
 
@@ -42,6 +47,29 @@ type tstoreBackend struct {
 	// record so that it can perform multiple read/write operations
 	// in a concurrent safe manner. These mutexes are lazy loaded.
 	recordMtxs map[string]*sync.Mutex
+
+	// changesMtx protects the change log sequence number and the
+	// channel that is closed when a new change is appended.
+	changesMtx sync.Mutex
+	changeSeq  uint64
+	changesC   chan struct{}
+
+	// auditMtx protects the audit log sequence number and the audit
+	// queue. The queued entries are appended onto the audit tree by the
+	// audit worker. auditDone is closed once the audit worker exits.
+	auditMtx  sync.Mutex
+	auditSeq  uint64
+	auditC    chan auditItem
+	auditDone chan struct{}
+
+	// identity is the politeiad identity. It is used to sign the
+	// inventory snapshots and the censored files.
+	identity *identity.FullIdentity
+
+	// cron runs the inventory snapshot job. snapshotMtx serializes the
+	// inventory snapshots.
+	cron        *cron.Cron
+	snapshotMtx sync.Mutex
 }
 
 // isShutdown returns whether the backend is shutdown.
@@ -68,6 +96,20 @@ func (t *tstoreBackend) recordMutex(token []byte) *sync.Mutex {
 	return m
 }
 
+// recordLock registers a write with tstore, then acquires the record lock. The
+// returned function releases both. The write is registered first so that it
+// does not overlap with a transaction that executes plugin commands. See
+// tstore.Tstore.TxPluginsBegin.
+func (t *tstoreBackend) recordLock(token []byte) func() {
+	t.tstore.WriteBegin()
+	m := t.recordMutex(token)
+	m.Lock()
+	return func() {
+		m.Unlock()
+		t.tstore.WriteEnd()
+	}
+}
+
 // metadataStreamsVerify verifies that all provided metadata streams are sane.
 func metadataStreamsVerify(metadata []backend.MetadataStream) error {
 	// Verify metadata
@@ -311,12 +353,17 @@ func filesUpdate(filesCurr, filesAdd []backend.File, filesDel []string) []backen
 	return f
 }
 
-// recordMetadataNew returns a new record metadata.
-func recordMetadataNew(token []byte, files []backend.File, state backend.StateT, status backend.StatusT, version, iteration uint32) (*backend.RecordMetadata, error) {
-	digests := make([]string, 0, len(files))
+// recordMetadataNew returns a new record metadata. The digests of the
+// censored files are included in the merkle root since the censored files are
+// still part of the record version.
+func recordMetadataNew(token []byte, files []backend.File, censored []backend.CensoredFile, state backend.StateT, status backend.StatusT, version, iteration uint32) (*backend.RecordMetadata, error) {
+	digests := make([]string, 0, len(files)+len(censored))
 	for _, v := range files {
 		digests = append(digests, v.Digest)
 	}
+	for _, v := range censored {
+		digests = append(digests, v.Digest)
+	}
 	m, err := util.MerkleRoot(digests)
 	if err != nil {
 		return nil, err
@@ -369,7 +416,7 @@ func (t *tstoreBackend) RecordNew(metadata []backend.MetadataStream, files []bac
 	}
 
 	// Create record metadata
-	rm, err := recordMetadataNew(token, files, backend.StateUnvetted,
+	rm, err := recordMetadataNew(token, files, nil, backend.StateUnvetted,
 		backend.StatusUnreviewed, 1, 1)
 	if err != nil {
 		return nil, err
@@ -391,11 +438,16 @@ func (t *tstoreBackend) RecordNew(metadata []backend.MetadataStream, files []bac
 	if err != nil {
 		return nil, err
 	}
+	t.tstore.WriteBegin()
 	t.tstore.PluginHookPost(plugins.HookTypeNewRecordPost, string(b))
+	t.tstore.WriteEnd()
 
 	// Update the inventory cache
 	t.inventoryAdd(backend.StateUnvetted, token, backend.StatusUnreviewed)
 
+	// Update the change log
+	t.changeAppend(changeRecord(backend.ChangeRecordNew, *rm))
+
 	// Get the full record to return
 	r, err := t.tstore.RecordLatest(token)
 	if err != nil {
@@ -412,18 +464,6 @@ func (t *tstoreBackend) RecordNew(metadata []backend.MetadataStream, files []bac
 func (t *tstoreBackend) RecordEdit(token []byte, mdAppend, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*backend.Record, error) {
 	log.Tracef("RecordEdit: %x", token)
 
-	// Verify record contents. Send in a single metadata array to
-	// verify there are no dups.
-	allMD := append(mdAppend, mdOverwrite...)
-	err := metadataStreamsVerify(allMD)
-	if err != nil {
-		return nil, err
-	}
-	err = filesVerify(filesAdd, filesDel)
-	if err != nil {
-		return nil, err
-	}
-
 	// Verify record exists
 	if !t.RecordExists(token) {
 		return nil, backend.ErrRecordNotFound
@@ -434,24 +474,80 @@ func (t *tstoreBackend) RecordEdit(token []byte, mdAppend, mdOverwrite []backend
 	if t.isShutdown() {
 		return nil, backend.ErrShutdown
 	}
-	m := t.recordMutex(token)
-	m.Lock()
-	defer m.Unlock()
+	defer t.recordLock(token)()
+
+	return t.recordWriteSave(token, func(r *backend.Record) (*recordWrite, error) {
+		return t.recordEdit(token, r, mdAppend, mdOverwrite, filesAdd, filesDel)
+	})
+}
+
+// recordWrite is a record write that has been prepared, but that has not
+// been saved yet. The pre plugin hooks of the write have already been
+// executed.
+type recordWrite struct {
+	record backend.Record // Record once the write has been saved
+	save   func() error   // Saves the write
+	post   func()         // Executes the post plugin hooks
+}
 
+// recordWriteSave prepares a record write against the latest version of the
+// record, saves it, and executes its post plugin hooks. The updated record is
+// returned.
+//
+// This function must be called WITH the record lock held.
+func (t *tstoreBackend) recordWriteSave(token []byte, prepare func(r *backend.Record) (*recordWrite, error)) (*backend.Record, error) {
 	// Get existing record
 	r, err := t.tstore.RecordLatest(token)
 	if err != nil {
 		return nil, fmt.Errorf("RecordLatest: %v", err)
 	}
 
-	// Apply changes
+	// Prepare and save the write
+	w, err := prepare(r)
+	if err != nil {
+		return nil, err
+	}
+	err = w.save()
+	if err != nil {
+		return nil, err
+	}
+	w.post()
+
+	// Return updated record
+	r, err = t.tstore.RecordLatest(token)
+	if err != nil {
+		return nil, fmt.Errorf("RecordLatest: %v", err)
+	}
+
+	return r, nil
+}
+
+// recordEdit prepares the edit of an existing record. The returned write saves
+// the new version of the record.
+//
+// This function must be called WITH the record lock held.
+func (t *tstoreBackend) recordEdit(token []byte, r *backend.Record, mdAppend, mdOverwrite []backend.MetadataStream, filesAdd []backend.File, filesDel []string) (*recordWrite, error) {
+	// Verify record contents. Send in a single metadata array to
+	// verify there are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	err := metadataStreamsVerify(allMD)
+	if err != nil {
+		return nil, err
+	}
+	err = filesVerify(filesAdd, filesDel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply changes. Censored files do not have a payload and are
+	// not carried over into the new version.
 	var (
 		rm       = r.RecordMetadata
 		metadata = metadataStreamsUpdate(r.Metadata, mdAppend, mdOverwrite)
 		files    = filesUpdate(r.Files, filesAdd, filesDel)
 	)
-	recordMD, err := recordMetadataNew(token, files, rm.State, rm.Status,
-		rm.Version+1, rm.Iteration+1)
+	recordMD, err := recordMetadataNew(token, files, nil, rm.State,
+		rm.Status, rm.Version+1, rm.Iteration+1)
 	if err != nil {
 		return nil, err
 	}
@@ -480,27 +576,36 @@ func (t *tstoreBackend) RecordEdit(token []byte, mdAppend, mdOverwrite []backend
 		return nil, err
 	}
 
-	// Save record
-	err = t.tstore.RecordSave(token, *recordMD, metadata, files)
-	if err != nil {
-		switch err {
-		case backend.ErrRecordLocked:
-			return nil, err
-		default:
-			return nil, fmt.Errorf("RecordSave: %v", err)
-		}
-	}
-
-	// Call post plugin hooks
-	t.tstore.PluginHookPost(plugins.HookTypeEditRecordPost, string(b))
-
-	// Return updated record
-	r, err = t.tstore.RecordLatest(token)
-	if err != nil {
-		return nil, fmt.Errorf("RecordLatest: %v", err)
-	}
+	return &recordWrite{
+		record: backend.Record{
+			RecordMetadata: *recordMD,
+			Metadata:       metadata,
+			Files:          files,
+		},
+		save: func() error {
+			// Save record
+			err := t.tstore.RecordSave(token, *recordMD, metadata, files)
+			if err != nil {
+				// A content error is returned if the edit adds content
+				// that has been censored.
+				var ce backend.ContentError
+				switch {
+				case err == backend.ErrRecordLocked, errors.As(err, &ce):
+					return err
+				default:
+					return fmt.Errorf("RecordSave: %v", err)
+				}
+			}
+			return nil
+		},
+		post: func() {
+			// Call post plugin hooks
+			t.tstore.PluginHookPost(plugins.HookTypeEditRecordPost, string(b))
 
-	return r, nil
+			// Update the change log
+			t.changeAppend(changeRecord(backend.ChangeRecordEdit, *recordMD))
+		},
+	}, nil
 }
 
 // RecordEditMetadata edits the metadata of a record without changing any
@@ -511,17 +616,6 @@ func (t *tstoreBackend) RecordEdit(token []byte, mdAppend, mdOverwrite []backend
 func (t *tstoreBackend) RecordEditMetadata(token []byte, mdAppend, mdOverwrite []backend.MetadataStream) (*backend.Record, error) {
 	log.Tracef("RecordEditMetadata: %x", token)
 
-	// Verify metadata. Send in a single metadata array to verify there
-	// are no dups.
-	allMD := append(mdAppend, mdOverwrite...)
-	err := metadataStreamsVerify(allMD)
-	if err != nil {
-		return nil, err
-	}
-	if len(mdAppend) == 0 && len(mdOverwrite) == 0 {
-		return nil, backend.ErrNoRecordChanges
-	}
-
 	// Verify record exists
 	if !t.RecordExists(token) {
 		return nil, backend.ErrRecordNotFound
@@ -532,14 +626,27 @@ func (t *tstoreBackend) RecordEditMetadata(token []byte, mdAppend, mdOverwrite [
 	if t.isShutdown() {
 		return nil, backend.ErrShutdown
 	}
-	m := t.recordMutex(token)
-	m.Lock()
-	defer m.Unlock()
+	defer t.recordLock(token)()
 
-	// Get existing record
-	r, err := t.tstore.RecordLatest(token)
+	return t.recordWriteSave(token, func(r *backend.Record) (*recordWrite, error) {
+		return t.recordEditMetadata(token, r, mdAppend, mdOverwrite)
+	})
+}
+
+// recordEditMetadata prepares the edit of the metadata of a record. The
+// returned write saves the new iteration of the record.
+//
+// This function must be called WITH the record lock held.
+func (t *tstoreBackend) recordEditMetadata(token []byte, r *backend.Record, mdAppend, mdOverwrite []backend.MetadataStream) (*recordWrite, error) {
+	// Verify metadata. Send in a single metadata array to verify there
+	// are no dups.
+	allMD := append(mdAppend, mdOverwrite...)
+	err := metadataStreamsVerify(allMD)
 	if err != nil {
-		return nil, fmt.Errorf("RecordLatest: %v", err)
+		return nil, err
+	}
+	if len(mdAppend) == 0 && len(mdOverwrite) == 0 {
+		return nil, backend.ErrNoRecordChanges
 	}
 
 	// Apply changes. The version is not incremented for metadata only
@@ -548,8 +655,8 @@ func (t *tstoreBackend) RecordEditMetadata(token []byte, mdAppend, mdOverwrite [
 		rm       = r.RecordMetadata
 		metadata = metadataStreamsUpdate(r.Metadata, mdAppend, mdOverwrite)
 	)
-	recordMD, err := recordMetadataNew(token, r.Files, rm.State, rm.Status,
-		rm.Version, rm.Iteration+1)
+	recordMD, err := recordMetadataNew(token, r.Files, r.CensoredFiles,
+		rm.State, rm.Status, rm.Version, rm.Iteration+1)
 	if err != nil {
 		return nil, err
 	}
@@ -568,27 +675,35 @@ func (t *tstoreBackend) RecordEditMetadata(token []byte, mdAppend, mdOverwrite [
 		return nil, err
 	}
 
-	// Update metadata
-	err = t.tstore.RecordSave(token, *recordMD, metadata, r.Files)
-	if err != nil {
-		switch err {
-		case backend.ErrRecordLocked, backend.ErrNoRecordChanges:
-			return nil, err
-		default:
-			return nil, fmt.Errorf("RecordSave: %v", err)
-		}
-	}
-
-	// Call post plugin hooks
-	t.tstore.PluginHookPost(plugins.HookTypeEditMetadataPost, string(b))
-
-	// Return updated record
-	r, err = t.tstore.RecordLatest(token)
-	if err != nil {
-		return nil, fmt.Errorf("RecordLatest: %v", err)
-	}
+	return &recordWrite{
+		record: backend.Record{
+			RecordMetadata: *recordMD,
+			Metadata:       metadata,
+			Files:          r.Files,
+			CensoredFiles:  r.CensoredFiles,
+		},
+		save: func() error {
+			// Update metadata
+			err := t.tstore.RecordSave(token, *recordMD, metadata, r.Files)
+			if err != nil {
+				switch err {
+				case backend.ErrRecordLocked, backend.ErrNoRecordChanges:
+					return err
+				default:
+					return fmt.Errorf("RecordSave: %v", err)
+				}
+			}
+			return nil
+		},
+		post: func() {
+			// Call post plugin hooks
+			t.tstore.PluginHookPost(plugins.HookTypeEditMetadataPost, string(b))
 
-	return r, nil
+			// Update the change log
+			t.changeAppend(changeRecord(backend.ChangeRecordEditMetadata,
+				*recordMD))
+		},
+	}, nil
 }
 
 var (
@@ -685,15 +800,18 @@ func (t *tstoreBackend) RecordSetStatus(token []byte, status backend.StatusT, md
 	if t.isShutdown() {
 		return nil, backend.ErrShutdown
 	}
-	m := t.recordMutex(token)
-	m.Lock()
-	defer m.Unlock()
+	defer t.recordLock(token)()
 
-	// Get existing record
-	r, err := t.tstore.RecordLatest(token)
-	if err != nil {
-		return nil, fmt.Errorf("RecordLatest: %v", err)
-	}
+	return t.recordWriteSave(token, func(r *backend.Record) (*recordWrite, error) {
+		return t.recordSetStatus(token, r, status, mdAppend, mdOverwrite)
+	})
+}
+
+// recordSetStatus prepares the status change of a record. The post plugin
+// hooks of the returned write also update the inventory cache.
+//
+// This function must be called WITH the record lock held.
+func (t *tstoreBackend) recordSetStatus(token []byte, r *backend.Record, status backend.StatusT, mdAppend, mdOverwrite []backend.MetadataStream) (*recordWrite, error) {
 	currStatus := r.RecordMetadata.Status
 
 	// Validate status change
@@ -720,7 +838,7 @@ func (t *tstoreBackend) RecordSetStatus(token []byte, status backend.StatusT, md
 	}
 
 	// Apply changes
-	recordMD, err := recordMetadataNew(token, r.Files,
+	recordMD, err := recordMetadataNew(token, r.Files, r.CensoredFiles,
 		state, status, version, iter)
 	if err != nil {
 		return nil, err
@@ -742,51 +860,121 @@ func (t *tstoreBackend) RecordSetStatus(token []byte, status backend.StatusT, md
 		return nil, err
 	}
 
-	// Update record status
-	switch status {
-	case backend.StatusPublic:
-		err := t.setStatusPublic(token, *recordMD, metadata, r.Files)
-		if err != nil {
-			return nil, err
-		}
-	case backend.StatusArchived:
-		err := t.setStatusArchived(token, *recordMD, metadata, r.Files)
-		if err != nil {
-			return nil, err
-		}
-	case backend.StatusCensored:
-		err := t.setStatusCensored(token, *recordMD, metadata, r.Files)
-		if err != nil {
-			return nil, err
-		}
-	default:
-		// Should not happen
-		return nil, fmt.Errorf("unknown status %v", status)
+	// The files of a censored record are deleted
+	updated := backend.Record{
+		RecordMetadata: *recordMD,
+		Metadata:       metadata,
+		Files:          r.Files,
+		CensoredFiles:  r.CensoredFiles,
+	}
+	if status == backend.StatusCensored {
+		updated.Files = []backend.File{}
+	}
+
+	return &recordWrite{
+		record: updated,
+		save: func() error {
+			// Update record status
+			switch status {
+			case backend.StatusPublic:
+				return t.setStatusPublic(token, *recordMD, metadata, r.Files)
+			case backend.StatusArchived:
+				return t.setStatusArchived(token, *recordMD, metadata, r.Files)
+			case backend.StatusCensored:
+				return t.setStatusCensored(token, *recordMD, metadata, r.Files)
+			default:
+				// Should not happen
+				return fmt.Errorf("unknown status %v", status)
+			}
+		},
+		post: func() {
+			log.Debugf("Status updated %x from %v (%v) to %v (%v)",
+				token, backend.Statuses[currStatus], currStatus,
+				backend.Statuses[status], status)
+
+			// Call post plugin hooks
+			t.tstore.PluginHookPost(plugins.HookTypeSetRecordStatusPost,
+				string(b))
+
+			// Update inventory cache
+			switch status {
+			case backend.StatusPublic:
+				// The state is updated to vetted when a record is made
+				// public
+				t.inventoryMoveToVetted(token, status)
+			default:
+				t.inventoryUpdate(r.RecordMetadata.State, token, status)
+			}
+
+			// Update the change log
+			t.changeAppend(changeRecord(backend.ChangeRecordSetStatus,
+				*recordMD))
+		},
+	}, nil
+}
+
+// RecordCensorFiles permanently deletes the payloads of the provided record
+// files. Only the files of public and archived records can be censored. The
+// record metadata and the record tree are not modified, allowing the remaining
+// record files to still be verified against the record merkle root.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) RecordCensorFiles(token []byte, files []backend.CensorFile, reason string) ([]backend.CensoredFile, error) {
+	log.Tracef("RecordCensorFiles: %x %v", token, files)
+
+	if len(files) == 0 {
+		return nil, backend.ErrNoRecordChanges
 	}
 
-	log.Debugf("Status updated %x from %v (%v) to %v (%v)",
-		token, backend.Statuses[currStatus], currStatus,
-		backend.Statuses[status], status)
+	// Verify record exists
+	if !t.RecordExists(token) {
+		return nil, backend.ErrRecordNotFound
+	}
 
-	// Call post plugin hooks
-	t.tstore.PluginHookPost(plugins.HookTypeSetRecordStatusPost, string(b))
+	// The record lock must be held for the rest of this function so
+	// that the record is not updated while its files are being
+	// censored.
+	if t.isShutdown() {
+		return nil, backend.ErrShutdown
+	}
+	defer t.recordLock(token)()
 
-	// Update inventory cache
-	switch status {
-	case backend.StatusPublic:
-		// The state is updated to vetted when a record is made public
-		t.inventoryMoveToVetted(token, status)
+	// Verify the record status
+	r, err := t.tstore.RecordPartial(token, 0, nil, true)
+	if err != nil {
+		return nil, fmt.Errorf("RecordPartial: %v", err)
+	}
+	switch r.RecordMetadata.Status {
+	case backend.StatusPublic, backend.StatusArchived:
+		// Files of these records can be censored
 	default:
-		t.inventoryUpdate(r.RecordMetadata.State, token, status)
+		return nil, backend.ErrRecordLocked
 	}
 
-	// Return updated record
-	r, err = t.tstore.RecordLatest(token)
+	// Censor the files
+	cf, err := t.tstore.RecordCensorFiles(token, files, reason, t.identity)
 	if err != nil {
-		return nil, fmt.Errorf("RecordLatest: %v", err)
+		return nil, err
 	}
 
-	return r, nil
+	log.Debugf("Files censored %x: %v", token, len(cf))
+
+	// Call post plugin hooks
+	hcf := plugins.HookCensorFiles{
+		RecordMetadata: r.RecordMetadata,
+		CensoredFiles:  cf,
+	}
+	b, err := json.Marshal(hcf)
+	if err != nil {
+		return nil, err
+	}
+	t.tstore.PluginHookPost(plugins.HookTypeCensorFilesPost, string(b))
+
+	// Update the change log
+	t.changeAppend(changeRecord(backend.ChangeRecordCensorFiles,
+		r.RecordMetadata))
+
+	return cf, nil
 }
 
 // RecordExists returns whether a record exists.
@@ -816,6 +1004,136 @@ func (t *tstoreBackend) RecordExists(token []byte) bool {
 	return t.tstore.RecordExists(token)
 }
 
+// Transaction applies the provided operations to a record atomically. The
+// operations are applied in order while the record lock is held. Either all of
+// the operations are applied or, if any of the operations fails, none of them
+// are. A TxError is returned that contains the index of the operation that
+// failed. A TxPartialError is returned if the operations were only partially
+// committed onto the record tree. The post plugin hooks are not executed in
+// that case and the record should be checked by fsck.
+//
+// Each operation is prepared against the record as it will be once the prior
+// operations have been applied. The pre plugin hooks of all of the operations
+// are executed before any of the operations are saved. The post plugin hooks
+// are executed once the transaction has been committed.
+//
+// The plugin caches are shared between records. A transaction that contains
+// plugin writes blocks all other writes while it is in progress so that the
+// writes that its plugin commands make to the plugin caches can be undone.
+// The writes that a plugin command makes to other records are not part of the
+// transaction and are rejected.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) Transaction(token []byte, ops []backend.TxOp) (*backend.TxReply, error) {
+	log.Tracef("Transaction: %x %v ops", token, len(ops))
+
+	if len(ops) == 0 {
+		return nil, backend.ErrNoRecordChanges
+	}
+
+	// Verify record exists
+	if !t.RecordExists(token) {
+		return nil, backend.ErrRecordNotFound
+	}
+
+	// The record lock must be held for the duration of the
+	// transaction. A transaction that executes plugin commands
+	// must not overlap with any other writes so that its plugin
+	// cache writes can be undone.
+	if t.isShutdown() {
+		return nil, backend.ErrShutdown
+	}
+	var pluginWrites bool
+	for _, v := range ops {
+		if v.Type == backend.TxOpPluginWrite {
+			pluginWrites = true
+		}
+	}
+	if pluginWrites {
+		t.tstore.TxPluginsBegin()
+		defer t.tstore.TxPluginsEnd()
+	} else {
+		t.tstore.WriteBegin()
+		defer t.tstore.WriteEnd()
+	}
+	m := t.recordMutex(token)
+	m.Lock()
+	defer m.Unlock()
+
+	// Prepare the operations and execute their pre plugin hooks.
+	// Nothing is saved until all of the operations have been
+	// prepared.
+	r, err := t.tstore.RecordLatest(token)
+	if err != nil {
+		return nil, fmt.Errorf("RecordLatest: %v", err)
+	}
+	var (
+		writes   = make([]*recordWrite, 0, len(ops))
+		payloads = make([]string, len(ops))
+	)
+	for i, v := range ops {
+		var w *recordWrite
+		switch v.Type {
+		case backend.TxOpRecordEdit:
+			w, err = t.recordEdit(token, r, v.MDAppend, v.MDOverwrite,
+				v.FilesAdd, v.FilesDel)
+		case backend.TxOpRecordEditMetadata:
+			w, err = t.recordEditMetadata(token, r, v.MDAppend, v.MDOverwrite)
+		case backend.TxOpRecordSetStatus:
+			w, err = t.recordSetStatus(token, r, v.Status, v.MDAppend,
+				v.MDOverwrite)
+		case backend.TxOpPluginWrite:
+			w, err = t.pluginWriteTx(token, r, v.PluginID, v.PluginCmd,
+				v.Payload, &payloads[i])
+		default:
+			err = backend.ErrTxOpInvalid
+		}
+		if err != nil {
+			return nil, backend.TxError{
+				Index: i,
+				Err:   err,
+			}
+		}
+		writes = append(writes, w)
+		r = &w.record
+	}
+
+	// Save the operations
+	err = t.tstore.Tx(token, func() error {
+		for i, w := range writes {
+			err := w.save()
+			if err != nil {
+				return backend.TxError{
+					Index: i,
+					Err:   err,
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Transaction committed %x: %v ops", token, len(ops))
+
+	// Execute the post plugin hooks of the operations
+	for _, w := range writes {
+		w.post()
+	}
+
+	// Get the updated record
+	r, err = t.tstore.RecordLatest(token)
+	if err != nil {
+		return nil, fmt.Errorf("RecordLatest: %v", err)
+	}
+
+	return &backend.TxReply{
+		Record:   *r,
+		Payloads: payloads,
+	}, nil
+}
+
 // RecordTimestamps returns the timestamps for a record. If no version is
 // provided then timestamps for the most recent version will be returned.
 //
@@ -826,6 +1144,28 @@ func (t *tstoreBackend) RecordTimestamps(token []byte, version uint32) (*backend
 	return t.tstore.RecordTimestamps(token, version)
 }
 
+// RecordDiff returns the changes that were made to a record between two
+// record versions. If no to version is provided then the most recent version
+// is used. If no from version is provided then the version prior to the to
+// version is used.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) RecordDiff(token []byte, fromVersion, toVersion uint32) (*backend.RecordDiff, error) {
+	log.Tracef("RecordDiff: %x %v %v", token, fromVersion, toVersion)
+
+	return t.tstore.RecordDiff(token, fromVersion, toVersion)
+}
+
+// RecordHistory returns an entry for every iteration of a record, sorted from
+// oldest to newest.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) RecordHistory(token []byte) ([]backend.RecordHistoryEntry, error) {
+	log.Tracef("RecordHistory: %x", token)
+
+	return t.tstore.RecordHistory(token)
+}
+
 // Records retreives a batch of records. Individual record errors are not
 // returned. If the record was not found then it will not be included in the
 // returned map.
@@ -865,17 +1205,19 @@ func (t *tstoreBackend) Records(reqs []backend.RecordRequest) (map[string]backen
 // record state and record status. The tokens are ordered by the timestamp of
 // their most recent status change, sorted from newest to oldest.
 //
-// The state, status, and page arguments can be provided to request a specific
-// page of record tokens.
+// The state, status, and page or cursor arguments can be provided to request
+// a specific page of record tokens. The page number is ignored if a cursor is
+// provided.
 //
 // If no status is provided then the most recent page of tokens for all
 // statuses will be returned. All other arguments are ignored.
 //
 // This function satisfies the backendv2 Backend interface.
-func (t *tstoreBackend) Inventory(state backend.StateT, status backend.StatusT, pageSize, pageNumber uint32) (*backend.Inventory, error) {
-	log.Tracef("Inventory: %v %v %v %v", state, status, pageSize, pageNumber)
+func (t *tstoreBackend) Inventory(state backend.StateT, status backend.StatusT, pageSize, pageNumber uint32, cursor string) (*backend.Inventory, error) {
+	log.Tracef("Inventory: %v %v %v %v %v",
+		state, status, pageSize, pageNumber, cursor)
 
-	inv, err := t.invByStatus(state, status, pageSize, pageNumber)
+	inv, err := t.invByStatus(state, status, pageSize, pageNumber, cursor)
 	if err != nil {
 		return nil, err
 	}
@@ -883,23 +1225,53 @@ func (t *tstoreBackend) Inventory(state backend.StateT, status backend.StatusT,
 	return &backend.Inventory{
 		Unvetted: inv.Unvetted,
 		Vetted:   inv.Vetted,
+		Cursor:   inv.Cursor,
 	}, nil
 }
 
 // InventoryOrdered returns a page of record tokens ordered by the timestamp of
 // their most recent status change. The returned tokens will include all record
-// statuses.
+// statuses. The page number is ignored if a cursor is provided. The cursor of
+// the following page is returned when the page is full.
 //
 // This function satisfies the backendv2 Backend interface.
-func (t *tstoreBackend) InventoryOrdered(state backend.StateT, pageSize, pageNumber uint32) ([]string, error) {
-	log.Tracef("InventoryOrdered: %v %v %v", state, pageSize, pageNumber)
+func (t *tstoreBackend) InventoryOrdered(state backend.StateT, pageSize, pageNumber uint32, cursor string) ([]string, string, error) {
+	log.Tracef("InventoryOrdered: %v %v %v %v",
+		state, pageSize, pageNumber, cursor)
 
-	tokens, err := t.invOrdered(state, pageSize, pageNumber)
+	tokens, next, err := t.invOrdered(state, pageSize, pageNumber, cursor)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return tokens, nil
+	return tokens, next, nil
+}
+
+// InventoryCounts returns the number of records in the inventory categorized
+// by record state and record status.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) InventoryCounts() (*backend.InventoryCounts, error) {
+	log.Tracef("InventoryCounts")
+
+	return t.invCounts()
+}
+
+// TlogPublicKey returns the public key that the log roots of the tlog trees
+// are signed with. nil is returned when a trillian log server is used.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) TlogPublicKey() []byte {
+	return t.tstore.TlogPublicKey()
+}
+
+// Health returns the health of the tlog and of the key-value store.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) Health() map[string]error {
+	log.Tracef("Health")
+
+	return t.tstore.Health()
 }
 
 // PluginRegister registers a plugin.
@@ -931,7 +1303,14 @@ func (t *tstoreBackend) PluginRead(token []byte, pluginID, pluginCmd, payload st
 	}
 
 	// Execute plugin command
-	return t.tstore.PluginRead(token, pluginID, pluginCmd, payload)
+	start := time.Now()
+	reply, err := t.tstore.PluginRead(token, pluginID, pluginCmd, payload)
+	pluginCmdMetrics(pluginID, pluginCmd, metrics.PluginCmdRead, err, start)
+	if err != nil {
+		return "", err
+	}
+
+	return reply, nil
 }
 
 // PluginWrite executes a plugin command that writes data.
@@ -954,11 +1333,55 @@ func (t *tstoreBackend) PluginWrite(token []byte, pluginID, pluginCmd, payload s
 	if t.isShutdown() {
 		return "", backend.ErrShutdown
 	}
+	err := t.tstore.PluginWriteBegin()
+	if err != nil {
+		return "", err
+	}
+	defer t.tstore.WriteEnd()
 	m := t.recordMutex(token)
 	m.Lock()
 	defer m.Unlock()
 
-	// Call pre plugin hooks
+	err = t.pluginWritePre(token, pluginID, pluginCmd, payload)
+	if err != nil {
+		return "", err
+	}
+	reply, post, err := t.pluginWrite(token, pluginID, pluginCmd, payload)
+	if err != nil {
+		return "", err
+	}
+	post()
+
+	return reply, nil
+}
+
+// pluginWriteTx prepares a plugin write that is executed as part of a
+// transaction. The pre plugin hooks are executed immediately. The plugin
+// command is executed when the write is saved and its reply is set on the
+// provided reply. A plugin write does not change the record.
+//
+// This function must be called WITH the record lock held.
+func (t *tstoreBackend) pluginWriteTx(token []byte, r *backend.Record, pluginID, pluginCmd, payload string, reply *string) (*recordWrite, error) {
+	err := t.pluginWritePre(token, pluginID, pluginCmd, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &recordWrite{
+		record: *r,
+	}
+	w.save = func() error {
+		var err error
+		*reply, w.post, err = t.pluginWrite(token, pluginID, pluginCmd,
+			payload)
+		return err
+	}
+
+	return w, nil
+}
+
+// pluginWritePre executes the pre plugin hooks of a plugin write.
+func (t *tstoreBackend) pluginWritePre(token []byte, pluginID, pluginCmd, payload string) error {
 	hp := plugins.HookPluginPre{
 		Token:    token,
 		PluginID: pluginID,
@@ -967,33 +1390,58 @@ func (t *tstoreBackend) PluginWrite(token []byte, pluginID, pluginCmd, payload s
 	}
 	b, err := json.Marshal(hp)
 	if err != nil {
-		return "", err
-	}
-	err = t.tstore.PluginHookPre(plugins.HookTypePluginPre, string(b))
-	if err != nil {
-		return "", err
+		return err
 	}
+	return t.tstore.PluginHookPre(plugins.HookTypePluginPre, string(b))
+}
 
+// pluginWrite executes a plugin command that writes data. The pre plugin hooks
+// must have already been executed. The returned function executes the post
+// plugin hooks and must be called once the changes have been committed.
+//
+// This function must be called WITH the record lock held.
+func (t *tstoreBackend) pluginWrite(token []byte, pluginID, pluginCmd, payload string) (string, func(), error) {
 	// Execute plugin command
+	start := time.Now()
 	reply, err := t.tstore.PluginWrite(token, pluginID, pluginCmd, payload)
+	pluginCmdMetrics(pluginID, pluginCmd, metrics.PluginCmdWrite, err, start)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 
-	// Call post plugin hooks
+	// Prepare the post plugin hooks payload
 	hpp := plugins.HookPluginPost{
 		PluginID: pluginID,
 		Cmd:      pluginCmd,
 		Payload:  payload,
 		Reply:    reply,
 	}
-	b, err = json.Marshal(hpp)
+	b, err := json.Marshal(hpp)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
-	t.tstore.PluginHookPost(plugins.HookTypePluginPost, string(b))
 
-	return reply, nil
+	return reply, func() {
+		// Call post plugin hooks
+		t.tstore.PluginHookPost(plugins.HookTypePluginPost, string(b))
+
+		// Update the change log
+		t.changeAppend(changePluginWrite(token, pluginID, pluginCmd))
+	}, nil
+}
+
+// pluginCmdMetrics records the duration of a plugin command that started at
+// the provided time and counts the command as failed if an error occurred.
+func pluginCmdMetrics(pluginID, pluginCmd, cmdType string, err error, start time.Time) {
+	var errKind string
+	if err != nil {
+		var pe backend.PluginError
+		errKind = metrics.PluginErrBackend
+		if errors.As(err, &pe) {
+			errKind = metrics.PluginErrPlugin
+		}
+	}
+	metrics.PluginCmd(pluginID, pluginCmd, cmdType, errKind, start)
 }
 
 // PluginInventory returns all registered plugins.
@@ -1006,120 +1454,183 @@ func (t *tstoreBackend) PluginInventory() []backend.Plugin {
 }
 
 // Fsck performs a synchronous filesystem check that verifies the coherency
-// of record and plugin data and caches.
+// of record and plugin data and caches. The backend remains online during the
+// check. Problems are repaired one record at a time while holding the record
+// lock so that a repair does not race with a concurrent write.
 //
 // This function satisfies the backendv2 Backend interface.
-func (t *tstoreBackend) Fsck() error {
+func (t *tstoreBackend) Fsck(opts backend.FsckOpts) (*backend.FsckReport, error) {
 	log.Infof("Performing fsck on the tstore backend")
+	if opts.DryRun {
+		log.Infof("Fsck dry run; problems will not be repaired")
+	}
+	if opts.Progress == nil {
+		opts.Progress = func(string, int, int) {}
+	}
 
 	// The tstore backend fsck includes:
 	//
-	// - Rebuilding the inventory cache. The inventory cache contains
-	//   the tokens of all records in backend, categorized by their
-	//   record status and sorted from oldest to newest.
+	// - Verifying that the blobs of all records exist in the key-value
+	//   store.
+	//
+	// - Verifying the inventory cache. The inventory cache contains
+	//   the tokens of all records in backend, indexed by their record
+	//   state and status and sorted by the timestamp of their most
+	//   recent status change.
+	//
+	// - Removing the blobs that are not referenced by any tree. These
+	//   are left behind when politeiad stops in the middle of a write,
+	//   e.g. during a transaction.
 
 	// Get the tokens for all records in the backend
 	allTokens, err := t.tstore.Inventory()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Infof("%v records found in the tstore backend", len(allTokens))
 
-	// Categorize the records by their record state, vetted or
-	// unvetted, then sort each category by timestamp.
-	records := make(map[string]*backend.Record, len(allTokens))
+	issues := make([]backend.FsckIssue, 0, 64)
 	for i, token := range allTokens {
+		if t.isShutdown() {
+			return nil, backend.ErrShutdown
+		}
 		if i%50 == 0 {
-			log.Infof("Sorting records by timestamp %v/%v", i+1, len(allTokens))
+			log.Infof("Verifying records %v/%v", i+1, len(allTokens))
 		}
-		r, err := t.tstore.RecordPartial(token, 0, nil, true)
+		ri, err := t.fsckRecord(token, opts.DryRun)
 		if err != nil {
-			return err
-		}
-		records[r.RecordMetadata.Token] = r
-	}
-	var (
-		vetted   = make([]*backend.Record, 0, len(allTokens))
-		unvetted = make([]*backend.Record, 0, len(allTokens))
-	)
-	for _, token := range allTokens {
-		record := records[hex.EncodeToString(token)]
-		if record.RecordMetadata.State == backend.StateVetted {
-			vetted = append(vetted, record)
-		}
-		if record.RecordMetadata.State == backend.StateUnvetted {
-			unvetted = append(unvetted, record)
+			return nil, fmt.Errorf("fsck record %x: %v", token, err)
 		}
+		issues = append(issues, ri...)
+
+		opts.Progress("records", i+1, len(allTokens))
 	}
 
-	// Sort records by the timestamp of their record metadata, from
-	// oldest to newest.
-	//
-	// The order of the record inventory when rebuilt by this function
-	// will be slightly different than the order that is created at
-	// runtime. At runtime, the inventory cache updated when the status
-	// of the record is changed. This fsck function uses the record
-	// metadata timestamp, which is updated anytime the record status
-	// is changed, but also when the record is edited. This means that
-	// the runtime cache that was built will differ from the fsck cache
-	// that is built if there are records that were edited after their
-	// most recent status change. This difference is inconsequential,
-	// so just making a note of it is fine for now.
-	sort.Slice(vetted, func(i, j int) bool {
-		return vetted[i].RecordMetadata.Timestamp <
-			vetted[j].RecordMetadata.Timestamp
-	})
-	sort.Slice(unvetted, func(i, j int) bool {
-		return unvetted[i].RecordMetadata.Timestamp <
-			unvetted[j].RecordMetadata.Timestamp
-	})
+	// Verify that the inventory does not contain any entries for
+	// records that do not exist.
+	log.Infof("Verifying the inventory cache")
 
-	// Delete the inventory cache. We must actually delete
-	// the cache and rebuilt it from scratch instead of just
-	// checking the coherency of it because of the ordering
-	// difference that is noted in the comment above.
-	err = t.invRemoveVetted()
+	ii, err := t.invFsck(allTokens, opts.DryRun)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = t.invRemoveUnvetted()
+	issues = append(issues, ii...)
+
+	// Perform a tstore fsck. This will fsck all plugins all well.
+	ti, err := t.tstore.Fsck(allTokens, opts, t.fsckRecordLock)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	issues = append(issues, ti...)
 
-	// Build the vetted inventory cache.
-	//
-	// This is done by adding the record to the unvetted inventory
-	// then moving it to the vetted inventory. This is how it is
-	// done during normal operations, so we do it the same way here
-	// because that is what the inventory API allows for.
-	log.Infof("Building the inventory cache for %v vetted records", len(vetted))
-
-	for _, record := range vetted {
-		bToken, err := hex.DecodeString(record.RecordMetadata.Token)
-		if err != nil {
-			return err
+	log.Infof("Fsck complete; %v problems found", len(issues))
+
+	return &backend.FsckReport{
+		DryRun:  opts.DryRun,
+		Records: len(allTokens),
+		Issues:  issues,
+	}, nil
+}
+
+// fsckRecord verifies the blobs and the inventory entry of a record.
+func (t *tstoreBackend) fsckRecord(token []byte, dryRun bool) ([]backend.FsckIssue, error) {
+	unlock := t.fsckRecordLock(token)
+	defer unlock()
+
+	issues, err := t.tstore.FsckRecord(token)
+	if errors.Is(err, backend.ErrRecordNotFound) {
+		// The tree exists, but the record has not been saved
+		// to it yet. This happens when a record is created
+		// while the fsck is running.
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Verify the inventory entry. The record metadata can't be
+	// retrieved if its blob is missing, which has already been
+	// reported.
+	r, err := t.tstore.RecordPartial(token, 0, nil, true)
+	if err != nil {
+		if len(issues) > 0 {
+			return issues, nil
 		}
-		t.inventoryAdd(backend.StateUnvetted, bToken, backend.StatusUnreviewed)
-		t.inventoryMoveToVetted(bToken, record.RecordMetadata.Status)
+		return nil, err
+	}
+	issue, err := t.invVerify(r.RecordMetadata, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if issue != nil {
+		issues = append(issues, *issue)
 	}
 
-	// Build the unvetted inventory cache
-	log.Infof("Building the inventory cache for %v unvetted records",
-		len(unvetted))
+	return issues, nil
+}
 
-	for _, record := range unvetted {
-		bToken, err := hex.DecodeString(record.RecordMetadata.Token)
-		if err != nil {
-			return err
-		}
-		t.inventoryAdd(record.RecordMetadata.State, bToken,
-			record.RecordMetadata.Status)
+// fsckRecordLock locks the provided record against concurrent writes and
+// returns a function that releases the lock.
+func (t *tstoreBackend) fsckRecordLock(token []byte) func() {
+	return t.recordLock(token)
+}
+
+// Reencrypt rotates the encryption key of the key-value store and
+// re-encrypts all encrypted blobs using the new key. An interrupted
+// re-encryption is resumed. The backend remains online during the
+// re-encryption.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) Reencrypt(opts backend.ReencryptOpts) (*backend.ReencryptReport, error) {
+	log.Tracef("Reencrypt")
+
+	if t.isShutdown() {
+		return nil, backend.ErrShutdown
 	}
 
-	// Perform a tstore fsck. This will fsck all plugins all well.
-	return t.tstore.Fsck(allTokens)
+	return t.tstore.Reencrypt(opts)
+}
+
+// Reencode re-encodes all blobs that were saved using a previous blob
+// encoding using the current encoding. An interrupted re-encoding is resumed.
+// The backend remains online during the re-encoding.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) Reencode(opts backend.ReencodeOpts) (*backend.ReencodeReport, error) {
+	log.Tracef("Reencode")
+
+	if t.isShutdown() {
+		return nil, backend.ErrShutdown
+	}
+
+	return t.tstore.Reencode(opts)
+}
+
+// AnchorStatus returns the status of the anchoring of the tstore trees.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) AnchorStatus() (*backend.AnchorStatus, error) {
+	log.Tracef("AnchorStatus")
+
+	if t.isShutdown() {
+		return nil, backend.ErrShutdown
+	}
+
+	return t.tstore.AnchorStatus()
+}
+
+// AnchorDrop drops an anchor for all tstore trees that have unanchored leaves
+// without waiting for the anchor schedule.
+//
+// This function satisfies the backendv2 Backend interface.
+func (t *tstoreBackend) AnchorDrop() error {
+	log.Tracef("AnchorDrop")
+
+	if t.isShutdown() {
+		return backend.ErrShutdown
+	}
+
+	return t.tstore.AnchorDrop()
 }
 
 // Close performs cleanup of the backend.
@@ -1131,8 +1642,11 @@ func (t *tstoreBackend) Close() {
 	t.Lock()
 	defer t.Unlock()
 
-	// Shutdown backend
+	// Shutdown backend. The queued audit log entries are appended
+	// before the tstore connections are closed.
 	t.shutdown = true
+	t.cron.Stop()
+	t.auditStop()
 
 	// Close tstore connections
 	t.tstore.Close()
@@ -1140,15 +1654,52 @@ func (t *tstoreBackend) Close() {
 
 // setup performs any required work to setup the tstore instance.
 func (t *tstoreBackend) setup() error {
-	return t.tstore.Setup()
+	err := t.tstore.Setup()
+	if err != nil {
+		return err
+	}
+
+	// Build the inventory counters and migrate the legacy inventory
+	// files
+	err = t.invCountsMigrate()
+	if err != nil {
+		return err
+	}
+	err = t.invMigrate()
+	if err != nil {
+		return err
+	}
+
+	// Restore the change log sequence number
+	err = t.changesSetup()
+	if err != nil {
+		return err
+	}
+
+	// Restore the audit log sequence number and start the audit
+	// worker
+	err = t.auditSetup()
+	if err != nil {
+		return err
+	}
+
+	// Launch the inventory snapshot job
+	return t.snapshotSetup()
 }
 
 // New returns a new tstoreBackend.
-func New(appDir, dataDir string, anp *chaincfg.Params, tlogHost, dbHost, dbPass, dcrtimeHost, dcrtimeCert string) (*tstoreBackend, error) {
+func New(appDir, dataDir string, anp *chaincfg.Params, tlogType, tlogHost, dbType, dbHost, dbPass string, providers []anchors.Provider, cold coldstore.Store, leafCacheSize, indexCacheSize int64, id *identity.FullIdentity) (*tstoreBackend, error) {
 	// Setup tstore instances
-	ts, err := tstore.New(appDir, dataDir, anp, tlogHost,
-		dbHost, dbPass, dcrtimeHost, dcrtimeCert)
+	ts, err := tstore.New(appDir, dataDir, anp, tlogType, tlogHost,
+		dbType, dbHost, dbPass, providers, cold, leafCacheSize,
+		indexCacheSize)
 	if err != nil {
+		// A connection error is returned as is so that the caller is
+		// able to retry.
+		var ce tstore.ConnError
+		if errors.As(err, &ce) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("new tstore: %v", err)
 	}
 
@@ -1158,11 +1709,17 @@ func New(appDir, dataDir string, anp *chaincfg.Params, tlogHost, dbHost, dbPass,
 		dataDir:    dataDir,
 		tstore:     ts,
 		recordMtxs: make(map[string]*sync.Mutex),
+		identity:   id,
+		cron:       cron.New(),
 	}
 
-	// Perform any required setup
+	// Perform any required setup. The connections are closed on
+	// failure.
 	err = t.setup()
 	if err != nil {
+		t.cron.Stop()
+		t.auditStop()
+		ts.Close()
 		return nil, fmt.Errorf("setup: %v", err)
 	}
 
@@ -0,0 +1,681 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tstorebe
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	backend "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/util"
+)
+
+// newFile returns a backend file for the provided data.
+func newFile(name string, payload []byte) backend.File {
+	return backend.File{
+		Name:    name,
+		MIME:    http.DetectContentType(payload),
+		Digest:  hex.EncodeToString(util.Digest(payload)),
+		Payload: base64.StdEncoding.EncodeToString(payload),
+	}
+}
+
+// verifyMerkle verifies that the record files and the censored files make up
+// the record merkle root.
+func verifyMerkle(t *testing.T, r *backend.Record) {
+	t.Helper()
+
+	digests := make([]string, 0, len(r.Files)+len(r.CensoredFiles))
+	for _, v := range r.Files {
+		digests = append(digests, v.Digest)
+	}
+	for _, v := range r.CensoredFiles {
+		digests = append(digests, v.Digest)
+	}
+	m, err := util.MerkleRoot(digests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(m[:]) != r.RecordMetadata.Merkle {
+		t.Fatalf("merkle mismatch: got %x, want %v",
+			m[:], r.RecordMetadata.Merkle)
+	}
+}
+
+func TestRecordCensorFiles(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Create a new record
+	var (
+		fileIndex  = newFile("index.md", []byte("This is my proposal."))
+		fileCensor = newFile("notes.txt", []byte("Illegal content."))
+		fileNew    = newFile("extra.txt", []byte("Some extra content."))
+	)
+	r, err := tb.RecordNew(nil, []backend.File{fileIndex, fileCensor})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(r.RecordMetadata.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	censor := []backend.CensorFile{{Version: 1, Name: fileCensor.Name}}
+
+	// Files of unvetted records can not be censored
+	_, err = tb.RecordCensorFiles(token, censor, "reason")
+	if err != backend.ErrRecordLocked {
+		t.Fatalf("got err %v, want %v", err, backend.ErrRecordLocked)
+	}
+
+	// Make the record public and add a new version. The censored file
+	// is shared between both versions.
+	_, err = tb.RecordSetStatus(token, backend.StatusPublic, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tb.RecordEdit(token, nil, nil, []backend.File{fileNew}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify invalid files are rejected
+	var ce backend.ContentError
+	invalid := []backend.CensorFile{{Version: 1, Name: fileNew.Name}}
+	_, err = tb.RecordCensorFiles(token, invalid, "reason")
+	if !errors.As(err, &ce) ||
+		ce.ErrorCode != backend.ContentErrorFileNameInvalid {
+		t.Fatalf("got err %v, want content error %v",
+			err, backend.ContentErrorFileNameInvalid)
+	}
+
+	// Censor the file. It should be censored in both versions.
+	cf, err := tb.RecordCensorFiles(token, censor, "reason")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cf) != 2 {
+		t.Fatalf("got %v censored files, want 2", len(cf))
+	}
+	for _, v := range cf {
+		if v.Name != fileCensor.Name || v.Digest != fileCensor.Digest {
+			t.Fatalf("unexpected censored file %+v", v)
+		}
+		sig, err := identity.SignatureFromString(v.Signature)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg := backend.CensoredFileMsg(r.RecordMetadata.Token, v)
+		if !tb.identity.Public.VerifyMessage([]byte(msg), *sig) {
+			t.Fatalf("invalid censored file signature %+v", v)
+		}
+	}
+
+	// Censoring the same file again is not a change
+	_, err = tb.RecordCensorFiles(token, censor, "reason")
+	if err != backend.ErrNoRecordChanges {
+		t.Fatalf("got err %v, want %v", err, backend.ErrNoRecordChanges)
+	}
+
+	// Verify the censored file is no longer returned for either version
+	// but the record merkle root can still be verified.
+	for _, version := range []uint32{1, 2} {
+		r, err := tb.tstore.Record(token, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range r.Files {
+			if v.Name == fileCensor.Name {
+				t.Fatalf("censored file returned for version %v", version)
+			}
+		}
+		if len(r.CensoredFiles) != 1 {
+			t.Fatalf("got %v censored files for version %v, want 1",
+				len(r.CensoredFiles), version)
+		}
+		verifyMerkle(t, r)
+	}
+
+	// Verify that the censored content can not be added back to the
+	// record by a new version.
+	var (
+		fileCopy = newFile("copy.txt", []byte("Illegal content."))
+		filesAdd = []backend.File{fileCopy}
+		filesDel = []string{fileNew.Name}
+	)
+	_, err = tb.RecordEdit(token, nil, nil, filesAdd, filesDel)
+	if !errors.As(err, &ce) ||
+		ce.ErrorCode != backend.ContentErrorFilePayloadInvalid {
+		t.Fatalf("got err %v, want content error %v",
+			err, backend.ContentErrorFilePayloadInvalid)
+	}
+
+	// Verify that the censored file is carried over by status changes
+	// that do not create a new record version.
+	r, err = tb.RecordSetStatus(token, backend.StatusArchived, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.CensoredFiles) != 1 {
+		t.Fatalf("got %v censored files, want 1", len(r.CensoredFiles))
+	}
+	verifyMerkle(t, r)
+}
+
+func TestRecordCensorFilesSharedContent(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Create two public records that share the content of a file. The
+	// content blob is shared between both records.
+	var (
+		fileIndex  = newFile("index.md", []byte("This is my proposal."))
+		fileShared = newFile("notes.txt", []byte("Shared content."))
+		tokens     = make([][]byte, 0, 2)
+	)
+	for i := 0; i < 2; i++ {
+		index := newFile(fileIndex.Name, []byte(fmt.Sprintf("Proposal %v", i)))
+		r, err := tb.RecordNew(nil, []backend.File{index, fileShared})
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := hex.DecodeString(r.RecordMetadata.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tb.RecordSetStatus(token, backend.StatusPublic, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	// Censor the shared file in the first record
+	censor := []backend.CensorFile{{Version: 1, Name: fileShared.Name}}
+	_, err := tb.RecordCensorFiles(tokens[0], censor, "reason")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the censored file is no longer returned by the first record
+	// even though the blob is still referenced by the second record.
+	r, err := tb.tstore.Record(tokens[0], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range r.Files {
+		if v.Name == fileShared.Name || v.Digest == fileShared.Digest {
+			t.Fatalf("censored file returned")
+		}
+	}
+	if len(r.CensoredFiles) != 1 {
+		t.Fatalf("got %v censored files, want 1", len(r.CensoredFiles))
+	}
+	verifyMerkle(t, r)
+
+	// Verify the second record still returns the file
+	r, err = tb.tstore.Record(tokens[1], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Files) != 2 || len(r.CensoredFiles) != 0 {
+		t.Fatalf("got %v files and %v censored files, want 2 and 0",
+			len(r.Files), len(r.CensoredFiles))
+	}
+	verifyMerkle(t, r)
+}
+
+func TestTransaction(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Create a new record
+	var (
+		fileIndex = newFile("index.md", []byte("This is my proposal."))
+		fileNew   = newFile("extra.txt", []byte("Some extra content."))
+		md        = backend.MetadataStream{
+			PluginID: "test",
+			StreamID: 1,
+			Payload:  `{"foo":"bar"}`,
+		}
+	)
+	r, err := tb.RecordNew(nil, []backend.File{fileIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(r.RecordMetadata.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit the record and make it public in a single transaction
+	txr, err := tb.Transaction(token, []backend.TxOp{
+		{
+			Type:     backend.TxOpRecordEdit,
+			FilesAdd: []backend.File{fileNew},
+		},
+		{
+			Type:   backend.TxOpRecordSetStatus,
+			Status: backend.StatusPublic,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = &txr.Record
+	if r.RecordMetadata.Status != backend.StatusPublic ||
+		r.RecordMetadata.State != backend.StateVetted {
+		t.Fatalf("got status %v state %v, want public vetted",
+			r.RecordMetadata.Status, r.RecordMetadata.State)
+	}
+	if len(r.Files) != 2 {
+		t.Fatalf("got %v files, want 2", len(r.Files))
+	}
+	if len(txr.Payloads) != 2 {
+		t.Fatalf("got %v payloads, want 2", len(txr.Payloads))
+	}
+	verifyMerkle(t, r)
+
+	// A transaction with a failing operation must not apply any of
+	// its operations.
+	_, err = tb.Transaction(token, []backend.TxOp{
+		{
+			Type:     backend.TxOpRecordEditMetadata,
+			MDAppend: []backend.MetadataStream{md},
+		},
+		{
+			Type:   backend.TxOpRecordSetStatus,
+			Status: backend.StatusUnreviewed,
+		},
+	})
+	var (
+		te  backend.TxError
+		ste backend.StatusTransitionError
+	)
+	if !errors.As(err, &te) || te.Index != 1 || !errors.As(err, &ste) {
+		t.Fatalf("got err %v, want status transition error on op 1", err)
+	}
+	got, err := tb.tstore.RecordLatest(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RecordMetadata.Iteration != r.RecordMetadata.Iteration ||
+		len(got.Metadata) != 0 {
+		t.Fatalf("record was modified by a failed transaction: %+v",
+			got.RecordMetadata)
+	}
+
+	// Invalid operations are rejected
+	_, err = tb.Transaction(token, []backend.TxOp{{Type: backend.TxOpInvalid}})
+	if !errors.Is(err, backend.ErrTxOpInvalid) {
+		t.Fatalf("got err %v, want %v", err, backend.ErrTxOpInvalid)
+	}
+
+	// The record can still be updated once a transaction has been
+	// rolled back.
+	r, err = tb.RecordEditMetadata(token, []backend.MetadataStream{md}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.Iteration != got.RecordMetadata.Iteration+1 {
+		t.Fatalf("got iteration %v, want %v", r.RecordMetadata.Iteration,
+			got.RecordMetadata.Iteration+1)
+	}
+}
+
+func TestDrafts(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Create a new draft
+	var (
+		fileIndex = newFile("index.md", []byte("This is my draft."))
+		fileNew   = newFile("extra.txt", []byte("Some extra content."))
+	)
+	d, err := tb.DraftNew(nil, []backend.File{fileIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.RecordMetadata.State != backend.StateDraft {
+		t.Fatalf("got state %v, want draft", d.RecordMetadata.State)
+	}
+	token, err := hex.DecodeString(d.RecordMetadata.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Drafts are not records
+	if tb.RecordExists(token) {
+		t.Fatalf("draft exists as a record")
+	}
+
+	// Edit the draft
+	d, err = tb.DraftEdit(token, nil, nil, []backend.File{fileNew}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.RecordMetadata.Version != 2 || len(d.Files) != 2 {
+		t.Fatalf("got version %v with %v files, want version 2 with 2 files",
+			d.RecordMetadata.Version, len(d.Files))
+	}
+	verifyMerkle(t, d)
+
+	// Promote the draft
+	r, err := tb.DraftPromote(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RecordMetadata.State != backend.StateUnvetted ||
+		r.RecordMetadata.Version != 1 || len(r.Files) != 2 {
+		t.Fatalf("got state %v version %v with %v files, want unvetted "+
+			"version 1 with 2 files", r.RecordMetadata.State,
+			r.RecordMetadata.Version, len(r.Files))
+	}
+	if r.RecordMetadata.Merkle != d.RecordMetadata.Merkle {
+		t.Fatalf("got merkle %v, want %v",
+			r.RecordMetadata.Merkle, d.RecordMetadata.Merkle)
+	}
+
+	// The draft is deleted once it has been promoted
+	_, err = tb.Draft(token)
+	if !errors.Is(err, backend.ErrRecordNotFound) {
+		t.Fatalf("got error %v, want %v", err, backend.ErrRecordNotFound)
+	}
+	err = tb.DraftDel(token)
+	if !errors.Is(err, backend.ErrRecordNotFound) {
+		t.Fatalf("got error %v, want %v", err, backend.ErrRecordNotFound)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Drafts are not added to the change log
+	fileIndex := newFile("index.md", []byte("This is my record."))
+	_, err := tb.DraftNew(nil, []backend.File{fileIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := tb.Changes(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 0 {
+		t.Fatalf("got %v changes, want 0", len(c))
+	}
+
+	// Appending a change notifies the waiting callers
+	notify := tb.ChangesNotify()
+	r, err := tb.RecordNew(nil, []backend.File{fileIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-notify:
+	default:
+		t.Fatalf("change notification not sent")
+	}
+	token, err := hex.DecodeString(r.RecordMetadata.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tb.RecordSetStatus(token, backend.StatusPublic, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileNew := newFile("extra.txt", []byte("Some extra content."))
+	_, err = tb.RecordEdit(token, nil, nil, []backend.File{fileNew}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the change log
+	want := []struct {
+		ct      backend.ChangeT
+		state   backend.StateT
+		status  backend.StatusT
+		version uint32
+	}{
+		{backend.ChangeRecordNew, backend.StateUnvetted,
+			backend.StatusUnreviewed, 1},
+		{backend.ChangeRecordSetStatus, backend.StateVetted,
+			backend.StatusPublic, 1},
+		{backend.ChangeRecordEdit, backend.StateVetted,
+			backend.StatusPublic, 2},
+	}
+	c, err = tb.Changes(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != len(want) {
+		t.Fatalf("got %v changes, want %v", len(c), len(want))
+	}
+	for i, v := range c {
+		w := want[i]
+		if v.Sequence != uint64(i+1) || v.Type != w.ct ||
+			v.Token != r.RecordMetadata.Token || v.State != w.state ||
+			v.Status != w.status || v.Version != w.version {
+			t.Fatalf("got change %+v, want %+v", v, w)
+		}
+	}
+
+	// Changes are returned after the provided sequence number and
+	// are limited to the provided page size.
+	c, err = tb.Changes(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 1 || c[0].Sequence != 2 {
+		t.Fatalf("got changes %+v, want sequence 2", c)
+	}
+	c, err = tb.Changes(3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 0 {
+		t.Fatalf("got %v changes, want 0", len(c))
+	}
+
+	// The sequence number is restored on startup
+	tb.changeSeq = 0
+	err = tb.changesSetup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tb.changeSeq != 3 {
+		t.Fatalf("got sequence %v, want 3", tb.changeSeq)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// No snapshot exists yet
+	_, _, err := tb.Snapshot(0)
+	if !errors.Is(err, backend.ErrSnapshotNotFound) {
+		t.Fatalf("got err %v, want %v", err, backend.ErrSnapshotNotFound)
+	}
+
+	// Create an unvetted and a vetted record
+	fileIndex := newFile("index.md", []byte("This is my record."))
+	tokens := make([][]byte, 0, 2)
+	for i := 0; i < 2; i++ {
+		r, err := tb.RecordNew(nil, []backend.File{fileIndex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := hex.DecodeString(r.RecordMetadata.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	_, err = tb.RecordSetStatus(tokens[1], backend.StatusPublic, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Take a snapshot. A second snapshot is not taken when the inventory
+	// has not changed.
+	err = tb.snapshotNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tb.snapshotNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _, err := tb.Snapshot(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Sequence != 1 {
+		t.Fatalf("got snapshot %v, want 1", s.Sequence)
+	}
+	err = backend.VerifySnapshot(*s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Verify the inclusion proofs. The snapshot has not been anchored.
+	for _, token := range tokens {
+		si, err := tb.SnapshotProof(token, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = backend.VerifySnapshotInclusion(*si)
+		if !errors.Is(err, backend.ErrNotTimestamped) {
+			t.Fatalf("got err %v, want %v", err, backend.ErrNotTimestamped)
+		}
+	}
+
+	// A tampered record merkle root fails verification
+	si, err := tb.SnapshotProof(tokens[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	si.Merkle = si.Snapshot.Roots[1].MerkleRoot
+	err = backend.VerifySnapshotInclusion(*si)
+	if err == nil || errors.Is(err, backend.ErrNotTimestamped) {
+		t.Fatalf("tampered snapshot inclusion verified")
+	}
+
+	// A new record results in a new snapshot that the record is included
+	// in, but not in the previous one.
+	r, err := tb.RecordNew(nil, []backend.File{fileIndex})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := hex.DecodeString(r.RecordMetadata.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tb.snapshotNew()
+	if err != nil {
+		t.Fatal(err)
+	}
+	si, err = tb.SnapshotProof(token, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if si.Snapshot.Sequence != 2 {
+		t.Fatalf("got snapshot %v, want 2", si.Snapshot.Sequence)
+	}
+	_, err = tb.SnapshotProof(token, 1)
+	if !errors.Is(err, backend.ErrRecordNotFound) {
+		t.Fatalf("got err %v, want %v", err, backend.ErrRecordNotFound)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	tb, cleanup := NewTestTstoreBackend(t)
+	defer cleanup()
+
+	// Append the audit entries
+	entries := []backend.AuditEntry{
+		{Route: "/v2/recordnew", Credential: "www", Token: "a"},
+		{Route: "/v2/pluginwrite", Credential: "voter", Token: "a",
+			PluginID: "ticketvote", PluginCmd: "castballot"},
+		{Route: "/v2/recordsetstatus", Credential: "www", Token: "b"},
+	}
+	for _, v := range entries {
+		err := tb.AuditAppend(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	tb.auditFlush()
+
+	// Verify the queries
+	var tests = []struct {
+		name   string
+		filter backend.AuditFilter
+		limit  uint32
+		want   []uint64
+	}{
+		{"all", backend.AuditFilter{}, 10, []uint64{1, 2, 3}},
+		{"limit", backend.AuditFilter{}, 2, []uint64{1, 2}},
+		{"after", backend.AuditFilter{After: 2}, 10, []uint64{3}},
+		{"credential", backend.AuditFilter{Credential: "www"}, 10,
+			[]uint64{1, 3}},
+		{"token", backend.AuditFilter{Token: "a"}, 10, []uint64{1, 2}},
+		{"plugin", backend.AuditFilter{PluginID: "ticketvote"}, 10,
+			[]uint64{2}},
+		{"route", backend.AuditFilter{Route: "/v2/recordnew"}, 10,
+			[]uint64{1}},
+		{"no match", backend.AuditFilter{Token: "c"}, 10, []uint64{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := tb.Audit(tc.filter, tc.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]uint64, 0, len(e))
+			for _, v := range e {
+				got = append(got, v.Sequence)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// Verify the timestamps. The audit tree has not been anchored.
+	e, err := tb.Audit(backend.AuditFilter{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err := tb.AuditTimestamps([]uint64{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != len(e) {
+		t.Fatalf("got %v timestamps, want %v", len(ts), len(e))
+	}
+	for _, v := range e {
+		var te backend.AuditEntry
+		err = json.Unmarshal([]byte(ts[v.Sequence].Data), &te)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if te != v {
+			t.Fatalf("got timestamp data %+v, want %+v", te, v)
+		}
+		err = backend.VerifyTimestamp(ts[v.Sequence])
+		if !errors.Is(err, backend.ErrNotTimestamped) {
+			t.Fatalf("got err %v, want %v", err, backend.ErrNotTimestamped)
+		}
+	}
+}
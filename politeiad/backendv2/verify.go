@@ -1,16 +1,28 @@
-// Copyright (c) 2020-2021 The Decred developers
+// Copyright (c) 2020-2022 The Decred developers
 // Use of this source code is governed by an ISC
 // license that can be found in the LICENSE file.
 
 package backendv2
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/decred/dcrtime/merkle"
 	dmerkle "github.com/decred/dcrtime/merkle"
@@ -26,6 +38,14 @@ const (
 
 	// ProofTypeDcrtime represents a dcrtime proof.
 	ProofTypeDcrtime = "dcrtime"
+
+	// ProofTypeRFC3161 represents an RFC 3161 time-stamp token that was
+	// issued by a Time-Stamp Authority.
+	ProofTypeRFC3161 = "rfc3161"
+
+	// ProofTypeOpenTimestamps represents an OpenTimestamps proof that
+	// commits to a bitcoin block merkle root.
+	ProofTypeOpenTimestamps = "opentimestamps"
 )
 
 // ExtraDataTrillianRFC6962 contains the extra data required to verify a
@@ -145,13 +165,537 @@ func verifyProofDcrtime(p Proof) error {
 	return nil
 }
 
-// verifyProof verifies a backend proof.
-func verifyProof(p Proof) error {
+// ExtraDataRFC3161 contains the extra data required to verify an RFC 3161
+// time-stamp token. The digest of the proof is the message imprint of the
+// token. The token does not aggregate digests, so the merkle root of the proof
+// is the digest itself and the merkle path is empty.
+type ExtraDataRFC3161 struct {
+	Token string `json:"token"` // DER encoded TimeStampToken, base64 encoded
+}
+
+// ASN.1 object identifiers that are used by RFC 3161 time-stamp tokens.
+var (
+	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidSHA256        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// The following types are the ASN.1 structures of a time-stamp token. A
+// time-stamp token is a CMS SignedData structure (RFC 5652) that contains a
+// TSTInfo structure (RFC 3161).
+type (
+	contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+
+	signedData struct {
+		Version          int
+		DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+		EncapContentInfo encapContentInfo
+		Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+		CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+		SignerInfos      []signerInfo  `asn1:"set"`
+	}
+
+	encapContentInfo struct {
+		EContentType asn1.ObjectIdentifier
+		EContent     []byte `asn1:"explicit,optional,tag:0"`
+	}
+
+	signerInfo struct {
+		Version            int
+		SID                asn1.RawValue
+		DigestAlgorithm    pkix.AlgorithmIdentifier
+		SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+		SignatureAlgorithm pkix.AlgorithmIdentifier
+		Signature          []byte
+		UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+	}
+
+	issuerAndSerial struct {
+		Issuer asn1.RawValue
+		Serial *big.Int
+	}
+
+	attribute struct {
+		Type   asn1.ObjectIdentifier
+		Values asn1.RawValue `asn1:"set"`
+	}
+
+	// tstInfo only contains the leading TSTInfo fields. The optional
+	// fields that follow the GenTime are not used.
+	tstInfo struct {
+		Version        int
+		Policy         asn1.ObjectIdentifier
+		MessageImprint messageImprint
+		SerialNumber   *big.Int
+		GenTime        time.Time `asn1:"generalized"`
+	}
+
+	messageImprint struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		HashedMessage []byte
+	}
+)
+
+var (
+	// tsaRoots contains the root certificates of the Time-Stamp
+	// Authorities that are trusted to issue RFC 3161 time-stamp tokens.
+	// The system root certificates are used when it is nil.
+	tsaRoots    *x509.CertPool
+	tsaRootsMtx sync.RWMutex
+)
+
+// SetTSARoots sets the root certificates of the Time-Stamp Authorities that
+// are trusted to issue RFC 3161 time-stamp tokens. The system root
+// certificates are trusted if no roots are set.
+func SetTSARoots(roots *x509.CertPool) {
+	tsaRootsMtx.Lock()
+	defer tsaRootsMtx.Unlock()
+
+	tsaRoots = roots
+}
+
+// LoadTSARoots returns a certificate pool that contains the PEM encoded
+// certificates of the provided file.
+func LoadTSARoots(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("no certificates found in %v", path)
+	}
+	return roots, nil
+}
+
+// verifyProofRFC3161 verifies a proof with the type ProofTypeRFC3161.
+//
+// The token must contain the certificate of the Time-Stamp Authority that
+// signed it. The certificate must chain to one of the trusted TSA roots, see
+// SetTSARoots, and must be valid for time stamping at the time that the token
+// was issued. The token signature is verified using this certificate.
+func verifyProofRFC3161(p Proof) error {
+	if p.Type != ProofTypeRFC3161 {
+		return fmt.Errorf("invalid proof type")
+	}
+	if p.MerkleRoot != p.Digest {
+		return fmt.Errorf("merkle root %v does not match digest %v",
+			p.MerkleRoot, p.Digest)
+	}
+	digest, err := hex.DecodeString(p.Digest)
+	if err != nil {
+		return err
+	}
+
+	// Decode extra data
+	var ed ExtraDataRFC3161
+	err = json.Unmarshal([]byte(p.ExtraData), &ed)
+	if err != nil {
+		return err
+	}
+	token, err := base64.StdEncoding.DecodeString(ed.Token)
+	if err != nil {
+		return err
+	}
+
+	// Decode the signed data
+	var ci contentInfo
+	_, err = asn1.Unmarshal(token, &ci)
+	if err != nil {
+		return fmt.Errorf("decode content info: %v", err)
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return fmt.Errorf("unexpected content type %v", ci.ContentType)
+	}
+	var sd signedData
+	_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+	if err != nil {
+		return fmt.Errorf("decode signed data: %v", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return fmt.Errorf("unexpected encapsulated content type %v",
+			sd.EncapContentInfo.EContentType)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return fmt.Errorf("unexpected number of signers: got %v, want 1",
+			len(sd.SignerInfos))
+	}
+
+	// Verify the message imprint matches the digest
+	var info tstInfo
+	_, err = asn1.Unmarshal(sd.EncapContentInfo.EContent, &info)
+	if err != nil {
+		return fmt.Errorf("decode tst info: %v", err)
+	}
+	if !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) {
+		return fmt.Errorf("unexpected message imprint hash algorithm %v",
+			info.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	if !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return fmt.Errorf("message imprint %x does not match digest %v",
+			info.MessageImprint.HashedMessage, p.Digest)
+	}
+
+	// Find the signer certificate
+	si := sd.SignerInfos[0]
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return fmt.Errorf("parse certificates: %v", err)
+	}
+	cert, err := rfc3161Signer(si.SID, certs)
+	if err != nil {
+		return err
+	}
+	var timestamping bool
+	for _, v := range cert.ExtKeyUsage {
+		if v == x509.ExtKeyUsageTimeStamping {
+			timestamping = true
+			break
+		}
+	}
+	if !timestamping {
+		return fmt.Errorf("signer certificate is not a time stamping " +
+			"certificate")
+	}
+	err = rfc3161VerifyChain(cert, certs, info.GenTime)
+	if err != nil {
+		return err
+	}
+
+	// Verify the signed attributes commit to the encapsulated content
+	hash, err := rfc3161Hash(si.DigestAlgorithm.Algorithm)
+	if err != nil {
+		return err
+	}
+	if len(si.SignedAttrs.Bytes) == 0 {
+		return fmt.Errorf("signed attributes not found")
+	}
+	attrs, err := rfc3161Attributes(si.SignedAttrs.Bytes)
+	if err != nil {
+		return fmt.Errorf("decode signed attributes: %v", err)
+	}
+	var (
+		contentType   asn1.ObjectIdentifier
+		messageDigest []byte
+	)
+	for _, v := range attrs {
+		switch {
+		case v.Type.Equal(oidContentType):
+			_, err = asn1.Unmarshal(v.Values.Bytes, &contentType)
+		case v.Type.Equal(oidMessageDigest):
+			_, err = asn1.Unmarshal(v.Values.Bytes, &messageDigest)
+		}
+		if err != nil {
+			return fmt.Errorf("decode attribute %v: %v", v.Type, err)
+		}
+	}
+	if !contentType.Equal(oidTSTInfo) {
+		return fmt.Errorf("unexpected signed content type %v", contentType)
+	}
+	h := hash.New()
+	h.Write(sd.EncapContentInfo.EContent)
+	if !bytes.Equal(h.Sum(nil), messageDigest) {
+		return fmt.Errorf("message digest attribute does not match the " +
+			"tst info")
+	}
+
+	// Verify the signature of the signed attributes. The signature is
+	// over the DER encoding of the attributes using the SET OF tag
+	// instead of the implicit tag.
+	signed := make([]byte, len(si.SignedAttrs.FullBytes))
+	copy(signed, si.SignedAttrs.FullBytes)
+	signed[0] = 0x31 // SET OF
+	alg, err := rfc3161SignatureAlgorithm(cert.PublicKeyAlgorithm, hash)
+	if err != nil {
+		return err
+	}
+	err = cert.CheckSignature(alg, signed, si.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %v", err)
+	}
+
+	return nil
+}
+
+// rfc3161Attributes decodes the content of the implicitly tagged SET OF
+// Attribute that contains the signed attributes of a signer.
+func rfc3161Attributes(b []byte) ([]attribute, error) {
+	attrs := make([]attribute, 0, 4)
+	for len(b) > 0 {
+		var a attribute
+		rest, err := asn1.Unmarshal(b, &a)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, a)
+		b = rest
+	}
+	return attrs, nil
+}
+
+// rfc3161Signer returns the certificate that matches the signer identifier of
+// a time-stamp token.
+func rfc3161Signer(sid asn1.RawValue, certs []*x509.Certificate) (*x509.Certificate, error) {
+	switch {
+	case sid.Class == asn1.ClassContextSpecific && sid.Tag == 0:
+		// Subject key identifier
+		for _, v := range certs {
+			if bytes.Equal(v.SubjectKeyId, sid.Bytes) {
+				return v, nil
+			}
+		}
+	case sid.Tag == asn1.TagSequence:
+		// Issuer and serial number
+		var is issuerAndSerial
+		_, err := asn1.Unmarshal(sid.FullBytes, &is)
+		if err != nil {
+			return nil, fmt.Errorf("decode signer identifier: %v", err)
+		}
+		for _, v := range certs {
+			if bytes.Equal(v.RawIssuer, is.Issuer.FullBytes) &&
+				v.SerialNumber.Cmp(is.Serial) == 0 {
+				return v, nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid signer identifier")
+	}
+	return nil, fmt.Errorf("signer certificate not found")
+}
+
+// rfc3161VerifyChain verifies that the signer certificate of a time-stamp token
+// chains to a trusted TSA root and that every certificate of the chain allows
+// time stamping at the provided time. The other certificates of the token are
+// used as intermediates.
+func rfc3161VerifyChain(signer *x509.Certificate, certs []*x509.Certificate, genTime time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, v := range certs {
+		if v != signer {
+			intermediates.AddCert(v)
+		}
+	}
+
+	tsaRootsMtx.RLock()
+	roots := tsaRoots
+	tsaRootsMtx.RUnlock()
+
+	_, err := signer.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   genTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	})
+	if err != nil {
+		return fmt.Errorf("verify signer certificate: %v", err)
+	}
+	return nil
+}
+
+// rfc3161Hash returns the hash function for a digest algorithm identifier.
+func rfc3161Hash(oid asn1.ObjectIdentifier) (crypto.Hash, error) {
+	switch {
+	case oid.Equal(oidSHA256):
+		return crypto.SHA256, nil
+	case oid.Equal(oidSHA384):
+		return crypto.SHA384, nil
+	case oid.Equal(oidSHA512):
+		return crypto.SHA512, nil
+	}
+	return 0, fmt.Errorf("unsupported digest algorithm %v", oid)
+}
+
+// rfc3161SignatureAlgorithm returns the x509 signature algorithm for a public
+// key algorithm and hash function.
+func rfc3161SignatureAlgorithm(pk x509.PublicKeyAlgorithm, hash crypto.Hash) (x509.SignatureAlgorithm, error) {
+	algs := map[x509.PublicKeyAlgorithm]map[crypto.Hash]x509.SignatureAlgorithm{
+		x509.RSA: {
+			crypto.SHA256: x509.SHA256WithRSA,
+			crypto.SHA384: x509.SHA384WithRSA,
+			crypto.SHA512: x509.SHA512WithRSA,
+		},
+		x509.ECDSA: {
+			crypto.SHA256: x509.ECDSAWithSHA256,
+			crypto.SHA384: x509.ECDSAWithSHA384,
+			crypto.SHA512: x509.ECDSAWithSHA512,
+		},
+	}
+	if pk == x509.Ed25519 {
+		return x509.PureEd25519, nil
+	}
+	alg, ok := algs[pk][hash]
+	if !ok {
+		return 0, fmt.Errorf("unsupported signature algorithm %v %v", pk, hash)
+	}
+	return alg, nil
+}
+
+const (
+	// OpenTimestampsOpSHA256 replaces the message with its SHA256
+	// digest.
+	OpenTimestampsOpSHA256 = "sha256"
+
+	// OpenTimestampsOpAppend appends the argument to the message.
+	OpenTimestampsOpAppend = "append"
+
+	// OpenTimestampsOpPrepend prepends the argument to the message.
+	OpenTimestampsOpPrepend = "prepend"
+)
+
+// OpenTimestampsOp is a single operation of an OpenTimestamps proof. The
+// argument is hex encoded and is only used by the append and prepend
+// operations.
+type OpenTimestampsOp struct {
+	Type string `json:"type"`
+	Arg  string `json:"arg,omitempty"`
+}
+
+// ExtraDataOpenTimestamps contains the extra data required to verify an
+// OpenTimestamps proof. The operations are applied to the digest of the proof,
+// in order, and must result in the merkle root of the proof. The merkle root
+// is the merkle root of the bitcoin block at the provided height, using the
+// internal bitcoin byte order. The merkle root is verified against the block
+// header that is returned by the bitcoin header source, see
+// SetBitcoinHeaderSource.
+type ExtraDataOpenTimestamps struct {
+	Ops    []OpenTimestampsOp `json:"ops"`
+	Height uint64             `json:"height"` // Bitcoin block height
+}
+
+// BitcoinHeaderSource provides the bitcoin block headers that OpenTimestamps
+// proofs are verified against.
+type BitcoinHeaderSource interface {
+	// MerkleRoot returns the merkle root of the bitcoin block at the
+	// provided height. It is hex encoded using the internal bitcoin
+	// byte order.
+	MerkleRoot(height uint64) (string, error)
+}
+
+var (
+	// ErrAttestationUnverified is returned when the merkle root of an
+	// OpenTimestamps proof is valid, but it could not be verified that
+	// it is the merkle root of the bitcoin block that the proof attests
+	// to since no bitcoin header source has been set. The proof does not
+	// prove anything until it has been verified.
+	ErrAttestationUnverified = errors.New("bitcoin attestation has not " +
+		"been verified against a block header")
+
+	// btcHeaders is the source of the bitcoin block headers that the
+	// OpenTimestamps proofs are verified against.
+	btcHeaders    BitcoinHeaderSource
+	btcHeadersMtx sync.RWMutex
+)
+
+// SetBitcoinHeaderSource sets the source of the bitcoin block headers that
+// OpenTimestamps proofs are verified against. OpenTimestamps proofs are not
+// verified against the bitcoin blockchain if no source is set and an
+// ErrAttestationUnverified error is returned instead.
+func SetBitcoinHeaderSource(s BitcoinHeaderSource) {
+	btcHeadersMtx.Lock()
+	defer btcHeadersMtx.Unlock()
+
+	btcHeaders = s
+}
+
+// verifyProofOpenTimestamps verifies a proof with the type
+// ProofTypeOpenTimestamps. An ErrAttestationUnverified error is returned if
+// the proof is valid, but no bitcoin header source has been set.
+func verifyProofOpenTimestamps(p Proof) error {
+	if p.Type != ProofTypeOpenTimestamps {
+		return fmt.Errorf("invalid proof type")
+	}
+
+	// Decode extra data
+	var ed ExtraDataOpenTimestamps
+	err := json.Unmarshal([]byte(p.ExtraData), &ed)
+	if err != nil {
+		return err
+	}
+	if len(ed.Ops) == 0 {
+		return fmt.Errorf("no operations found")
+	}
+	if ed.Height == 0 {
+		return fmt.Errorf("bitcoin block height not found")
+	}
+
+	// Apply the operations to the digest
+	msg, err := hex.DecodeString(p.Digest)
+	if err != nil {
+		return err
+	}
+	for i, v := range ed.Ops {
+		msg, err = ApplyOpenTimestampsOp(v, msg)
+		if err != nil {
+			return fmt.Errorf("op %v: %v", i, err)
+		}
+	}
+
+	// Verify merkle root matches
+	merkleRoot := hex.EncodeToString(msg)
+	if merkleRoot != p.MerkleRoot {
+		return fmt.Errorf("invalid merkle root: got %v, want %v",
+			merkleRoot, p.MerkleRoot)
+	}
+
+	// Verify the merkle root is the merkle root of the bitcoin block
+	btcHeadersMtx.RLock()
+	src := btcHeaders
+	btcHeadersMtx.RUnlock()
+	if src == nil {
+		return ErrAttestationUnverified
+	}
+	blockRoot, err := src.MerkleRoot(ed.Height)
+	if err != nil {
+		return fmt.Errorf("bitcoin block %v: %v", ed.Height, err)
+	}
+	if blockRoot != merkleRoot {
+		return fmt.Errorf("merkle root %v is not the merkle root of "+
+			"bitcoin block %v: %v", merkleRoot, ed.Height, blockRoot)
+	}
+
+	return nil
+}
+
+// ApplyOpenTimestampsOp applies an OpenTimestamps operation to the provided
+// message and returns the result.
+func ApplyOpenTimestampsOp(op OpenTimestampsOp, msg []byte) ([]byte, error) {
+	arg, err := hex.DecodeString(op.Arg)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Type {
+	case OpenTimestampsOpSHA256:
+		d := sha256.Sum256(msg)
+		return d[:], nil
+	case OpenTimestampsOpAppend:
+		r := make([]byte, 0, len(msg)+len(arg))
+		r = append(r, msg...)
+		return append(r, arg...), nil
+	case OpenTimestampsOpPrepend:
+		r := make([]byte, 0, len(msg)+len(arg))
+		r = append(r, arg...)
+		return append(r, msg...), nil
+	}
+	return nil, fmt.Errorf("invalid op type '%v'", op.Type)
+}
+
+// VerifyProof verifies a backend proof.
+func VerifyProof(p Proof) error {
 	switch p.Type {
 	case ProofTypeTrillianRFC6962:
 		return verifyProofTrillian(p)
 	case ProofTypeDcrtime:
 		return verifyProofDcrtime(p)
+	case ProofTypeRFC3161:
+		return verifyProofRFC3161(p)
+	case ProofTypeOpenTimestamps:
+		return verifyProofOpenTimestamps(p)
 	}
 	return fmt.Errorf("invalid proof type")
 }
@@ -165,6 +709,10 @@ var (
 
 // VerifyTimestamp verifies the inclusion of the data in the merkle root that
 // was timestamped onto the dcr blockchain.
+//
+// An ErrAttestationUnverified error is returned if the timestamp is otherwise
+// valid, but contains an OpenTimestamps proof that could not be verified
+// against the bitcoin blockchain. See SetBitcoinHeaderSource.
 func VerifyTimestamp(t Timestamp) error {
 	if t.TxID == "" {
 		return ErrNotTimestamped
@@ -205,13 +753,127 @@ func VerifyTimestamp(t Timestamp) error {
 			"merkle root: got %v, want %v", nextDigest, t.MerkleRoot)
 	}
 
-	// Verify proofs
+	// Verify proofs. An unverified attestation is only reported once
+	// all other checks have passed.
+	var unverified bool
 	for _, v := range t.Proofs {
-		err := verifyProof(v)
+		err := VerifyProof(v)
+		if err == ErrAttestationUnverified {
+			unverified = true
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("invalid %v proof: %v", v.Type, err)
 		}
 	}
 
+	// Verify the anchors. The digest of every anchor proof must be the
+	// log merkle root, i.e. the merkle root of the first proof.
+	for _, v := range t.Anchors {
+		if v.Proof.Digest != t.Proofs[0].MerkleRoot {
+			return fmt.Errorf("invalid %v anchor digest: got %v, want %v",
+				v.Provider, v.Proof.Digest, t.Proofs[0].MerkleRoot)
+		}
+		if v.Proof.MerkleRoot != v.MerkleRoot {
+			return fmt.Errorf("invalid %v anchor merkle root: got %v, want %v",
+				v.Provider, v.Proof.MerkleRoot, v.MerkleRoot)
+		}
+		err := VerifyProof(v.Proof)
+		if err == ErrAttestationUnverified {
+			unverified = true
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %v anchor: %v", v.Provider, err)
+		}
+	}
+	if unverified {
+		return ErrAttestationUnverified
+	}
+
 	return nil
 }
+
+// CensoredFileMsg returns the message that is signed by the server for a
+// censored file of the record with the provided hex encoded token.
+func CensoredFileMsg(token string, f CensoredFile) string {
+	return token + strconv.FormatUint(uint64(f.Version), 10) + f.Name +
+		f.Digest + f.Reason + strconv.FormatInt(f.Timestamp, 10)
+}
+
+// SnapshotLeaf returns the snapshot leaf of a record. The snapshot leaf is the
+// hex encoded SHA256 digest of the hex encoded record token concatenated with
+// the record merkle root.
+func SnapshotLeaf(token, merkle string) string {
+	return hex.EncodeToString(util.Digest([]byte(token + merkle)))
+}
+
+// SnapshotMsg returns the message that is signed by the server for an
+// inventory snapshot. It is the sequence number and the timestamp of the
+// snapshot followed by the state, the record count, and the merkle root of
+// every snapshot root, in order.
+func SnapshotMsg(s InventorySnapshot) string {
+	var b strings.Builder
+	b.WriteString(strconv.FormatUint(s.Sequence, 10))
+	b.WriteString(strconv.FormatInt(s.Timestamp, 10))
+	for _, v := range s.Roots {
+		b.WriteString(strconv.FormatUint(uint64(v.State), 10))
+		b.WriteString(strconv.FormatUint(uint64(v.Records), 10))
+		b.WriteString(v.MerkleRoot)
+	}
+	return b.String()
+}
+
+// VerifySnapshot verifies the server signature of an inventory snapshot.
+func VerifySnapshot(s InventorySnapshot) error {
+	return util.VerifySignature(s.Signature, s.PublicKey, SnapshotMsg(s))
+}
+
+// VerifySnapshotInclusion verifies that a record was included in a signed
+// inventory snapshot and that the snapshot was timestamped. A
+// ErrNotTimestamped error is returned if the inclusion is valid but the
+// snapshot has not been anchored yet.
+func VerifySnapshotInclusion(si SnapshotInclusion) error {
+	// Verify the snapshot signature
+	err := VerifySnapshot(si.Snapshot)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot signature: %v", err)
+	}
+
+	// Verify the inclusion proof of the record
+	var root *SnapshotRoot
+	for i, v := range si.Snapshot.Roots {
+		if v.State == si.State {
+			root = &si.Snapshot.Roots[i]
+			break
+		}
+	}
+	if root == nil {
+		return fmt.Errorf("snapshot root not found for state %v", si.State)
+	}
+	leaf := SnapshotLeaf(si.Token, si.Merkle)
+	if si.Proof.Digest != leaf {
+		return fmt.Errorf("invalid proof digest: got %v, want %v",
+			si.Proof.Digest, leaf)
+	}
+	if si.Proof.MerkleRoot != root.MerkleRoot {
+		return fmt.Errorf("invalid proof merkle root: got %v, want %v",
+			si.Proof.MerkleRoot, root.MerkleRoot)
+	}
+	err = VerifyProof(si.Proof)
+	if err != nil {
+		return fmt.Errorf("invalid %v proof: %v", si.Proof.Type, err)
+	}
+
+	// Verify that the timestamp is for the snapshot
+	var s InventorySnapshot
+	err = json.Unmarshal([]byte(si.Timestamp.Data), &s)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp data: %v", err)
+	}
+	if !reflect.DeepEqual(s, si.Snapshot) {
+		return fmt.Errorf("timestamp data does not match the snapshot")
+	}
+
+	return VerifyTimestamp(si.Timestamp)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC license that can be found in
+// the LICENSE file.
+
+package backendv2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testHeaders is a BitcoinHeaderSource that is used for testing.
+type testHeaders map[uint64]string
+
+// MerkleRoot satisfies the BitcoinHeaderSource interface.
+func (h testHeaders) MerkleRoot(height uint64) (string, error) {
+	r, ok := h[height]
+	if !ok {
+		return "", errors.New("block not found")
+	}
+	return r, nil
+}
+
+func TestVerifyProofOpenTimestamps(t *testing.T) {
+	// Setup a proof of a single sha256 operation
+	const height = 700000
+	digest := sha256.Sum256([]byte("data"))
+	root := sha256.Sum256(digest[:])
+	ed, err := json.Marshal(ExtraDataOpenTimestamps{
+		Ops: []OpenTimestampsOp{
+			{Type: OpenTimestampsOpSHA256},
+		},
+		Height: height,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Proof{
+		Type:       ProofTypeOpenTimestamps,
+		Digest:     hex.EncodeToString(digest[:]),
+		MerkleRoot: hex.EncodeToString(root[:]),
+		MerklePath: []string{},
+		ExtraData:  string(ed),
+	}
+	defer SetBitcoinHeaderSource(nil)
+
+	// Without a header source the attestation is unverified
+	SetBitcoinHeaderSource(nil)
+	err = VerifyProof(p)
+	if err != ErrAttestationUnverified {
+		t.Fatalf("got %v, want %v", err, ErrAttestationUnverified)
+	}
+
+	// The merkle root must match the merkle root of the block
+	SetBitcoinHeaderSource(testHeaders{
+		height: hex.EncodeToString(root[:]),
+	})
+	err = VerifyProof(p)
+	if err != nil {
+		t.Fatalf("VerifyProof: %v", err)
+	}
+
+	SetBitcoinHeaderSource(testHeaders{
+		height: hex.EncodeToString(digest[:]),
+	})
+	err = VerifyProof(p)
+	if err == nil || err == ErrAttestationUnverified {
+		t.Fatalf("got %v, want merkle root mismatch", err)
+	}
+
+	SetBitcoinHeaderSource(testHeaders{})
+	err = VerifyProof(p)
+	if err == nil || err == ErrAttestationUnverified {
+		t.Fatalf("got %v, want missing block", err)
+	}
+}
+
+func TestEsploraHeaderSource(t *testing.T) {
+	// Setup a block header and its block hash
+	header := make([]byte, btcHeaderSize)
+	for i := range header {
+		header[i] = byte(i)
+	}
+	d := sha256.Sum256(header)
+	d = sha256.Sum256(d[:])
+	for i, j := 0, len(d)-1; i < j; i, j = i+1, j-1 {
+		d[i], d[j] = d[j], d[i]
+	}
+	hash := hex.EncodeToString(d[:])
+	root := hex.EncodeToString(header[btcMerkleRootOffset : btcMerkleRootOffset+32])
+
+	// Setup the Esplora server. The header that is returned for the
+	// height can be corrupted.
+	var (
+		corrupt  bool
+		requests int
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/block-height/1", "/block-height/2":
+			w.Write([]byte(hash + "\n"))
+		case "/block/" + hash + "/header":
+			h := append([]byte{}, header...)
+			if corrupt {
+				h[0] ^= 0xff
+			}
+			w.Write([]byte(hex.EncodeToString(h)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	src := NewEsploraHeaderSource(s.URL+"/", s.Client())
+
+	// Verify the merkle root and that it is cached
+	r, err := src.MerkleRoot(1)
+	if err != nil {
+		t.Fatalf("MerkleRoot: %v", err)
+	}
+	if r != root {
+		t.Fatalf("got %v, want %v", r, root)
+	}
+	n := requests
+	_, err = src.MerkleRoot(1)
+	if err != nil {
+		t.Fatalf("MerkleRoot: %v", err)
+	}
+	if requests != n {
+		t.Fatalf("merkle root was not cached")
+	}
+
+	// A header that does not hash to the block hash is rejected
+	corrupt = true
+	_, err = src.MerkleRoot(2)
+	if err == nil {
+		t.Fatalf("corrupt header was accepted")
+	}
+
+	// A missing block is an error
+	_, err = src.MerkleRoot(3)
+	if err == nil {
+		t.Fatalf("missing block was accepted")
+	}
+}
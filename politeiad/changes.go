@@ -0,0 +1,189 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+)
+
+const (
+	// changesKeepAlive is the interval at which a keep alive comment is
+	// sent on an idle change stream.
+	changesKeepAlive = 15 * time.Second
+
+	// changesRetry is the reconnection delay that is sent to the clients
+	// of a change stream, in milliseconds.
+	changesRetry = 1000
+)
+
+// changeFeed contains the backend methods that are used to serve the change
+// log. It allows the change log routes to be tested without a backend.
+type changeFeed interface {
+	Changes(since uint64, limit uint32) ([]backendv2.Change, error)
+	ChangesNotify() <-chan struct{}
+}
+
+// changesSince returns the sequence number that the client wants to receive
+// the changes after. The Last-Event-ID header that is sent by a reconnecting
+// event stream client takes precedence over the "since" query parameter.
+func changesSince(r *http.Request) (uint64, error) {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("since")
+	}
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// changesWantStream returns whether the client has requested the changes to
+// be streamed as server-sent events.
+func changesWantStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// changesDuration returns the maximum duration of a change request. The
+// request must be completed before the server write timeout closes the
+// connection. A zero duration indicates that there is no limit.
+func changesDuration(writeTimeout time.Duration) time.Duration {
+	return writeTimeout / 2
+}
+
+// changesWait returns the changes that come after the provided sequence
+// number. If there are none, it waits for a new change until the duration has
+// elapsed or the context is canceled. An empty list is returned if no change
+// is made while waiting.
+func changesWait(ctx context.Context, f changeFeed, since uint64, d time.Duration) ([]backendv2.Change, error) {
+	var timeout <-chan time.Time
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	for {
+		// The notification channel must be retrieved before the
+		// changes so that a change is not missed.
+		notify := f.ChangesNotify()
+		c, err := f.Changes(since, v2.ChangesPageSize)
+		if err != nil {
+			return nil, err
+		}
+		if len(c) > 0 {
+			return c, nil
+		}
+		select {
+		case <-notify:
+		case <-timeout:
+			return []backendv2.Change{}, nil
+		case <-ctx.Done():
+			return []backendv2.Change{}, nil
+		}
+	}
+}
+
+// changesStream streams the changes that come after the provided sequence
+// number as server-sent events. The stream is closed once the duration has
+// elapsed or the context is canceled. The client resumes the stream using the
+// Last-Event-ID header.
+func changesStream(ctx context.Context, w http.ResponseWriter, f changeFeed, since uint64, d time.Duration) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming not supported")
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	_, err := fmt.Fprintf(w, "retry: %v\n\n", changesRetry)
+	if err != nil {
+		return err
+	}
+	flusher.Flush()
+
+	var timeout <-chan time.Time
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	keepAlive := time.NewTicker(changesKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		notify := f.ChangesNotify()
+		c, err := f.Changes(since, v2.ChangesPageSize)
+		if err != nil {
+			return err
+		}
+		for _, v := range c {
+			b, err := json.Marshal(convertChangeToV2(v))
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "id: %v\nevent: change\ndata: %s\n\n",
+				v.Sequence, b)
+			if err != nil {
+				return err
+			}
+			since = v.Sequence
+		}
+		if len(c) > 0 {
+			flusher.Flush()
+		}
+		if len(c) == int(v2.ChangesPageSize) {
+			// There may be more changes
+			continue
+		}
+
+		select {
+		case <-notify:
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep alive\n\n")
+			if err != nil {
+				return err
+			}
+			flusher.Flush()
+		case <-timeout:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func convertChangeToV2(c backendv2.Change) v2.Change {
+	return v2.Change{
+		Sequence:  c.Sequence,
+		Type:      v2.ChangeT(c.Type),
+		Token:     c.Token,
+		State:     v2.RecordStateT(c.State),
+		Status:    v2.RecordStatusT(c.Status),
+		Version:   c.Version,
+		Iteration: c.Iteration,
+		PluginID:  c.PluginID,
+		PluginCmd: c.PluginCmd,
+		Timestamp: c.Timestamp,
+	}
+}
+
+func convertChangesToV2(changes []backendv2.Change) []v2.Change {
+	c := make([]v2.Change, 0, len(changes))
+	for _, v := range changes {
+		c = append(c, convertChangeToV2(v))
+	}
+	return c
+}
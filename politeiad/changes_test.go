@@ -0,0 +1,146 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// testChangeFeed is an in-memory changeFeed.
+type testChangeFeed struct {
+	sync.Mutex
+	changes []backendv2.Change
+	notify  chan struct{}
+}
+
+func newTestChangeFeed() *testChangeFeed {
+	return &testChangeFeed{
+		notify: make(chan struct{}),
+	}
+}
+
+func (f *testChangeFeed) append(token string) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.changes = append(f.changes, backendv2.Change{
+		Sequence: uint64(len(f.changes) + 1),
+		Type:     backendv2.ChangeRecordNew,
+		Token:    token,
+	})
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+func (f *testChangeFeed) Changes(since uint64, limit uint32) ([]backendv2.Change, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	c := make([]backendv2.Change, 0, limit)
+	for _, v := range f.changes {
+		if v.Sequence > since && len(c) < int(limit) {
+			c = append(c, v)
+		}
+	}
+	return c, nil
+}
+
+func (f *testChangeFeed) ChangesNotify() <-chan struct{} {
+	f.Lock()
+	defer f.Unlock()
+
+	return f.notify
+}
+
+func TestChangesSince(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v2/changes?since=5", nil)
+	since, err := changesSince(r)
+	if err != nil || since != 5 {
+		t.Fatalf("got %v %v, want 5", since, err)
+	}
+
+	// The Last-Event-ID header takes precedence
+	r.Header.Set("Last-Event-ID", "7")
+	since, err = changesSince(r)
+	if err != nil || since != 7 {
+		t.Fatalf("got %v %v, want 7", since, err)
+	}
+
+	// Invalid sequence numbers are rejected
+	r = httptest.NewRequest(http.MethodGet, "/v2/changes?since=x", nil)
+	_, err = changesSince(r)
+	if err == nil {
+		t.Fatalf("got nil error, want error")
+	}
+}
+
+func TestChangesWait(t *testing.T) {
+	f := newTestChangeFeed()
+	ctx := context.Background()
+
+	// An empty list is returned once the duration has elapsed
+	c, err := changesWait(ctx, f, 0, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 0 {
+		t.Fatalf("got %v changes, want 0", len(c))
+	}
+
+	// A waiting caller is woken up by a new change
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		f.append("a")
+	}()
+	c, err = changesWait(ctx, f, 0, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 1 || c[0].Token != "a" {
+		t.Fatalf("got changes %+v, want token a", c)
+	}
+
+	// Existing changes are returned without waiting
+	f.append("b")
+	c, err = changesWait(ctx, f, 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c) != 1 || c[0].Sequence != 2 {
+		t.Fatalf("got changes %+v, want sequence 2", c)
+	}
+}
+
+func TestChangesStream(t *testing.T) {
+	f := newTestChangeFeed()
+	f.append("a")
+	f.append("b")
+
+	w := httptest.NewRecorder()
+	err := changesStream(context.Background(), w, f, 1,
+		10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got content type %v, want text/event-stream", ct)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "id: 2\nevent: change\ndata: ") ||
+		!strings.Contains(body, `"token":"b"`) {
+		t.Fatalf("change 2 not streamed: %q", body)
+	}
+	if strings.Contains(body, `"token":"a"`) {
+		t.Fatalf("change 1 streamed: %q", body)
+	}
+}
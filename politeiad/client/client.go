@@ -10,8 +10,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 
 	"github.com/decred/politeia/politeiad/api/v1/identity"
+	pdv2 "github.com/decred/politeia/politeiad/api/v2"
 	"github.com/decred/politeia/util"
 )
 
@@ -22,7 +24,14 @@ type Client struct {
 	rpcUser string
 	rpcPass string
 	http    *http.Client
+
+	// pid is the politeiad identity that is used to verify the
+	// challenge responses. It is updated when the politeiad identity
+	// has been rotated. history contains the verified transitions that
+	// lead up to it.
+	mtx     sync.RWMutex
 	pid     *identity.PublicIdentity
+	history []pdv2.IdentityTransition
 }
 
 // ErrorReply represents the request body that is returned from politeaid when
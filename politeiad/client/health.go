@@ -0,0 +1,48 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/decred/politeia/util"
+)
+
+// Ready sends a request to the politeiad readiness route. An error is
+// returned if politeiad is not reachable or if politeiad is not ready to
+// serve requests, in which case the error contains the names of the
+// politeiad dependencies that are failing.
+func (c *Client) Ready(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.rpcHost+util.HealthRouteReady, nil)
+	if err != nil {
+		return err
+	}
+	r, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	var hr util.HealthReply
+	err = json.NewDecoder(r.Body).Decode(&hr)
+	if err != nil {
+		return fmt.Errorf("status code %v: %v", r.StatusCode, err)
+	}
+	if r.StatusCode != http.StatusOK {
+		failed := util.HealthFailures(hr)
+		if len(failed) == 0 {
+			return fmt.Errorf("politeiad is %v", hr.Status)
+		}
+		return fmt.Errorf("politeiad is %v: %v failing", hr.Status,
+			strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	pdv2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/util"
+)
+
+// IdentityHistory sends a IdentityHistory request to the politeiad v2 API.
+// The reply is not verified against the identity of the client since the
+// identity of the client may have been rotated. Use IdentityHistoryVerify to
+// verify the reply.
+func (c *Client) IdentityHistory(ctx context.Context) (*pdv2.IdentityHistoryReply, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	ih := pdv2.IdentityHistory{
+		Challenge: hex.EncodeToString(challenge),
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteIdentityHistory, ih)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var ihr pdv2.IdentityHistoryReply
+	err = json.Unmarshal(resBody, &ihr)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := identity.PublicIdentityFromString(ihr.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	err = util.VerifyChallenge(pid, challenge, ihr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ihr, nil
+}
+
+// IdentityUpdate fetches the identity history from politeiad and verifies
+// that the current server key descends from the identity of the client
+// through a chain of cross-signed transitions. The client identity is updated
+// to the current server key and returned.
+func (c *Client) IdentityUpdate(ctx context.Context) (*identity.PublicIdentity, error) {
+	ihr, err := c.IdentityHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	err = IdentityHistoryVerify(c.pid.String(), ihr.PublicKey, ihr.Transitions)
+	if err != nil {
+		return nil, err
+	}
+	pid, err := identity.PublicIdentityFromString(ihr.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	c.pid = pid
+	c.history = ihr.Transitions
+
+	return pid, nil
+}
+
+// ServerIdentity returns the politeiad identity that is used by the client to
+// verify the politeiad replies.
+func (c *Client) ServerIdentity() *identity.PublicIdentity {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	return c.pid
+}
+
+// serverSignatureVerify verifies a signature that was made by politeiad at
+// the provided unix timestamp. The politeiad identity may have been rotated
+// since then, so the signature is verified using the keys that were valid at
+// that time according to the identity history of the client.
+func (c *Client) serverSignatureVerify(timestamp int64, signature, msg string) error {
+	c.mtx.RLock()
+	keys := IdentityKeys(c.pid.String(), c.history, timestamp, timestamp)
+	c.mtx.RUnlock()
+
+	err := fmt.Errorf("no server key was valid at %v", timestamp)
+	for _, k := range keys {
+		err = util.VerifySignature(signature, k, msg)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// censoredFilesVerify verifies the server signatures of the censored files of
+// the record with the provided full length token. The censored files are
+// signed when they are censored, so the signatures are verified using the
+// keys that were valid at the censorship timestamp.
+func (c *Client) censoredFilesVerify(token string, files []pdv2.CensoredFile) error {
+	for _, v := range files {
+		msg := backendv2.CensoredFileMsg(token, backendv2.CensoredFile{
+			Version:   v.Version,
+			Name:      v.Name,
+			MIME:      v.MIME,
+			Digest:    v.Digest,
+			Reason:    v.Reason,
+			Timestamp: v.Timestamp,
+		})
+		err := c.serverSignatureVerify(v.Timestamp, v.Signature, msg)
+		if err != nil {
+			return fmt.Errorf("censored file %v %v: %v",
+				v.Version, v.Name, err)
+		}
+	}
+	return nil
+}
+
+// VerifyChallenge verifies a politeiad challenge response. The politeiad
+// identity may have been rotated since the client identity was last updated,
+// so the client identity is updated and the response is verified again when
+// the verification fails.
+func (c *Client) VerifyChallenge(ctx context.Context, challenge []byte, response string) error {
+	pid := c.ServerIdentity()
+	err := util.VerifyChallenge(pid, challenge, response)
+	if err == nil {
+		return nil
+	}
+
+	// Update the identity. It may have been updated concurrently, in
+	// which case the update is not needed.
+	if c.ServerIdentity() == pid {
+		_, uerr := c.IdentityUpdate(ctx)
+		if uerr != nil {
+			return fmt.Errorf("%v; identity update: %v", err, uerr)
+		}
+	}
+	return util.VerifyChallenge(c.ServerIdentity(), challenge, response)
+}
+
+// IdentityTransitionMsg returns the message that is signed by both keys of an
+// identity transition.
+func IdentityTransitionMsg(t pdv2.IdentityTransition) string {
+	return t.OldPublicKey + t.NewPublicKey + strconv.FormatInt(t.Timestamp, 10)
+}
+
+// IdentityTransitionVerify verifies the signatures of the old key and the new
+// key of an identity transition.
+func IdentityTransitionVerify(t pdv2.IdentityTransition) error {
+	msg := IdentityTransitionMsg(t)
+	err := util.VerifySignature(t.OldSignature, t.OldPublicKey, msg)
+	if err != nil {
+		return fmt.Errorf("old key signature: %v", err)
+	}
+	err = util.VerifySignature(t.NewSignature, t.NewPublicKey, msg)
+	if err != nil {
+		return fmt.Errorf("new key signature: %v", err)
+	}
+	return nil
+}
+
+// IdentityHistoryVerify verifies that the transitions form a chain of
+// cross-signed transitions that ends at the current key and that the trusted
+// key is part of the chain. The trusted key is a key that has been pinned by
+// the caller.
+func IdentityHistoryVerify(trusted, current string, transitions []pdv2.IdentityTransition) error {
+	found := trusted == current
+	for i, v := range transitions {
+		err := IdentityTransitionVerify(v)
+		if err != nil {
+			return fmt.Errorf("transition %v: %v", i, err)
+		}
+		if i > 0 {
+			prev := transitions[i-1]
+			if v.OldPublicKey != prev.NewPublicKey {
+				return fmt.Errorf("transition %v: old key does not match "+
+					"the new key of the previous transition", i)
+			}
+			if v.Timestamp <= prev.Timestamp {
+				return fmt.Errorf("transition %v: timestamp does not "+
+					"follow the previous transition", i)
+			}
+		}
+		if v.OldPublicKey == trusted {
+			found = true
+		}
+	}
+	if len(transitions) > 0 &&
+		transitions[len(transitions)-1].NewPublicKey != current {
+		return fmt.Errorf("last transition does not end at the current key")
+	}
+	if !found {
+		return fmt.Errorf("trusted key %v is not part of the identity "+
+			"history", trusted)
+	}
+	return nil
+}
+
+// IdentityKeys returns the server keys that were valid at any time between
+// the start and the end timestamp, inclusive. A key is valid from the
+// timestamp of the transition that introduced it until the timestamp of the
+// transition that replaced it, inclusive, so that the keys overlap at the
+// transition timestamp. The identity history must have been verified.
+func IdentityKeys(current string, transitions []pdv2.IdentityTransition, start, end int64) []string {
+	keys := make([]string, 0, len(transitions)+1)
+	for i, v := range transitions {
+		// The first key has been valid since before the first
+		// transition.
+		if i > 0 && transitions[i-1].Timestamp > end {
+			break
+		}
+		if v.Timestamp >= start {
+			keys = append(keys, v.OldPublicKey)
+		}
+	}
+	if len(transitions) == 0 ||
+		transitions[len(transitions)-1].Timestamp <= end {
+		keys = append(keys, current)
+	}
+	return keys
+}
@@ -78,7 +78,7 @@ func (c *Client) NewRecord(ctx context.Context, metadata []pdv1.MetadataStream,
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, nrr.Response)
+	err = c.VerifyChallenge(ctx, challenge, nrr.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +112,7 @@ func (c *Client) updateRecord(ctx context.Context, route, token string, mdAppend
 	if err != nil {
 		return err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, urr.Response)
+	err = c.VerifyChallenge(ctx, challenge, urr.Response)
 	if err != nil {
 		return err
 	}
@@ -161,7 +161,7 @@ func (c *Client) UpdateVettedMetadata(ctx context.Context, token string, mdAppen
 	if err != nil {
 		return err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, uvmr.Response)
+	err = c.VerifyChallenge(ctx, challenge, uvmr.Response)
 	if err != nil {
 		return err
 	}
@@ -198,7 +198,7 @@ func (c *Client) SetUnvettedStatus(ctx context.Context, token string, status pdv
 	if err != nil {
 		return err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, susr.Response)
+	err = c.VerifyChallenge(ctx, challenge, susr.Response)
 	if err != nil {
 		return err
 	}
@@ -234,7 +234,7 @@ func (c *Client) SetVettedStatus(ctx context.Context, token string, status pdv1.
 	if err != nil {
 		return err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, svsr.Response)
+	err = c.VerifyChallenge(ctx, challenge, svsr.Response)
 	if err != nil {
 		return err
 	}
@@ -267,7 +267,7 @@ func (c *Client) GetUnvetted(ctx context.Context, token string) (*pdv1.Record, e
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, gur.Response)
+	err = c.VerifyChallenge(ctx, challenge, gur.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -301,7 +301,7 @@ func (c *Client) GetVetted(ctx context.Context, token, version string) (*pdv1.Re
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, gvr.Response)
+	err = c.VerifyChallenge(ctx, challenge, gvr.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -337,7 +337,7 @@ func (c *Client) PluginCommand(ctx context.Context, pluginID, cmd, payload strin
 	if err != nil {
 		return "", err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, pcr.Response)
+	err = c.VerifyChallenge(ctx, challenge, pcr.Response)
 	if err != nil {
 		return "", err
 	}
@@ -16,6 +16,7 @@ import (
 	"github.com/decred/politeia/politeiad/api/v1/identity"
 	pdv2 "github.com/decred/politeia/politeiad/api/v2"
 	v2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
 	"github.com/decred/politeia/util"
 )
 
@@ -45,7 +46,7 @@ func (c *Client) RecordNew(ctx context.Context, metadata []pdv2.MetadataStream,
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, rnr.Response)
+	err = c.VerifyChallenge(ctx, challenge, rnr.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +83,7 @@ func (c *Client) RecordEdit(ctx context.Context, token string, mdAppend, mdOverw
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, rer.Response)
+	err = c.VerifyChallenge(ctx, challenge, rer.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -118,7 +119,7 @@ func (c *Client) RecordEditMetadata(ctx context.Context, token string, mdAppend,
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, reply.Response)
+	err = c.VerifyChallenge(ctx, challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -154,7 +155,7 @@ func (c *Client) RecordSetStatus(ctx context.Context, token string, status pdv2.
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, reply.Response)
+	err = c.VerifyChallenge(ctx, challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +163,46 @@ func (c *Client) RecordSetStatus(ctx context.Context, token string, status pdv2.
 	return &reply.Record, nil
 }
 
+// RecordCensorFiles sends a RecordCensorFiles command to the politeiad v2
+// API.
+func (c *Client) RecordCensorFiles(ctx context.Context, token string, files []pdv2.CensorFile, reason string) ([]pdv2.CensoredFile, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	rcf := pdv2.RecordCensorFiles{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+		Files:     files,
+		Reason:    reason,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteRecordCensorFiles, rcf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var reply pdv2.RecordCensorFilesReply
+	err = json.Unmarshal(resBody, &reply)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+	err = c.censoredFilesVerify(token, reply.CensoredFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return reply.CensoredFiles, nil
+}
+
 // RecordTimestamps sends a RecordTimestamps command to the politeiad v2 API.
 func (c *Client) RecordTimestamps(ctx context.Context, token string, version uint32) (*pdv2.RecordTimestampsReply, error) {
 	// Setup request
@@ -169,231 +210,931 @@ func (c *Client) RecordTimestamps(ctx context.Context, token string, version uin
 	if err != nil {
 		return nil, err
 	}
-	rgt := pdv2.RecordTimestamps{
+	rgt := pdv2.RecordTimestamps{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+		Version:   version,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteRecordTimestamps, rgt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var reply pdv2.RecordTimestampsReply
+	err = json.Unmarshal(resBody, &reply)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reply, nil
+}
+
+// RecordDiff sends a RecordDiff command to the politeiad v2 API.
+func (c *Client) RecordDiff(ctx context.Context, token string, fromVersion, toVersion uint32) (*pdv2.RecordDiffReply, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	rd := pdv2.RecordDiff{
+		Challenge:   hex.EncodeToString(challenge),
+		Token:       token,
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteRecordDiff, rd)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var reply pdv2.RecordDiffReply
+	err = json.Unmarshal(resBody, &reply)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reply, nil
+}
+
+// RecordHistory sends a RecordHistory command to the politeiad v2 API.
+func (c *Client) RecordHistory(ctx context.Context, token string) ([]pdv2.RecordHistoryEntry, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	rh := pdv2.RecordHistory{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteRecordHistory, rh)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var reply pdv2.RecordHistoryReply
+	err = json.Unmarshal(resBody, &reply)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return reply.History, nil
+}
+
+// Records sends a Records command to the politeiad v2 API.
+func (c *Client) Records(ctx context.Context, reqs []pdv2.RecordRequest) (map[string]pdv2.Record, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	rgb := pdv2.Records{
+		Challenge: hex.EncodeToString(challenge),
+		Requests:  reqs,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteRecords, rgb)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var reply pdv2.RecordsReply
+	err = json.Unmarshal(resBody, &reply)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range reply.Records {
+		err = c.censoredFilesVerify(v.CensorshipRecord.Token, v.CensoredFiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return reply.Records, nil
+}
+
+// Inventory sends a Inventory command to the politeiad v2 API.
+func (c *Client) Inventory(ctx context.Context, state pdv2.RecordStateT, status pdv2.RecordStatusT, page uint32) (*pdv2.InventoryReply, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	i := pdv2.Inventory{
+		Challenge: hex.EncodeToString(challenge),
+		State:     state,
+		Status:    status,
+		Page:      page,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteInventory, i)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var ir pdv2.InventoryReply
+	err = json.Unmarshal(resBody, &ir)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, ir.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ir, nil
+}
+
+// InventoryOrdered sends a InventoryOrdered command to the politeiad v2 API.
+func (c *Client) InventoryOrdered(ctx context.Context, state pdv2.RecordStateT, page uint32) ([]string, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	i := pdv2.InventoryOrdered{
+		Challenge: hex.EncodeToString(challenge),
+		State:     state,
+		Page:      page,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteInventoryOrdered, i)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var ir pdv2.InventoryOrderedReply
+	err = json.Unmarshal(resBody, &ir)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, ir.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return ir.Tokens, nil
+}
+
+// PluginWrite sends a PluginWrite command to the politeiad v2 API.
+func (c *Client) PluginWrite(ctx context.Context, cmd pdv2.PluginCmd) (string, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return "", err
+	}
+	pw := pdv2.PluginWrite{
+		Challenge: hex.EncodeToString(challenge),
+		Cmd:       cmd,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RoutePluginWrite, pw)
+	if err != nil {
+		return "", err
+	}
+
+	// Decode reply
+	var pwr pdv2.PluginWriteReply
+	err = json.Unmarshal(resBody, &pwr)
+	if err != nil {
+		return "", err
+	}
+	err = c.VerifyChallenge(ctx, challenge, pwr.Response)
+	if err != nil {
+		return "", err
+	}
+
+	return pwr.Payload, nil
+}
+
+// Transaction sends a Transaction command to the politeiad v2 API. The
+// operations are applied to the record atomically.
+func (c *Client) Transaction(ctx context.Context, token string, ops []pdv2.TxOp) (*pdv2.TransactionReply, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	tx := pdv2.Transaction{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+		Ops:       ops,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteTransaction, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var reply pdv2.TransactionReply
+	err = json.Unmarshal(resBody, &reply)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, reply.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reply, nil
+}
+
+// DraftNew sends a DraftNew command to the politeiad v2 API.
+func (c *Client) DraftNew(ctx context.Context, metadata []pdv2.MetadataStream, files []pdv2.File) (*pdv2.Record, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	dn := pdv2.DraftNew{
+		Challenge: hex.EncodeToString(challenge),
+		Metadata:  metadata,
+		Files:     files,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteDraftNew, dn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var dnr pdv2.DraftNewReply
+	err = json.Unmarshal(resBody, &dnr)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, dnr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dnr.Record, nil
+}
+
+// DraftEdit sends a DraftEdit command to the politeiad v2 API.
+func (c *Client) DraftEdit(ctx context.Context, token string, mdAppend, mdOverwrite []pdv2.MetadataStream, filesAdd []pdv2.File, filesDel []string) (*pdv2.Record, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	de := pdv2.DraftEdit{
+		Challenge:   hex.EncodeToString(challenge),
+		Token:       token,
+		MDAppend:    mdAppend,
+		MDOverwrite: mdOverwrite,
+		FilesAdd:    filesAdd,
+		FilesDel:    filesDel,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteDraftEdit, de)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var der pdv2.DraftEditReply
+	err = json.Unmarshal(resBody, &der)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, der.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &der.Record, nil
+}
+
+// DraftDel sends a DraftDel command to the politeiad v2 API.
+func (c *Client) DraftDel(ctx context.Context, token string) error {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	dd := pdv2.DraftDel{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteDraftDel, dd)
+	if err != nil {
+		return err
+	}
+
+	// Decode reply
+	var ddr pdv2.DraftDelReply
+	err = json.Unmarshal(resBody, &ddr)
+	if err != nil {
+		return err
+	}
+	err = c.VerifyChallenge(ctx, challenge, ddr.Response)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Draft sends a Draft command to the politeiad v2 API.
+func (c *Client) Draft(ctx context.Context, token string) (*pdv2.Record, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	d := pdv2.Draft{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteDraft, d)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var dr pdv2.DraftReply
+	err = json.Unmarshal(resBody, &dr)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, dr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dr.Record, nil
+}
+
+// DraftPromote sends a DraftPromote command to the politeiad v2 API.
+func (c *Client) DraftPromote(ctx context.Context, token string) (*pdv2.Record, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	dp := pdv2.DraftPromote{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteDraftPromote, dp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var dpr pdv2.DraftPromoteReply
+	err = json.Unmarshal(resBody, &dpr)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, dpr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dpr.Record, nil
+}
+
+// PluginReads sends a PluginReads command to the politeiad v2 API.
+func (c *Client) PluginReads(ctx context.Context, cmds []pdv2.PluginCmd) ([]pdv2.PluginCmdReply, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	pr := pdv2.PluginReads{
 		Challenge: hex.EncodeToString(challenge),
-		Token:     token,
-		Version:   version,
+		Cmds:      cmds,
 	}
 
 	// Send request
 	resBody, err := c.makeReq(ctx, http.MethodPost,
-		pdv2.APIRoute, pdv2.RouteRecordTimestamps, rgt)
+		pdv2.APIRoute, pdv2.RoutePluginReads, pr)
 	if err != nil {
 		return nil, err
 	}
 
 	// Decode reply
-	var reply pdv2.RecordTimestampsReply
-	err = json.Unmarshal(resBody, &reply)
+	var prr pdv2.PluginReadsReply
+	err = json.Unmarshal(resBody, &prr)
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, reply.Response)
+	err = c.VerifyChallenge(ctx, challenge, prr.Response)
 	if err != nil {
 		return nil, err
 	}
 
-	return &reply, nil
+	return prr.Replies, nil
 }
 
-// Records sends a Records command to the politeiad v2 API.
-func (c *Client) Records(ctx context.Context, reqs []pdv2.RecordRequest) (map[string]pdv2.Record, error) {
+// PluginInventory sends a PluginInventory command to the politeiad v2 API.
+func (c *Client) PluginInventory(ctx context.Context) ([]pdv2.Plugin, error) {
 	// Setup request
 	challenge, err := util.Random(pdv2.ChallengeSize)
 	if err != nil {
 		return nil, err
 	}
-	rgb := pdv2.Records{
+	pi := pdv2.PluginInventory{
 		Challenge: hex.EncodeToString(challenge),
-		Requests:  reqs,
 	}
 
 	// Send request
 	resBody, err := c.makeReq(ctx, http.MethodPost,
-		pdv2.APIRoute, pdv2.RouteRecords, rgb)
+		pdv2.APIRoute, pdv2.RoutePluginInventory, pi)
 	if err != nil {
 		return nil, err
 	}
 
 	// Decode reply
-	var reply pdv2.RecordsReply
-	err = json.Unmarshal(resBody, &reply)
+	var pir pdv2.PluginInventoryReply
+	err = json.Unmarshal(resBody, &pir)
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, reply.Response)
+	err = c.VerifyChallenge(ctx, challenge, pir.Response)
 	if err != nil {
 		return nil, err
 	}
 
-	return reply.Records, nil
+	return pir.Plugins, nil
 }
 
-// Inventory sends a Inventory command to the politeiad v2 API.
-func (c *Client) Inventory(ctx context.Context, state pdv2.RecordStateT, status pdv2.RecordStatusT, page uint32) (*pdv2.InventoryReply, error) {
+// Fsck sends a Fsck command to the politeiad v2 API. The filesystem check is
+// started in the background. Its progress can be retrieved using FsckStatus.
+// This command requires admin privileges.
+func (c *Client) Fsck(ctx context.Context, dryRun bool) error {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	f := pdv2.Fsck{
+		Challenge: hex.EncodeToString(challenge),
+		DryRun:    dryRun,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteFsck, f)
+	if err != nil {
+		return err
+	}
+
+	// Decode reply
+	var fr pdv2.FsckReply
+	err = json.Unmarshal(resBody, &fr)
+	if err != nil {
+		return err
+	}
+	err = c.VerifyChallenge(ctx, challenge, fr.Response)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FsckStatus sends a FsckStatus command to the politeiad v2 API. This command
+// requires admin privileges.
+func (c *Client) FsckStatus(ctx context.Context) (*pdv2.FsckStatusReply, error) {
 	// Setup request
 	challenge, err := util.Random(pdv2.ChallengeSize)
 	if err != nil {
 		return nil, err
 	}
-	i := pdv2.Inventory{
+	fs := pdv2.FsckStatus{
 		Challenge: hex.EncodeToString(challenge),
-		State:     state,
-		Status:    status,
-		Page:      page,
 	}
 
 	// Send request
 	resBody, err := c.makeReq(ctx, http.MethodPost,
-		pdv2.APIRoute, pdv2.RouteInventory, i)
+		pdv2.APIRoute, pdv2.RouteFsckStatus, fs)
 	if err != nil {
 		return nil, err
 	}
 
 	// Decode reply
-	var ir pdv2.InventoryReply
-	err = json.Unmarshal(resBody, &ir)
+	var fsr pdv2.FsckStatusReply
+	err = json.Unmarshal(resBody, &fsr)
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, ir.Response)
+	err = c.VerifyChallenge(ctx, challenge, fsr.Response)
 	if err != nil {
 		return nil, err
 	}
 
-	return &ir, nil
+	return &fsr, nil
 }
 
-// InventoryOrdered sends a InventoryOrdered command to the politeiad v2 API.
-func (c *Client) InventoryOrdered(ctx context.Context, state pdv2.RecordStateT, page uint32) ([]string, error) {
+// Reencrypt sends a Reencrypt command to the politeiad v2 API. The encryption
+// key is rotated and the re-encryption is started in the background. Its
+// progress can be retrieved using ReencryptStatus. This command requires admin
+// privileges.
+func (c *Client) Reencrypt(ctx context.Context) error {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	re := pdv2.Reencrypt{
+		Challenge: hex.EncodeToString(challenge),
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteReencrypt, re)
+	if err != nil {
+		return err
+	}
+
+	// Decode reply
+	var rr pdv2.ReencryptReply
+	err = json.Unmarshal(resBody, &rr)
+	if err != nil {
+		return err
+	}
+	err = c.VerifyChallenge(ctx, challenge, rr.Response)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReencryptStatus sends a ReencryptStatus command to the politeiad v2 API.
+// This command requires admin privileges.
+func (c *Client) ReencryptStatus(ctx context.Context) (*pdv2.ReencryptStatusReply, error) {
 	// Setup request
 	challenge, err := util.Random(pdv2.ChallengeSize)
 	if err != nil {
 		return nil, err
 	}
-	i := pdv2.InventoryOrdered{
+	rs := pdv2.ReencryptStatus{
 		Challenge: hex.EncodeToString(challenge),
-		State:     state,
-		Page:      page,
 	}
 
 	// Send request
 	resBody, err := c.makeReq(ctx, http.MethodPost,
-		pdv2.APIRoute, pdv2.RouteInventoryOrdered, i)
+		pdv2.APIRoute, pdv2.RouteReencryptStatus, rs)
 	if err != nil {
 		return nil, err
 	}
 
 	// Decode reply
-	var ir pdv2.InventoryOrderedReply
-	err = json.Unmarshal(resBody, &ir)
+	var rsr pdv2.ReencryptStatusReply
+	err = json.Unmarshal(resBody, &rsr)
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, ir.Response)
+	err = c.VerifyChallenge(ctx, challenge, rsr.Response)
 	if err != nil {
 		return nil, err
 	}
 
-	return ir.Tokens, nil
+	return &rsr, nil
 }
 
-// PluginWrite sends a PluginWrite command to the politeiad v2 API.
-func (c *Client) PluginWrite(ctx context.Context, cmd pdv2.PluginCmd) (string, error) {
+// Reencode sends a Reencode command to the politeiad v2 API. The blobs that
+// use a previous blob encoding are re-encoded in the background and the
+// progress can be monitored using ReencodeStatus. This command requires admin
+// privileges.
+func (c *Client) Reencode(ctx context.Context) error {
 	// Setup request
 	challenge, err := util.Random(pdv2.ChallengeSize)
 	if err != nil {
-		return "", err
+		return err
 	}
-	pw := pdv2.PluginWrite{
+	re := pdv2.Reencode{
 		Challenge: hex.EncodeToString(challenge),
-		Cmd:       cmd,
 	}
 
 	// Send request
 	resBody, err := c.makeReq(ctx, http.MethodPost,
-		pdv2.APIRoute, pdv2.RoutePluginWrite, pw)
+		pdv2.APIRoute, pdv2.RouteReencode, re)
 	if err != nil {
-		return "", err
+		return err
 	}
 
 	// Decode reply
-	var pwr pdv2.PluginWriteReply
-	err = json.Unmarshal(resBody, &pwr)
+	var rr pdv2.ReencodeReply
+	err = json.Unmarshal(resBody, &rr)
 	if err != nil {
-		return "", err
+		return err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, pwr.Response)
+	err = c.VerifyChallenge(ctx, challenge, rr.Response)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return pwr.Payload, nil
+	return nil
 }
 
-// PluginReads sends a PluginReads command to the politeiad v2 API.
-func (c *Client) PluginReads(ctx context.Context, cmds []pdv2.PluginCmd) ([]pdv2.PluginCmdReply, error) {
+// ReencodeStatus sends a ReencodeStatus command to the politeiad v2 API. This
+// command requires admin privileges.
+func (c *Client) ReencodeStatus(ctx context.Context) (*pdv2.ReencodeStatusReply, error) {
 	// Setup request
 	challenge, err := util.Random(pdv2.ChallengeSize)
 	if err != nil {
 		return nil, err
 	}
-	pr := pdv2.PluginReads{
+	rs := pdv2.ReencodeStatus{
 		Challenge: hex.EncodeToString(challenge),
-		Cmds:      cmds,
 	}
 
 	// Send request
 	resBody, err := c.makeReq(ctx, http.MethodPost,
-		pdv2.APIRoute, pdv2.RoutePluginReads, pr)
+		pdv2.APIRoute, pdv2.RouteReencodeStatus, rs)
 	if err != nil {
 		return nil, err
 	}
 
 	// Decode reply
-	var prr pdv2.PluginReadsReply
-	err = json.Unmarshal(resBody, &prr)
+	var rsr pdv2.ReencodeStatusReply
+	err = json.Unmarshal(resBody, &rsr)
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, prr.Response)
+	err = c.VerifyChallenge(ctx, challenge, rsr.Response)
 	if err != nil {
 		return nil, err
 	}
 
-	return prr.Replies, nil
+	return &rsr, nil
 }
 
-// PluginInventory sends a PluginInventory command to the politeiad v2 API.
-func (c *Client) PluginInventory(ctx context.Context) ([]pdv2.Plugin, error) {
+// AnchorStatus sends a AnchorStatus command to the politeiad v2 API. This
+// command requires admin privileges.
+func (c *Client) AnchorStatus(ctx context.Context) (*pdv2.AnchorStatusReply, error) {
 	// Setup request
 	challenge, err := util.Random(pdv2.ChallengeSize)
 	if err != nil {
 		return nil, err
 	}
-	pi := pdv2.PluginInventory{
+	as := pdv2.AnchorStatus{
 		Challenge: hex.EncodeToString(challenge),
 	}
 
 	// Send request
 	resBody, err := c.makeReq(ctx, http.MethodPost,
-		pdv2.APIRoute, pdv2.RoutePluginInventory, pi)
+		pdv2.APIRoute, pdv2.RouteAnchorStatus, as)
 	if err != nil {
 		return nil, err
 	}
 
 	// Decode reply
-	var pir pdv2.PluginInventoryReply
-	err = json.Unmarshal(resBody, &pir)
+	var asr pdv2.AnchorStatusReply
+	err = json.Unmarshal(resBody, &asr)
 	if err != nil {
 		return nil, err
 	}
-	err = util.VerifyChallenge(c.pid, challenge, pir.Response)
+	err = c.VerifyChallenge(ctx, challenge, asr.Response)
 	if err != nil {
 		return nil, err
 	}
 
-	return pir.Plugins, nil
+	return &asr, nil
+}
+
+// AnchorDrop sends a AnchorDrop command to the politeiad v2 API. The anchor
+// drops in the background and can be monitored using AnchorStatus. This
+// command requires admin privileges.
+func (c *Client) AnchorDrop(ctx context.Context) error {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return err
+	}
+	ad := pdv2.AnchorDrop{
+		Challenge: hex.EncodeToString(challenge),
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteAnchorDrop, ad)
+	if err != nil {
+		return err
+	}
+
+	// Decode reply
+	var adr pdv2.AnchorDropReply
+	err = json.Unmarshal(resBody, &adr)
+	if err != nil {
+		return err
+	}
+	err = c.VerifyChallenge(ctx, challenge, adr.Response)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Changes sends a Changes request to the politeiad v2 API. It returns the
+// changes that come after the provided sequence number. The server waits for
+// a new change when there are none, so this call can be used for long
+// polling. An empty list is returned if no change is made while the server
+// waits.
+func (c *Client) Changes(ctx context.Context, since uint64) ([]pdv2.Change, error) {
+	// Send request
+	route := fmt.Sprintf("%v?since=%v", pdv2.RouteChanges, since)
+	resBody, err := c.makeReq(ctx, http.MethodGet,
+		pdv2.APIRoute, route, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var cr pdv2.ChangesReply
+	err = json.Unmarshal(resBody, &cr)
+	if err != nil {
+		return nil, err
+	}
+
+	return cr.Changes, nil
+}
+
+// Snapshot sends a Snapshot request to the politeiad v2 API. It returns the
+// inventory snapshot with the provided sequence number and its timestamp. A
+// sequence number of 0 returns the most recent snapshot.
+func (c *Client) Snapshot(ctx context.Context, seq uint64) (*pdv2.SnapshotReply, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	s := pdv2.Snapshot{
+		Challenge: hex.EncodeToString(challenge),
+		Sequence:  seq,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteSnapshot, s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var sr pdv2.SnapshotReply
+	err = json.Unmarshal(resBody, &sr)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, sr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sr, nil
+}
+
+// SnapshotProof sends a SnapshotProof request to the politeiad v2 API. It
+// returns the proof that a record was included in the inventory snapshot with
+// the provided sequence number. A sequence number of 0 uses the most recent
+// snapshot.
+func (c *Client) SnapshotProof(ctx context.Context, token string, seq uint64) (*pdv2.SnapshotInclusion, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	sp := pdv2.SnapshotProof{
+		Challenge: hex.EncodeToString(challenge),
+		Token:     token,
+		Sequence:  seq,
+	}
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteSnapshotProof, sp)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var spr pdv2.SnapshotProofReply
+	err = json.Unmarshal(resBody, &spr)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, spr.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &spr.Inclusion, nil
+}
+
+// SnapshotInclusionVerify verifies that a record was included in a signed
+// inventory snapshot and that the snapshot was timestamped. A
+// backendv2.ErrNotTimestamped error is returned if the snapshot has not been
+// anchored yet.
+func SnapshotInclusionVerify(si pdv2.SnapshotInclusion) error {
+	return backendv2.VerifySnapshotInclusion(convertSnapshotInclusionToBackend(si))
+}
+
+// Audit sends a Audit request to the politeiad v2 API. The challenge of the
+// request is set by this function. This command requires admin privileges.
+func (c *Client) Audit(ctx context.Context, a pdv2.Audit) (*pdv2.AuditReply, error) {
+	// Setup request
+	challenge, err := util.Random(pdv2.ChallengeSize)
+	if err != nil {
+		return nil, err
+	}
+	a.Challenge = hex.EncodeToString(challenge)
+
+	// Send request
+	resBody, err := c.makeReq(ctx, http.MethodPost,
+		pdv2.APIRoute, pdv2.RouteAudit, a)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode reply
+	var ar pdv2.AuditReply
+	err = json.Unmarshal(resBody, &ar)
+	if err != nil {
+		return nil, err
+	}
+	err = c.VerifyChallenge(ctx, challenge, ar.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ar, nil
+}
+
+// AuditEntryVerify verifies that the timestamp of an audit log entry contains
+// the entry and that the entry was timestamped. A backendv2.ErrNotTimestamped
+// error is returned if the entry has not been anchored yet.
+func AuditEntryVerify(e pdv2.AuditEntry, ts pdv2.Timestamp) error {
+	var te pdv2.AuditEntry
+	err := json.Unmarshal([]byte(ts.Data), &te)
+	if err != nil {
+		return fmt.Errorf("unmarshal timestamp data: %v", err)
+	}
+	if te != e {
+		return fmt.Errorf("timestamp data does not match the audit entry")
+	}
+	return backendv2.VerifyTimestamp(convertTimestampToBackend(ts))
 }
 
 // RecordVerify verifies the censorship record of a v2 Record.
@@ -482,3 +1223,52 @@ func extractPluginCmdError(pcr pdv2.PluginCmdReply) error {
 	}
 	return nil
 }
+
+func convertProofToBackend(p pdv2.Proof) backendv2.Proof {
+	return backendv2.Proof{
+		Type:       p.Type,
+		Digest:     p.Digest,
+		MerkleRoot: p.MerkleRoot,
+		MerklePath: p.MerklePath,
+		ExtraData:  p.ExtraData,
+	}
+}
+
+func convertTimestampToBackend(t pdv2.Timestamp) backendv2.Timestamp {
+	proofs := make([]backendv2.Proof, 0, len(t.Proofs))
+	for _, v := range t.Proofs {
+		proofs = append(proofs, convertProofToBackend(v))
+	}
+	return backendv2.Timestamp{
+		Data:       t.Data,
+		Digest:     t.Digest,
+		TxID:       t.TxID,
+		MerkleRoot: t.MerkleRoot,
+		Proofs:     proofs,
+	}
+}
+
+func convertSnapshotInclusionToBackend(si pdv2.SnapshotInclusion) backendv2.SnapshotInclusion {
+	roots := make([]backendv2.SnapshotRoot, 0, len(si.Snapshot.Roots))
+	for _, v := range si.Snapshot.Roots {
+		roots = append(roots, backendv2.SnapshotRoot{
+			State:      backendv2.StateT(v.State),
+			Records:    v.Records,
+			MerkleRoot: v.MerkleRoot,
+		})
+	}
+	return backendv2.SnapshotInclusion{
+		Snapshot: backendv2.InventorySnapshot{
+			Sequence:  si.Snapshot.Sequence,
+			Timestamp: si.Snapshot.Timestamp,
+			Roots:     roots,
+			PublicKey: si.Snapshot.PublicKey,
+			Signature: si.Snapshot.Signature,
+		},
+		Timestamp: convertTimestampToBackend(si.Timestamp),
+		Token:     si.Token,
+		State:     backendv2.StateT(si.State),
+		Merkle:    si.Merkle,
+		Proof:     convertProofToBackend(si.Proof),
+	}
+}
@@ -157,7 +157,7 @@ type importCmd struct {
 func newImportCmd(legacyDir, tlogHost, dbHost, dbPass, importToken string, stubUsers bool, params *chaincfg.Params) (*importCmd, error) {
 	// Setup the tstore connection
 	ts, err := tstore.New(politeiadHomeDir, politeiadDataDir,
-		params, tlogHost, dbHost, dbPass, "", "")
+		params, tstore.TlogTypeTrillian, tlogHost, tstore.DBTypeMySQL, dbHost, dbPass, nil, nil, 0, 0)
 	if err != nil {
 		return nil, err
 	}
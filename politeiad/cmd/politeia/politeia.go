@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/decred/dcrd/dcrutil/v3"
@@ -77,6 +78,20 @@ Available commands:
                    Args: <token>
   inventory        Get the record inventory 
                    Args (optional): <state> <status> <page>
+  fsck             Start a filesystem check in the background (admin)
+                   Args (optional): dryrun
+  fsckstatus       Get the progress and report of the filesystem check
+                   (admin)
+  reencrypt        Rotate the encryption key and re-encrypt all data in the
+                   background (admin)
+  reencryptstatus  Get the progress and report of the re-encryption (admin)
+  reencode         Re-encode the blobs that use a previous blob encoding in the
+                   background (admin)
+  reencodestatus   Get the progress and report of the re-encoding (admin)
+  anchorstatus     Get the anchor lag, the in-flight anchor and the recent
+                   anchor failures (admin)
+  anchordrop       Drop an anchor without waiting for the anchor schedule
+                   (admin)
 
 Metadata actions: appendmetadata, overwritemetadata
 File actions: add, del
@@ -814,6 +829,347 @@ func recordInventory() error {
 	return nil
 }
 
+// fsck starts a filesystem check of the backend. The check is run in the
+// background by politeiad. A dry run lists the problems that are found
+// without repairing them.
+func fsck() error {
+	flags := flag.Args()[1:] // Chop off action.
+
+	var dryRun bool
+	switch {
+	case len(flags) == 0:
+		// Not a dry run
+	case len(flags) == 1 && flags[0] == "dryrun":
+		dryRun = true
+	default:
+		return fmt.Errorf("invalid arguments %v; the only allowed "+
+			"argument is 'dryrun'", flags)
+	}
+
+	// Load server identity
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Setup client
+	c, err := pdclient.New(*rpchost, *rpccert, *rpcuser, *rpcpass, pid)
+	if err != nil {
+		return err
+	}
+
+	// Start the fsck
+	err = c.Fsck(context.Background(), dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *verbose {
+		fmt.Printf("Fsck started (dry run: %v)\n", dryRun)
+	}
+
+	return nil
+}
+
+// fsckStatus retrieves the progress of the most recent filesystem check and
+// the report of what was found once it has completed.
+func fsckStatus() error {
+	// Load server identity
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Setup client
+	c, err := pdclient.New(*rpchost, *rpccert, *rpcuser, *rpcpass, pid)
+	if err != nil {
+		return err
+	}
+
+	// Get the fsck status
+	fsr, err := c.FsckStatus(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if fsr.Started == 0 {
+		fmt.Printf("No fsck has been run\n")
+		return nil
+	}
+	fmt.Printf("Started  : %v\n", time.Unix(fsr.Started, 0).UTC())
+	fmt.Printf("Dry run  : %v\n", fsr.DryRun)
+	switch {
+	case fsr.Running:
+		fmt.Printf("Stage    : %v %v/%v\n", fsr.Stage, fsr.Done, fsr.Total)
+	case fsr.Error != "":
+		fmt.Printf("Failed   : %v\n", time.Unix(fsr.Completed, 0).UTC())
+		fmt.Printf("Error    : %v\n", fsr.Error)
+	default:
+		fmt.Printf("Completed: %v\n", time.Unix(fsr.Completed, 0).UTC())
+		fmt.Printf("%v\n", util.FormatJSON(fsr.Report))
+	}
+
+	return nil
+}
+
+// reencrypt rotates the backend encryption key and starts a re-encryption of
+// all encrypted data using the new key. The re-encryption is run in the
+// background by politeiad. An interrupted re-encryption is resumed.
+func reencrypt() error {
+	// Load server identity
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Setup client
+	c, err := pdclient.New(*rpchost, *rpccert, *rpcuser, *rpcpass, pid)
+	if err != nil {
+		return err
+	}
+
+	// Start the re-encryption
+	err = c.Reencrypt(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *verbose {
+		fmt.Printf("Re-encryption started\n")
+	}
+
+	return nil
+}
+
+// reencryptStatus retrieves the progress of the most recent re-encryption and
+// its report once it has completed.
+func reencryptStatus() error {
+	// Load server identity
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Setup client
+	c, err := pdclient.New(*rpchost, *rpccert, *rpcuser, *rpcpass, pid)
+	if err != nil {
+		return err
+	}
+
+	// Get the re-encryption status
+	rsr, err := c.ReencryptStatus(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if rsr.Started == 0 {
+		fmt.Printf("No re-encryption has been run\n")
+		return nil
+	}
+	fmt.Printf("Started  : %v\n", time.Unix(rsr.Started, 0).UTC())
+	switch {
+	case rsr.Running:
+		fmt.Printf("Progress : %v/%v\n", rsr.Done, rsr.Total)
+	case rsr.Error != "":
+		fmt.Printf("Failed   : %v\n", time.Unix(rsr.Completed, 0).UTC())
+		fmt.Printf("Error    : %v\n", rsr.Error)
+	default:
+		fmt.Printf("Completed: %v\n", time.Unix(rsr.Completed, 0).UTC())
+		fmt.Printf("%v\n", util.FormatJSON(rsr.Report))
+	}
+
+	return nil
+}
+
+// reencode starts a re-encoding of the backend blobs that use a previous blob
+// encoding. The re-encoding is run in the background by politeiad. An
+// interrupted re-encoding is resumed.
+func reencode() error {
+	// Load server identity
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Setup client
+	c, err := pdclient.New(*rpchost, *rpccert, *rpcuser, *rpcpass, pid)
+	if err != nil {
+		return err
+	}
+
+	// Start the re-encoding
+	err = c.Reencode(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *verbose {
+		fmt.Printf("Re-encoding started\n")
+	}
+
+	return nil
+}
+
+// reencodeStatus retrieves the progress of the most recent re-encoding and its
+// report once it has completed.
+func reencodeStatus() error {
+	// Load server identity
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Setup client
+	c, err := pdclient.New(*rpchost, *rpccert, *rpcuser, *rpcpass, pid)
+	if err != nil {
+		return err
+	}
+
+	// Get the re-encoding status
+	rsr, err := c.ReencodeStatus(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if rsr.Started == 0 {
+		fmt.Printf("No re-encoding has been run\n")
+		return nil
+	}
+	fmt.Printf("Started  : %v\n", time.Unix(rsr.Started, 0).UTC())
+	switch {
+	case rsr.Running:
+		fmt.Printf("Progress : %v/%v records\n", rsr.Done, rsr.Total)
+	case rsr.Error != "":
+		fmt.Printf("Failed   : %v\n", time.Unix(rsr.Completed, 0).UTC())
+		fmt.Printf("Error    : %v\n", rsr.Error)
+	default:
+		fmt.Printf("Completed: %v\n", time.Unix(rsr.Completed, 0).UTC())
+		fmt.Printf("%v\n", util.FormatJSON(rsr.Report))
+	}
+
+	return nil
+}
+
+// anchorStatus retrieves the status of the anchoring of the records. The lag
+// is reported overall and for every record that has unanchored leaves.
+func anchorStatus() error {
+	// Load server identity
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Setup client
+	c, err := pdclient.New(*rpchost, *rpccert, *rpcuser, *rpcpass, pid)
+	if err != nil {
+		return err
+	}
+
+	// Get the anchor status
+	asr, err := c.AnchorStatus(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *verbose {
+		fmt.Printf("%v\n", util.FormatJSON(asr))
+		return nil
+	}
+
+	if len(asr.Providers) == 0 {
+		fmt.Printf("Providers  : none; anchoring is disabled\n")
+	} else {
+		fmt.Printf("Providers  : %v\n", strings.Join(asr.Providers, ", "))
+	}
+	fmt.Printf("Records    : %v\n", asr.Records)
+	if asr.LastAnchor == 0 {
+		fmt.Printf("Last anchor: never\n")
+	} else {
+		fmt.Printf("Last anchor: %v\n", time.Unix(asr.LastAnchor, 0).UTC())
+	}
+	fmt.Printf("Unanchored : %v records, %v leaves, max lag %v\n",
+		len(asr.Unanchored), asr.Leaves,
+		time.Duration(asr.Lag)*time.Second)
+	fmt.Printf("Dropping   : %v\n", asr.Dropping)
+	if asr.InFlight != nil {
+		fmt.Printf("In-flight  : %v records, started %v\n",
+			len(asr.InFlight.Tokens),
+			time.Unix(asr.InFlight.Started, 0).UTC())
+		for _, id := range asr.Providers {
+			d, ok := asr.InFlight.Pending[id]
+			if !ok {
+				continue
+			}
+			fmt.Printf("  %v: %v digests pending\n", id, len(d))
+		}
+	}
+
+	if len(asr.Unanchored) > 0 {
+		fmt.Printf("\nUnanchored records\n")
+		for _, v := range asr.Unanchored {
+			last := "never"
+			if v.LastAnchor != 0 {
+				last = time.Unix(v.LastAnchor, 0).UTC().String()
+			}
+			var inFlight string
+			if v.InFlight {
+				inFlight = " (in-flight)"
+			}
+			fmt.Printf("  %v: %v leaves, lag %v, last anchor %v%v\n",
+				v.Token, v.Leaves, time.Duration(v.Lag)*time.Second,
+				last, inFlight)
+		}
+	}
+
+	if len(asr.Failures) > 0 {
+		fmt.Printf("\nRecent failures\n")
+		for _, v := range asr.Failures {
+			var subject string
+			switch {
+			case v.Provider != "" && v.Token != "":
+				subject = v.Provider + " " + v.Token + ": "
+			case v.Provider != "":
+				subject = v.Provider + ": "
+			case v.Token != "":
+				subject = v.Token + ": "
+			}
+			fmt.Printf("  %v %v%v\n", time.Unix(v.Timestamp, 0).UTC(),
+				subject, v.Error)
+		}
+	}
+
+	return nil
+}
+
+// anchorDrop drops an anchor for all records that have unanchored leaves
+// without waiting for the anchor schedule. The anchor drops in the background
+// and can be monitored using the anchorstatus command.
+func anchorDrop() error {
+	// Load server identity
+	pid, err := identity.LoadPublicIdentity(*identityFilename)
+	if err != nil {
+		return err
+	}
+
+	// Setup client
+	c, err := pdclient.New(*rpchost, *rpccert, *rpcuser, *rpcpass, pid)
+	if err != nil {
+		return err
+	}
+
+	// Drop the anchor
+	err = c.AnchorDrop(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if *verbose {
+		fmt.Printf("Anchor drop started\n")
+	}
+
+	return nil
+}
+
 func _main() error {
 	flag.Usage = usage
 	flag.Parse()
@@ -867,6 +1223,22 @@ func _main() error {
 				return record()
 			case "inventory":
 				return recordInventory()
+			case "fsck":
+				return fsck()
+			case "fsckstatus":
+				return fsckStatus()
+			case "reencrypt":
+				return reencrypt()
+			case "reencryptstatus":
+				return reencryptStatus()
+			case "reencode":
+				return reencode()
+			case "reencodestatus":
+				return reencodeStatus()
+			case "anchorstatus":
+				return anchorStatus()
+			case "anchordrop":
+				return anchorDrop()
 			default:
 				return fmt.Errorf("invalid action: %v", a)
 			}
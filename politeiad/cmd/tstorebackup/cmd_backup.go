@@ -0,0 +1,105 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/decred/politeia/util"
+	"github.com/decred/slog"
+)
+
+var (
+	// CLI flags for the backup command
+	backupFlags  = flag.NewFlagSet(backupCmdName, flag.ExitOnError)
+	backupTstore = newTstoreFlags(backupFlags)
+	backupKey    = backupFlags.String("keyfile", "", "")
+)
+
+// execBackupCmd executes the backup command.
+//
+// The backup command writes a backup archive of the full tstore to disk. The
+// archive is first written to a temporary file that is renamed once the
+// backup has completed successfully so that a partial archive is never left
+// at the archive path. The archive is encrypted using the key in the key file.
+// A new key is created if the key file does not exist.
+func execBackupCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("archive path argument not provided")
+	}
+	archive := util.CleanAndExpandPath(args[0])
+	if _, err := os.Stat(archive); err == nil {
+		return fmt.Errorf("archive already exists: %v", archive)
+	}
+
+	// Parse the CLI flags
+	err := backupFlags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	// Print the total elapsed time on exit
+	t := time.Now()
+	defer func() {
+		fmt.Printf("Backup elapsed time: %v\n", time.Since(t))
+	}()
+
+	if *backupKey == "" {
+		return fmt.Errorf("--keyfile flag not provided")
+	}
+	keyFile := util.CleanAndExpandPath(*backupKey)
+	if !util.FileExists(keyFile) {
+		fmt.Printf("Creating archive key: %v\n", keyFile)
+	}
+	key, err := util.LoadEncryptionKey(slog.Disabled, keyFile)
+	if err != nil {
+		return err
+	}
+	defer util.Zero(key[:])
+
+	ts, err := backupTstore.newTstore()
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+
+	// Write the archive
+	tmp := archive + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	bs, err := ts.Backup(f, key)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, archive)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Trees : %v\n", bs.Trees)
+	fmt.Printf("Leaves: %v\n", bs.Leaves)
+	fmt.Printf("Blobs : %v\n", bs.Blobs)
+	fmt.Printf("Files : %v\n", bs.Files)
+	fmt.Printf("Backup saved to %v\n", archive)
+
+	return nil
+}
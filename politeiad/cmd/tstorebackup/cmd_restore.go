@@ -0,0 +1,85 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/decred/politeia/util"
+	"github.com/decred/slog"
+)
+
+var (
+	// CLI flags for the restore command
+	restoreFlags  = flag.NewFlagSet(restoreCmdName, flag.ExitOnError)
+	restoreTstore = newTstoreFlags(restoreFlags)
+	restoreKey    = restoreFlags.String("keyfile", "", "")
+)
+
+// execRestoreCmd executes the restore command.
+//
+// The restore command restores a backup archive into an empty tstore. The
+// trees, leaves, and blobs are verified against the archive as they are
+// restored. An error is returned if any of the verifications fail. The archive
+// is decrypted using the key in the key file.
+func execRestoreCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("archive path argument not provided")
+	}
+	archive := util.CleanAndExpandPath(args[0])
+
+	// Parse the CLI flags
+	err := restoreFlags.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+
+	// Print the total elapsed time on exit
+	t := time.Now()
+	defer func() {
+		fmt.Printf("Restore elapsed time: %v\n", time.Since(t))
+	}()
+
+	if *restoreKey == "" {
+		return fmt.Errorf("--keyfile flag not provided")
+	}
+	keyFile := util.CleanAndExpandPath(*restoreKey)
+	if !util.FileExists(keyFile) {
+		return fmt.Errorf("key file not found: %v", keyFile)
+	}
+	key, err := util.LoadEncryptionKey(slog.Disabled, keyFile)
+	if err != nil {
+		return err
+	}
+	defer util.Zero(key[:])
+
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ts, err := restoreTstore.newTstore()
+	if err != nil {
+		return err
+	}
+	defer ts.Close()
+
+	bs, err := ts.Restore(f, key)
+	if err != nil {
+		return fmt.Errorf("restore failed: %v", err)
+	}
+
+	fmt.Printf("Trees : %v\n", bs.Trees)
+	fmt.Printf("Leaves: %v\n", bs.Leaves)
+	fmt.Printf("Blobs : %v\n", bs.Blobs)
+	fmt.Printf("Files : %v\n", bs.Files)
+	fmt.Printf("Restore verified and complete\n")
+
+	return nil
+}
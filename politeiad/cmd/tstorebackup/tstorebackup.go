@@ -0,0 +1,127 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tstore"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// Command names. See the usage.go file for details on command usage.
+	backupCmdName  = "backup"
+	restoreCmdName = "restore"
+
+	// tstore settings
+	defaultDBType   = tstore.DBTypeMySQL
+	defaultDBHost   = "localhost:3306"
+	defaultTlogType = tstore.TlogTypeTrillian
+	defaultTlogHost = "localhost:8090"
+
+	// envDBPass is the env variable that contains the database password.
+	// This is the same env variable that is used by politeiad.
+	envDBPass = "DBPASS"
+)
+
+var (
+	defaultHomeDir = dcrutil.AppDataDir("politeiad", false)
+)
+
+// tstoreFlags contains the CLI flags that are used to connect to a tstore
+// instance. Both the backup and the restore commands use these flags.
+type tstoreFlags struct {
+	homeDir  *string
+	testnet  *bool
+	dbType   *string
+	dbHost   *string
+	tlogType *string
+	tlogHost *string
+}
+
+// newTstoreFlags registers the tstore flags with the provided flag set. We
+// print a custom usage message, see usage.go, so the individual flag usage
+// messages are left blank.
+func newTstoreFlags(fs *flag.FlagSet) tstoreFlags {
+	return tstoreFlags{
+		homeDir:  fs.String("homedir", defaultHomeDir, ""),
+		testnet:  fs.Bool("testnet", false, ""),
+		dbType:   fs.String("dbtype", defaultDBType, ""),
+		dbHost:   fs.String("dbhost", defaultDBHost, ""),
+		tlogType: fs.String("tlogtype", defaultTlogType, ""),
+		tlogHost: fs.String("tloghost", defaultTlogHost, ""),
+	}
+}
+
+// newTstore returns a new tstore instance that is setup using the same data
+// directory layout as politeiad. The database password is read from the env.
+func (f *tstoreFlags) newTstore() (*tstore.Tstore, error) {
+	dbPass := os.Getenv(envDBPass)
+	if dbPass == "" {
+		return nil, fmt.Errorf("dbpass not found; you must provide the " +
+			"database password for the politeiad user in the env " +
+			"variable DBPASS")
+	}
+
+	// Testnet or mainnet. politeiad uses the network name as the name
+	// of the data directory, with the exception of testnet3.
+	var (
+		params  = chaincfg.MainNetParams()
+		netName = params.Name
+	)
+	if *f.testnet {
+		params = chaincfg.TestNet3Params()
+		netName = "testnet3"
+	}
+	homeDir := util.CleanAndExpandPath(*f.homeDir)
+	dataDir := filepath.Join(homeDir, "data", netName)
+
+	fmt.Printf("Network  : %v\n", params.Name)
+	fmt.Printf("Data dir : %v\n", dataDir)
+	fmt.Printf("DB type  : %v\n", *f.dbType)
+	fmt.Printf("Tlog type: %v\n", *f.tlogType)
+	fmt.Printf("\n")
+
+	return tstore.New(homeDir, dataDir, params, *f.tlogType, *f.tlogHost,
+		*f.dbType, *f.dbHost, dbPass, nil, nil, 0, 0)
+}
+
+func _main() error {
+	// Parse the CLI args
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usageMsg)
+		return fmt.Errorf("no command specified")
+	}
+
+	// Execute the specified command
+	switch args[0] {
+	case backupCmdName:
+		return execBackupCmd(args[1:])
+	case restoreCmdName:
+		return execRestoreCmd(args[1:])
+	default:
+		return fmt.Errorf("command '%v' not found", args[0])
+	}
+}
+
+func main() {
+	// Use a custom help message
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, usageMsg)
+	}
+	err := _main()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
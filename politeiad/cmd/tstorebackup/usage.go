@@ -0,0 +1,81 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+const usageMsg = `tstorebackup usage:
+
+Commands
+  backup   Write a backup archive of a tstore backend.
+  restore  Restore a backup archive into an empty tstore backend.
+
+politeiad must be stopped while either command is being run. The database
+password must be provided in the DBPASS env variable, the same as politeiad.
+
+Command Usage: backup
+
+  $ tstorebackup backup <archive> --keyfile=<keyfile>
+
+  Write a backup archive of the full tstore backend to disk. The archive
+  contains all tlog trees and leaves, all key-value store blobs, including the
+  record inventory and the plugin caches, and the plugin data files from the
+  politeiad data directory. The log root of every tree is verified against the
+  tree leaves before the tree is written to the archive.
+
+  Only a backend that uses the embedded tlog can be backed up since archives
+  can only be restored into the embedded tlog. See the restore command.
+
+  The archive is encrypted using the secretbox key in the key file, including
+  the blobs of unvetted records. A new key is created if the key file does not
+  exist. The key is required to restore the archive and must be stored
+  separately from it.
+
+  The blobs of frozen records that politeiad has moved to a cold store are not
+  included in the archive, only their archive manifests. The cold store must be
+  backed up separately.
+
+  Arguments:
+
+  1. archive  (string)  Path that the archive will be written to. The file
+                        must not already exist.
+
+  Flags:
+
+  --keyfile   (string)  Path to the archive encryption key. (required)
+
+  --homedir   (string)  politeiad home directory.
+                        (default: the politeiad application data directory)
+
+  --testnet   (bool)    Use the testnet data directory. (default: false)
+
+  --dbtype    (string)  Database type {mysql, sqlite}. (default: mysql)
+
+  --dbhost    (string)  Host for the mysql db. (default: localhost:3306)
+
+  --tlogtype  (string)  Tlog type {trillian, embedded}. (default: trillian)
+
+  --tloghost  (string)  Host for trillian. (default: localhost:8090)
+
+Command Usage: restore
+
+  $ tstorebackup restore <archive> --keyfile=<keyfile>
+
+  Restore a backup archive into an empty tstore backend. Every tree is
+  re-created using its original tree ID so that record tokens are preserved.
+  The leaf hashes and log root of every tree and the digest of every record
+  blob are verified against the archive. The command only reports success
+  once all verifications have passed.
+
+  Trillian does not allow the tree ID of a new tree to be specified. Archives
+  can only be restored into the embedded tlog, and only a backend that uses
+  the embedded tlog can be backed up.
+
+  Arguments:
+
+  1. archive  (string)  Path to the backup archive.
+
+  Flags:
+
+  The restore command uses the same flags as the backup command. The key file
+  must contain the key that the archive was encrypted with.`
@@ -20,6 +20,10 @@ import (
 
 	"github.com/decred/dcrd/dcrutil/v3"
 	v1 "github.com/decred/dcrtime/api/v1"
+	"github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/anchors"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/coldstore"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tstore"
 	"github.com/decred/politeia/util"
 	"github.com/decred/politeia/util/version"
 	flags "github.com/jessevdk/go-flags"
@@ -32,6 +36,7 @@ const (
 	defaultLogDirname       = "logs"
 	defaultLogFilename      = "politeiad.log"
 	defaultIdentityFilename = "identity.json"
+	defaultHistoryFilename  = "identityhistory.json"
 
 	defaultMainnetPort = "49374"
 	defaultTestnetPort = "59374"
@@ -45,11 +50,27 @@ const (
 	defaultBackend = backendTstore
 
 	// Tstore default settings
+	defaultDBType   = tstore.DBTypeMySQL
 	defaultDBHost   = "localhost:3306" // MySQL default host
+	defaultTlogType = tstore.TlogTypeTrillian
 	defaultTlogHost = "localhost:8090"
 
+	// Tree cache default settings in MiB
+	defaultLeafCacheSize  = 256
+	defaultIndexCacheSize = 32
+
+	// Anchor default settings
+	defaultAnchor      = anchors.ProviderDcrtime
+	defaultOTSCalendar = anchors.DefaultOpenTimestampsCalendar
+	defaultBTCHeaders  = backendv2.DefaultBitcoinHeaderHost
+
+	// Cold store default settings
+	defaultColdStoreDirname = "coldstore"
+	defaultS3Region         = "us-east-1"
+
 	// Environment variables
-	envDBPass = "DBPASS"
+	envDBPass      = "DBPASS"
+	envS3SecretKey = "S3SECRETKEY"
 )
 
 var (
@@ -60,6 +81,7 @@ var (
 	defaultHTTPSCertFile = filepath.Join(defaultHomeDir, "https.cert")
 	defaultLogDir        = filepath.Join(defaultHomeDir, defaultLogDirname)
 	defaultIdentityFile  = filepath.Join(defaultHomeDir, defaultIdentityFilename)
+	defaultHistoryFile   = filepath.Join(defaultHomeDir, defaultHistoryFilename)
 
 	// defaultReadTimeout is the maximum duration in seconds that is spent
 	// reading the request headers and body.
@@ -99,9 +121,12 @@ type config struct {
 	HTTPSKey    string `long:"httpskey" description:"File containing the https certificate key"`
 	RPCUser     string `long:"rpcuser" description:"RPC user name for privileged commands"`
 	RPCPass     string `long:"rpcpass" description:"RPC password for privileged commands"`
+	APIKeys     string `long:"apikeys" description:"File containing the scoped API keys; replaces rpcuser and rpcpass"`
 	DcrtimeHost string `long:"dcrtimehost" description:"Dcrtime ip:port"`
 	DcrtimeCert string `long:"dcrtimecert" description:"Dcrtime HTTPS certificate"`
 	Identity    string `long:"identity" description:"File containing the politeiad identity file"`
+	History     string `long:"identityhistory" description:"File containing the cross-signed transitions of the politeiad identity"`
+	Rotate      bool   `long:"rotateidentity" description:"Replace the politeiad identity with a new identity that is signed by the old identity on startup"`
 	Backend     string `long:"backend" description:"Backend type"`
 	Fsck        bool   `long:"fsck" description:"Perform filesystem checks on all record and plugin data"`
 
@@ -110,15 +135,47 @@ type config struct {
 	WriteTimeout     int64 `long:"writetimeout" description:"Maximum duration in seconds that a request connection is kept open"`
 	ReqBodySizeLimit int64 `long:"reqbodysizelimit" description:"Maximum number of bytes allowed for a request body from a http client"`
 
+	// Metrics settings
+	MetricsListen string `long:"metricslisten" description:"Interface/port to serve the Prometheus metrics on over plain HTTP; the interface defaults to localhost; disabled if empty"`
+	MetricsUser   string `long:"metricsuser" description:"Basic auth user name for the metrics; required when the metrics are served on a non-loopback interface"`
+	MetricsPass   string `long:"metricspass" description:"Basic auth password for the metrics; required when the metrics are served on a non-loopback interface"`
+
+	// Health check settings
+	HealthListen string `long:"healthlisten" description:"Interface/port to serve the liveness and readiness routes on over plain HTTP; disabled if empty"`
+
 	// Git backend options
 	GitTrace    bool   `long:"gittrace" description:"Enable git tracing in logs"`
 	DcrdataHost string `long:"dcrdatahost" description:"Dcrdata ip:port"`
 
 	// Tstore backend options
+	DBType   string `long:"dbtype" description:"Database type {mysql, sqlite}"`
 	DBHost   string `long:"dbhost" description:"Database ip:port"`
 	DBPass   string // Provided in env variable "DBPASS"
+	TlogType string `long:"tlogtype" description:"Tlog type {trillian, embedded}"`
 	TlogHost string `long:"tloghost" description:"Trillian log ip:port"`
 
+	// Tree cache options
+	LeafCacheSize  int64 `long:"leafcachesize" description:"Maximum memory in MiB that is used to cache tlog leaves; 0 disables the cache"`
+	IndexCacheSize int64 `long:"indexcachesize" description:"Maximum memory in MiB that is used to cache record indexes; 0 disables the cache"`
+
+	// Anchor options
+	Anchors     []string `long:"anchor" description:"Anchor providers {dcrtime, rfc3161, opentimestamps}"`
+	TSAHost     string   `long:"tsahost" description:"RFC 3161 time-stamp authority URL"`
+	TSACert     string   `long:"tsacert" description:"RFC 3161 time-stamp authority HTTPS certificate"`
+	TSARoots    string   `long:"tsaroots" description:"RFC 3161 trusted time-stamp authority root certificates; defaults to the system roots"`
+	OTSCalendar string   `long:"otscalendar" description:"OpenTimestamps calendar URL"`
+	BTCHeaders  string   `long:"btcheaders" description:"Esplora API URL that the bitcoin block headers of OpenTimestamps proofs are verified against"`
+
+	// Cold store options
+	ColdStore    string `long:"coldstore" description:"Cold store that the blobs of frozen records are moved to {fs, s3}"`
+	ColdStoreDir string `long:"coldstoredir" description:"Directory of the fs cold store"`
+	S3Endpoint   string `long:"s3endpoint" description:"S3 compatible object storage URL"`
+	S3Bucket     string `long:"s3bucket" description:"S3 bucket of the cold store"`
+	S3Region     string `long:"s3region" description:"S3 region of the bucket"`
+	S3AccessKey  string `long:"s3accesskey" description:"S3 access key ID"`
+	S3SecretKey  string // Provided in env variable "S3SECRETKEY"
+	S3Cert       string `long:"s3cert" description:"S3 endpoint HTTPS certificate"`
+
 	// Plugin options
 	Plugins        []string `long:"plugin" description:"Plugins"`
 	PluginSettings []string `long:"pluginsetting" description:"Plugin settings"`
@@ -238,6 +295,33 @@ func normalizeAddresses(addrs []string, defaultPort string) []string {
 	return removeDuplicateAddresses(addrs)
 }
 
+// metricsListenAddr returns the metrics listen address with the interface
+// defaulted to localhost when only a port is provided.
+func metricsListenAddr(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// isLoopbackAddr returns whether the host of the provided host:port address
+// is a loopback interface.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 // newConfigParser returns a new command line flags parser.
 func newConfigParser(cfg *config, so *serviceOptions, options flags.Options) *flags.Parser {
 	parser := flags.NewParser(cfg, options)
@@ -274,8 +358,14 @@ func loadConfig() (*config, []string, error) {
 		ReadTimeout:      defaultReadTimeout,
 		WriteTimeout:     defaultWriteTimeout,
 		ReqBodySizeLimit: defaultReqBodySizeLimit,
+		DBType:           defaultDBType,
 		DBHost:           defaultDBHost,
+		TlogType:         defaultTlogType,
 		TlogHost:         defaultTlogHost,
+		LeafCacheSize:    defaultLeafCacheSize,
+		IndexCacheSize:   defaultIndexCacheSize,
+		OTSCalendar:      defaultOTSCalendar,
+		BTCHeaders:       defaultBTCHeaders,
 	}
 
 	// Service options which are only added on Windows.
@@ -485,6 +575,25 @@ func loadConfig() (*config, []string, error) {
 	// duplicate addresses.
 	cfg.Listeners = normalizeAddresses(cfg.Listeners, port)
 
+	// Verify the metrics settings. The metrics are served over plain
+	// HTTP, so they are only served without authentication when the
+	// listener is bound to a loopback interface.
+	if cfg.MetricsListen != "" {
+		cfg.MetricsListen, err = metricsListenAddr(cfg.MetricsListen)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid metricslisten: %v", err)
+		}
+	}
+	if (cfg.MetricsUser == "") != (cfg.MetricsPass == "") {
+		return nil, nil, fmt.Errorf("metricsuser and metricspass must " +
+			"be provided together")
+	}
+	if cfg.MetricsListen != "" && cfg.MetricsUser == "" &&
+		!isLoopbackAddr(cfg.MetricsListen) {
+		return nil, nil, fmt.Errorf("metricsuser and metricspass are " +
+			"required when metricslisten is not a loopback interface")
+	}
+
 	if len(cfg.DcrdataHost) == 0 {
 		if cfg.TestNet {
 			cfg.DcrdataHost = defaultTestnetDcrdata
@@ -533,6 +642,10 @@ func loadConfig() (*config, []string, error) {
 		cfg.Identity = defaultIdentityFile
 	}
 	cfg.Identity = util.CleanAndExpandPath(cfg.Identity)
+	if cfg.History == "" {
+		cfg.History = defaultHistoryFile
+	}
+	cfg.History = util.CleanAndExpandPath(cfg.History)
 
 	// Set random username and password when not specified
 	if cfg.RPCUser == "" {
@@ -555,7 +668,14 @@ func loadConfig() (*config, []string, error) {
 	// Verify backend specific settings
 	switch cfg.Backend {
 	case backendGit:
-		// Nothing to do
+		if cfg.APIKeys != "" {
+			return nil, nil, fmt.Errorf("apikeys is not supported by " +
+				"the git backend")
+		}
+		if cfg.Rotate {
+			return nil, nil, fmt.Errorf("rotateidentity is not " +
+				"supported by the git backend")
+		}
 	case backendTstore:
 		err = verifyTstoreSettings(&cfg)
 		if err != nil {
@@ -578,7 +698,17 @@ func loadConfig() (*config, []string, error) {
 // verifyTstoreSettings verifies the config settings that are specific to the
 // tstore backend.
 func verifyTstoreSettings(cfg *config) error {
+	// Verify the database type
+	switch cfg.DBType {
+	case tstore.DBTypeMySQL, tstore.DBTypeSQLite:
+		// These are allowed
+	default:
+		return fmt.Errorf("invalid db type '%v'", cfg.DBType)
+	}
+
 	// Parse the database password. It is provided in an env variable.
+	// The password is used to derive the encryption key for both the
+	// MySQL and the SQLite database types.
 	cfg.DBPass = os.Getenv(envDBPass)
 	if cfg.DBPass == "" {
 		return fmt.Errorf("dbpass not found; you must provide the " +
@@ -587,9 +717,129 @@ func verifyTstoreSettings(cfg *config) error {
 	}
 
 	// Verify tlog options
-	_, err := url.Parse(cfg.TlogHost)
-	if err != nil {
-		return fmt.Errorf("invalid tlog host '%v': %v", cfg.TlogHost, err)
+	switch cfg.TlogType {
+	case tstore.TlogTypeTrillian:
+		_, err := url.Parse(cfg.TlogHost)
+		if err != nil {
+			return fmt.Errorf("invalid tlog host '%v': %v", cfg.TlogHost, err)
+		}
+	case tstore.TlogTypeEmbedded:
+		// The tlog host is not used
+	default:
+		return fmt.Errorf("invalid tlog type '%v'", cfg.TlogType)
+	}
+
+	// Verify the API keys file
+	if cfg.APIKeys != "" {
+		cfg.APIKeys = util.CleanAndExpandPath(cfg.APIKeys)
+		if !util.FileExists(cfg.APIKeys) {
+			return fmt.Errorf("apikeys file not found: %v", cfg.APIKeys)
+		}
+	}
+
+	// Verify tree cache options
+	if cfg.LeafCacheSize < 0 || cfg.IndexCacheSize < 0 {
+		return fmt.Errorf("leafcachesize and indexcachesize must not be " +
+			"negative")
+	}
+
+	// Verify anchor options. The trees are anchored using dcrtime when
+	// no anchor providers are specified. The TSA roots are used to
+	// verify all RFC 3161 proofs, including the proofs of anchors that
+	// were dropped prior to the rfc3161 provider being disabled.
+	if cfg.TSARoots != "" {
+		cfg.TSARoots = util.CleanAndExpandPath(cfg.TSARoots)
+		if !util.FileExists(cfg.TSARoots) {
+			return fmt.Errorf("tsaroots %v does not exist", cfg.TSARoots)
+		}
+	}
+	if len(cfg.Anchors) == 0 {
+		cfg.Anchors = []string{defaultAnchor}
+	}
+	providers := make(map[string]struct{}, len(cfg.Anchors))
+	for _, v := range cfg.Anchors {
+		if _, ok := providers[v]; ok {
+			return fmt.Errorf("duplicate anchor provider '%v'", v)
+		}
+		providers[v] = struct{}{}
+
+		switch v {
+		case anchors.ProviderDcrtime:
+			// The dcrtime host has already been verified
+		case anchors.ProviderRFC3161:
+			if cfg.TSAHost == "" {
+				return fmt.Errorf("tsahost is required by the %v anchor "+
+					"provider", v)
+			}
+			_, err := url.Parse(cfg.TSAHost)
+			if err != nil {
+				return fmt.Errorf("invalid tsa host '%v': %v", cfg.TSAHost, err)
+			}
+			if cfg.TSACert != "" {
+				cfg.TSACert = util.CleanAndExpandPath(cfg.TSACert)
+				if !util.FileExists(cfg.TSACert) {
+					return fmt.Errorf("tsacert %v does not exist", cfg.TSACert)
+				}
+			}
+		case anchors.ProviderOpenTimestamps:
+			_, err := url.Parse(cfg.OTSCalendar)
+			if err != nil {
+				return fmt.Errorf("invalid ots calendar '%v': %v",
+					cfg.OTSCalendar, err)
+			}
+			if cfg.BTCHeaders != "" {
+				_, err := url.Parse(cfg.BTCHeaders)
+				if err != nil {
+					return fmt.Errorf("invalid btc headers host '%v': %v",
+						cfg.BTCHeaders, err)
+				}
+			}
+		default:
+			return fmt.Errorf("invalid anchor provider '%v'", v)
+		}
+	}
+
+	// Verify cold store options. The blobs of frozen records are only
+	// moved to a cold store when one has been specified.
+	switch cfg.ColdStore {
+	case "":
+		// Archival is disabled
+	case coldstore.TypeFS:
+		if cfg.ColdStoreDir == "" {
+			cfg.ColdStoreDir = filepath.Join(cfg.DataDir,
+				defaultColdStoreDirname)
+		}
+		cfg.ColdStoreDir = util.CleanAndExpandPath(cfg.ColdStoreDir)
+	case coldstore.TypeS3:
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return fmt.Errorf("s3endpoint and s3bucket are required by " +
+				"the s3 cold store")
+		}
+		_, err := url.Parse(cfg.S3Endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid s3 endpoint '%v': %v",
+				cfg.S3Endpoint, err)
+		}
+		if cfg.S3Region == "" {
+			cfg.S3Region = defaultS3Region
+		}
+		if cfg.S3Cert != "" {
+			cfg.S3Cert = util.CleanAndExpandPath(cfg.S3Cert)
+			if !util.FileExists(cfg.S3Cert) {
+				return fmt.Errorf("s3cert %v does not exist", cfg.S3Cert)
+			}
+		}
+
+		// The secret key is provided in an env variable so that it
+		// does not end up in the config file.
+		cfg.S3SecretKey = os.Getenv(envS3SecretKey)
+		if cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			return fmt.Errorf("s3 credentials not found; you must provide " +
+				"the s3accesskey setting and the secret key in the env " +
+				"variable S3SECRETKEY")
+		}
+	default:
+		return fmt.Errorf("invalid cold store '%v'", cfg.ColdStore)
 	}
 
 	return nil
@@ -0,0 +1,142 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// fsckFunc is the same function signature as the backendv2 Fsck function.
+// This allows test coverage to be added to the fsck job using a custom fsck
+// function setup for testing.
+type fsckFunc func(opts backendv2.FsckOpts) (*backendv2.FsckReport, error)
+
+// fsckJob runs a backend filesystem check in the background and tracks its
+// progress. Only one filesystem check can be run at a time. The status of
+// the most recent check is kept in memory until a new check is started.
+type fsckJob struct {
+	sync.Mutex
+	running   bool
+	dryRun    bool
+	stage     string
+	done      int
+	total     int
+	started   int64 // Unix timestamp
+	completed int64 // Unix timestamp
+	err       error
+	report    *backendv2.FsckReport
+}
+
+// start starts a filesystem check in the background. A user error is returned
+// if a filesystem check is already running.
+func (j *fsckJob) start(fn fsckFunc, dryRun bool) error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.running {
+		return v2.UserErrorReply{
+			ErrorCode: v2.ErrorCodeFsckInProgress,
+		}
+	}
+
+	// Reset the status of the previous check
+	j.running = true
+	j.dryRun = dryRun
+	j.stage = ""
+	j.done = 0
+	j.total = 0
+	j.started = time.Now().Unix()
+	j.completed = 0
+	j.err = nil
+	j.report = nil
+
+	go j.run(fn, dryRun)
+
+	return nil
+}
+
+// run executes the filesystem check and records the result.
+func (j *fsckJob) run(fn fsckFunc, dryRun bool) {
+	log.Infof("Starting fsck (dry run: %v)", dryRun)
+
+	report, err := fn(backendv2.FsckOpts{
+		DryRun:   dryRun,
+		Progress: j.setProgress,
+	})
+
+	j.Lock()
+	defer j.Unlock()
+
+	j.running = false
+	j.completed = time.Now().Unix()
+	j.err = err
+	j.report = report
+
+	if err != nil {
+		log.Errorf("Fsck failed: %v", err)
+		return
+	}
+
+	log.Infof("Fsck complete: %v records checked, %v issues found",
+		report.Records, len(report.Issues))
+}
+
+// setProgress updates the progress of the running filesystem check.
+func (j *fsckJob) setProgress(stage string, done, total int) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.stage = stage
+	j.done = done
+	j.total = total
+}
+
+// status returns the status of the most recent filesystem check. The
+// challenge response is not populated.
+func (j *fsckJob) status() v2.FsckStatusReply {
+	j.Lock()
+	defer j.Unlock()
+
+	sr := v2.FsckStatusReply{
+		Running:   j.running,
+		DryRun:    j.dryRun,
+		Stage:     j.stage,
+		Done:      j.done,
+		Total:     j.total,
+		Started:   j.started,
+		Completed: j.completed,
+	}
+	if j.err != nil {
+		sr.Error = j.err.Error()
+	}
+	if j.report != nil {
+		r := convertFsckReportToV2(*j.report)
+		sr.Report = &r
+	}
+
+	return sr
+}
+
+func convertFsckReportToV2(r backendv2.FsckReport) v2.FsckReport {
+	issues := make([]v2.FsckIssue, 0, len(r.Issues))
+	for _, v := range r.Issues {
+		issues = append(issues, v2.FsckIssue{
+			Type:        v2.FsckIssueT(v.Type),
+			PluginID:    v.PluginID,
+			Token:       v.Token,
+			Description: v.Description,
+			Repaired:    v.Repaired,
+		})
+	}
+	return v2.FsckReport{
+		DryRun:  r.DryRun,
+		Records: r.Records,
+		Issues:  issues,
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/slog"
+)
+
+// waitFsck waits for the fsck job to complete and returns its status.
+func waitFsck(t *testing.T, j *fsckJob) v2.FsckStatusReply {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		sr := j.status()
+		if !sr.Running {
+			return sr
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("fsck did not complete")
+	return v2.FsckStatusReply{}
+}
+
+func TestFsckJob(t *testing.T) {
+	// The log rotator is not initialized during tests
+	log = slog.Disabled
+
+	var (
+		j       fsckJob
+		proceed = make(chan struct{})
+		report  = backendv2.FsckReport{
+			DryRun:  true,
+			Records: 1,
+			Issues: []backendv2.FsckIssue{
+				{
+					Type:        backendv2.FsckIssueBlobMissing,
+					Token:       "0000000000000001",
+					Description: "blob missing",
+				},
+			},
+		}
+	)
+	fn := func(opts backendv2.FsckOpts) (*backendv2.FsckReport, error) {
+		if !opts.DryRun {
+			return nil, errors.New("dry run not set")
+		}
+		opts.Progress("records", 1, 2)
+		<-proceed
+		return &report, nil
+	}
+
+	// No fsck has been run
+	sr := j.status()
+	if sr.Started != 0 || sr.Running {
+		t.Fatalf("got status %+v, want none", sr)
+	}
+
+	// Start the fsck. A second fsck can't be started while the
+	// first one is running.
+	err := j.start(fn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = j.start(fn, true)
+	var ue v2.UserErrorReply
+	if !errors.As(err, &ue) || ue.ErrorCode != v2.ErrorCodeFsckInProgress {
+		t.Fatalf("got error %v, want %v", err, v2.ErrorCodeFsckInProgress)
+	}
+	sr = j.status()
+	if !sr.Running || !sr.DryRun || sr.Report != nil {
+		t.Fatalf("got status %+v, want running dry run", sr)
+	}
+
+	// Let the fsck complete
+	close(proceed)
+	sr = waitFsck(t, &j)
+	if sr.Error != "" || sr.Completed == 0 || sr.Report == nil {
+		t.Fatalf("got status %+v, want completed", sr)
+	}
+	if len(sr.Report.Issues) != 1 ||
+		sr.Report.Issues[0].Type != v2.FsckIssueBlobMissing {
+		t.Fatalf("got report %+v, want %+v", sr.Report, report)
+	}
+
+	// A failed fsck reports the error
+	err = j.start(fn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sr = waitFsck(t, &j)
+	if sr.Error == "" || sr.Report != nil {
+		t.Fatalf("got status %+v, want error", sr)
+	}
+}
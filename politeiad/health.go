@@ -0,0 +1,205 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	dcrtime "github.com/decred/dcrtime/api/v2"
+	"github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/anchors"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tstore"
+	"github.com/decred/politeia/util"
+)
+
+const (
+	// healthBackend is the name of the readiness check that reports
+	// the backend setup error until the backend has been setup. It is
+	// replaced by the backend dependency checks once setup succeeds.
+	healthBackend = "backend"
+
+	// healthDcrtime is the name of the dcrtime readiness check.
+	healthDcrtime = "dcrtime"
+
+	// backendRetryInterval is the amount of time that is waited before
+	// retrying a backend setup that failed.
+	backendRetryInterval = 15 * time.Second
+)
+
+// setupHealth registers the readiness checks that do not depend on the
+// backend. dcrtime is checked when the backend relies on it, i.e. when the
+// git backend is used or when dcrtime is an anchor provider.
+func (p *politeia) setupHealth() error {
+	useDcrtime := p.cfg.Backend == backendGit
+	for _, v := range p.cfg.Anchors {
+		if v == anchors.ProviderDcrtime {
+			useDcrtime = true
+		}
+	}
+	if !useDcrtime {
+		return nil
+	}
+
+	c, err := util.NewHTTPClient(false, p.cfg.DcrtimeCert)
+	if err != nil {
+		return err
+	}
+	p.health.Register(healthDcrtime, func(ctx context.Context) error {
+		return dcrtimePing(ctx, c, p.cfg.DcrtimeHost)
+	})
+
+	return nil
+}
+
+// dcrtimePing verifies that dcrtime is reachable by requesting its status.
+func dcrtimePing(ctx context.Context, c *http.Client, host string) error {
+	b, err := json.Marshal(dcrtime.Status{ID: "politeiad"})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		host+dcrtime.StatusRoute, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	r, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return fmt.Errorf("dcrtime status: %v", r.Status)
+	}
+
+	return nil
+}
+
+// healthListen serves the liveness and readiness routes on the health
+// listener. The routes are served over plain HTTP without authentication for
+// orchestrators that are not able to probe the TLS listeners. No other routes
+// are served on the health listener.
+func (p *politeia) healthListen() error {
+	handler := http.NewServeMux()
+	handler.HandleFunc(util.HealthRouteLive, p.health.HandleLive)
+	handler.HandleFunc(util.HealthRouteReady, p.health.HandleReady)
+	s := &http.Server{
+		Handler:      handler,
+		Addr:         p.cfg.HealthListen,
+		ReadTimeout:  time.Duration(p.cfg.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(p.cfg.WriteTimeout) * time.Second,
+	}
+
+	log.Infof("Health listen: %v", p.cfg.HealthListen)
+
+	return s.ListenAndServe()
+}
+
+// tstoreConfigCheck verifies the tstore backend settings that do not depend on
+// a backend service being reachable. A setup that fails because of one of
+// these settings fails on every retry, so they are verified up front.
+func (p *politeia) tstoreConfigCheck() error {
+	switch p.cfg.DBType {
+	case tstore.DBTypeMySQL:
+		if p.cfg.DBHost == "" {
+			return fmt.Errorf("dbhost not provided")
+		}
+	case tstore.DBTypeSQLite:
+	default:
+		return fmt.Errorf("invalid db type '%v'", p.cfg.DBType)
+	}
+	if p.cfg.DBPass == "" {
+		return fmt.Errorf("dbpass not provided")
+	}
+	switch p.cfg.TlogType {
+	case tstore.TlogTypeTrillian:
+		if p.cfg.TlogHost == "" {
+			return fmt.Errorf("tloghost not provided")
+		}
+	case tstore.TlogTypeEmbedded:
+	default:
+		return fmt.Errorf("invalid tlog type '%v'", p.cfg.TlogType)
+	}
+
+	// The identity key is used to sign snapshots and censored files
+	if p.identity == nil || !util.FileExists(p.cfg.Identity) {
+		return fmt.Errorf("identity key file %v not found", p.cfg.Identity)
+	}
+
+	// Verify that the data directory can be written to
+	err := os.MkdirAll(p.cfg.DataDir, 0700)
+	if err != nil {
+		return fmt.Errorf("data dir: %v", err)
+	}
+	f, err := os.CreateTemp(p.cfg.DataDir, ".politeiad")
+	if err != nil {
+		return fmt.Errorf("data dir %v is not writable: %v",
+			p.cfg.DataDir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// newBackendTstore returns a new tstore backend. A setup that fails because
+// trillian or the key-value store is not reachable is retried until they are.
+// The setup error is reported by the readiness route in the meantime. All
+// other setup errors are returned. A nil backend is returned if a signal is
+// received while waiting for a retry.
+func (p *politeia) newBackendTstore(anp *chaincfg.Params, sigs <-chan os.Signal) (backendv2.Backend, error) {
+	err := p.tstoreConfigCheck()
+	if err != nil {
+		return nil, err
+	}
+	providers, err := p.anchorProviders()
+	if err != nil {
+		return nil, err
+	}
+	cold, err := p.coldStore()
+	if err != nil {
+		return nil, fmt.Errorf("new cold store: %v", err)
+	}
+
+	for {
+		b, err := tstorebe.New(p.cfg.HomeDir, p.cfg.DataDir,
+			anp, p.cfg.TlogType, p.cfg.TlogHost, p.cfg.DBType, p.cfg.DBHost,
+			p.cfg.DBPass, providers, cold, p.cfg.LeafCacheSize<<20,
+			p.cfg.IndexCacheSize<<20, p.identity)
+		if err == nil {
+			// Replace the setup error with the checks of the backend
+			// dependencies.
+			p.health.RegisterGroup(healthBackend,
+				func(ctx context.Context) map[string]error {
+					return b.Health()
+				})
+			return b, nil
+		}
+		var ce tstore.ConnError
+		if !errors.As(err, &ce) {
+			return nil, fmt.Errorf("new tstorebe: %v", err)
+		}
+
+		err = fmt.Errorf("new tstorebe: %v", err)
+		p.health.Register(healthBackend, func(ctx context.Context) error {
+			return err
+		})
+		log.Errorf("%v; retry in %v", err, backendRetryInterval)
+
+		select {
+		case <-time.After(backendRetryInterval):
+		case sig := <-sigs:
+			log.Infof("Terminating with %v", sig)
+			return nil, nil
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	"github.com/decred/politeia/politeiad/client"
+)
+
+// identityHistoryFile is the JSON structure of the identity history file. The
+// transitions are ordered from oldest to newest.
+type identityHistoryFile struct {
+	Transitions []v2.IdentityTransition `json:"transitions"`
+}
+
+// loadIdentityHistory loads the identity history file and verifies that the
+// transitions form a chain of cross-signed transitions that ends at the
+// provided public key. An empty history is returned if the file does not
+// exist, i.e. the identity has never been rotated.
+func loadIdentityHistory(fp string, pid *identity.PublicIdentity) ([]v2.IdentityTransition, error) {
+	b, err := os.ReadFile(fp)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return []v2.IdentityTransition{}, nil
+	case err != nil:
+		return nil, err
+	}
+	var f identityHistoryFile
+	err = json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal %v: %v", fp, err)
+	}
+	if len(f.Transitions) == 0 {
+		return []v2.IdentityTransition{}, nil
+	}
+
+	// The chain is verified from the first key of the history since
+	// the history is the only source of the previous keys.
+	err = client.IdentityHistoryVerify(f.Transitions[0].OldPublicKey,
+		pid.String(), f.Transitions)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", fp, err)
+	}
+
+	return f.Transitions, nil
+}
+
+// saveIdentityHistory saves the identity history file. The file is written to
+// a temporary file that is then renamed so that the history is never left
+// partially written.
+func saveIdentityHistory(fp string, transitions []v2.IdentityTransition) error {
+	b, err := json.MarshalIndent(identityHistoryFile{
+		Transitions: transitions,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := fp + ".tmp"
+	err = os.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, fp)
+}
+
+// rotateIdentity replaces the identity with a newly generated identity and
+// appends the cross-signed transition onto the identity history. The old
+// identity is kept alongside the identity file, suffixed with the transition
+// timestamp, so that it can be recovered.
+//
+// The new identity is written to a temporary file first and is only moved
+// into place once the history has been saved. An interrupted rotation leaves
+// either the old identity and the old history, or a history that ends at the
+// key in the temporary file. The latter fails the history verification on
+// startup and is fixed by moving the temporary file into place.
+func rotateIdentity(idFile, historyFile string) (*identity.FullIdentity, []v2.IdentityTransition, error) {
+	old, err := identity.LoadFullIdentity(idFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	transitions, err := loadIdentityHistory(historyFile, &old.Public)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := identity.New()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Cross-sign the transition
+	t := v2.IdentityTransition{
+		OldPublicKey: old.Public.String(),
+		NewPublicKey: id.Public.String(),
+		Timestamp:    time.Now().Unix(),
+	}
+	msg := []byte(client.IdentityTransitionMsg(t))
+	oldSig := old.SignMessage(msg)
+	newSig := id.SignMessage(msg)
+	t.OldSignature = hex.EncodeToString(oldSig[:])
+	t.NewSignature = hex.EncodeToString(newSig[:])
+	if len(transitions) > 0 &&
+		t.Timestamp <= transitions[len(transitions)-1].Timestamp {
+		return nil, nil, fmt.Errorf("identity was rotated less than a " +
+			"second ago")
+	}
+	transitions = append(transitions, t)
+
+	// Save the new identity and the history
+	tmp := idFile + ".new"
+	err = id.Save(tmp)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = old.Save(idFile + "." + strconv.FormatInt(t.Timestamp, 10))
+	if err != nil {
+		return nil, nil, err
+	}
+	err = saveIdentityHistory(historyFile, transitions)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = os.Rename(tmp, idFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("identity history has been updated but "+
+			"the new identity could not be moved from %v to %v: %v",
+			tmp, idFile, err)
+	}
+
+	return id, transitions, nil
+}
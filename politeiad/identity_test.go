@@ -0,0 +1,140 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/decred/politeia/politeiad/api/v1/identity"
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	"github.com/decred/politeia/politeiad/client"
+)
+
+func TestRotateIdentity(t *testing.T) {
+	var (
+		dir         = t.TempDir()
+		idFile      = filepath.Join(dir, "identity.json")
+		historyFile = filepath.Join(dir, "identityhistory.json")
+	)
+	id, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = id.Save(idFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An identity that has never been rotated has an empty history
+	h, err := loadIdentityHistory(historyFile, &id.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 0 {
+		t.Fatalf("got %v transitions, want 0", len(h))
+	}
+
+	// Rotate the identity
+	id1, h, err := rotateIdentity(idFile, historyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := identity.LoadFullIdentity(idFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Public.String() != id1.Public.String() {
+		t.Fatalf("rotated identity not saved")
+	}
+	if len(h) != 1 || h[0].OldPublicKey != id.Public.String() ||
+		h[0].NewPublicKey != id1.Public.String() {
+		t.Fatalf("unexpected transitions %+v", h)
+	}
+
+	// The history is verified against the current identity
+	_, err = loadIdentityHistory(historyFile, &id1.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = loadIdentityHistory(historyFile, &id.Public)
+	if err == nil {
+		t.Fatalf("history verified against the old identity")
+	}
+
+	// Append a second transition manually since the timestamps of
+	// the transitions must increase.
+	id2, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t2 := h[0]
+	t2.OldPublicKey = id1.Public.String()
+	t2.NewPublicKey = id2.Public.String()
+	t2.Timestamp = h[0].Timestamp + 100
+	msg := []byte(client.IdentityTransitionMsg(t2))
+	oldSig := id1.SignMessage(msg)
+	newSig := id2.SignMessage(msg)
+	t2.OldSignature = hex.EncodeToString(oldSig[:])
+	t2.NewSignature = hex.EncodeToString(newSig[:])
+	h = append(h, t2)
+	current := id2.Public.String()
+
+	// The pinned key must be part of the chain
+	for _, v := range []string{id.Public.String(), id1.Public.String(),
+		current} {
+		err = client.IdentityHistoryVerify(v, current, h)
+		if err != nil {
+			t.Fatalf("pinned %v: %v", v, err)
+		}
+	}
+	other, err := identity.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.IdentityHistoryVerify(other.Public.String(), current, h)
+	if err == nil {
+		t.Fatalf("unknown pinned key verified")
+	}
+
+	// A transition that is not signed by the old key is rejected
+	forged := append([]v2.IdentityTransition(nil), h...)
+	forged[1].OldSignature = forged[1].NewSignature
+	err = client.IdentityHistoryVerify(id.Public.String(), current, forged)
+	if err == nil {
+		t.Fatalf("forged transition verified")
+	}
+
+	// Keys valid at a timestamp. The keys overlap at the transition
+	// timestamps.
+	var (
+		ts0 = h[0].Timestamp
+		ts1 = h[1].Timestamp
+		k0  = id.Public.String()
+		k1  = id1.Public.String()
+	)
+	var tests = []struct {
+		name       string
+		start, end int64
+		want       []string
+	}{
+		{"before first", ts0 - 1, ts0 - 1, []string{k0}},
+		{"first transition", ts0, ts0, []string{k0, k1}},
+		{"between", ts0 + 1, ts1 - 1, []string{k1}},
+		{"second transition", ts1, ts1, []string{k1, current}},
+		{"after last", ts1 + 1, ts1 + 1, []string{current}},
+		{"all", 0, ts1 + 1, []string{k0, k1, current}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := client.IdentityKeys(current, h, tc.start, tc.end)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+)
+
+// jobFunc is the function that is executed by a background job. The progress
+// function must be invoked by the job function as work is completed.
+type jobFunc[R any] func(progress func(done, total int)) (*R, error)
+
+// jobStatus is the status of the most recent run of a background job.
+type jobStatus[R any] struct {
+	running   bool
+	done      int
+	total     int
+	started   int64 // Unix timestamp
+	completed int64 // Unix timestamp
+	err       error
+	report    *R
+}
+
+// backgroundJob runs a backend operation in the background and tracks its
+// progress. Only one run of a job can be in progress at a time. The status of
+// the most recent run is kept in memory until the job is started again. The
+// zero value is ready to use.
+type backgroundJob[R any] struct {
+	sync.Mutex
+	jobStatus[R]
+}
+
+// start starts the job function in the background. The name is used in log
+// messages. A user error with the provided error code is returned if the job
+// is already running.
+func (j *backgroundJob[R]) start(name string, inProgress v2.ErrorCodeT, fn jobFunc[R]) error {
+	j.Lock()
+	defer j.Unlock()
+
+	if j.running {
+		return v2.UserErrorReply{
+			ErrorCode: inProgress,
+		}
+	}
+
+	// Reset the status of the previous run
+	j.jobStatus = jobStatus[R]{
+		running: true,
+		started: time.Now().Unix(),
+	}
+
+	go j.run(name, fn)
+
+	return nil
+}
+
+// run executes the job function and records the result.
+func (j *backgroundJob[R]) run(name string, fn jobFunc[R]) {
+	log.Infof("Starting %v", name)
+
+	report, err := fn(j.setProgress)
+
+	j.Lock()
+	defer j.Unlock()
+
+	j.running = false
+	j.completed = time.Now().Unix()
+	j.err = err
+	j.report = report
+
+	if err != nil {
+		log.Errorf("Failed %v: %v", name, err)
+	}
+}
+
+// setProgress updates the progress of the running job.
+func (j *backgroundJob[R]) setProgress(done, total int) {
+	j.Lock()
+	defer j.Unlock()
+
+	j.done = done
+	j.total = total
+}
+
+// status returns the status of the most recent run of the job.
+func (j *backgroundJob[R]) status() jobStatus[R] {
+	j.Lock()
+	defer j.Unlock()
+
+	return j.jobStatus
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	"github.com/decred/slog"
+)
+
+func TestBackgroundJob(t *testing.T) {
+	// The log rotator is not initialized during tests
+	log = slog.Disabled
+
+	type report struct {
+		count int
+	}
+	var (
+		j          backgroundJob[report]
+		inProgress = v2.ErrorCodeReencodeInProgress
+		proceed    = make(chan struct{})
+		fail       bool
+	)
+	fn := func(progress func(done, total int)) (*report, error) {
+		if fail {
+			return nil, errors.New("job failed")
+		}
+		progress(1, 2)
+		<-proceed
+		return &report{count: 2}, nil
+	}
+	waitStatus := func(done func(jobStatus[report]) bool) jobStatus[report] {
+		t.Helper()
+		for i := 0; i < 100; i++ {
+			s := j.status()
+			if done(s) {
+				return s
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("job status was not updated")
+		return jobStatus[report]{}
+	}
+
+	// Only one run of the job can be in progress at a time
+	err := j.start("test job", inProgress, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = j.start("test job", inProgress, fn)
+	var ue v2.UserErrorReply
+	if !errors.As(err, &ue) || ue.ErrorCode != inProgress {
+		t.Fatalf("got error %v, want %v", err, inProgress)
+	}
+	waitStatus(func(s jobStatus[report]) bool {
+		return s.running && s.done == 1 && s.total == 2
+	})
+	close(proceed)
+	s := waitStatus(func(s jobStatus[report]) bool {
+		return !s.running
+	})
+	if s.err != nil || s.report == nil || s.report.count != 2 ||
+		s.completed == 0 {
+		t.Fatalf("got status %+v, want completed", s)
+	}
+
+	// A failed run reports the error and resets the previous status
+	fail = true
+	err = j.start("test job", inProgress, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = waitStatus(func(s jobStatus[report]) bool {
+		return !s.running
+	})
+	if s.err == nil || s.report != nil || s.done != 0 {
+		t.Fatalf("got status %+v, want error", s)
+	}
+}
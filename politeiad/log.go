@@ -11,13 +11,17 @@ import (
 
 	"github.com/decred/politeia/politeiad/backend/gitbe"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/anchors"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/coldstore"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/comments"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/dcrdata"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/pi"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/search"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/ticketvote"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/plugins/usermd"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store/localdb"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store/mysql"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/store/sqlite"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tlog"
 	"github.com/decred/politeia/politeiad/backendv2/tstorebe/tstore"
 	"github.com/decred/politeia/politeiawww/wsdcrdata"
@@ -60,6 +64,8 @@ var (
 	wsdcrdataLog = backendLog.Logger("WSDD")
 	pluginLog    = backendLog.Logger("PLUG")
 	tlogLog      = backendLog.Logger("TLOG")
+	anchorLog    = backendLog.Logger("ANCH")
+	coldLog      = backendLog.Logger("COLD")
 )
 
 // Initialize package-global logger variables.
@@ -72,7 +78,10 @@ func init() {
 	tstore.UseLogger(tstoreLog)
 	localdb.UseLogger(kvstoreLog)
 	mysql.UseLogger(kvstoreLog)
+	sqlite.UseLogger(kvstoreLog)
 	tlog.UseLogger(tlogLog)
+	anchors.UseLogger(anchorLog)
+	coldstore.UseLogger(coldLog)
 
 	// Plugin loggers
 	comments.UseLogger(pluginLog)
@@ -80,6 +89,7 @@ func init() {
 	ticketvote.UseLogger(pluginLog)
 	usermd.UseLogger(pluginLog)
 	pi.UseLogger(pluginLog)
+	search.UseLogger(pluginLog)
 
 	// Other loggers
 	wsdcrdata.UseLogger(wsdcrdataLog)
@@ -95,6 +105,8 @@ var subsystemLoggers = map[string]slog.Logger{
 	"WSDD": wsdcrdataLog,
 	"PLUG": pluginLog,
 	"TLOG": tlogLog,
+	"ANCH": anchorLog,
+	"COLD": coldLog,
 }
 
 // initLogRotator initializes the logging rotater to write logs to logFile and
@@ -0,0 +1,105 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"time"
+
+	"github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/metrics"
+)
+
+const (
+	// metricsRoute is the route that the metrics are served on.
+	metricsRoute = "/metrics"
+
+	// metricsPollInterval is the interval at which the backend metrics
+	// are updated.
+	metricsPollInterval = time.Minute
+)
+
+// metricsListen serves the metrics on the metrics listener. The metrics are
+// served over plain HTTP. They require basic auth when the metrics user and
+// password are configured, which is enforced by the config for listeners that
+// are not bound to a loopback interface.
+func (p *politeia) metricsListen() error {
+	handler := http.NewServeMux()
+	handler.Handle(metricsRoute, p.metricsAuth(metrics.Handler()))
+	s := &http.Server{
+		Handler:      handler,
+		Addr:         p.cfg.MetricsListen,
+		ReadTimeout:  time.Duration(p.cfg.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(p.cfg.WriteTimeout) * time.Second,
+	}
+
+	log.Infof("Metrics listen: %v", p.cfg.MetricsListen)
+
+	return s.ListenAndServe()
+}
+
+// metricsAuth requires the metrics basic auth credentials for the provided
+// handler. The handler is returned unchanged when no credentials have been
+// configured.
+func (p *politeia) metricsAuth(h http.Handler) http.Handler {
+	if p.cfg.MetricsUser == "" {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(user),
+				[]byte(p.cfg.MetricsUser)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(pass),
+				[]byte(p.cfg.MetricsPass)) != 1 {
+			log.Infof("%v Unauthorized metrics access for: %v",
+				remoteAddr(r), user)
+			w.Header().Set("WWW-Authenticate", `Basic realm="Politeiad"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// metricsPoll updates the backend metrics until the done channel is closed.
+func (p *politeia) metricsPoll(done <-chan struct{}) {
+	ticker := time.NewTicker(metricsPollInterval)
+	defer ticker.Stop()
+	for {
+		p.metricsUpdate()
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		}
+	}
+}
+
+// metricsUpdate updates the inventory and the anchor metrics. Errors are
+// logged since the metrics are best effort.
+func (p *politeia) metricsUpdate() {
+	ic, err := p.backendv2.InventoryCounts()
+	if err != nil {
+		log.Errorf("metricsUpdate: InventoryCounts: %v", err)
+	} else {
+		for s, n := range ic.Unvetted {
+			metrics.Inventory(backendv2.States[backendv2.StateUnvetted],
+				backendv2.Statuses[s], n)
+		}
+		for s, n := range ic.Vetted {
+			metrics.Inventory(backendv2.States[backendv2.StateVetted],
+				backendv2.Statuses[s], n)
+		}
+	}
+
+	as, err := p.backendv2.AnchorStatus()
+	if err != nil {
+		log.Errorf("metricsUpdate: AnchorStatus: %v", err)
+		return
+	}
+	metrics.Anchor(as.Lag, as.Leaves, as.LastAnchor)
+}
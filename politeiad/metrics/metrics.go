@@ -0,0 +1,327 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package metrics contains the Prometheus metrics of politeiad. The metrics
+// are recorded regardless of whether they are served. They are registered
+// with a dedicated registry that is served by Handler.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// namespace is the namespace of all politeiad metrics.
+	namespace = "politeiad"
+
+	// PluginCmdRead and PluginCmdWrite are the plugin command types.
+	PluginCmdRead  = "read"
+	PluginCmdWrite = "write"
+
+	// PluginErrPlugin is used for the plugin errors, i.e. the user errors
+	// that are returned by a plugin. PluginErrBackend is used for all
+	// other errors.
+	PluginErrPlugin  = "plugin"
+	PluginErrBackend = "backend"
+
+	// BlobKVGet and BlobKVPut are the blob key-value store operations.
+	BlobKVGet = "get"
+	BlobKVPut = "put"
+
+	// TreeCacheLeaves and TreeCacheIndexes are the tstore tree caches.
+	TreeCacheLeaves  = "leaves"
+	TreeCacheIndexes = "indexes"
+
+	// TreeCacheHit, TreeCacheExtend, and TreeCacheMiss are the results of
+	// the tree cache reads. A leaves read is an extend when only the
+	// leaves that were appended since the leaves were cached are fetched.
+	TreeCacheHit    = "hit"
+	TreeCacheExtend = "extend"
+	TreeCacheMiss   = "miss"
+)
+
+var (
+	// registry contains the politeiad metrics along with the Go runtime
+	// and the process metrics.
+	registry = prometheus.NewRegistry()
+
+	// sizeBuckets are the histogram buckets that are used for sizes in
+	// bytes, from 256 bytes to 64 MiB.
+	sizeBuckets = prometheus.ExponentialBuckets(256, 4, 10)
+
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the v2 API requests by route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"route", "code"},
+	)
+
+	pluginCmdDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "plugin",
+			Name:      "command_duration_seconds",
+			Help:      "Duration of the plugin commands by plugin, command, and type.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"plugin", "command", "type"},
+	)
+
+	pluginCmdErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "plugin",
+			Name:      "command_errors_total",
+			Help:      "Number of failed plugin commands by plugin, command, type, and error kind.",
+		},
+		[]string{"plugin", "command", "type", "error"},
+	)
+
+	pluginReadsBatchSize = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "plugin",
+			Name:      "reads_batch_size",
+			Help:      "Number of commands in the plugin reads batches.",
+			Buckets:   []float64{1, 2, 5, 10, 20, 50, 100, 200, 500},
+		},
+	)
+
+	tlogAppendDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "tlog",
+			Name:      "leaves_append_duration_seconds",
+			Help:      "Duration of the tlog leaf appends.",
+			Buckets:   prometheus.DefBuckets,
+		},
+	)
+
+	blobKVDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "blobkv",
+			Name:      "duration_seconds",
+			Help:      "Duration of the blob key-value store operations.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"op"},
+	)
+
+	blobKVSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "blobkv",
+			Name:      "size_bytes",
+			Help:      "Total size of the blobs of the blob key-value store operations.",
+			Buckets:   sizeBuckets,
+		},
+		[]string{"op"},
+	)
+
+	treeCacheReads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "treecache",
+			Name:      "reads_total",
+			Help:      "Number of tree cache reads by cache and result.",
+		},
+		[]string{"cache", "result"},
+	)
+
+	treeCacheEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "treecache",
+			Name:      "evictions_total",
+			Help:      "Number of trees that were evicted from the tree cache by cache.",
+		},
+		[]string{"cache"},
+	)
+
+	treeCacheTrees = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "treecache",
+			Name:      "trees",
+			Help:      "Number of trees in the tree cache by cache.",
+		},
+		[]string{"cache"},
+	)
+
+	treeCacheBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "treecache",
+			Name:      "size_bytes",
+			Help:      "Approximate memory used by the tree cache by cache.",
+		},
+		[]string{"cache"},
+	)
+
+	inventoryRecords = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "inventory",
+			Name:      "records",
+			Help:      "Number of records in the inventory by state and status.",
+		},
+		[]string{"state", "status"},
+	)
+
+	anchorLag = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "anchor",
+			Name:      "lag_seconds",
+			Help:      "Age of the oldest unanchored leaf.",
+		},
+	)
+
+	anchorUnanchored = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "anchor",
+			Name:      "unanchored_leaves",
+			Help:      "Number of leaves that have not been anchored.",
+		},
+	)
+
+	anchorLast = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "anchor",
+			Name:      "last_timestamp_seconds",
+			Help:      "Unix timestamp of the most recent anchor.",
+		},
+	)
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestDuration,
+		pluginCmdDuration,
+		pluginCmdErrors,
+		pluginReadsBatchSize,
+		tlogAppendDuration,
+		blobKVDuration,
+		blobKVSize,
+		treeCacheReads,
+		treeCacheEvictions,
+		treeCacheTrees,
+		treeCacheBytes,
+		inventoryRecords,
+		anchorLag,
+		anchorUnanchored,
+		anchorLast,
+	)
+}
+
+// Handler returns the HTTP handler that serves the metrics.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// statusWriter wraps a http ResponseWriter in order to record the status code
+// of the reply.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before writing it.
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush flushes the underlying ResponseWriter if it supports flushing. It is
+// required by the routes that stream their replies.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// InstrumentRoute wraps the handler of a route in order to record the request
+// duration of the route.
+func InstrumentRoute(route string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+		fn(sw, r)
+		requestDuration.WithLabelValues(route, strconv.Itoa(sw.status)).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// PluginCmd records the duration of a plugin command that started at the
+// provided time. The error kind is empty if the command succeeded.
+func PluginCmd(pluginID, cmd, cmdType, errKind string, start time.Time) {
+	pluginCmdDuration.WithLabelValues(pluginID, cmd, cmdType).
+		Observe(time.Since(start).Seconds())
+	if errKind != "" {
+		pluginCmdErrors.WithLabelValues(pluginID, cmd, cmdType, errKind).Inc()
+	}
+}
+
+// PluginReadsBatch records the number of commands of a plugin reads batch.
+func PluginReadsBatch(size int) {
+	pluginReadsBatchSize.Observe(float64(size))
+}
+
+// TlogAppend records the duration of a tlog leaf append that started at the
+// provided time.
+func TlogAppend(start time.Time) {
+	tlogAppendDuration.Observe(time.Since(start).Seconds())
+}
+
+// BlobKV records the duration of a blob key-value store operation that
+// started at the provided time and the total size of its blobs.
+func BlobKV(op string, size int, start time.Time) {
+	blobKVDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+	blobKVSize.WithLabelValues(op).Observe(float64(size))
+}
+
+// TreeCacheRead records the result of a tree cache read.
+func TreeCacheRead(cache, result string) {
+	treeCacheReads.WithLabelValues(cache, result).Inc()
+}
+
+// TreeCacheSize sets the number of trees and the approximate memory used by a
+// tree cache and adds the provided number of evictions.
+func TreeCacheSize(cache string, trees int, bytes int64, evictions uint64) {
+	treeCacheTrees.WithLabelValues(cache).Set(float64(trees))
+	treeCacheBytes.WithLabelValues(cache).Set(float64(bytes))
+	treeCacheEvictions.WithLabelValues(cache).Add(float64(evictions))
+}
+
+// Inventory sets the number of records in the inventory for a state and a
+// status.
+func Inventory(state, status string, records int) {
+	inventoryRecords.WithLabelValues(state, status).Set(float64(records))
+}
+
+// Anchor sets the anchor metrics. The lag is in seconds and the last anchor
+// is a Unix timestamp.
+func Anchor(lag int64, unanchored uint64, lastAnchor int64) {
+	anchorLag.Set(float64(lag))
+	anchorUnanchored.Set(float64(unanchored))
+	anchorLast.Set(float64(lastAnchor))
+}
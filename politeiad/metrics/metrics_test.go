@@ -0,0 +1,59 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	// Record a request to an instrumented route
+	fn := InstrumentRoute("/v2/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	fn(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	// Record the remaining metrics
+	PluginCmd("comments", "new", PluginCmdWrite, PluginErrPlugin, time.Now())
+	PluginReadsBatch(3)
+	TlogAppend(time.Now())
+	BlobKV(BlobKVPut, 1024, time.Now())
+	TreeCacheRead(TreeCacheLeaves, TreeCacheExtend)
+	TreeCacheSize(TreeCacheIndexes, 3, 2048, 2)
+	Inventory("vetted", "public", 7)
+	Anchor(60, 5, 1650000000)
+
+	// Scrape the metrics
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got code %v, want %v", w.Code, http.StatusOK)
+	}
+	body := w.Body.String()
+	want := []string{
+		`politeiad_http_request_duration_seconds_count{code="400",route="/v2/test"} 1`,
+		`politeiad_plugin_command_errors_total{command="new",error="plugin",plugin="comments",type="write"} 1`,
+		`politeiad_plugin_reads_batch_size_sum 3`,
+		`politeiad_tlog_leaves_append_duration_seconds_count 1`,
+		`politeiad_blobkv_size_bytes_sum{op="put"} 1024`,
+		`politeiad_treecache_reads_total{cache="leaves",result="extend"} 1`,
+		`politeiad_treecache_trees{cache="indexes"} 3`,
+		`politeiad_treecache_size_bytes{cache="indexes"} 2048`,
+		`politeiad_treecache_evictions_total{cache="indexes"} 2`,
+		`politeiad_inventory_records{state="vetted",status="public"} 7`,
+		`politeiad_anchor_lag_seconds 60`,
+		`politeiad_anchor_unanchored_leaves 5`,
+		`politeiad_anchor_last_timestamp_seconds 1.65e+09`,
+	}
+	for _, v := range want {
+		if !strings.Contains(body, v) {
+			t.Errorf("metric not found: %v", v)
+		}
+	}
+}
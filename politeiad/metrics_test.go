@@ -0,0 +1,89 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/decred/slog"
+)
+
+func TestMetricsAuth(t *testing.T) {
+	// The log rotator is not initialized during tests
+	log = slog.Disabled
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	var tests = []struct {
+		name     string
+		cfgUser  string
+		cfgPass  string
+		user     string
+		pass     string
+		wantCode int
+	}{
+		{"no credentials configured", "", "", "", "", http.StatusOK},
+		{"no credentials provided", "user", "pass", "", "", http.StatusUnauthorized},
+		{"wrong password", "user", "pass", "user", "wrong", http.StatusUnauthorized},
+		{"wrong user", "user", "pass", "wrong", "pass", http.StatusUnauthorized},
+		{"valid credentials", "user", "pass", "user", "pass", http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := politeia{
+				cfg: &config{
+					MetricsUser: tc.cfgUser,
+					MetricsPass: tc.cfgPass,
+				},
+			}
+			r := httptest.NewRequest(http.MethodGet, metricsRoute, nil)
+			if tc.user != "" {
+				r.SetBasicAuth(tc.user, tc.pass)
+			}
+			w := httptest.NewRecorder()
+			p.metricsAuth(h).ServeHTTP(w, r)
+			if w.Code != tc.wantCode {
+				t.Fatalf("got code %v, want %v", w.Code, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestMetricsListenAddr(t *testing.T) {
+	var tests = []struct {
+		addr     string
+		want     string
+		loopback bool
+	}{
+		{":9374", "localhost:9374", true},
+		{"127.0.0.1:9374", "127.0.0.1:9374", true},
+		{"[::1]:9374", "[::1]:9374", true},
+		{"0.0.0.0:9374", "0.0.0.0:9374", false},
+		{"10.0.0.1:9374", "10.0.0.1:9374", false},
+	}
+	for _, tc := range tests {
+		addr, err := metricsListenAddr(tc.addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr != tc.want {
+			t.Errorf("%v: got %v, want %v", tc.addr, addr, tc.want)
+		}
+		if isLoopbackAddr(addr) != tc.loopback {
+			t.Errorf("%v: got loopback %v, want %v",
+				tc.addr, !tc.loopback, tc.loopback)
+		}
+	}
+
+	// An address without a port is invalid
+	_, err := metricsListenAddr("9374")
+	if err == nil {
+		t.Fatalf("got nil error, want error")
+	}
+}
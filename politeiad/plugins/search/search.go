@@ -0,0 +1,100 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package search provides a politeiad plugin that indexes the text content of
+// records and provides an API for full-text searches of the indexed records.
+package search
+
+const (
+	// PluginID is the unique identifier for this plugin.
+	PluginID = "search"
+
+	// CmdSearch command returns the records that match a search query.
+	CmdSearch = "search"
+)
+
+// Plugin setting keys can be used to specify custom plugin settings. Default
+// plugin setting values can be overridden by providing a plugin setting key
+// and value to the plugin on startup.
+const (
+	// SettingKeyPageSize is the plugin setting key for the
+	// SettingPageSize plugin setting.
+	SettingKeyPageSize = "pagesize"
+)
+
+// Plugin setting default values. These can be overridden by providing a plugin
+// setting key and value to the plugin on startup.
+const (
+	// SettingPageSize is the default number of search results that are
+	// returned in a single SearchReply.
+	SettingPageSize uint32 = 20
+)
+
+// ErrorCodeT represents a plugin error that was caused by the user.
+type ErrorCodeT uint32
+
+const (
+	// ErrorCodeInvalid is an invalid error code.
+	ErrorCodeInvalid ErrorCodeT = 0
+
+	// ErrorCodeQueryInvalid is returned when a search query does not
+	// contain any searchable terms.
+	ErrorCodeQueryInvalid ErrorCodeT = 1
+
+	// ErrorCodeDateRangeInvalid is returned when the end of the date
+	// range filter is before the start of the date range.
+	ErrorCodeDateRangeInvalid ErrorCodeT = 2
+
+	// ErrorCodeLast unit test only.
+	ErrorCodeLast ErrorCodeT = 3
+)
+
+var (
+	// ErrorCodes contains the human readable errors.
+	ErrorCodes = map[ErrorCodeT]string{
+		ErrorCodeInvalid:          "error code invalid",
+		ErrorCodeQueryInvalid:     "query invalid",
+		ErrorCodeDateRangeInvalid: "date range invalid",
+	}
+)
+
+// Search requests the records that match the provided search query. The
+// record name, the record index file, and the author user ID are searched.
+//
+// Unvetted and vetted records are indexed separately. Only vetted records are
+// searched unless the Unvetted field is set. Records that have been censored
+// are removed from the index.
+//
+// The Status, UserID, After, and Before fields are optional filters. Status
+// is the record status. After and Before are Unix timestamps that are compared
+// against the timestamp of the most recent record update and are inclusive.
+//
+// Page starts at 1. A page of 0 is treated as page 1.
+type Search struct {
+	Query    string `json:"query"`
+	Unvetted bool   `json:"unvetted,omitempty"`
+	Status   uint32 `json:"status,omitempty"`
+	UserID   string `json:"userid,omitempty"`
+	After    int64  `json:"after,omitempty"`
+	Before   int64  `json:"before,omitempty"`
+	Page     uint32 `json:"page,omitempty"`
+}
+
+// Result is a single search result. The Score is the relevance of the record
+// to the search query. Results are ordered by score from highest to lowest.
+type Result struct {
+	Token     string  `json:"token"`
+	Name      string  `json:"name"`
+	UserID    string  `json:"userid"`
+	Status    uint32  `json:"status"`
+	Timestamp int64   `json:"timestamp"`
+	Score     float64 `json:"score"`
+}
+
+// SearchReply is the reply to the Search command. Total is the total number of
+// records that matched the search, across all pages.
+type SearchReply struct {
+	Results []Result `json:"results"`
+	Total   uint32   `json:"total"`
+}
@@ -707,6 +707,17 @@ type Proof struct {
 	ExtraData  string   `json:"extradata"` // JSON encoded
 }
 
+// TimestampAnchor contains the proof that the log merkle root of a timestamp
+// was anchored by an anchor provider. The TxID is the DCR transaction ID for
+// dcrtime anchors. The other providers use it for their own reference of the
+// anchor.
+type TimestampAnchor struct {
+	Provider   string `json:"provider"`
+	TxID       string `json:"txid"`
+	MerkleRoot string `json:"merkleroot"`
+	Proof      Proof  `json:"proof"`
+}
+
 // Timestamp contains all of the data required to verify that a piece of data
 // was timestamped onto the decred blockchain.
 //
@@ -716,12 +727,17 @@ type Proof struct {
 // TxID, MerkleRoot, and Proofs will only be populated once the merkle root has
 // been included in a DCR tx and the tx has 6 confirmations. The Data field
 // will not be populated if the data has been censored.
+//
+// The log merkle root can be anchored by multiple anchor providers. Anchors
+// contains the anchors of all providers. TxID, MerkleRoot, and the last proof
+// correspond to the first anchor.
 type Timestamp struct {
-	Data       string  `json:"data"` // JSON encoded
-	Digest     string  `json:"digest"`
-	TxID       string  `json:"txid"`
-	MerkleRoot string  `json:"merkleroot"`
-	Proofs     []Proof `json:"proofs"`
+	Data       string            `json:"data"` // JSON encoded
+	Digest     string            `json:"digest"`
+	TxID       string            `json:"txid"`
+	MerkleRoot string            `json:"merkleroot"`
+	Proofs     []Proof           `json:"proofs"`
+	Anchors    []TimestampAnchor `json:"anchors"`
 }
 
 // Timestamps requests the timestamps for a ticket vote.
@@ -124,7 +124,8 @@ type AuthorReply struct {
 }
 
 // UserRecords retrieves the tokens of all records that were submitted by the
-// provided user ID. The returned tokens are sorted from newest to oldest.
+// provided user ID. The returned tokens are sorted from newest to oldest. The
+// tokens of the drafts of the user are also returned.
 type UserRecords struct {
 	UserID string `json:"userid"`
 }
@@ -133,4 +134,5 @@ type UserRecords struct {
 type UserRecordsReply struct {
 	Unvetted []string `json:"unvetted"`
 	Vetted   []string `json:"vetted"`
+	Drafts   []string `json:"drafts"`
 }
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"crypto/elliptic"
 	"crypto/x509"
 	"encoding/json"
@@ -26,7 +27,9 @@ import (
 	"github.com/decred/politeia/politeiad/backend"
 	"github.com/decred/politeia/politeiad/backend/gitbe"
 	"github.com/decred/politeia/politeiad/backendv2"
-	"github.com/decred/politeia/politeiad/backendv2/tstorebe"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/anchors"
+	"github.com/decred/politeia/politeiad/backendv2/tstorebe/coldstore"
+	"github.com/decred/politeia/politeiad/metrics"
 	"github.com/decred/politeia/util"
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
@@ -37,6 +40,11 @@ type permission uint
 const (
 	permissionPublic permission = iota
 	permissionAuth
+
+	// permissionKey is used for the routes that are public unless API
+	// keys have been configured, in which case they require an API key
+	// that is allowed to access the route.
+	permissionKey
 )
 
 // politeia application context.
@@ -46,6 +54,21 @@ type politeia struct {
 	cfg       *config
 	router    *mux.Router
 	identity  *identity.FullIdentity
+	fsck      fsckJob
+	reencrypt reencryptJob
+	reencode  reencodeJob
+
+	// identityHistory contains the cross-signed transitions of the
+	// identity, ordered from oldest to newest.
+	identityHistory []v2.IdentityTransition
+
+	// apiKeys is only set when an API keys file has been configured.
+	// The API keys replace the RPC user and password.
+	apiKeys *apiKeys
+
+	// health serves the liveness and readiness routes and keeps the
+	// API routes unavailable until the backend has been setup.
+	health *util.Health
 }
 
 func remoteAddr(r *http.Request) string {
@@ -107,6 +130,9 @@ func (p *politeia) check(user, pass string) bool {
 }
 
 func (p *politeia) auth(fn http.HandlerFunc) http.HandlerFunc {
+	if p.apiKeys != nil {
+		return p.authAPIKey(fn)
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		user, pass, ok := r.BasicAuth()
 		if !ok || !p.check(user, pass) {
@@ -124,16 +150,52 @@ func (p *politeia) auth(fn http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// authAPIKey authenticates the API key of the request and verifies that the
+// key is allowed to access the route. The key is added to the request context
+// so that the handlers can verify the plugin commands that the key is allowed
+// to execute.
+func (p *politeia) authAPIKey(fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		k, ok := p.apiKeys.authenticate(id, secret)
+		if !ok {
+			log.Infof("%v Unauthorized access for: %v",
+				remoteAddr(r), id)
+			w.Header().Set("WWW-Authenticate",
+				`Basic realm="Politeiad"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			p.respondWithUserError(w, v1.ErrorStatusInvalidRPCCredentials, nil)
+			return
+		}
+		if !k.routeAllowed(r.URL.Path) {
+			log.Infof("%v Forbidden access for: %v %v",
+				remoteAddr(r), k.ID, r.URL.Path)
+			util.RespondWithJSON(w, http.StatusForbidden,
+				v2.UserErrorReply{
+					ErrorCode: v2.ErrorCodePermissionDenied,
+				})
+			return
+		}
+		log.Debugf("%v Authorized access for: %v %v",
+			remoteAddr(r), k.ID, r.URL.Path)
+		ctx := context.WithValue(r.Context(), apiKeyContextKey{}, k)
+		fn(w, r.WithContext(ctx))
+	}
+}
+
 func (p *politeia) addRoute(method string, route string, handler http.HandlerFunc, perm permission) {
-	if perm == permissionAuth {
+	switch {
+	case perm == permissionAuth:
 		handler = p.auth(handler)
+	case perm == permissionKey && p.apiKeys != nil:
+		handler = p.authAPIKey(handler)
 	}
 	p.router.StrictSlash(true).HandleFunc(route, handler).Methods(method)
 }
 
 func (p *politeia) addRouteV2(method string, route string, handler http.HandlerFunc, perm permission) {
 	route = v2.APIRoute + route
-	p.addRoute(method, route, handler, perm)
+	p.addRoute(method, route, metrics.InstrumentRoute(route, handler), perm)
 }
 
 func (p *politeia) setupBackendGit(anp *chaincfg.Params) error {
@@ -268,16 +330,79 @@ func parsePluginSetting(setting string) (string, *backendv2.PluginSetting, error
 	}, nil
 }
 
-func (p *politeia) setupBackendTstore(anp *chaincfg.Params) error {
+// anchorProviders returns the anchor providers that are enabled in the config.
+// The trusted TSA roots and the bitcoin header source are setup as well since
+// they are required to verify the RFC 3161 and OpenTimestamps proofs.
+func (p *politeia) anchorProviders() ([]anchors.Provider, error) {
+	if p.cfg.TSARoots != "" {
+		roots, err := backendv2.LoadTSARoots(p.cfg.TSARoots)
+		if err != nil {
+			return nil, fmt.Errorf("load tsa roots: %v", err)
+		}
+		backendv2.SetTSARoots(roots)
+	}
+	if p.cfg.BTCHeaders != "" {
+		c, err := util.NewHTTPClient(false, "")
+		if err != nil {
+			return nil, err
+		}
+		backendv2.SetBitcoinHeaderSource(
+			backendv2.NewEsploraHeaderSource(p.cfg.BTCHeaders, c))
+	}
+
+	providers := make([]anchors.Provider, 0, len(p.cfg.Anchors))
+	for _, v := range p.cfg.Anchors {
+		var (
+			ap  anchors.Provider
+			err error
+		)
+		switch v {
+		case anchors.ProviderDcrtime:
+			ap, err = anchors.NewDcrtime(p.cfg.DcrtimeHost, p.cfg.DcrtimeCert)
+		case anchors.ProviderRFC3161:
+			ap, err = anchors.NewRFC3161(p.cfg.TSAHost, p.cfg.TSACert)
+		case anchors.ProviderOpenTimestamps:
+			ap, err = anchors.NewOpenTimestamps(p.cfg.OTSCalendar)
+		default:
+			err = fmt.Errorf("invalid anchor provider '%v'", v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("new %v anchor provider: %v", v, err)
+		}
+		providers = append(providers, ap)
+	}
+	return providers, nil
+}
+
+// coldStore returns the cold store that is enabled in the config. Nil is
+// returned if archival is disabled.
+func (p *politeia) coldStore() (coldstore.Store, error) {
+	switch p.cfg.ColdStore {
+	case "":
+		return nil, nil
+	case coldstore.TypeFS:
+		return coldstore.NewFS(p.cfg.ColdStoreDir)
+	case coldstore.TypeS3:
+		return coldstore.NewS3(p.cfg.S3Endpoint, p.cfg.S3Bucket,
+			p.cfg.S3Region, p.cfg.S3AccessKey, p.cfg.S3SecretKey, p.cfg.S3Cert)
+	}
+	return nil, fmt.Errorf("invalid cold store '%v'", p.cfg.ColdStore)
+}
+
+// setupBackendTstore sets up the tstore backend and its routes. The backend
+// setup is retried until its dependencies are reachable. The backend is not
+// set if a signal is received while waiting.
+func (p *politeia) setupBackendTstore(anp *chaincfg.Params, sigs <-chan os.Signal) error {
 	if p.router == nil {
 		return errors.Errorf("router must be initialized")
 	}
 
-	b, err := tstorebe.New(p.cfg.HomeDir, p.cfg.DataDir,
-		anp, p.cfg.TlogHost, p.cfg.DBHost, p.cfg.DBPass,
-		p.cfg.DcrtimeHost, p.cfg.DcrtimeCert)
+	b, err := p.newBackendTstore(anp, sigs)
 	if err != nil {
-		return fmt.Errorf("new tstorebe: %v", err)
+		return err
+	}
+	if b == nil {
+		return nil
 	}
 	p.backendv2 = b
 
@@ -288,32 +413,79 @@ func (p *politeia) setupBackendTstore(anp *chaincfg.Params) error {
 	p.addRoute(http.MethodPost, v1.IdentityRoute,
 		p.getIdentity, permissionPublic)
 
-	// Setup v2 routes
+	// Setup v2 routes. The write routes are wrapped by the audit
+	// handler so that every write is appended onto the audit log.
 	p.addRouteV2(http.MethodPost, v2.RouteRecordNew,
-		p.handleRecordNew, permissionPublic)
+		p.audit(p.handleRecordNew), permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RouteRecordEdit,
-		p.handleRecordEdit, permissionPublic)
+		p.audit(p.handleRecordEdit), permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RouteRecordEditMetadata,
-		p.handleRecordEditMetadata, permissionPublic)
+		p.audit(p.handleRecordEditMetadata), permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RouteRecordSetStatus,
-		p.handleRecordSetStatus, permissionPublic)
+		p.audit(p.handleRecordSetStatus), permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteRecordCensorFiles,
+		p.audit(p.handleRecordCensorFiles), permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RouteRecords,
-		p.handleRecords, permissionPublic)
+		p.handleRecords, permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RouteRecordTimestamps,
-		p.handleRecordTimestamps, permissionPublic)
+		p.handleRecordTimestamps, permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteRecordDiff,
+		p.handleRecordDiff, permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteRecordHistory,
+		p.handleRecordHistory, permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RouteInventory,
-		p.handleInventory, permissionPublic)
+		p.handleInventory, permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RouteInventoryOrdered,
-		p.handleInventoryOrdered, permissionPublic)
+		p.handleInventoryOrdered, permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RoutePluginWrite,
-		p.handlePluginWrite, permissionPublic)
+		p.audit(p.handlePluginWrite), permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RoutePluginReads,
-		p.handlePluginReads, permissionPublic)
+		p.handlePluginReads, permissionKey)
 	p.addRouteV2(http.MethodPost, v2.RoutePluginInventory,
-		p.handlePluginInventory, permissionPublic)
+		p.handlePluginInventory, permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteTransaction,
+		p.audit(p.handleTransaction), permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteDraftNew,
+		p.audit(p.handleDraftNew), permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteDraftEdit,
+		p.audit(p.handleDraftEdit), permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteDraftDel,
+		p.audit(p.handleDraftDel), permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteDraft,
+		p.handleDraft, permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteDraftPromote,
+		p.audit(p.handleDraftPromote), permissionKey)
+	p.addRouteV2(http.MethodGet, v2.RouteChanges,
+		p.handleChanges, permissionKey)
+	p.addRouteV2(http.MethodPost, v2.RouteSnapshot,
+		p.handleSnapshot, permissionPublic)
+	p.addRouteV2(http.MethodPost, v2.RouteSnapshotProof,
+		p.handleSnapshotProof, permissionPublic)
+	p.addRouteV2(http.MethodPost, v2.RouteIdentityHistory,
+		p.handleIdentityHistory, permissionPublic)
 
 	p.addRouteV2(http.MethodPost, v2.RoutePluginInventory,
-		p.handlePluginInventory, permissionPublic)
+		p.handlePluginInventory, permissionKey)
+
+	// Setup v2 admin routes
+	p.addRouteV2(http.MethodPost, v2.RouteFsck,
+		p.audit(p.handleFsck), permissionAuth)
+	p.addRouteV2(http.MethodPost, v2.RouteFsckStatus,
+		p.handleFsckStatus, permissionAuth)
+	p.addRouteV2(http.MethodPost, v2.RouteReencrypt,
+		p.audit(p.handleReencrypt), permissionAuth)
+	p.addRouteV2(http.MethodPost, v2.RouteReencryptStatus,
+		p.handleReencryptStatus, permissionAuth)
+	p.addRouteV2(http.MethodPost, v2.RouteReencode,
+		p.audit(p.handleReencode), permissionAuth)
+	p.addRouteV2(http.MethodPost, v2.RouteReencodeStatus,
+		p.handleReencodeStatus, permissionAuth)
+	p.addRouteV2(http.MethodPost, v2.RouteAnchorStatus,
+		p.handleAnchorStatus, permissionAuth)
+	p.addRouteV2(http.MethodPost, v2.RouteAnchorDrop,
+		p.audit(p.handleAnchorDrop), permissionAuth)
+	p.addRouteV2(http.MethodPost, v2.RouteAudit,
+		p.handleAudit, permissionAuth)
 
 	// Setup plugins
 	if len(p.cfg.Plugins) > 0 {
@@ -370,10 +542,14 @@ func (p *politeia) setupBackendTstore(anp *chaincfg.Params) error {
 
 	// Perform filesytem check
 	if p.cfg.Fsck {
-		err = p.backendv2.Fsck()
+		report, err := p.backendv2.Fsck(backendv2.FsckOpts{})
 		if err != nil {
 			return err
 		}
+		for _, v := range report.Issues {
+			log.Infof("Fsck %v %v %v: %v (repaired: %v)",
+				v.Type, v.PluginID, v.Token, v.Description, v.Repaired)
+		}
 	}
 
 	return nil
@@ -449,12 +625,28 @@ func _main() error {
 		router: router,
 	}
 
-	// Load identity.
-	p.identity, err = identity.LoadFullIdentity(cfg.Identity)
-	if err != nil {
-		return err
+	// Load identity. The identity is rotated before it is used when
+	// requested.
+	if cfg.Rotate {
+		p.identity, p.identityHistory, err = rotateIdentity(cfg.Identity,
+			cfg.History)
+		if err != nil {
+			return fmt.Errorf("rotate identity: %v", err)
+		}
+		log.Infof("Identity rotated")
+	} else {
+		p.identity, err = identity.LoadFullIdentity(cfg.Identity)
+		if err != nil {
+			return err
+		}
+		p.identityHistory, err = loadIdentityHistory(cfg.History,
+			&p.identity.Public)
+		if err != nil {
+			return fmt.Errorf("load identity history: %v", err)
+		}
 	}
 	log.Infof("Public key: %x", p.identity.Public.Key)
+	log.Infof("Identity transitions: %v", len(p.identityHistory))
 
 	// Load certs, if there.  If they aren't there assume OS is used to
 	// resolve cert validity.
@@ -475,30 +667,33 @@ func _main() error {
 		}
 	}
 
-	// Setup backend
-	log.Infof("Backend: %v", cfg.Backend)
-	switch cfg.Backend {
-	case backendGit:
-		err := p.setupBackendGit(activeNetParams.Params)
-		if err != nil {
-			return err
-		}
-	case backendTstore:
-		err := p.setupBackendTstore(activeNetParams.Params)
+	// Load the API keys. The API keys must be loaded before the routes
+	// are setup.
+	if cfg.APIKeys != "" {
+		p.apiKeys, err = newAPIKeys(cfg.APIKeys)
 		if err != nil {
-			return err
+			return fmt.Errorf("load api keys: %v", err)
 		}
-	default:
-		return fmt.Errorf("invalid backend selected: %v", cfg.Backend)
+		log.Infof("API keys enabled; rpcuser and rpcpass are not used")
+	}
+
+	// Setup the readiness checks
+	p.health = util.NewHealth()
+	err = p.setupHealth()
+	if err != nil {
+		return fmt.Errorf("setup health: %v", err)
 	}
 
-	// Bind to a port and pass our router in
+	// Bind to a port and pass our router in. The listeners are started
+	// before the backend is setup so that the liveness and readiness
+	// routes are served while the backend dependencies are unreachable.
+	// All other routes return a 503 until the backend has been setup.
 	listenC := make(chan error)
 	for _, listener := range cfg.Listeners {
 		listen := listener
 		go func() {
 			s := &http.Server{
-				Handler:      p.router,
+				Handler:      p.health.Handler(p.router),
 				Addr:         listen,
 				ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,
 				WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Second,
@@ -509,15 +704,68 @@ func _main() error {
 		}()
 	}
 
-	// Tell user we are ready to go.
-	log.Infof("Start of day")
+	// Serve the metrics and the health checks on separate listeners.
+	if cfg.MetricsListen != "" {
+		go func() {
+			listenC <- p.metricsListen()
+		}()
+	}
+	if cfg.HealthListen != "" {
+		go func() {
+			listenC <- p.healthListen()
+		}()
+	}
 
 	// Setup OS signals
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGINT)
+
+	// Setup backend
+	log.Infof("Backend: %v", cfg.Backend)
+	switch cfg.Backend {
+	case backendGit:
+		err := p.setupBackendGit(activeNetParams.Params)
+		if err != nil {
+			return err
+		}
+	case backendTstore:
+		err := p.setupBackendTstore(activeNetParams.Params, sigs)
+		if err != nil {
+			return err
+		}
+		if p.backendv2 == nil {
+			// A signal was received during setup
+			return nil
+		}
+	default:
+		return fmt.Errorf("invalid backend selected: %v", cfg.Backend)
+	}
+
+	// The backend metrics are polled since they are too expensive to
+	// collect on every scrape.
+	done := make(chan struct{})
+	if cfg.MetricsListen != "" && p.backendv2 != nil {
+		go p.metricsPoll(done)
+	}
+
+	// Tell user we are ready to go.
+	p.health.SetStarted()
+	log.Infof("Start of day")
+
+	// The API keys are reloaded on SIGHUP so that keys can be rotated
+	// without a restart.
+	hup := make(chan os.Signal, 1)
+	if p.apiKeys != nil {
+		signal.Notify(hup, syscall.SIGHUP)
+	}
 	for {
 		select {
+		case <-hup:
+			err := p.apiKeys.reload()
+			if err != nil {
+				log.Errorf("Reload api keys: %v", err)
+			}
 		case sig := <-sigs:
 			log.Infof("Terminating with %v", sig)
 			goto done
@@ -527,6 +775,7 @@ func _main() error {
 		}
 	}
 done:
+	close(done)
 	switch p.cfg.Backend {
 	case backendGit:
 		p.backend.Close()
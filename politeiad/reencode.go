@@ -0,0 +1,66 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// reencodeFunc is the same function signature as the backendv2 Reencode
+// function.
+type reencodeFunc func(opts backendv2.ReencodeOpts) (*backendv2.ReencodeReport, error)
+
+// reencodeJob runs a backend blob re-encoding in the background. A re-encoding
+// that is interrupted by a server restart is resumed the next time that it is
+// started.
+type reencodeJob struct {
+	job backgroundJob[backendv2.ReencodeReport]
+}
+
+// start starts a re-encoding in the background. A user error is returned if a
+// re-encoding is already running.
+func (j *reencodeJob) start(fn reencodeFunc) error {
+	return j.job.start("blob re-encoding", v2.ErrorCodeReencodeInProgress,
+		func(progress func(done, total int)) (*backendv2.ReencodeReport, error) {
+			report, err := fn(backendv2.ReencodeOpts{
+				Progress: progress,
+			})
+			if err != nil {
+				return nil, err
+			}
+			log.Infof("Blob re-encoding complete: %v of %v blobs re-encoded "+
+				"using blob version %v", report.Reencoded, report.Inspected,
+				report.Version)
+			return report, nil
+		})
+}
+
+// status returns the status of the most recent re-encoding. The challenge
+// response is not populated.
+func (j *reencodeJob) status() v2.ReencodeStatusReply {
+	s := j.job.status()
+	sr := v2.ReencodeStatusReply{
+		Running:   s.running,
+		Done:      s.done,
+		Total:     s.total,
+		Started:   s.started,
+		Completed: s.completed,
+	}
+	if s.err != nil {
+		sr.Error = s.err.Error()
+	}
+	if s.report != nil {
+		sr.Report = &v2.ReencodeReport{
+			Version:   s.report.Version,
+			Resumed:   s.report.Resumed,
+			Records:   s.report.Records,
+			Inspected: s.report.Inspected,
+			Reencoded: s.report.Reencoded,
+		}
+	}
+
+	return sr
+}
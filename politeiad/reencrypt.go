@@ -0,0 +1,64 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	v2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+)
+
+// reencryptFunc is the same function signature as the backendv2 Reencrypt
+// function.
+type reencryptFunc func(opts backendv2.ReencryptOpts) (*backendv2.ReencryptReport, error)
+
+// reencryptJob runs a backend re-encryption in the background. The backend
+// persists the progress of a re-encryption, so a job that is interrupted by a
+// server restart is resumed the next time that it is started.
+type reencryptJob struct {
+	job backgroundJob[backendv2.ReencryptReport]
+}
+
+// start starts a re-encryption in the background. A user error is returned if
+// a re-encryption is already running.
+func (j *reencryptJob) start(fn reencryptFunc) error {
+	return j.job.start("re-encryption", v2.ErrorCodeReencryptInProgress,
+		func(progress func(done, total int)) (*backendv2.ReencryptReport, error) {
+			report, err := fn(backendv2.ReencryptOpts{
+				Progress: progress,
+			})
+			if err != nil {
+				return nil, err
+			}
+			log.Infof("Re-encryption complete: %v blobs re-encrypted using "+
+				"key version %v", report.Reencrypted, report.KeyVersion)
+			return report, nil
+		})
+}
+
+// status returns the status of the most recent re-encryption. The challenge
+// response is not populated.
+func (j *reencryptJob) status() v2.ReencryptStatusReply {
+	s := j.job.status()
+	sr := v2.ReencryptStatusReply{
+		Running:   s.running,
+		Done:      s.done,
+		Total:     s.total,
+		Started:   s.started,
+		Completed: s.completed,
+	}
+	if s.err != nil {
+		sr.Error = s.err.Error()
+	}
+	if s.report != nil {
+		sr.Report = &v2.ReencryptReport{
+			KeyVersion:  s.report.KeyVersion,
+			Resumed:     s.report.Resumed,
+			Inspected:   s.report.Inspected,
+			Reencrypted: s.report.Reencrypted,
+		}
+	}
+
+	return sr
+}
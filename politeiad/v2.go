@@ -14,6 +14,7 @@ import (
 
 	v2 "github.com/decred/politeia/politeiad/api/v2"
 	"github.com/decred/politeia/politeiad/backendv2"
+	"github.com/decred/politeia/politeiad/metrics"
 	"github.com/decred/politeia/util"
 )
 
@@ -58,6 +59,8 @@ func (p *politeia) handleRecordNew(w http.ResponseWriter, r *http.Request) {
 		Record:   p.convertRecordToV2(*rc),
 	}
 
+	auditSetToken(r.Context(), rc.RecordMetadata.Token)
+
 	log.Infof("%v Record created %v",
 		util.RemoteAddr(r), rc.RecordMetadata.Token)
 
@@ -233,6 +236,59 @@ func (p *politeia) handleRecordSetStatus(w http.ResponseWriter, r *http.Request)
 	util.RespondWithJSON(w, http.StatusOK, rer)
 }
 
+func (p *politeia) handleRecordCensorFiles(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRecordCensorFiles")
+
+	// Decode request
+	var rcf v2.RecordCensorFiles
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rcf); err != nil {
+		respondWithErrorV2(w, r, "handleRecordCensorFiles: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(rcf.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleRecordCensorFiles: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+	token, err := decodeToken(rcf.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleRecordCensorFiles: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
+
+	// Censor record files
+	files := convertCensorFilesToBackend(rcf.Files)
+	cf, err := p.backendv2.RecordCensorFiles(token, files, rcf.Reason)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleRecordCensorFiles: RecordCensorFiles: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	rcfr := v2.RecordCensorFilesReply{
+		Response:      hex.EncodeToString(response[:]),
+		CensoredFiles: convertCensoredFilesToV2(cf),
+	}
+
+	log.Infof("%v Record files censored %v %v", util.RemoteAddr(r),
+		rcf.Token, len(cf))
+
+	util.RespondWithJSON(w, http.StatusOK, rcfr)
+}
+
 func (p *politeia) handleRecords(w http.ResponseWriter, r *http.Request) {
 	log.Tracef("handleRecords")
 
@@ -338,6 +394,108 @@ func (p *politeia) handleRecordTimestamps(w http.ResponseWriter, r *http.Request
 	util.RespondWithJSON(w, http.StatusOK, rtr)
 }
 
+func (p *politeia) handleRecordDiff(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRecordDiff")
+
+	// Decode request
+	var rd v2.RecordDiff
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rd); err != nil {
+		respondWithErrorV2(w, r, "handleRecordDiff: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(rd.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleRecordDiff: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+	token, err := decodeTokenAnyLength(rd.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleRecordDiff: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
+
+	// Get record diff
+	d, err := p.backendv2.RecordDiff(token, rd.FromVersion, rd.ToVersion)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleRecordDiff: RecordDiff: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	rdr := v2.RecordDiffReply{
+		Response:       hex.EncodeToString(response[:]),
+		FromVersion:    d.FromVersion,
+		ToVersion:      d.ToVersion,
+		RecordMetadata: convertRecordMetadataDiffsToV2(d.RecordMetadata),
+		Metadata:       convertMetadataStreamDiffsToV2(d.Metadata),
+		Files:          convertFileDiffsToV2(d.Files),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, rdr)
+}
+
+func (p *politeia) handleRecordHistory(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleRecordHistory")
+
+	// Decode request
+	var rh v2.RecordHistory
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rh); err != nil {
+		respondWithErrorV2(w, r, "handleRecordHistory: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(rh.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleRecordHistory: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+	token, err := decodeTokenAnyLength(rh.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleRecordHistory: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
+
+	// Get record history
+	h, err := p.backendv2.RecordHistory(token)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleRecordHistory: RecordHistory: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	rhr := v2.RecordHistoryReply{
+		Response: hex.EncodeToString(response[:]),
+		History:  convertRecordHistoryToV2(h),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, rhr)
+}
+
 func (p *politeia) handleInventory(w http.ResponseWriter, r *http.Request) {
 	log.Tracef("handleInventory")
 
@@ -390,7 +548,8 @@ func (p *politeia) handleInventory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get inventory
-	inv, err := p.backendv2.Inventory(state, status, pageSize, pageNumber)
+	inv, err := p.backendv2.Inventory(state, status, pageSize, pageNumber,
+		i.Cursor)
 	if err != nil {
 		respondWithErrorV2(w, r,
 			"handleInventory: Inventory: %v", err)
@@ -413,6 +572,7 @@ func (p *politeia) handleInventory(w http.ResponseWriter, r *http.Request) {
 		Response: hex.EncodeToString(response[:]),
 		Unvetted: unvetted,
 		Vetted:   vetted,
+		Cursor:   inv.Cursor,
 	}
 
 	util.RespondWithJSON(w, http.StatusOK, ir)
@@ -454,8 +614,8 @@ func (p *politeia) handleInventoryOrdered(w http.ResponseWriter, r *http.Request
 	}
 
 	// Get inventory
-	tokens, err := p.backendv2.InventoryOrdered(state,
-		v2.InventoryPageSize, i.Page)
+	tokens, cursor, err := p.backendv2.InventoryOrdered(state,
+		v2.InventoryPageSize, i.Page, i.Cursor)
 	if err != nil {
 		respondWithErrorV2(w, r,
 			"handleInventoryOrdered: InventoryOrdered: %v", err)
@@ -466,6 +626,7 @@ func (p *politeia) handleInventoryOrdered(w http.ResponseWriter, r *http.Request
 	ir := v2.InventoryOrderedReply{
 		Response: hex.EncodeToString(response[:]),
 		Tokens:   tokens,
+		Cursor:   cursor,
 	}
 
 	util.RespondWithJSON(w, http.StatusOK, ir)
@@ -502,6 +663,14 @@ func (p *politeia) handlePluginWrite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !pluginAllowed(r.Context(), pw.Cmd.ID, pw.Cmd.Command) {
+		respondWithErrorV2(w, r, "handlePluginWrite: plugin not allowed",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodePermissionDenied,
+			})
+		return
+	}
+
 	// Execute plugin cmd
 	payload, err := p.backendv2.PluginWrite(token, pw.Cmd.ID,
 		pw.Cmd.Command, pw.Cmd.Payload)
@@ -524,186 +693,1096 @@ func (p *politeia) handlePluginWrite(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, pwr)
 }
 
-func (p *politeia) handlePluginReads(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handlePluginReads")
+func (p *politeia) handleTransaction(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleTransaction")
 
 	// Decode request
-	var pr v2.PluginReads
+	var tx v2.Transaction
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&pr); err != nil {
-		respondWithErrorV2(w, r, "handlePluginReads: unmarshal",
+	if err := decoder.Decode(&tx); err != nil {
+		respondWithErrorV2(w, r, "handleTransaction: unmarshal",
 			v2.UserErrorReply{
 				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
 			})
 		return
 	}
-	challenge, err := hex.DecodeString(pr.Challenge)
+	challenge, err := hex.DecodeString(tx.Challenge)
 	if err != nil || len(challenge) != v2.ChallengeSize {
-		respondWithErrorV2(w, r, "handlePluginReads: decode challenge",
+		respondWithErrorV2(w, r, "handleTransaction: decode challenge",
 			v2.UserErrorReply{
 				ErrorCode: v2.ErrorCodeChallengeInvalid,
 			})
 		return
 	}
+	token, err := decodeToken(tx.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleTransaction: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
 
-	// Execute the batch of read cmds
-	batch := newBatch(pr.Cmds)
-	batch.execConcurrently(p.backendv2.PluginRead)
-
-	// Prepare the replies
-	replies := make([]v2.PluginCmdReply, len(pr.Cmds))
-	for k, v := range batch.entries {
-		if v.err == nil {
-			// Command executed successfully
-			replies[k] = v2.PluginCmdReply{
-				Token:   v.cmd.Token,
-				ID:      v.cmd.ID,
-				Command: v.cmd.Command,
-				Payload: v.reply,
-			}
+	for _, v := range tx.Ops {
+		if v.PluginID == "" {
 			continue
 		}
-
-		// An error was encountered. Plugin and user errors
-		// are returned in valid replies. Unexpected errors
-		// cause a 500 and the whole batch to be aborted.
-		var (
-			pluginErr   backendv2.PluginError
-			userErr     v2.UserErrorReply
-			userErrCode = convertErrorToV2(v.err)
-		)
-		switch {
-		case errors.As(v.err, &pluginErr):
-			// A plugin error was returned
-			replies[k] = v2.PluginCmdReply{
-				PluginError: &v2.PluginErrorReply{
-					PluginID:     pluginErr.PluginID,
-					ErrorCode:    pluginErr.ErrorCode,
-					ErrorContext: pluginErr.ErrorContext,
-				},
-			}
-
-		case errors.As(v.err, &userErr):
-			// A user error was returned
-			replies[k] = v2.PluginCmdReply{
-				UserError: &userErr,
-			}
-
-		case userErrCode != v2.ErrorCodeInvalid:
-			// Backend error was returned that was
-			// converted into a valid user error.
-			replies[k] = v2.PluginCmdReply{
-				UserError: &v2.UserErrorReply{
-					ErrorCode: userErrCode,
-				},
-			}
-
-		default:
-			// Internal server error. Log it and return a 500.
-			t := time.Now().Unix()
-			e := fmt.Sprintf("PluginRead %v %v %v: %v",
-				v.cmd.ID, v.cmd.Command, v.cmd.Payload, v.err)
-			log.Errorf("%v %v %v %v Internal error %v: %v",
-				util.RemoteAddr(r), r.Method, r.URL, r.Proto, t, e)
-			log.Errorf("Stacktrace (NOT A REAL CRASH): %s", debug.Stack())
-
-			util.RespondWithJSON(w, http.StatusInternalServerError,
-				v2.ServerErrorReply{
-					ErrorCode: t,
+		if !pluginAllowed(r.Context(), v.PluginID, v.PluginCmd) {
+			respondWithErrorV2(w, r, "handleTransaction: plugin not allowed",
+				v2.UserErrorReply{
+					ErrorCode:    v2.ErrorCodePermissionDenied,
+					ErrorContext: v.PluginID + " " + v.PluginCmd,
 				})
 			return
 		}
 	}
 
+	// Execute transaction
+	txr, err := p.backendv2.Transaction(token, convertTxOpsToBackend(tx.Ops))
+	if err != nil {
+		// The error of the failed operation is returned to the
+		// caller.
+		var te backendv2.TxError
+		if errors.As(err, &te) {
+			log.Infof("%v Transaction %v failed on op %v",
+				util.RemoteAddr(r), tx.Token, te.Index)
+			err = te.Err
+		}
+		respondWithErrorV2(w, r,
+			"handleTransaction: Transaction: %v", err)
+		return
+	}
+
 	// Prepare reply
 	response := p.identity.SignMessage(challenge)
-	prr := v2.PluginReadsReply{
+	tr := v2.TransactionReply{
 		Response: hex.EncodeToString(response[:]),
-		Replies:  replies,
+		Record:   p.convertRecordToV2(txr.Record),
+		Payloads: txr.Payloads,
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, prr)
+	log.Infof("%v Transaction committed %v: %v ops", util.RemoteAddr(r),
+		txr.Record.RecordMetadata.Token, len(tx.Ops))
 
+	util.RespondWithJSON(w, http.StatusOK, tr)
 }
 
-func (p *politeia) handlePluginInventory(w http.ResponseWriter, r *http.Request) {
-	log.Tracef("handlePluginInventory")
+func (p *politeia) handleDraftNew(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDraftNew")
 
 	// Decode request
-	var pi v2.PluginInventory
+	var dn v2.DraftNew
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&pi); err != nil {
-		respondWithErrorV2(w, r, "handlePluginInventory: unmarshal",
+	if err := decoder.Decode(&dn); err != nil {
+		respondWithErrorV2(w, r, "handleDraftNew: unmarshal",
 			v2.UserErrorReply{
 				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
 			})
 		return
 	}
-	challenge, err := hex.DecodeString(pi.Challenge)
+	challenge, err := hex.DecodeString(dn.Challenge)
 	if err != nil || len(challenge) != v2.ChallengeSize {
-		respondWithErrorV2(w, r, "handlePluginInventory: decode challenge",
+		respondWithErrorV2(w, r, "handleDraftNew: decode challenge",
 			v2.UserErrorReply{
 				ErrorCode: v2.ErrorCodeChallengeInvalid,
 			})
 		return
 	}
 
-	// Get plugin inventory
-	plugins := p.backendv2.PluginInventory()
+	// Create new draft
+	var (
+		metadata = convertMetadataStreamsToBackend(dn.Metadata)
+		files    = convertFilesToBackend(dn.Files)
+	)
+	d, err := p.backendv2.DraftNew(metadata, files)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleDraftNew: DraftNew: %v", err)
+		return
+	}
 
 	// Prepare reply
 	response := p.identity.SignMessage(challenge)
-	ir := v2.PluginInventoryReply{
+	dnr := v2.DraftNewReply{
 		Response: hex.EncodeToString(response[:]),
-		Plugins:  convertPluginsToV2(plugins),
+		Record:   p.convertRecordToV2(*d),
 	}
 
-	util.RespondWithJSON(w, http.StatusOK, ir)
+	auditSetToken(r.Context(), d.RecordMetadata.Token)
 
-}
+	log.Infof("%v Draft created %v",
+		util.RemoteAddr(r), d.RecordMetadata.Token)
 
-// decodeToken decodes a v2 token and errors if the token is not the full
-// length token.
-func decodeToken(token string) ([]byte, error) {
-	return util.TokenDecode(util.TokenTypeTstore, token)
+	util.RespondWithJSON(w, http.StatusOK, dnr)
 }
 
-// decodeTokenAnyLength decodes a v2 token. It accepts both the full length
-// token and the short token.
-func decodeTokenAnyLength(token string) ([]byte, error) {
-	return util.TokenDecodeAnyLength(util.TokenTypeTstore, token)
-}
+func (p *politeia) handleDraftEdit(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDraftEdit")
 
-func (p *politeia) convertRecordToV2(r backendv2.Record) v2.Record {
-	var (
-		metadata = convertMetadataStreamsToV2(r.Metadata)
-		files    = convertFilesToV2(r.Files)
-		rm       = r.RecordMetadata
-		sig      = p.identity.SignMessage([]byte(rm.Merkle + rm.Token))
-	)
-	return v2.Record{
-		State:     v2.RecordStateT(rm.State),
-		Status:    v2.RecordStatusT(rm.Status),
-		Version:   rm.Version,
-		Timestamp: rm.Timestamp,
-		Metadata:  metadata,
-		Files:     files,
-		CensorshipRecord: v2.CensorshipRecord{
-			Token:     rm.Token,
-			Merkle:    rm.Merkle,
-			Signature: hex.EncodeToString(sig[:]),
-		},
+	// Decode request
+	var de v2.DraftEdit
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&de); err != nil {
+		respondWithErrorV2(w, r, "handleDraftEdit: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
 	}
-}
-
-func convertMetadataStreamsToBackend(metadata []v2.MetadataStream) []backendv2.MetadataStream {
-	ms := make([]backendv2.MetadataStream, 0, len(metadata))
-	for _, v := range metadata {
-		ms = append(ms, backendv2.MetadataStream{
-			PluginID: v.PluginID,
-			StreamID: v.StreamID,
-			Payload:  v.Payload,
-		})
+	challenge, err := hex.DecodeString(de.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleDraftEdit: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+	token, err := decodeToken(de.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleDraftEdit: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
+
+	// Edit draft
+	var (
+		mdAppend    = convertMetadataStreamsToBackend(de.MDAppend)
+		mdOverwrite = convertMetadataStreamsToBackend(de.MDOverwrite)
+		filesAdd    = convertFilesToBackend(de.FilesAdd)
+	)
+	d, err := p.backendv2.DraftEdit(token, mdAppend,
+		mdOverwrite, filesAdd, de.FilesDel)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleDraftEdit: DraftEdit: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	der := v2.DraftEditReply{
+		Response: hex.EncodeToString(response[:]),
+		Record:   p.convertRecordToV2(*d),
+	}
+
+	log.Infof("%v Draft edited %v",
+		util.RemoteAddr(r), d.RecordMetadata.Token)
+
+	util.RespondWithJSON(w, http.StatusOK, der)
+}
+
+func (p *politeia) handleDraftDel(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDraftDel")
+
+	// Decode request
+	var dd v2.DraftDel
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dd); err != nil {
+		respondWithErrorV2(w, r, "handleDraftDel: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(dd.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleDraftDel: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+	token, err := decodeToken(dd.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleDraftDel: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
+
+	// Delete draft
+	err = p.backendv2.DraftDel(token)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleDraftDel: DraftDel: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	ddr := v2.DraftDelReply{
+		Response: hex.EncodeToString(response[:]),
+	}
+
+	log.Infof("%v Draft deleted %v", util.RemoteAddr(r), dd.Token)
+
+	util.RespondWithJSON(w, http.StatusOK, ddr)
+}
+
+func (p *politeia) handleDraft(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDraft")
+
+	// Decode request
+	var d v2.Draft
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&d); err != nil {
+		respondWithErrorV2(w, r, "handleDraft: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(d.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleDraft: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+	token, err := decodeToken(d.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleDraft: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
+
+	// Get draft
+	dr, err := p.backendv2.Draft(token)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleDraft: Draft: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	reply := v2.DraftReply{
+		Response: hex.EncodeToString(response[:]),
+		Record:   p.convertRecordToV2(*dr),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+func (p *politeia) handleDraftPromote(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleDraftPromote")
+
+	// Decode request
+	var dp v2.DraftPromote
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dp); err != nil {
+		respondWithErrorV2(w, r, "handleDraftPromote: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(dp.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleDraftPromote: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+	token, err := decodeToken(dp.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleDraftPromote: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
+
+	// Promote draft
+	rc, err := p.backendv2.DraftPromote(token)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleDraftPromote: DraftPromote: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	dpr := v2.DraftPromoteReply{
+		Response: hex.EncodeToString(response[:]),
+		Record:   p.convertRecordToV2(*rc),
+	}
+
+	log.Infof("%v Draft %v promoted to record %v",
+		util.RemoteAddr(r), dp.Token, rc.RecordMetadata.Token)
+
+	util.RespondWithJSON(w, http.StatusOK, dpr)
+}
+
+func (p *politeia) handlePluginReads(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handlePluginReads")
+
+	// Decode request
+	var pr v2.PluginReads
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&pr); err != nil {
+		respondWithErrorV2(w, r, "handlePluginReads: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(pr.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handlePluginReads: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	for _, v := range pr.Cmds {
+		if !pluginAllowed(r.Context(), v.ID, v.Command) {
+			respondWithErrorV2(w, r, "handlePluginReads: plugin not allowed",
+				v2.UserErrorReply{
+					ErrorCode:    v2.ErrorCodePermissionDenied,
+					ErrorContext: v.ID + " " + v.Command,
+				})
+			return
+		}
+	}
+
+	// Execute the batch of read cmds
+	metrics.PluginReadsBatch(len(pr.Cmds))
+	batch := newBatch(pr.Cmds)
+	batch.execConcurrently(p.backendv2.PluginRead)
+
+	// Prepare the replies
+	replies := make([]v2.PluginCmdReply, len(pr.Cmds))
+	for k, v := range batch.entries {
+		if v.err == nil {
+			// Command executed successfully
+			replies[k] = v2.PluginCmdReply{
+				Token:   v.cmd.Token,
+				ID:      v.cmd.ID,
+				Command: v.cmd.Command,
+				Payload: v.reply,
+			}
+			continue
+		}
+
+		// An error was encountered. Plugin and user errors
+		// are returned in valid replies. Unexpected errors
+		// cause a 500 and the whole batch to be aborted.
+		var (
+			pluginErr   backendv2.PluginError
+			userErr     v2.UserErrorReply
+			userErrCode = convertErrorToV2(v.err)
+		)
+		switch {
+		case errors.As(v.err, &pluginErr):
+			// A plugin error was returned
+			replies[k] = v2.PluginCmdReply{
+				PluginError: &v2.PluginErrorReply{
+					PluginID:     pluginErr.PluginID,
+					ErrorCode:    pluginErr.ErrorCode,
+					ErrorContext: pluginErr.ErrorContext,
+				},
+			}
+
+		case errors.As(v.err, &userErr):
+			// A user error was returned
+			replies[k] = v2.PluginCmdReply{
+				UserError: &userErr,
+			}
+
+		case userErrCode != v2.ErrorCodeInvalid:
+			// Backend error was returned that was
+			// converted into a valid user error.
+			replies[k] = v2.PluginCmdReply{
+				UserError: &v2.UserErrorReply{
+					ErrorCode: userErrCode,
+				},
+			}
+
+		default:
+			// Internal server error. Log it and return a 500.
+			t := time.Now().Unix()
+			e := fmt.Sprintf("PluginRead %v %v %v: %v",
+				v.cmd.ID, v.cmd.Command, v.cmd.Payload, v.err)
+			log.Errorf("%v %v %v %v Internal error %v: %v",
+				util.RemoteAddr(r), r.Method, r.URL, r.Proto, t, e)
+			log.Errorf("Stacktrace (NOT A REAL CRASH): %s", debug.Stack())
+
+			util.RespondWithJSON(w, http.StatusInternalServerError,
+				v2.ServerErrorReply{
+					ErrorCode: t,
+				})
+			return
+		}
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	prr := v2.PluginReadsReply{
+		Response: hex.EncodeToString(response[:]),
+		Replies:  replies,
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, prr)
+
+}
+
+func (p *politeia) handlePluginInventory(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handlePluginInventory")
+
+	// Decode request
+	var pi v2.PluginInventory
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&pi); err != nil {
+		respondWithErrorV2(w, r, "handlePluginInventory: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(pi.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handlePluginInventory: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Get plugin inventory
+	plugins := p.backendv2.PluginInventory()
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	ir := v2.PluginInventoryReply{
+		Response: hex.EncodeToString(response[:]),
+		Plugins:  convertPluginsToV2(plugins),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, ir)
+
+}
+
+// handleFsck starts a filesystem check of the backend in the background.
+func (p *politeia) handleFsck(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleFsck")
+
+	// Decode request
+	var f v2.Fsck
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&f); err != nil {
+		respondWithErrorV2(w, r, "handleFsck: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(f.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleFsck: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Start the fsck
+	err = p.fsck.start(p.backendv2.Fsck, f.DryRun)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleFsck: start: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	fr := v2.FsckReply{
+		Response: hex.EncodeToString(response[:]),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, fr)
+}
+
+// handleFsckStatus returns the status of the most recent filesystem check.
+func (p *politeia) handleFsckStatus(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleFsckStatus")
+
+	// Decode request
+	var fs v2.FsckStatus
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&fs); err != nil {
+		respondWithErrorV2(w, r, "handleFsckStatus: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(fs.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleFsckStatus: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	fsr := p.fsck.status()
+	fsr.Response = hex.EncodeToString(response[:])
+
+	util.RespondWithJSON(w, http.StatusOK, fsr)
+}
+
+// handleReencrypt rotates the encryption key and starts a re-encryption of
+// the backend data in the background.
+func (p *politeia) handleReencrypt(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleReencrypt")
+
+	// Decode request
+	var re v2.Reencrypt
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&re); err != nil {
+		respondWithErrorV2(w, r, "handleReencrypt: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(re.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleReencrypt: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Start the re-encryption
+	err = p.reencrypt.start(p.backendv2.Reencrypt)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleReencrypt: start: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	rr := v2.ReencryptReply{
+		Response: hex.EncodeToString(response[:]),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, rr)
+}
+
+// handleReencryptStatus returns the status of the most recent re-encryption.
+func (p *politeia) handleReencryptStatus(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleReencryptStatus")
+
+	// Decode request
+	var rs v2.ReencryptStatus
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rs); err != nil {
+		respondWithErrorV2(w, r, "handleReencryptStatus: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(rs.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleReencryptStatus: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	rsr := p.reencrypt.status()
+	rsr.Response = hex.EncodeToString(response[:])
+
+	util.RespondWithJSON(w, http.StatusOK, rsr)
+}
+
+// handleReencode starts a re-encoding of the backend blobs that use a previous
+// blob encoding in the background.
+func (p *politeia) handleReencode(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleReencode")
+
+	// Decode request
+	var re v2.Reencode
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&re); err != nil {
+		respondWithErrorV2(w, r, "handleReencode: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(re.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleReencode: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Start the re-encoding
+	err = p.reencode.start(p.backendv2.Reencode)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleReencode: start: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	rr := v2.ReencodeReply{
+		Response: hex.EncodeToString(response[:]),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, rr)
+}
+
+// handleReencodeStatus returns the status of the most recent re-encoding.
+func (p *politeia) handleReencodeStatus(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleReencodeStatus")
+
+	// Decode request
+	var rs v2.ReencodeStatus
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&rs); err != nil {
+		respondWithErrorV2(w, r, "handleReencodeStatus: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(rs.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleReencodeStatus: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	rsr := p.reencode.status()
+	rsr.Response = hex.EncodeToString(response[:])
+
+	util.RespondWithJSON(w, http.StatusOK, rsr)
+}
+
+// handleAnchorStatus returns the status of the anchoring of the records.
+func (p *politeia) handleAnchorStatus(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAnchorStatus")
+
+	// Decode request
+	var as v2.AnchorStatus
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&as); err != nil {
+		respondWithErrorV2(w, r, "handleAnchorStatus: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(as.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleAnchorStatus: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Get anchor status
+	s, err := p.backendv2.AnchorStatus()
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleAnchorStatus: AnchorStatus: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	asr := convertAnchorStatusToV2(*s)
+	asr.Response = hex.EncodeToString(response[:])
+
+	util.RespondWithJSON(w, http.StatusOK, asr)
+}
+
+// handleAnchorDrop drops an anchor without waiting for the anchor schedule.
+func (p *politeia) handleAnchorDrop(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAnchorDrop")
+
+	// Decode request
+	var ad v2.AnchorDrop
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ad); err != nil {
+		respondWithErrorV2(w, r, "handleAnchorDrop: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(ad.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleAnchorDrop: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Drop anchor
+	err = p.backendv2.AnchorDrop()
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleAnchorDrop: AnchorDrop: %v", err)
+		return
+	}
+
+	log.Infof("%v Anchor drop started", util.RemoteAddr(r))
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	adr := v2.AnchorDropReply{
+		Response: hex.EncodeToString(response[:]),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, adr)
+}
+
+func (p *politeia) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSnapshot")
+
+	// Decode request
+	var s v2.Snapshot
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&s); err != nil {
+		respondWithErrorV2(w, r, "handleSnapshot: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(s.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleSnapshot: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Get snapshot
+	snapshot, ts, err := p.backendv2.Snapshot(s.Sequence)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleSnapshot: Snapshot: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	sr := v2.SnapshotReply{
+		Response:  hex.EncodeToString(response[:]),
+		Snapshot:  convertInventorySnapshotToV2(*snapshot),
+		Timestamp: convertTimestampToV2(*ts),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, sr)
+}
+
+func (p *politeia) handleSnapshotProof(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleSnapshotProof")
+
+	// Decode request
+	var sp v2.SnapshotProof
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&sp); err != nil {
+		respondWithErrorV2(w, r, "handleSnapshotProof: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(sp.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleSnapshotProof: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+	token, err := decodeToken(sp.Token)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleSnapshotProof: decode token",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeTokenInvalid,
+				ErrorContext: util.TokenRegexp(),
+			})
+		return
+	}
+
+	// Get snapshot inclusion proof
+	si, err := p.backendv2.SnapshotProof(token, sp.Sequence)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleSnapshotProof: SnapshotProof: %v", err)
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	spr := v2.SnapshotProofReply{
+		Response:  hex.EncodeToString(response[:]),
+		Inclusion: convertSnapshotInclusionToV2(*si),
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, spr)
+}
+
+func (p *politeia) handleIdentityHistory(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleIdentityHistory")
+
+	// Decode request
+	var ih v2.IdentityHistory
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&ih); err != nil {
+		respondWithErrorV2(w, r, "handleIdentityHistory: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(ih.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleIdentityHistory: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	ihr := v2.IdentityHistoryReply{
+		Response:    hex.EncodeToString(response[:]),
+		PublicKey:   p.identity.Public.String(),
+		Transitions: p.identityHistory,
+	}
+	if k := p.backendv2.TlogPublicKey(); k != nil {
+		ihr.TlogPublicKey = hex.EncodeToString(k)
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, ihr)
+}
+
+func (p *politeia) handleAudit(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleAudit")
+
+	// Decode request
+	var a v2.Audit
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&a); err != nil {
+		respondWithErrorV2(w, r, "handleAudit: unmarshal",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeRequestPayloadInvalid,
+			})
+		return
+	}
+	challenge, err := hex.DecodeString(a.Challenge)
+	if err != nil || len(challenge) != v2.ChallengeSize {
+		respondWithErrorV2(w, r, "handleAudit: decode challenge",
+			v2.UserErrorReply{
+				ErrorCode: v2.ErrorCodeChallengeInvalid,
+			})
+		return
+	}
+
+	// Query the audit log
+	f := backendv2.AuditFilter{
+		After:      a.After,
+		Route:      a.Route,
+		Credential: a.Credential,
+		Token:      a.Token,
+		PluginID:   a.PluginID,
+		Start:      a.Start,
+		End:        a.End,
+	}
+	entries, err := p.backendv2.Audit(f, v2.AuditPageSize)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleAudit: Audit: %v", err)
+		return
+	}
+	var timestamps map[uint64]v2.Timestamp
+	if a.Timestamps && len(entries) > 0 {
+		seqs := make([]uint64, 0, len(entries))
+		for _, v := range entries {
+			seqs = append(seqs, v.Sequence)
+		}
+		ts, err := p.backendv2.AuditTimestamps(seqs)
+		if err != nil {
+			respondWithErrorV2(w, r,
+				"handleAudit: AuditTimestamps: %v", err)
+			return
+		}
+		timestamps = make(map[uint64]v2.Timestamp, len(ts))
+		for k, v := range ts {
+			timestamps[k] = convertTimestampToV2(v)
+		}
+	}
+
+	// Prepare reply
+	response := p.identity.SignMessage(challenge)
+	e := make([]v2.AuditEntry, 0, len(entries))
+	for _, v := range entries {
+		e = append(e, convertAuditEntryToV2(v))
+	}
+	ar := v2.AuditReply{
+		Response:   hex.EncodeToString(response[:]),
+		Entries:    e,
+		Timestamps: timestamps,
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, ar)
+}
+
+func (p *politeia) handleChanges(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("handleChanges")
+
+	// Decode request
+	since, err := changesSince(r)
+	if err != nil {
+		respondWithErrorV2(w, r, "handleChanges: parse since",
+			v2.UserErrorReply{
+				ErrorCode:    v2.ErrorCodeRequestPayloadInvalid,
+				ErrorContext: "invalid sequence number",
+			})
+		return
+	}
+	d := changesDuration(time.Duration(p.cfg.WriteTimeout) * time.Second)
+
+	// Stream the changes
+	if changesWantStream(r) {
+		err = changesStream(r.Context(), w, p.backendv2, since, d)
+		if err != nil {
+			// The reply headers have already been sent so the
+			// error can only be logged.
+			log.Errorf("%v handleChanges: changesStream: %v",
+				util.RemoteAddr(r), err)
+		}
+		return
+	}
+
+	// Wait for the changes
+	c, err := changesWait(r.Context(), p.backendv2, since, d)
+	if err != nil {
+		respondWithErrorV2(w, r,
+			"handleChanges: changesWait: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, v2.ChangesReply{
+		Changes: convertChangesToV2(c),
+	})
+}
+
+// decodeToken decodes a v2 token and errors if the token is not the full
+// length token.
+func decodeToken(token string) ([]byte, error) {
+	return util.TokenDecode(util.TokenTypeTstore, token)
+}
+
+// decodeTokenAnyLength decodes a v2 token. It accepts both the full length
+// token and the short token.
+func decodeTokenAnyLength(token string) ([]byte, error) {
+	return util.TokenDecodeAnyLength(util.TokenTypeTstore, token)
+}
+
+func (p *politeia) convertRecordToV2(r backendv2.Record) v2.Record {
+	var (
+		metadata = convertMetadataStreamsToV2(r.Metadata)
+		files    = convertFilesToV2(r.Files)
+		rm       = r.RecordMetadata
+		sig      = p.identity.SignMessage([]byte(rm.Merkle + rm.Token))
+	)
+	var censored []v2.CensoredFile
+	if len(r.CensoredFiles) > 0 {
+		censored = convertCensoredFilesToV2(r.CensoredFiles)
+	}
+	return v2.Record{
+		State:         v2.RecordStateT(rm.State),
+		Status:        v2.RecordStatusT(rm.Status),
+		Version:       rm.Version,
+		Timestamp:     rm.Timestamp,
+		Metadata:      metadata,
+		Files:         files,
+		CensoredFiles: censored,
+		CensorshipRecord: v2.CensorshipRecord{
+			Token:     rm.Token,
+			Merkle:    rm.Merkle,
+			Signature: hex.EncodeToString(sig[:]),
+		},
+	}
+}
+
+func convertCensoredFilesToV2(files []backendv2.CensoredFile) []v2.CensoredFile {
+	cf := make([]v2.CensoredFile, 0, len(files))
+	for _, v := range files {
+		cf = append(cf, v2.CensoredFile{
+			Version:   v.Version,
+			Name:      v.Name,
+			MIME:      v.MIME,
+			Digest:    v.Digest,
+			Reason:    v.Reason,
+			Timestamp: v.Timestamp,
+			Signature: v.Signature,
+		})
+	}
+	return cf
+}
+
+func convertCensorFilesToBackend(files []v2.CensorFile) []backendv2.CensorFile {
+	cf := make([]backendv2.CensorFile, 0, len(files))
+	for _, v := range files {
+		cf = append(cf, backendv2.CensorFile{
+			Version: v.Version,
+			Name:    v.Name,
+		})
+	}
+	return cf
+}
+
+func convertMetadataStreamsToBackend(metadata []v2.MetadataStream) []backendv2.MetadataStream {
+	ms := make([]backendv2.MetadataStream, 0, len(metadata))
+	for _, v := range metadata {
+		ms = append(ms, backendv2.MetadataStream{
+			PluginID: v.PluginID,
+			StreamID: v.StreamID,
+			Payload:  v.Payload,
+		})
 	}
 	return ms
 }
@@ -765,6 +1844,24 @@ func convertRecordRequestsToBackend(reqs []v2.RecordRequest) []backendv2.RecordR
 	return r
 }
 
+func convertTxOpsToBackend(ops []v2.TxOp) []backendv2.TxOp {
+	b := make([]backendv2.TxOp, 0, len(ops))
+	for _, v := range ops {
+		b = append(b, backendv2.TxOp{
+			Type:        backendv2.TxOpT(v.Type),
+			MDAppend:    convertMetadataStreamsToBackend(v.MDAppend),
+			MDOverwrite: convertMetadataStreamsToBackend(v.MDOverwrite),
+			FilesAdd:    convertFilesToBackend(v.FilesAdd),
+			FilesDel:    v.FilesDel,
+			Status:      backendv2.StatusT(v.Status),
+			PluginID:    v.PluginID,
+			PluginCmd:   v.PluginCmd,
+			Payload:     v.Payload,
+		})
+	}
+	return b
+}
+
 func convertProofToV2(p backendv2.Proof) v2.Proof {
 	return v2.Proof{
 		Type:       p.Type,
@@ -780,12 +1877,22 @@ func convertTimestampToV2(t backendv2.Timestamp) v2.Timestamp {
 	for _, v := range t.Proofs {
 		proofs = append(proofs, convertProofToV2(v))
 	}
+	anchors := make([]v2.TimestampAnchor, 0, len(t.Anchors))
+	for _, v := range t.Anchors {
+		anchors = append(anchors, v2.TimestampAnchor{
+			Provider:   v.Provider,
+			TxID:       v.TxID,
+			MerkleRoot: v.MerkleRoot,
+			Proof:      convertProofToV2(v.Proof),
+		})
+	}
 	return v2.Timestamp{
 		Data:       t.Data,
 		Digest:     t.Digest,
 		TxID:       t.TxID,
 		MerkleRoot: t.MerkleRoot,
 		Proofs:     proofs,
+		Anchors:    anchors,
 	}
 }
 
@@ -812,12 +1919,76 @@ func convertFileTimestampsToV2(files map[string]backendv2.Timestamp) map[string]
 	return fs
 }
 
+func convertRecordMetadataDiffsToV2(diffs []backendv2.RecordMetadataDiff) []v2.RecordMetadataDiff {
+	d := make([]v2.RecordMetadataDiff, 0, len(diffs))
+	for _, v := range diffs {
+		d = append(d, v2.RecordMetadataDiff{
+			Field: v.Field,
+			From:  v.From,
+			To:    v.To,
+		})
+	}
+	return d
+}
+
+func convertMetadataStreamDiffsToV2(diffs []backendv2.MetadataStreamDiff) []v2.MetadataStreamDiff {
+	d := make([]v2.MetadataStreamDiff, 0, len(diffs))
+	for _, v := range diffs {
+		d = append(d, v2.MetadataStreamDiff{
+			Action:      v2.DiffActionT(v.Action),
+			PluginID:    v.PluginID,
+			StreamID:    v.StreamID,
+			FromPayload: v.FromPayload,
+			ToPayload:   v.ToPayload,
+		})
+	}
+	return d
+}
+
+func convertFileDiffsToV2(diffs []backendv2.FileDiff) []v2.FileDiff {
+	d := make([]v2.FileDiff, 0, len(diffs))
+	for _, v := range diffs {
+		d = append(d, v2.FileDiff{
+			Action:     v2.DiffActionT(v.Action),
+			Name:       v.Name,
+			FromMIME:   v.FromMIME,
+			ToMIME:     v.ToMIME,
+			FromDigest: v.FromDigest,
+			ToDigest:   v.ToDigest,
+			Censored:   v.Censored,
+			LineDiff:   v.LineDiff,
+		})
+	}
+	return d
+}
+
+func convertRecordHistoryToV2(entries []backendv2.RecordHistoryEntry) []v2.RecordHistoryEntry {
+	h := make([]v2.RecordHistoryEntry, 0, len(entries))
+	for _, v := range entries {
+		rm := v.RecordMetadata
+		h = append(h, v2.RecordHistoryEntry{
+			State:      v2.RecordStateT(rm.State),
+			Status:     v2.RecordStatusT(rm.Status),
+			Version:    rm.Version,
+			Iteration:  rm.Iteration,
+			Timestamp:  rm.Timestamp,
+			Merkle:     rm.Merkle,
+			Metadata:   convertMetadataStreamsToV2(v.Metadata),
+			Anchored:   v.Anchored,
+			AnchorTxID: v.AnchorTxID,
+		})
+	}
+	return h
+}
+
 func convertRecordStateToBackend(s v2.RecordStateT) backendv2.StateT {
 	switch s {
 	case v2.RecordStateUnvetted:
 		return backendv2.StateUnvetted
 	case v2.RecordStateVetted:
 		return backendv2.StateVetted
+	case v2.RecordStateDraft:
+		return backendv2.StateDraft
 	}
 	return backendv2.StateInvalid
 }
@@ -858,6 +2029,76 @@ func convertPluginsToV2(bplugins []backendv2.Plugin) []v2.Plugin {
 	return plugins
 }
 
+func convertAnchorStatusToV2(s backendv2.AnchorStatus) v2.AnchorStatusReply {
+	var inFlight *v2.AnchorInFlight
+	if s.InFlight != nil {
+		inFlight = &v2.AnchorInFlight{
+			Started: s.InFlight.Started,
+			Tokens:  s.InFlight.Tokens,
+			Pending: s.InFlight.Pending,
+		}
+	}
+	unanchored := make([]v2.AnchorTree, 0, len(s.Unanchored))
+	for _, v := range s.Unanchored {
+		unanchored = append(unanchored, v2.AnchorTree{
+			Token:      v.Token,
+			Leaves:     v.Leaves,
+			Lag:        v.Lag,
+			LastAnchor: v.LastAnchor,
+			InFlight:   v.InFlight,
+		})
+	}
+	failures := make([]v2.AnchorFailure, 0, len(s.Failures))
+	for _, v := range s.Failures {
+		failures = append(failures, v2.AnchorFailure{
+			Provider:  v.Provider,
+			Token:     v.Token,
+			Error:     v.Error,
+			Timestamp: v.Timestamp,
+		})
+	}
+	return v2.AnchorStatusReply{
+		Providers:  s.Providers,
+		Dropping:   s.Dropping,
+		InFlight:   inFlight,
+		LastAnchor: s.LastAnchor,
+		Records:    s.Records,
+		Unanchored: unanchored,
+		Leaves:     s.Leaves,
+		Lag:        s.Lag,
+		Failures:   failures,
+	}
+}
+
+func convertInventorySnapshotToV2(s backendv2.InventorySnapshot) v2.InventorySnapshot {
+	roots := make([]v2.SnapshotRoot, 0, len(s.Roots))
+	for _, v := range s.Roots {
+		roots = append(roots, v2.SnapshotRoot{
+			State:      v2.RecordStateT(v.State),
+			Records:    v.Records,
+			MerkleRoot: v.MerkleRoot,
+		})
+	}
+	return v2.InventorySnapshot{
+		Sequence:  s.Sequence,
+		Timestamp: s.Timestamp,
+		Roots:     roots,
+		PublicKey: s.PublicKey,
+		Signature: s.Signature,
+	}
+}
+
+func convertSnapshotInclusionToV2(si backendv2.SnapshotInclusion) v2.SnapshotInclusion {
+	return v2.SnapshotInclusion{
+		Snapshot:  convertInventorySnapshotToV2(si.Snapshot),
+		Timestamp: convertTimestampToV2(si.Timestamp),
+		Token:     si.Token,
+		State:     v2.RecordStateT(si.State),
+		Merkle:    si.Merkle,
+		Proof:     convertProofToV2(si.Proof),
+	}
+}
+
 func respondWithErrorV2(w http.ResponseWriter, r *http.Request, format string, err error) {
 	var (
 		errCode = convertErrorToV2(err)
@@ -960,6 +2201,18 @@ func convertErrorToV2(e error) v2.ErrorCodeT {
 		return v2.ErrorCodePluginCmdInvalid
 	case backendv2.ErrDuplicatePayload:
 		return v2.ErrorCodeDuplicatePayload
+	case backendv2.ErrAnchorInProgress:
+		return v2.ErrorCodeAnchorInProgress
+	case backendv2.ErrAnchoringDisabled:
+		return v2.ErrorCodeAnchoringDisabled
+	case backendv2.ErrTxOpInvalid:
+		return v2.ErrorCodeTxOpInvalid
+	case backendv2.ErrSnapshotNotFound:
+		return v2.ErrorCodeSnapshotNotFound
+	case backendv2.ErrInventoryCursorInvalid:
+		return v2.ErrorCodeInventoryCursorInvalid
+	case backendv2.ErrTxInProgress:
+		return v2.ErrorCodeTxInProgress
 	}
 	return v2.ErrorCodeInvalid
 }
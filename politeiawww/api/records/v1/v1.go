@@ -22,12 +22,22 @@ const (
 	// RouteSetStatus sets the status of a record.
 	RouteSetStatus = "/setstatus"
 
+	// RouteCensorFiles censors individual files of a record.
+	RouteCensorFiles = "/censorfiles"
+
 	// RouteDetails returns the details of a record.
 	RouteDetails = "/details"
 
 	// RouteTimestamps returns the timestamps of a record.
 	RouteTimestamps = "/timestamps"
 
+	// RouteDiff returns the changes that were made to a record between two
+	// record versions.
+	RouteDiff = "/diff"
+
+	// RouteHistory returns the history of a record.
+	RouteHistory = "/history"
+
 	// RouteRecords returns a batch of records.
 	RouteRecords = "/records"
 
@@ -41,6 +51,24 @@ const (
 
 	// RouteUserRecords returnes the tokens of all records submitted by a user.
 	RouteUserRecords = "/userrecords"
+
+	// RouteSearch returns the records that match a full-text search query.
+	RouteSearch = "/search"
+
+	// RouteDraftNew saves a new draft record.
+	RouteDraftNew = "/draftnew"
+
+	// RouteDraftEdit edits a draft record.
+	RouteDraftEdit = "/draftedit"
+
+	// RouteDraftDel deletes a draft record.
+	RouteDraftDel = "/draftdel"
+
+	// RouteDraftDetails returns the details of a draft record.
+	RouteDraftDetails = "/draftdetails"
+
+	// RouteDraftPromote submits a draft record as a new record.
+	RouteDraftPromote = "/draftpromote"
 )
 
 // ErrorCodeT represents a user error code.
@@ -207,6 +235,7 @@ type Policy struct{}
 type PolicyReply struct {
 	RecordsPageSize   uint32 `json:"recordspagesize"`
 	InventoryPageSize uint32 `json:"inventorypagesize"`
+	SearchPageSize    uint32 `json:"searchpagesize"`
 }
 
 // RecordStateT represents the state of a record.
@@ -222,8 +251,12 @@ const (
 	// RecordStateVetted indicates a record has been made public.
 	RecordStateVetted RecordStateT = 2
 
+	// RecordStateDraft indicates a record is a draft. Drafts are only
+	// visible to their author.
+	RecordStateDraft RecordStateT = 3
+
 	// RecordStateLast unit test only.
-	RecordStateLast RecordStateT = 3
+	RecordStateLast RecordStateT = 4
 )
 
 var (
@@ -232,6 +265,7 @@ var (
 		RecordStateInvalid:  "invalid",
 		RecordStateUnvetted: "unvetted",
 		RecordStateVetted:   "vetted",
+		RecordStateDraft:    "draft",
 	}
 )
 
@@ -300,21 +334,43 @@ type CensorshipRecord struct {
 	Token string `json:"token"`
 
 	// Merkle is the ordered merkle root of all files in the record.
+	// This includes any files that have been censored.
 	Merkle string `json:"merkle"`
 
 	// Signature is the server signature of the Merkle+Token.
 	Signature string `json:"signature"`
 }
 
+// CensoredFile describes a record file whose payload has been permanently
+// deleted by the server. The digest of the censored file is still part of the
+// record merkle root.
+//
+// Signature is the politeiad signature of the
+// Token+Version+Name+Digest+Reason+Timestamp, where the version and timestamp
+// are base 10 encoded.
+type CensoredFile struct {
+	Version   uint32 `json:"version"`   // Record version
+	Name      string `json:"name"`      // Filename
+	MIME      string `json:"mime"`      // Mime type
+	Digest    string `json:"digest"`    // SHA256 digest of censored payload
+	Reason    string `json:"reason"`    // Reason for censorship
+	Timestamp int64  `json:"timestamp"` // Unix timestamp of censorship
+	Signature string `json:"signature"` // Server signature
+}
+
 // Record represents a record and all of its content.
+//
+// CensoredFiles contains the files of this record version that have been
+// censored. These files are not included in the Files field.
 type Record struct {
-	State     RecordStateT     `json:"state"`     // Record state
-	Status    RecordStatusT    `json:"status"`    // Record status
-	Version   uint32           `json:"version"`   // Version of this record
-	Timestamp int64            `json:"timestamp"` // Last update
-	Username  string           `json:"username"`  // Author username
-	Metadata  []MetadataStream `json:"metadata"`  // Metadata streams
-	Files     []File           `json:"files"`     // User submitted files
+	State         RecordStateT     `json:"state"`     // Record state
+	Status        RecordStatusT    `json:"status"`    // Record status
+	Version       uint32           `json:"version"`   // Version of this record
+	Timestamp     int64            `json:"timestamp"` // Last update
+	Username      string           `json:"username"`  // Author username
+	Metadata      []MetadataStream `json:"metadata"`  // Metadata streams
+	Files         []File           `json:"files"`     // User submitted files
+	CensoredFiles []CensoredFile   `json:"censoredfiles,omitempty"`
 
 	CensorshipRecord CensorshipRecord `json:"censorshiprecord"`
 }
@@ -393,6 +449,31 @@ type SetStatusReply struct {
 	Record Record `json:"record"`
 }
 
+// CensorFile identifies a record file that is to be censored.
+type CensorFile struct {
+	Version uint32 `json:"version"` // Record version
+	Name    string `json:"name"`    // Filename
+}
+
+// CensorFiles permanently deletes the payloads of individual files of a
+// public or archived record. The rest of the record is not modified. A file
+// that is shared by multiple record versions is censored in all of them.
+//
+// Signature is the client signature of the Token+Reason followed by the
+// Version+Name of each file being censored.
+type CensorFiles struct {
+	Token     string       `json:"token"`
+	Files     []CensorFile `json:"files"`
+	Reason    string       `json:"reason"`
+	PublicKey string       `json:"publickey"`
+	Signature string       `json:"signature"`
+}
+
+// CensorFilesReply is the reply to the CensorFiles command.
+type CensorFilesReply struct {
+	CensoredFiles []CensoredFile `json:"censoredfiles"`
+}
+
 // Details requests the details of a record. The full record will be returned.
 // If no version is specified then the most recent version will be returned.
 type Details struct {
@@ -418,6 +499,17 @@ type Proof struct {
 	ExtraData  string   `json:"extradata"` // JSON encoded
 }
 
+// TimestampAnchor contains the proof that the log merkle root of a timestamp
+// was anchored by an anchor provider. The TxID is the DCR transaction ID for
+// dcrtime anchors. The other providers use it for their own reference of the
+// anchor.
+type TimestampAnchor struct {
+	Provider   string `json:"provider"`
+	TxID       string `json:"txid"`
+	MerkleRoot string `json:"merkleroot"`
+	Proof      Proof  `json:"proof"`
+}
+
 // Timestamp contains all of the data required to verify that a piece of
 // record data was timestamped onto the decred blockchain.
 //
@@ -427,12 +519,17 @@ type Proof struct {
 // TxID, MerkleRoot, and Proofs will only be populated once the merkle root
 // has been included in a DCR tx and the tx has 6 confirmations. The Data
 // field will not be populated if the data has been censored.
+//
+// The log merkle root can be anchored by multiple anchor providers. Anchors
+// contains the anchors of all providers. TxID, MerkleRoot, and the last proof
+// correspond to the first anchor.
 type Timestamp struct {
-	Data       string  `json:"data"` // JSON encoded
-	Digest     string  `json:"digest"`
-	TxID       string  `json:"txid"`
-	MerkleRoot string  `json:"merkleroot"`
-	Proofs     []Proof `json:"proofs"`
+	Data       string            `json:"data"` // JSON encoded
+	Digest     string            `json:"digest"`
+	TxID       string            `json:"txid"`
+	MerkleRoot string            `json:"merkleroot"`
+	Proofs     []Proof           `json:"proofs"`
+	Anchors    []TimestampAnchor `json:"anchors"`
 }
 
 // Timestamps requests the timestamps for a specific record version. If the
@@ -454,6 +551,121 @@ type TimestampsReply struct {
 	Files map[string]Timestamp `json:"files"`
 }
 
+// DiffActionT represents the type of change that was made to a piece of record
+// content between two record versions.
+type DiffActionT uint32
+
+const (
+	// DiffActionInvalid is an invalid diff action.
+	DiffActionInvalid DiffActionT = 0
+
+	// DiffActionAdd indicates that the content was added.
+	DiffActionAdd DiffActionT = 1
+
+	// DiffActionDel indicates that the content was removed.
+	DiffActionDel DiffActionT = 2
+
+	// DiffActionModify indicates that the content was modified.
+	DiffActionModify DiffActionT = 3
+)
+
+var (
+	// DiffActions contains the human readable diff actions.
+	DiffActions = map[DiffActionT]string{
+		DiffActionInvalid: "invalid",
+		DiffActionAdd:     "add",
+		DiffActionDel:     "del",
+		DiffActionModify:  "modify",
+	}
+)
+
+// RecordMetadataDiff describes a change to a single field of the record
+// metadata, such as the version, status, or timestamp. The values are
+// formatted as strings.
+type RecordMetadataDiff struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// MetadataStreamDiff describes a change to a metadata stream. The from payload
+// is empty for added streams and the to payload is empty for removed streams.
+type MetadataStreamDiff struct {
+	Action      DiffActionT `json:"action"`
+	PluginID    string      `json:"pluginid"`
+	StreamID    uint32      `json:"streamid"`
+	FromPayload string      `json:"frompayload,omitempty"` // JSON encoded
+	ToPayload   string      `json:"topayload,omitempty"`   // JSON encoded
+}
+
+// FileDiff describes a change to a record file. The from fields are empty for
+// added files and the to fields are empty for removed files.
+//
+// LineDiff contains a unified line diff of the file payloads. It is only
+// populated for text files whose payloads have not been censored.
+type FileDiff struct {
+	Action     DiffActionT `json:"action"`
+	Name       string      `json:"name"`
+	FromMIME   string      `json:"frommime,omitempty"`
+	ToMIME     string      `json:"tomime,omitempty"`
+	FromDigest string      `json:"fromdigest,omitempty"`
+	ToDigest   string      `json:"todigest,omitempty"`
+	Censored   bool        `json:"censored,omitempty"`
+	LineDiff   string      `json:"linediff,omitempty"`
+}
+
+// Diff requests the changes that were made to a record between two record
+// versions. If the to version is omitted, the most recent version is used. If
+// the from version is omitted, the version prior to the to version is used.
+type Diff struct {
+	Token       string `json:"token"`
+	FromVersion uint32 `json:"fromversion,omitempty"`
+	ToVersion   uint32 `json:"toversion,omitempty"`
+}
+
+// DiffReply is the reply to the Diff command. Content that did not change
+// between the two versions is not included. Unvetted file changes are only
+// returned to admins and the record author.
+type DiffReply struct {
+	FromVersion    uint32               `json:"fromversion"`
+	ToVersion      uint32               `json:"toversion"`
+	RecordMetadata []RecordMetadataDiff `json:"recordmetadata"`
+	Metadata       []MetadataStreamDiff `json:"metadata"`
+	Files          []FileDiff           `json:"files"`
+}
+
+// HistoryEntry describes a single iteration of a record. The iteration is
+// incremented anytime any record content changes, including status changes.
+// The version is only incremented when the record files change. Merkle is the
+// merkle root of the record files of the iteration.
+//
+// StatusChange is only populated for the iterations that changed the record
+// status. AnchorTxID is the decred transaction that contains the anchor of the
+// iteration. It is empty if the iteration has not been anchored yet.
+type HistoryEntry struct {
+	State        RecordStateT  `json:"state"`
+	Status       RecordStatusT `json:"status"`
+	Version      uint32        `json:"version"`
+	Iteration    uint32        `json:"iteration"`
+	Timestamp    int64         `json:"timestamp"`
+	Merkle       string        `json:"merkle"`
+	StatusChange *StatusChange `json:"statuschange,omitempty"`
+	Anchored     bool          `json:"anchored"`
+	AnchorTxID   string        `json:"anchortxid,omitempty"`
+}
+
+// History requests the history of a record. The unvetted history of a record
+// is not returned once the record has been made public.
+type History struct {
+	Token string `json:"token"`
+}
+
+// HistoryReply is the reply to the History command. It contains an entry for
+// every iteration of the record, sorted from oldest to newest.
+type HistoryReply struct {
+	History []HistoryEntry `json:"history"`
+}
+
 const (
 	// RecordsPageSize is the maximum number of records that can be
 	// requested in a Records request.
@@ -535,6 +747,7 @@ type InventoryOrderedReply struct {
 
 // UserRecords requests the tokens of all records submitted by a user.
 // Unvetted record tokens are only returned to admins and the record author.
+// Draft tokens are only returned to the record author.
 type UserRecords struct {
 	UserID string `json:"userid"`
 }
@@ -543,4 +756,116 @@ type UserRecords struct {
 type UserRecordsReply struct {
 	Unvetted []string `json:"unvetted"`
 	Vetted   []string `json:"vetted"`
+	Drafts   []string `json:"drafts,omitempty"`
+}
+
+// DraftNew saves a new draft record. Drafts are stored encrypted and are not
+// timestamped. A draft can be edited freely by its author and is only visible
+// to its author. The draft files are not validated against the record policy
+// until the draft is promoted.
+//
+// Signature is the client signature of the record merkle root. The merkle root
+// is the ordered merkle root of all record Files.
+type DraftNew struct {
+	Files     []File `json:"files"`
+	PublicKey string `json:"publickey"`
+	Signature string `json:"signature"`
+}
+
+// DraftNewReply is the reply to the DraftNew command.
+type DraftNewReply struct {
+	Record Record `json:"record"`
+}
+
+// DraftEdit edits an existing draft record. Previous versions of a draft are
+// not retained.
+//
+// Signature is the client signature of the record merkle root. The merkle root
+// is the ordered merkle root of all record Files.
+type DraftEdit struct {
+	Token     string `json:"token"`
+	Files     []File `json:"files"`
+	PublicKey string `json:"publickey"`
+	Signature string `json:"signature"`
+}
+
+// DraftEditReply is the reply to the DraftEdit command.
+type DraftEditReply struct {
+	Record Record `json:"record"`
+}
+
+// DraftDel deletes a draft record.
+type DraftDel struct {
+	Token string `json:"token"`
+}
+
+// DraftDelReply is the reply to the DraftDel command.
+type DraftDelReply struct{}
+
+// DraftDetails requests the details of a draft record.
+type DraftDetails struct {
+	Token string `json:"token"`
+}
+
+// DraftDetailsReply is the reply to the DraftDetails command.
+type DraftDetailsReply struct {
+	Record Record `json:"record"`
+}
+
+// DraftPromote submits a draft record as a new unvetted record. The record is
+// subject to the same validation as a record that is submitted using the New
+// command. The new record is assigned a new token. The draft is deleted once
+// the record has been submitted.
+type DraftPromote struct {
+	Token string `json:"token"`
+}
+
+// DraftPromoteReply is the reply to the DraftPromote command.
+type DraftPromoteReply struct {
+	Record Record `json:"record"`
+}
+
+const (
+	// SearchPageSize is the number of search results that will be
+	// returned per page.
+	SearchPageSize uint32 = 20
+)
+
+// Search requests the records that match a full-text search query. The record
+// name, the record index file, and the author user ID are searched. Results
+// are ordered by relevance to the query from most to least relevant.
+//
+// Vetted records are searched by default. Unvetted records can only be
+// searched by admins. Censored records are not searchable.
+//
+// The Status, UserID, After, and Before fields are optional filters. After and
+// Before are inclusive Unix timestamps that are compared against the timestamp
+// of the most recent record update.
+type Search struct {
+	Query  string        `json:"query"`
+	State  RecordStateT  `json:"state,omitempty"`
+	Status RecordStatusT `json:"status,omitempty"`
+	UserID string        `json:"userid,omitempty"`
+	After  int64         `json:"after,omitempty"`
+	Before int64         `json:"before,omitempty"`
+	Page   uint32        `json:"page,omitempty"`
+}
+
+// SearchResult is a record that matched a search query. Score is the relevance
+// of the record to the query.
+type SearchResult struct {
+	Token     string        `json:"token"`
+	Name      string        `json:"name"`
+	UserID    string        `json:"userid"`
+	Username  string        `json:"username"`
+	Status    RecordStatusT `json:"status"`
+	Timestamp int64         `json:"timestamp"`
+	Score     float64       `json:"score"`
+}
+
+// SearchReply is the reply to the Search command. Total is the number of
+// records that matched the query across all pages.
+type SearchReply struct {
+	Results []SearchResult `json:"results"`
+	Total   uint32         `json:"total"`
 }
@@ -315,11 +315,21 @@ func convertCommentTimestamp(t cmv1.Timestamp) backend.Timestamp {
 	for _, v := range t.Proofs {
 		proofs = append(proofs, convertCommentProof(v))
 	}
+	anchors := make([]backend.TimestampAnchor, 0, len(t.Anchors))
+	for _, v := range t.Anchors {
+		anchors = append(anchors, backend.TimestampAnchor{
+			Provider:   v.Provider,
+			TxID:       v.TxID,
+			MerkleRoot: v.MerkleRoot,
+			Proof:      convertCommentProof(v.Proof),
+		})
+	}
 	return backend.Timestamp{
 		Data:       t.Data,
 		Digest:     t.Digest,
 		TxID:       t.TxID,
 		MerkleRoot: t.MerkleRoot,
 		Proofs:     proofs,
+		Anchors:    anchors,
 	}
 }
@@ -93,6 +93,23 @@ func (c *Client) RecordSetStatus(ss rcv1.SetStatus) (*rcv1.SetStatusReply, error
 	return &ssr, nil
 }
 
+// RecordCensorFiles sends a records v1 CensorFiles request to politeiawww.
+func (c *Client) RecordCensorFiles(cf rcv1.CensorFiles) (*rcv1.CensorFilesReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteCensorFiles, cf)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfr rcv1.CensorFilesReply
+	err = json.Unmarshal(resBody, &cfr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfr, nil
+}
+
 // RecordDetails sends a records v1 Details request to politeiawww.
 func (c *Client) RecordDetails(d rcv1.Details) (*rcv1.Record, error) {
 	resBody, err := c.makeReq(http.MethodPost,
@@ -127,6 +144,40 @@ func (c *Client) RecordTimestamps(t rcv1.Timestamps) (*rcv1.TimestampsReply, err
 	return &tr, nil
 }
 
+// RecordDiff sends a records v1 Diff request to politeiawww.
+func (c *Client) RecordDiff(d rcv1.Diff) (*rcv1.DiffReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteDiff, d)
+	if err != nil {
+		return nil, err
+	}
+
+	var dr rcv1.DiffReply
+	err = json.Unmarshal(resBody, &dr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dr, nil
+}
+
+// RecordHistory sends a records v1 History request to politeiawww.
+func (c *Client) RecordHistory(h rcv1.History) (*rcv1.HistoryReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteHistory, h)
+	if err != nil {
+		return nil, err
+	}
+
+	var hr rcv1.HistoryReply
+	err = json.Unmarshal(resBody, &hr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hr, nil
+}
+
 // Records sends a records v1 Records request to politeiawww.
 func (c *Client) Records(r rcv1.Records) (map[string]rcv1.Record, error) {
 	resBody, err := c.makeReq(http.MethodPost,
@@ -196,6 +247,108 @@ func (c *Client) UserRecords(ur rcv1.UserRecords) (*rcv1.UserRecordsReply, error
 	return &urr, nil
 }
 
+// RecordSearch sends a records v1 Search request to politeiawww.
+func (c *Client) RecordSearch(s rcv1.Search) (*rcv1.SearchReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteSearch, s)
+	if err != nil {
+		return nil, err
+	}
+
+	var sr rcv1.SearchReply
+	err = json.Unmarshal(resBody, &sr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sr, nil
+}
+
+// DraftNew sends a records v1 DraftNew request to politeiawww.
+func (c *Client) DraftNew(dn rcv1.DraftNew) (*rcv1.DraftNewReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteDraftNew, dn)
+	if err != nil {
+		return nil, err
+	}
+
+	var dnr rcv1.DraftNewReply
+	err = json.Unmarshal(resBody, &dnr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dnr, nil
+}
+
+// DraftEdit sends a records v1 DraftEdit request to politeiawww.
+func (c *Client) DraftEdit(de rcv1.DraftEdit) (*rcv1.DraftEditReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteDraftEdit, de)
+	if err != nil {
+		return nil, err
+	}
+
+	var der rcv1.DraftEditReply
+	err = json.Unmarshal(resBody, &der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &der, nil
+}
+
+// DraftDel sends a records v1 DraftDel request to politeiawww.
+func (c *Client) DraftDel(dd rcv1.DraftDel) (*rcv1.DraftDelReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteDraftDel, dd)
+	if err != nil {
+		return nil, err
+	}
+
+	var ddr rcv1.DraftDelReply
+	err = json.Unmarshal(resBody, &ddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ddr, nil
+}
+
+// DraftDetails sends a records v1 DraftDetails request to politeiawww.
+func (c *Client) DraftDetails(dd rcv1.DraftDetails) (*rcv1.DraftDetailsReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteDraftDetails, dd)
+	if err != nil {
+		return nil, err
+	}
+
+	var ddr rcv1.DraftDetailsReply
+	err = json.Unmarshal(resBody, &ddr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ddr, nil
+}
+
+// DraftPromote sends a records v1 DraftPromote request to politeiawww.
+func (c *Client) DraftPromote(dp rcv1.DraftPromote) (*rcv1.DraftPromoteReply, error) {
+	resBody, err := c.makeReq(http.MethodPost,
+		rcv1.APIRoute, rcv1.RouteDraftPromote, dp)
+	if err != nil {
+		return nil, err
+	}
+
+	var dpr rcv1.DraftPromoteReply
+	err = json.Unmarshal(resBody, &dpr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dpr, nil
+}
+
 // digestsVerify verifies that all file digests match the calculated SHA256
 // digests of the file payloads.
 func digestsVerify(files []rcv1.File) error {
@@ -220,26 +373,45 @@ func digestsVerify(files []rcv1.File) error {
 }
 
 // CensorshipRecordVerify verifies the censorship record of a records v1
-// Record.
+// Record and the server signatures of its censored files.
 func CensorshipRecordVerify(r rcv1.Record, serverPubKey string) error {
+	err := CensorshipRecordSignatureVerify(r, serverPubKey)
+	if err != nil {
+		return err
+	}
+
+	// Verify censored files
+	return CensoredFilesVerify(r.CensorshipRecord.Token,
+		r.CensoredFiles, serverPubKey)
+}
+
+// CensorshipRecordSignatureVerify verifies the merkle root and the server
+// signature of the censorship record of a records v1 Record. The server
+// signatures of the censored files are not verified.
+func CensorshipRecordSignatureVerify(r rcv1.Record, serverPubKey string) error {
 	if r.Status == rcv1.RecordStatusCensored {
 		// The files of a censored record will be deleted.
 		// There is nothing to verify.
 		return nil
 	}
 
-	// Verify censorship record merkle root
-	if len(r.Files) > 0 {
+	// Verify censorship record merkle root. The payloads of censored
+	// files no longer exist, but their digests are still part of the
+	// merkle root.
+	if len(r.Files) > 0 || len(r.CensoredFiles) > 0 {
 		// Verify digests
 		err := digestsVerify(r.Files)
 		if err != nil {
 			return err
 		}
 		// Verify merkle root
-		digests := make([]string, 0, len(r.Files))
+		digests := make([]string, 0, len(r.Files)+len(r.CensoredFiles))
 		for _, v := range r.Files {
 			digests = append(digests, v.Digest)
 		}
+		for _, v := range r.CensoredFiles {
+			digests = append(digests, v.Digest)
+		}
 		mr, err := util.MerkleRoot(digests)
 		if err != nil {
 			return err
@@ -266,6 +438,47 @@ func CensorshipRecordVerify(r rcv1.Record, serverPubKey string) error {
 	return nil
 }
 
+// CensorFilesMsg returns the message that is signed by the client for a
+// records v1 CensorFiles request.
+func CensorFilesMsg(cf rcv1.CensorFiles) string {
+	var b strings.Builder
+	b.WriteString(cf.Token + cf.Reason)
+	for _, v := range cf.Files {
+		b.WriteString(strconv.FormatUint(uint64(v.Version), 10) + v.Name)
+	}
+	return b.String()
+}
+
+// CensoredFileMsg returns the message that is signed by the server for a
+// censored record file.
+func CensoredFileMsg(token string, f rcv1.CensoredFile) string {
+	return token + strconv.FormatUint(uint64(f.Version), 10) + f.Name +
+		f.Digest + f.Reason + strconv.FormatInt(f.Timestamp, 10)
+}
+
+// CensoredFilesVerify verifies the server signatures of the censored files of
+// a record.
+func CensoredFilesVerify(token string, files []rcv1.CensoredFile, serverPubKey string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	id, err := identity.PublicIdentityFromString(serverPubKey)
+	if err != nil {
+		return err
+	}
+	for _, v := range files {
+		s, err := util.ConvertSignature(v.Signature)
+		if err != nil {
+			return err
+		}
+		if !id.VerifyMessage([]byte(CensoredFileMsg(token, v)), s) {
+			return fmt.Errorf("invalid censored file signature: %v %v",
+				v.Version, v.Name)
+		}
+	}
+	return nil
+}
+
 // RecordVerify verfifies the contents of a record. This includes verifying
 // the censorship record, the user metadata, and any status changes that are
 // present.
@@ -439,11 +652,21 @@ func convertRecordTimestamp(t rcv1.Timestamp) backend.Timestamp {
 	for _, v := range t.Proofs {
 		proofs = append(proofs, convertRecordProof(v))
 	}
+	anchors := make([]backend.TimestampAnchor, 0, len(t.Anchors))
+	for _, v := range t.Anchors {
+		anchors = append(anchors, backend.TimestampAnchor{
+			Provider:   v.Provider,
+			TxID:       v.TxID,
+			MerkleRoot: v.MerkleRoot,
+			Proof:      convertRecordProof(v.Proof),
+		})
+	}
 	return backend.Timestamp{
 		Data:       t.Data,
 		Digest:     t.Digest,
 		TxID:       t.TxID,
 		MerkleRoot: t.MerkleRoot,
 		Proofs:     proofs,
+		Anchors:    anchors,
 	}
 }
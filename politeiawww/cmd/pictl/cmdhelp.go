@@ -77,6 +77,8 @@ func (c *cmdHelp) Execute(args []string) error {
 		fmt.Printf("%s\n", proposalEditHelpMsg)
 	case "proposalsetstatus":
 		fmt.Printf("%s\n", proposalSetStatusHelpMsg)
+	case "proposalcensorfiles":
+		fmt.Printf("%s\n", proposalCensorFilesHelpMsg)
 	case "proposalsetbillingstatus":
 		fmt.Printf("%s\n", proposalSetBillingStatusHelpMsg)
 	case "proposalbillingstatuschanges":
@@ -85,6 +87,10 @@ func (c *cmdHelp) Execute(args []string) error {
 		fmt.Printf("%s\n", proposalDetailsHelpMsg)
 	case "proposaltimestamps":
 		fmt.Printf("%s\n", proposalTimestampsHelpMsg)
+	case "proposaldiff":
+		fmt.Printf("%s\n", proposalDiffHelpMsg)
+	case "proposalhistory":
+		fmt.Printf("%s\n", proposalHistoryHelpMsg)
 	case "proposals":
 		fmt.Printf("%s\n", proposalsHelpMsg)
 	case "proposalsummaries":
@@ -95,10 +101,18 @@ func (c *cmdHelp) Execute(args []string) error {
 		fmt.Printf("%s\n", proposalInvOrderedHelpMsg)
 	case "userproposals":
 		fmt.Printf("%s\n", userProposalsHelpMsg)
+	case "proposaldraftdetails":
+		fmt.Printf("%s\n", proposalDraftDetailsHelpMsg)
+	case "proposaldraftdel":
+		fmt.Printf("%s\n", proposalDraftDelHelpMsg)
+	case "proposaldraftpromote":
+		fmt.Printf("%s\n", proposalDraftPromoteHelpMsg)
 
 		// Record commands
 	case "recordpolicy":
 		fmt.Printf("%s\n", recordPolicyHelpMsg)
+	case "search":
+		fmt.Printf("%s\n", searchHelpMsg)
 
 		// Comment commands
 	case "commentpolicy":
@@ -0,0 +1,122 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	rcv1 "github.com/decred/politeia/politeiawww/api/records/v1"
+	pclient "github.com/decred/politeia/politeiawww/client"
+	"github.com/decred/politeia/politeiawww/cmd/shared"
+)
+
+// cmdProposalCensorFiles censors individual files of a proposal.
+type cmdProposalCensorFiles struct {
+	Args struct {
+		Token    string `positional-arg-name:"token" required:"true"`
+		Reason   string `positional-arg-name:"reason" required:"true"`
+		Filename string `positional-arg-name:"filename" required:"true"`
+	} `positional-args:"true"`
+
+	// Version is the proposal version that contains the file. The
+	// latest version is used if one is not provided.
+	Version uint32 `long:"version" optional:"true"`
+}
+
+// Execute executes the cmdProposalCensorFiles command.
+//
+// This function satisfies the go-flags Commander interface.
+func (c *cmdProposalCensorFiles) Execute(args []string) error {
+	// Verify user identity. This will be needed to sign the request.
+	if cfg.Identity == nil {
+		return shared.ErrUserIdentityNotFound
+	}
+
+	// Setup client
+	opts := pclient.Opts{
+		HTTPSCert:  cfg.HTTPSCert,
+		Cookies:    cfg.Cookies,
+		HeaderCSRF: cfg.CSRF,
+		Verbose:    cfg.Verbose,
+		RawJSON:    cfg.RawJSON,
+	}
+	pc, err := pclient.New(cfg.Host, opts)
+	if err != nil {
+		return err
+	}
+
+	// Get the record. The censored files are signed using the full
+	// length token, which may not have been provided.
+	r, err := pc.RecordDetails(rcv1.Details{
+		Token: c.Args.Token,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Setup version
+	version := c.Version
+	if version == 0 {
+		version = r.Version
+	}
+
+	// Setup request
+	cf := rcv1.CensorFiles{
+		Token: c.Args.Token,
+		Files: []rcv1.CensorFile{
+			{
+				Version: version,
+				Name:    c.Args.Filename,
+			},
+		},
+		Reason:    c.Args.Reason,
+		PublicKey: cfg.Identity.Public.String(),
+	}
+	sig := cfg.Identity.SignMessage([]byte(pclient.CensorFilesMsg(cf)))
+	cf.Signature = hex.EncodeToString(sig[:])
+
+	// Send request
+	cfr, err := pc.RecordCensorFiles(cf)
+	if err != nil {
+		return err
+	}
+
+	// Verify the censored files
+	vr, err := client.Version()
+	if err != nil {
+		return err
+	}
+	err = pclient.CensoredFilesVerify(r.CensorshipRecord.Token,
+		cfr.CensoredFiles, vr.PubKey)
+	if err != nil {
+		return fmt.Errorf("unable to verify censored files: %v", err)
+	}
+
+	// Print censored files to stdout
+	for _, v := range cfr.CensoredFiles {
+		printf("Censored %v version %v (%v)\n", v.Name, v.Version, v.Digest)
+	}
+
+	return nil
+}
+
+// proposalCensorFilesHelpMsg is printed to stdout by the help command.
+const proposalCensorFilesHelpMsg = `proposalcensorfiles "token" "reason" "filename"
+
+Censor an individual file of a public or abandoned proposal. The file payload
+is permanently deleted. The rest of the proposal is not modified and can still
+be verified. A file that is shared by multiple proposal versions is censored in
+all of them. Requires admin priviledges.
+
+Arguments:
+1. token    (string, required)  Proposal censorship token
+2. reason   (string, required)  Censorship reason
+3. filename (string, required)  Name of the file to censor
+
+Flags:
+ --version (uint32) Proposal version that contains the file. The latest
+                    version is used if one is not provided.
+`
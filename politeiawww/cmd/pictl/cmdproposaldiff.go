@@ -0,0 +1,108 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	rcv1 "github.com/decred/politeia/politeiawww/api/records/v1"
+	pclient "github.com/decred/politeia/politeiawww/client"
+)
+
+// cmdProposalDiff retrieves the changes that were made to a proposal between
+// two proposal versions.
+type cmdProposalDiff struct {
+	Args struct {
+		Token       string `positional-arg-name:"token" required:"true"`
+		FromVersion uint32 `positional-arg-name:"fromversion" optional:"true"`
+		ToVersion   uint32 `positional-arg-name:"toversion" optional:"true"`
+	} `positional-args:"true"`
+}
+
+// Execute executes the cmdProposalDiff command.
+//
+// This function satisfies the go-flags Commander interface.
+func (c *cmdProposalDiff) Execute(args []string) error {
+	// Setup client
+	opts := pclient.Opts{
+		HTTPSCert:  cfg.HTTPSCert,
+		Cookies:    cfg.Cookies,
+		HeaderCSRF: cfg.CSRF,
+		Verbose:    cfg.Verbose,
+		RawJSON:    cfg.RawJSON,
+	}
+	pc, err := pclient.New(cfg.Host, opts)
+	if err != nil {
+		return err
+	}
+
+	// Get the proposal diff
+	d := rcv1.Diff{
+		Token:       c.Args.Token,
+		FromVersion: c.Args.FromVersion,
+		ToVersion:   c.Args.ToVersion,
+	}
+	dr, err := pc.RecordDiff(d)
+	if err != nil {
+		return err
+	}
+
+	// Print the diff to stdout
+	printProposalDiff(*dr)
+
+	return nil
+}
+
+// printProposalDiff prints a proposal diff.
+func printProposalDiff(dr rcv1.DiffReply) {
+	printf("From version: %v\n", dr.FromVersion)
+	printf("To version  : %v\n", dr.ToVersion)
+	if len(dr.RecordMetadata) > 0 {
+		printf("Record metadata\n")
+		for _, v := range dr.RecordMetadata {
+			printf("  %-10v %v -> %v\n", v.Field, v.From, v.To)
+		}
+	}
+	if len(dr.Metadata) > 0 {
+		printf("Metadata\n")
+		for _, v := range dr.Metadata {
+			printf("  %-6v %-8v %v\n", rcv1.DiffActions[v.Action],
+				v.PluginID, v.StreamID)
+		}
+	}
+	if len(dr.Files) > 0 {
+		printf("Files\n")
+		for _, v := range dr.Files {
+			var censored string
+			if v.Censored {
+				censored = "(censored)"
+			}
+			printf("  %-6v %-22v %v\n", rcv1.DiffActions[v.Action],
+				v.Name, censored)
+		}
+	}
+	for _, v := range dr.Files {
+		if v.LineDiff == "" {
+			continue
+		}
+		printf("\n%v", v.LineDiff)
+	}
+}
+
+// proposalDiffHelpMsg is printed to stdout by the help command.
+const proposalDiffHelpMsg = `proposaldiff [flags] "token" "fromversion" "toversion"
+
+Retrieve the changes that were made to a proposal between two proposal
+versions. This includes the added, removed, and modified files, a line diff of
+the text files, and the changes to the proposal metadata.
+
+If the to version is omitted, the most recent version is used. If the from
+version is omitted, the version prior to the to version is used.
+
+Unvetted file changes are only returned to admins and the proposal author.
+
+Arguments:
+1. token        (string, required) Proposal token.
+2. fromversion  (uint32, optional) Proposal version to compare from.
+3. toversion    (uint32, optional) Proposal version to compare to.
+`
@@ -0,0 +1,57 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	rcv1 "github.com/decred/politeia/politeiawww/api/records/v1"
+	pclient "github.com/decred/politeia/politeiawww/client"
+)
+
+// cmdProposalDraftDel deletes a draft proposal.
+type cmdProposalDraftDel struct {
+	Args struct {
+		Token string `positional-arg-name:"token" required:"true"`
+	} `positional-args:"true"`
+}
+
+// Execute executes the cmdProposalDraftDel command.
+//
+// This function satisfies the go-flags Commander interface.
+func (c *cmdProposalDraftDel) Execute(args []string) error {
+	// Setup client
+	opts := pclient.Opts{
+		HTTPSCert:  cfg.HTTPSCert,
+		Cookies:    cfg.Cookies,
+		HeaderCSRF: cfg.CSRF,
+		Verbose:    cfg.Verbose,
+		RawJSON:    cfg.RawJSON,
+	}
+	pc, err := pclient.New(cfg.Host, opts)
+	if err != nil {
+		return err
+	}
+
+	// Delete draft
+	dd := rcv1.DraftDel{
+		Token: c.Args.Token,
+	}
+	_, err = pc.DraftDel(dd)
+	if err != nil {
+		return err
+	}
+
+	printf("Draft deleted\n")
+
+	return nil
+}
+
+// proposalDraftDelHelpMsg is printed to stdout by the help command.
+const proposalDraftDelHelpMsg = `proposaldraftdel "token"
+
+Delete a draft proposal. Drafts can only be deleted by their author.
+
+Arguments:
+1. token  (string, required)  Draft token.
+`
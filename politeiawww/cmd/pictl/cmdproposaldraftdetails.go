@@ -0,0 +1,61 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	rcv1 "github.com/decred/politeia/politeiawww/api/records/v1"
+	pclient "github.com/decred/politeia/politeiawww/client"
+)
+
+// cmdProposalDraftDetails retrieves a draft proposal.
+type cmdProposalDraftDetails struct {
+	Args struct {
+		Token string `positional-arg-name:"token" required:"true"`
+	} `positional-args:"true"`
+}
+
+// Execute executes the cmdProposalDraftDetails command.
+//
+// This function satisfies the go-flags Commander interface.
+func (c *cmdProposalDraftDetails) Execute(args []string) error {
+	// Setup client
+	opts := pclient.Opts{
+		HTTPSCert:  cfg.HTTPSCert,
+		Cookies:    cfg.Cookies,
+		HeaderCSRF: cfg.CSRF,
+		Verbose:    cfg.Verbose,
+		RawJSON:    cfg.RawJSON,
+	}
+	pc, err := pclient.New(cfg.Host, opts)
+	if err != nil {
+		return err
+	}
+
+	// Get draft details
+	dd := rcv1.DraftDetails{
+		Token: c.Args.Token,
+	}
+	ddr, err := pc.DraftDetails(dd)
+	if err != nil {
+		return err
+	}
+
+	// Print draft to stdout
+	err = printProposal(ddr.Record)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// proposalDraftDetailsHelpMsg is printed to stdout by the help command.
+const proposalDraftDetailsHelpMsg = `proposaldraftdetails "token"
+
+Retrieve a draft proposal. Drafts can only be retrieved by their author.
+
+Arguments:
+1. token  (string, required)  Draft token.
+`
@@ -0,0 +1,74 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	rcv1 "github.com/decred/politeia/politeiawww/api/records/v1"
+	pclient "github.com/decred/politeia/politeiawww/client"
+)
+
+// cmdProposalDraftPromote submits a draft proposal.
+type cmdProposalDraftPromote struct {
+	Args struct {
+		Token string `positional-arg-name:"token" required:"true"`
+	} `positional-args:"true"`
+}
+
+// Execute executes the cmdProposalDraftPromote command.
+//
+// This function satisfies the go-flags Commander interface.
+func (c *cmdProposalDraftPromote) Execute(args []string) error {
+	// Setup client
+	opts := pclient.Opts{
+		HTTPSCert:  cfg.HTTPSCert,
+		Cookies:    cfg.Cookies,
+		HeaderCSRF: cfg.CSRF,
+		Verbose:    cfg.Verbose,
+		RawJSON:    cfg.RawJSON,
+	}
+	pc, err := pclient.New(cfg.Host, opts)
+	if err != nil {
+		return err
+	}
+
+	// Submit draft
+	dp := rcv1.DraftPromote{
+		Token: c.Args.Token,
+	}
+	dpr, err := pc.DraftPromote(dp)
+	if err != nil {
+		return err
+	}
+
+	// Verify record
+	vr, err := client.Version()
+	if err != nil {
+		return err
+	}
+	err = pclient.RecordVerify(dpr.Record, vr.PubKey)
+	if err != nil {
+		return fmt.Errorf("unable to verify record: %v", err)
+	}
+
+	// Print censorship record
+	printf("Token  : %v\n", dpr.Record.CensorshipRecord.Token)
+	printf("Merkle : %v\n", dpr.Record.CensorshipRecord.Merkle)
+	printf("Receipt: %v\n", dpr.Record.CensorshipRecord.Signature)
+
+	return nil
+}
+
+// proposalDraftPromoteHelpMsg is printed to stdout by the help command.
+const proposalDraftPromoteHelpMsg = `proposaldraftpromote "token"
+
+Submit a draft proposal. The proposal goes through the same validation as a
+proposal that is submitted using the proposalnew command. The submitted
+proposal is assigned a new token and the draft is deleted.
+
+Arguments:
+1. token  (string, required)  Draft token.
+`
@@ -55,6 +55,9 @@ type cmdProposalEdit struct {
 	// RandomImages generates random image attachments. The Attachments
 	// argument is not allowed when using this flag.
 	RandomImages bool `long:"randomimages" optional:"true"`
+
+	// Draft edits a draft proposal instead of a submitted proposal.
+	Draft bool `long:"draft" optional:"true"`
 }
 
 // Execute executes the cmdProposalEdit command.
@@ -143,7 +146,17 @@ func proposalEdit(c *cmdProposalEdit) (*rcv1.Record, error) {
 
 	// Get current proposal if we are using the existing metadata
 	var curr *rcv1.Record
-	if c.UseMD {
+	switch {
+	case c.UseMD && c.Draft:
+		dd := rcv1.DraftDetails{
+			Token: token,
+		}
+		ddr, err := pc.DraftDetails(dd)
+		if err != nil {
+			return nil, err
+		}
+		curr = &ddr.Record
+	case c.UseMD:
 		d := rcv1.Details{
 			Token: token,
 		}
@@ -273,15 +286,31 @@ func proposalEdit(c *cmdProposalEdit) (*rcv1.Record, error) {
 	if err != nil {
 		return nil, err
 	}
-	e := rcv1.Edit{
-		Token:     token,
-		Files:     files,
-		PublicKey: cfg.Identity.Public.String(),
-		Signature: sig,
-	}
-	er, err := pc.RecordEdit(e)
-	if err != nil {
-		return nil, err
+	var r rcv1.Record
+	if c.Draft {
+		de := rcv1.DraftEdit{
+			Token:     token,
+			Files:     files,
+			PublicKey: cfg.Identity.Public.String(),
+			Signature: sig,
+		}
+		der, err := pc.DraftEdit(de)
+		if err != nil {
+			return nil, err
+		}
+		r = der.Record
+	} else {
+		e := rcv1.Edit{
+			Token:     token,
+			Files:     files,
+			PublicKey: cfg.Identity.Public.String(),
+			Signature: sig,
+		}
+		er, err := pc.RecordEdit(e)
+		if err != nil {
+			return nil, err
+		}
+		r = er.Record
 	}
 
 	// Verify record
@@ -289,19 +318,19 @@ func proposalEdit(c *cmdProposalEdit) (*rcv1.Record, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = pclient.RecordVerify(er.Record, vr.PubKey)
+	err = pclient.RecordVerify(r, vr.PubKey)
 	if err != nil {
 		return nil, fmt.Errorf("unable to verify record: %v", err)
 	}
 
 	// Print proposal to stdout
 	printf("Proposal editted\n")
-	err = printProposal(er.Record)
+	err = printProposal(r)
 	if err != nil {
 		return nil, err
 	}
 
-	return &er.Record, nil
+	return &r, nil
 }
 
 // proposalEditHelpMsg is the printed to stdout by the help command.
@@ -354,4 +383,7 @@ Flags:
 
  --randomimages (bool)   Generate random attachments. The attachments argument
                          is not allowed when using this flag.
+
+ --draft        (bool)   Edit a draft proposal. The token must be a draft
+                         token.
 `
@@ -0,0 +1,85 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	rcv1 "github.com/decred/politeia/politeiawww/api/records/v1"
+	pclient "github.com/decred/politeia/politeiawww/client"
+)
+
+// cmdProposalHistory retrieves the history of a proposal.
+type cmdProposalHistory struct {
+	Args struct {
+		Token string `positional-arg-name:"token" required:"true"`
+	} `positional-args:"true"`
+}
+
+// Execute executes the cmdProposalHistory command.
+//
+// This function satisfies the go-flags Commander interface.
+func (c *cmdProposalHistory) Execute(args []string) error {
+	// Setup client
+	opts := pclient.Opts{
+		HTTPSCert:  cfg.HTTPSCert,
+		Cookies:    cfg.Cookies,
+		HeaderCSRF: cfg.CSRF,
+		Verbose:    cfg.Verbose,
+		RawJSON:    cfg.RawJSON,
+	}
+	pc, err := pclient.New(cfg.Host, opts)
+	if err != nil {
+		return err
+	}
+
+	// Get the proposal history
+	h := rcv1.History{
+		Token: c.Args.Token,
+	}
+	hr, err := pc.RecordHistory(h)
+	if err != nil {
+		return err
+	}
+
+	// Print the history to stdout
+	for _, v := range hr.History {
+		printHistoryEntry(v)
+		printf("\n")
+	}
+
+	return nil
+}
+
+// printHistoryEntry prints a single iteration of a proposal's history.
+func printHistoryEntry(e rcv1.HistoryEntry) {
+	printf("Iteration: %v\n", e.Iteration)
+	printf("Version  : %v\n", e.Version)
+	printf("State    : %v\n", rcv1.RecordStates[e.State])
+	printf("Status   : %v\n", rcv1.RecordStatuses[e.Status])
+	printf("Timestamp: %v\n", dateAndTimeFromUnix(e.Timestamp))
+	printf("Merkle   : %v\n", e.Merkle)
+	if e.StatusChange != nil && e.StatusChange.Reason != "" {
+		printf("Reason   : %v\n", e.StatusChange.Reason)
+	}
+	anchor := "not anchored"
+	if e.Anchored {
+		anchor = e.AnchorTxID
+	}
+	printf("Anchor   : %v\n", anchor)
+}
+
+// proposalHistoryHelpMsg is printed to stdout by the help command.
+const proposalHistoryHelpMsg = `proposalhistory [flags] "token"
+
+Retrieve the history of a proposal. An entry is printed for every iteration of
+the proposal. The iteration is incremented anytime any proposal content
+changes, including status changes. Each entry includes the status change
+reason and the decred transaction that the iteration was anchored in.
+
+The unvetted history of a proposal is not returned once the proposal has been
+made public.
+
+Arguments:
+1. token  (string, required) Proposal token.
+`
@@ -49,6 +49,9 @@ type cmdProposalNew struct {
 	// RandomImages generates random image attachments. The Attachments
 	// argument is not allowed when using this flag.
 	RandomImages bool `long:"randomimages" optional:"true"`
+
+	// Draft saves the proposal as a draft instead of submitting it.
+	Draft bool `long:"draft" optional:"true"`
 }
 
 // Execute executes the cmdProposalNew command.
@@ -239,14 +242,29 @@ func proposalNew(c *cmdProposalNew) (*rcv1.Record, error) {
 	if err != nil {
 		return nil, err
 	}
-	n := rcv1.New{
-		Files:     files,
-		PublicKey: cfg.Identity.Public.String(),
-		Signature: sig,
-	}
-	nr, err := pc.RecordNew(n)
-	if err != nil {
-		return nil, err
+	var r rcv1.Record
+	if c.Draft {
+		dn := rcv1.DraftNew{
+			Files:     files,
+			PublicKey: cfg.Identity.Public.String(),
+			Signature: sig,
+		}
+		dnr, err := pc.DraftNew(dn)
+		if err != nil {
+			return nil, err
+		}
+		r = dnr.Record
+	} else {
+		n := rcv1.New{
+			Files:     files,
+			PublicKey: cfg.Identity.Public.String(),
+			Signature: sig,
+		}
+		nr, err := pc.RecordNew(n)
+		if err != nil {
+			return nil, err
+		}
+		r = nr.Record
 	}
 
 	// Verify record
@@ -254,17 +272,17 @@ func proposalNew(c *cmdProposalNew) (*rcv1.Record, error) {
 	if err != nil {
 		return nil, err
 	}
-	err = pclient.RecordVerify(nr.Record, vr.PubKey)
+	err = pclient.RecordVerify(r, vr.PubKey)
 	if err != nil {
 		return nil, fmt.Errorf("unable to verify record: %v", err)
 	}
 
 	// Print censorship record
-	printf("Token  : %v\n", nr.Record.CensorshipRecord.Token)
-	printf("Merkle : %v\n", nr.Record.CensorshipRecord.Merkle)
-	printf("Receipt: %v\n", nr.Record.CensorshipRecord.Signature)
+	printf("Token  : %v\n", r.CensorshipRecord.Token)
+	printf("Merkle : %v\n", r.CensorshipRecord.Merkle)
+	printf("Receipt: %v\n", r.CensorshipRecord.Signature)
 
-	return &nr.Record, nil
+	return &r, nil
 }
 
 // proposalNewHelpMsg is the printed to stdout by the help command.
@@ -315,6 +333,10 @@ Flags:
  --randomimages (bool)   Generate random attachments. The attachments argument
                          is not allowed when using this flag.
 
+ --draft        (bool)   Save the proposal as a draft. Drafts are only visible
+                         to their author and can be submitted later using the
+                         proposaldraftpromote command.
+
 Examples:
 
 # Set linkby 24 hours from current time
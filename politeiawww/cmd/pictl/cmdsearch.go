@@ -0,0 +1,142 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	rcv1 "github.com/decred/politeia/politeiawww/api/records/v1"
+	pclient "github.com/decred/politeia/politeiawww/client"
+)
+
+// cmdSearch searches the proposal name, index file, and author of all
+// proposals for the provided query.
+type cmdSearch struct {
+	Args struct {
+		Query string `positional-arg-name:"query" required:"true"`
+	} `positional-args:"true"`
+
+	// Filtering options
+	Unvetted bool   `long:"unvetted" optional:"true"`
+	Status   string `long:"status" optional:"true"`
+	UserID   string `long:"userid" optional:"true"`
+	After    string `long:"after" optional:"true"`
+	Before   string `long:"before" optional:"true"`
+	Page     uint32 `long:"page" optional:"true"`
+}
+
+// Execute executes the cmdSearch command.
+//
+// This function satisfies the go-flags Commander interface.
+func (c *cmdSearch) Execute(args []string) error {
+	// Setup client
+	opts := pclient.Opts{
+		HTTPSCert:  cfg.HTTPSCert,
+		Cookies:    cfg.Cookies,
+		HeaderCSRF: cfg.CSRF,
+		Verbose:    cfg.Verbose,
+		RawJSON:    cfg.RawJSON,
+	}
+	pc, err := pclient.New(cfg.Host, opts)
+	if err != nil {
+		return err
+	}
+
+	// Setup the search filters
+	state := rcv1.RecordStateVetted
+	if c.Unvetted {
+		state = rcv1.RecordStateUnvetted
+	}
+	var status rcv1.RecordStatusT
+	if c.Status != "" {
+		// Parse status. This can be either the numeric status code or
+		// the human readable equivalent.
+		status, err = parseRecordStatus(c.Status)
+		if err != nil {
+			return err
+		}
+	}
+	var after, before int64
+	if c.After != "" {
+		after, err = unixFromDate(c.After)
+		if err != nil {
+			return fmt.Errorf("invalid after date: %v", err)
+		}
+	}
+	if c.Before != "" {
+		before, err = unixFromDate(c.Before)
+		if err != nil {
+			return fmt.Errorf("invalid before date: %v", err)
+		}
+
+		// The before date is inclusive. Include the entire day.
+		before += int64((24 * time.Hour).Seconds()) - 1
+	}
+
+	// Search the proposals
+	s := rcv1.Search{
+		Query:  c.Args.Query,
+		State:  state,
+		Status: status,
+		UserID: c.UserID,
+		After:  after,
+		Before: before,
+		Page:   c.Page,
+	}
+	sr, err := pc.RecordSearch(s)
+	if err != nil {
+		return err
+	}
+
+	// Print the results to stdout
+	printf("Total results: %v\n", sr.Total)
+	for _, v := range sr.Results {
+		printf("\n")
+		printSearchResult(v)
+	}
+
+	return nil
+}
+
+// printSearchResult prints a single search result.
+func printSearchResult(r rcv1.SearchResult) {
+	printf("Token    : %v\n", r.Token)
+	printf("Name     : %v\n", r.Name)
+	printf("Author   : %v\n", r.Username)
+	printf("Status   : %v\n", rcv1.RecordStatuses[r.Status])
+	printf("Timestamp: %v\n", dateAndTimeFromUnix(r.Timestamp))
+	printf("Score    : %.4f\n", r.Score)
+}
+
+// searchHelpMsg is printed to stdout by the help command.
+const searchHelpMsg = `search [flags] "query"
+
+Search the name, index file, and author of all proposals. Results are ordered
+by relevance to the query and are returned one page at a time. A user ID can
+be included in the query to search for the proposals of an author.
+
+Public proposals are searched by default. Unvetted proposals can only be
+searched by admins. Censored proposals are not searchable.
+
+Arguments:
+1. query  (string, required) Search query.
+
+Flags:
+ --unvetted (bool, optional)   Search unvetted proposals.
+ --status   (string, optional) Only return proposals with this status. This
+                               can be the numeric status code or the human
+                               readable equivalent.
+ --userid   (string, optional) Only return proposals submitted by this user.
+ --after    (string, optional) Only return proposals that were updated on or
+                               after this date. Format: MM/DD/YYYY
+ --before   (string, optional) Only return proposals that were updated on or
+                               before this date. Format: MM/DD/YYYY
+ --page     (uint32, optional) Page number. Defaults to 1.
+
+Examples:
+$ pictl search "website redesign"
+$ pictl search --status=public --after=01/01/2022 marketing
+`
@@ -69,18 +69,25 @@ type pictl struct {
 	ProposalNew                  cmdProposalNew                  `command:"proposalnew"`
 	ProposalEdit                 cmdProposalEdit                 `command:"proposaledit"`
 	ProposalSetStatus            cmdProposalSetStatus            `command:"proposalsetstatus"`
+	ProposalCensorFiles          cmdProposalCensorFiles          `command:"proposalcensorfiles"`
 	ProposalSetBillingStatus     cmdProposalSetBillingStatus     `command:"proposalsetbillingstatus"`
 	ProposalBillingStatusChanges cmdProposalBillingStatusChanges `command:"proposalbillingstatuschanges"`
 	ProposalDetails              cmdProposalDetails              `command:"proposaldetails"`
 	ProposalTimestamps           cmdProposalTimestamps           `command:"proposaltimestamps"`
+	ProposalDiff                 cmdProposalDiff                 `command:"proposaldiff"`
+	ProposalHistory              cmdProposalHistory              `command:"proposalhistory"`
 	Proposals                    cmdProposals                    `command:"proposals"`
 	ProposalSummaries            cmdProposalSummaries            `command:"proposalsummaries"`
 	ProposalInv                  cmdProposalInv                  `command:"proposalinv"`
 	ProposalInvOrdered           cmdProposalInvOrdered           `command:"proposalinvordered"`
 	UserProposals                cmdUserProposals                `command:"userproposals"`
+	ProposalDraftDetails         cmdProposalDraftDetails         `command:"proposaldraftdetails"`
+	ProposalDraftDel             cmdProposalDraftDel             `command:"proposaldraftdel"`
+	ProposalDraftPromote         cmdProposalDraftPromote         `command:"proposaldraftpromote"`
 
 	// Records commands
 	RecordPolicy cmdRecordPolicy `command:"recordpolicy"`
+	Search       cmdSearch       `command:"search"`
 
 	// Comments commands
 	CommentsPolicy    cmdCommentPolicy     `command:"commentpolicy"`
@@ -164,18 +171,25 @@ Proposal commands
   proposalnew                  (user)   Submit a new proposal
   proposaledit                 (user)   Edit an existing proposal
   proposalsetstatus            (admin)  Set the status of a proposal
+  proposalcensorfiles          (admin)  Censor an individual proposal file
   proposalsetbillingstatus     (admin)  Set the billing status of a proposal
   proposalbillingstatuschanges (public) Get billing status changes
   proposaldetails              (public) Get a full proposal record
   proposaltimestamps           (public) Get timestamps for a proposal
+  proposaldiff                 (public) Get the changes between two versions
+  proposalhistory              (public) Get the history of a proposal
   proposals                    (public) Get proposals without their files
   proposalsummaries            (public) Get proposal summaries
   proposalinv                  (public) Get inventory by proposal status
   proposalinvordered           (public) Get inventory ordered chronologically
   userproposals                (public) Get proposals submitted by a user
+  proposaldraftdetails         (user)   Get a draft proposal
+  proposaldraftdel             (user)   Delete a draft proposal
+  proposaldraftpromote         (user)   Submit a draft proposal
 
 Record commands
   recordpolicy                 (public) Get the records api policy
+  search                       (public) Search proposals
 
 Comment commands
   commentpolicy                (public) Get the comments api policy
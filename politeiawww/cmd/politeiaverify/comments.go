@@ -49,7 +49,13 @@ func verifyCommentsBundle(fp string) error {
 		dels     int
 	)
 	for _, v := range cb.Comments {
-		err := client.CommentVerify(v, cb.ServerPublicKey)
+		keys, err := serverKeysAt(cb.ServerPublicKey, v.Timestamp)
+		if err != nil {
+			return err
+		}
+		err = verifyWithKeys(keys, func(serverPubKey string) error {
+			return client.CommentVerify(v, serverPubKey)
+		})
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,83 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	pdv2 "github.com/decred/politeia/politeiad/api/v2"
+	pdclient "github.com/decred/politeia/politeiad/client"
+	"github.com/decred/politeia/util"
+)
+
+// history contains the politeiad identity history that was provided using
+// the -i flag. It is nil if no identity history was provided.
+var history *pdv2.IdentityHistoryReply
+
+// loadIdentityHistory loads the politeiad identity history file. The file
+// contains the reply of the politeiad identity history route.
+func loadIdentityHistory(fp string) (*pdv2.IdentityHistoryReply, error) {
+	b, err := os.ReadFile(util.CleanAndExpandPath(fp))
+	if err != nil {
+		return nil, err
+	}
+	var ih pdv2.IdentityHistoryReply
+	err = json.Unmarshal(b, &ih)
+	if err != nil {
+		return nil, fmt.Errorf("could not unmarshal identity history: %v", err)
+	}
+	return &ih, nil
+}
+
+// serverKeys returns the server public keys that were valid at any time
+// between the start and the end timestamp, inclusive. The provided server key
+// is returned as is when no identity history was provided. Otherwise, the
+// server key must be part of the identity history.
+func serverKeys(serverPubKey string, start, end int64) ([]string, error) {
+	if history == nil {
+		return []string{serverPubKey}, nil
+	}
+	err := pdclient.IdentityHistoryVerify(serverPubKey, history.PublicKey,
+		history.Transitions)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify identity history: %v", err)
+	}
+	keys := pdclient.IdentityKeys(history.PublicKey, history.Transitions,
+		start, end)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no server key was valid between %v and %v",
+			start, end)
+	}
+	return keys, nil
+}
+
+// serverKeysAt returns the server public keys that were valid at the provided
+// timestamp.
+func serverKeysAt(serverPubKey string, timestamp int64) ([]string, error) {
+	return serverKeys(serverPubKey, timestamp, timestamp)
+}
+
+// serverKeysAll returns all server public keys of the identity history. It is
+// used when the time of the server signature is not known.
+func serverKeysAll(serverPubKey string) ([]string, error) {
+	return serverKeys(serverPubKey, 0, math.MaxInt64)
+}
+
+// verifyWithKeys runs the verify function with each of the server keys until
+// one of them succeeds. The error of the last key is returned if none of them
+// succeed.
+func verifyWithKeys(keys []string, verify func(serverPubKey string) error) error {
+	var err error
+	for _, k := range keys {
+		err = verify(k)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -13,6 +13,7 @@ import (
 	"regexp"
 
 	"github.com/decred/politeia/politeiad/api/v1/identity"
+	"github.com/decred/politeia/politeiad/backendv2"
 	rcv1 "github.com/decred/politeia/politeiawww/api/records/v1"
 	"github.com/decred/politeia/politeiawww/client"
 	"github.com/decred/politeia/util"
@@ -23,6 +24,9 @@ var (
 	publicKey = flag.String("k", "", "server public key")
 	token     = flag.String("t", "", "record censorship token")
 	signature = flag.String("s", "", "record censorship signature")
+	idHistory = flag.String("i", "", "politeiad identity history file")
+	btcHost   = flag.String("b", backendv2.DefaultBitcoinHeaderHost,
+		"esplora api url that bitcoin attestations are verified against")
 )
 
 // loadFiles loads and returns a politeiawww records v1 File for each provided
@@ -94,7 +98,13 @@ func verifyCensorshipRecord(serverPubKey, token, signature string, filepaths []s
 			Signature: signature,
 		},
 	}
-	err = client.RecordVerify(r, pid.String())
+	keys, err := serverKeysAll(pid.String())
+	if err != nil {
+		return err
+	}
+	err = verifyWithKeys(keys, func(serverPubKey string) error {
+		return client.RecordVerify(r, serverPubKey)
+	})
 	if err != nil {
 		return err
 	}
@@ -117,6 +127,7 @@ var (
 	expCommentTimestamps = `^[0-9a-f]{7,16}-comments-timestamps.json$`
 	expVotes             = `^[0-9a-f]{7,16}-votes.json$`
 	expVoteTimestamps    = `^[0-9a-f]{7,16}-votes-timestamps.json$`
+	expSnapshot          = `^[0-9a-f]{7,16}-snapshot.json$`
 
 	regexpJSONFile          = regexp.MustCompile(expJSONFile)
 	regexpRecord            = regexp.MustCompile(expRecord)
@@ -125,6 +136,7 @@ var (
 	regexpCommentTimestamps = regexp.MustCompile(expCommentTimestamps)
 	regexpVotes             = regexp.MustCompile(expVotes)
 	regexpVoteTimestamps    = regexp.MustCompile(expVoteTimestamps)
+	regexpSnapshot          = regexp.MustCompile(expSnapshot)
 )
 
 // verifyFile verifies a data file downloaded from politeiagui. This can be
@@ -138,6 +150,7 @@ var (
 // Comment timestamps: [token]-comments-timestamps.json
 // Votes bundle      : [token]-votes.json
 // Vote timestamps   : [token]-votes-timestamps.json
+// Snapshot proof    : [token]-snapshot.json
 func verifyFile(fp string) error {
 	fp = util.CleanAndExpandPath(fp)
 	filename := filepath.Base(fp)
@@ -156,6 +169,8 @@ func verifyFile(fp string) error {
 		return verifyVotesBundle(fp)
 	case regexpVoteTimestamps.FindString(filename) != "":
 		return verifyVoteTimestamps(fp)
+	case regexpSnapshot.FindString(filename) != "":
+		return verifySnapshotInclusion(fp)
 	}
 
 	return fmt.Errorf("file not recognized")
@@ -169,6 +184,29 @@ func _main() error {
 		return fmt.Errorf("no arguments provided")
 	}
 
+	// Load the politeiad identity history. The server signatures are
+	// verified against the keys that were valid at the time of the
+	// signature when it has been provided.
+	if *idHistory != "" {
+		ih, err := loadIdentityHistory(*idHistory)
+		if err != nil {
+			return err
+		}
+		history = ih
+	}
+
+	// Setup the source of the bitcoin block headers that the
+	// OpenTimestamps attestations are verified against. The
+	// attestations are reported as unverified when it is disabled.
+	if *btcHost != "" {
+		c, err := util.NewHTTPClient(false, "")
+		if err != nil {
+			return err
+		}
+		backendv2.SetBitcoinHeaderSource(
+			backendv2.NewEsploraHeaderSource(*btcHost, c))
+	}
+
 	// Check if the user is trying to verify a record submission
 	// manually. This requires passing in the server public key, the
 	// censorship token, the censorship record signature, and all of
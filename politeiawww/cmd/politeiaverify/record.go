@@ -42,11 +42,33 @@ func verifyRecordBundle(fp string) error {
 	fmt.Printf("  Token      : %v\n", rb.Record.CensorshipRecord.Token)
 	fmt.Printf("  Merkle root: %v\n", rb.Record.CensorshipRecord.Merkle)
 	fmt.Printf("  Signature  : %v\n", rb.Record.CensorshipRecord.Signature)
+	for _, v := range rb.Record.CensoredFiles {
+		fmt.Printf("  Censored   : %v %v\n", v.Name, v.Digest)
+	}
 
-	err = client.RecordVerify(rb.Record, rb.ServerPublicKey)
+	keys, err := serverKeysAt(rb.ServerPublicKey, rb.Record.Timestamp)
+	if err != nil {
+		return err
+	}
+	err = verifyWithKeys(keys, func(serverPubKey string) error {
+		return client.CensorshipRecordSignatureVerify(rb.Record, serverPubKey)
+	})
 	if err != nil {
 		return fmt.Errorf("could not verify record: %v", err)
 	}
+	for _, v := range rb.Record.CensoredFiles {
+		keys, err := serverKeysAt(rb.ServerPublicKey, v.Timestamp)
+		if err != nil {
+			return err
+		}
+		err = verifyWithKeys(keys, func(serverPubKey string) error {
+			return client.CensoredFilesVerify(rb.Record.CensorshipRecord.Token,
+				[]rcv1.CensoredFile{v}, serverPubKey)
+		})
+		if err != nil {
+			return fmt.Errorf("could not verify record: %v", err)
+		}
+	}
 
 	fmt.Printf("Censorship record verified!\n")
 	fmt.Printf("\n")
@@ -0,0 +1,84 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	pdv2 "github.com/decred/politeia/politeiad/api/v2"
+	backendv2 "github.com/decred/politeia/politeiad/backendv2"
+	pdclient "github.com/decred/politeia/politeiad/client"
+)
+
+// verifySnapshotInclusion takes the file path of a politeiad snapshot
+// inclusion proof and verifies that the record was included in the signed
+// inventory snapshot and that the snapshot has been timestamped.
+func verifySnapshotInclusion(fp string) error {
+	// Decode snapshot inclusion proof
+	b, err := os.ReadFile(fp)
+	if err != nil {
+		return err
+	}
+	var si pdv2.SnapshotInclusion
+	err = json.Unmarshal(b, &si)
+	if err != nil {
+		return fmt.Errorf("could not unmarshal snapshot inclusion: %v", err)
+	}
+
+	fmt.Printf("Server public key: %v\n", si.Snapshot.PublicKey)
+	fmt.Printf("Snapshot %v\n", si.Snapshot.Sequence)
+	fmt.Printf("  Timestamp  : %v\n", si.Snapshot.Timestamp)
+	for _, v := range si.Snapshot.Roots {
+		fmt.Printf("  %-11v: %v records %v\n", pdv2.RecordStates[v.State],
+			v.Records, v.MerkleRoot)
+	}
+	fmt.Printf("  Signature  : %v\n", si.Snapshot.Signature)
+	fmt.Printf("Record\n")
+	fmt.Printf("  Token      : %v\n", si.Token)
+	fmt.Printf("  State      : %v\n", pdv2.RecordStates[si.State])
+	fmt.Printf("  Merkle root: %v\n", si.Merkle)
+
+	// Verify that the snapshot key was valid when the snapshot was
+	// taken. This can only be checked when an identity history has
+	// been provided.
+	if history != nil {
+		keys, err := serverKeysAt(si.Snapshot.PublicKey,
+			si.Snapshot.Timestamp)
+		if err != nil {
+			return err
+		}
+		var valid bool
+		for _, v := range keys {
+			if v == si.Snapshot.PublicKey {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("snapshot key was not valid at the " +
+				"snapshot timestamp")
+		}
+	}
+
+	// Verify snapshot inclusion
+	err = pdclient.SnapshotInclusionVerify(si)
+	switch {
+	case errors.Is(err, backendv2.ErrNotTimestamped):
+		return fmt.Errorf("snapshot not anchored yet")
+	case err != nil:
+		return err
+	}
+
+	fmt.Printf("Anchor\n")
+	fmt.Printf("  Merkle root: %v\n", si.Timestamp.MerkleRoot)
+	fmt.Printf("  DCR tx     : %v\n", si.Timestamp.TxID)
+	fmt.Printf("Snapshot inclusion verified!\n")
+	fmt.Printf("The merkle root can be found in the OP_RETURN of the DCR tx.\n")
+
+	return nil
+}
@@ -7,6 +7,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 
 	backend "github.com/decred/politeia/politeiad/backendv2"
@@ -54,7 +55,13 @@ func verifyVotesBundle(fp string) error {
 		fmt.Printf("  Public key: %v\n", v.PublicKey)
 		fmt.Printf("  Signature : %v\n", v.Signature)
 		fmt.Printf("  Receipt   : %v\n", v.Receipt)
-		err = client.AuthDetailsVerify(v, vb.ServerPublicKey)
+		keys, err := serverKeysAt(vb.ServerPublicKey, v.Timestamp)
+		if err != nil {
+			return err
+		}
+		err = verifyWithKeys(keys, func(serverPubKey string) error {
+			return client.AuthDetailsVerify(v, serverPubKey)
+		})
 		if err != nil {
 			return err
 		}
@@ -72,7 +79,24 @@ func verifyVotesBundle(fp string) error {
 	fmt.Printf("  Signature : %v\n", vb.Details.Signature)
 	fmt.Printf("  Receipt   : %v\n", vb.Details.Receipt)
 
-	err = client.VoteDetailsVerify(*vb.Details, vb.ServerPublicKey)
+	// The vote details do not contain a timestamp. The vote was started
+	// after the last authorization and before the first cast vote.
+	var (
+		start = vb.Auths[len(vb.Auths)-1].Timestamp
+		end   = int64(math.MaxInt64)
+	)
+	for _, v := range vb.Votes {
+		if v.Timestamp < end {
+			end = v.Timestamp
+		}
+	}
+	keys, err := serverKeys(vb.ServerPublicKey, start, end)
+	if err != nil {
+		return err
+	}
+	err = verifyWithKeys(keys, func(serverPubKey string) error {
+		return client.VoteDetailsVerify(*vb.Details, serverPubKey)
+	})
 	if err != nil {
 		return err
 	}
@@ -100,7 +124,13 @@ func verifyVotesBundle(fp string) error {
 		"valid signatures...\n")
 
 	for _, v := range vb.Votes {
-		err := client.CastVoteDetailsVerify(v, vb.ServerPublicKey)
+		keys, err := serverKeysAt(vb.ServerPublicKey, v.Timestamp)
+		if err != nil {
+			return err
+		}
+		err = verifyWithKeys(keys, func(serverPubKey string) error {
+			return client.CastVoteDetailsVerify(v, serverPubKey)
+		})
 		if err != nil {
 			return fmt.Errorf("could not verify vote %v: %v",
 				v.Ticket, err)
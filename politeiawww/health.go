@@ -0,0 +1,63 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	pdclient "github.com/decred/politeia/politeiad/client"
+)
+
+const (
+	// healthPoliteiad is the name of the politeiad readiness check.
+	healthPoliteiad = "politeiad"
+
+	// healthUserDB is the name of the user database readiness check.
+	healthUserDB = "userdb"
+
+	// politeiadRetryInterval is the amount of time that is waited before
+	// checking whether politeiad is ready again during startup.
+	politeiadRetryInterval = 15 * time.Second
+)
+
+// waitForPoliteiad blocks until politeiad is ready to serve requests. The
+// politeiad error is reported by the readiness route in the meantime. False
+// is returned if a signal is received while waiting.
+func (p *politeiawww) waitForPoliteiad(pdc *pdclient.Client, sigs <-chan os.Signal) bool {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(),
+			politeiadRetryInterval)
+		err := pdc.Ready(ctx)
+		cancel()
+		if err == nil {
+			return true
+		}
+
+		log.Errorf("politeiad is not ready: %v; retry in %v",
+			err, politeiadRetryInterval)
+
+		select {
+		case <-time.After(politeiadRetryInterval):
+		case sig := <-sigs:
+			log.Infof("Terminating with %v", sig)
+			return false
+		}
+	}
+}
+
+// setupUserDBHealth registers the user database readiness check. The legacy
+// server manages its own user database.
+func (p *politeiawww) setupUserDBHealth() {
+	switch {
+	case p.legacy != nil:
+		p.health.Register(healthUserDB, func(ctx context.Context) error {
+			return p.legacy.PingUserDB()
+		})
+	case p.db != nil:
+		p.health.Register(healthUserDB, p.db.PingContext)
+	}
+}
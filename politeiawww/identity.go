@@ -7,8 +7,10 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -17,6 +19,7 @@ import (
 
 	v1 "github.com/decred/politeia/politeiad/api/v1"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
+	pdclient "github.com/decred/politeia/politeiad/client"
 	"github.com/decred/politeia/util"
 )
 
@@ -118,3 +121,28 @@ func remoteIdentity(skipTLSVerify bool, host, cert string) (*identity.PublicIden
 
 	return identity, nil
 }
+
+// updateIdentity follows the identity history of politeiad from the pinned
+// identity to the current politeiad identity. The history must contain a
+// chain of cross-signed transitions that starts at the pinned identity. The
+// client identity is updated to the current identity and the current identity
+// is returned. The pinned identity is returned unchanged if politeiad does not
+// serve an identity history, e.g. when it runs the git backend.
+func updateIdentity(ctx context.Context, pdc *pdclient.Client, pinned *identity.PublicIdentity) (*identity.PublicIdentity, error) {
+	pid, err := pdc.IdentityUpdate(ctx)
+	if err != nil {
+		var re pdclient.RespError
+		if errors.As(err, &re) && re.HTTPCode == http.StatusNotFound {
+			log.Warnf("politeiad identity history not found; using the " +
+				"pinned identity")
+			return pinned, nil
+		}
+		return nil, err
+	}
+	if pid.String() != pinned.String() {
+		log.Infof("politeiad identity has been rotated")
+		log.Infof("Key        : %x", pid.Key)
+		log.Infof("Fingerprint: %v", pid.Fingerprint())
+	}
+	return pid, nil
+}
@@ -390,7 +390,7 @@ func (p *Politeiawww) updateInvoicePayment(ctx context.Context, payment *databas
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return err
 	}
@@ -455,7 +455,7 @@ func (p *Politeiawww) invoiceStatusPaid(ctx context.Context, token, key string)
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return err
 	}
@@ -658,11 +658,21 @@ func convertTimestamp(t comments.Timestamp) v1.Timestamp {
 	for _, v := range t.Proofs {
 		proofs = append(proofs, convertProof(v))
 	}
+	anchors := make([]v1.TimestampAnchor, 0, len(t.Anchors))
+	for _, v := range t.Anchors {
+		anchors = append(anchors, v1.TimestampAnchor{
+			Provider:   v.Provider,
+			TxID:       v.TxID,
+			MerkleRoot: v.MerkleRoot,
+			Proof:      convertProof(v.Proof),
+		})
+	}
 	return v1.Timestamp{
 		Data:       t.Data,
 		Digest:     t.Digest,
 		TxID:       t.TxID,
 		MerkleRoot: t.MerkleRoot,
 		Proofs:     proofs,
+		Anchors:    anchors,
 	}
 }
@@ -485,7 +485,7 @@ func (p *Politeiawww) processNewDCC(ctx context.Context, nd cms.NewDCC, u *user.
 	}
 
 	// Verify NewRecord challenge
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -536,7 +536,7 @@ func (p *Politeiawww) processNewDCC(ctx context.Context, nd cms.NewDCC, u *user.
 	}
 
 	// Verify the SetUnvettedStatus challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge,
+	err = p.politeiad.VerifyChallenge(ctx, challenge,
 		pdSetUnvettedStatusReply.Response)
 	if err != nil {
 		return nil, err
@@ -990,7 +990,7 @@ func (p *Politeiawww) processSupportOpposeDCC(ctx context.Context, sd cms.Suppor
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1135,7 +1135,7 @@ func (p *Politeiawww) processNewCommentDCC(ctx context.Context, nc www.NewCommen
 			"PluginCommandReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1325,7 +1325,7 @@ func (p *Politeiawww) processSetDCCStatus(ctx context.Context, sds cms.SetDCCSta
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1469,7 +1469,7 @@ func (p *Politeiawww) processCastVoteDCC(ctx context.Context, cv cms.CastVote, u
 	}
 
 	// Verify the challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1545,7 +1545,7 @@ func (p *Politeiawww) cmsVoteDetails(ctx context.Context, token string) (*cmsplu
 		return nil, fmt.Errorf("could not unmarshal "+
 			"PluginCommandReply: %v", err)
 	}
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1591,7 +1591,7 @@ func (p *Politeiawww) cmsVoteSummary(ctx context.Context, token string) (*cmsplu
 		return nil, fmt.Errorf("could not unmarshal "+
 			"PluginCommandReply: %v", err)
 	}
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1632,7 +1632,7 @@ func (p *Politeiawww) processActiveVoteDCC(ctx context.Context) (*cms.ActiveVote
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1911,7 +1911,7 @@ func (p *Politeiawww) processStartVoteDCC(ctx context.Context, sv cms.StartVote,
 		return nil, fmt.Errorf("could not unmarshal "+
 			"PluginCommandReply: %v", err)
 	}
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -717,7 +717,7 @@ func (p *Politeiawww) processNewInvoice(ctx context.Context, ni cms.NewInvoice,
 	}
 
 	// Verify NewRecord challenge
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -768,7 +768,7 @@ func (p *Politeiawww) processNewInvoice(ctx context.Context, ni cms.NewInvoice,
 	}
 
 	// Verify the SetUnvettedStatus challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge,
+	err = p.politeiad.VerifyChallenge(ctx, challenge,
 		pdSetUnvettedStatusReply.Response)
 	if err != nil {
 		return nil, err
@@ -1303,7 +1303,7 @@ func (p *Politeiawww) processSetInvoiceStatus(ctx context.Context, sis cms.SetIn
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1556,7 +1556,7 @@ func (p *Politeiawww) processEditInvoice(ctx context.Context, ei cms.EditInvoice
 		return nil, fmt.Errorf("Unmarshal UpdateUnvettedReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -1603,7 +1603,7 @@ func (p *Politeiawww) processEditInvoice(ctx context.Context, ei cms.EditInvoice
 	}
 
 	// Verify the UpdateVettedMetadata challenge.
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, updateMetaReply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, updateMetaReply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -2001,7 +2001,7 @@ func (p *Politeiawww) processNewCommentInvoice(ctx context.Context, nc www.NewCo
 			"PluginCommandReply: %v", err)
 	}
 
-	err = util.VerifyChallenge(p.cfg.Identity, challenge, reply.Response)
+	err = p.politeiad.VerifyChallenge(ctx, challenge, reply.Response)
 	if err != nil {
 		return nil, err
 	}
@@ -2176,7 +2176,7 @@ func (p *Politeiawww) processPayInvoices(ctx context.Context, u *user.User) (*cm
 		}
 
 		// Verify the UpdateVettedMetadata challenge.
-		err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+		err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 		if err != nil {
 			return nil, err
 		}
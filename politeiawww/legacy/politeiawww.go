@@ -211,6 +211,11 @@ func (p *Politeiawww) Close() {
 	}
 }
 
+// PingUserDB verifies that the user database is reachable.
+func (p *Politeiawww) PingUserDB() error {
+	return p.db.Ping()
+}
+
 // Setup performs any required setup for Politeiawww.
 func (p *Politeiawww) setup() error {
 	// Setup email-userID cache
@@ -385,7 +390,7 @@ func (p *Politeiawww) setupCMS() error {
 			}
 
 			// Verify the UpdateVettedMetadata challenge.
-			err = util.VerifyChallenge(p.cfg.Identity, challenge, pdReply.Response)
+			err = p.politeiad.VerifyChallenge(ctx, challenge, pdReply.Response)
 			if err != nil {
 				return err
 			}
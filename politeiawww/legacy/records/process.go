@@ -12,11 +12,13 @@ import (
 	"time"
 
 	pdv2 "github.com/decred/politeia/politeiad/api/v2"
+	"github.com/decred/politeia/politeiad/plugins/search"
 	"github.com/decred/politeia/politeiad/plugins/usermd"
 	v1 "github.com/decred/politeia/politeiawww/api/records/v1"
 	"github.com/decred/politeia/politeiawww/client"
 	"github.com/decred/politeia/politeiawww/config"
 	"github.com/decred/politeia/politeiawww/legacy/user"
+	"github.com/decred/politeia/util"
 	"github.com/google/uuid"
 )
 
@@ -42,22 +44,10 @@ func (r *Records) processNew(ctx context.Context, n v1.New, u user.User) (*v1.Ne
 	}
 
 	// Setup metadata stream
-	um := usermd.UserMetadata{
-		UserID:    u.ID.String(),
-		PublicKey: n.PublicKey,
-		Signature: n.Signature,
-	}
-	b, err := json.Marshal(um)
+	metadata, err := userMetadataStreams(u, n.PublicKey, n.Signature)
 	if err != nil {
 		return nil, err
 	}
-	metadata := []pdv2.MetadataStream{
-		{
-			PluginID: usermd.PluginID,
-			StreamID: usermd.StreamIDUserMetadata,
-			Payload:  string(b),
-		},
-	}
 
 	// Save record to politeiad
 	f := convertFilesToPD(n.Files)
@@ -144,22 +134,10 @@ func (r *Records) processEdit(ctx context.Context, e v1.Edit, u user.User) (*v1.
 	filesDel := filesToDel(curr.Files, e.Files)
 
 	// Setup metadata
-	um := usermd.UserMetadata{
-		UserID:    u.ID.String(),
-		PublicKey: e.PublicKey,
-		Signature: e.Signature,
-	}
-	b, err := json.Marshal(um)
+	mdOverwrite, err := userMetadataStreams(u, e.PublicKey, e.Signature)
 	if err != nil {
 		return nil, err
 	}
-	mdOverwrite := []pdv2.MetadataStream{
-		{
-			PluginID: usermd.PluginID,
-			StreamID: usermd.StreamIDUserMetadata,
-			Payload:  string(b),
-		},
-	}
 	mdAppend := []pdv2.MetadataStream{}
 
 	// Save update to politeiad
@@ -245,6 +223,60 @@ func (r *Records) processSetStatus(ctx context.Context, ss v1.SetStatus, u user.
 	}, nil
 }
 
+func (r *Records) processCensorFiles(ctx context.Context, cf v1.CensorFiles, u user.User) (*v1.CensorFilesReply, error) {
+	log.Tracef("processCensorFiles: %v %v %v", cf.Token, cf.Files, cf.Reason)
+
+	// Verify user signed using active identity
+	if u.PublicKey() != cf.PublicKey {
+		return nil, v1.UserErrorReply{
+			ErrorCode:    v1.ErrorCodePublicKeyInvalid,
+			ErrorContext: "not active identity",
+		}
+	}
+
+	// Verify request
+	if len(cf.Files) == 0 {
+		return nil, v1.UserErrorReply{
+			ErrorCode: v1.ErrorCodeFilesEmpty,
+		}
+	}
+	if cf.Reason == "" {
+		return nil, v1.UserErrorReply{
+			ErrorCode:    v1.ErrorCodeInputInvalid,
+			ErrorContext: "reason not found",
+		}
+	}
+	err := util.VerifySignature(cf.Signature, cf.PublicKey,
+		client.CensorFilesMsg(cf))
+	if err != nil {
+		return nil, v1.UserErrorReply{
+			ErrorCode:    v1.ErrorCodeSignatureInvalid,
+			ErrorContext: err.Error(),
+		}
+	}
+
+	// Send politeiad request
+	files := make([]pdv2.CensorFile, 0, len(cf.Files))
+	for _, v := range cf.Files {
+		files = append(files, pdv2.CensorFile{
+			Version: v.Version,
+			Name:    v.Name,
+		})
+	}
+	pdcf, err := r.politeiad.RecordCensorFiles(ctx, cf.Token,
+		files, cf.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Record files censored %v by %v: %v",
+		cf.Token, u.Username, len(pdcf))
+
+	return &v1.CensorFilesReply{
+		CensoredFiles: convertCensoredFilesToV1(pdcf),
+	}, nil
+}
+
 func (r *Records) processDetails(ctx context.Context, d v1.Details, u *user.User) (*v1.DetailsReply, error) {
 	log.Tracef("processDetails: %v %v", d.Token, d.Version)
 
@@ -335,6 +367,84 @@ func (r *Records) processTimestamps(ctx context.Context, t v1.Timestamps, isAdmi
 	}, nil
 }
 
+func (r *Records) processDiff(ctx context.Context, d v1.Diff, u *user.User) (*v1.DiffReply, error) {
+	log.Tracef("processDiff: %v %v %v", d.Token, d.FromVersion, d.ToVersion)
+
+	// Get record diff
+	rd, err := r.politeiad.RecordDiff(ctx, d.Token, d.FromVersion,
+		d.ToVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the record. We need to know the record state and the
+	// record author.
+	reqs := []pdv2.RecordRequest{
+		{
+			Token:        d.Token,
+			OmitAllFiles: true,
+		},
+	}
+	rcs, err := r.records(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	rc, ok := rcs[d.Token]
+	if !ok {
+		return nil, v1.UserErrorReply{
+			ErrorCode: v1.ErrorCodeRecordNotFound,
+		}
+	}
+
+	dr := v1.DiffReply{
+		FromVersion:    rd.FromVersion,
+		ToVersion:      rd.ToVersion,
+		RecordMetadata: convertRecordMetadataDiffsToV1(rd.RecordMetadata),
+		Metadata:       convertMetadataStreamDiffsToV1(rd.Metadata),
+		Files:          convertFileDiffsToV1(rd.Files),
+	}
+
+	// Only admins and the record author are allowed to retrieve
+	// unvetted record files. Remove the file changes if the user is
+	// not an admin or the author. This is a public route so a user
+	// may not exist.
+	if rc.State != v1.RecordStateVetted {
+		var (
+			authorID = userIDFromMetadataStreams(rc.Metadata)
+			isAuthor = u != nil && u.ID.String() == authorID
+			isAdmin  = u != nil && u.Admin
+		)
+		if !isAuthor && !isAdmin {
+			dr.Files = []v1.FileDiff{}
+		}
+	}
+
+	return &dr, nil
+}
+
+func (r *Records) processHistory(ctx context.Context, h v1.History) (*v1.HistoryReply, error) {
+	log.Tracef("processHistory: %v", h.Token)
+
+	// Get record history. The history only contains record metadata
+	// and metadata streams, which are public for all records.
+	entries, err := r.politeiad.RecordHistory(ctx, h.Token)
+	if err != nil {
+		return nil, err
+	}
+	history := make([]v1.HistoryEntry, 0, len(entries))
+	for _, v := range entries {
+		e, err := convertHistoryEntryToV1(v)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *e)
+	}
+
+	return &v1.HistoryReply{
+		History: history,
+	}, nil
+}
+
 func (r *Records) processRecords(ctx context.Context, rs v1.Records, u *user.User) (*v1.RecordsReply, error) {
 	log.Tracef("processRecords: %v reqs", len(rs.Requests))
 
@@ -460,6 +570,12 @@ func (r *Records) processUserRecords(ctx context.Context, ur v1.UserRecords, u *
 		return nil, err
 	}
 
+	// Draft tokens are only returned to the author
+	var drafts []string
+	if u != nil && ur.UserID == u.ID.String() {
+		drafts = urr.Drafts
+	}
+
 	// Determine if unvetted tokens should be returned
 	switch {
 	case u == nil:
@@ -477,6 +593,256 @@ func (r *Records) processUserRecords(ctx context.Context, ur v1.UserRecords, u *
 	return &v1.UserRecordsReply{
 		Unvetted: urr.Unvetted,
 		Vetted:   urr.Vetted,
+		Drafts:   drafts,
+	}, nil
+}
+
+func (r *Records) processSearch(ctx context.Context, s v1.Search, u *user.User) (*v1.SearchReply, error) {
+	log.Tracef("processSearch: %q %v %v", s.Query, s.State, s.Page)
+
+	// Verify state. Vetted records are searched by default.
+	state := pdv2.RecordStateVetted
+	if s.State != v1.RecordStateInvalid {
+		state = convertStateToPD(s.State)
+		if state == pdv2.RecordStateInvalid {
+			return nil, v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeRecordStateInvalid,
+			}
+		}
+	}
+
+	// Verify status filter
+	var status pdv2.RecordStatusT
+	if s.Status != v1.RecordStatusInvalid {
+		status = convertStatusToPD(s.Status)
+		if status == pdv2.RecordStatusInvalid {
+			return nil, v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeRecordStatusInvalid,
+			}
+		}
+	}
+
+	// Only admins are allowed to search unvetted records. This is a
+	// public route so a user may or may not exist.
+	isAdmin := u != nil && u.Admin
+	if state == pdv2.RecordStateUnvetted && !isAdmin {
+		return &v1.SearchReply{
+			Results: []v1.SearchResult{},
+		}, nil
+	}
+
+	// Send plugin command
+	sr, err := r.politeiad.Search(ctx, search.Search{
+		Query:    s.Query,
+		Unvetted: state == pdv2.RecordStateUnvetted,
+		Status:   uint32(status),
+		UserID:   s.UserID,
+		After:    s.After,
+		Before:   s.Before,
+		Page:     s.Page,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare reply. The username of each author is only looked up
+	// once.
+	var (
+		results   = make([]v1.SearchResult, 0, len(sr.Results))
+		usernames = make(map[string]string, len(sr.Results))
+	)
+	for _, v := range sr.Results {
+		username, ok := usernames[v.UserID]
+		if !ok {
+			uid, err := uuid.Parse(v.UserID)
+			if err != nil {
+				return nil, err
+			}
+			u, err := r.userdb.UserGetById(uid)
+			if err != nil {
+				return nil, err
+			}
+			username = u.Username
+			usernames[v.UserID] = username
+		}
+		results = append(results, v1.SearchResult{
+			Token:     v.Token,
+			Name:      v.Name,
+			UserID:    v.UserID,
+			Username:  username,
+			Status:    convertStatusToV1(pdv2.RecordStatusT(v.Status)),
+			Timestamp: v.Timestamp,
+			Score:     v.Score,
+		})
+	}
+
+	return &v1.SearchReply{
+		Results: results,
+		Total:   sr.Total,
+	}, nil
+}
+
+func (r *Records) processDraftNew(ctx context.Context, dn v1.DraftNew, u user.User) (*v1.DraftNewReply, error) {
+	log.Tracef("processDraftNew: %v", u.Username)
+
+	// Verify user signed using active identity
+	if u.PublicKey() != dn.PublicKey {
+		return nil, v1.UserErrorReply{
+			ErrorCode:    v1.ErrorCodePublicKeyInvalid,
+			ErrorContext: "not active identity",
+		}
+	}
+
+	// Setup metadata stream
+	metadata, err := userMetadataStreams(u, dn.PublicKey, dn.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save draft to politeiad
+	f := convertFilesToPD(dn.Files)
+	pdr, err := r.politeiad.DraftNew(ctx, metadata, f)
+	if err != nil {
+		return nil, err
+	}
+	d := convertRecordToV1(*pdr)
+	recordPopulateUserData(&d, u)
+
+	log.Infof("Draft saved: %v", d.CensorshipRecord.Token)
+
+	return &v1.DraftNewReply{
+		Record: d,
+	}, nil
+}
+
+func (r *Records) processDraftEdit(ctx context.Context, de v1.DraftEdit, u user.User) (*v1.DraftEditReply, error) {
+	log.Tracef("processDraftEdit: %v %v", de.Token, u.Username)
+
+	// Verify user signed using active identity
+	if u.PublicKey() != de.PublicKey {
+		return nil, v1.UserErrorReply{
+			ErrorCode:    v1.ErrorCodePublicKeyInvalid,
+			ErrorContext: "not active identity",
+		}
+	}
+
+	// Get current draft
+	curr, err := r.draft(ctx, de.Token, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup files
+	filesAdd := convertFilesToPD(de.Files)
+	filesDel := filesToDel(curr.Files, de.Files)
+
+	// Setup metadata
+	mdOverwrite, err := userMetadataStreams(u, de.PublicKey, de.Signature)
+	if err != nil {
+		return nil, err
+	}
+	mdAppend := []pdv2.MetadataStream{}
+
+	// Save update to politeiad
+	pdr, err := r.politeiad.DraftEdit(ctx, de.Token, mdAppend,
+		mdOverwrite, filesAdd, filesDel)
+	if err != nil {
+		return nil, err
+	}
+	d := convertRecordToV1(*pdr)
+	recordPopulateUserData(&d, u)
+
+	log.Infof("Draft edited: %v", d.CensorshipRecord.Token)
+
+	return &v1.DraftEditReply{
+		Record: d,
+	}, nil
+}
+
+func (r *Records) processDraftDel(ctx context.Context, dd v1.DraftDel, u user.User) (*v1.DraftDelReply, error) {
+	log.Tracef("processDraftDel: %v %v", dd.Token, u.Username)
+
+	// Verify the user is the draft author
+	_, err := r.draft(ctx, dd.Token, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Delete draft
+	err = r.politeiad.DraftDel(ctx, dd.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Draft deleted: %v", dd.Token)
+
+	return &v1.DraftDelReply{}, nil
+}
+
+func (r *Records) processDraftDetails(ctx context.Context, dd v1.DraftDetails, u user.User) (*v1.DraftDetailsReply, error) {
+	log.Tracef("processDraftDetails: %v %v", dd.Token, u.Username)
+
+	d, err := r.draft(ctx, dd.Token, u)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v1.DraftDetailsReply{
+		Record: *d,
+	}, nil
+}
+
+func (r *Records) processDraftPromote(ctx context.Context, dp v1.DraftPromote, u user.User) (*v1.DraftPromoteReply, error) {
+	log.Tracef("processDraftPromote: %v %v", dp.Token, u.Username)
+
+	// Verify the user is the draft author
+	_, err := r.draft(ctx, dp.Token, u)
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute pre plugin hooks. Checking the mode is a temporary
+	// measure until user plugins have been properly implemented.
+	switch r.cfg.Mode {
+	case config.PiWWWMode:
+		err := r.piHookNewRecordPre(u)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Submit draft as a new record
+	pdr, err := r.politeiad.DraftPromote(ctx, dp.Token)
+	if err != nil {
+		return nil, err
+	}
+	rc := convertRecordToV1(*pdr)
+	recordPopulateUserData(&rc, u)
+
+	log.Infof("Draft %v submitted: %v", dp.Token, rc.CensorshipRecord.Token)
+	for k, f := range rc.Files {
+		log.Debugf("%02v: %v", k, f.Name)
+	}
+
+	// Execute post plugin hooks. Checking the mode is a temporary
+	// measure until user plugins have been properly implemented.
+	switch r.cfg.Mode {
+	case config.PiWWWMode:
+		err := r.piHookNewRecordPost(u, rc.CensorshipRecord.Token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Emit event
+	r.events.Emit(EventTypeNew,
+		EventNew{
+			User:   u,
+			Record: rc,
+		})
+
+	return &v1.DraftPromoteReply{
+		Record: rc,
 	}, nil
 }
 
@@ -524,6 +890,45 @@ func (r *Records) record(ctx context.Context, token string, version uint32) (*v1
 	return &rc, nil
 }
 
+// draft returns a draft record from politeiad. A record not found error is
+// returned if the draft does not exist or if the user is not the draft author
+// so that the existence of a draft is not revealed to other users.
+func (r *Records) draft(ctx context.Context, token string, u user.User) (*v1.Record, error) {
+	pdr, err := r.politeiad.Draft(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	d := convertRecordToV1(*pdr)
+	if userIDFromMetadataStreams(d.Metadata) != u.ID.String() {
+		return nil, v1.UserErrorReply{
+			ErrorCode: v1.ErrorCodeRecordNotFound,
+		}
+	}
+	recordPopulateUserData(&d, u)
+	return &d, nil
+}
+
+// userMetadataStreams returns the politeiad metadata streams that contain the
+// usermd UserMetadata for a record submitted by the provided user.
+func userMetadataStreams(u user.User, publicKey, signature string) ([]pdv2.MetadataStream, error) {
+	um := usermd.UserMetadata{
+		UserID:    u.ID.String(),
+		PublicKey: publicKey,
+		Signature: signature,
+	}
+	b, err := json.Marshal(um)
+	if err != nil {
+		return nil, err
+	}
+	return []pdv2.MetadataStream{
+		{
+			PluginID: usermd.PluginID,
+			StreamID: usermd.StreamIDUserMetadata,
+			Payload:  string(b),
+		},
+	}, nil
+}
+
 // convertRecordToV1 converts a politeiad's Record to a records API Record,
 // then it populates the user data.
 func (r *Records) convertRecordToV1(pdr pdv2.Record) (*v1.Record, error) {
@@ -569,6 +974,8 @@ func convertStateToV1(s pdv2.RecordStateT) v1.RecordStateT {
 		return v1.RecordStateUnvetted
 	case pdv2.RecordStateVetted:
 		return v1.RecordStateVetted
+	case pdv2.RecordStateDraft:
+		return v1.RecordStateDraft
 	}
 	return v1.RecordStateInvalid
 }
@@ -612,18 +1019,39 @@ func convertMetadataStreamsToV1(ms []pdv2.MetadataStream) []v1.MetadataStream {
 	return metadata
 }
 
+func convertCensoredFilesToV1(f []pdv2.CensoredFile) []v1.CensoredFile {
+	files := make([]v1.CensoredFile, 0, len(f))
+	for _, v := range f {
+		files = append(files, v1.CensoredFile{
+			Version:   v.Version,
+			Name:      v.Name,
+			MIME:      v.MIME,
+			Digest:    v.Digest,
+			Reason:    v.Reason,
+			Timestamp: v.Timestamp,
+			Signature: v.Signature,
+		})
+	}
+	return files
+}
+
 func convertRecordToV1(r pdv2.Record) v1.Record {
 	// User fields that are not part of the politeiad record have
 	// been intentionally left blank. These fields must be pulled
 	// from the user database.
+	var censored []v1.CensoredFile
+	if len(r.CensoredFiles) > 0 {
+		censored = convertCensoredFilesToV1(r.CensoredFiles)
+	}
 	return v1.Record{
-		State:     convertStateToV1(r.State),
-		Status:    convertStatusToV1(r.Status),
-		Version:   r.Version,
-		Timestamp: r.Timestamp,
-		Username:  "", // Intentionally left blank
-		Metadata:  convertMetadataStreamsToV1(r.Metadata),
-		Files:     convertFilesToV1(r.Files),
+		State:         convertStateToV1(r.State),
+		Status:        convertStatusToV1(r.Status),
+		Version:       r.Version,
+		Timestamp:     r.Timestamp,
+		Username:      "", // Intentionally left blank
+		Metadata:      convertMetadataStreamsToV1(r.Metadata),
+		Files:         convertFilesToV1(r.Files),
+		CensoredFiles: censored,
 		CensorshipRecord: v1.CensorshipRecord{
 			Token:     r.CensorshipRecord.Token,
 			Merkle:    r.CensorshipRecord.Merkle,
@@ -647,13 +1075,96 @@ func convertTimestampToV1(t pdv2.Timestamp) v1.Timestamp {
 	for _, v := range t.Proofs {
 		proofs = append(proofs, convertProofToV1(v))
 	}
+	anchors := make([]v1.TimestampAnchor, 0, len(t.Anchors))
+	for _, v := range t.Anchors {
+		anchors = append(anchors, v1.TimestampAnchor{
+			Provider:   v.Provider,
+			TxID:       v.TxID,
+			MerkleRoot: v.MerkleRoot,
+			Proof:      convertProofToV1(v.Proof),
+		})
+	}
 	return v1.Timestamp{
 		Data:       t.Data,
 		Digest:     t.Digest,
 		TxID:       t.TxID,
 		MerkleRoot: t.MerkleRoot,
 		Proofs:     proofs,
+		Anchors:    anchors,
+	}
+}
+
+func convertRecordMetadataDiffsToV1(diffs []pdv2.RecordMetadataDiff) []v1.RecordMetadataDiff {
+	d := make([]v1.RecordMetadataDiff, 0, len(diffs))
+	for _, v := range diffs {
+		d = append(d, v1.RecordMetadataDiff{
+			Field: v.Field,
+			From:  v.From,
+			To:    v.To,
+		})
+	}
+	return d
+}
+
+func convertMetadataStreamDiffsToV1(diffs []pdv2.MetadataStreamDiff) []v1.MetadataStreamDiff {
+	d := make([]v1.MetadataStreamDiff, 0, len(diffs))
+	for _, v := range diffs {
+		d = append(d, v1.MetadataStreamDiff{
+			Action:      v1.DiffActionT(v.Action),
+			PluginID:    v.PluginID,
+			StreamID:    v.StreamID,
+			FromPayload: v.FromPayload,
+			ToPayload:   v.ToPayload,
+		})
+	}
+	return d
+}
+
+func convertFileDiffsToV1(diffs []pdv2.FileDiff) []v1.FileDiff {
+	d := make([]v1.FileDiff, 0, len(diffs))
+	for _, v := range diffs {
+		d = append(d, v1.FileDiff{
+			Action:     v1.DiffActionT(v.Action),
+			Name:       v.Name,
+			FromMIME:   v.FromMIME,
+			ToMIME:     v.ToMIME,
+			FromDigest: v.FromDigest,
+			ToDigest:   v.ToDigest,
+			Censored:   v.Censored,
+			LineDiff:   v.LineDiff,
+		})
 	}
+	return d
+}
+
+// convertHistoryEntryToV1 converts a politeiad record history entry to a
+// records v1 history entry. The politeiad entry only contains the metadata
+// streams that were modified by the iteration, so the status changes stream
+// is only present when the iteration changed the record status.
+func convertHistoryEntryToV1(e pdv2.RecordHistoryEntry) (*v1.HistoryEntry, error) {
+	he := v1.HistoryEntry{
+		State:      convertStateToV1(e.State),
+		Status:     convertStatusToV1(e.Status),
+		Version:    e.Version,
+		Iteration:  e.Iteration,
+		Timestamp:  e.Timestamp,
+		Merkle:     e.Merkle,
+		Anchored:   e.Anchored,
+		AnchorTxID: e.AnchorTxID,
+	}
+
+	// The status changes are appended to the status changes metadata
+	// stream. The last status change is the one that was made by this
+	// iteration.
+	sc, err := client.StatusChangesDecode(convertMetadataStreamsToV1(e.Metadata))
+	if err != nil {
+		return nil, err
+	}
+	if len(sc) > 0 && sc[len(sc)-1].Status == he.Status {
+		he.StatusChange = &sc[len(sc)-1]
+	}
+
+	return &he, nil
 }
 
 func convertFilesToPD(f []v1.File) []pdv2.File {
@@ -675,6 +1186,8 @@ func convertStateToPD(s v1.RecordStateT) pdv2.RecordStateT {
 		return pdv2.RecordStateUnvetted
 	case v1.RecordStateVetted:
 		return pdv2.RecordStateVetted
+	case v1.RecordStateDraft:
+		return pdv2.RecordStateDraft
 	}
 	return pdv2.RecordStateInvalid
 }
@@ -127,6 +127,38 @@ func (c *Records) HandleSetStatus(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, ssr)
 }
 
+// HandleCensorFiles is the request handler for the records v1 CensorFiles
+// route.
+func (c *Records) HandleCensorFiles(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleCensorFiles")
+
+	var cf v1.CensorFiles
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&cf); err != nil {
+		respondWithError(w, r, "HandleCensorFiles: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	u, err := c.sessions.GetSessionUser(w, r)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleCensorFiles: GetSessionUser: %v", err)
+		return
+	}
+
+	cfr, err := c.processCensorFiles(r.Context(), cf, *u)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleCensorFiles: processCensorFiles: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, cfr)
+}
+
 // HandleDetails is the request handler for the records v1 Details route.
 func (c *Records) HandleDetails(w http.ResponseWriter, r *http.Request) {
 	log.Tracef("HandleDetails")
@@ -194,6 +226,63 @@ func (c *Records) HandleTimestamps(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, tr)
 }
 
+// HandleDiff is the request handler for the records v1 Diff route.
+func (c *Records) HandleDiff(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleDiff")
+
+	var d v1.Diff
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&d); err != nil {
+		respondWithError(w, r, "HandleDiff: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	// Lookup session user. This is a public route so a session may not
+	// exist. Ignore any session not found errors.
+	u, err := c.sessions.GetSessionUser(w, r)
+	if err != nil && err != sessions.ErrSessionNotFound {
+		respondWithError(w, r,
+			"HandleDiff: GetSessionUser: %v", err)
+		return
+	}
+
+	dr, err := c.processDiff(r.Context(), d, u)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDiff: processDiff: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, dr)
+}
+
+// HandleHistory is the request handler for the records v1 History route.
+func (c *Records) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleHistory")
+
+	var h v1.History
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&h); err != nil {
+		respondWithError(w, r, "HandleHistory: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	hr, err := c.processHistory(r.Context(), h)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleHistory: processHistory: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, hr)
+}
+
 // HandleRecords is the request handler for the records v1 Records route.
 func (c *Records) HandleRecords(w http.ResponseWriter, r *http.Request) {
 	log.Tracef("HandleRecords")
@@ -328,6 +417,194 @@ func (c *Records) HandleUserRecords(w http.ResponseWriter, r *http.Request) {
 	util.RespondWithJSON(w, http.StatusOK, urr)
 }
 
+// HandleSearch is the request handler for the records v1 Search route.
+func (c *Records) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleSearch")
+
+	var s v1.Search
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&s); err != nil {
+		respondWithError(w, r, "HandleSearch: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	// Lookup session user. This is a public route so a session may not
+	// exist. Ignore any session not found error.
+	u, err := c.sessions.GetSessionUser(w, r)
+	if err != nil && err != sessions.ErrSessionNotFound {
+		respondWithError(w, r,
+			"HandleSearch: GetSessionUser: %v", err)
+		return
+	}
+
+	sr, err := c.processSearch(r.Context(), s, u)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleSearch: processSearch: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, sr)
+}
+
+// HandleDraftNew is the request handler for the records v1 DraftNew route.
+func (c *Records) HandleDraftNew(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleDraftNew")
+
+	var dn v1.DraftNew
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dn); err != nil {
+		respondWithError(w, r, "HandleDraftNew: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	u, err := c.sessions.GetSessionUser(w, r)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftNew: GetSessionUser: %v", err)
+		return
+	}
+
+	reply, err := c.processDraftNew(r.Context(), dn, *u)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftNew: processDraftNew: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// HandleDraftEdit is the request handler for the records v1 DraftEdit route.
+func (c *Records) HandleDraftEdit(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleDraftEdit")
+
+	var de v1.DraftEdit
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&de); err != nil {
+		respondWithError(w, r, "HandleDraftEdit: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	u, err := c.sessions.GetSessionUser(w, r)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftEdit: GetSessionUser: %v", err)
+		return
+	}
+
+	reply, err := c.processDraftEdit(r.Context(), de, *u)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftEdit: processDraftEdit: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// HandleDraftDel is the request handler for the records v1 DraftDel route.
+func (c *Records) HandleDraftDel(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleDraftDel")
+
+	var dd v1.DraftDel
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dd); err != nil {
+		respondWithError(w, r, "HandleDraftDel: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	u, err := c.sessions.GetSessionUser(w, r)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftDel: GetSessionUser: %v", err)
+		return
+	}
+
+	reply, err := c.processDraftDel(r.Context(), dd, *u)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftDel: processDraftDel: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// HandleDraftDetails is the request handler for the records v1 DraftDetails route.
+func (c *Records) HandleDraftDetails(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleDraftDetails")
+
+	var dd v1.DraftDetails
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dd); err != nil {
+		respondWithError(w, r, "HandleDraftDetails: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	u, err := c.sessions.GetSessionUser(w, r)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftDetails: GetSessionUser: %v", err)
+		return
+	}
+
+	reply, err := c.processDraftDetails(r.Context(), dd, *u)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftDetails: processDraftDetails: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
+// HandleDraftPromote is the request handler for the records v1 DraftPromote route.
+func (c *Records) HandleDraftPromote(w http.ResponseWriter, r *http.Request) {
+	log.Tracef("HandleDraftPromote")
+
+	var dp v1.DraftPromote
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&dp); err != nil {
+		respondWithError(w, r, "HandleDraftPromote: unmarshal",
+			v1.UserErrorReply{
+				ErrorCode: v1.ErrorCodeInputInvalid,
+			})
+		return
+	}
+
+	u, err := c.sessions.GetSessionUser(w, r)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftPromote: GetSessionUser: %v", err)
+		return
+	}
+
+	reply, err := c.processDraftPromote(r.Context(), dp, *u)
+	if err != nil {
+		respondWithError(w, r,
+			"HandleDraftPromote: processDraftPromote: %v", err)
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, reply)
+}
+
 // New returns a new Records context.
 func New(cfg *config.Config, pdc *pdclient.Client, udb user.Database, s *sessions.Sessions, e *events.Manager) *Records {
 	return &Records{
@@ -339,6 +616,7 @@ func New(cfg *config.Config, pdc *pdclient.Client, udb user.Database, s *session
 		policy: &v1.PolicyReply{
 			RecordsPageSize:   v1.RecordsPageSize,
 			InventoryPageSize: v1.InventoryPageSize,
+			SearchPageSize:    v1.SearchPageSize,
 		},
 	}
 }
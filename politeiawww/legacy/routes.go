@@ -362,12 +362,21 @@ func (p *Politeiawww) setPiRoutes(r *records.Records, c *comments.Comments, t *t
 	p.addRoute(http.MethodPost, rcv1.APIRoute,
 		rcv1.RouteSetStatus, r.HandleSetStatus,
 		permissionAdmin)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteCensorFiles, r.HandleCensorFiles,
+		permissionAdmin)
 	p.addRoute(http.MethodPost, rcv1.APIRoute,
 		rcv1.RouteDetails, r.HandleDetails,
 		permissionPublic)
 	p.addRoute(http.MethodPost, rcv1.APIRoute,
 		rcv1.RouteTimestamps, r.HandleTimestamps,
 		permissionPublic)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteDiff, r.HandleDiff,
+		permissionPublic)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteHistory, r.HandleHistory,
+		permissionPublic)
 	p.addRoute(http.MethodPost, rcv1.APIRoute,
 		rcv1.RouteRecords, r.HandleRecords,
 		permissionPublic)
@@ -380,6 +389,24 @@ func (p *Politeiawww) setPiRoutes(r *records.Records, c *comments.Comments, t *t
 	p.addRoute(http.MethodPost, rcv1.APIRoute,
 		rcv1.RouteUserRecords, r.HandleUserRecords,
 		permissionPublic)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteSearch, r.HandleSearch,
+		permissionPublic)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteDraftNew, r.HandleDraftNew,
+		permissionLogin)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteDraftEdit, r.HandleDraftEdit,
+		permissionLogin)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteDraftDel, r.HandleDraftDel,
+		permissionLogin)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteDraftDetails, r.HandleDraftDetails,
+		permissionLogin)
+	p.addRoute(http.MethodPost, rcv1.APIRoute,
+		rcv1.RouteDraftPromote, r.HandleDraftPromote,
+		permissionLogin)
 
 	// Comment routes
 	p.addRoute(http.MethodPost, cmv1.APIRoute,
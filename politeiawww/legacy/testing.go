@@ -13,6 +13,7 @@ import (
 
 	"github.com/decred/dcrd/chaincfg/v3"
 	"github.com/decred/politeia/politeiad/api/v1/identity"
+	pdclient "github.com/decred/politeia/politeiad/client"
 	cms "github.com/decred/politeia/politeiawww/api/cms/v1"
 	www "github.com/decred/politeia/politeiawww/api/www/v1"
 	"github.com/decred/politeia/politeiawww/config"
@@ -266,9 +267,16 @@ func newTestPoliteiawww(t *testing.T) (*Politeiawww, func()) {
 		t.Fatalf("create cookie key: %v", err)
 	}
 
+	// Setup politeiad client. It is not used to send requests.
+	pdc, err := pdclient.New("", "", "", "", cfg.Identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// Setup politeiawww context
 	p := Politeiawww{
 		cfg:             cfg,
+		politeiad:       pdc,
 		params:          chaincfg.TestNet3Params(),
 		router:          mux.NewRouter(),
 		auth:            mux.NewRouter(),
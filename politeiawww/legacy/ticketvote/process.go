@@ -548,11 +548,21 @@ func convertTimestampToV1(t ticketvote.Timestamp) v1.Timestamp {
 	for _, v := range t.Proofs {
 		proofs = append(proofs, convertProofToV1(v))
 	}
+	anchors := make([]v1.TimestampAnchor, 0, len(t.Anchors))
+	for _, v := range t.Anchors {
+		anchors = append(anchors, v1.TimestampAnchor{
+			Provider:   v.Provider,
+			TxID:       v.TxID,
+			MerkleRoot: v.MerkleRoot,
+			Proof:      convertProofToV1(v.Proof),
+		})
+	}
 	return v1.Timestamp{
 		Data:       t.Data,
 		Digest:     t.Digest,
 		TxID:       t.TxID,
 		MerkleRoot: t.MerkleRoot,
 		Proofs:     proofs,
+		Anchors:    anchors,
 	}
 }
@@ -887,6 +887,19 @@ func (c *cockroachdb) convertEmailHistoryToUser(eh EmailHistory) (*user.EmailHis
 	return &h, nil
 }
 
+// Ping verifies that the database is reachable.
+//
+// Ping satisfies the Database interface.
+func (c *cockroachdb) Ping() error {
+	log.Tracef("Ping")
+
+	if c.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	return c.userDB.DB().Ping()
+}
+
 // Close shuts down the database. All interface functions must return with
 // errShutdown if the backend is shutting down.
 //
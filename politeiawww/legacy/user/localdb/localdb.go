@@ -542,6 +542,21 @@ func (l *localdb) EmailHistoriesGet(users []uuid.UUID) (map[uuid.UUID]user.Email
 	return histories, nil
 }
 
+// Ping verifies that the database is reachable. The leveldb database is
+// embedded so it is reachable until it has been shutdown.
+//
+// Ping satisfies the Database interface.
+func (l *localdb) Ping() error {
+	l.RLock()
+	defer l.RUnlock()
+
+	if l.shutdown {
+		return user.ErrShutdown
+	}
+
+	return nil
+}
+
 // Close shuts down the database.  All interface functions MUST return with
 // errShutdown if the backend is shutting down.
 //
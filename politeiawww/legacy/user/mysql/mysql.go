@@ -1315,6 +1315,22 @@ func (m *mysql) EmailHistoriesGet(users []uuid.UUID) (map[uuid.UUID]user.EmailHi
 	return histories, nil
 }
 
+// Ping verifies that the database is reachable.
+//
+// Ping satisfies the Database interface.
+func (m *mysql) Ping() error {
+	log.Tracef("Ping")
+
+	if m.isShutdown() {
+		return user.ErrShutdown
+	}
+
+	ctx, cancel := ctxWithTimeout()
+	defer cancel()
+
+	return m.userDB.PingContext(ctx)
+}
+
 // Close shuts down the database.  All interface functions must return with
 // errShutdown if the backend is shutting down.
 //
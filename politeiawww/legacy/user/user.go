@@ -532,6 +532,9 @@ type Database interface {
 	// Execute a plugin command
 	PluginExec(PluginCommand) (*PluginCommandReply, error)
 
+	// Ping verifies that the database is reachable.
+	Ping() error
+
 	// Close performs cleanup of the backend.
 	Close() error
 }
@@ -5,7 +5,6 @@
 package legacy
 
 import (
-	"encoding/hex"
 	"encoding/json"
 	"net/http"
 
@@ -24,7 +23,7 @@ func (p *Politeiawww) handleVersion(w http.ResponseWriter, r *http.Request) {
 		Version:      v1.PoliteiaWWWAPIVersion,
 		Route:        v1.PoliteiaWWWAPIRoute,
 		BuildVersion: p.cfg.Version,
-		PubKey:       hex.EncodeToString(p.cfg.Identity.Key[:]),
+		PubKey:       p.politeiad.ServerIdentity().String(),
 		TestNet:      p.cfg.TestNet,
 		Mode:         p.cfg.Mode,
 	}
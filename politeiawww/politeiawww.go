@@ -5,6 +5,7 @@
 package main
 
 import (
+	"context"
 	"crypto/elliptic"
 	"crypto/tls"
 	"database/sql"
@@ -60,6 +61,10 @@ type politeiawww struct {
 
 	// legacy contains the legacy politeiawww server.
 	legacy *legacy.Politeiawww
+
+	// health serves the liveness and readiness routes and keeps the API
+	// routes unavailable until startup has finished.
+	health *util.Health
 }
 
 func _main() error {
@@ -117,6 +122,7 @@ func _main() error {
 		cfg:       cfg,
 		router:    nil, // Set in setupRouter()
 		protected: nil, // Set in setupRouter()
+		health:    util.NewHealth(),
 
 		// Not implemented yet
 		db:       nil,
@@ -139,28 +145,10 @@ func _main() error {
 		return err
 	}
 
-	// Setup the API routes. The legacy routes are
-	// used by default. If the legacy routes have been
-	// disabled then the plugin routes will be setup.
-	if cfg.DisableLegacy {
-		// Legacy routes have been disabled
-		p.setupPluginRoutes()
-		err = p.setupPlugins()
-		if err != nil {
-			return err
-		}
-	} else {
-		// Legacy routes are not disabled
-		legacywww, err := legacy.NewPoliteiawww(p.cfg,
-			p.router, p.protected, cfg.ActiveNet.Params,
-			pdc)
-		if err != nil {
-			return err
-		}
-		p.legacy = legacywww
-	}
-
-	// Bind to a port and pass our router in
+	// Bind to a port and pass our router in. The listeners are started
+	// before the routes are setup so that the liveness and readiness
+	// routes are served while politeiad is unreachable. All other routes
+	// return a 503 until startup has finished.
 	listenC := make(chan error)
 	for _, listener := range cfg.Listeners {
 		listen := listener
@@ -180,7 +168,7 @@ func _main() error {
 				},
 			}
 			srv := &http.Server{
-				Handler:      p.router,
+				Handler:      p.health.Handler(p.router),
 				Addr:         listen,
 				ReadTimeout:  time.Duration(cfg.ReadTimeout) * time.Second,
 				WriteTimeout: time.Duration(cfg.WriteTimeout) * time.Second,
@@ -195,13 +183,55 @@ func _main() error {
 		}()
 	}
 
-	// Tell user we are ready to go.
-	log.Infof("Start of day")
-
 	// Setup OS signals
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGINT)
+
+	// Wait for politeiad to be ready. The legacy server requires
+	// politeiad during its setup.
+	p.health.Register(healthPoliteiad, pdc.Ready)
+	if !p.waitForPoliteiad(pdc, sigs) {
+		return nil
+	}
+
+	// Follow the politeiad identity history in case the identity has
+	// been rotated since it was pinned. The politeiad client updates
+	// the identity again whenever a politeiad reply can not be verified
+	// using it, i.e. when the identity is rotated while running.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	cfg.Identity, err = updateIdentity(ctx, pdc, cfg.Identity)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("update politeiad identity: %v", err)
+	}
+
+	// Setup the API routes. The legacy routes are
+	// used by default. If the legacy routes have been
+	// disabled then the plugin routes will be setup.
+	if cfg.DisableLegacy {
+		// Legacy routes have been disabled
+		p.setupPluginRoutes()
+		err = p.setupPlugins()
+		if err != nil {
+			return err
+		}
+	} else {
+		// Legacy routes are not disabled
+		legacywww, err := legacy.NewPoliteiawww(p.cfg,
+			p.router, p.protected, cfg.ActiveNet.Params,
+			pdc)
+		if err != nil {
+			return err
+		}
+		p.legacy = legacywww
+	}
+	p.setupUserDBHealth()
+
+	// Tell user we are ready to go.
+	p.health.SetStarted()
+	log.Infof("Start of day")
+
 	for {
 		select {
 		case sig := <-sigs:
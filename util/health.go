@@ -0,0 +1,226 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// HealthRouteLive is the liveness route. It returns a 200 as long as
+	// the server is able to serve http requests.
+	HealthRouteLive = "/healthz"
+
+	// HealthRouteReady is the readiness route. It returns a 200 once the
+	// server has finished starting up and all of its dependencies are
+	// reachable. A 503 is returned otherwise.
+	HealthRouteReady = "/readyz"
+
+	// HealthStatusOK is the status of a healthy server or dependency.
+	HealthStatusOK = "ok"
+
+	// HealthStatusStarting is the status of a server that has not
+	// finished starting up.
+	HealthStatusStarting = "starting"
+
+	// HealthStatusFail is the status of an unhealthy server or
+	// dependency.
+	HealthStatusFail = "fail"
+
+	// healthCheckTimeout is the maximum amount of time that the
+	// readiness checks are allowed to run for.
+	healthCheckTimeout = 5 * time.Second
+)
+
+// HealthCheck contains the result of a dependency check.
+type HealthCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HealthReply is the reply to the liveness and readiness routes. Checks
+// contains the result of every dependency check, keyed by dependency name. It
+// is only populated by the readiness route.
+type HealthReply struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheckFunc checks a group of dependencies and returns the result of
+// every dependency, keyed by dependency name. A nil error means that the
+// dependency is healthy.
+type HealthCheckFunc func(ctx context.Context) map[string]error
+
+// Health keeps track of whether a server has finished starting up and of the
+// checks of its dependencies. It serves the liveness and readiness routes.
+type Health struct {
+	started atomic.Bool
+
+	mtx    sync.Mutex
+	checks map[string]HealthCheckFunc // [group]check
+}
+
+// NewHealth returns a new Health.
+func NewHealth() *Health {
+	return &Health{
+		checks: make(map[string]HealthCheckFunc),
+	}
+}
+
+// Register registers a check for a single dependency. A check that was
+// previously registered under the same name is replaced.
+func (h *Health) Register(name string, check func(ctx context.Context) error) {
+	h.RegisterGroup(name, func(ctx context.Context) map[string]error {
+		return map[string]error{
+			name: check(ctx),
+		}
+	})
+}
+
+// RegisterGroup registers a check that reports on a group of dependencies. A
+// group that was previously registered under the same name is replaced.
+func (h *Health) RegisterGroup(group string, check HealthCheckFunc) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	h.checks[group] = check
+}
+
+// SetStarted marks the server as having finished starting up.
+func (h *Health) SetStarted() {
+	h.started.Store(true)
+}
+
+// Started returns whether the server has finished starting up.
+func (h *Health) Started() bool {
+	return h.started.Load()
+}
+
+// Check runs all of the registered checks concurrently and returns the
+// readiness of the server. A group that does not complete its check before
+// the check timeout is reported as failed under the group name.
+func (h *Health) Check(ctx context.Context) HealthReply {
+	h.mtx.Lock()
+	checks := make(map[string]HealthCheckFunc, len(h.checks))
+	for k, v := range h.checks {
+		checks[k] = v
+	}
+	h.mtx.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	type groupResult struct {
+		group   string
+		results map[string]error
+	}
+	resultsC := make(chan groupResult, len(checks))
+	for group, check := range checks {
+		go func(group string, check HealthCheckFunc) {
+			resultsC <- groupResult{
+				group:   group,
+				results: check(ctx),
+			}
+		}(group, check)
+	}
+
+	results := make(map[string]error, len(checks))
+	for len(checks) > 0 {
+		select {
+		case r := <-resultsC:
+			delete(checks, r.group)
+			for k, v := range r.results {
+				results[k] = v
+			}
+		case <-ctx.Done():
+			for group := range checks {
+				results[group] = ctx.Err()
+			}
+			checks = nil
+		}
+	}
+
+	reply := HealthReply{
+		Status: HealthStatusOK,
+		Checks: make(map[string]HealthCheck, len(results)),
+	}
+	for k, v := range results {
+		if v != nil {
+			reply.Status = HealthStatusFail
+			reply.Checks[k] = HealthCheck{
+				Status: HealthStatusFail,
+				Error:  v.Error(),
+			}
+			continue
+		}
+		reply.Checks[k] = HealthCheck{
+			Status: HealthStatusOK,
+		}
+	}
+	if !h.Started() {
+		reply.Status = HealthStatusStarting
+	}
+
+	return reply
+}
+
+// HandleLive is the request handler for the liveness route.
+func (h *Health) HandleLive(w http.ResponseWriter, r *http.Request) {
+	RespondWithJSON(w, http.StatusOK, HealthReply{
+		Status: HealthStatusOK,
+	})
+}
+
+// HandleReady is the request handler for the readiness route.
+func (h *Health) HandleReady(w http.ResponseWriter, r *http.Request) {
+	reply := h.Check(r.Context())
+	code := http.StatusOK
+	if reply.Status != HealthStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	RespondWithJSON(w, code, reply)
+}
+
+// Handler returns a handler that serves the liveness and readiness routes
+// and that passes all other requests on to the provided handler once the
+// server has finished starting up. Requests that are received before then
+// are answered with a 503.
+func (h *Health) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case HealthRouteLive:
+			h.HandleLive(w, r)
+			return
+		case HealthRouteReady:
+			h.HandleReady(w, r)
+			return
+		}
+		if !h.Started() {
+			RespondWithJSON(w, http.StatusServiceUnavailable, HealthReply{
+				Status: HealthStatusStarting,
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HealthFailures returns the sorted names of the dependencies that failed
+// their check.
+func HealthFailures(hr HealthReply) []string {
+	failed := make([]string, 0, len(hr.Checks))
+	for k, v := range hr.Checks {
+		if v.Status != HealthStatusOK {
+			failed = append(failed, k)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
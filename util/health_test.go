@@ -0,0 +1,146 @@
+// Copyright (c) 2022 The Decred developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealth(t *testing.T) {
+	var (
+		h      = NewHealth()
+		errDep = errors.New("unreachable")
+		depErr error
+	)
+	h.Register("dep", func(ctx context.Context) error {
+		return depErr
+	})
+	h.RegisterGroup("group", func(ctx context.Context) map[string]error {
+		return map[string]error{
+			"dep1": nil,
+			"dep2": nil,
+		}
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := h.Handler(next)
+
+	// serve sends a request to the provided route and returns the http
+	// status code and the decoded reply.
+	serve := func(route string) (int, HealthReply) {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, route, nil))
+
+		var hr HealthReply
+		if route != "/api" {
+			err := json.NewDecoder(w.Body).Decode(&hr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, hr
+	}
+
+	// Setup tests
+	var tests = []struct {
+		name       string
+		started    bool
+		depErr     error
+		route      string
+		wantCode   int
+		wantStatus string
+		wantFailed int
+	}{
+		{
+			"live while starting",
+			false,
+			nil,
+			HealthRouteLive,
+			http.StatusOK,
+			HealthStatusOK,
+			0,
+		},
+		{
+			"ready while starting",
+			false,
+			nil,
+			HealthRouteReady,
+			http.StatusServiceUnavailable,
+			HealthStatusStarting,
+			0,
+		},
+		{
+			"api while starting",
+			false,
+			nil,
+			"/api",
+			http.StatusServiceUnavailable,
+			"",
+			0,
+		},
+		{
+			"ready with failing dependency",
+			true,
+			errDep,
+			HealthRouteReady,
+			http.StatusServiceUnavailable,
+			HealthStatusFail,
+			1,
+		},
+		{
+			"ready",
+			true,
+			nil,
+			HealthRouteReady,
+			http.StatusOK,
+			HealthStatusOK,
+			0,
+		},
+		{
+			"api",
+			true,
+			nil,
+			"/api",
+			http.StatusOK,
+			"",
+			0,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.started {
+				h.SetStarted()
+			}
+			depErr = tc.depErr
+
+			code, hr := serve(tc.route)
+			if code != tc.wantCode {
+				t.Errorf("got code %v, want %v", code, tc.wantCode)
+			}
+			if hr.Status != tc.wantStatus {
+				t.Errorf("got status %v, want %v", hr.Status, tc.wantStatus)
+			}
+			if tc.route != HealthRouteReady {
+				return
+			}
+			if len(hr.Checks) != 3 {
+				t.Errorf("got %v checks, want 3", len(hr.Checks))
+			}
+			failed := HealthFailures(hr)
+			if len(failed) != tc.wantFailed {
+				t.Errorf("got %v failed checks, want %v",
+					len(failed), tc.wantFailed)
+			}
+		})
+	}
+}